@@ -22,9 +22,10 @@ func main() {
 
 	log.Info("starting application", slog.String("env", cfg.Env))
 
-	application := app.New(log, cfg.GRPC.Port, cfg.TokenTTL, cfg.RefreshTokenTTL)
+	application := app.New(log, cfg.GRPC.Port, cfg.HTTP, cfg.TokenTTL, cfg.RefreshTokenTTL, cfg.StepUpTokenTTL, cfg.Registration, cfg.SMS, cfg.PhoneLogin, cfg.MFA, cfg.Mailer, cfg.Passkey, cfg.RBAC, cfg.Policy, cfg.OAuth)
 
 	go application.GRPCSrv.MustRun()
+	go application.HTTPSrv.MustRun()
 
 	//graceful shutdown
 
@@ -36,6 +37,7 @@ func main() {
 	log.Info("stopping application", slog.String("signal", sign.String()))
 
 	application.GRPCSrv.Stop()
+	application.HTTPSrv.Stop()
 
 	log.Info("application stopped")
 }
@@ -1,23 +1,57 @@
 package app
 
 import (
+	"fmt"
 	grpcapp "github.com/sol1corejz/auth-service/internal/app/grpc"
+	httpapp "github.com/sol1corejz/auth-service/internal/app/http"
+	"github.com/sol1corejz/auth-service/internal/config"
+	"github.com/sol1corejz/auth-service/internal/http/oauth"
+	"github.com/sol1corejz/auth-service/internal/lib/mailer"
+	"github.com/sol1corejz/auth-service/internal/lib/policy"
+	"github.com/sol1corejz/auth-service/internal/lib/secretbox"
+	"github.com/sol1corejz/auth-service/internal/lib/signingkey"
+	"github.com/sol1corejz/auth-service/internal/lib/sms"
+	"github.com/sol1corejz/auth-service/internal/services/admin"
 	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/services/authz"
+	"github.com/sol1corejz/auth-service/internal/services/client"
+	"github.com/sol1corejz/auth-service/internal/services/device"
 	jwt_provider "github.com/sol1corejz/auth-service/internal/services/jwt"
+	"github.com/sol1corejz/auth-service/internal/services/mfa"
+	oauthsvc "github.com/sol1corejz/auth-service/internal/services/oauth"
+	"github.com/sol1corejz/auth-service/internal/services/org"
+	"github.com/sol1corejz/auth-service/internal/services/passkey"
+	"github.com/sol1corejz/auth-service/internal/services/pat"
+	"github.com/sol1corejz/auth-service/internal/services/phone"
+	"github.com/sol1corejz/auth-service/internal/services/rbac"
+	"github.com/sol1corejz/auth-service/internal/services/serviceaccount"
 	"github.com/sol1corejz/auth-service/internal/storage/postgres"
 	"log/slog"
+	"net/http"
 	"time"
 )
 
 type App struct {
 	GRPCSrv *grpcapp.App
+	HTTPSrv *httpapp.App
 }
 
 func New(
 	log *slog.Logger,
 	grpcPort int,
+	httpCfg config.HTTPConfig,
 	tokenTTL time.Duration,
 	refreshTokenTTL time.Duration,
+	stepUpTokenTTL time.Duration,
+	registrationCfg config.RegistrationConfig,
+	smsCfg config.SMSConfig,
+	phoneLoginCfg config.PhoneLoginConfig,
+	mfaCfg config.MFAConfig,
+	mailerCfg config.MailerConfig,
+	passkeyCfg config.PasskeyConfig,
+	rbacCfg config.RBACConfig,
+	policyCfg config.PolicyConfig,
+	oauthCfg config.OAuthConfig,
 ) *App {
 
 	storage, err := postgres.New()
@@ -27,10 +61,210 @@ func New(
 
 	jwtProvider := jwt_provider.New(tokenTTL, refreshTokenTTL)
 
-	authService := auth.New(log, storage, storage, storage, jwtProvider, tokenTTL, refreshTokenTTL)
+	mfaBox, err := secretbox.New(mfaCfg.EncryptionKey)
+	if err != nil {
+		panic("mfa encryption key: " + err.Error())
+	}
+
+	mail := mustMailer(log, mailerCfg)
+
+	mfaService := mfa.New(
+		log,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		mail,
+		mfaBox,
+		mfa.Settings{
+			Issuer:               mfaCfg.Issuer,
+			ChallengeTTL:         mfaCfg.ChallengeTTL,
+			MaxAttempts:          mfaCfg.MaxAttempts,
+			TOTPLockout:          mfaCfg.TOTPLockout,
+			EmailCodeTTL:         mfaCfg.EmailCodeTTL,
+			EmailResendInterval:  mfaCfg.EmailResendInterval,
+			EmailMaxCodesPerHour: mfaCfg.EmailMaxCodesPerHour,
+			StepUpTTL:            stepUpTokenTTL,
+		},
+		tokenTTL,
+		refreshTokenTTL,
+	)
+
+	authService := auth.New(log, storage, storage, storage, storage, jwtProvider, mfaService, storage, tokenTTL, refreshTokenTTL, registrationCfg.RequireApproval)
+
+	phoneService := phone.New(
+		log,
+		storage,
+		storage,
+		storage,
+		storage,
+		mfaService,
+		mustSMSSender(log, smsCfg),
+		phone.Limits{
+			CodeTTL:         phoneLoginCfg.CodeTTL,
+			ResendInterval:  phoneLoginCfg.ResendInterval,
+			MaxCodesPerHour: phoneLoginCfg.MaxCodesPerHour,
+			MaxAttempts:     phoneLoginCfg.MaxAttempts,
+		},
+		tokenTTL,
+		refreshTokenTTL,
+	)
+
+	rbacService := rbac.New(log, storage, storage, storage, storage, storage, rbacCfg.CacheTTL, rbacCfg.CacheSize)
+
+	adminService := admin.New(log, storage, storage, storage, storage, storage, storage, storage, storage, rbacService)
+
+	passkeyService := passkey.New(log, storage, storage, storage, storage, passkeyCfg.SessionTTL, tokenTTL, refreshTokenTTL, stepUpTokenTTL)
+
+	orgService := org.New(log, storage, storage, storage, storage, tokenTTL, refreshTokenTTL)
+
+	authzService := authz.New(log, storage, storage, storage, mustPolicy(policyCfg), mustLocation(policyCfg.Timezone))
+
+	clientService := client.New(log, storage, storage, tokenTTL)
+
+	patService := pat.New(log, storage, storage)
+
+	oauthService := oauthsvc.New(
+		log,
+		authService,
+		mfaService,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		mustSigningKey(log, oauthCfg.SigningKeyPath, oauthCfg.RequireSigningKey),
+		oauthCfg.Issuer,
+		oauthCfg.CodeTTL,
+		tokenTTL,
+		refreshTokenTTL,
+	)
+
+	deviceService := device.New(
+		log,
+		authService,
+		mfaService,
+		storage,
+		storage,
+		storage,
+		storage,
+		oauthCfg.DeviceCodeTTL,
+		oauthCfg.DevicePollInterval,
+		tokenTTL,
+		refreshTokenTTL,
+	)
+
+	serviceAccountService := serviceaccount.New(
+		log,
+		storage,
+		storage,
+		oauthCfg.Issuer,
+		oauthCfg.ServiceAccountTokenTTL,
+	)
+
+	grpcApp := grpcapp.New(
+		log,
+		authService,
+		phoneService,
+		adminService,
+		mfaService,
+		passkeyService,
+		rbacService,
+		orgService,
+		authzService,
+		clientService,
+		patService,
+		grpcPort,
+	)
+
+	mux := http.NewServeMux()
+	oauth.Register(mux, log, clientService, oauthService, oauthService, deviceService, serviceAccountService)
+	httpApp := httpapp.New(log, mux, httpCfg.Port, httpCfg.Timeout)
 
-	grpcApp := grpcapp.New(log, authService, grpcPort)
 	return &App{
 		GRPCSrv: grpcApp,
+		HTTPSrv: httpApp,
+	}
+}
+
+func mustSMSSender(log *slog.Logger, cfg config.SMSConfig) sms.Sender {
+	switch cfg.Provider {
+	case "log":
+		return sms.NewLogSender(log)
+	case "file":
+		return sms.NewFileSender(cfg.FilePath)
+	case "http":
+		if cfg.HTTP.URL == "" {
+			panic("sms http provider requires url")
+		}
+		return sms.NewHTTPSender(cfg.HTTP.URL, cfg.HTTP.Token, cfg.HTTP.From, cfg.HTTP.Timeout)
+	default:
+		panic(fmt.Sprintf("unknown sms provider: %q", cfg.Provider))
+	}
+}
+
+func mustMailer(log *slog.Logger, cfg config.MailerConfig) mailer.Mailer {
+	switch cfg.Provider {
+	case "log":
+		return mailer.NewLogMailer(log)
+	case "smtp":
+		if cfg.SMTP.Host == "" || cfg.From == "" {
+			panic("smtp mailer requires host and from")
+		}
+		return mailer.NewSMTPMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.From)
+	default:
+		panic(fmt.Sprintf("unknown mailer provider: %q", cfg.Provider))
+	}
+}
+
+// mustPolicy loads authorization rules. Without a path every Authorize call is denied.
+func mustPolicy(cfg config.PolicyConfig) *policy.Policy {
+	if cfg.Path == "" {
+		return &policy.Policy{}
+	}
+
+	p, err := policy.Load(cfg.Path)
+	if err != nil {
+		panic("authorization policy: " + err.Error())
 	}
+
+	return p
+}
+
+// mustSigningKey loads the key ID tokens are signed with, or generates one for development
+// unless the key is required.
+func mustSigningKey(log *slog.Logger, path string, required bool) *signingkey.Key {
+	if path == "" {
+		if required {
+			panic("oauth signing key is required, set OAUTH_SIGNING_KEY_PATH")
+		}
+
+		log.Warn("oauth signing key is not configured, generating one; id tokens will not survive restarts")
+
+		key, err := signingkey.Generate()
+		if err != nil {
+			panic(err)
+		}
+
+		return key
+	}
+
+	key, err := signingkey.Load(path)
+	if err != nil {
+		panic("oauth signing key: " + err.Error())
+	}
+
+	return key
+}
+
+func mustLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic("policy timezone: " + err.Error())
+	}
+
+	return loc
 }
@@ -15,10 +15,23 @@ type App struct {
 }
 
 // New creates new grpc server app.
-func New(log *slog.Logger, authService authgrpc.Auth, port int) *App {
+func New(
+	log *slog.Logger,
+	authService authgrpc.Auth,
+	phoneService authgrpc.PhoneAuth,
+	adminService authgrpc.Admin,
+	mfaService authgrpc.MFA,
+	passkeyService authgrpc.Passkey,
+	rbacService authgrpc.RBAC,
+	orgService authgrpc.Org,
+	authzService authgrpc.Authz,
+	clientService authgrpc.ClientCredentials,
+	patService authgrpc.PersonalAccessTokens,
+	port int,
+) *App {
 	gRPCServer := grpc.NewServer()
 
-	authgrpc.Register(gRPCServer, authService)
+	authgrpc.Register(gRPCServer, authService, phoneService, adminService, mfaService, passkeyService, rbacService, orgService, authzService, clientService, patService)
 
 	return &App{
 		log:        log,
@@ -8,10 +8,21 @@ import (
 )
 
 type Config struct {
-	Env             string        `yaml:"env" env-default:"local"`
-	TokenTTL        time.Duration `yaml:"token_ttl" env-required:"true"`
-	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-required:"true"`
-	GRPC            GRPCConfig    `yaml:"grpc"`
+	Env             string             `yaml:"env" env-default:"local"`
+	TokenTTL        time.Duration      `yaml:"token_ttl" env-required:"true"`
+	RefreshTokenTTL time.Duration      `yaml:"refresh_token_ttl" env-required:"true"`
+	StepUpTokenTTL  time.Duration      `yaml:"step_up_token_ttl" env-default:"5m"`
+	Registration    RegistrationConfig `yaml:"registration"`
+	GRPC            GRPCConfig         `yaml:"grpc"`
+	HTTP            HTTPConfig         `yaml:"http"`
+	SMS             SMSConfig          `yaml:"sms"`
+	PhoneLogin      PhoneLoginConfig   `yaml:"phone_login"`
+	MFA             MFAConfig          `yaml:"mfa"`
+	Mailer          MailerConfig       `yaml:"mailer"`
+	Passkey         PasskeyConfig      `yaml:"passkey"`
+	RBAC            RBACConfig         `yaml:"rbac"`
+	Policy          PolicyConfig       `yaml:"policy"`
+	OAuth           OAuthConfig        `yaml:"oauth"`
 }
 
 type GRPCConfig struct {
@@ -19,6 +30,40 @@ type GRPCConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+// HTTPConfig configures the server of OAuth endpoints.
+type HTTPConfig struct {
+	Port    int           `yaml:"port" env-default:"8080"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+// SMSConfig selects how SMS are delivered: "log" and "file" are development stubs,
+// "http" posts messages to a provider endpoint.
+type SMSConfig struct {
+	Provider string        `yaml:"provider" env-default:"log"`
+	FilePath string        `yaml:"file_path" env-default:"./storage/sms.log"`
+	HTTP     SMSHTTPConfig `yaml:"http"`
+}
+
+type SMSHTTPConfig struct {
+	URL     string        `yaml:"url" env:"SMS_HTTP_URL"`
+	Token   string        `yaml:"token" env:"SMS_HTTP_TOKEN"`
+	From    string        `yaml:"from"`
+	Timeout time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
+// RegistrationConfig configures sign up. With RequireApproval, new users are pending
+// until an admin activates them.
+type RegistrationConfig struct {
+	RequireApproval bool `yaml:"require_approval" env-default:"false"`
+}
+
+type PhoneLoginConfig struct {
+	CodeTTL         time.Duration `yaml:"code_ttl" env-default:"5m"`
+	ResendInterval  time.Duration `yaml:"resend_interval" env-default:"1m"`
+	MaxCodesPerHour int           `yaml:"max_codes_per_hour" env-default:"5"`
+	MaxAttempts     int           `yaml:"max_attempts" env-default:"5"`
+}
+
 func MustLoad() *Config {
 	path := fetchConfigPath()
 	if path == "" {
@@ -54,3 +99,70 @@ func fetchConfigPath() string {
 
 	return res
 }
+
+type MFAConfig struct {
+	Issuer string `yaml:"issuer" env-default:"SSO"`
+	// EncryptionKey encrypts TOTP secrets at rest.
+	EncryptionKey string        `yaml:"encryption_key" env:"MFA_ENCRYPTION_KEY"`
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+	MaxAttempts   int           `yaml:"max_attempts" env-default:"5"`
+	// TOTPLockout is how long TOTP codes of a user are refused after max_attempts in a row failed.
+	TOTPLockout time.Duration `yaml:"totp_lockout" env-default:"15m"`
+	// Email one-time codes, an alternative to TOTP.
+	EmailCodeTTL         time.Duration `yaml:"email_code_ttl" env-default:"10m"`
+	EmailResendInterval  time.Duration `yaml:"email_resend_interval" env-default:"1m"`
+	EmailMaxCodesPerHour int           `yaml:"email_max_codes_per_hour" env-default:"5"`
+}
+
+// MailerConfig selects how emails are delivered: "log" is a development stub, "smtp" uses a relay.
+type MailerConfig struct {
+	Provider string           `yaml:"provider" env-default:"log"`
+	From     string           `yaml:"from" env:"MAILER_FROM"`
+	SMTP     MailerSMTPConfig `yaml:"smtp"`
+}
+
+type MailerSMTPConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+}
+
+// PasskeyConfig configures WebAuthn ceremonies; relying parties are set per app.
+type PasskeyConfig struct {
+	// SessionTTL is how long a started registration or login can be finished.
+	SessionTTL time.Duration `yaml:"session_ttl" env-default:"5m"`
+}
+
+// RBACConfig tunes the in-process cache of permission checks. Changes made on
+// other instances become visible once cached entries expire.
+type RBACConfig struct {
+	CacheTTL  time.Duration `yaml:"cache_ttl" env-default:"30s"`
+	CacheSize int           `yaml:"cache_size" env-default:"100000"`
+}
+
+// PolicyConfig points to attribute-based authorization rules. Hours and weekdays
+// the rules compare are taken in Timezone.
+type PolicyConfig struct {
+	Path     string `yaml:"path" env:"POLICY_PATH"`
+	Timezone string `yaml:"timezone" env-default:"UTC"`
+}
+
+// OAuthConfig configures the authorization code flow and OpenID Connect. Codes are exchanged
+// right after the redirect, so CodeTTL stays short. Issuer is the public URL of the HTTP server
+// and has no default, so deployments cannot advertise localhost by accident.
+// ID tokens are signed with the PEM-encoded RSA key at SigningKeyPath; without it a key is
+// generated on start, and ID tokens issued before a restart stop verifying. RequireSigningKey
+// refuses to start without the key instead.
+// Device codes give users DeviceCodeTTL to approve them, and devices poll no more often
+// than every DevicePollInterval.
+type OAuthConfig struct {
+	CodeTTL            time.Duration `yaml:"code_ttl" env-default:"1m"`
+	Issuer             string        `yaml:"issuer" env:"OAUTH_ISSUER" env-required:"true"`
+	SigningKeyPath     string        `yaml:"signing_key_path" env:"OAUTH_SIGNING_KEY_PATH"`
+	RequireSigningKey  bool          `yaml:"require_signing_key" env-default:"false"`
+	DeviceCodeTTL      time.Duration `yaml:"device_code_ttl" env-default:"10m"`
+	DevicePollInterval time.Duration `yaml:"device_poll_interval" env-default:"5s"`
+	// ServiceAccountTokenTTL is how long tokens issued for JWT assertions of service accounts live.
+	ServiceAccountTokenTTL time.Duration `yaml:"service_account_token_ttl" env-default:"15m"`
+}
@@ -1,8 +1,102 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AppAccessMode decides which users may log in to an app.
+type AppAccessMode string
+
+const (
+	// AppAccessOpen apps admit any active user.
+	AppAccessOpen AppAccessMode = "open"
+	// AppAccessMembers apps admit users added to the app by an admin.
+	AppAccessMembers AppAccessMode = "members"
+	// AppAccessEmailDomain apps admit users whose email is in one of the allowed domains.
+	AppAccessEmailDomain AppAccessMode = "email_domain"
+)
 
 type App struct {
-	ID   uuid.UUID
-	Name string
+	ID          uuid.UUID
+	Name        string
+	Description string
+	// OwnerID is the user responsible for the app, zero if nobody is.
+	OwnerID uuid.UUID
+	// Enabled apps accept logins. Access tokens issued before an app was disabled stay valid until
+	// they expire, but can't be refreshed.
+	Enabled   bool
+	CreatedAt time.Time
+	MFAPolicy MFAPolicy
+	// WebAuthnRPID is the relying party ID passkeys of the app are bound to.
+	// Passkeys are disabled for the app while it is empty.
+	WebAuthnRPID    string
+	WebAuthnOrigins []string
+	// ClientScopes are scopes the app may request for itself with the client credentials grant.
+	ClientScopes []string
+	// RedirectURIs are where the authorization code flow may send users back to; matched exactly.
+	RedirectURIs []string
+	// PostLogoutRedirectURIs are where RP-initiated logout may send users back to; matched exactly.
+	PostLogoutRedirectURIs []string
+	// AccessMode decides which users may log in to the app; it does not affect tokens already issued.
+	AccessMode AppAccessMode
+	// AllowedEmailDomains are lower-case domains, e.g. "example.com", admitted in AppAccessEmailDomain
+	// mode. Subdomains must be listed on their own.
+	AllowedEmailDomains []string
+}
+
+// AdmitsEmail reports whether the email is in one of the allowed domains of the app.
+func (a App) AdmitsEmail(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+
+	return slices.Contains(a.AllowedEmailDomains, strings.ToLower(email[at+1:]))
+}
+
+// AppMembership tells which users were added to apps, see AppAccessMembers.
+type AppMembership interface {
+	IsAppMember(ctx context.Context, appID uuid.UUID, userID uuid.UUID) (bool, error)
+}
+
+// Admits reports whether the access mode of the app lets the user log in.
+// Membership is looked up only for apps in AppAccessMembers mode.
+func (a App) Admits(ctx context.Context, membership AppMembership, user User) (bool, error) {
+	switch a.AccessMode {
+	case AppAccessMembers:
+		return membership.IsAppMember(ctx, a.ID, user.ID)
+	case AppAccessEmailDomain:
+		return a.AdmitsEmail(user.Email), nil
+	default:
+		return true, nil
+	}
+}
+
+// AppUpdate lists app fields to change; nil fields are left as they are.
+type AppUpdate struct {
+	Name        *string
+	Description *string
+	// OwnerID set to zero UUID clears the owner.
+	OwnerID                *uuid.UUID
+	Enabled                *bool
+	ClientScopes           *[]string
+	RedirectURIs           *[]string
+	PostLogoutRedirectURIs *[]string
+	AccessMode             *AppAccessMode
+	AllowedEmailDomains    *[]string
+}
+
+// AppMember is a user added to an app, which matters for apps in AppAccessMembers mode.
+type AppMember struct {
+	AppID  uuid.UUID
+	UserID uuid.UUID
+	Email  string
+	// AddedBy is the admin who added the user, zero if they were deleted since.
+	AddedBy   uuid.UUID
+	CreatedAt time.Time
 }
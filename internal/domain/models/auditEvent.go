@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent is a security-relevant action recorded in the audit log.
+type AuditEvent struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	Event     string
+	Details   map[string]any
+	CreatedAt time.Time
+}
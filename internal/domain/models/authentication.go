@@ -0,0 +1,102 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuthMethod is an authentication method reference (RFC 8176) put into the amr claim.
+type AuthMethod string
+
+const (
+	AuthMethodPassword    AuthMethod = "pwd"
+	AuthMethodOTP         AuthMethod = "otp"
+	AuthMethodSMS         AuthMethod = "sms"
+	AuthMethodHardwareKey AuthMethod = "hwk"
+	AuthMethodMultiFactor AuthMethod = "mfa"
+)
+
+// ACR is an authentication context class put into the acr claim. Higher classes are stronger.
+type ACR string
+
+const (
+	// ACRSingleFactor is a session started with one factor.
+	ACRSingleFactor ACR = "1"
+	// ACRMultiFactor is a session started with several factors, or with a user verified passkey.
+	ACRMultiFactor ACR = "2"
+	// ACRStepUp is a short-lived token issued after re-verifying a factor within a session.
+	ACRStepUp ACR = "3"
+)
+
+var acrLevels = map[ACR]int{
+	ACRSingleFactor: 1,
+	ACRMultiFactor:  2,
+	ACRStepUp:       3,
+}
+
+// Valid tells whether the class is known.
+func (a ACR) Valid() bool {
+	_, ok := acrLevels[a]
+	return ok
+}
+
+// AtLeast tells whether the class is as strong as min.
+func (a ACR) AtLeast(min ACR) bool {
+	return acrLevels[a] >= acrLevels[min]
+}
+
+// Authentication tells how and when the user proved their identity for a session.
+type Authentication struct {
+	Methods []AuthMethod
+	Time    time.Time
+	// OrgID is the organization the session was scoped to by an org login,
+	// zero for sessions outside organizations.
+	OrgID uuid.UUID
+	// Scopes are what the user granted a client that started the session with
+	// the authorization code flow, empty for first-party logins.
+	Scopes []string
+}
+
+// NewAuthentication returns authentication with the given methods performed now.
+func NewAuthentication(methods ...AuthMethod) Authentication {
+	return Authentication{Time: time.Now()}.With(methods...)
+}
+
+// With returns a copy with methods added. Once two factors are present, "mfa" is added as well.
+func (a Authentication) With(methods ...AuthMethod) Authentication {
+	result := Authentication{Methods: slices.Clone(a.Methods), Time: a.Time, OrgID: a.OrgID, Scopes: slices.Clone(a.Scopes)}
+	for _, method := range methods {
+		if !slices.Contains(result.Methods, method) {
+			result.Methods = append(result.Methods, method)
+		}
+	}
+
+	if result.factors() > 1 && !slices.Contains(result.Methods, AuthMethodMultiFactor) {
+		result.Methods = append(result.Methods, AuthMethodMultiFactor)
+	}
+
+	return result
+}
+
+// ACR returns the class a session with this authentication gets.
+// Passkeys are always user verified, so they count as multi-factor on their own.
+func (a Authentication) ACR() ACR {
+	if a.factors() > 1 || slices.Contains(a.Methods, AuthMethodHardwareKey) {
+		return ACRMultiFactor
+	}
+
+	return ACRSingleFactor
+}
+
+func (a Authentication) factors() int {
+	n := 0
+	for _, method := range a.Methods {
+		if method != AuthMethodMultiFactor {
+			n++
+		}
+	}
+
+	return n
+}
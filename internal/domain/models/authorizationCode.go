@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuthorizationRequest is an OAuth authorization request of a client (RFC 6749, section 4.1.1)
+// with a PKCE challenge (RFC 7636).
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// Nonce is echoed in the ID token, binding it to the client's session (OpenID Connect Core, section 3.1.2.1).
+	Nonce string
+	// RedirectURIDefaulted is set if the client omitted RedirectURI and it was filled in with the
+	// only URI the app registered. The token request then needn't send it (RFC 6749, section 4.1.3).
+	RedirectURIDefaulted bool
+}
+
+// AuthorizationCode is issued to a client once the user logged in, and exchanged for tokens.
+// Only its hash is stored.
+type AuthorizationCode struct {
+	AppID  uuid.UUID
+	UserID uuid.UUID
+	// RedirectURI is empty if the authorization request omitted it.
+	RedirectURI   string
+	CodeChallenge string
+	Scopes        []string
+	Nonce         string
+	Auth          Authentication
+	ExpiresAt     time.Time
+}
+
+// OAuthTokens are what an authorization code is exchanged for. IDToken is issued
+// only if the openid scope was granted.
+type OAuthTokens struct {
+	AccessToken  string
+	RefreshToken string
+	IDToken      string
+	Scopes       []string
+	ExpiresIn    time.Duration
+}
+
+// LogoutRequest is an RP-initiated logout request (OpenID Connect RP-Initiated Logout 1.0, section 2).
+type LogoutRequest struct {
+	IDTokenHint           string
+	ClientID              string
+	PostLogoutRedirectURI string
+	State                 string
+}
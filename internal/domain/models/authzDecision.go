@@ -0,0 +1,8 @@
+package models
+
+// AuthzDecision is the outcome of a policy evaluation. Rule is the ID of the rule
+// that decided it, empty when no rule applied and access was denied by default.
+type AuthzDecision struct {
+	Allowed bool
+	Rule    string
+}
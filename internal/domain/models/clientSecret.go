@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClientSecret is a secret an app authenticates with as an OAuth client.
+// Only its hash is stored; the secret itself is shown once, when it is created.
+type ClientSecret struct {
+	ID    uuid.UUID
+	AppID uuid.UUID
+	Name  string
+	// ExpiresAt is zero for secrets that do not expire.
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// ClientToken is an access token issued to an app acting on its own behalf.
+type ClientToken struct {
+	AccessToken string
+	ExpiresAt   time.Time
+	Scopes      []string
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceCodeStatus is where a device authorization is in its life.
+type DeviceCodeStatus string
+
+const (
+	DeviceCodePending  DeviceCodeStatus = "pending"
+	DeviceCodeApproved DeviceCodeStatus = "approved"
+	DeviceCodeDenied   DeviceCodeStatus = "denied"
+	// DeviceCodeConsumed codes were exchanged for tokens.
+	DeviceCodeConsumed DeviceCodeStatus = "consumed"
+)
+
+// DeviceAuthorization is returned to a device starting the device authorization grant (RFC 8628, section 3.2).
+type DeviceAuthorization struct {
+	DeviceCode string
+	UserCode   string
+	ExpiresIn  time.Duration
+	Interval   time.Duration
+}
+
+// DeviceCode is a device authorization as stored. Only the hash of its device code is stored;
+// the user code is stored normalized.
+type DeviceCode struct {
+	AppID    uuid.UUID
+	UserCode string
+	Scopes   []string
+	Status   DeviceCodeStatus
+	// UserID and Auth are set once the user resolved the code.
+	UserID uuid.UUID
+	Auth   Authentication
+	// LastPolledAt is zero until the device first polls.
+	LastPolledAt time.Time
+	ExpiresAt    time.Time
+}
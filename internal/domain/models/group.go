@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Group is a team of users. Groups can contain other groups: members of a subgroup
+// are members of every group above it and inherit roles assigned to those groups.
+type Group struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	CreatedAt   time.Time
+}
@@ -0,0 +1,17 @@
+package models
+
+// IdentifierKind tells which user attribute an Identifier refers to.
+type IdentifierKind int
+
+const (
+	IdentifierID IdentifierKind = iota
+	IdentifierEmail
+	IdentifierUsername
+	IdentifierPhone
+)
+
+// Identifier is a normalized value a user can be looked up by.
+type Identifier struct {
+	Kind  IdentifierKind
+	Value string
+}
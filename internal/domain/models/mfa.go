@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFAPolicy tells which users of an app must pass a second factor on login.
+// Users who enrolled a factor are always challenged regardless of the policy.
+type MFAPolicy string
+
+const (
+	MFAPolicyOptional MFAPolicy = "optional"
+	MFAPolicyAdmins   MFAPolicy = "admins"
+	MFAPolicyAll      MFAPolicy = "all"
+)
+
+// MFAMethod is a second factor a challenge can be completed with.
+type MFAMethod string
+
+const (
+	MFAMethodTOTP  MFAMethod = "totp"
+	MFAMethodEmail MFAMethod = "email"
+)
+
+type TOTP struct {
+	UserID          uuid.UUID
+	SecretEncrypted []byte
+	// ConfirmedAt is zero until the first code is verified.
+	ConfirmedAt  time.Time
+	LastUsedStep int64
+}
+
+// MFAChallenge is issued after a successful password check and exchanged,
+// together with a second factor, for a token pair.
+type MFAChallenge struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	AppID  uuid.UUID
+	// EnrollmentRequired is set when the app requires MFA but the user has not enrolled yet;
+	// the challenge then allows enrolling TOTP before completing it.
+	EnrollmentRequired bool
+	// FirstFactor is how the user authenticated before the challenge, e.g. with a password.
+	FirstFactor AuthMethod
+	// Methods the user has enrolled; empty when enrollment is required.
+	// It is not stored and is only set on a newly issued challenge.
+	Methods   []MFAMethod
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+// EmailCode is a one-time code mailed to the user as a second factor.
+type EmailCode struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// ChallengeID is zero for codes confirming changes to the account's MFA settings.
+	ChallengeID uuid.UUID
+	CodeHash    []byte
+	Attempts    int
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
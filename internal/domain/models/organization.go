@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrgRole is the role of a member inside an organization.
+type OrgRole string
+
+const (
+	// OrgRoleAdmin manages members of the organization.
+	OrgRoleAdmin  OrgRole = "admin"
+	OrgRoleMember OrgRole = "member"
+)
+
+// Organization is a customer tenant users belong to.
+type Organization struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
+}
+
+// OrgMember is a user's membership in an organization. Zero AcceptedAt marks
+// an invitation the user has not accepted yet.
+type OrgMember struct {
+	OrgID      uuid.UUID
+	User       User
+	Role       OrgRole
+	CreatedAt  time.Time
+	AcceptedAt time.Time
+}
+
+// Pending reports whether the membership is an invitation the user has not accepted.
+func (m OrgMember) Pending() bool {
+	return m.AcceptedAt.IsZero()
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasskeyCredential is a WebAuthn public key credential registered by a user.
+type PasskeyCredential struct {
+	ID              []byte
+	UserID          uuid.UUID
+	RPID            string
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	// SignCount is the last signature counter reported by the authenticator.
+	SignCount      uint32
+	Transports     []string
+	BackupEligible bool
+	BackupState    bool
+	Name           string
+	CreatedAt      time.Time
+	LastUsedAt     time.Time
+}
+
+type PasskeyCeremony string
+
+const (
+	PasskeyCeremonyRegistration PasskeyCeremony = "registration"
+	PasskeyCeremonyLogin        PasskeyCeremony = "login"
+	PasskeyCeremonyStepUp       PasskeyCeremony = "step_up"
+)
+
+// PasskeySession keeps the server side state of a WebAuthn ceremony between its two steps.
+type PasskeySession struct {
+	ID       uuid.UUID
+	Ceremony PasskeyCeremony
+	// UserID is zero for login, where the user is only known from the assertion.
+	UserID    uuid.UUID
+	AppID     uuid.UUID
+	Data      []byte
+	ExpiresAt time.Time
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PersonalAccessToken is a long-lived token a user creates for scripts, separate from their sessions.
+// Only its hash is stored; the token itself is shown once, when it is created.
+type PersonalAccessToken struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Name   string
+	Scopes []string
+	// ExpiresAt is zero for tokens that do not expire.
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
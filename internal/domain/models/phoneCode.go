@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PhoneCodePurpose tells what a one-time SMS code was issued for.
+type PhoneCodePurpose string
+
+const (
+	PhoneCodeLogin  PhoneCodePurpose = "login"
+	PhoneCodeVerify PhoneCodePurpose = "verify"
+)
+
+type PhoneCode struct {
+	ID        uuid.UUID
+	Phone     string
+	Purpose   PhoneCodePurpose
+	UserID    uuid.UUID
+	CodeHash  []byte
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
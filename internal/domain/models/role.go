@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoleAdmin is the global role that replaced the users.is_admin flag.
+const RoleAdmin = "admin"
+
+// Role is a named set of permissions. Roles with zero AppID are global:
+// they can be granted across all apps or within any single app.
+type Role struct {
+	ID          uuid.UUID
+	AppID       uuid.UUID
+	Name        string
+	Description string
+	Permissions []string
+	CreatedAt   time.Time
+}
+
+// Permission is an action a role allows, e.g. "invoices:read".
+// Permissions with zero AppID are global and can be attached to roles of any app.
+type Permission struct {
+	ID          uuid.UUID
+	AppID       uuid.UUID
+	Name        string
+	Description string
+	CreatedAt   time.Time
+}
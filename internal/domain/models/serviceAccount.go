@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceAccount is a non-human identity of a workload. Its ID is the ID of the user backing it,
+// so roles, groups and statuses apply to it like to any user.
+type ServiceAccount struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	CreatedAt   time.Time
+}
+
+// ServiceAccountKey is a public key a service account signs JWT assertions with.
+type ServiceAccountKey struct {
+	ID        uuid.UUID
+	AccountID uuid.UUID
+	// PublicKey is the DER-encoded PKIX public key.
+	PublicKey []byte
+	Algorithm string
+	// ExpiresAt is zero for keys that do not expire.
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// ServiceAccountToken is an access token issued to a service account for a JWT assertion.
+// It can't be refreshed: the account signs a new assertion instead.
+type ServiceAccountToken struct {
+	AccessToken string
+	ExpiresAt   time.Time
+	ExpiresIn   time.Duration
+}
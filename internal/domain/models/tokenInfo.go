@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// TokenInfo describes an active access token to resource servers.
+type TokenInfo struct {
+	UserID string
+	Email  string
+	AppID  string
+	// OrgID is empty unless the token is scoped to an organization.
+	OrgID string
+	// PersonalAccessTokenID is empty unless the token is a personal access token, which has no app.
+	PersonalAccessTokenID string
+	// ServiceAccount is set for tokens issued to service accounts; UserID is then the account ID.
+	ServiceAccount bool
+	// ClientID is set for tokens an app got for itself with client credentials; UserID is then empty.
+	ClientID string
+	IssuedAt time.Time
+	// ExpiresAt is zero for personal access tokens that do not expire.
+	ExpiresAt time.Time
+	Auth      Authentication
+	ACR       ACR
+}
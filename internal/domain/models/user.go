@@ -1,9 +1,60 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserStatus string
+
+const (
+	UserStatusPending     UserStatus = "pending"
+	UserStatusActive      UserStatus = "active"
+	UserStatusSuspended   UserStatus = "suspended"
+	UserStatusDeactivated UserStatus = "deactivated"
+)
 
 type User struct {
 	ID       uuid.UUID
 	Email    string
+	Username string
+	Phone    string
 	PassHash []byte
+
+	Status       UserStatus
+	StatusReason string
+	// SuspendedUntil is zero for suspensions without an end date.
+	SuspendedUntil time.Time
+	// SessionsRevokedAt invalidates refresh tokens issued before it.
+	SessionsRevokedAt time.Time
+
+	// IsAdmin is filled in only by user listings and exports; elsewhere ask the storage.
+	IsAdmin     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+// IsActive reports whether user is allowed to log in and refresh tokens at the given time.
+// Suspension with an end date lifts by itself once the date has passed.
+func (u User) IsActive(now time.Time) bool {
+	switch u.Status {
+	case UserStatusActive:
+		return true
+	case UserStatusSuspended:
+		return !u.SuspendedUntil.IsZero() && now.After(u.SuspendedUntil)
+	default:
+		return false
+	}
+}
+
+// UserStatusChange describes a status transition made by an admin.
+type UserStatusChange struct {
+	UserID  uuid.UUID
+	ActorID uuid.UUID
+	From    UserStatus
+	To      UserStatus
+	Reason  string
+	Until   time.Time
 }
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserSortField string
+
+const (
+	UserSortCreatedAt   UserSortField = "created_at"
+	UserSortLastLoginAt UserSortField = "last_login_at"
+	UserSortEmail       UserSortField = "email"
+)
+
+// UserFilter selects a page of users. Zero values disable the corresponding filter.
+// Time ranges are half-open: [From, To).
+type UserFilter struct {
+	EmailPrefix string
+	Statuses    []UserStatus
+	// Role selects holders of the global role with this name or, if AppID is set,
+	// of the role of that app, whether assigned directly or through groups.
+	Role          string
+	AppID         uuid.UUID
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	LastLoginFrom time.Time
+	LastLoginTo   time.Time
+
+	SortBy     UserSortField
+	Descending bool
+	// Cursor is the opaque value returned with the previous page.
+	Cursor string
+	Limit  int
+}
+
+// UserExportFilter selects users to export. Zero values disable the corresponding filter.
+type UserExportFilter struct {
+	EmailPrefix string
+	Statuses    []UserStatus
+	// UpdatedSince exports only users changed at or after this time, for incremental dumps.
+	// Role and group changes don't touch users, so IsAdmin changes alone are not picked up.
+	UpdatedSince time.Time
+}
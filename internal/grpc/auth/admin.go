@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/admin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+func (s *ServerAPI) ActivateUser(ctx context.Context, req *ssov1.ActivateUserRequest) (*ssov1.ActivateUserResponse, error) {
+	if err := validateAdminUser(req.GetAdminToken(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.admin.ActivateUser(ctx, req.GetAdminToken(), req.GetUserId()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.ActivateUserResponse{}, nil
+}
+
+func (s *ServerAPI) SuspendUser(ctx context.Context, req *ssov1.SuspendUserRequest) (*ssov1.SuspendUserResponse, error) {
+	if err := validateAdminUser(req.GetAdminToken(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	var until time.Time
+	if req.GetUntil() != 0 {
+		until = time.Unix(req.GetUntil(), 0)
+		if !until.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "until must be in the future")
+		}
+	}
+
+	if err := s.admin.SuspendUser(ctx, req.GetAdminToken(), req.GetUserId(), req.GetReason(), until); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.SuspendUserResponse{}, nil
+}
+
+func (s *ServerAPI) DeactivateUser(ctx context.Context, req *ssov1.DeactivateUserRequest) (*ssov1.DeactivateUserResponse, error) {
+	if err := validateAdminUser(req.GetAdminToken(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.admin.DeactivateUser(ctx, req.GetAdminToken(), req.GetUserId(), req.GetReason()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.DeactivateUserResponse{}, nil
+}
+
+func (s *ServerAPI) ListUsers(ctx context.Context, req *ssov1.ListUsersRequest) (*ssov1.ListUsersResponse, error) {
+	if req.GetAdminToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	filter, err := userFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	users, next, err := s.admin.ListUsers(ctx, req.GetAdminToken(), filter)
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	resp := &ssov1.ListUsersResponse{
+		Users:      make([]*ssov1.User, 0, len(users)),
+		NextCursor: next,
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, &ssov1.User{
+			UserId:         user.ID.String(),
+			Email:          user.Email,
+			Username:       user.Username,
+			Phone:          user.Phone,
+			Status:         string(user.Status),
+			StatusReason:   user.StatusReason,
+			SuspendedUntil: unixOrZero(user.SuspendedUntil),
+			IsAdmin:        user.IsAdmin,
+			CreatedAt:      unixOrZero(user.CreatedAt),
+			LastLoginAt:    unixOrZero(user.LastLoginAt),
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) SetUserAttributes(ctx context.Context, req *ssov1.SetUserAttributesRequest) (*ssov1.SetUserAttributesResponse, error) {
+	if err := validateAdminUser(req.GetAdminToken(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.admin.SetUserAttributes(ctx, req.GetAdminToken(), req.GetUserId(), req.GetAttributes().AsMap()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.SetUserAttributesResponse{}, nil
+}
+
+// userFilter converts ListUsersRequest to the filter of the admin service.
+func userFilter(req *ssov1.ListUsersRequest) (models.UserFilter, error) {
+	filter := models.UserFilter{
+		EmailPrefix:   req.GetEmailPrefix(),
+		Role:          req.GetRole(),
+		CreatedFrom:   timeOrZero(req.GetCreatedFrom()),
+		CreatedTo:     timeOrZero(req.GetCreatedTo()),
+		LastLoginFrom: timeOrZero(req.GetLastLoginFrom()),
+		LastLoginTo:   timeOrZero(req.GetLastLoginTo()),
+		SortBy:        models.UserSortField(req.GetSortBy()),
+		Descending:    req.GetDescending(),
+		Cursor:        req.GetCursor(),
+		Limit:         int(req.GetLimit()),
+	}
+
+	for _, st := range req.GetStatuses() {
+		filter.Statuses = append(filter.Statuses, models.UserStatus(st))
+	}
+
+	if req.GetAppId() != "" {
+		appID, err := uuid.Parse(req.GetAppId())
+		if err != nil {
+			return models.UserFilter{}, status.Error(codes.InvalidArgument, "invalid app_id")
+		}
+		filter.AppID = appID
+	}
+
+	return filter, nil
+}
+
+// timeOrZero converts Unix time of a request to time.Time, keeping 0 as the zero time.
+func timeOrZero(unix int64) time.Time {
+	if unix == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(unix, 0)
+}
+
+// unixOrZero converts time.Time to Unix time of a response, keeping the zero time as 0.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+// adminError maps errors of the admin service to gRPC statuses.
+func adminError(err error) error {
+	switch {
+	case errors.Is(err, admin.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, admin.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, admin.ErrInvalidTransition):
+		return status.Error(codes.FailedPrecondition, "invalid status transition")
+	case errors.Is(err, admin.ErrStatusConflict):
+		return status.Error(codes.Aborted, "user status changed concurrently")
+	case errors.Is(err, admin.ErrInvalidFilter):
+		return status.Error(codes.InvalidArgument, "invalid filter")
+	case errors.Is(err, admin.ErrInvalidPolicy):
+		return status.Error(codes.InvalidArgument, "invalid mfa policy")
+	case errors.Is(err, admin.ErrAppNotFound):
+		return status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, admin.ErrInvalidRP):
+		return status.Error(codes.InvalidArgument, "invalid webauthn relying party")
+	case errors.Is(err, admin.ErrInvalidAttributes):
+		return status.Error(codes.InvalidArgument, "invalid user attributes")
+	case errors.Is(err, admin.ErrAppExists):
+		return status.Error(codes.AlreadyExists, "app already exists")
+	case errors.Is(err, admin.ErrInvalidApp):
+		return status.Error(codes.InvalidArgument, "invalid app")
+	case errors.Is(err, admin.ErrAppOwnerNotFound):
+		return status.Error(codes.NotFound, "app owner not found")
+	case errors.Is(err, admin.ErrInvalidScopes):
+		return status.Error(codes.InvalidArgument, "invalid client scopes")
+	case errors.Is(err, admin.ErrClientSecretNotFound):
+		return status.Error(codes.NotFound, "client secret not found")
+	case errors.Is(err, admin.ErrInvalidSecretTTL):
+		return status.Error(codes.InvalidArgument, "invalid client secret ttl")
+	case errors.Is(err, admin.ErrInvalidRedirectURIs):
+		return status.Error(codes.InvalidArgument, "invalid redirect uris")
+	case errors.Is(err, admin.ErrServiceAccountExists):
+		return status.Error(codes.AlreadyExists, "service account already exists")
+	case errors.Is(err, admin.ErrServiceAccountNotFound):
+		return status.Error(codes.NotFound, "service account not found")
+	case errors.Is(err, admin.ErrInvalidServiceAccount):
+		return status.Error(codes.InvalidArgument, "invalid service account")
+	case errors.Is(err, admin.ErrInvalidPublicKey):
+		return status.Error(codes.InvalidArgument, "invalid public key")
+	case errors.Is(err, admin.ErrInvalidKeyTTL):
+		return status.Error(codes.InvalidArgument, "invalid key ttl")
+	case errors.Is(err, admin.ErrServiceAccountKeyNotFound):
+		return status.Error(codes.NotFound, "service account key not found")
+	case errors.Is(err, admin.ErrInvalidAccess):
+		return status.Error(codes.InvalidArgument, "invalid app access")
+	case errors.Is(err, admin.ErrAppMemberExists):
+		return status.Error(codes.AlreadyExists, "user is already a member of the app")
+	case errors.Is(err, admin.ErrAppMemberNotFound):
+		return status.Error(codes.NotFound, "app member not found")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func validateAdminUser(adminToken string, userID string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if userID == "" {
+		return status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeAdmin records arguments of the last call and fails every call with err.
+// Methods a test doesn't override panic through the nil embedded Admin.
+type fakeAdmin struct {
+	Admin
+	err    error
+	userID string
+	reason string
+	until  time.Time
+	filter models.UserFilter
+	users  []models.User
+
+	attributes map[string]any
+	app        string
+	update     models.AppUpdate
+	ttl        time.Duration
+	account    string
+}
+
+func (f *fakeAdmin) ActivateUser(_ context.Context, _ string, userID string) error {
+	f.userID = userID
+
+	return f.err
+}
+
+func (f *fakeAdmin) SuspendUser(_ context.Context, _ string, userID string, reason string, until time.Time) error {
+	f.userID, f.reason, f.until = userID, reason, until
+
+	return f.err
+}
+
+func (f *fakeAdmin) DeactivateUser(_ context.Context, _ string, userID string, reason string) error {
+	f.userID, f.reason = userID, reason
+
+	return f.err
+}
+
+func (f *fakeAdmin) ListUsers(_ context.Context, _ string, filter models.UserFilter) ([]models.User, string, error) {
+	f.filter = filter
+
+	return f.users, "next", f.err
+}
+
+func TestSuspendUser(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	_, err := srv.SuspendUser(context.Background(), &ssov1.SuspendUserRequest{
+		AdminToken: "token",
+		UserId:     "user",
+		Reason:     "spam",
+		Until:      until.Unix(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user", fake.userID)
+	assert.Equal(t, "spam", fake.reason)
+	assert.True(t, until.Equal(fake.until))
+
+	// Zero until suspends without an end date.
+	_, err = srv.SuspendUser(context.Background(), &ssov1.SuspendUserRequest{AdminToken: "token", UserId: "user"})
+	require.NoError(t, err)
+	assert.True(t, fake.until.IsZero())
+
+	_, err = srv.SuspendUser(context.Background(), &ssov1.SuspendUserRequest{
+		AdminToken: "token",
+		UserId:     "user",
+		Until:      time.Now().Add(-time.Hour).Unix(),
+	})
+	assertCode(t, codes.InvalidArgument, err)
+}
+
+func TestActivateUser_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.ActivateUserRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.ActivateUserRequest{UserId: "user"}, want: codes.InvalidArgument},
+		{name: "no user", req: &ssov1.ActivateUserRequest{AdminToken: "token"}, want: codes.InvalidArgument},
+		{name: "not admin", err: admin.ErrPermissionDenied, want: codes.PermissionDenied},
+		{name: "user not found", err: admin.ErrUserNotFound, want: codes.NotFound},
+		{name: "invalid transition", err: admin.ErrInvalidTransition, want: codes.FailedPrecondition},
+		{name: "conflict", err: admin.ErrStatusConflict, want: codes.Aborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.ActivateUserRequest{AdminToken: "token", UserId: "user"}
+			}
+
+			srv := &ServerAPI{admin: &fakeAdmin{err: tt.err}}
+
+			_, err := srv.ActivateUser(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestDeactivateUser(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	_, err := srv.DeactivateUser(context.Background(), &ssov1.DeactivateUserRequest{AdminToken: "token", UserId: "user", Reason: "left"})
+	require.NoError(t, err)
+	assert.Equal(t, "user", fake.userID)
+	assert.Equal(t, "left", fake.reason)
+}
+
+func TestListUsers(t *testing.T) {
+	user := models.User{
+		ID:        uuid.New(),
+		Email:     "alice@example.com",
+		Status:    models.UserStatusActive,
+		CreatedAt: time.Unix(1700000000, 0),
+	}
+	fake := &fakeAdmin{users: []models.User{user}}
+	srv := &ServerAPI{admin: fake}
+
+	appID := uuid.New()
+
+	resp, err := srv.ListUsers(context.Background(), &ssov1.ListUsersRequest{
+		AdminToken:    "token",
+		EmailPrefix:   "ali",
+		Statuses:      []string{"active", "pending"},
+		Role:          "support",
+		AppId:         appID.String(),
+		LastLoginFrom: 1690000000,
+		SortBy:        "email",
+		Descending:    true,
+		Limit:         10,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, models.UserFilter{
+		EmailPrefix:   "ali",
+		Statuses:      []models.UserStatus{models.UserStatusActive, models.UserStatusPending},
+		Role:          "support",
+		AppID:         appID,
+		LastLoginFrom: time.Unix(1690000000, 0),
+		SortBy:        models.UserSortEmail,
+		Descending:    true,
+		Limit:         10,
+	}, fake.filter)
+
+	assert.Equal(t, "next", resp.GetNextCursor())
+	require.Len(t, resp.GetUsers(), 1)
+	assert.Equal(t, user.ID.String(), resp.GetUsers()[0].GetUserId())
+	assert.Equal(t, "active", resp.GetUsers()[0].GetStatus())
+	assert.Equal(t, int64(1700000000), resp.GetUsers()[0].GetCreatedAt())
+	assert.Zero(t, resp.GetUsers()[0].GetLastLoginAt())
+}
+
+func TestListUsers_Errors(t *testing.T) {
+	srv := &ServerAPI{admin: &fakeAdmin{}}
+
+	_, err := srv.ListUsers(context.Background(), &ssov1.ListUsersRequest{})
+	assertCode(t, codes.InvalidArgument, err)
+
+	_, err = srv.ListUsers(context.Background(), &ssov1.ListUsersRequest{AdminToken: "token", AppId: "app"})
+	assertCode(t, codes.InvalidArgument, err)
+
+	srv = &ServerAPI{admin: &fakeAdmin{err: admin.ErrInvalidFilter}}
+
+	_, err = srv.ListUsers(context.Background(), &ssov1.ListUsersRequest{AdminToken: "token"})
+	assertCode(t, codes.InvalidArgument, err)
+}
+
+func (f *fakeAdmin) SetUserAttributes(_ context.Context, _ string, userID string, attributes map[string]any) error {
+	f.userID, f.attributes = userID, attributes
+
+	return f.err
+}
+
+func TestSetUserAttributes(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	attributes, err := structpb.NewStruct(map[string]any{"region": "eu", "level": 3})
+	require.NoError(t, err)
+
+	_, err = srv.SetUserAttributes(context.Background(), &ssov1.SetUserAttributesRequest{
+		AdminToken: "token",
+		UserId:     "user",
+		Attributes: attributes,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user", fake.userID)
+	assert.Equal(t, map[string]any{"region": "eu", "level": float64(3)}, fake.attributes)
+
+	srv = &ServerAPI{admin: &fakeAdmin{err: admin.ErrInvalidAttributes}}
+	_, err = srv.SetUserAttributes(context.Background(), &ssov1.SetUserAttributesRequest{AdminToken: "token", UserId: "user"})
+	assertCode(t, codes.InvalidArgument, err)
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ServerAPI) AddAppMember(ctx context.Context, req *ssov1.AddAppMemberRequest) (*ssov1.AddAppMemberResponse, error) {
+	if err := validateAdminAppMember(req.GetAdminToken(), req.GetApp(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.admin.AddAppMember(ctx, req.GetAdminToken(), req.GetApp(), req.GetUserId()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.AddAppMemberResponse{}, nil
+}
+
+func (s *ServerAPI) RemoveAppMember(ctx context.Context, req *ssov1.RemoveAppMemberRequest) (*ssov1.RemoveAppMemberResponse, error) {
+	if err := validateAdminAppMember(req.GetAdminToken(), req.GetApp(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.admin.RemoveAppMember(ctx, req.GetAdminToken(), req.GetApp(), req.GetUserId()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.RemoveAppMemberResponse{}, nil
+}
+
+func (s *ServerAPI) ListAppMembers(ctx context.Context, req *ssov1.ListAppMembersRequest) (*ssov1.ListAppMembersResponse, error) {
+	if err := validateAdminApp(req.GetAdminToken(), req.GetApp()); err != nil {
+		return nil, err
+	}
+
+	members, err := s.admin.ListAppMembers(ctx, req.GetAdminToken(), req.GetApp())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	resp := &ssov1.ListAppMembersResponse{Members: make([]*ssov1.AppMember, 0, len(members))}
+	for _, member := range members {
+		resp.Members = append(resp.Members, appMemberToProto(member))
+	}
+
+	return resp, nil
+}
+
+func appMemberToProto(member models.AppMember) *ssov1.AppMember {
+	return &ssov1.AppMember{
+		UserId:    member.UserID.String(),
+		Email:     member.Email,
+		AddedBy:   idOrEmpty(member.AddedBy),
+		CreatedAt: unixOrZero(member.CreatedAt),
+	}
+}
+
+func validateAdminAppMember(adminToken string, app string, userID string) error {
+	if err := validateAdminApp(adminToken, app); err != nil {
+		return err
+	}
+
+	if userID == "" {
+		return status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	return nil
+}
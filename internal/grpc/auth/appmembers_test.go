@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func (f *fakeAdmin) AddAppMember(_ context.Context, _ string, app string, userID string) error {
+	f.app, f.userID = app, userID
+
+	return f.err
+}
+
+func (f *fakeAdmin) RemoveAppMember(_ context.Context, _ string, app string, userID string) error {
+	f.app, f.userID = app, userID
+
+	return f.err
+}
+
+func (f *fakeAdmin) ListAppMembers(_ context.Context, _ string, app string) ([]models.AppMember, error) {
+	f.app = app
+
+	return []models.AppMember{{
+		AppID:     uuid.New(),
+		UserID:    uuid.New(),
+		Email:     "alice@example.com",
+		CreatedAt: time.Unix(100, 0),
+	}}, f.err
+}
+
+func TestAddAppMember(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	_, err := srv.AddAppMember(context.Background(), &ssov1.AddAppMemberRequest{AdminToken: "token", App: "coin-keeper", UserId: "user"})
+	require.NoError(t, err)
+	assert.Equal(t, "coin-keeper", fake.app)
+	assert.Equal(t, "user", fake.userID)
+}
+
+func TestAddAppMember_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.AddAppMemberRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.AddAppMemberRequest{App: "coin-keeper", UserId: "user"}, want: codes.InvalidArgument},
+		{name: "no app", req: &ssov1.AddAppMemberRequest{AdminToken: "token", UserId: "user"}, want: codes.InvalidArgument},
+		{name: "no user", req: &ssov1.AddAppMemberRequest{AdminToken: "token", App: "coin-keeper"}, want: codes.InvalidArgument},
+		{name: "not admin", err: admin.ErrPermissionDenied, want: codes.PermissionDenied},
+		{name: "unknown app", err: admin.ErrAppNotFound, want: codes.NotFound},
+		{name: "unknown user", err: admin.ErrUserNotFound, want: codes.NotFound},
+		{name: "already member", err: admin.ErrAppMemberExists, want: codes.AlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.AddAppMemberRequest{AdminToken: "token", App: "coin-keeper", UserId: "user"}
+			}
+
+			srv := &ServerAPI{admin: &fakeAdmin{err: tt.err}}
+
+			_, err := srv.AddAppMember(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestRemoveAppMember(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	_, err := srv.RemoveAppMember(context.Background(), &ssov1.RemoveAppMemberRequest{AdminToken: "token", App: "coin-keeper", UserId: "user"})
+	require.NoError(t, err)
+	assert.Equal(t, "coin-keeper", fake.app)
+	assert.Equal(t, "user", fake.userID)
+
+	_, err = srv.RemoveAppMember(context.Background(), &ssov1.RemoveAppMemberRequest{AdminToken: "token", App: "coin-keeper"})
+	assertCode(t, codes.InvalidArgument, err)
+
+	srv = &ServerAPI{admin: &fakeAdmin{err: admin.ErrAppMemberNotFound}}
+	_, err = srv.RemoveAppMember(context.Background(), &ssov1.RemoveAppMemberRequest{AdminToken: "token", App: "coin-keeper", UserId: "user"})
+	assertCode(t, codes.NotFound, err)
+}
+
+func TestListAppMembers(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	resp, err := srv.ListAppMembers(context.Background(), &ssov1.ListAppMembersRequest{AdminToken: "token", App: "coin-keeper"})
+	require.NoError(t, err)
+	assert.Equal(t, "coin-keeper", fake.app)
+	require.Len(t, resp.GetMembers(), 1)
+
+	member := resp.GetMembers()[0]
+	assert.Equal(t, "alice@example.com", member.GetEmail())
+	assert.Empty(t, member.GetAddedBy(), "deleted admins are left empty")
+	assert.Equal(t, int64(100), member.GetCreatedAt())
+
+	_, err = srv.ListAppMembers(context.Background(), &ssov1.ListAppMembersRequest{AdminToken: "token"})
+	assertCode(t, codes.InvalidArgument, err)
+}
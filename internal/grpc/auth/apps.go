@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ServerAPI) CreateApp(ctx context.Context, req *ssov1.CreateAppRequest) (*ssov1.AppResponse, error) {
+	if err := validateAdminTokenAndName(req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	app, err := s.admin.CreateApp(ctx, req.GetAdminToken(), req.GetName(), req.GetDescription(), req.GetOwnerId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.AppResponse{App: appToProto(app)}, nil
+}
+
+func (s *ServerAPI) GetApp(ctx context.Context, req *ssov1.GetAppRequest) (*ssov1.AppResponse, error) {
+	if err := validateAdminApp(req.GetAdminToken(), req.GetApp()); err != nil {
+		return nil, err
+	}
+
+	app, err := s.admin.GetApp(ctx, req.GetAdminToken(), req.GetApp())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.AppResponse{App: appToProto(app)}, nil
+}
+
+func (s *ServerAPI) ListApps(ctx context.Context, req *ssov1.ListAppsRequest) (*ssov1.ListAppsResponse, error) {
+	if req.GetAdminToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	apps, err := s.admin.ListApps(ctx, req.GetAdminToken())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	resp := &ssov1.ListAppsResponse{Apps: make([]*ssov1.App, 0, len(apps))}
+	for _, app := range apps {
+		resp.Apps = append(resp.Apps, appToProto(app))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) UpdateApp(ctx context.Context, req *ssov1.UpdateAppRequest) (*ssov1.AppResponse, error) {
+	if err := validateAdminApp(req.GetAdminToken(), req.GetApp()); err != nil {
+		return nil, err
+	}
+
+	update, err := appUpdate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := s.admin.UpdateApp(ctx, req.GetAdminToken(), req.GetApp(), update)
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.AppResponse{App: appToProto(app)}, nil
+}
+
+func (s *ServerAPI) DeleteApp(ctx context.Context, req *ssov1.DeleteAppRequest) (*ssov1.DeleteAppResponse, error) {
+	if err := validateAdminApp(req.GetAdminToken(), req.GetApp()); err != nil {
+		return nil, err
+	}
+
+	if err := s.admin.DeleteApp(ctx, req.GetAdminToken(), req.GetApp()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.DeleteAppResponse{}, nil
+}
+
+// appUpdate converts UpdateAppRequest to the update of the admin service, leaving unset fields nil.
+func appUpdate(req *ssov1.UpdateAppRequest) (models.AppUpdate, error) {
+	update := models.AppUpdate{
+		Name:        req.Name,
+		Description: req.Description,
+		Enabled:     req.Enabled,
+	}
+
+	if req.OwnerId != nil {
+		ownerID := uuid.Nil
+		if req.GetOwnerId() != "" {
+			var err error
+			if ownerID, err = uuid.Parse(req.GetOwnerId()); err != nil {
+				return models.AppUpdate{}, status.Error(codes.InvalidArgument, "invalid owner_id")
+			}
+		}
+		update.OwnerID = &ownerID
+	}
+
+	if req.ClientScopes != nil {
+		scopes := req.GetClientScopes().GetValues()
+		update.ClientScopes = &scopes
+	}
+
+	if req.RedirectUris != nil {
+		uris := req.GetRedirectUris().GetValues()
+		update.RedirectURIs = &uris
+	}
+
+	if req.PostLogoutRedirectUris != nil {
+		uris := req.GetPostLogoutRedirectUris().GetValues()
+		update.PostLogoutRedirectURIs = &uris
+	}
+
+	if req.AccessMode != nil {
+		mode := models.AppAccessMode(req.GetAccessMode())
+		update.AccessMode = &mode
+	}
+
+	if req.AllowedEmailDomains != nil {
+		domains := req.GetAllowedEmailDomains().GetValues()
+		update.AllowedEmailDomains = &domains
+	}
+
+	return update, nil
+}
+
+func appToProto(app models.App) *ssov1.App {
+	return &ssov1.App{
+		AppId:                  app.ID.String(),
+		Name:                   app.Name,
+		Description:            app.Description,
+		OwnerId:                idOrEmpty(app.OwnerID),
+		Enabled:                app.Enabled,
+		CreatedAt:              unixOrZero(app.CreatedAt),
+		MfaPolicy:              string(app.MFAPolicy),
+		WebauthnRpId:           app.WebAuthnRPID,
+		WebauthnOrigins:        app.WebAuthnOrigins,
+		ClientScopes:           app.ClientScopes,
+		RedirectUris:           app.RedirectURIs,
+		PostLogoutRedirectUris: app.PostLogoutRedirectURIs,
+		AccessMode:             string(app.AccessMode),
+		AllowedEmailDomains:    app.AllowedEmailDomains,
+	}
+}
+
+func validateAdminApp(adminToken string, app string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if app == "" {
+		return status.Error(codes.InvalidArgument, "app required")
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+func (f *fakeAdmin) CreateApp(_ context.Context, _ string, name string, description string, ownerID string) (models.App, error) {
+	f.app, f.userID = name, ownerID
+
+	return models.App{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: description,
+		Enabled:     true,
+		CreatedAt:   time.Unix(100, 0),
+	}, f.err
+}
+
+func (f *fakeAdmin) UpdateApp(_ context.Context, _ string, app string, update models.AppUpdate) (models.App, error) {
+	f.app, f.update = app, update
+
+	updated := models.App{ID: uuid.New(), Name: app}
+	if update.AccessMode != nil {
+		updated.AccessMode = *update.AccessMode
+	}
+	if update.AllowedEmailDomains != nil {
+		updated.AllowedEmailDomains = *update.AllowedEmailDomains
+	}
+
+	return updated, f.err
+}
+
+func (f *fakeAdmin) DeleteApp(_ context.Context, _ string, app string) error {
+	f.app = app
+
+	return f.err
+}
+
+func TestCreateApp(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	resp, err := srv.CreateApp(context.Background(), &ssov1.CreateAppRequest{
+		AdminToken:  "token",
+		Name:        "coin-keeper",
+		Description: "Budgets",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "coin-keeper", fake.app)
+	assert.Empty(t, fake.userID, "owner is optional")
+
+	app := resp.GetApp()
+	assert.Equal(t, "coin-keeper", app.GetName())
+	assert.Equal(t, "Budgets", app.GetDescription())
+	assert.Empty(t, app.GetOwnerId())
+	assert.True(t, app.GetEnabled())
+	assert.Equal(t, int64(100), app.GetCreatedAt())
+}
+
+func TestCreateApp_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.CreateAppRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.CreateAppRequest{Name: "coin-keeper"}, want: codes.InvalidArgument},
+		{name: "no name", req: &ssov1.CreateAppRequest{AdminToken: "token"}, want: codes.InvalidArgument},
+		{name: "not admin", err: admin.ErrPermissionDenied, want: codes.PermissionDenied},
+		{name: "invalid name", err: admin.ErrInvalidApp, want: codes.InvalidArgument},
+		{name: "taken", err: admin.ErrAppExists, want: codes.AlreadyExists},
+		{name: "unknown owner", err: admin.ErrAppOwnerNotFound, want: codes.NotFound},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.CreateAppRequest{AdminToken: "token", Name: "coin-keeper"}
+			}
+
+			srv := &ServerAPI{admin: &fakeAdmin{err: tt.err}}
+
+			_, err := srv.CreateApp(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestUpdateApp(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	_, err := srv.UpdateApp(context.Background(), &ssov1.UpdateAppRequest{
+		AdminToken: "token",
+		App:        "coin-keeper",
+		Enabled:    proto.Bool(false),
+		OwnerId:    proto.String(""),
+		RedirectUris: &ssov1.StringList{
+			Values: []string{"https://coin-keeper.example.com/callback"},
+		},
+		PostLogoutRedirectUris: &ssov1.StringList{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "coin-keeper", fake.app)
+
+	assert.Nil(t, fake.update.Name, "unset fields stay unchanged")
+	assert.Nil(t, fake.update.Description)
+	require.NotNil(t, fake.update.Enabled)
+	assert.False(t, *fake.update.Enabled)
+	require.NotNil(t, fake.update.OwnerID)
+	assert.Equal(t, uuid.Nil, *fake.update.OwnerID, "empty owner clears it")
+	require.NotNil(t, fake.update.RedirectURIs)
+	assert.Equal(t, []string{"https://coin-keeper.example.com/callback"}, *fake.update.RedirectURIs)
+	require.NotNil(t, fake.update.PostLogoutRedirectURIs, "an empty list clears the uris")
+	assert.Empty(t, *fake.update.PostLogoutRedirectURIs)
+	assert.Nil(t, fake.update.AccessMode)
+	assert.Nil(t, fake.update.AllowedEmailDomains)
+}
+
+func TestUpdateApp_Access(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	resp, err := srv.UpdateApp(context.Background(), &ssov1.UpdateAppRequest{
+		AdminToken:          "token",
+		App:                 "coin-keeper",
+		AccessMode:          proto.String("email_domain"),
+		AllowedEmailDomains: &ssov1.StringList{Values: []string{"example.com"}},
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, fake.update.AccessMode)
+	assert.Equal(t, models.AppAccessEmailDomain, *fake.update.AccessMode)
+	require.NotNil(t, fake.update.AllowedEmailDomains)
+	assert.Equal(t, []string{"example.com"}, *fake.update.AllowedEmailDomains)
+
+	assert.Equal(t, "email_domain", resp.GetApp().GetAccessMode())
+	assert.Equal(t, []string{"example.com"}, resp.GetApp().GetAllowedEmailDomains())
+}
+
+func TestUpdateApp_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.UpdateAppRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.UpdateAppRequest{App: "coin-keeper"}, want: codes.InvalidArgument},
+		{name: "no app", req: &ssov1.UpdateAppRequest{AdminToken: "token"}, want: codes.InvalidArgument},
+		{
+			name: "malformed owner",
+			req:  &ssov1.UpdateAppRequest{AdminToken: "token", App: "coin-keeper", OwnerId: proto.String("owner")},
+			want: codes.InvalidArgument,
+		},
+		{name: "unknown app", err: admin.ErrAppNotFound, want: codes.NotFound},
+		{name: "taken", err: admin.ErrAppExists, want: codes.AlreadyExists},
+		{name: "bad redirect uris", err: admin.ErrInvalidRedirectURIs, want: codes.InvalidArgument},
+		{name: "bad access", err: admin.ErrInvalidAccess, want: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.UpdateAppRequest{AdminToken: "token", App: "coin-keeper"}
+			}
+
+			srv := &ServerAPI{admin: &fakeAdmin{err: tt.err}}
+
+			_, err := srv.UpdateApp(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestDeleteApp(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	_, err := srv.DeleteApp(context.Background(), &ssov1.DeleteAppRequest{AdminToken: "token", App: "coin-keeper"})
+	require.NoError(t, err)
+	assert.Equal(t, "coin-keeper", fake.app)
+
+	srv = &ServerAPI{admin: &fakeAdmin{err: admin.ErrAppNotFound}}
+	_, err = srv.DeleteApp(context.Background(), &ssov1.DeleteAppRequest{AdminToken: "token", App: "coin-keeper"})
+	assertCode(t, codes.NotFound, err)
+}
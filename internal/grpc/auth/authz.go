@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/services/authz"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ServerAPI) Authorize(ctx context.Context, req *ssov1.AuthorizeRequest) (*ssov1.AuthorizeResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	if req.GetAppName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "app_name required")
+	}
+
+	if req.GetAction() == "" {
+		return nil, status.Error(codes.InvalidArgument, "action required")
+	}
+
+	decision, err := s.authz.Authorize(
+		ctx,
+		req.GetUserId(),
+		req.GetAppName(),
+		req.GetAction(),
+		req.GetResource().AsMap(),
+		req.GetContext().AsMap(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, authz.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, authz.ErrAppNotFound):
+			return nil, status.Error(codes.NotFound, "app not found")
+		case errors.Is(err, authz.ErrInvalidInput):
+			return nil, status.Error(codes.InvalidArgument, "invalid authorization request")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &ssov1.AuthorizeResponse{
+		Allowed: decision.Allowed,
+		Rule:    decision.Rule,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeAuthz records the resource and context of the last call and fails every call with err.
+type fakeAuthz struct {
+	err        error
+	decision   models.AuthzDecision
+	resource   map[string]any
+	reqContext map[string]any
+}
+
+func (f *fakeAuthz) Authorize(
+	_ context.Context,
+	_ string,
+	_ string,
+	_ string,
+	resource map[string]any,
+	reqContext map[string]any,
+) (models.AuthzDecision, error) {
+	f.resource, f.reqContext = resource, reqContext
+
+	return f.decision, f.err
+}
+
+func TestAuthorize(t *testing.T) {
+	fake := &fakeAuthz{decision: models.AuthzDecision{Allowed: true, Rule: "admins"}}
+	srv := &ServerAPI{authz: fake}
+
+	resource, err := structpb.NewStruct(map[string]any{"region": "eu", "tags": []any{"a", "b"}})
+	require.NoError(t, err)
+
+	resp, err := srv.Authorize(context.Background(), &ssov1.AuthorizeRequest{
+		UserId:   "user",
+		AppName:  "app",
+		Action:   "users:read",
+		Resource: resource,
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.GetAllowed())
+	assert.Equal(t, "admins", resp.GetRule())
+
+	assert.Equal(t, map[string]any{"region": "eu", "tags": []any{"a", "b"}}, fake.resource)
+	assert.Equal(t, map[string]any{}, fake.reqContext, "missing context is passed empty")
+}
+
+func TestAuthorize_Errors(t *testing.T) {
+	valid := &ssov1.AuthorizeRequest{UserId: "user", AppName: "app", Action: "users:read"}
+
+	tests := []struct {
+		name string
+		req  *ssov1.AuthorizeRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no user", req: &ssov1.AuthorizeRequest{AppName: "app", Action: "users:read"}, want: codes.InvalidArgument},
+		{name: "no app", req: &ssov1.AuthorizeRequest{UserId: "user", Action: "users:read"}, want: codes.InvalidArgument},
+		{name: "no action", req: &ssov1.AuthorizeRequest{UserId: "user", AppName: "app"}, want: codes.InvalidArgument},
+		{name: "unknown user", req: valid, err: authz.ErrUserNotFound, want: codes.NotFound},
+		{name: "unknown app", req: valid, err: authz.ErrAppNotFound, want: codes.NotFound},
+		{name: "storage failure", req: valid, err: errors.New("boom"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &ServerAPI{authz: &fakeAuthz{err: tt.err}}
+
+			_, err := srv.Authorize(context.Background(), tt.req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
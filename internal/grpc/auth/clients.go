@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+func (s *ServerAPI) ClientCredentials(ctx context.Context, req *ssov1.ClientCredentialsRequest) (*ssov1.ClientCredentialsResponse, error) {
+	if req.GetClientId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "client_id required")
+	}
+
+	if req.GetClientSecret() == "" {
+		return nil, status.Error(codes.InvalidArgument, "client_secret required")
+	}
+
+	token, err := s.clients.ClientCredentials(ctx, req.GetClientId(), req.GetClientSecret(), req.GetScopes())
+	if err != nil {
+		switch {
+		case errors.Is(err, client.ErrInvalidClient):
+			return nil, status.Error(codes.Unauthenticated, "invalid client")
+		case errors.Is(err, client.ErrInvalidScope):
+			return nil, status.Error(codes.InvalidArgument, "invalid scope")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &ssov1.ClientCredentialsResponse{
+		AccessToken: token.AccessToken,
+		ExpiresAt:   unixOrZero(token.ExpiresAt),
+		Scopes:      token.Scopes,
+	}, nil
+}
+
+func (s *ServerAPI) CreateClientSecret(ctx context.Context, req *ssov1.CreateClientSecretRequest) (*ssov1.CreateClientSecretResponse, error) {
+	if err := validateAdminApp(req.GetAdminToken(), req.GetApp()); err != nil {
+		return nil, err
+	}
+
+	meta, secret, err := s.admin.CreateClientSecret(
+		ctx,
+		req.GetAdminToken(),
+		req.GetApp(),
+		req.GetName(),
+		time.Duration(req.GetTtl())*time.Second,
+	)
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.CreateClientSecretResponse{
+		ClientSecret: clientSecretToProto(meta),
+		Secret:       secret,
+	}, nil
+}
+
+func (s *ServerAPI) ListClientSecrets(ctx context.Context, req *ssov1.ListClientSecretsRequest) (*ssov1.ListClientSecretsResponse, error) {
+	if err := validateAdminApp(req.GetAdminToken(), req.GetApp()); err != nil {
+		return nil, err
+	}
+
+	secrets, err := s.admin.ListClientSecrets(ctx, req.GetAdminToken(), req.GetApp())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	resp := &ssov1.ListClientSecretsResponse{ClientSecrets: make([]*ssov1.ClientSecret, 0, len(secrets))}
+	for _, secret := range secrets {
+		resp.ClientSecrets = append(resp.ClientSecrets, clientSecretToProto(secret))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) RevokeClientSecret(ctx context.Context, req *ssov1.RevokeClientSecretRequest) (*ssov1.RevokeClientSecretResponse, error) {
+	if err := validateAdminApp(req.GetAdminToken(), req.GetApp()); err != nil {
+		return nil, err
+	}
+
+	if req.GetSecretId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "secret_id required")
+	}
+
+	if err := s.admin.RevokeClientSecret(ctx, req.GetAdminToken(), req.GetApp(), req.GetSecretId()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.RevokeClientSecretResponse{}, nil
+}
+
+func clientSecretToProto(secret models.ClientSecret) *ssov1.ClientSecret {
+	return &ssov1.ClientSecret{
+		SecretId:   secret.ID.String(),
+		AppId:      secret.AppID.String(),
+		Name:       secret.Name,
+		ExpiresAt:  unixOrZero(secret.ExpiresAt),
+		CreatedAt:  unixOrZero(secret.CreatedAt),
+		LastUsedAt: unixOrZero(secret.LastUsedAt),
+	}
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/admin"
+	"github.com/sol1corejz/auth-service/internal/services/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeClients struct {
+	err    error
+	scopes []string
+}
+
+func (f *fakeClients) ClientCredentials(_ context.Context, _ string, _ string, scopes []string) (models.ClientToken, error) {
+	f.scopes = scopes
+
+	return models.ClientToken{AccessToken: "access", ExpiresAt: time.Unix(200, 0), Scopes: []string{"jobs:run"}}, f.err
+}
+
+func (f *fakeAdmin) CreateClientSecret(_ context.Context, _ string, app string, _ string, ttl time.Duration) (models.ClientSecret, string, error) {
+	f.app, f.ttl = app, ttl
+
+	return models.ClientSecret{ID: uuid.New(), AppID: uuid.New(), Name: "billing-job", CreatedAt: time.Unix(100, 0)}, "secret", f.err
+}
+
+func (f *fakeAdmin) RevokeClientSecret(_ context.Context, _ string, app string, _ string) error {
+	f.app = app
+
+	return f.err
+}
+
+func TestClientCredentials(t *testing.T) {
+	fake := &fakeClients{}
+	srv := &ServerAPI{clients: fake}
+
+	resp, err := srv.ClientCredentials(context.Background(), &ssov1.ClientCredentialsRequest{
+		ClientId:     uuid.NewString(),
+		ClientSecret: "secret",
+		Scopes:       []string{"jobs:run"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jobs:run"}, fake.scopes)
+
+	assert.Equal(t, "access", resp.GetAccessToken())
+	assert.Equal(t, int64(200), resp.GetExpiresAt())
+	assert.Equal(t, []string{"jobs:run"}, resp.GetScopes())
+}
+
+func TestClientCredentials_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.ClientCredentialsRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no client", req: &ssov1.ClientCredentialsRequest{ClientSecret: "secret"}, want: codes.InvalidArgument},
+		{name: "no secret", req: &ssov1.ClientCredentialsRequest{ClientId: "client"}, want: codes.InvalidArgument},
+		{name: "invalid client", err: client.ErrInvalidClient, want: codes.Unauthenticated},
+		{name: "invalid scope", err: client.ErrInvalidScope, want: codes.InvalidArgument},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.ClientCredentialsRequest{ClientId: "client", ClientSecret: "secret"}
+			}
+
+			srv := &ServerAPI{clients: &fakeClients{err: tt.err}}
+
+			_, err := srv.ClientCredentials(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestCreateClientSecret(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	resp, err := srv.CreateClientSecret(context.Background(), &ssov1.CreateClientSecretRequest{
+		AdminToken: "token",
+		App:        "coin-keeper",
+		Name:       "billing-job",
+		Ttl:        3600,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "coin-keeper", fake.app)
+	assert.Equal(t, time.Hour, fake.ttl)
+
+	assert.Equal(t, "secret", resp.GetSecret())
+	assert.Equal(t, "billing-job", resp.GetClientSecret().GetName())
+	assert.Equal(t, int64(100), resp.GetClientSecret().GetCreatedAt())
+	assert.Zero(t, resp.GetClientSecret().GetExpiresAt())
+	assert.Zero(t, resp.GetClientSecret().GetLastUsedAt())
+
+	srv = &ServerAPI{admin: &fakeAdmin{err: admin.ErrInvalidSecretTTL}}
+	_, err = srv.CreateClientSecret(context.Background(), &ssov1.CreateClientSecretRequest{AdminToken: "token", App: "coin-keeper", Ttl: -1})
+	assertCode(t, codes.InvalidArgument, err)
+}
+
+func TestRevokeClientSecret_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.RevokeClientSecretRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.RevokeClientSecretRequest{App: "coin-keeper", SecretId: "secret"}, want: codes.InvalidArgument},
+		{name: "no app", req: &ssov1.RevokeClientSecretRequest{AdminToken: "token", SecretId: "secret"}, want: codes.InvalidArgument},
+		{name: "no secret", req: &ssov1.RevokeClientSecretRequest{AdminToken: "token", App: "coin-keeper"}, want: codes.InvalidArgument},
+		{name: "not admin", err: admin.ErrPermissionDenied, want: codes.PermissionDenied},
+		{name: "unknown app", err: admin.ErrAppNotFound, want: codes.NotFound},
+		{name: "unknown secret", err: admin.ErrClientSecretNotFound, want: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.RevokeClientSecretRequest{AdminToken: "token", App: "coin-keeper", SecretId: "secret"}
+			}
+
+			srv := &ServerAPI{admin: &fakeAdmin{err: tt.err}}
+
+			_, err := srv.RevokeClientSecret(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestUpdateApp_ClientScopes(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	_, err := srv.UpdateApp(context.Background(), &ssov1.UpdateAppRequest{
+		AdminToken:   "token",
+		App:          "coin-keeper",
+		ClientScopes: &ssov1.StringList{},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, fake.update.ClientScopes, "empty list clears the scopes")
+	assert.Empty(t, *fake.update.ClientScopes)
+
+	_, err = srv.UpdateApp(context.Background(), &ssov1.UpdateAppRequest{AdminToken: "token", App: "coin-keeper", Name: proto.String("notes")})
+	require.NoError(t, err)
+	assert.Nil(t, fake.update.ClientScopes)
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ServerAPI) CreateGroup(ctx context.Context, req *ssov1.CreateGroupRequest) (*ssov1.CreateGroupResponse, error) {
+	if err := validateAdminTokenAndName(req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	group, err := s.rbac.CreateGroup(ctx, req.GetAdminToken(), req.GetName(), req.GetDescription())
+	if err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.CreateGroupResponse{
+		Group: groupToProto(group),
+	}, nil
+}
+
+func (s *ServerAPI) DeleteGroup(ctx context.Context, req *ssov1.DeleteGroupRequest) (*ssov1.DeleteGroupResponse, error) {
+	if err := validateAdminTokenAndName(req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.DeleteGroup(ctx, req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.DeleteGroupResponse{}, nil
+}
+
+func (s *ServerAPI) AddGroupMember(ctx context.Context, req *ssov1.AddGroupMemberRequest) (*ssov1.AddGroupMemberResponse, error) {
+	if err := validateGroupMember(req.GetAdminToken(), req.GetGroup(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.AddGroupMember(ctx, req.GetAdminToken(), req.GetGroup(), req.GetUserId()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.AddGroupMemberResponse{}, nil
+}
+
+func (s *ServerAPI) RemoveGroupMember(ctx context.Context, req *ssov1.RemoveGroupMemberRequest) (*ssov1.RemoveGroupMemberResponse, error) {
+	if err := validateGroupMember(req.GetAdminToken(), req.GetGroup(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.RemoveGroupMember(ctx, req.GetAdminToken(), req.GetGroup(), req.GetUserId()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.RemoveGroupMemberResponse{}, nil
+}
+
+func (s *ServerAPI) AddSubgroup(ctx context.Context, req *ssov1.AddSubgroupRequest) (*ssov1.AddSubgroupResponse, error) {
+	if err := validateSubgroup(req.GetAdminToken(), req.GetGroup(), req.GetSubgroup()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.AddSubgroup(ctx, req.GetAdminToken(), req.GetGroup(), req.GetSubgroup()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.AddSubgroupResponse{}, nil
+}
+
+func (s *ServerAPI) RemoveSubgroup(ctx context.Context, req *ssov1.RemoveSubgroupRequest) (*ssov1.RemoveSubgroupResponse, error) {
+	if err := validateSubgroup(req.GetAdminToken(), req.GetGroup(), req.GetSubgroup()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.RemoveSubgroup(ctx, req.GetAdminToken(), req.GetGroup(), req.GetSubgroup()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.RemoveSubgroupResponse{}, nil
+}
+
+func (s *ServerAPI) AssignGroupRole(ctx context.Context, req *ssov1.AssignGroupRoleRequest) (*ssov1.AssignGroupRoleResponse, error) {
+	if err := validateGroupRole(req.GetAdminToken(), req.GetGroup(), req.GetRole()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.AssignGroupRole(ctx, req.GetAdminToken(), req.GetGroup(), req.GetAppName(), req.GetRole()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.AssignGroupRoleResponse{}, nil
+}
+
+func (s *ServerAPI) UnassignGroupRole(ctx context.Context, req *ssov1.UnassignGroupRoleRequest) (*ssov1.UnassignGroupRoleResponse, error) {
+	if err := validateGroupRole(req.GetAdminToken(), req.GetGroup(), req.GetRole()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.UnassignGroupRole(ctx, req.GetAdminToken(), req.GetGroup(), req.GetAppName(), req.GetRole()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.UnassignGroupRoleResponse{}, nil
+}
+
+func (s *ServerAPI) UserGroups(ctx context.Context, req *ssov1.UserGroupsRequest) (*ssov1.UserGroupsResponse, error) {
+	if req.GetAdminToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	groups, err := s.rbac.UserGroups(ctx, req.GetAdminToken(), req.GetUserId())
+	if err != nil {
+		return nil, rbacError(err)
+	}
+
+	resp := &ssov1.UserGroupsResponse{
+		Groups: make([]*ssov1.Group, 0, len(groups)),
+	}
+	for _, group := range groups {
+		resp.Groups = append(resp.Groups, groupToProto(group))
+	}
+
+	return resp, nil
+}
+
+func groupToProto(group models.Group) *ssov1.Group {
+	return &ssov1.Group{
+		GroupId:     group.ID.String(),
+		Name:        group.Name,
+		Description: group.Description,
+		CreatedAt:   unixOrZero(group.CreatedAt),
+	}
+}
+
+func validateGroupMember(adminToken string, group string, userID string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if group == "" {
+		return status.Error(codes.InvalidArgument, "group required")
+	}
+
+	if userID == "" {
+		return status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	return nil
+}
+
+func validateSubgroup(adminToken string, group string, subgroup string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if group == "" {
+		return status.Error(codes.InvalidArgument, "group required")
+	}
+
+	if subgroup == "" {
+		return status.Error(codes.InvalidArgument, "subgroup required")
+	}
+
+	return nil
+}
+
+func validateGroupRole(adminToken string, group string, role string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if group == "" {
+		return status.Error(codes.InvalidArgument, "group required")
+	}
+
+	if role == "" {
+		return status.Error(codes.InvalidArgument, "role required")
+	}
+
+	return nil
+}
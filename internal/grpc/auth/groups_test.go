@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/rbac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func (f *fakeRBAC) AddSubgroup(_ context.Context, _ string, _ string, _ string) error {
+	return f.err
+}
+
+func (f *fakeRBAC) AssignGroupRole(_ context.Context, _ string, _ string, appName string, roleName string) error {
+	f.appName, f.role = appName, roleName
+
+	return f.err
+}
+
+func (f *fakeRBAC) UserGroups(_ context.Context, _ string, _ string) ([]models.Group, error) {
+	return []models.Group{
+		{ID: uuid.New(), Name: "billing-team", Description: "Billing", CreatedAt: time.Unix(100, 0)},
+	}, f.err
+}
+
+func TestAddSubgroup_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.AddSubgroupRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.AddSubgroupRequest{Group: "a", Subgroup: "b"}, want: codes.InvalidArgument},
+		{name: "no group", req: &ssov1.AddSubgroupRequest{AdminToken: "token", Subgroup: "b"}, want: codes.InvalidArgument},
+		{name: "no subgroup", req: &ssov1.AddSubgroupRequest{AdminToken: "token", Group: "a"}, want: codes.InvalidArgument},
+		{
+			name: "cycle",
+			req:  &ssov1.AddSubgroupRequest{AdminToken: "token", Group: "a", Subgroup: "b"},
+			err:  rbac.ErrGroupCycle,
+			want: codes.FailedPrecondition,
+		},
+		{
+			name: "unknown group",
+			req:  &ssov1.AddSubgroupRequest{AdminToken: "token", Group: "a", Subgroup: "b"},
+			err:  rbac.ErrGroupNotFound,
+			want: codes.NotFound,
+		},
+		{
+			name: "not admin",
+			req:  &ssov1.AddSubgroupRequest{AdminToken: "token", Group: "a", Subgroup: "b"},
+			err:  rbac.ErrPermissionDenied,
+			want: codes.PermissionDenied,
+		},
+		{
+			name: "storage failure",
+			req:  &ssov1.AddSubgroupRequest{AdminToken: "token", Group: "a", Subgroup: "b"},
+			err:  errors.New("boom"),
+			want: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &ServerAPI{rbac: &fakeRBAC{err: tt.err}}
+
+			_, err := srv.AddSubgroup(context.Background(), tt.req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestAssignGroupRole(t *testing.T) {
+	fake := &fakeRBAC{}
+	srv := &ServerAPI{rbac: fake}
+
+	_, err := srv.AssignGroupRole(context.Background(), &ssov1.AssignGroupRoleRequest{
+		AdminToken: "token",
+		Group:      "billing-team",
+		Role:       "viewer",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, fake.appName, "empty app assigns the role within all apps")
+	assert.Equal(t, "viewer", fake.role)
+
+	_, err = srv.AssignGroupRole(context.Background(), &ssov1.AssignGroupRoleRequest{AdminToken: "token", Role: "viewer"})
+	assertCode(t, codes.InvalidArgument, err)
+}
+
+func TestUserGroups(t *testing.T) {
+	srv := &ServerAPI{rbac: &fakeRBAC{}}
+
+	resp, err := srv.UserGroups(context.Background(), &ssov1.UserGroupsRequest{AdminToken: "token", UserId: uuid.NewString()})
+	require.NoError(t, err)
+
+	require.Len(t, resp.GetGroups(), 1)
+	group := resp.GetGroups()[0]
+	assert.Equal(t, "billing-team", group.GetName())
+	assert.Equal(t, "Billing", group.GetDescription())
+	assert.Equal(t, int64(100), group.GetCreatedAt())
+
+	_, err = srv.UserGroups(context.Background(), &ssov1.UserGroupsRequest{AdminToken: "token"})
+	assertCode(t, codes.InvalidArgument, err)
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/mfa"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ServerAPI) BeginTOTPEnrollment(ctx context.Context, req *ssov1.BeginTOTPEnrollmentRequest) (*ssov1.BeginTOTPEnrollmentResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	secret, uri, err := s.mfa.BeginTOTPEnrollment(ctx, req.GetAccessToken())
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.BeginTOTPEnrollmentResponse{
+		Secret: secret,
+		Uri:    uri,
+	}, nil
+}
+
+func (s *ServerAPI) BeginChallengeTOTPEnrollment(
+	ctx context.Context,
+	req *ssov1.BeginChallengeTOTPEnrollmentRequest,
+) (*ssov1.BeginTOTPEnrollmentResponse, error) {
+	if req.GetChallengeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "challenge_id required")
+	}
+
+	secret, uri, err := s.mfa.BeginChallengeTOTPEnrollment(ctx, req.GetChallengeId())
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.BeginTOTPEnrollmentResponse{
+		Secret: secret,
+		Uri:    uri,
+	}, nil
+}
+
+func (s *ServerAPI) ConfirmTOTPEnrollment(ctx context.Context, req *ssov1.ConfirmTOTPEnrollmentRequest) (*ssov1.ConfirmTOTPEnrollmentResponse, error) {
+	if err := validateTokenAndCode(req.GetAccessToken(), req.GetCode()); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.mfa.ConfirmTOTPEnrollment(ctx, req.GetAccessToken(), req.GetCode())
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.ConfirmTOTPEnrollmentResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *ServerAPI) RegenerateRecoveryCodes(ctx context.Context, req *ssov1.RegenerateRecoveryCodesRequest) (*ssov1.RegenerateRecoveryCodesResponse, error) {
+	if err := validateTokenAndCode(req.GetAccessToken(), req.GetCode()); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.mfa.RegenerateRecoveryCodes(ctx, req.GetAccessToken(), req.GetCode())
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.RegenerateRecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *ServerAPI) DisableTOTP(ctx context.Context, req *ssov1.DisableTOTPRequest) (*ssov1.DisableTOTPResponse, error) {
+	if err := validateTokenAndCode(req.GetAccessToken(), req.GetCode()); err != nil {
+		return nil, err
+	}
+
+	if err := s.mfa.DisableTOTP(ctx, req.GetAccessToken(), req.GetCode()); err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.DisableTOTPResponse{}, nil
+}
+
+func (s *ServerAPI) SendEmailMFACode(ctx context.Context, req *ssov1.SendEmailMFACodeRequest) (*ssov1.SendEmailMFACodeResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if err := s.mfa.SendEmailMFACode(ctx, req.GetAccessToken()); err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.SendEmailMFACodeResponse{}, nil
+}
+
+func (s *ServerAPI) ConfirmEmailMFA(ctx context.Context, req *ssov1.ConfirmEmailMFARequest) (*ssov1.ConfirmEmailMFAResponse, error) {
+	if err := validateTokenAndCode(req.GetAccessToken(), req.GetCode()); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.mfa.ConfirmEmailMFA(ctx, req.GetAccessToken(), req.GetCode())
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.ConfirmEmailMFAResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *ServerAPI) DisableEmailMFA(ctx context.Context, req *ssov1.DisableEmailMFARequest) (*ssov1.DisableEmailMFAResponse, error) {
+	if err := validateTokenAndCode(req.GetAccessToken(), req.GetCode()); err != nil {
+		return nil, err
+	}
+
+	if err := s.mfa.DisableEmailMFA(ctx, req.GetAccessToken(), req.GetCode()); err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.DisableEmailMFAResponse{}, nil
+}
+
+func (s *ServerAPI) SendChallengeEmailCode(ctx context.Context, req *ssov1.SendChallengeEmailCodeRequest) (*ssov1.SendChallengeEmailCodeResponse, error) {
+	if req.GetChallengeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "challenge_id required")
+	}
+
+	if err := s.mfa.SendChallengeEmailCode(ctx, req.GetChallengeId()); err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.SendChallengeEmailCodeResponse{}, nil
+}
+
+func (s *ServerAPI) CompleteMFA(ctx context.Context, req *ssov1.CompleteMFARequest) (*ssov1.CompleteMFAResponse, error) {
+	if req.GetChallengeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "challenge_id required")
+	}
+
+	if req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "code required")
+	}
+
+	accessToken, refreshToken, recoveryCodes, err := s.mfa.CompleteMFA(ctx, req.GetChallengeId(), req.GetCode())
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.CompleteMFAResponse{
+		AccessToken:   accessToken,
+		RefreshToken:  refreshToken,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *ServerAPI) StepUp(ctx context.Context, req *ssov1.StepUpRequest) (*ssov1.StepUpResponse, error) {
+	if err := validateTokenAndCode(req.GetAccessToken(), req.GetCode()); err != nil {
+		return nil, err
+	}
+
+	stepUpToken, err := s.mfa.StepUp(ctx, req.GetAccessToken(), req.GetCode())
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &ssov1.StepUpResponse{
+		StepUpToken: stepUpToken,
+	}, nil
+}
+
+func (s *ServerAPI) SetAppMFAPolicy(ctx context.Context, req *ssov1.SetAppMFAPolicyRequest) (*ssov1.SetAppMFAPolicyResponse, error) {
+	if req.GetAdminToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if req.GetAppName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "app_name required")
+	}
+
+	if err := s.admin.SetAppMFAPolicy(ctx, req.GetAdminToken(), req.GetAppName(), models.MFAPolicy(req.GetPolicy())); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.SetAppMFAPolicyResponse{}, nil
+}
+
+// mfaError maps errors of the MFA service to gRPC statuses.
+func mfaError(err error) error {
+	switch {
+	case errors.Is(err, mfa.ErrAccessDenied):
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return status.Error(codes.InvalidArgument, "invalid code")
+	case errors.Is(err, mfa.ErrInvalidChallenge):
+		return status.Error(codes.InvalidArgument, "invalid or expired challenge")
+	case errors.Is(err, mfa.ErrAlreadyEnrolled):
+		return status.Error(codes.AlreadyExists, "mfa already enrolled")
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		return status.Error(codes.FailedPrecondition, "mfa not enrolled")
+	case errors.Is(err, mfa.ErrEnrollmentBlocked):
+		return status.Error(codes.FailedPrecondition, "challenge does not allow enrollment")
+	case errors.Is(err, mfa.ErrUserInactive):
+		return status.Error(codes.PermissionDenied, "user is not active")
+	case errors.Is(err, mfa.ErrRateLimited):
+		return status.Error(codes.ResourceExhausted, "too many codes requested")
+	case errors.Is(err, mfa.ErrTooManyAttempts):
+		return status.Error(codes.ResourceExhausted, "too many invalid codes, try again later")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func validateTokenAndCode(accessToken string, code string) error {
+	if accessToken == "" {
+		return status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if code == "" {
+		return status.Error(codes.InvalidArgument, "code required")
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/admin"
+	"github.com/sol1corejz/auth-service/internal/services/mfa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeMFA fails every call with err. Methods a test doesn't override panic
+// through the nil embedded MFA.
+type fakeMFA struct {
+	MFA
+	err error
+}
+
+func (f *fakeMFA) ConfirmTOTPEnrollment(context.Context, string, string) ([]string, error) {
+	return []string{"code-1", "code-2"}, f.err
+}
+
+func (f *fakeMFA) RegenerateRecoveryCodes(context.Context, string, string) ([]string, error) {
+	return []string{"code-3"}, f.err
+}
+
+func (f *fakeMFA) SendChallengeEmailCode(context.Context, string) error {
+	return f.err
+}
+
+func (f *fakeMFA) ConfirmEmailMFA(context.Context, string, string) ([]string, error) {
+	return []string{"code-4"}, f.err
+}
+
+func (f *fakeMFA) StepUp(context.Context, string, string) (string, error) {
+	return "step-up", f.err
+}
+
+func (f *fakeMFA) CompleteMFA(context.Context, string, string) (string, string, []string, error) {
+	return "access", "refresh", []string{"code-1"}, f.err
+}
+
+func TestCompleteMFA(t *testing.T) {
+	srv := &ServerAPI{mfa: &fakeMFA{}}
+
+	resp, err := srv.CompleteMFA(context.Background(), &ssov1.CompleteMFARequest{ChallengeId: "challenge", Code: "123456"})
+	require.NoError(t, err)
+	assert.Equal(t, "access", resp.GetAccessToken())
+	assert.Equal(t, "refresh", resp.GetRefreshToken())
+	assert.Equal(t, []string{"code-1"}, resp.GetRecoveryCodes())
+}
+
+func TestCompleteMFA_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.CompleteMFARequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no challenge", req: &ssov1.CompleteMFARequest{Code: "123456"}, want: codes.InvalidArgument},
+		{name: "no code", req: &ssov1.CompleteMFARequest{ChallengeId: "challenge"}, want: codes.InvalidArgument},
+		{name: "invalid code", err: mfa.ErrInvalidCode, want: codes.InvalidArgument},
+		{name: "invalid challenge", err: mfa.ErrInvalidChallenge, want: codes.InvalidArgument},
+		{name: "inactive", err: mfa.ErrUserInactive, want: codes.PermissionDenied},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.CompleteMFARequest{ChallengeId: "challenge", Code: "123456"}
+			}
+
+			srv := &ServerAPI{mfa: &fakeMFA{err: tt.err}}
+
+			_, err := srv.CompleteMFA(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestConfirmTOTPEnrollment(t *testing.T) {
+	srv := &ServerAPI{mfa: &fakeMFA{}}
+
+	resp, err := srv.ConfirmTOTPEnrollment(context.Background(), &ssov1.ConfirmTOTPEnrollmentRequest{AccessToken: "token", Code: "123456"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code-1", "code-2"}, resp.GetRecoveryCodes())
+
+	srv = &ServerAPI{mfa: &fakeMFA{err: mfa.ErrAccessDenied}}
+
+	_, err = srv.ConfirmTOTPEnrollment(context.Background(), &ssov1.ConfirmTOTPEnrollmentRequest{AccessToken: "token", Code: "123456"})
+	assertCode(t, codes.Unauthenticated, err)
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	srv := &ServerAPI{mfa: &fakeMFA{}}
+
+	resp, err := srv.RegenerateRecoveryCodes(context.Background(), &ssov1.RegenerateRecoveryCodesRequest{AccessToken: "token", Code: "123456"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code-3"}, resp.GetRecoveryCodes())
+
+	tests := []struct {
+		name string
+		req  *ssov1.RegenerateRecoveryCodesRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no code", req: &ssov1.RegenerateRecoveryCodesRequest{AccessToken: "token"}, want: codes.InvalidArgument},
+		{name: "not enrolled", err: mfa.ErrNotEnrolled, want: codes.FailedPrecondition},
+		{name: "invalid code", err: mfa.ErrInvalidCode, want: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.RegenerateRecoveryCodesRequest{AccessToken: "token", Code: "123456"}
+			}
+
+			srv := &ServerAPI{mfa: &fakeMFA{err: tt.err}}
+
+			_, err := srv.RegenerateRecoveryCodes(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestConfirmEmailMFA(t *testing.T) {
+	srv := &ServerAPI{mfa: &fakeMFA{}}
+
+	resp, err := srv.ConfirmEmailMFA(context.Background(), &ssov1.ConfirmEmailMFARequest{AccessToken: "token", Code: "123456"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code-4"}, resp.GetRecoveryCodes())
+
+	srv = &ServerAPI{mfa: &fakeMFA{err: mfa.ErrAlreadyEnrolled}}
+
+	_, err = srv.ConfirmEmailMFA(context.Background(), &ssov1.ConfirmEmailMFARequest{AccessToken: "token", Code: "123456"})
+	assertCode(t, codes.AlreadyExists, err)
+}
+
+func TestSendChallengeEmailCode_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.SendChallengeEmailCodeRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no challenge", req: &ssov1.SendChallengeEmailCodeRequest{}, want: codes.InvalidArgument},
+		{name: "invalid challenge", err: mfa.ErrInvalidChallenge, want: codes.InvalidArgument},
+		{name: "not enrolled", err: mfa.ErrNotEnrolled, want: codes.FailedPrecondition},
+		{name: "rate limited", err: mfa.ErrRateLimited, want: codes.ResourceExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.SendChallengeEmailCodeRequest{ChallengeId: "challenge"}
+			}
+
+			srv := &ServerAPI{mfa: &fakeMFA{err: tt.err}}
+
+			_, err := srv.SendChallengeEmailCode(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestStepUp(t *testing.T) {
+	srv := &ServerAPI{mfa: &fakeMFA{}}
+
+	resp, err := srv.StepUp(context.Background(), &ssov1.StepUpRequest{AccessToken: "token", Code: "123456"})
+	require.NoError(t, err)
+	assert.Equal(t, "step-up", resp.GetStepUpToken())
+
+	srv = &ServerAPI{mfa: &fakeMFA{err: mfa.ErrUserInactive}}
+
+	_, err = srv.StepUp(context.Background(), &ssov1.StepUpRequest{AccessToken: "token", Code: "123456"})
+	assertCode(t, codes.PermissionDenied, err)
+	srv = &ServerAPI{mfa: &fakeMFA{err: mfa.ErrTooManyAttempts}}
+
+	_, err = srv.StepUp(context.Background(), &ssov1.StepUpRequest{AccessToken: "token", Code: "123456"})
+	assertCode(t, codes.ResourceExhausted, err)
+}
+
+func (f *fakeAdmin) SetAppMFAPolicy(context.Context, string, string, models.MFAPolicy) error {
+	return f.err
+}
+
+func TestSetAppMFAPolicy_Errors(t *testing.T) {
+	req := &ssov1.SetAppMFAPolicyRequest{AdminToken: "token", AppName: "app", Policy: "all"}
+
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "not admin", err: admin.ErrPermissionDenied, want: codes.PermissionDenied},
+		{name: "invalid policy", err: admin.ErrInvalidPolicy, want: codes.InvalidArgument},
+		{name: "unknown app", err: admin.ErrAppNotFound, want: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &ServerAPI{admin: &fakeAdmin{err: tt.err}}
+
+			_, err := srv.SetAppMFAPolicy(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+// fakeStream keeps trailers the handler sets.
+type fakeStream struct {
+	grpc.ServerTransportStream
+	trailer metadata.MD
+}
+
+func (f *fakeStream) SetTrailer(md metadata.MD) error {
+	f.trailer = metadata.Join(f.trailer, md)
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/org"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ServerAPI) CreateOrganization(ctx context.Context, req *ssov1.CreateOrganizationRequest) (*ssov1.CreateOrganizationResponse, error) {
+	if err := validateAdminTokenAndName(req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	if req.GetAdminUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_user_id required")
+	}
+
+	organization, err := s.org.CreateOrganization(ctx, req.GetAdminToken(), req.GetName(), req.GetAdminUserId())
+	if err != nil {
+		return nil, orgError(err)
+	}
+
+	return &ssov1.CreateOrganizationResponse{
+		Organization: organizationToProto(organization),
+	}, nil
+}
+
+func (s *ServerAPI) UserOrganizations(ctx context.Context, req *ssov1.UserOrganizationsRequest) (*ssov1.UserOrganizationsResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	orgs, err := s.org.UserOrganizations(ctx, req.GetAccessToken())
+	if err != nil {
+		return nil, orgError(err)
+	}
+
+	return &ssov1.UserOrganizationsResponse{
+		Organizations: organizationsToProto(orgs),
+	}, nil
+}
+
+func (s *ServerAPI) ListOrgInvitations(ctx context.Context, req *ssov1.ListOrgInvitationsRequest) (*ssov1.ListOrgInvitationsResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	orgs, err := s.org.Invitations(ctx, req.GetAccessToken())
+	if err != nil {
+		return nil, orgError(err)
+	}
+
+	return &ssov1.ListOrgInvitationsResponse{
+		Organizations: organizationsToProto(orgs),
+	}, nil
+}
+
+func (s *ServerAPI) AcceptOrgInvitation(ctx context.Context, req *ssov1.AcceptOrgInvitationRequest) (*ssov1.AcceptOrgInvitationResponse, error) {
+	if err := validateOrgSession(req.GetAccessToken(), req.GetOrgId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.org.AcceptInvitation(ctx, req.GetAccessToken(), req.GetOrgId()); err != nil {
+		return nil, orgError(err)
+	}
+
+	return &ssov1.AcceptOrgInvitationResponse{}, nil
+}
+
+func (s *ServerAPI) LoginToOrganization(ctx context.Context, req *ssov1.LoginToOrganizationRequest) (*ssov1.LoginResponse, error) {
+	if err := validateOrgSession(req.GetAccessToken(), req.GetOrgId()); err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, err := s.org.LoginToOrganization(ctx, req.GetAccessToken(), req.GetOrgId())
+	if err != nil {
+		return nil, orgError(err)
+	}
+
+	return &ssov1.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *ServerAPI) ListOrgMembers(ctx context.Context, req *ssov1.ListOrgMembersRequest) (*ssov1.ListOrgMembersResponse, error) {
+	if req.GetAdminToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if req.GetOrgId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "org_id required")
+	}
+
+	members, err := s.org.ListMembers(ctx, req.GetAdminToken(), req.GetOrgId())
+	if err != nil {
+		return nil, orgError(err)
+	}
+
+	resp := &ssov1.ListOrgMembersResponse{
+		Members: make([]*ssov1.OrgMember, 0, len(members)),
+	}
+	for _, member := range members {
+		resp.Members = append(resp.Members, &ssov1.OrgMember{
+			UserId:     member.User.ID.String(),
+			Email:      member.User.Email,
+			Username:   member.User.Username,
+			Role:       string(member.Role),
+			Pending:    member.Pending(),
+			CreatedAt:  unixOrZero(member.CreatedAt),
+			AcceptedAt: unixOrZero(member.AcceptedAt),
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) AddOrgMember(ctx context.Context, req *ssov1.AddOrgMemberRequest) (*ssov1.AddOrgMemberResponse, error) {
+	if err := validateOrgMember(req.GetAdminToken(), req.GetOrgId(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	role := models.OrgRole(req.GetRole())
+	if err := s.org.AddMember(ctx, req.GetAdminToken(), req.GetOrgId(), req.GetUserId(), role); err != nil {
+		return nil, orgError(err)
+	}
+
+	return &ssov1.AddOrgMemberResponse{}, nil
+}
+
+func (s *ServerAPI) SetOrgMemberRole(ctx context.Context, req *ssov1.SetOrgMemberRoleRequest) (*ssov1.SetOrgMemberRoleResponse, error) {
+	if err := validateOrgMember(req.GetAdminToken(), req.GetOrgId(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	role := models.OrgRole(req.GetRole())
+	if err := s.org.SetMemberRole(ctx, req.GetAdminToken(), req.GetOrgId(), req.GetUserId(), role); err != nil {
+		return nil, orgError(err)
+	}
+
+	return &ssov1.SetOrgMemberRoleResponse{}, nil
+}
+
+func (s *ServerAPI) RemoveOrgMember(ctx context.Context, req *ssov1.RemoveOrgMemberRequest) (*ssov1.RemoveOrgMemberResponse, error) {
+	if err := validateOrgMember(req.GetAdminToken(), req.GetOrgId(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.org.RemoveMember(ctx, req.GetAdminToken(), req.GetOrgId(), req.GetUserId()); err != nil {
+		return nil, orgError(err)
+	}
+
+	return &ssov1.RemoveOrgMemberResponse{}, nil
+}
+
+func organizationToProto(organization models.Organization) *ssov1.Organization {
+	return &ssov1.Organization{
+		OrgId:     organization.ID.String(),
+		Name:      organization.Name,
+		CreatedAt: unixOrZero(organization.CreatedAt),
+	}
+}
+
+func organizationsToProto(orgs []models.Organization) []*ssov1.Organization {
+	result := make([]*ssov1.Organization, 0, len(orgs))
+	for _, organization := range orgs {
+		result = append(result, organizationToProto(organization))
+	}
+
+	return result
+}
+
+// orgError maps errors of the Org service to gRPC statuses.
+func orgError(err error) error {
+	switch {
+	case errors.Is(err, org.ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, org.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, org.ErrInvalidName):
+		return status.Error(codes.InvalidArgument, "invalid organization name")
+	case errors.Is(err, org.ErrInvalidRole):
+		return status.Error(codes.InvalidArgument, "invalid organization role")
+	case errors.Is(err, org.ErrOrgExists):
+		return status.Error(codes.AlreadyExists, "organization already exists")
+	case errors.Is(err, org.ErrOrgNotFound):
+		return status.Error(codes.NotFound, "organization not found")
+	case errors.Is(err, org.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, org.ErrNotMember):
+		return status.Error(codes.NotFound, "organization member not found")
+	case errors.Is(err, org.ErrMemberExists):
+		return status.Error(codes.AlreadyExists, "user is already a member of the organization")
+	case errors.Is(err, org.ErrNotInvited):
+		return status.Error(codes.NotFound, "invitation not found")
+	case errors.Is(err, org.ErrLastAdmin):
+		return status.Error(codes.FailedPrecondition, "organization must keep an admin")
+	case errors.Is(err, org.ErrAppDisabled):
+		return status.Error(codes.FailedPrecondition, "app is disabled")
+	case errors.Is(err, org.ErrAppAccessDenied):
+		return status.Error(codes.PermissionDenied, "user is not allowed into the app")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func validateOrgSession(accessToken string, orgID string) error {
+	if accessToken == "" {
+		return status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if orgID == "" {
+		return status.Error(codes.InvalidArgument, "org_id required")
+	}
+
+	return nil
+}
+
+func validateOrgMember(adminToken string, orgID string, userID string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if orgID == "" {
+		return status.Error(codes.InvalidArgument, "org_id required")
+	}
+
+	if userID == "" {
+		return status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	return nil
+}
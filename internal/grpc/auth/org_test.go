@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/org"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// fakeOrg fails every call with err. Methods a test doesn't override panic through the nil embedded Org.
+type fakeOrg struct {
+	Org
+	err     error
+	members []models.OrgMember
+	role    models.OrgRole
+}
+
+func (f *fakeOrg) ListMembers(_ context.Context, _ string, _ string) ([]models.OrgMember, error) {
+	return f.members, f.err
+}
+
+func (f *fakeOrg) AddMember(_ context.Context, _ string, _ string, _ string, role models.OrgRole) error {
+	f.role = role
+
+	return f.err
+}
+
+func (f *fakeOrg) AcceptInvitation(_ context.Context, _ string, _ string) error {
+	return f.err
+}
+
+func TestListOrgMembers(t *testing.T) {
+	invited := uuid.New()
+	srv := &ServerAPI{org: &fakeOrg{members: []models.OrgMember{
+		{
+			User:       models.User{ID: uuid.New(), Email: "owner@example.com"},
+			Role:       models.OrgRoleAdmin,
+			CreatedAt:  time.Unix(100, 0),
+			AcceptedAt: time.Unix(100, 0),
+		},
+		{User: models.User{ID: invited}, Role: models.OrgRoleMember, CreatedAt: time.Unix(200, 0)},
+	}}}
+
+	resp, err := srv.ListOrgMembers(context.Background(), &ssov1.ListOrgMembersRequest{AdminToken: "token", OrgId: "org"})
+	require.NoError(t, err)
+	require.Len(t, resp.GetMembers(), 2)
+
+	owner := resp.GetMembers()[0]
+	assert.Equal(t, "owner@example.com", owner.GetEmail())
+	assert.Equal(t, "admin", owner.GetRole())
+	assert.False(t, owner.GetPending())
+	assert.Equal(t, int64(100), owner.GetAcceptedAt())
+
+	pending := resp.GetMembers()[1]
+	assert.Equal(t, invited.String(), pending.GetUserId())
+	assert.Empty(t, pending.GetEmail())
+	assert.True(t, pending.GetPending())
+	assert.Equal(t, int64(200), pending.GetCreatedAt())
+	assert.Zero(t, pending.GetAcceptedAt())
+}
+
+func TestAddOrgMember(t *testing.T) {
+	fake := &fakeOrg{}
+	srv := &ServerAPI{org: fake}
+
+	_, err := srv.AddOrgMember(context.Background(), &ssov1.AddOrgMemberRequest{
+		AdminToken: "token",
+		OrgId:      "org",
+		UserId:     "user",
+		Role:       "member",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.OrgRoleMember, fake.role)
+
+	_, err = srv.AddOrgMember(context.Background(), &ssov1.AddOrgMemberRequest{AdminToken: "token", OrgId: "org"})
+	assertCode(t, codes.InvalidArgument, err)
+}
+
+func TestOrgErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "invalid token", err: org.ErrInvalidToken, want: codes.Unauthenticated},
+		{name: "permission denied", err: org.ErrPermissionDenied, want: codes.PermissionDenied},
+		{name: "invalid role", err: org.ErrInvalidRole, want: codes.InvalidArgument},
+		{name: "already member", err: org.ErrMemberExists, want: codes.AlreadyExists},
+		{name: "not invited", err: org.ErrNotInvited, want: codes.NotFound},
+		{name: "last admin", err: org.ErrLastAdmin, want: codes.FailedPrecondition},
+		{name: "disabled app", err: org.ErrAppDisabled, want: codes.FailedPrecondition},
+		{name: "not allowed into app", err: org.ErrAppAccessDenied, want: codes.PermissionDenied},
+		{name: "storage failure", err: errors.New("boom"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &ServerAPI{org: &fakeOrg{err: tt.err}}
+
+			_, err := srv.AcceptOrgInvitation(context.Background(), &ssov1.AcceptOrgInvitationRequest{
+				AccessToken: "token",
+				OrgId:       "org",
+			})
+			assertCode(t, tt.want, err)
+		})
+	}
+}
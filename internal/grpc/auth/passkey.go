@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/services/passkey"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ServerAPI) BeginPasskeyRegistration(ctx context.Context, req *ssov1.BeginPasskeyRegistrationRequest) (*ssov1.BeginPasskeyResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	options, sessionID, err := s.passkey.BeginPasskeyRegistration(ctx, req.GetAccessToken())
+	if err != nil {
+		return nil, passkeyError(err)
+	}
+
+	return &ssov1.BeginPasskeyResponse{
+		Options:   options,
+		SessionId: sessionID,
+	}, nil
+}
+
+func (s *ServerAPI) FinishPasskeyRegistration(ctx context.Context, req *ssov1.FinishPasskeyRegistrationRequest) (*ssov1.FinishPasskeyRegistrationResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if err := validateCeremony(req.GetSessionId(), req.GetResponse()); err != nil {
+		return nil, err
+	}
+
+	if err := s.passkey.FinishPasskeyRegistration(ctx, req.GetAccessToken(), req.GetSessionId(), req.GetResponse(), req.GetName()); err != nil {
+		return nil, passkeyError(err)
+	}
+
+	return &ssov1.FinishPasskeyRegistrationResponse{}, nil
+}
+
+func (s *ServerAPI) BeginPasskeyLogin(ctx context.Context, req *ssov1.BeginPasskeyLoginRequest) (*ssov1.BeginPasskeyResponse, error) {
+	if req.GetAppName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "app_name required")
+	}
+
+	options, sessionID, err := s.passkey.BeginPasskeyLogin(ctx, req.GetAppName())
+	if err != nil {
+		return nil, passkeyError(err)
+	}
+
+	return &ssov1.BeginPasskeyResponse{
+		Options:   options,
+		SessionId: sessionID,
+	}, nil
+}
+
+func (s *ServerAPI) FinishPasskeyLogin(ctx context.Context, req *ssov1.FinishPasskeyLoginRequest) (*ssov1.LoginResponse, error) {
+	if err := validateCeremony(req.GetSessionId(), req.GetResponse()); err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, err := s.passkey.FinishPasskeyLogin(ctx, req.GetSessionId(), req.GetResponse())
+	if err != nil {
+		return nil, passkeyError(err)
+	}
+
+	return &ssov1.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *ServerAPI) BeginPasskeyStepUp(ctx context.Context, req *ssov1.BeginPasskeyStepUpRequest) (*ssov1.BeginPasskeyResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	options, sessionID, err := s.passkey.BeginPasskeyStepUp(ctx, req.GetAccessToken())
+	if err != nil {
+		return nil, passkeyError(err)
+	}
+
+	return &ssov1.BeginPasskeyResponse{
+		Options:   options,
+		SessionId: sessionID,
+	}, nil
+}
+
+func (s *ServerAPI) FinishPasskeyStepUp(ctx context.Context, req *ssov1.FinishPasskeyStepUpRequest) (*ssov1.StepUpResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if err := validateCeremony(req.GetSessionId(), req.GetResponse()); err != nil {
+		return nil, err
+	}
+
+	stepUpToken, err := s.passkey.FinishPasskeyStepUp(ctx, req.GetAccessToken(), req.GetSessionId(), req.GetResponse())
+	if err != nil {
+		return nil, passkeyError(err)
+	}
+
+	return &ssov1.StepUpResponse{
+		StepUpToken: stepUpToken,
+	}, nil
+}
+
+func (s *ServerAPI) SetAppWebAuthn(ctx context.Context, req *ssov1.SetAppWebAuthnRequest) (*ssov1.SetAppWebAuthnResponse, error) {
+	if req.GetAdminToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if req.GetAppName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "app_name required")
+	}
+
+	if err := s.admin.SetAppWebAuthn(ctx, req.GetAdminToken(), req.GetAppName(), req.GetRpId(), req.GetOrigins()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.SetAppWebAuthnResponse{}, nil
+}
+
+// passkeyError maps errors of the passkey service to gRPC statuses.
+func passkeyError(err error) error {
+	switch {
+	case errors.Is(err, passkey.ErrAccessDenied):
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, passkey.ErrAppNotFound):
+		return status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, passkey.ErrPasskeysDisabled):
+		return status.Error(codes.FailedPrecondition, "passkeys are not enabled for the app")
+	case errors.Is(err, passkey.ErrAppDisabled):
+		return status.Error(codes.FailedPrecondition, "app is disabled")
+	case errors.Is(err, passkey.ErrAppAccessDenied):
+		return status.Error(codes.PermissionDenied, "user is not allowed into the app")
+	case errors.Is(err, passkey.ErrInvalidSession):
+		return status.Error(codes.InvalidArgument, "invalid or expired passkey session")
+	case errors.Is(err, passkey.ErrInvalidCredential):
+		return status.Error(codes.InvalidArgument, "invalid passkey credential")
+	case errors.Is(err, passkey.ErrPasskeyExists):
+		return status.Error(codes.AlreadyExists, "passkey already registered")
+	case errors.Is(err, passkey.ErrUserInactive):
+		return status.Error(codes.PermissionDenied, "user is not active")
+	case errors.Is(err, passkey.ErrNoPasskeys):
+		return status.Error(codes.FailedPrecondition, "user has no passkeys for the app")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func validateCeremony(sessionID string, response []byte) error {
+	if sessionID == "" {
+		return status.Error(codes.InvalidArgument, "session_id required")
+	}
+
+	if len(response) == 0 {
+		return status.Error(codes.InvalidArgument, "response required")
+	}
+
+	return nil
+}
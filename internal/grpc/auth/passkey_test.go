@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/services/admin"
+	"github.com/sol1corejz/auth-service/internal/services/passkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// fakePasskey fails every call with err. Methods a test doesn't override panic
+// through the nil embedded Passkey.
+type fakePasskey struct {
+	Passkey
+	err error
+}
+
+func (f *fakePasskey) BeginPasskeyLogin(context.Context, string) ([]byte, string, error) {
+	return []byte(`{"publicKey":{}}`), "session", f.err
+}
+
+func (f *fakePasskey) FinishPasskeyLogin(context.Context, string, []byte) (string, string, error) {
+	return "access", "refresh", f.err
+}
+
+func (f *fakePasskey) FinishPasskeyStepUp(context.Context, string, string, []byte) (string, error) {
+	return "step-up", f.err
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	srv := &ServerAPI{passkey: &fakePasskey{}}
+	ctx := context.Background()
+
+	begin, err := srv.BeginPasskeyLogin(ctx, &ssov1.BeginPasskeyLoginRequest{AppName: "app"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"publicKey":{}}`, string(begin.GetOptions()))
+	assert.Equal(t, "session", begin.GetSessionId())
+
+	resp, err := srv.FinishPasskeyLogin(ctx, &ssov1.FinishPasskeyLoginRequest{SessionId: "session", Response: []byte(`{}`)})
+	require.NoError(t, err)
+	assert.Equal(t, "access", resp.GetAccessToken())
+	assert.Equal(t, "refresh", resp.GetRefreshToken())
+}
+
+func TestFinishPasskeyLogin_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.FinishPasskeyLoginRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no session", req: &ssov1.FinishPasskeyLoginRequest{Response: []byte(`{}`)}, want: codes.InvalidArgument},
+		{name: "no response", req: &ssov1.FinishPasskeyLoginRequest{SessionId: "session"}, want: codes.InvalidArgument},
+		{name: "invalid session", err: passkey.ErrInvalidSession, want: codes.InvalidArgument},
+		{name: "invalid credential", err: passkey.ErrInvalidCredential, want: codes.InvalidArgument},
+		{name: "inactive", err: passkey.ErrUserInactive, want: codes.PermissionDenied},
+		{name: "access denied", err: passkey.ErrAppAccessDenied, want: codes.PermissionDenied},
+		{name: "disabled app", err: passkey.ErrAppDisabled, want: codes.FailedPrecondition},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.FinishPasskeyLoginRequest{SessionId: "session", Response: []byte(`{}`)}
+			}
+
+			srv := &ServerAPI{passkey: &fakePasskey{err: tt.err}}
+
+			_, err := srv.FinishPasskeyLogin(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestBeginPasskeyLogin_Disabled(t *testing.T) {
+	srv := &ServerAPI{passkey: &fakePasskey{err: passkey.ErrPasskeysDisabled}}
+
+	_, err := srv.BeginPasskeyLogin(context.Background(), &ssov1.BeginPasskeyLoginRequest{AppName: "app"})
+	assertCode(t, codes.FailedPrecondition, err)
+}
+
+func TestFinishPasskeyStepUp(t *testing.T) {
+	req := &ssov1.FinishPasskeyStepUpRequest{AccessToken: "token", SessionId: "session", Response: []byte(`{}`)}
+
+	srv := &ServerAPI{passkey: &fakePasskey{}}
+
+	resp, err := srv.FinishPasskeyStepUp(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "step-up", resp.GetStepUpToken())
+
+	srv = &ServerAPI{passkey: &fakePasskey{err: passkey.ErrNoPasskeys}}
+
+	_, err = srv.FinishPasskeyStepUp(context.Background(), req)
+	assertCode(t, codes.FailedPrecondition, err)
+}
+
+func (f *fakeAdmin) SetAppWebAuthn(context.Context, string, string, string, []string) error {
+	return f.err
+}
+
+func TestSetAppWebAuthn_InvalidRP(t *testing.T) {
+	srv := &ServerAPI{admin: &fakeAdmin{err: admin.ErrInvalidRP}}
+
+	_, err := srv.SetAppWebAuthn(context.Background(), &ssov1.SetAppWebAuthnRequest{
+		AdminToken: "token",
+		AppName:    "app",
+		RpId:       "example.com",
+		Origins:    []string{"https://evil.test"},
+	})
+	assertCode(t, codes.InvalidArgument, err)
+}
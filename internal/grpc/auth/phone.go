@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/services/phone"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ServerAPI) StartPhoneLogin(ctx context.Context, req *ssov1.StartPhoneLoginRequest) (*ssov1.StartPhoneLoginResponse, error) {
+	if req.GetPhone() == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone required")
+	}
+
+	if err := s.phoneAuth.StartPhoneLogin(ctx, req.GetPhone()); err != nil {
+		return nil, phoneError(ctx, err)
+	}
+
+	return &ssov1.StartPhoneLoginResponse{}, nil
+}
+
+func (s *ServerAPI) CompletePhoneLogin(ctx context.Context, req *ssov1.CompletePhoneLoginRequest) (*ssov1.LoginResponse, error) {
+	if err := validateCompletePhoneLogin(req); err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, err := s.phoneAuth.CompletePhoneLogin(ctx, req.GetPhone(), req.GetCode(), req.GetAppName())
+	if err != nil {
+		return nil, phoneError(ctx, err)
+	}
+
+	return &ssov1.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *ServerAPI) StartPhoneVerification(ctx context.Context, req *ssov1.StartPhoneVerificationRequest) (*ssov1.StartPhoneVerificationResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if req.GetPhone() == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone required")
+	}
+
+	if err := s.phoneAuth.StartPhoneVerification(ctx, req.GetAccessToken(), req.GetPhone()); err != nil {
+		return nil, phoneError(ctx, err)
+	}
+
+	return &ssov1.StartPhoneVerificationResponse{}, nil
+}
+
+func (s *ServerAPI) ConfirmPhone(ctx context.Context, req *ssov1.ConfirmPhoneRequest) (*ssov1.ConfirmPhoneResponse, error) {
+	if err := validateConfirmPhone(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.phoneAuth.ConfirmPhone(ctx, req.GetAccessToken(), req.GetPhone(), req.GetCode()); err != nil {
+		return nil, phoneError(ctx, err)
+	}
+
+	return &ssov1.ConfirmPhoneResponse{}, nil
+}
+
+// phoneError maps errors of the phone service to gRPC statuses.
+func phoneError(ctx context.Context, err error) error {
+	var mfaErr *auth.MFARequiredError
+
+	switch {
+	case errors.As(err, &mfaErr):
+		return mfaRequired(ctx, mfaErr)
+	case errors.Is(err, phone.ErrInvalidPhone):
+		return status.Error(codes.InvalidArgument, "invalid phone")
+	case errors.Is(err, phone.ErrInvalidCode):
+		return status.Error(codes.InvalidArgument, "invalid code")
+	case errors.Is(err, phone.ErrInvalidApp):
+		return status.Error(codes.InvalidArgument, "invalid app")
+	case errors.Is(err, phone.ErrRateLimited):
+		return status.Error(codes.ResourceExhausted, "too many codes requested")
+	case errors.Is(err, phone.ErrPhoneTaken):
+		return status.Error(codes.AlreadyExists, "phone already taken")
+	case errors.Is(err, phone.ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, phone.ErrUserInactive):
+		return status.Error(codes.PermissionDenied, "user is not active")
+	case errors.Is(err, phone.ErrAppDisabled):
+		return status.Error(codes.FailedPrecondition, "app is disabled")
+	case errors.Is(err, phone.ErrAppAccessDenied):
+		return status.Error(codes.PermissionDenied, "user is not allowed into the app")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func validateCompletePhoneLogin(req *ssov1.CompletePhoneLoginRequest) error {
+	if req.GetPhone() == "" {
+		return status.Error(codes.InvalidArgument, "phone required")
+	}
+
+	if req.GetCode() == "" {
+		return status.Error(codes.InvalidArgument, "code required")
+	}
+
+	if req.GetAppName() == "" {
+		return status.Error(codes.InvalidArgument, "app name or id required")
+	}
+
+	return nil
+}
+
+func validateConfirmPhone(req *ssov1.ConfirmPhoneRequest) error {
+	if req.GetAccessToken() == "" {
+		return status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if req.GetPhone() == "" {
+		return status.Error(codes.InvalidArgument, "phone required")
+	}
+
+	if req.GetCode() == "" {
+		return status.Error(codes.InvalidArgument, "code required")
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/services/phone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// fakePhoneAuth fails every call with err.
+type fakePhoneAuth struct {
+	err error
+}
+
+func (f *fakePhoneAuth) StartPhoneLogin(context.Context, string) error {
+	return f.err
+}
+
+func (f *fakePhoneAuth) CompletePhoneLogin(context.Context, string, string, string) (string, string, error) {
+	return "access", "refresh", f.err
+}
+
+func (f *fakePhoneAuth) StartPhoneVerification(context.Context, string, string) error {
+	return f.err
+}
+
+func (f *fakePhoneAuth) ConfirmPhone(context.Context, string, string, string) error {
+	return f.err
+}
+
+func TestCompletePhoneLogin(t *testing.T) {
+	srv := &ServerAPI{phoneAuth: &fakePhoneAuth{}}
+
+	resp, err := srv.CompletePhoneLogin(context.Background(), &ssov1.CompletePhoneLoginRequest{
+		Phone:   "+15551234567",
+		Code:    "123456",
+		AppName: "app",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "access", resp.GetAccessToken())
+	assert.Equal(t, "refresh", resp.GetRefreshToken())
+}
+
+func TestCompletePhoneLogin_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.CompletePhoneLoginRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no phone", req: &ssov1.CompletePhoneLoginRequest{Code: "1", AppName: "app"}, want: codes.InvalidArgument},
+		{name: "no code", req: &ssov1.CompletePhoneLoginRequest{Phone: "+1", AppName: "app"}, want: codes.InvalidArgument},
+		{name: "no app", req: &ssov1.CompletePhoneLoginRequest{Phone: "+1", Code: "1"}, want: codes.InvalidArgument},
+		{name: "invalid code", err: phone.ErrInvalidCode, want: codes.InvalidArgument},
+		{name: "invalid app", err: phone.ErrInvalidApp, want: codes.InvalidArgument},
+		{name: "inactive", err: phone.ErrUserInactive, want: codes.PermissionDenied},
+		{name: "access denied", err: phone.ErrAppAccessDenied, want: codes.PermissionDenied},
+		{name: "disabled app", err: phone.ErrAppDisabled, want: codes.FailedPrecondition},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.CompletePhoneLoginRequest{Phone: "+15551234567", Code: "123456", AppName: "app"}
+			}
+
+			srv := &ServerAPI{phoneAuth: &fakePhoneAuth{err: tt.err}}
+
+			_, err := srv.CompletePhoneLogin(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestCompletePhoneLogin_MFARequired(t *testing.T) {
+	srv := &ServerAPI{phoneAuth: &fakePhoneAuth{err: &auth.MFARequiredError{
+		ChallengeID: "challenge",
+		Methods:     []models.MFAMethod{models.MFAMethodTOTP, models.MFAMethodEmail},
+	}}}
+
+	stream := &fakeStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+	_, err := srv.CompletePhoneLogin(ctx, &ssov1.CompletePhoneLoginRequest{Phone: "+15551234567", Code: "123456", AppName: "app"})
+	assertCode(t, codes.FailedPrecondition, err)
+
+	assert.Equal(t, []string{"challenge"}, stream.trailer.Get(mfaChallengeIDKey))
+	assert.Equal(t, []string{"false"}, stream.trailer.Get(mfaEnrollmentRequiredKey))
+	assert.Equal(t, []string{"totp,email"}, stream.trailer.Get(mfaMethodsKey))
+}
+
+func TestStartPhoneLogin_RateLimited(t *testing.T) {
+	srv := &ServerAPI{phoneAuth: &fakePhoneAuth{err: phone.ErrRateLimited}}
+
+	_, err := srv.StartPhoneLogin(context.Background(), &ssov1.StartPhoneLoginRequest{Phone: "+15551234567"})
+	assertCode(t, codes.ResourceExhausted, err)
+}
+
+func TestConfirmPhone_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.ConfirmPhoneRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.ConfirmPhoneRequest{Phone: "+1", Code: "1"}, want: codes.InvalidArgument},
+		{name: "invalid token", err: phone.ErrInvalidToken, want: codes.Unauthenticated},
+		{name: "invalid phone", err: phone.ErrInvalidPhone, want: codes.InvalidArgument},
+		{name: "taken", err: phone.ErrPhoneTaken, want: codes.AlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.ConfirmPhoneRequest{AccessToken: "token", Phone: "+15551234567", Code: "123456"}
+			}
+
+			srv := &ServerAPI{phoneAuth: &fakePhoneAuth{err: tt.err}}
+
+			_, err := srv.ConfirmPhone(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
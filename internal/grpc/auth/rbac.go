@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/rbac"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ServerAPI) CheckPermissions(ctx context.Context, req *ssov1.CheckPermissionsRequest) (*ssov1.CheckPermissionsResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	if req.GetAppName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "app_name required")
+	}
+
+	allowed, err := s.rbac.CheckPermissions(ctx, req.GetUserId(), req.GetAppName(), req.GetPermissions())
+	if err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.CheckPermissionsResponse{
+		Allowed: allowed,
+	}, nil
+}
+
+func (s *ServerAPI) CreateRole(ctx context.Context, req *ssov1.CreateRoleRequest) (*ssov1.CreateRoleResponse, error) {
+	if err := validateAdminTokenAndName(req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	role, err := s.rbac.CreateRole(ctx, req.GetAdminToken(), req.GetAppName(), req.GetName(), req.GetDescription())
+	if err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.CreateRoleResponse{
+		Role: roleToProto(role),
+	}, nil
+}
+
+func (s *ServerAPI) DeleteRole(ctx context.Context, req *ssov1.DeleteRoleRequest) (*ssov1.DeleteRoleResponse, error) {
+	if err := validateAdminTokenAndName(req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.DeleteRole(ctx, req.GetAdminToken(), req.GetAppName(), req.GetName()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.DeleteRoleResponse{}, nil
+}
+
+func (s *ServerAPI) ListRoles(ctx context.Context, req *ssov1.ListRolesRequest) (*ssov1.ListRolesResponse, error) {
+	if req.GetAdminToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	roles, err := s.rbac.ListRoles(ctx, req.GetAdminToken(), req.GetAppName())
+	if err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.ListRolesResponse{
+		Roles: rolesToProto(roles),
+	}, nil
+}
+
+func (s *ServerAPI) CreatePermission(ctx context.Context, req *ssov1.CreatePermissionRequest) (*ssov1.CreatePermissionResponse, error) {
+	if err := validateAdminTokenAndName(req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	permission, err := s.rbac.CreatePermission(ctx, req.GetAdminToken(), req.GetAppName(), req.GetName(), req.GetDescription())
+	if err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.CreatePermissionResponse{
+		Permission: permissionToProto(permission),
+	}, nil
+}
+
+func (s *ServerAPI) DeletePermission(ctx context.Context, req *ssov1.DeletePermissionRequest) (*ssov1.DeletePermissionResponse, error) {
+	if err := validateAdminTokenAndName(req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.DeletePermission(ctx, req.GetAdminToken(), req.GetAppName(), req.GetName()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.DeletePermissionResponse{}, nil
+}
+
+func (s *ServerAPI) ListPermissions(ctx context.Context, req *ssov1.ListPermissionsRequest) (*ssov1.ListPermissionsResponse, error) {
+	if req.GetAdminToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	permissions, err := s.rbac.ListPermissions(ctx, req.GetAdminToken(), req.GetAppName())
+	if err != nil {
+		return nil, rbacError(err)
+	}
+
+	resp := &ssov1.ListPermissionsResponse{
+		Permissions: make([]*ssov1.Permission, 0, len(permissions)),
+	}
+	for _, permission := range permissions {
+		resp.Permissions = append(resp.Permissions, permissionToProto(permission))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) GrantPermission(ctx context.Context, req *ssov1.GrantPermissionRequest) (*ssov1.GrantPermissionResponse, error) {
+	if err := validateRolePermission(req.GetAdminToken(), req.GetRole(), req.GetPermission()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.GrantPermission(ctx, req.GetAdminToken(), req.GetAppName(), req.GetRole(), req.GetPermission()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.GrantPermissionResponse{}, nil
+}
+
+func (s *ServerAPI) RevokePermission(ctx context.Context, req *ssov1.RevokePermissionRequest) (*ssov1.RevokePermissionResponse, error) {
+	if err := validateRolePermission(req.GetAdminToken(), req.GetRole(), req.GetPermission()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.RevokePermission(ctx, req.GetAdminToken(), req.GetAppName(), req.GetRole(), req.GetPermission()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.RevokePermissionResponse{}, nil
+}
+
+func (s *ServerAPI) AssignRole(ctx context.Context, req *ssov1.AssignRoleRequest) (*ssov1.AssignRoleResponse, error) {
+	if err := validateRoleAssignment(req.GetAdminToken(), req.GetUserId(), req.GetRole()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.AssignRole(ctx, req.GetAdminToken(), req.GetUserId(), req.GetAppName(), req.GetRole()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.AssignRoleResponse{}, nil
+}
+
+func (s *ServerAPI) UnassignRole(ctx context.Context, req *ssov1.UnassignRoleRequest) (*ssov1.UnassignRoleResponse, error) {
+	if err := validateRoleAssignment(req.GetAdminToken(), req.GetUserId(), req.GetRole()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.UnassignRole(ctx, req.GetAdminToken(), req.GetUserId(), req.GetAppName(), req.GetRole()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.UnassignRoleResponse{}, nil
+}
+
+func (s *ServerAPI) UserRoles(ctx context.Context, req *ssov1.UserRolesRequest) (*ssov1.UserRolesResponse, error) {
+	if err := validateAdminUser(req.GetAdminToken(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.rbac.UserRoles(ctx, req.GetAdminToken(), req.GetUserId(), req.GetAppName())
+	if err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.UserRolesResponse{Roles: rolesToProto(roles)}, nil
+}
+
+func (s *ServerAPI) GrantAdmin(ctx context.Context, req *ssov1.GrantAdminRequest) (*ssov1.GrantAdminResponse, error) {
+	if err := validateAdminUser(req.GetAdminToken(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.GrantAdmin(ctx, req.GetAdminToken(), req.GetUserId(), req.GetAppName()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.GrantAdminResponse{}, nil
+}
+
+func (s *ServerAPI) RevokeAdmin(ctx context.Context, req *ssov1.RevokeAdminRequest) (*ssov1.RevokeAdminResponse, error) {
+	if err := validateAdminUser(req.GetAdminToken(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbac.RevokeAdmin(ctx, req.GetAdminToken(), req.GetUserId(), req.GetAppName()); err != nil {
+		return nil, rbacError(err)
+	}
+
+	return &ssov1.RevokeAdminResponse{}, nil
+}
+
+func roleToProto(role models.Role) *ssov1.Role {
+	return &ssov1.Role{
+		RoleId:      role.ID.String(),
+		AppId:       idOrEmpty(role.AppID),
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.Permissions,
+		CreatedAt:   unixOrZero(role.CreatedAt),
+	}
+}
+
+func rolesToProto(roles []models.Role) []*ssov1.Role {
+	result := make([]*ssov1.Role, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, roleToProto(role))
+	}
+
+	return result
+}
+
+func permissionToProto(permission models.Permission) *ssov1.Permission {
+	return &ssov1.Permission{
+		PermissionId: permission.ID.String(),
+		AppId:        idOrEmpty(permission.AppID),
+		Name:         permission.Name,
+		Description:  permission.Description,
+		CreatedAt:    unixOrZero(permission.CreatedAt),
+	}
+}
+
+// idOrEmpty converts an ID of a response to a string, keeping the zero ID of the global scope empty.
+func idOrEmpty(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+
+	return id.String()
+}
+
+// rbacError maps errors of the RBAC service to gRPC statuses.
+func rbacError(err error) error {
+	switch {
+	case errors.Is(err, rbac.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, rbac.ErrAppNotFound):
+		return status.Error(codes.NotFound, "app not found")
+	case errors.Is(err, rbac.ErrAppDisabled):
+		return status.Error(codes.FailedPrecondition, "app is disabled")
+	case errors.Is(err, rbac.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, rbac.ErrInvalidName):
+		return status.Error(codes.InvalidArgument, "invalid name")
+	case errors.Is(err, rbac.ErrRoleExists):
+		return status.Error(codes.AlreadyExists, "role already exists")
+	case errors.Is(err, rbac.ErrRoleNotFound):
+		return status.Error(codes.NotFound, "role not found")
+	case errors.Is(err, rbac.ErrPermissionExists):
+		return status.Error(codes.AlreadyExists, "permission already exists")
+	case errors.Is(err, rbac.ErrPermissionNotFound):
+		return status.Error(codes.NotFound, "permission not found")
+	case errors.Is(err, rbac.ErrReservedRole):
+		return status.Error(codes.FailedPrecondition, "role is reserved")
+	case errors.Is(err, rbac.ErrGroupExists):
+		return status.Error(codes.AlreadyExists, "group already exists")
+	case errors.Is(err, rbac.ErrGroupNotFound):
+		return status.Error(codes.NotFound, "group not found")
+	case errors.Is(err, rbac.ErrGroupCycle):
+		return status.Error(codes.FailedPrecondition, "group cannot contain itself")
+	case errors.Is(err, rbac.ErrMembershipNotFound):
+		return status.Error(codes.NotFound, "group membership not found")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func validateAdminTokenAndName(adminToken string, name string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "name required")
+	}
+
+	return nil
+}
+
+func validateRolePermission(adminToken string, role string, permission string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if role == "" {
+		return status.Error(codes.InvalidArgument, "role required")
+	}
+
+	if permission == "" {
+		return status.Error(codes.InvalidArgument, "permission required")
+	}
+
+	return nil
+}
+
+func validateRoleAssignment(adminToken string, userID string, role string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if userID == "" {
+		return status.Error(codes.InvalidArgument, "user_id required")
+	}
+
+	if role == "" {
+		return status.Error(codes.InvalidArgument, "role required")
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/rbac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// fakeRBAC records the last call and fails every call with err. Methods a test doesn't
+// override panic through the nil embedded RBAC.
+type fakeRBAC struct {
+	RBAC
+	err     error
+	appName string
+	role    string
+}
+
+func (f *fakeRBAC) ListRoles(_ context.Context, _ string, appName string) ([]models.Role, error) {
+	f.appName = appName
+
+	return []models.Role{
+		{ID: uuid.New(), Name: "viewer", Permissions: []string{"invoices:read"}, CreatedAt: time.Unix(100, 0)},
+	}, f.err
+}
+
+func (f *fakeRBAC) AssignRole(_ context.Context, _ string, _ string, appName string, roleName string) error {
+	f.appName, f.role = appName, roleName
+
+	return f.err
+}
+
+func (f *fakeRBAC) UserRoles(_ context.Context, _ string, _ string, appName string) ([]models.Role, error) {
+	f.appName = appName
+
+	return []models.Role{
+		{ID: uuid.New(), Name: models.RoleAdmin, CreatedAt: time.Unix(100, 0)},
+	}, f.err
+}
+
+func (f *fakeRBAC) GrantAdmin(_ context.Context, _ string, _ string, appName string) error {
+	f.appName, f.role = appName, models.RoleAdmin
+
+	return f.err
+}
+
+func (f *fakeRBAC) RevokeAdmin(_ context.Context, _ string, _ string, appName string) error {
+	f.appName, f.role = appName, models.RoleAdmin
+
+	return f.err
+}
+
+func (f *fakeRBAC) CheckPermissions(_ context.Context, _ string, appName string, permissions []string) ([]bool, error) {
+	f.appName = appName
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	allowed := make([]bool, len(permissions))
+	for i, permission := range permissions {
+		allowed[i] = permission == "invoices:read"
+	}
+
+	return allowed, nil
+}
+
+func TestCheckPermissions(t *testing.T) {
+	fake := &fakeRBAC{}
+	srv := &ServerAPI{rbac: fake}
+
+	resp, err := srv.CheckPermissions(context.Background(), &ssov1.CheckPermissionsRequest{
+		UserId:      uuid.NewString(),
+		AppName:     "app",
+		Permissions: []string{"invoices:read", "invoices:write"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "app", fake.appName)
+	assert.Equal(t, []bool{true, false}, resp.GetAllowed())
+}
+
+func TestCheckPermissions_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.CheckPermissionsRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no user", req: &ssov1.CheckPermissionsRequest{AppName: "app"}, want: codes.InvalidArgument},
+		{name: "no app", req: &ssov1.CheckPermissionsRequest{UserId: "user"}, want: codes.InvalidArgument},
+		{
+			name: "unknown app",
+			req:  &ssov1.CheckPermissionsRequest{UserId: "user", AppName: "app"},
+			err:  rbac.ErrAppNotFound,
+			want: codes.NotFound,
+		},
+		{
+			name: "disabled app",
+			req:  &ssov1.CheckPermissionsRequest{UserId: "user", AppName: "app"},
+			err:  rbac.ErrAppDisabled,
+			want: codes.FailedPrecondition,
+		},
+		{
+			name: "unknown user",
+			req:  &ssov1.CheckPermissionsRequest{UserId: "user", AppName: "app"},
+			err:  rbac.ErrUserNotFound,
+			want: codes.NotFound,
+		},
+		{
+			name: "storage failure",
+			req:  &ssov1.CheckPermissionsRequest{UserId: "user", AppName: "app"},
+			err:  errors.New("boom"),
+			want: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &ServerAPI{rbac: &fakeRBAC{err: tt.err}}
+
+			_, err := srv.CheckPermissions(context.Background(), tt.req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestListRoles(t *testing.T) {
+	fake := &fakeRBAC{}
+	srv := &ServerAPI{rbac: fake}
+
+	resp, err := srv.ListRoles(context.Background(), &ssov1.ListRolesRequest{AdminToken: "token"})
+	require.NoError(t, err)
+	assert.Empty(t, fake.appName, "empty app selects the global scope")
+
+	require.Len(t, resp.GetRoles(), 1)
+	role := resp.GetRoles()[0]
+	assert.Equal(t, "viewer", role.GetName())
+	assert.Empty(t, role.GetAppId())
+	assert.Equal(t, []string{"invoices:read"}, role.GetPermissions())
+	assert.Equal(t, int64(100), role.GetCreatedAt())
+}
+
+func TestAssignRole(t *testing.T) {
+	fake := &fakeRBAC{}
+	srv := &ServerAPI{rbac: fake}
+
+	_, err := srv.AssignRole(context.Background(), &ssov1.AssignRoleRequest{
+		AdminToken: "token",
+		UserId:     uuid.NewString(),
+		AppName:    "app",
+		Role:       "viewer",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "app", fake.appName)
+	assert.Equal(t, "viewer", fake.role)
+}
+
+func TestAssignRole_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.AssignRoleRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.AssignRoleRequest{UserId: "user", Role: "viewer"}, want: codes.InvalidArgument},
+		{name: "no user", req: &ssov1.AssignRoleRequest{AdminToken: "token", Role: "viewer"}, want: codes.InvalidArgument},
+		{name: "no role", req: &ssov1.AssignRoleRequest{AdminToken: "token", UserId: "user"}, want: codes.InvalidArgument},
+		{name: "not admin", err: rbac.ErrPermissionDenied, want: codes.PermissionDenied},
+		{name: "unknown app", err: rbac.ErrAppNotFound, want: codes.NotFound},
+		{name: "unknown user", err: rbac.ErrUserNotFound, want: codes.NotFound},
+		{name: "unknown role", err: rbac.ErrRoleNotFound, want: codes.NotFound},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.AssignRoleRequest{AdminToken: "token", UserId: "user", Role: "viewer"}
+			}
+
+			srv := &ServerAPI{rbac: &fakeRBAC{err: tt.err}}
+
+			_, err := srv.AssignRole(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestUserRoles(t *testing.T) {
+	fake := &fakeRBAC{}
+	srv := &ServerAPI{rbac: fake}
+
+	resp, err := srv.UserRoles(context.Background(), &ssov1.UserRolesRequest{
+		AdminToken: "token",
+		UserId:     uuid.NewString(),
+		AppName:    "app",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "app", fake.appName)
+
+	require.Len(t, resp.GetRoles(), 1)
+	assert.Equal(t, models.RoleAdmin, resp.GetRoles()[0].GetName())
+}
+
+func TestGrantAdmin(t *testing.T) {
+	fake := &fakeRBAC{}
+	srv := &ServerAPI{rbac: fake}
+
+	_, err := srv.GrantAdmin(context.Background(), &ssov1.GrantAdminRequest{
+		AdminToken: "token",
+		UserId:     uuid.NewString(),
+		AppName:    "app",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "app", fake.appName)
+	assert.Equal(t, models.RoleAdmin, fake.role)
+}
+
+func TestRevokeAdmin_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.RevokeAdminRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.RevokeAdminRequest{UserId: "user"}, want: codes.InvalidArgument},
+		{name: "no user", req: &ssov1.RevokeAdminRequest{AdminToken: "token"}, want: codes.InvalidArgument},
+		{name: "not admin", err: rbac.ErrPermissionDenied, want: codes.PermissionDenied},
+		{name: "own admin role", err: rbac.ErrReservedRole, want: codes.FailedPrecondition},
+		{name: "not granted", err: rbac.ErrRoleNotFound, want: codes.NotFound},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.RevokeAdminRequest{AdminToken: "token", UserId: "user"}
+			}
+
+			srv := &ServerAPI{rbac: &fakeRBAC{err: tt.err}}
+
+			_, err := srv.RevokeAdmin(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestRBACError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{err: rbac.ErrInvalidName, want: codes.InvalidArgument},
+		{err: rbac.ErrRoleExists, want: codes.AlreadyExists},
+		{err: rbac.ErrPermissionExists, want: codes.AlreadyExists},
+		{err: rbac.ErrPermissionNotFound, want: codes.NotFound},
+		{err: rbac.ErrReservedRole, want: codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assertCode(t, tt.want, rbacError(tt.err))
+		})
+	}
+}
@@ -3,27 +3,215 @@ package auth
 import (
 	"context"
 	"errors"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/email"
+	"github.com/sol1corejz/auth-service/internal/lib/pat"
+	"github.com/sol1corejz/auth-service/internal/lib/username"
 	"github.com/sol1corejz/auth-service/internal/services/auth"
-	ssov1 "github.com/sol1corejz/sso-protos/gen/go/sso"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trailer keys of the MFA challenge returned by Login and CompletePhoneLogin with codes.FailedPrecondition.
+const (
+	mfaChallengeIDKey        = "x-mfa-challenge-id"
+	mfaEnrollmentRequiredKey = "x-mfa-enrollment-required"
+	mfaMethodsKey            = "x-mfa-methods"
 )
 
 type Auth interface {
-	Login(ctx context.Context, email string, password string, appID string) (acessToken string, refreshToken string, err error)
+	Login(ctx context.Context, login string, password string, appIDOrName string) (acessToken string, refreshToken string, err error)
 	RegisterNewUser(ctx context.Context, email string, password string) (userID string, err error)
-	IsAdmin(ctx context.Context, userID string) (bool, error)
+	SetUsername(ctx context.Context, accessToken string, username string) error
+	// IsAdmin checks admin status within the app, or across all apps if appName is empty.
+	IsAdmin(ctx context.Context, userID string, appName string) (bool, error)
 	CheckAndRefreshTokens(ctx context.Context, accessToken string, refreshToken string) (bool, string, string, error)
+	Introspect(ctx context.Context, accessToken string, minACR models.ACR, maxAge time.Duration) (models.TokenInfo, error)
+}
+
+// PhoneAuth backs phone login and phone verification RPCs.
+type PhoneAuth interface {
+	StartPhoneLogin(ctx context.Context, phone string) error
+	CompletePhoneLogin(ctx context.Context, phone string, code string, appName string) (accessToken string, refreshToken string, err error)
+	StartPhoneVerification(ctx context.Context, accessToken string, phone string) error
+	ConfirmPhone(ctx context.Context, accessToken string, phone string, code string) error
+}
+
+// Admin backs user management RPCs. AdminToken is the caller's access token.
+type Admin interface {
+	ActivateUser(ctx context.Context, adminToken string, userID string) error
+	SuspendUser(ctx context.Context, adminToken string, userID string, reason string, until time.Time) error
+	DeactivateUser(ctx context.Context, adminToken string, userID string, reason string) error
+	ListUsers(ctx context.Context, adminToken string, filter models.UserFilter) (users []models.User, nextCursor string, err error)
+	SetAppMFAPolicy(ctx context.Context, adminToken string, appName string, policy models.MFAPolicy) error
+	SetAppWebAuthn(ctx context.Context, adminToken string, appName string, rpID string, origins []string) error
+	SetUserAttributes(ctx context.Context, adminToken string, userID string, attributes map[string]any) error
+	CreateApp(ctx context.Context, adminToken string, name string, description string, ownerID string) (models.App, error)
+	GetApp(ctx context.Context, adminToken string, app string) (models.App, error)
+	ListApps(ctx context.Context, adminToken string) ([]models.App, error)
+	UpdateApp(ctx context.Context, adminToken string, app string, update models.AppUpdate) (models.App, error)
+	DeleteApp(ctx context.Context, adminToken string, app string) error
+	AddAppMember(ctx context.Context, adminToken string, app string, userID string) error
+	RemoveAppMember(ctx context.Context, adminToken string, app string, userID string) error
+	ListAppMembers(ctx context.Context, adminToken string, app string) ([]models.AppMember, error)
+	CreateClientSecret(ctx context.Context, adminToken string, app string, name string, ttl time.Duration) (meta models.ClientSecret, secret string, err error)
+	ListClientSecrets(ctx context.Context, adminToken string, app string) ([]models.ClientSecret, error)
+	RevokeClientSecret(ctx context.Context, adminToken string, app string, secretID string) error
+	CreateServiceAccount(ctx context.Context, adminToken string, name string, description string) (models.ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context, adminToken string) ([]models.ServiceAccount, error)
+	DeleteServiceAccount(ctx context.Context, adminToken string, account string) error
+	AddServiceAccountKey(ctx context.Context, adminToken string, account string, publicKeyPEM string, ttl time.Duration) (models.ServiceAccountKey, error)
+	ListServiceAccountKeys(ctx context.Context, adminToken string, account string) ([]models.ServiceAccountKey, error)
+	RemoveServiceAccountKey(ctx context.Context, adminToken string, account string, keyID string) error
+}
+
+// MFA backs TOTP and email MFA enrollment, recovery codes and the second step of login.
+type MFA interface {
+	BeginTOTPEnrollment(ctx context.Context, accessToken string) (secret string, uri string, err error)
+	BeginChallengeTOTPEnrollment(ctx context.Context, challengeID string) (secret string, uri string, err error)
+	ConfirmTOTPEnrollment(ctx context.Context, accessToken string, code string) (recoveryCodes []string, err error)
+	RegenerateRecoveryCodes(ctx context.Context, accessToken string, code string) (recoveryCodes []string, err error)
+	DisableTOTP(ctx context.Context, accessToken string, code string) error
+	SendEmailMFACode(ctx context.Context, accessToken string) error
+	ConfirmEmailMFA(ctx context.Context, accessToken string, code string) (recoveryCodes []string, err error)
+	DisableEmailMFA(ctx context.Context, accessToken string, code string) error
+	SendChallengeEmailCode(ctx context.Context, challengeID string) error
+	StepUp(ctx context.Context, accessToken string, code string) (stepUpToken string, err error)
+	CompleteMFA(ctx context.Context, challengeID string, code string) (accessToken string, refreshToken string, recoveryCodes []string, err error)
+}
+
+// Passkey backs WebAuthn ceremonies. Options and responses are the JSON the browser API
+// takes and returns, so clients pass them through without decoding.
+type Passkey interface {
+	BeginPasskeyRegistration(ctx context.Context, accessToken string) (options []byte, sessionID string, err error)
+	FinishPasskeyRegistration(ctx context.Context, accessToken string, sessionID string, response []byte, name string) error
+	BeginPasskeyLogin(ctx context.Context, appName string) (options []byte, sessionID string, err error)
+	FinishPasskeyLogin(ctx context.Context, sessionID string, response []byte) (accessToken string, refreshToken string, err error)
+	BeginPasskeyStepUp(ctx context.Context, accessToken string) (options []byte, sessionID string, err error)
+	FinishPasskeyStepUp(ctx context.Context, accessToken string, sessionID string, response []byte) (stepUpToken string, err error)
+}
+
+// RBAC backs role, permission and group management RPCs and CheckPermissions.
+// Empty app name selects the global scope.
+type RBAC interface {
+	CheckPermissions(ctx context.Context, userID string, appName string, permissions []string) (allowed []bool, err error)
+	CreateRole(ctx context.Context, adminToken string, appName string, name string, description string) (models.Role, error)
+	DeleteRole(ctx context.Context, adminToken string, appName string, name string) error
+	ListRoles(ctx context.Context, adminToken string, appName string) ([]models.Role, error)
+	CreatePermission(ctx context.Context, adminToken string, appName string, name string, description string) (models.Permission, error)
+	DeletePermission(ctx context.Context, adminToken string, appName string, name string) error
+	ListPermissions(ctx context.Context, adminToken string, appName string) ([]models.Permission, error)
+	GrantPermission(ctx context.Context, adminToken string, appName string, roleName string, permissionName string) error
+	RevokePermission(ctx context.Context, adminToken string, appName string, roleName string, permissionName string) error
+	AssignRole(ctx context.Context, adminToken string, userID string, appName string, roleName string) error
+	UnassignRole(ctx context.Context, adminToken string, userID string, appName string, roleName string) error
+	GrantAdmin(ctx context.Context, adminToken string, userID string, appName string) error
+	RevokeAdmin(ctx context.Context, adminToken string, userID string, appName string) error
+	UserRoles(ctx context.Context, adminToken string, userID string, appName string) ([]models.Role, error)
+	CreateGroup(ctx context.Context, adminToken string, name string, description string) (models.Group, error)
+	DeleteGroup(ctx context.Context, adminToken string, name string) error
+	AddGroupMember(ctx context.Context, adminToken string, groupName string, userID string) error
+	RemoveGroupMember(ctx context.Context, adminToken string, groupName string, userID string) error
+	AddSubgroup(ctx context.Context, adminToken string, groupName string, subgroupName string) error
+	RemoveSubgroup(ctx context.Context, adminToken string, groupName string, subgroupName string) error
+	AssignGroupRole(ctx context.Context, adminToken string, groupName string, appName string, roleName string) error
+	UnassignGroupRole(ctx context.Context, adminToken string, groupName string, appName string, roleName string) error
+	UserGroups(ctx context.Context, adminToken string, userID string) ([]models.Group, error)
+}
+
+// Org backs organization RPCs. Org admins manage members only with a token scoped to their
+// organization, which LoginToOrganization exchanges a regular session for. Added members
+// join once they accept the invitation.
+type Org interface {
+	CreateOrganization(ctx context.Context, adminToken string, name string, adminID string) (models.Organization, error)
+	UserOrganizations(ctx context.Context, accessToken string) ([]models.Organization, error)
+	Invitations(ctx context.Context, accessToken string) ([]models.Organization, error)
+	AcceptInvitation(ctx context.Context, accessToken string, orgID string) error
+	LoginToOrganization(ctx context.Context, accessToken string, orgID string) (orgAccessToken string, orgRefreshToken string, err error)
+	ListMembers(ctx context.Context, adminToken string, orgID string) ([]models.OrgMember, error)
+	AddMember(ctx context.Context, adminToken string, orgID string, userID string, role models.OrgRole) error
+	SetMemberRole(ctx context.Context, adminToken string, orgID string, userID string, role models.OrgRole) error
+	RemoveMember(ctx context.Context, adminToken string, orgID string, userID string) error
 }
 
+// Authz backs the Authorize RPC, which evaluates attribute-based policy rules.
+type Authz interface {
+	Authorize(
+		ctx context.Context,
+		userID string,
+		appName string,
+		action string,
+		resource map[string]any,
+		reqContext map[string]any,
+	) (models.AuthzDecision, error)
+}
+
+// ClientCredentials backs the ClientCredentials RPC, which issues tokens to apps acting on their
+// own behalf. The same grant is served over HTTP at /oauth/token.
+type ClientCredentials interface {
+	ClientCredentials(ctx context.Context, clientID string, clientSecret string, scopes []string) (models.ClientToken, error)
+}
+
+// PersonalAccessTokens backs personal access token RPCs. AccessToken is the caller's session
+// token; the personal access token itself is returned only by CreatePersonalAccessToken.
+type PersonalAccessTokens interface {
+	CreatePersonalAccessToken(
+		ctx context.Context,
+		accessToken string,
+		name string,
+		scopes []string,
+		ttl time.Duration,
+	) (meta models.PersonalAccessToken, token string, err error)
+	ListPersonalAccessTokens(ctx context.Context, accessToken string) ([]models.PersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, accessToken string, tokenID string) error
+}
+
+// ServerAPI implements ssov1.AuthServer.
 type ServerAPI struct {
 	ssov1.UnimplementedAuthServer
-	auth Auth
+	auth      Auth
+	phoneAuth PhoneAuth
+	admin     Admin
+	mfa       MFA
+	passkey   Passkey
+	rbac      RBAC
+	org       Org
+	authz     Authz
+	clients   ClientCredentials
+	tokens    PersonalAccessTokens
 }
 
-func Register(gRPC *grpc.Server, auth Auth) {
-	ssov1.RegisterAuthServer(gRPC, &ServerAPI{auth: auth})
+func Register(
+	gRPC *grpc.Server,
+	auth Auth,
+	phoneAuth PhoneAuth,
+	admin Admin,
+	mfa MFA,
+	passkey Passkey,
+	rbac RBAC,
+	org Org,
+	authz Authz,
+	clients ClientCredentials,
+	tokens PersonalAccessTokens,
+) {
+	ssov1.RegisterAuthServer(gRPC, &ServerAPI{
+		auth:      auth,
+		phoneAuth: phoneAuth,
+		admin:     admin,
+		mfa:       mfa,
+		passkey:   passkey,
+		rbac:      rbac,
+		org:       org,
+		authz:     authz,
+		clients:   clients,
+		tokens:    tokens,
+	})
 }
 
 func (s *ServerAPI) Login(ctx context.Context, req *ssov1.LoginRequest) (*ssov1.LoginResponse, error) {
@@ -37,6 +225,22 @@ func (s *ServerAPI) Login(ctx context.Context, req *ssov1.LoginRequest) (*ssov1.
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
 		}
+		if errors.Is(err, auth.ErrUserInactive) {
+			return nil, status.Error(codes.PermissionDenied, "user is not active")
+		}
+		if errors.Is(err, auth.ErrInvalidAppID) {
+			return nil, status.Error(codes.InvalidArgument, "invalid app")
+		}
+		if errors.Is(err, auth.ErrAppDisabled) {
+			return nil, status.Error(codes.FailedPrecondition, "app is disabled")
+		}
+		if errors.Is(err, auth.ErrAppAccessDenied) {
+			return nil, status.Error(codes.PermissionDenied, "user is not allowed into the app")
+		}
+		var mfaErr *auth.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return nil, mfaRequired(ctx, mfaErr)
+		}
 
 		return nil, status.Error(codes.Internal, "internal error")
 	}
@@ -66,16 +270,41 @@ func (s *ServerAPI) Register(ctx context.Context, req *ssov1.RegisterRequest) (*
 	}, nil
 }
 
+func (s *ServerAPI) SetUsername(ctx context.Context, req *ssov1.SetUsernameRequest) (*ssov1.SetUsernameResponse, error) {
+	if err := validateSetUsername(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.SetUsername(ctx, req.GetAccessToken(), req.GetUsername()); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if errors.Is(err, auth.ErrInvalidUsername) {
+			return nil, status.Error(codes.InvalidArgument, "invalid username")
+		}
+		if errors.Is(err, auth.ErrUsernameTaken) {
+			return nil, status.Error(codes.AlreadyExists, "username already taken")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &ssov1.SetUsernameResponse{}, nil
+}
+
 func (s *ServerAPI) IsAdmin(ctx context.Context, req *ssov1.IsAdminRequest) (*ssov1.IsAdminResponse, error) {
 	if err := validateIsAdmin(req); err != nil {
 		return nil, err
 	}
 
-	isAdmin, err := s.auth.IsAdmin(ctx, req.GetUserId())
+	isAdmin, err := s.auth.IsAdmin(ctx, req.GetUserId(), req.GetAppName())
 	if err != nil {
 		if errors.Is(err, auth.ErrUserNotFound) {
 			return nil, status.Error(codes.NotFound, "user not found")
 		}
+		if errors.Is(err, auth.ErrInvalidAppID) {
+			return nil, status.Error(codes.InvalidArgument, "invalid app")
+		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
@@ -94,31 +323,114 @@ func (s *ServerAPI) CheckAndRefreshTokens(ctx context.Context, req *ssov1.TokenC
 
 	isValid, newAccessToken, newRefreshToken, err := s.auth.CheckAndRefreshTokens(ctx, req.GetAccessToken(), req.GetRefreshToken())
 	if err != nil {
-		return &ssov1.TokenCheckResponse{
-			IsValid:         false,
-			NewAccessToken:  "",
-			NewRefreshToken: "",
-		}, err
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if errors.Is(err, auth.ErrUserInactive) {
+			return nil, status.Error(codes.PermissionDenied, "user is not active")
+		}
+		if errors.Is(err, auth.ErrAppDisabled) {
+			return nil, status.Error(codes.FailedPrecondition, "app is disabled")
+		}
+		if errors.Is(err, auth.ErrAppAccessDenied) {
+			return nil, status.Error(codes.PermissionDenied, "user is not allowed into the app")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
 	}
 
 	return &ssov1.TokenCheckResponse{
 		IsValid:         isValid,
 		NewAccessToken:  newAccessToken,
 		NewRefreshToken: newRefreshToken,
-	}, err
+	}, nil
+}
+
+func (s *ServerAPI) Introspect(ctx context.Context, req *ssov1.IntrospectRequest) (*ssov1.IntrospectResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if req.GetMaxAge() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max_age must not be negative")
+	}
+
+	maxAge := time.Duration(req.GetMaxAge()) * time.Second
+
+	info, err := s.auth.Introspect(ctx, req.GetAccessToken(), models.ACR(req.GetMinAcr()), maxAge)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if errors.Is(err, auth.ErrInvalidACR) {
+			return nil, status.Error(codes.InvalidArgument, "invalid acr")
+		}
+		if errors.Is(err, auth.ErrInsufficientAuthentication) {
+			return nil, status.Error(codes.PermissionDenied, "insufficient user authentication")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	amr := make([]string, 0, len(info.Auth.Methods))
+	for _, method := range info.Auth.Methods {
+		amr = append(amr, string(method))
+	}
+
+	return &ssov1.IntrospectResponse{
+		UserId:                info.UserID,
+		Email:                 info.Email,
+		AppId:                 info.AppID,
+		OrgId:                 info.OrgID,
+		PersonalAccessTokenId: info.PersonalAccessTokenID,
+		ServiceAccount:        info.ServiceAccount,
+		IssuedAt:              unixOrZero(info.IssuedAt),
+		ExpiresAt:             unixOrZero(info.ExpiresAt),
+		AuthTime:              unixOrZero(info.Auth.Time),
+		Amr:                   amr,
+		Acr:                   string(info.ACR),
+		Scopes:                info.Auth.Scopes,
+		ClientId:              info.ClientID,
+	}, nil
+}
+
+// mfaRequired returns the status of a login that needs a second factor.
+// LoginResponse has no room for the challenge, so it travels in trailers.
+func mfaRequired(ctx context.Context, mfaErr *auth.MFARequiredError) error {
+	methods := make([]string, 0, len(mfaErr.Methods))
+	for _, method := range mfaErr.Methods {
+		methods = append(methods, string(method))
+	}
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(
+		mfaChallengeIDKey, mfaErr.ChallengeID,
+		mfaEnrollmentRequiredKey, strconv.FormatBool(mfaErr.EnrollmentRequired),
+		mfaMethodsKey, strings.Join(methods, ","),
+	))
+
+	return status.Error(codes.FailedPrecondition, "mfa required")
 }
 
+// validateLogin accepts either email or username in the email field.
 func validateLogin(req *ssov1.LoginRequest) error {
 	if req.GetEmail() == "" {
-		return status.Error(codes.InvalidArgument, "email required")
+		return status.Error(codes.InvalidArgument, "email or username required")
+	}
+
+	if strings.Contains(req.GetEmail(), "@") {
+		if err := email.Validate(email.Normalize(req.GetEmail())); err != nil {
+			return status.Error(codes.InvalidArgument, "invalid email")
+		}
+	} else if err := username.Validate(username.Normalize(req.GetEmail())); errors.Is(err, username.ErrInvalidUsername) {
+		return status.Error(codes.InvalidArgument, "invalid username")
 	}
 
 	if req.GetPassword() == "" {
 		return status.Error(codes.InvalidArgument, "password required")
 	}
 
+	// AppName holds either the app name or its ID.
 	if req.GetAppName() == "" {
-		return status.Error(codes.InvalidArgument, "app name required")
+		return status.Error(codes.InvalidArgument, "app name or id required")
 	}
 
 	return nil
@@ -129,6 +441,10 @@ func validateRegister(req *ssov1.RegisterRequest) error {
 		return status.Error(codes.InvalidArgument, "email required")
 	}
 
+	if err := email.Validate(email.Normalize(req.GetEmail())); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid email")
+	}
+
 	if req.GetPassword() == "" {
 		return status.Error(codes.InvalidArgument, "password required")
 	}
@@ -136,6 +452,18 @@ func validateRegister(req *ssov1.RegisterRequest) error {
 	return nil
 }
 
+func validateSetUsername(req *ssov1.SetUsernameRequest) error {
+	if req.GetAccessToken() == "" {
+		return status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if req.GetUsername() == "" {
+		return status.Error(codes.InvalidArgument, "username required")
+	}
+
+	return nil
+}
+
 func validateIsAdmin(req *ssov1.IsAdminRequest) error {
 	if req.GetUserId() == "" {
 		return status.Error(codes.InvalidArgument, "user_id required")
@@ -149,7 +477,8 @@ func validateTokens(req *ssov1.TokenCheckRequest) error {
 		return status.Error(codes.InvalidArgument, "access_token required")
 	}
 
-	if req.GetRefreshToken() == "" {
+	// Personal access tokens are checked without a refresh token.
+	if req.GetRefreshToken() == "" && !pat.Looks(req.GetAccessToken()) {
 		return status.Error(codes.InvalidArgument, "refresh_token required")
 	}
 
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAuth records the last call and fails every call with err.
+type fakeAuth struct {
+	err      error
+	token    string
+	username string
+	appName  string
+	minACR   models.ACR
+	maxAge   time.Duration
+}
+
+func (f *fakeAuth) Login(context.Context, string, string, string) (string, string, error) {
+	return "access", "refresh", f.err
+}
+
+func (f *fakeAuth) RegisterNewUser(context.Context, string, string) (string, error) {
+	return "user-id", f.err
+}
+
+func (f *fakeAuth) SetUsername(_ context.Context, accessToken string, username string) error {
+	f.token, f.username = accessToken, username
+
+	return f.err
+}
+
+func (f *fakeAuth) IsAdmin(_ context.Context, _ string, appName string) (bool, error) {
+	f.appName = appName
+
+	return true, f.err
+}
+
+func (f *fakeAuth) CheckAndRefreshTokens(context.Context, string, string) (bool, string, string, error) {
+	return true, "", "", f.err
+}
+
+func (f *fakeAuth) Introspect(_ context.Context, _ string, minACR models.ACR, maxAge time.Duration) (models.TokenInfo, error) {
+	f.minACR, f.maxAge = minACR, maxAge
+
+	return models.TokenInfo{
+		UserID:    "user-id",
+		AppID:     "app-id",
+		IssuedAt:  time.Unix(100, 0),
+		ExpiresAt: time.Unix(200, 0),
+		Auth: models.Authentication{
+			Methods: []models.AuthMethod{models.AuthMethodPassword, models.AuthMethodOTP},
+			Time:    time.Unix(50, 0),
+		},
+		ACR: models.ACRStepUp,
+	}, f.err
+}
+
+// assertCode checks that err is a gRPC status error with the code.
+func assertCode(t *testing.T, want codes.Code, err error) {
+	t.Helper()
+
+	require.Error(t, err)
+	assert.Equal(t, want, status.Code(err), err.Error())
+}
+
+func TestSetUsername(t *testing.T) {
+	fake := &fakeAuth{}
+	srv := &ServerAPI{auth: fake}
+
+	_, err := srv.SetUsername(context.Background(), &ssov1.SetUsernameRequest{AccessToken: "token", Username: "Alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "token", fake.token)
+	assert.Equal(t, "Alice", fake.username)
+}
+
+func TestSetUsername_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.SetUsernameRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.SetUsernameRequest{Username: "alice"}, want: codes.InvalidArgument},
+		{name: "no username", req: &ssov1.SetUsernameRequest{AccessToken: "token"}, want: codes.InvalidArgument},
+		{name: "invalid token", err: auth.ErrInvalidToken, want: codes.Unauthenticated},
+		{name: "invalid username", err: auth.ErrInvalidUsername, want: codes.InvalidArgument},
+		{name: "taken", err: auth.ErrUsernameTaken, want: codes.AlreadyExists},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.SetUsernameRequest{AccessToken: "token", Username: "alice"}
+			}
+
+			srv := &ServerAPI{auth: &fakeAuth{err: tt.err}}
+
+			_, err := srv.SetUsername(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	fake := &fakeAuth{}
+	srv := &ServerAPI{auth: fake}
+
+	resp, err := srv.IsAdmin(context.Background(), &ssov1.IsAdminRequest{UserId: "user-id", AppName: "app"})
+	require.NoError(t, err)
+	assert.True(t, resp.GetIsAdmin())
+	assert.Equal(t, "app", fake.appName)
+}
+
+func TestIsAdmin_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.IsAdminRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no user", req: &ssov1.IsAdminRequest{AppName: "app"}, want: codes.InvalidArgument},
+		{name: "unknown user", err: auth.ErrUserNotFound, want: codes.NotFound},
+		{name: "unknown app", err: auth.ErrInvalidAppID, want: codes.InvalidArgument},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.IsAdminRequest{UserId: "user-id", AppName: "app"}
+			}
+
+			srv := &ServerAPI{auth: &fakeAuth{err: tt.err}}
+
+			_, err := srv.IsAdmin(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	fake := &fakeAuth{}
+	srv := &ServerAPI{auth: fake}
+
+	resp, err := srv.Introspect(context.Background(), &ssov1.IntrospectRequest{AccessToken: "token", MinAcr: "3", MaxAge: 300})
+	require.NoError(t, err)
+	assert.Equal(t, models.ACRStepUp, fake.minACR)
+	assert.Equal(t, 5*time.Minute, fake.maxAge)
+
+	assert.Equal(t, "user-id", resp.GetUserId())
+	assert.Equal(t, "app-id", resp.GetAppId())
+	assert.Equal(t, int64(100), resp.GetIssuedAt())
+	assert.Equal(t, int64(200), resp.GetExpiresAt())
+	assert.Equal(t, int64(50), resp.GetAuthTime())
+	assert.Equal(t, []string{"pwd", "otp"}, resp.GetAmr())
+	assert.Equal(t, "3", resp.GetAcr())
+}
+
+func TestCheckAndRefreshTokens_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.TokenCheckRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no access token", req: &ssov1.TokenCheckRequest{RefreshToken: "refresh"}, want: codes.InvalidArgument},
+		{name: "no refresh token", req: &ssov1.TokenCheckRequest{AccessToken: "token"}, want: codes.InvalidArgument},
+		{name: "invalid token", err: auth.ErrInvalidToken, want: codes.Unauthenticated},
+		{name: "inactive user", err: auth.ErrUserInactive, want: codes.PermissionDenied},
+		{name: "disabled app", err: auth.ErrAppDisabled, want: codes.FailedPrecondition},
+		{name: "not allowed into app", err: auth.ErrAppAccessDenied, want: codes.PermissionDenied},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.TokenCheckRequest{AccessToken: "token", RefreshToken: "refresh"}
+			}
+
+			srv := &ServerAPI{auth: &fakeAuth{err: tt.err}}
+
+			_, err := srv.CheckAndRefreshTokens(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestIntrospect_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.IntrospectRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.IntrospectRequest{}, want: codes.InvalidArgument},
+		{name: "negative max age", req: &ssov1.IntrospectRequest{AccessToken: "token", MaxAge: -1}, want: codes.InvalidArgument},
+		{name: "invalid token", err: auth.ErrInvalidToken, want: codes.Unauthenticated},
+		{name: "invalid acr", err: auth.ErrInvalidACR, want: codes.InvalidArgument},
+		{name: "insufficient", err: auth.ErrInsufficientAuthentication, want: codes.PermissionDenied},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.IntrospectRequest{AccessToken: "token"}
+			}
+
+			srv := &ServerAPI{auth: &fakeAuth{err: tt.err}}
+
+			_, err := srv.Introspect(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
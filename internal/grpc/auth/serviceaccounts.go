@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+func (s *ServerAPI) CreateServiceAccount(ctx context.Context, req *ssov1.CreateServiceAccountRequest) (*ssov1.CreateServiceAccountResponse, error) {
+	if err := validateAdminTokenAndName(req.GetAdminToken(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	account, err := s.admin.CreateServiceAccount(ctx, req.GetAdminToken(), req.GetName(), req.GetDescription())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.CreateServiceAccountResponse{ServiceAccount: serviceAccountToProto(account)}, nil
+}
+
+func (s *ServerAPI) ListServiceAccounts(ctx context.Context, req *ssov1.ListServiceAccountsRequest) (*ssov1.ListServiceAccountsResponse, error) {
+	if req.GetAdminToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	accounts, err := s.admin.ListServiceAccounts(ctx, req.GetAdminToken())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	resp := &ssov1.ListServiceAccountsResponse{ServiceAccounts: make([]*ssov1.ServiceAccount, 0, len(accounts))}
+	for _, account := range accounts {
+		resp.ServiceAccounts = append(resp.ServiceAccounts, serviceAccountToProto(account))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) DeleteServiceAccount(ctx context.Context, req *ssov1.DeleteServiceAccountRequest) (*ssov1.DeleteServiceAccountResponse, error) {
+	if err := validateAdminServiceAccount(req.GetAdminToken(), req.GetAccount()); err != nil {
+		return nil, err
+	}
+
+	if err := s.admin.DeleteServiceAccount(ctx, req.GetAdminToken(), req.GetAccount()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.DeleteServiceAccountResponse{}, nil
+}
+
+func (s *ServerAPI) AddServiceAccountKey(ctx context.Context, req *ssov1.AddServiceAccountKeyRequest) (*ssov1.AddServiceAccountKeyResponse, error) {
+	if err := validateAdminServiceAccount(req.GetAdminToken(), req.GetAccount()); err != nil {
+		return nil, err
+	}
+
+	if req.GetPublicKeyPem() == "" {
+		return nil, status.Error(codes.InvalidArgument, "public_key_pem required")
+	}
+
+	key, err := s.admin.AddServiceAccountKey(
+		ctx,
+		req.GetAdminToken(),
+		req.GetAccount(),
+		req.GetPublicKeyPem(),
+		time.Duration(req.GetTtl())*time.Second,
+	)
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.AddServiceAccountKeyResponse{Key: serviceAccountKeyToProto(key)}, nil
+}
+
+func (s *ServerAPI) ListServiceAccountKeys(ctx context.Context, req *ssov1.ListServiceAccountKeysRequest) (*ssov1.ListServiceAccountKeysResponse, error) {
+	if err := validateAdminServiceAccount(req.GetAdminToken(), req.GetAccount()); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.admin.ListServiceAccountKeys(ctx, req.GetAdminToken(), req.GetAccount())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	resp := &ssov1.ListServiceAccountKeysResponse{Keys: make([]*ssov1.ServiceAccountKey, 0, len(keys))}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, serviceAccountKeyToProto(key))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) RemoveServiceAccountKey(ctx context.Context, req *ssov1.RemoveServiceAccountKeyRequest) (*ssov1.RemoveServiceAccountKeyResponse, error) {
+	if err := validateAdminServiceAccount(req.GetAdminToken(), req.GetAccount()); err != nil {
+		return nil, err
+	}
+
+	if req.GetKeyId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key_id required")
+	}
+
+	if err := s.admin.RemoveServiceAccountKey(ctx, req.GetAdminToken(), req.GetAccount(), req.GetKeyId()); err != nil {
+		return nil, adminError(err)
+	}
+
+	return &ssov1.RemoveServiceAccountKeyResponse{}, nil
+}
+
+func serviceAccountToProto(account models.ServiceAccount) *ssov1.ServiceAccount {
+	return &ssov1.ServiceAccount{
+		AccountId:   account.ID.String(),
+		Name:        account.Name,
+		Description: account.Description,
+		CreatedAt:   unixOrZero(account.CreatedAt),
+	}
+}
+
+func serviceAccountKeyToProto(key models.ServiceAccountKey) *ssov1.ServiceAccountKey {
+	return &ssov1.ServiceAccountKey{
+		KeyId:      key.ID.String(),
+		AccountId:  key.AccountID.String(),
+		Algorithm:  key.Algorithm,
+		PublicKey:  key.PublicKey,
+		ExpiresAt:  unixOrZero(key.ExpiresAt),
+		CreatedAt:  unixOrZero(key.CreatedAt),
+		LastUsedAt: unixOrZero(key.LastUsedAt),
+	}
+}
+
+func validateAdminServiceAccount(adminToken string, account string) error {
+	if adminToken == "" {
+		return status.Error(codes.InvalidArgument, "admin_token required")
+	}
+
+	if account == "" {
+		return status.Error(codes.InvalidArgument, "account required")
+	}
+
+	return nil
+}
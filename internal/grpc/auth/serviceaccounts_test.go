@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func (f *fakeAdmin) CreateServiceAccount(_ context.Context, _ string, name string, description string) (models.ServiceAccount, error) {
+	f.account = name
+
+	return models.ServiceAccount{ID: uuid.New(), Name: name, Description: description, CreatedAt: time.Unix(100, 0)}, f.err
+}
+
+func (f *fakeAdmin) DeleteServiceAccount(_ context.Context, _ string, account string) error {
+	f.account = account
+
+	return f.err
+}
+
+func (f *fakeAdmin) AddServiceAccountKey(_ context.Context, _ string, account string, _ string, ttl time.Duration) (models.ServiceAccountKey, error) {
+	f.account, f.ttl = account, ttl
+
+	return models.ServiceAccountKey{
+		ID:        uuid.New(),
+		AccountID: uuid.New(),
+		PublicKey: []byte("der"),
+		Algorithm: "ES256",
+		ExpiresAt: time.Unix(200, 0),
+	}, f.err
+}
+
+func (f *fakeAdmin) ListServiceAccountKeys(_ context.Context, _ string, account string) ([]models.ServiceAccountKey, error) {
+	f.account = account
+
+	return []models.ServiceAccountKey{{ID: uuid.New(), Algorithm: "RS256"}}, f.err
+}
+
+func (f *fakeAdmin) RemoveServiceAccountKey(_ context.Context, _ string, account string, _ string) error {
+	f.account = account
+
+	return f.err
+}
+
+func TestCreateServiceAccount(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	resp, err := srv.CreateServiceAccount(context.Background(), &ssov1.CreateServiceAccountRequest{
+		AdminToken:  "token",
+		Name:        "billing-job",
+		Description: "Nightly billing",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "billing-job", resp.GetServiceAccount().GetName())
+	assert.Equal(t, "Nightly billing", resp.GetServiceAccount().GetDescription())
+	assert.Equal(t, int64(100), resp.GetServiceAccount().GetCreatedAt())
+}
+
+func TestCreateServiceAccount_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.CreateServiceAccountRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.CreateServiceAccountRequest{Name: "billing-job"}, want: codes.InvalidArgument},
+		{name: "no name", req: &ssov1.CreateServiceAccountRequest{AdminToken: "token"}, want: codes.InvalidArgument},
+		{name: "not admin", err: admin.ErrPermissionDenied, want: codes.PermissionDenied},
+		{name: "taken", err: admin.ErrServiceAccountExists, want: codes.AlreadyExists},
+		{name: "malformed name", err: admin.ErrInvalidServiceAccount, want: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.CreateServiceAccountRequest{AdminToken: "token", Name: "billing-job"}
+			}
+
+			srv := &ServerAPI{admin: &fakeAdmin{err: tt.err}}
+
+			_, err := srv.CreateServiceAccount(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestDeleteServiceAccount(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	_, err := srv.DeleteServiceAccount(context.Background(), &ssov1.DeleteServiceAccountRequest{AdminToken: "token", Account: "billing-job"})
+	require.NoError(t, err)
+	assert.Equal(t, "billing-job", fake.account)
+
+	_, err = srv.DeleteServiceAccount(context.Background(), &ssov1.DeleteServiceAccountRequest{AdminToken: "token"})
+	assertCode(t, codes.InvalidArgument, err)
+
+	srv = &ServerAPI{admin: &fakeAdmin{err: admin.ErrServiceAccountNotFound}}
+	_, err = srv.DeleteServiceAccount(context.Background(), &ssov1.DeleteServiceAccountRequest{AdminToken: "token", Account: "billing-job"})
+	assertCode(t, codes.NotFound, err)
+}
+
+func TestAddServiceAccountKey(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	resp, err := srv.AddServiceAccountKey(context.Background(), &ssov1.AddServiceAccountKeyRequest{
+		AdminToken:   "token",
+		Account:      "billing-job",
+		PublicKeyPem: "-----BEGIN PUBLIC KEY-----",
+		Ttl:          3600,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "billing-job", fake.account)
+	assert.Equal(t, time.Hour, fake.ttl)
+
+	assert.Equal(t, "ES256", resp.GetKey().GetAlgorithm())
+	assert.Equal(t, []byte("der"), resp.GetKey().GetPublicKey())
+	assert.Equal(t, int64(200), resp.GetKey().GetExpiresAt())
+	assert.Zero(t, resp.GetKey().GetLastUsedAt())
+}
+
+func TestAddServiceAccountKey_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.AddServiceAccountKeyRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.AddServiceAccountKeyRequest{Account: "billing-job", PublicKeyPem: "pem"}, want: codes.InvalidArgument},
+		{name: "no account", req: &ssov1.AddServiceAccountKeyRequest{AdminToken: "token", PublicKeyPem: "pem"}, want: codes.InvalidArgument},
+		{name: "no key", req: &ssov1.AddServiceAccountKeyRequest{AdminToken: "token", Account: "billing-job"}, want: codes.InvalidArgument},
+		{name: "unknown account", err: admin.ErrServiceAccountNotFound, want: codes.NotFound},
+		{name: "invalid key", err: admin.ErrInvalidPublicKey, want: codes.InvalidArgument},
+		{name: "invalid ttl", err: admin.ErrInvalidKeyTTL, want: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.AddServiceAccountKeyRequest{AdminToken: "token", Account: "billing-job", PublicKeyPem: "pem"}
+			}
+
+			srv := &ServerAPI{admin: &fakeAdmin{err: tt.err}}
+
+			_, err := srv.AddServiceAccountKey(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestListServiceAccountKeys(t *testing.T) {
+	fake := &fakeAdmin{}
+	srv := &ServerAPI{admin: fake}
+
+	resp, err := srv.ListServiceAccountKeys(context.Background(), &ssov1.ListServiceAccountKeysRequest{AdminToken: "token", Account: "billing-job"})
+	require.NoError(t, err)
+	assert.Equal(t, "billing-job", fake.account)
+	require.Len(t, resp.GetKeys(), 1)
+	assert.Equal(t, "RS256", resp.GetKeys()[0].GetAlgorithm())
+}
+
+func TestRemoveServiceAccountKey_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.RemoveServiceAccountKeyRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no account", req: &ssov1.RemoveServiceAccountKeyRequest{AdminToken: "token", KeyId: "key"}, want: codes.InvalidArgument},
+		{name: "no key", req: &ssov1.RemoveServiceAccountKeyRequest{AdminToken: "token", Account: "billing-job"}, want: codes.InvalidArgument},
+		{name: "unknown key", err: admin.ErrServiceAccountKeyNotFound, want: codes.NotFound},
+		{name: "unknown account", err: admin.ErrServiceAccountNotFound, want: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.RemoveServiceAccountKeyRequest{AdminToken: "token", Account: "billing-job", KeyId: "key"}
+			}
+
+			srv := &ServerAPI{admin: &fakeAdmin{err: tt.err}}
+
+			_, err := srv.RemoveServiceAccountKey(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/pat"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+func (s *ServerAPI) CreatePersonalAccessToken(ctx context.Context, req *ssov1.CreatePersonalAccessTokenRequest) (*ssov1.CreatePersonalAccessTokenResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name required")
+	}
+
+	meta, token, err := s.tokens.CreatePersonalAccessToken(
+		ctx,
+		req.GetAccessToken(),
+		req.GetName(),
+		req.GetScopes(),
+		time.Duration(req.GetTtl())*time.Second,
+	)
+	if err != nil {
+		return nil, patError(err)
+	}
+
+	return &ssov1.CreatePersonalAccessTokenResponse{
+		PersonalAccessToken: personalAccessTokenToProto(meta),
+		Token:               token,
+	}, nil
+}
+
+func (s *ServerAPI) ListPersonalAccessTokens(ctx context.Context, req *ssov1.ListPersonalAccessTokensRequest) (*ssov1.ListPersonalAccessTokensResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	tokens, err := s.tokens.ListPersonalAccessTokens(ctx, req.GetAccessToken())
+	if err != nil {
+		return nil, patError(err)
+	}
+
+	resp := &ssov1.ListPersonalAccessTokensResponse{
+		PersonalAccessTokens: make([]*ssov1.PersonalAccessToken, 0, len(tokens)),
+	}
+	for _, token := range tokens {
+		resp.PersonalAccessTokens = append(resp.PersonalAccessTokens, personalAccessTokenToProto(token))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) RevokePersonalAccessToken(ctx context.Context, req *ssov1.RevokePersonalAccessTokenRequest) (*ssov1.RevokePersonalAccessTokenResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token required")
+	}
+
+	if req.GetTokenId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token_id required")
+	}
+
+	if err := s.tokens.RevokePersonalAccessToken(ctx, req.GetAccessToken(), req.GetTokenId()); err != nil {
+		return nil, patError(err)
+	}
+
+	return &ssov1.RevokePersonalAccessTokenResponse{}, nil
+}
+
+// patError maps errors of the personal access token service to gRPC statuses.
+func patError(err error) error {
+	switch {
+	case errors.Is(err, pat.ErrAccessDenied):
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, pat.ErrUserInactive):
+		return status.Error(codes.PermissionDenied, "user is not active")
+	case errors.Is(err, pat.ErrInvalidName):
+		return status.Error(codes.InvalidArgument, "invalid token name")
+	case errors.Is(err, pat.ErrInvalidScopes):
+		return status.Error(codes.InvalidArgument, "invalid token scopes")
+	case errors.Is(err, pat.ErrInvalidTTL):
+		return status.Error(codes.InvalidArgument, "invalid token ttl")
+	case errors.Is(err, pat.ErrTokenNotFound):
+		return status.Error(codes.NotFound, "personal access token not found")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func personalAccessTokenToProto(token models.PersonalAccessToken) *ssov1.PersonalAccessToken {
+	return &ssov1.PersonalAccessToken{
+		TokenId:    token.ID.String(),
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		ExpiresAt:  unixOrZero(token.ExpiresAt),
+		CreatedAt:  unixOrZero(token.CreatedAt),
+		LastUsedAt: unixOrZero(token.LastUsedAt),
+	}
+}
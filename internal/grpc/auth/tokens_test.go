@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/pat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// fakeTokens records the last call and fails every call with err.
+type fakeTokens struct {
+	err     error
+	name    string
+	scopes  []string
+	ttl     time.Duration
+	tokenID string
+}
+
+func (f *fakeTokens) CreatePersonalAccessToken(
+	_ context.Context,
+	_ string,
+	name string,
+	scopes []string,
+	ttl time.Duration,
+) (models.PersonalAccessToken, string, error) {
+	f.name, f.scopes, f.ttl = name, scopes, ttl
+	if f.err != nil {
+		return models.PersonalAccessToken{}, "", f.err
+	}
+
+	return models.PersonalAccessToken{ID: uuid.New(), Name: name, Scopes: scopes}, "pat_token", nil
+}
+
+func (f *fakeTokens) ListPersonalAccessTokens(context.Context, string) ([]models.PersonalAccessToken, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return []models.PersonalAccessToken{{ID: uuid.New(), Name: "deploy", ExpiresAt: time.Unix(1700000000, 0)}}, nil
+}
+
+func (f *fakeTokens) RevokePersonalAccessToken(_ context.Context, _ string, tokenID string) error {
+	f.tokenID = tokenID
+
+	return f.err
+}
+
+func TestCreatePersonalAccessToken(t *testing.T) {
+	fake := &fakeTokens{}
+	srv := &ServerAPI{tokens: fake}
+
+	resp, err := srv.CreatePersonalAccessToken(context.Background(), &ssov1.CreatePersonalAccessTokenRequest{
+		AccessToken: "token",
+		Name:        "deploy",
+		Scopes:      []string{"reports:read"},
+		Ttl:         3600,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pat_token", resp.GetToken())
+	assert.Equal(t, "deploy", resp.GetPersonalAccessToken().GetName())
+	assert.Equal(t, []string{"reports:read"}, fake.scopes)
+	assert.Equal(t, time.Hour, fake.ttl)
+	assert.Zero(t, resp.GetPersonalAccessToken().GetExpiresAt())
+}
+
+func TestCreatePersonalAccessToken_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.CreatePersonalAccessTokenRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.CreatePersonalAccessTokenRequest{Name: "deploy"}, want: codes.InvalidArgument},
+		{name: "no name", req: &ssov1.CreatePersonalAccessTokenRequest{AccessToken: "token"}, want: codes.InvalidArgument},
+		{name: "invalid session", err: pat.ErrAccessDenied, want: codes.Unauthenticated},
+		{name: "inactive", err: pat.ErrUserInactive, want: codes.PermissionDenied},
+		{name: "invalid name", err: pat.ErrInvalidName, want: codes.InvalidArgument},
+		{name: "invalid scopes", err: pat.ErrInvalidScopes, want: codes.InvalidArgument},
+		{name: "invalid ttl", err: pat.ErrInvalidTTL, want: codes.InvalidArgument},
+		{name: "storage failure", err: errors.New("connection reset"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.CreatePersonalAccessTokenRequest{AccessToken: "token", Name: "deploy"}
+			}
+
+			srv := &ServerAPI{tokens: &fakeTokens{err: tt.err}}
+
+			_, err := srv.CreatePersonalAccessToken(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
+
+func TestListPersonalAccessTokens(t *testing.T) {
+	srv := &ServerAPI{tokens: &fakeTokens{}}
+
+	resp, err := srv.ListPersonalAccessTokens(context.Background(), &ssov1.ListPersonalAccessTokensRequest{AccessToken: "token"})
+	require.NoError(t, err)
+	require.Len(t, resp.GetPersonalAccessTokens(), 1)
+	assert.Equal(t, "deploy", resp.GetPersonalAccessTokens()[0].GetName())
+	assert.Equal(t, int64(1700000000), resp.GetPersonalAccessTokens()[0].GetExpiresAt())
+
+	_, err = srv.ListPersonalAccessTokens(context.Background(), &ssov1.ListPersonalAccessTokensRequest{})
+	assertCode(t, codes.InvalidArgument, err)
+}
+
+func TestRevokePersonalAccessToken(t *testing.T) {
+	fake := &fakeTokens{}
+	srv := &ServerAPI{tokens: fake}
+
+	_, err := srv.RevokePersonalAccessToken(context.Background(), &ssov1.RevokePersonalAccessTokenRequest{
+		AccessToken: "token",
+		TokenId:     "token-id",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "token-id", fake.tokenID)
+}
+
+func TestRevokePersonalAccessToken_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ssov1.RevokePersonalAccessTokenRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "no token", req: &ssov1.RevokePersonalAccessTokenRequest{TokenId: "token-id"}, want: codes.InvalidArgument},
+		{name: "no token id", req: &ssov1.RevokePersonalAccessTokenRequest{AccessToken: "token"}, want: codes.InvalidArgument},
+		{name: "invalid session", err: pat.ErrAccessDenied, want: codes.Unauthenticated},
+		{name: "unknown token", err: pat.ErrTokenNotFound, want: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if req == nil {
+				req = &ssov1.RevokePersonalAccessTokenRequest{AccessToken: "token", TokenId: "token-id"}
+			}
+
+			srv := &ServerAPI{tokens: &fakeTokens{err: tt.err}}
+
+			_, err := srv.RevokePersonalAccessToken(context.Background(), req)
+			assertCode(t, tt.want, err)
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/services/oauth"
+)
+
+// Error codes of RFC 6749, section 4.1.2.1, on top of those shared with the token endpoint.
+const (
+	errAccessDenied            = "access_denied"
+	errUnsupportedResponseType = "unsupported_response_type"
+)
+
+const authorizePath = "/oauth/authorize"
+
+const grantAuthorizationCode = "authorization_code"
+
+// Authorizer backs the authorization code flow.
+type Authorizer interface {
+	ValidateAuthorization(ctx context.Context, req models.AuthorizationRequest) (models.App, models.AuthorizationRequest, error)
+	Authorize(ctx context.Context, req models.AuthorizationRequest, login string, password string) (code string, err error)
+	AuthorizeMFA(ctx context.Context, req models.AuthorizationRequest, challengeID string, code string) (authCode string, err error)
+	ExchangeCode(
+		ctx context.Context,
+		clientID string,
+		code string,
+		redirectURI string,
+		codeVerifier string,
+	) (models.OAuthTokens, error)
+}
+
+// pageHead is shared by the hosted pages.
+const pageHead = `{{define "head"}}<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>
+body { font-family: sans-serif; max-width: 22rem; margin: 4rem auto; padding: 0 1rem; }
+label, input, button { display: block; width: 100%; box-sizing: border-box; margin-top: .5rem; }
+input, button { padding: .5rem; }
+button { margin-top: 1rem; }
+.error { color: #b00020; }
+</style>{{end}}`
+
+// newPage parses a hosted page that can use the "head" template.
+func newPage(name string, body string) *template.Template {
+	return template.Must(template.Must(template.New(name).Parse(pageHead)).Parse(body))
+}
+
+// loginPage is the hosted login page, also showing the outcome of a logout. Parameters of
+// the authorization request travel in hidden fields, so the page keeps no state on the server.
+var loginPage = newPage("login", `<!DOCTYPE html>
+<html lang="en">
+<head>
+{{template "head"}}
+<title>{{if .Title}}{{.Title}}{{else}}Sign in{{with .App}} to {{.}}{{end}}{{end}}</title>
+</head>
+<body>
+{{if .Title}}<h1>{{.Title}}</h1>{{else if .App}}<h1>Sign in to {{.App}}</h1>{{else}}<h1>Sign in</h1>{{end}}
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+{{with .Message}}<p>{{.}}</p>{{end}}
+{{if .Request}}
+<form method="post" action="/oauth/authorize">
+{{range $name, $value := .Request}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}
+<label for="login">Email or username</label>
+<input id="login" name="login" autocomplete="username" required autofocus>
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required>
+<button type="submit">Sign in</button>
+</form>
+{{end}}
+</body>
+</html>
+`)
+
+type loginPageData struct {
+	// Title replaces the sign in heading on pages other than the login form.
+	Title   string
+	App     string
+	Error   string
+	Message string
+	// Request holds parameters of the authorization request; the form is hidden without them.
+	Request map[string]string
+}
+
+// mfaPage asks for the second factor of a login started on the login or the device page. It
+// carries the fields of the form the login started on and the challenge in hidden fields.
+var mfaPage = newPage("mfa", `<!DOCTYPE html>
+<html lang="en">
+<head>
+{{template "head"}}
+<title>Verify it's you</title>
+</head>
+<body>
+<h1>Verify it's you</h1>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+<p>{{if .EmailSent}}Enter the code we sent to your email{{else}}Enter the code from your authenticator app{{end}}, or one of your recovery codes.</p>
+<form method="post" action="{{.Action}}">
+{{range $name, $value := .Request}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}<input type="hidden" name="challenge_id" value="{{.ChallengeID}}">
+<label for="code">Code</label>
+<input id="code" name="code" autocomplete="one-time-code" required autofocus>
+<button type="submit">Verify</button>
+</form>
+</body>
+</html>
+`)
+
+type mfaPageData struct {
+	// Action is the path of the page the login started on.
+	Action      string
+	Error       string
+	EmailSent   bool
+	ChallengeID string
+	Request     map[string]string
+}
+
+// authorize serves the login page for a valid authorization request.
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request) {
+	req := authorizationRequest(r.URL.Query())
+
+	app, req, err := h.authorizer.ValidateAuthorization(r.Context(), req)
+	if err != nil {
+		h.authorizationError(w, r, req, err)
+		return
+	}
+
+	renderLogin(w, http.StatusOK, loginPageData{App: app.Name, Request: requestFields(req)})
+}
+
+// login checks credentials entered on the login page, or the second factor entered on the MFA
+// page, and redirects back to the client with a code.
+func (h *handler) login(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderLogin(w, http.StatusBadRequest, loginPageData{Error: "Malformed request."})
+		return
+	}
+
+	app, req, err := h.authorizer.ValidateAuthorization(r.Context(), authorizationRequest(r.PostForm))
+	if err != nil {
+		h.authorizationError(w, r, req, err)
+		return
+	}
+
+	if challengeID := r.PostForm.Get("challenge_id"); challengeID != "" {
+		h.completeMFA(w, r, app, req, challengeID)
+		return
+	}
+
+	code, err := h.authorizer.Authorize(r.Context(), req, r.PostForm.Get("login"), r.PostForm.Get("password"))
+	if err != nil {
+		var mfaErr *oauth.MFARequiredError
+		var message string
+		switch {
+		case errors.As(err, &mfaErr):
+			renderPage(w, mfaPage, http.StatusOK, mfaPageData{
+				Action:      authorizePath,
+				EmailSent:   mfaErr.EmailSent,
+				ChallengeID: mfaErr.ChallengeID,
+				Request:     requestFields(req),
+			})
+			return
+		case errors.Is(err, oauth.ErrInvalidCredentials):
+			message = "Invalid login or password."
+		case errors.Is(err, oauth.ErrEnrollmentRequired):
+			message = enrollmentRequiredMessage
+		default:
+			h.authorizationError(w, r, req, err)
+			return
+		}
+
+		renderLogin(w, http.StatusUnauthorized, loginPageData{App: app.Name, Error: message, Request: requestFields(req)})
+		return
+	}
+
+	redirect(w, r, req.RedirectURI, url.Values{"code": {code}}, req.State)
+}
+
+// enrollmentRequiredMessage is shown to users who have to set up a second factor, which the
+// hosted pages can't do.
+const enrollmentRequiredMessage = "This account has to set up a second factor before signing in here. " +
+	"Sign in to the application directly to set it up."
+
+// completeMFA checks the second factor entered on the MFA page. Wrong codes show the page again;
+// expired or exhausted challenges send the user back to the login page.
+func (h *handler) completeMFA(w http.ResponseWriter, r *http.Request, app models.App, req models.AuthorizationRequest, challengeID string) {
+	code, err := h.authorizer.AuthorizeMFA(r.Context(), req, challengeID, r.PostForm.Get("code"))
+	if err != nil {
+		var message string
+		switch {
+		case errors.Is(err, oauth.ErrInvalidCode):
+			renderPage(w, mfaPage, http.StatusUnauthorized, mfaPageData{
+				Action:      authorizePath,
+				Error:       "Invalid code.",
+				ChallengeID: challengeID,
+				Request:     requestFields(req),
+			})
+			return
+		case errors.Is(err, oauth.ErrInvalidChallenge):
+			message = "The sign in attempt expired, please sign in again."
+		case errors.Is(err, oauth.ErrEnrollmentRequired):
+			message = enrollmentRequiredMessage
+		default:
+			h.authorizationError(w, r, req, err)
+			return
+		}
+
+		renderLogin(w, http.StatusUnauthorized, loginPageData{App: app.Name, Error: message, Request: requestFields(req)})
+		return
+	}
+
+	redirect(w, r, req.RedirectURI, url.Values{"code": {code}}, req.State)
+}
+
+// authorizationError shows errors about the client and the redirect URI to the user,
+// and sends errors of a request with a verified redirect URI back to the client,
+// see RFC 6749, section 4.1.2.1. Unexpected errors are shown to the user, as they may
+// happen before the redirect URI is verified.
+func (h *handler) authorizationError(w http.ResponseWriter, r *http.Request, req models.AuthorizationRequest, err error) {
+	var code string
+	switch {
+	case errors.Is(err, oauth.ErrInvalidClient):
+		renderLogin(w, http.StatusBadRequest, loginPageData{Error: "Unknown application."})
+		return
+	case errors.Is(err, oauth.ErrInvalidRedirectURI):
+		renderLogin(w, http.StatusBadRequest, loginPageData{Error: "The application sent you to an unregistered address."})
+		return
+	case errors.Is(err, oauth.ErrUnsupportedResponseType):
+		code = errUnsupportedResponseType
+	case errors.Is(err, oauth.ErrInvalidScope):
+		code = errInvalidScope
+	case errors.Is(err, oauth.ErrInvalidRequest):
+		code = errInvalidRequest
+	case errors.Is(err, oauth.ErrAccessDenied):
+		code = errAccessDenied
+	default:
+		h.log.Error("failed to authorize client", sl.Err(err))
+		renderLogin(w, http.StatusInternalServerError, loginPageData{Error: "Something went wrong, please try again later."})
+		return
+	}
+
+	redirect(w, r, req.RedirectURI, url.Values{"error": {code}}, req.State)
+}
+
+func authorizationRequest(values url.Values) models.AuthorizationRequest {
+	return models.AuthorizationRequest{
+		ClientID:            values.Get("client_id"),
+		RedirectURI:         values.Get("redirect_uri"),
+		ResponseType:        values.Get("response_type"),
+		Scopes:              strings.Fields(values.Get("scope")),
+		State:               values.Get("state"),
+		CodeChallenge:       values.Get("code_challenge"),
+		CodeChallengeMethod: values.Get("code_challenge_method"),
+		Nonce:               values.Get("nonce"),
+	}
+}
+
+// requestFields are the hidden fields the login pages post the request back in. A defaulted
+// redirect URI is left out, so the code is issued as if the client omitted it.
+func requestFields(req models.AuthorizationRequest) map[string]string {
+	redirectURI := req.RedirectURI
+	if req.RedirectURIDefaulted {
+		redirectURI = ""
+	}
+
+	return map[string]string{
+		"client_id":             req.ClientID,
+		"redirect_uri":          redirectURI,
+		"response_type":         req.ResponseType,
+		"scope":                 strings.Join(req.Scopes, " "),
+		"state":                 req.State,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+		"nonce":                 req.Nonce,
+	}
+}
+
+// redirect sends the user back to the client with params and the state of the request.
+func redirect(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values, state string) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		renderLogin(w, http.StatusBadRequest, loginPageData{Error: "The application sent you to a malformed address."})
+		return
+	}
+
+	query := u.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	u.RawQuery = query.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func renderLogin(w http.ResponseWriter, status int, data loginPageData) {
+	renderPage(w, loginPage, status, data)
+}
+
+func renderPage(w http.ResponseWriter, page *template.Template, status int, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// Pages take passwords, so they must not be framed by other sites.
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(status)
+	_ = page.Execute(w, data)
+}
+
+// authorizationCode exchanges a code for tokens. Public clients identify themselves with
+// client_id and prove possession with the PKCE verifier; confidential clients also
+// authenticate with their secret.
+func (h *handler) authorizationCode(w http.ResponseWriter, r *http.Request) {
+	clientID, ok := h.tokenClient(w, r)
+	if !ok {
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	verifier := r.PostForm.Get("code_verifier")
+	if code == "" || verifier == "" {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "code and code_verifier required")
+		return
+	}
+
+	tokens, err := h.authorizer.ExchangeCode(r.Context(), clientID, code, r.PostForm.Get("redirect_uri"), verifier)
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidGrant) {
+			writeError(w, http.StatusBadRequest, errInvalidGrant, "")
+			return
+		}
+
+		h.log.Error("failed to exchange authorization code", sl.Err(err))
+		writeError(w, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken:  tokens.AccessToken,
+		IDToken:      tokens.IDToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		Scope:        strings.Join(tokens.Scopes, " "),
+	})
+}
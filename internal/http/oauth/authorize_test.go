@@ -0,0 +1,643 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/pkce"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/services/mfa"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRedirectURI           = "https://app.example.com/callback"
+	testLogin                 = "alice@example.com"
+	testPassword              = "correct horse"
+	testVerifier              = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testState                 = "af0ifjsldkj"
+	testPostLogoutRedirectURI = "https://app.example.com/signed-out"
+	// testMFALogin has TOTP enrolled, testEmailMFALogin only email MFA,
+	// and testEnrollLogin has to enroll a second factor. All share testPassword.
+	testMFALogin      = "bob@example.com"
+	testEmailMFALogin = "carol@example.com"
+	testEnrollLogin   = "dave@example.com"
+	testMFACode       = "123456"
+)
+
+var testUser = models.User{
+	ID:     uuid.MustParse("5f0c8a4e-2b1d-4c3e-9f7a-1a2b3c4d5e6f"),
+	Email:  testLogin,
+	Status: models.UserStatusActive,
+}
+
+var testApp = models.App{
+	ID:                     uuid.MustParse(testClientID),
+	Name:                   "reports",
+	Enabled:                true,
+	RedirectURIs:           []string{testRedirectURI},
+	PostLogoutRedirectURIs: []string{testPostLogoutRedirectURI},
+}
+
+type fakeAuthenticator struct{}
+
+func (fakeAuthenticator) Authenticate(_ context.Context, login string, password string, _ string) (models.User, models.App, models.Authentication, error) {
+	if password != testPassword {
+		return models.User{}, models.App{}, models.Authentication{}, auth.ErrInvalidCredentials
+	}
+
+	var mfaErr *auth.MFARequiredError
+	switch login {
+	case testLogin:
+		return testUser, testApp, models.NewAuthentication(models.AuthMethodPassword), nil
+	case testMFALogin:
+		mfaErr = &auth.MFARequiredError{ChallengeID: uuid.NewString(), Methods: []models.MFAMethod{models.MFAMethodTOTP}}
+	case testEmailMFALogin:
+		mfaErr = &auth.MFARequiredError{ChallengeID: uuid.NewString(), Methods: []models.MFAMethod{models.MFAMethodEmail}}
+	case testEnrollLogin:
+		mfaErr = &auth.MFARequiredError{ChallengeID: uuid.NewString(), EnrollmentRequired: true}
+	default:
+		return models.User{}, models.App{}, models.Authentication{}, auth.ErrInvalidCredentials
+	}
+
+	return models.User{}, models.App{}, models.Authentication{}, mfaErr
+}
+
+// fakeMFA accepts testMFACode for any challenge, and consumes the challenge on success
+// or after three wrong codes.
+type fakeMFA struct {
+	mu       sync.Mutex
+	attempts map[string]int
+	emailed  []string
+}
+
+func newFakeMFA() *fakeMFA {
+	return &fakeMFA{attempts: map[string]int{}}
+}
+
+func (f *fakeMFA) SendChallengeEmailCode(_ context.Context, challengeID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.emailed = append(f.emailed, challengeID)
+
+	return nil
+}
+
+func (f *fakeMFA) VerifyChallenge(_ context.Context, challengeID string, code string) (models.User, models.App, models.Authentication, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.attempts[challengeID] < 0 || f.attempts[challengeID] >= 3 {
+		return models.User{}, models.App{}, models.Authentication{}, mfa.ErrInvalidChallenge
+	}
+	if code != testMFACode {
+		f.attempts[challengeID]++
+
+		return models.User{}, models.App{}, models.Authentication{}, mfa.ErrInvalidCode
+	}
+	f.attempts[challengeID] = -1
+
+	return testUser, testApp, models.NewAuthentication(models.AuthMethodPassword, models.AuthMethodOTP), nil
+}
+
+// fakeUsers holds the test user and revokes its sessions like the postgres storage.
+type fakeUsers struct {
+	mu   sync.Mutex
+	user models.User
+}
+
+func (f *fakeUsers) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if identifier.Value != f.user.ID.String() {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return f.user, nil
+}
+
+func (f *fakeUsers) RevokeSessions(_ context.Context, userID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if userID != f.user.ID {
+		return storage.ErrUserNotFound
+	}
+	f.user.SessionsRevokedAt = time.Now()
+
+	return nil
+}
+
+func (f *fakeUsers) revokedAt() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.user.SessionsRevokedAt
+}
+
+type fakeApps struct{}
+
+func (fakeApps) AppByID(_ context.Context, appID string) (models.App, error) {
+	if appID != testClientID {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return testApp, nil
+}
+
+type fakeLogins struct{}
+
+func (fakeLogins) RecordLogin(context.Context, string, string) error {
+	return nil
+}
+
+// fakeCodes keeps codes like the postgres storage: a code can be consumed once and only before it expires.
+type fakeCodes struct {
+	mu    sync.Mutex
+	codes map[string]models.AuthorizationCode
+}
+
+func newFakeCodes() *fakeCodes {
+	return &fakeCodes{codes: map[string]models.AuthorizationCode{}}
+}
+
+func (f *fakeCodes) SaveAuthorizationCode(_ context.Context, code models.AuthorizationCode, hash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.codes[hash] = code
+
+	return nil
+}
+
+func (f *fakeCodes) ConsumeAuthorizationCode(_ context.Context, hash string, now time.Time) (models.AuthorizationCode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code, ok := f.codes[hash]
+	delete(f.codes, hash)
+	if !ok || !now.Before(code.ExpiresAt) {
+		return models.AuthorizationCode{}, storage.ErrAuthCodeNotFound
+	}
+
+	return code, nil
+}
+
+func authorizeParams() url.Values {
+	return url.Values{
+		"client_id":             {testClientID},
+		"redirect_uri":          {testRedirectURI},
+		"response_type":         {"code"},
+		"state":                 {testState},
+		"code_challenge":        {pkce.Challenge(testVerifier)},
+		"code_challenge_method": {pkce.MethodS256},
+	}
+}
+
+// noRedirects returns a client of the server that stops at redirects, so tests can read them.
+func noRedirects(srv *httptest.Server) *http.Client {
+	c := *srv.Client()
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &c
+}
+
+func getAuthorize(t *testing.T, srv *httptest.Server, params url.Values) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := noRedirects(srv).Get(srv.URL + "/oauth/authorize?" + params.Encode())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(body)
+}
+
+// postMFA posts the second factor of the challenge shown on the MFA page.
+func postMFA(t *testing.T, srv *httptest.Server, params url.Values, challengeID string, code string) (*http.Response, string) {
+	t.Helper()
+
+	form := url.Values{}
+	for name, values := range params {
+		form[name] = values
+	}
+	form.Set("challenge_id", challengeID)
+	form.Set("code", code)
+
+	resp, err := noRedirects(srv).PostForm(srv.URL+"/oauth/authorize", form)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(body)
+}
+
+// challengeIDRe finds the challenge in the hidden field of the MFA page.
+var challengeIDRe = regexp.MustCompile(`name="challenge_id" value="([^"]+)"`)
+
+// startMFA logs in with the password of login and returns the challenge of the MFA page.
+func startMFA(t *testing.T, srv *httptest.Server, login string) (string, string) {
+	t.Helper()
+
+	resp, page := postLogin(t, srv, authorizeParams(), login, testPassword)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	match := challengeIDRe.FindStringSubmatch(page)
+	require.Len(t, match, 2, page)
+
+	return match[1], page
+}
+
+func postLogin(t *testing.T, srv *httptest.Server, params url.Values, login string, password string) (*http.Response, string) {
+	t.Helper()
+
+	form := url.Values{}
+	for name, values := range params {
+		form[name] = values
+	}
+	form.Set("login", login)
+	form.Set("password", password)
+
+	resp, err := noRedirects(srv).PostForm(srv.URL+"/oauth/authorize", form)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(body)
+}
+
+// obtainCode logs the test user in and returns the code from the redirect back to the client.
+func obtainCode(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+
+	resp, _ := postLogin(t, srv, authorizeParams(), testLogin, testPassword)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.NotEmpty(t, location.Query().Get("code"))
+
+	return location.Query().Get("code")
+}
+
+func exchangeForm(code string, verifier string) url.Values {
+	return url.Values{
+		"grant_type":    {grantAuthorizationCode},
+		"client_id":     {testClientID},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	srv := newServer(t)
+
+	resp, page := getAuthorize(t, srv, authorizeParams())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+	assert.Contains(t, page, "Sign in to reports")
+	assert.Contains(t, page, `name="code_challenge" value="`+pkce.Challenge(testVerifier)+`"`)
+
+	resp, _ = postLogin(t, srv, authorizeParams(), testLogin, testPassword)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, testRedirectURI, location.Scheme+"://"+location.Host+location.Path)
+	assert.Equal(t, testState, location.Query().Get("state"))
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	resp, body := postToken(t, srv, exchangeForm(code, testVerifier), "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body["access_token"])
+	assert.NotEmpty(t, body["refresh_token"])
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.InDelta(t, time.Hour.Seconds(), body["expires_in"], 5)
+
+	// Codes are single use.
+	resp, body = postToken(t, srv, exchangeForm(code, testVerifier), "", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errInvalidGrant, body["error"])
+}
+
+func TestAuthorizationCodeFlow_ConfidentialClient(t *testing.T) {
+	srv := newServer(t)
+
+	form := exchangeForm(obtainCode(t, srv), testVerifier)
+	form.Del("client_id")
+
+	resp, body := postToken(t, srv, form, testClientID, testClientSecret)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body["access_token"])
+
+	resp, body = postToken(t, srv, exchangeForm(obtainCode(t, srv), testVerifier), testClientID, "cs_wrong")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, errInvalidClient, body["error"])
+}
+
+func TestToken_ConfidentialClientWithoutSecret(t *testing.T) {
+	srv := newServer(t)
+
+	tests := []struct {
+		name string
+		path string
+		form url.Values
+	}{
+		{
+			name: "authorization code",
+			path: "/oauth/token",
+			form: url.Values{
+				"grant_type":    {grantAuthorizationCode},
+				"client_id":     {testConfidentialClientID},
+				"code":          {"code"},
+				"code_verifier": {testVerifier},
+			},
+		},
+		{
+			name: "device code",
+			path: "/oauth/token",
+			form: url.Values{
+				"grant_type":  {grantDeviceCode},
+				"client_id":   {testConfidentialClientID},
+				"device_code": {"code"},
+			},
+		},
+		{
+			name: "device authorization",
+			path: deviceAuthorizationPath,
+			form: url.Values{"client_id": {testConfidentialClientID}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := srv.Client().PostForm(srv.URL+tt.path, tt.form)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			assert.Equal(t, errInvalidClient, body["error"])
+		})
+	}
+}
+
+func TestExchangeCode_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		codeTTL time.Duration
+		form    func(code string) url.Values
+	}{
+		{
+			name:    "wrong verifier",
+			codeTTL: time.Minute,
+			form: func(code string) url.Values {
+				return exchangeForm(code, strings.Repeat("x", 43))
+			},
+		},
+		{
+			name:    "other redirect uri",
+			codeTTL: time.Minute,
+			form: func(code string) url.Values {
+				form := exchangeForm(code, testVerifier)
+				form.Set("redirect_uri", "https://app.example.com/other")
+				return form
+			},
+		},
+		{
+			name:    "other client",
+			codeTTL: time.Minute,
+			form: func(code string) url.Values {
+				form := exchangeForm(code, testVerifier)
+				form.Set("client_id", uuid.NewString())
+				return form
+			},
+		},
+		{
+			name:    "unknown code",
+			codeTTL: time.Minute,
+			form: func(string) url.Values {
+				return exchangeForm("forged", testVerifier)
+			},
+		},
+		{
+			name:    "expired code",
+			codeTTL: -time.Second,
+			form: func(code string) url.Values {
+				return exchangeForm(code, testVerifier)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServerWithCodeTTL(t, tt.codeTTL)
+
+			resp, body := postToken(t, srv, tt.form(obtainCode(t, srv)), "", "")
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, errInvalidGrant, body["error"])
+		})
+	}
+}
+
+func TestExchangeCode_BurnedByFailedAttempt(t *testing.T) {
+	srv := newServer(t)
+	code := obtainCode(t, srv)
+
+	resp, _ := postToken(t, srv, exchangeForm(code, strings.Repeat("x", 43)), "", "")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, body := postToken(t, srv, exchangeForm(code, testVerifier), "", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errInvalidGrant, body["error"])
+}
+
+func TestAuthorize_ErrorsShownToUser(t *testing.T) {
+	srv := newServer(t)
+
+	tests := []struct {
+		name   string
+		modify func(url.Values)
+	}{
+		{
+			name:   "unregistered redirect uri",
+			modify: func(params url.Values) { params.Set("redirect_uri", "https://evil.example.com/callback") },
+		},
+		{
+			name:   "unknown client",
+			modify: func(params url.Values) { params.Set("client_id", uuid.NewString()) },
+		},
+		{
+			name:   "malformed client id",
+			modify: func(params url.Values) { params.Set("client_id", "reports") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := authorizeParams()
+			tt.modify(params)
+
+			resp, page := getAuthorize(t, srv, params)
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get("Location"))
+			assert.NotContains(t, page, "<form")
+		})
+	}
+}
+
+func TestAuthorize_ErrorsSentToClient(t *testing.T) {
+	srv := newServer(t)
+
+	tests := []struct {
+		name      string
+		modify    func(url.Values)
+		wantError string
+	}{
+		{
+			name:      "no code challenge",
+			modify:    func(params url.Values) { params.Del("code_challenge") },
+			wantError: errInvalidRequest,
+		},
+		{
+			name:      "plain challenge method",
+			modify:    func(params url.Values) { params.Set("code_challenge_method", "plain") },
+			wantError: errInvalidRequest,
+		},
+		{
+			name:      "implicit flow",
+			modify:    func(params url.Values) { params.Set("response_type", "token") },
+			wantError: errUnsupportedResponseType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := authorizeParams()
+			tt.modify(params)
+
+			resp, _ := getAuthorize(t, srv, params)
+			require.Equal(t, http.StatusFound, resp.StatusCode)
+
+			location, err := url.Parse(resp.Header.Get("Location"))
+			require.NoError(t, err)
+			assert.Equal(t, "app.example.com", location.Host)
+			assert.Equal(t, tt.wantError, location.Query().Get("error"))
+			assert.Equal(t, testState, location.Query().Get("state"))
+		})
+	}
+}
+
+func TestLogin_InvalidCredentials(t *testing.T) {
+	srv := newServer(t)
+
+	resp, page := postLogin(t, srv, authorizeParams(), testLogin, "wrong")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, page, "Invalid login or password.")
+	assert.Contains(t, page, `name="state" value="`+testState+`"`)
+}
+
+func TestAuthorize_DefaultsToOnlyRedirectURI(t *testing.T) {
+	srv := newServer(t)
+
+	params := authorizeParams()
+	params.Del("redirect_uri")
+
+	resp, page := getAuthorize(t, srv, params)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, page, `name="redirect_uri" value=""`, "the login form posts the request as the client sent it")
+
+	resp, _ = postLogin(t, srv, params, testLogin, testPassword)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Location"), testRedirectURI+"?"))
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	// The token request omits the redirect URI too.
+	form := exchangeForm(location.Query().Get("code"), testVerifier)
+	form.Del("redirect_uri")
+
+	resp, body := postToken(t, srv, form, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body["access_token"])
+}
+
+func TestAuthorizationCodeFlow_MFA(t *testing.T) {
+	srv := newServer(t)
+
+	challengeID, page := startMFA(t, srv, testMFALogin)
+	assert.Contains(t, page, "authenticator app")
+	assert.Contains(t, page, `name="state" value="`+testState+`"`)
+
+	resp, page := postMFA(t, srv, authorizeParams(), challengeID, "000000")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, page, "Invalid code.")
+	assert.Contains(t, page, `name="challenge_id" value="`+challengeID+`"`, "wrong codes keep the challenge")
+
+	resp, _ = postMFA(t, srv, authorizeParams(), challengeID, testMFACode)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, testState, location.Query().Get("state"))
+
+	resp, body := postToken(t, srv, exchangeForm(location.Query().Get("code"), testVerifier), "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body["access_token"])
+
+	// Challenges are single use.
+	resp, page = postMFA(t, srv, authorizeParams(), challengeID, testMFACode)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, page, "please sign in again")
+	assert.Contains(t, page, `name="password"`)
+}
+
+func TestAuthorizationCodeFlow_EmailMFA(t *testing.T) {
+	srv := newServer(t)
+
+	challengeID, page := startMFA(t, srv, testEmailMFALogin)
+	assert.Contains(t, page, "sent to your email")
+
+	resp, _ := postMFA(t, srv, authorizeParams(), challengeID, testMFACode)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func TestAuthorizationCodeFlow_MFAEnrollmentRequired(t *testing.T) {
+	srv := newServer(t)
+
+	resp, page := postLogin(t, srv, authorizeParams(), testEnrollLogin, testPassword)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, page, "set up a second factor")
+	assert.NotContains(t, page, `name="challenge_id"`)
+}
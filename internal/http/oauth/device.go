@@ -0,0 +1,254 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/services/device"
+)
+
+const (
+	deviceAuthorizationPath = "/oauth/device_authorization"
+	devicePath              = "/oauth/device"
+)
+
+const grantDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+// Error codes of RFC 8628, section 3.5.
+const (
+	errAuthorizationPending = "authorization_pending"
+	errSlowDown             = "slow_down"
+	errExpiredToken         = "expired_token"
+)
+
+// DeviceFlow backs the device authorization grant.
+type DeviceFlow interface {
+	Authorize(ctx context.Context, clientID string, scopes []string) (models.DeviceAuthorization, error)
+	Pending(ctx context.Context, userCode string) (models.App, error)
+	Resolve(ctx context.Context, userCode string, login string, password string, approve bool) error
+	ResolveMFA(ctx context.Context, userCode string, challengeID string, code string, approve bool) error
+	Poll(ctx context.Context, clientID string, deviceCode string) (models.OAuthTokens, error)
+}
+
+// devicePage is where users enter the code shown by a device and approve or deny it.
+var devicePage = newPage("device", `<!DOCTYPE html>
+<html lang="en">
+<head>
+{{template "head"}}
+<title>Connect a device</title>
+</head>
+<body>
+<h1>Connect a device</h1>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+{{with .Message}}<p>{{.}}</p>{{end}}
+{{if not .Done}}
+{{with .App}}<p>A device is asking to sign in to {{.}} as you. Only approve it if you started the sign in yourself.</p>{{end}}
+<form method="post" action="/oauth/device">
+<label for="user_code">Code shown on the device</label>
+<input id="user_code" name="user_code" value="{{.UserCode}}" autocomplete="off" required{{if not .UserCode}} autofocus{{end}}>
+<label for="login">Email or username</label>
+<input id="login" name="login" autocomplete="username" required{{if .UserCode}} autofocus{{end}}>
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required>
+<button type="submit" name="action" value="approve">Approve</button>
+<button type="submit" name="action" value="deny">Deny</button>
+</form>
+{{end}}
+</body>
+</html>
+`)
+
+type devicePageData struct {
+	App      string
+	UserCode string
+	Error    string
+	Message  string
+	// Done hides the form once the code is resolved.
+	Done bool
+}
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// deviceAuthorization starts the device authorization grant (RFC 8628, section 3.1).
+func (h *handler) deviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "malformed form")
+		return
+	}
+
+	clientID, ok := h.tokenClient(w, r)
+	if !ok {
+		return
+	}
+
+	authorization, err := h.devices.Authorize(r.Context(), clientID, strings.Fields(r.PostForm.Get("scope")))
+	if err != nil {
+		switch {
+		case errors.Is(err, device.ErrInvalidClient):
+			writeError(w, http.StatusUnauthorized, errInvalidClient, "")
+		case errors.Is(err, device.ErrInvalidScope):
+			writeError(w, http.StatusBadRequest, errInvalidScope, "")
+		default:
+			h.log.Error("failed to start device authorization", sl.Err(err))
+			writeError(w, http.StatusInternalServerError, errServerError, "")
+		}
+		return
+	}
+
+	verificationURI := h.openID.Issuer() + devicePath
+
+	writeJSON(w, http.StatusOK, deviceAuthorizationResponse{
+		DeviceCode:              authorization.DeviceCode,
+		UserCode:                authorization.UserCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {authorization.UserCode}}.Encode(),
+		ExpiresIn:               int64(authorization.ExpiresIn.Seconds()),
+		Interval:                int64(authorization.Interval.Seconds()),
+	})
+}
+
+// deviceVerification shows the device page, with the app of the code if the link carried one.
+func (h *handler) deviceVerification(w http.ResponseWriter, r *http.Request) {
+	userCode := r.URL.Query().Get("user_code")
+	if userCode == "" {
+		renderPage(w, devicePage, http.StatusOK, devicePageData{})
+		return
+	}
+
+	app, err := h.devices.Pending(r.Context(), userCode)
+	if err != nil {
+		if errors.Is(err, device.ErrInvalidUserCode) {
+			renderPage(w, devicePage, http.StatusNotFound, devicePageData{Error: "The code is unknown or has expired."})
+			return
+		}
+
+		h.log.Error("failed to get device authorization", sl.Err(err))
+		renderPage(w, devicePage, http.StatusInternalServerError, devicePageData{Error: "Something went wrong, please try again later."})
+		return
+	}
+
+	renderPage(w, devicePage, http.StatusOK, devicePageData{App: app.Name, UserCode: userCode})
+}
+
+// deviceResolve approves or denies the code entered on the device page, or completes the
+// second factor entered on the MFA page the login continued on.
+func (h *handler) deviceResolve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderPage(w, devicePage, http.StatusBadRequest, devicePageData{Error: "Malformed request."})
+		return
+	}
+
+	userCode := r.PostForm.Get("user_code")
+	action := r.PostForm.Get("action")
+	approve := action == "approve"
+
+	var err error
+	if challengeID := r.PostForm.Get("challenge_id"); challengeID != "" {
+		err = h.devices.ResolveMFA(r.Context(), userCode, challengeID, r.PostForm.Get("code"), approve)
+		if errors.Is(err, device.ErrInvalidCode) {
+			renderPage(w, mfaPage, http.StatusUnauthorized, mfaPageData{
+				Action:      devicePath,
+				Error:       "Invalid code.",
+				ChallengeID: challengeID,
+				Request:     map[string]string{"user_code": userCode, "action": action},
+			})
+			return
+		}
+	} else {
+		err = h.devices.Resolve(r.Context(), userCode, r.PostForm.Get("login"), r.PostForm.Get("password"), approve)
+	}
+	if err != nil {
+		var (
+			status  = http.StatusUnauthorized
+			message string
+			mfaErr  *device.MFARequiredError
+		)
+		switch {
+		case errors.As(err, &mfaErr):
+			renderPage(w, mfaPage, http.StatusOK, mfaPageData{
+				Action:      devicePath,
+				EmailSent:   mfaErr.EmailSent,
+				ChallengeID: mfaErr.ChallengeID,
+				Request:     map[string]string{"user_code": userCode, "action": action},
+			})
+			return
+		case errors.Is(err, device.ErrInvalidUserCode):
+			status, message, userCode = http.StatusNotFound, "The code is unknown or has expired.", ""
+		case errors.Is(err, device.ErrInvalidCredentials):
+			message = "Invalid login or password."
+		case errors.Is(err, device.ErrInvalidChallenge):
+			message = "The sign in attempt expired, please sign in again."
+		case errors.Is(err, device.ErrEnrollmentRequired):
+			message = enrollmentRequiredMessage
+		case errors.Is(err, device.ErrAccessDenied):
+			status, message = http.StatusForbidden, "You can't sign in to this application."
+		default:
+			h.log.Error("failed to resolve device authorization", sl.Err(err))
+			status, message = http.StatusInternalServerError, "Something went wrong, please try again later."
+		}
+
+		renderPage(w, devicePage, status, devicePageData{UserCode: userCode, Error: message})
+		return
+	}
+
+	message := "The device is signed in. You can return to it now."
+	if !approve {
+		message = "The sign in was denied."
+	}
+
+	renderPage(w, devicePage, http.StatusOK, devicePageData{Message: message, Done: true})
+}
+
+// deviceCode exchanges a device code for tokens once the user approved it (RFC 8628, section 3.4).
+func (h *handler) deviceCode(w http.ResponseWriter, r *http.Request) {
+	clientID, ok := h.tokenClient(w, r)
+	if !ok {
+		return
+	}
+
+	deviceCode := r.PostForm.Get("device_code")
+	if deviceCode == "" {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "device_code required")
+		return
+	}
+
+	tokens, err := h.devices.Poll(r.Context(), clientID, deviceCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, device.ErrAuthorizationPending):
+			writeError(w, http.StatusBadRequest, errAuthorizationPending, "")
+		case errors.Is(err, device.ErrSlowDown):
+			writeError(w, http.StatusBadRequest, errSlowDown, "")
+		case errors.Is(err, device.ErrAccessDenied):
+			writeError(w, http.StatusBadRequest, errAccessDenied, "")
+		case errors.Is(err, device.ErrExpiredToken):
+			writeError(w, http.StatusBadRequest, errExpiredToken, "")
+		case errors.Is(err, device.ErrInvalidGrant):
+			writeError(w, http.StatusBadRequest, errInvalidGrant, "")
+		default:
+			h.log.Error("failed to exchange device code", sl.Err(err))
+			writeError(w, http.StatusInternalServerError, errServerError, "")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		Scope:        strings.Join(tokens.Scopes, " "),
+	})
+}
@@ -0,0 +1,391 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeviceCodes keeps device codes like the postgres storage: a user code can be resolved
+// once while pending and unexpired, and an approved code is consumed by the first poll.
+type fakeDeviceCodes struct {
+	mu    sync.Mutex
+	codes map[string]*models.DeviceCode
+}
+
+func newFakeDeviceCodes() *fakeDeviceCodes {
+	return &fakeDeviceCodes{codes: map[string]*models.DeviceCode{}}
+}
+
+func (f *fakeDeviceCodes) SaveDeviceCode(_ context.Context, code models.DeviceCode, hash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code.Status = models.DeviceCodePending
+	f.codes[hash] = &code
+
+	return nil
+}
+
+func (f *fakeDeviceCodes) PendingDeviceCode(_ context.Context, userCode string, now time.Time) (models.DeviceCode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code := f.pending(userCode, now)
+	if code == nil {
+		return models.DeviceCode{}, storage.ErrDeviceCodeNotFound
+	}
+
+	return *code, nil
+}
+
+func (f *fakeDeviceCodes) ResolveDeviceCode(
+	_ context.Context,
+	userCode string,
+	userID uuid.UUID,
+	auth models.Authentication,
+	status models.DeviceCodeStatus,
+	now time.Time,
+) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code := f.pending(userCode, now)
+	if code == nil {
+		return storage.ErrDeviceCodeNotFound
+	}
+	code.Status, code.UserID, code.Auth = status, userID, auth
+
+	return nil
+}
+
+func (f *fakeDeviceCodes) PollDeviceCode(_ context.Context, hash string, now time.Time) (models.DeviceCode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code, ok := f.codes[hash]
+	if !ok {
+		return models.DeviceCode{}, storage.ErrDeviceCodeNotFound
+	}
+
+	old := *code
+	code.LastPolledAt = now
+	if code.Status == models.DeviceCodeApproved {
+		code.Status = models.DeviceCodeConsumed
+	}
+
+	return old, nil
+}
+
+func (f *fakeDeviceCodes) pending(userCode string, now time.Time) *models.DeviceCode {
+	for _, code := range f.codes {
+		if code.UserCode == userCode && code.Status == models.DeviceCodePending && now.Before(code.ExpiresAt) {
+			return code
+		}
+	}
+
+	return nil
+}
+
+func postDeviceAuthorization(t *testing.T, srv *httptest.Server, form url.Values) (*http.Response, map[string]any) {
+	t.Helper()
+
+	resp, err := srv.Client().PostForm(srv.URL+"/oauth/device_authorization", form)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	return resp, body
+}
+
+// startDevice starts a device authorization of the test client and returns the device and user codes.
+func startDevice(t *testing.T, srv *httptest.Server, scope string) (string, string) {
+	t.Helper()
+
+	resp, body := postDeviceAuthorization(t, srv, url.Values{"client_id": {testClientID}, "scope": {scope}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	deviceCode, _ := body["device_code"].(string)
+	userCode, _ := body["user_code"].(string)
+	require.NotEmpty(t, deviceCode)
+	require.NotEmpty(t, userCode)
+
+	return deviceCode, userCode
+}
+
+func pollDevice(t *testing.T, srv *httptest.Server, deviceCode string) (*http.Response, map[string]any) {
+	t.Helper()
+
+	return postToken(t, srv, url.Values{
+		"grant_type":  {grantDeviceCode},
+		"client_id":   {testClientID},
+		"device_code": {deviceCode},
+	}, "", "")
+}
+
+func getDevicePage(t *testing.T, srv *httptest.Server, userCode string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := srv.Client().Get(srv.URL + "/oauth/device?" + url.Values{"user_code": {userCode}}.Encode())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	page, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(page)
+}
+
+func postDevicePage(t *testing.T, srv *httptest.Server, userCode string, password string, action string) (*http.Response, string) {
+	t.Helper()
+
+	return postDeviceForm(t, srv, url.Values{
+		"user_code": {userCode},
+		"login":     {testLogin},
+		"password":  {password},
+		"action":    {action},
+	})
+}
+
+func postDeviceForm(t *testing.T, srv *httptest.Server, form url.Values) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := srv.Client().PostForm(srv.URL+"/oauth/device", form)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	page, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(page)
+}
+
+func TestDeviceFlow(t *testing.T) {
+	srv := newServer(t)
+
+	resp, body := postDeviceAuthorization(t, srv, url.Values{"client_id": {testClientID}, "scope": {"openid email"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	deviceCode, _ := body["device_code"].(string)
+	userCode, _ := body["user_code"].(string)
+	assert.Regexp(t, `^[A-Z]{4}-[A-Z]{4}$`, userCode)
+	assert.Equal(t, testIssuer+"/oauth/device", body["verification_uri"])
+	assert.Equal(t, testIssuer+"/oauth/device?user_code="+userCode, body["verification_uri_complete"])
+	assert.Equal(t, float64(60), body["expires_in"])
+	assert.Equal(t, testPollInterval.Seconds(), body["interval"])
+
+	resp, body = pollDevice(t, srv, deviceCode)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errAuthorizationPending, body["error"])
+
+	resp, page := getDevicePage(t, srv, userCode)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, page, "sign in to reports")
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+
+	// Users may type the code in lower case and without the dash.
+	resp, page = postDevicePage(t, srv, strings.ToLower(strings.ReplaceAll(userCode, "-", "")), testPassword, "approve")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, page, "The device is signed in.")
+
+	resp, body = pollDevice(t, srv, deviceCode)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body["access_token"])
+	assert.NotEmpty(t, body["refresh_token"])
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.Equal(t, "openid email", body["scope"])
+
+	accessToken, _ := body["access_token"].(string)
+	resp, info := getJSON(t, srv, "/oauth/userinfo", accessToken)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, testUser.ID.String(), info["sub"])
+
+	resp, body = pollDevice(t, srv, deviceCode)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errInvalidGrant, body["error"], "device codes are exchanged once")
+
+	resp, _ = postDevicePage(t, srv, userCode, testPassword, "approve")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "user codes are resolved once")
+}
+
+func TestDeviceFlow_SlowDown(t *testing.T) {
+	srv := newServer(t)
+
+	deviceCode, _ := startDevice(t, srv, "")
+
+	_, body := pollDevice(t, srv, deviceCode)
+	assert.Equal(t, errAuthorizationPending, body["error"])
+
+	resp, body := pollDevice(t, srv, deviceCode)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errSlowDown, body["error"])
+}
+
+func TestDeviceFlow_Denied(t *testing.T) {
+	srv := newServer(t)
+
+	deviceCode, userCode := startDevice(t, srv, "")
+
+	resp, page := postDevicePage(t, srv, userCode, testPassword, "deny")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, page, "The sign in was denied.")
+
+	resp, body := pollDevice(t, srv, deviceCode)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errAccessDenied, body["error"])
+}
+
+func TestDeviceFlow_Expired(t *testing.T) {
+	srv := newServerWithCodeTTL(t, -time.Second)
+
+	deviceCode, userCode := startDevice(t, srv, "")
+
+	resp, _ := getDevicePage(t, srv, userCode)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, body := pollDevice(t, srv, deviceCode)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errExpiredToken, body["error"])
+}
+
+func TestDeviceResolve_InvalidCredentials(t *testing.T) {
+	srv := newServer(t)
+
+	deviceCode, userCode := startDevice(t, srv, "")
+
+	resp, page := postDevicePage(t, srv, userCode, "wrong", "approve")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, page, "Invalid login or password.")
+	assert.Contains(t, page, userCode, "the form keeps the code for a retry")
+
+	_, body := pollDevice(t, srv, deviceCode)
+	assert.Equal(t, errAuthorizationPending, body["error"])
+}
+
+func TestDeviceFlow_MFA(t *testing.T) {
+	srv := newServer(t)
+
+	deviceCode, userCode := startDevice(t, srv, "")
+
+	resp, page := postDeviceForm(t, srv, url.Values{
+		"user_code": {userCode},
+		"login":     {testMFALogin},
+		"password":  {testPassword},
+		"action":    {"approve"},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, page, "authenticator app")
+	assert.Contains(t, page, `action="/oauth/device"`)
+	assert.Contains(t, page, `name="user_code" value="`+userCode+`"`)
+
+	match := challengeIDRe.FindStringSubmatch(page)
+	require.Len(t, match, 2, page)
+	mfaForm := url.Values{
+		"user_code":    {userCode},
+		"action":       {"approve"},
+		"challenge_id": {match[1]},
+		"code":         {"000000"},
+	}
+
+	resp, page = postDeviceForm(t, srv, mfaForm)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, page, "Invalid code.")
+	assert.Contains(t, page, `name="challenge_id" value="`+match[1]+`"`, "wrong codes keep the challenge")
+
+	mfaForm.Set("code", testMFACode)
+	resp, page = postDeviceForm(t, srv, mfaForm)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, page, "The device is signed in.")
+
+	resp, body := pollDevice(t, srv, deviceCode)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body["access_token"])
+}
+
+func TestDeviceFlow_MFAEnrollmentRequired(t *testing.T) {
+	srv := newServer(t)
+
+	deviceCode, userCode := startDevice(t, srv, "")
+
+	resp, page := postDeviceForm(t, srv, url.Values{
+		"user_code": {userCode},
+		"login":     {testEnrollLogin},
+		"password":  {testPassword},
+		"action":    {"approve"},
+	})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, page, "set up a second factor")
+	assert.NotContains(t, page, `name="challenge_id"`)
+
+	_, body := pollDevice(t, srv, deviceCode)
+	assert.Equal(t, errAuthorizationPending, body["error"])
+}
+
+func TestDeviceAuthorization_Rejected(t *testing.T) {
+	srv := newServer(t)
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "missing client",
+			form:       url.Values{},
+			wantStatus: http.StatusBadRequest,
+			wantError:  errInvalidRequest,
+		},
+		{
+			name:       "unknown client",
+			form:       url.Values{"client_id": {"7d7c2b7e-1f0e-4b57-9a55-6d3c1f0b2a12"}},
+			wantStatus: http.StatusUnauthorized,
+			wantError:  errInvalidClient,
+		},
+		{
+			name:       "wrong secret",
+			form:       url.Values{"client_id": {testClientID}, "client_secret": {"wrong"}},
+			wantStatus: http.StatusUnauthorized,
+			wantError:  errInvalidClient,
+		},
+		{
+			name:       "unsupported scope",
+			form:       url.Values{"client_id": {testClientID}, "scope": {"openid admin"}},
+			wantStatus: http.StatusBadRequest,
+			wantError:  errInvalidScope,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := postDeviceAuthorization(t, srv, tt.form)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantError, body["error"])
+		})
+	}
+}
+
+func TestDevicePoll_UnknownCode(t *testing.T) {
+	srv := newServer(t)
+
+	resp, body := pollDevice(t, srv, "forged")
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errInvalidGrant, body["error"])
+}
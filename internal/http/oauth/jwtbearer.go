@@ -0,0 +1,46 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/services/serviceaccount"
+)
+
+const grantJWTBearer = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// ServiceAccounts backs the JWT bearer grant of service accounts.
+type ServiceAccounts interface {
+	Exchange(ctx context.Context, assertion string) (models.ServiceAccountToken, error)
+}
+
+// jwtBearer exchanges an assertion signed by a service account for an access token
+// (RFC 7523, section 2.1). The assertion authenticates the account, so no client is involved.
+func (h *handler) jwtBearer(w http.ResponseWriter, r *http.Request) {
+	assertion := r.PostForm.Get("assertion")
+	if assertion == "" {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "assertion required")
+		return
+	}
+
+	token, err := h.serviceAccounts.Exchange(r.Context(), assertion)
+	if err != nil {
+		if errors.Is(err, serviceaccount.ErrInvalidGrant) {
+			writeError(w, http.StatusBadRequest, errInvalidGrant, "")
+			return
+		}
+
+		h.log.Error("failed to exchange assertion", sl.Err(err))
+		writeError(w, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(token.ExpiresIn.Seconds()),
+	})
+}
@@ -0,0 +1,201 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/publickey"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAccountName = "ci-deployer"
+
+var (
+	testAccount = models.ServiceAccount{
+		ID:   uuid.MustParse("9d1e7c2a-4b3f-4e8a-8c6d-2f1a0b9c8d7e"),
+		Name: testAccountName,
+	}
+	testAccountKeyID = uuid.MustParse("3c2b1a09-8f7e-4d6c-9b5a-4e3d2c1b0a98")
+	testAccountKey   = mustECKey()
+)
+
+func mustECKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// fakeServiceAccounts holds the test account with its single key, and remembers assertion IDs
+// like the postgres storage.
+type fakeServiceAccounts struct {
+	mu   sync.Mutex
+	jtis map[string]bool
+}
+
+func newFakeServiceAccounts() *fakeServiceAccounts {
+	return &fakeServiceAccounts{jtis: map[string]bool{}}
+}
+
+func (f *fakeServiceAccounts) LookupServiceAccount(_ context.Context, idOrName string) (models.ServiceAccount, error) {
+	if idOrName != testAccount.Name && idOrName != testAccount.ID.String() {
+		return models.ServiceAccount{}, storage.ErrServiceAccountNotFound
+	}
+
+	return testAccount, nil
+}
+
+func (f *fakeServiceAccounts) ActiveServiceAccountKeys(_ context.Context, accountID uuid.UUID, _ time.Time) ([]models.ServiceAccountKey, error) {
+	if accountID != testAccount.ID {
+		return nil, nil
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&testAccountKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return []models.ServiceAccountKey{{
+		ID:        testAccountKeyID,
+		AccountID: testAccount.ID,
+		PublicKey: der,
+		Algorithm: publickey.AlgorithmES256,
+	}}, nil
+}
+
+func (f *fakeServiceAccounts) SaveAssertion(_ context.Context, accountID uuid.UUID, _ uuid.UUID, jti string, _ time.Time, _ time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.jtis[accountID.String()+jti] {
+		return storage.ErrAssertionReplayed
+	}
+	f.jtis[accountID.String()+jti] = true
+
+	return nil
+}
+
+// signAssertion signs a valid assertion of the test account, changed by edit.
+func signAssertion(t *testing.T, key *ecdsa.PrivateKey, edit func(claims jwt.MapClaims, header map[string]any)) string {
+	t.Helper()
+
+	claims := jwt.MapClaims{
+		"iss": testAccountName,
+		"sub": testAccountName,
+		"aud": testIssuer + "/oauth/token",
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+		"jti": uuid.NewString(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = testAccountKeyID.String()
+	if edit != nil {
+		edit(claims, token.Header)
+	}
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestJWTBearer(t *testing.T) {
+	srv := newServer(t)
+
+	assertion := signAssertion(t, testAccountKey, nil)
+	form := url.Values{"grant_type": {grantJWTBearer}, "assertion": {assertion}}
+
+	resp, body := postToken(t, srv, form, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body["access_token"])
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.Equal(t, float64(60), body["expires_in"])
+	assert.NotContains(t, body, "refresh_token")
+
+	resp, body = postToken(t, srv, form, "", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errInvalidGrant, body["error"], "assertions are accepted once")
+}
+
+func TestJWTBearer_Rejected(t *testing.T) {
+	srv := newServer(t)
+
+	tests := []struct {
+		name string
+		key  *ecdsa.PrivateKey
+		edit func(claims jwt.MapClaims, header map[string]any)
+	}{
+		{
+			name: "wrong audience",
+			edit: func(claims jwt.MapClaims, _ map[string]any) { claims["aud"] = "https://other.example.com" },
+		},
+		{
+			name: "issuer is not the subject",
+			edit: func(claims jwt.MapClaims, _ map[string]any) { claims["iss"] = "someone-else" },
+		},
+		{
+			name: "unknown account",
+			edit: func(claims jwt.MapClaims, _ map[string]any) { claims["iss"], claims["sub"] = "nobody", "nobody" },
+		},
+		{
+			name: "unknown key",
+			edit: func(_ jwt.MapClaims, header map[string]any) { header["kid"] = uuid.NewString() },
+		},
+		{
+			name: "signed with another key",
+			key:  mustECKey(),
+		},
+		{
+			name: "expired",
+			edit: func(claims jwt.MapClaims, _ map[string]any) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		},
+		{
+			name: "lives too long",
+			edit: func(claims jwt.MapClaims, _ map[string]any) { claims["exp"] = time.Now().Add(24 * time.Hour).Unix() },
+		},
+		{
+			name: "no jti",
+			edit: func(claims jwt.MapClaims, _ map[string]any) { delete(claims, "jti") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.key
+			if key == nil {
+				key = testAccountKey
+			}
+
+			resp, body := postToken(t, srv, url.Values{
+				"grant_type": {grantJWTBearer},
+				"assertion":  {signAssertion(t, key, tt.edit)},
+			}, "", "")
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, errInvalidGrant, body["error"])
+		})
+	}
+}
+
+func TestJWTBearer_MissingAssertion(t *testing.T) {
+	srv := newServer(t)
+
+	resp, body := postToken(t, srv, url.Values{"grant_type": {grantJWTBearer}}, "", "")
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, errInvalidRequest, body["error"])
+}
@@ -0,0 +1,217 @@
+// Package oauth serves OAuth 2.0 (RFC 6749) and OpenID Connect endpoints over HTTP.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/services/client"
+)
+
+// Error codes of RFC 6749, section 5.2.
+const (
+	errInvalidRequest       = "invalid_request"
+	errInvalidClient        = "invalid_client"
+	errInvalidGrant         = "invalid_grant"
+	errInvalidScope         = "invalid_scope"
+	errUnsupportedGrantType = "unsupported_grant_type"
+	errServerError          = "server_error"
+)
+
+const grantClientCredentials = "client_credentials"
+
+// ClientCredentials backs the client credentials grant and authenticates confidential clients.
+type ClientCredentials interface {
+	ClientCredentials(ctx context.Context, clientID string, clientSecret string, scopes []string) (models.ClientToken, error)
+	AuthenticateClient(ctx context.Context, clientID string, clientSecret string) (models.App, error)
+	CheckPublicClient(ctx context.Context, clientID string) error
+}
+
+type handler struct {
+	log             *slog.Logger
+	clients         ClientCredentials
+	authorizer      Authorizer
+	openID          OpenID
+	devices         DeviceFlow
+	serviceAccounts ServiceAccounts
+}
+
+// Register adds OAuth and OpenID Connect endpoints to the mux.
+func Register(
+	mux *http.ServeMux,
+	log *slog.Logger,
+	clients ClientCredentials,
+	authorizer Authorizer,
+	openID OpenID,
+	devices DeviceFlow,
+	serviceAccounts ServiceAccounts,
+) {
+	h := &handler{
+		log:             log,
+		clients:         clients,
+		authorizer:      authorizer,
+		openID:          openID,
+		devices:         devices,
+		serviceAccounts: serviceAccounts,
+	}
+
+	mux.HandleFunc("GET "+authorizePath, h.authorize)
+	mux.HandleFunc("POST "+authorizePath, h.login)
+	mux.HandleFunc("POST /oauth/token", h.token)
+
+	mux.HandleFunc("POST "+deviceAuthorizationPath, h.deviceAuthorization)
+	mux.HandleFunc("GET "+devicePath, h.deviceVerification)
+	mux.HandleFunc("POST "+devicePath, h.deviceResolve)
+
+	mux.HandleFunc("GET "+discoveryPath, h.discovery)
+	mux.HandleFunc("GET "+jwksPath, h.jwks)
+	mux.HandleFunc("GET "+userInfoPath, h.userInfo)
+	mux.HandleFunc("POST "+userInfoPath, h.userInfo)
+	mux.HandleFunc("GET "+logoutPath, h.logout)
+	mux.HandleFunc("POST "+logoutPath, h.logout)
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func (h *handler) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "malformed form")
+		return
+	}
+
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case grantClientCredentials:
+		h.clientCredentials(w, r)
+	case grantAuthorizationCode:
+		h.authorizationCode(w, r)
+	case grantDeviceCode:
+		h.deviceCode(w, r)
+	case grantJWTBearer:
+		h.jwtBearer(w, r)
+	case "":
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "grant_type required")
+	default:
+		writeError(w, http.StatusBadRequest, errUnsupportedGrantType, "")
+	}
+}
+
+func (h *handler) clientCredentials(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, basic, ok := clientAuth(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "client authentication required")
+		return
+	}
+
+	token, err := h.clients.ClientCredentials(r.Context(), clientID, clientSecret, strings.Fields(r.PostForm.Get("scope")))
+	if err != nil {
+		if errors.Is(err, client.ErrInvalidScope) {
+			writeError(w, http.StatusBadRequest, errInvalidScope, "")
+			return
+		}
+
+		h.clientError(w, err, basic)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(token.ExpiresAt).Round(time.Second).Seconds()),
+		Scope:       strings.Join(token.Scopes, " "),
+	})
+}
+
+// clientError reports a failed client authentication, challenging clients that used Basic.
+func (h *handler) clientError(w http.ResponseWriter, err error, basic bool) {
+	if !errors.Is(err, client.ErrInvalidClient) {
+		h.log.Error("failed to authenticate client", sl.Err(err))
+		writeError(w, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+
+	if basic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	writeError(w, http.StatusUnauthorized, errInvalidClient, "")
+}
+
+// tokenClient identifies the client of a grant that public clients may use too. Public clients
+// only send client_id; confidential clients, which have secrets, must authenticate with one.
+// Writes the error response and returns false if the client can't be identified.
+func (h *handler) tokenClient(w http.ResponseWriter, r *http.Request) (string, bool) {
+	clientID, clientSecret, basic, _ := clientAuth(r)
+	if clientID == "" {
+		clientID = r.PostForm.Get("client_id")
+	}
+	if clientID == "" {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "client_id required")
+		return "", false
+	}
+
+	if clientSecret == "" {
+		if err := h.clients.CheckPublicClient(r.Context(), clientID); err != nil {
+			h.clientError(w, err, basic)
+			return "", false
+		}
+
+		return clientID, true
+	}
+
+	if _, err := h.clients.AuthenticateClient(r.Context(), clientID, clientSecret); err != nil {
+		h.clientError(w, err, basic)
+		return "", false
+	}
+
+	return clientID, true
+}
+
+// clientAuth reads client credentials from the Authorization header or, failing that, the form.
+// Basic credentials are form-urlencoded before being base64 encoded, see RFC 6749, section 2.3.1.
+func clientAuth(r *http.Request) (clientID string, clientSecret string, basic bool, ok bool) {
+	if id, secret, found := r.BasicAuth(); found {
+		id, errID := url.QueryUnescape(id)
+		secret, errSecret := url.QueryUnescape(secret)
+		if errID != nil || errSecret != nil || id == "" {
+			return "", "", true, false
+		}
+
+		return id, secret, true, true
+	}
+
+	clientID = r.PostForm.Get("client_id")
+	clientSecret = r.PostForm.Get("client_secret")
+
+	return clientID, clientSecret, false, clientID != "" && clientSecret != ""
+}
+
+func writeError(w http.ResponseWriter, status int, code string, description string) {
+	writeJSON(w, status, errorResponse{Error: code, ErrorDescription: description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
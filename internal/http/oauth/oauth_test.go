@@ -0,0 +1,250 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/services/client"
+	"github.com/sol1corejz/auth-service/internal/services/device"
+	oauthsvc "github.com/sol1corejz/auth-service/internal/services/oauth"
+	"github.com/sol1corejz/auth-service/internal/services/serviceaccount"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID     = "0b7a0a1e-8f0e-4a57-9a55-6d3c1f0b2a11"
+	testClientSecret = "cs_secret"
+	// testConfidentialClientID has a secret, so it can't use grants as a public client.
+	testConfidentialClientID = "6c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f"
+	// testPollInterval is long enough for any second poll of a test to be too early.
+	testPollInterval = time.Minute
+)
+
+type fakeClients struct {
+	allowed []string
+}
+
+func (f *fakeClients) ClientCredentials(_ context.Context, clientID string, clientSecret string, scopes []string) (models.ClientToken, error) {
+	if clientID != testClientID || clientSecret != testClientSecret {
+		return models.ClientToken{}, client.ErrInvalidClient
+	}
+	if len(scopes) == 0 {
+		scopes = f.allowed
+	}
+	for _, scope := range scopes {
+		if !strings.HasPrefix(scope, "reports:") {
+			return models.ClientToken{}, client.ErrInvalidScope
+		}
+	}
+
+	return models.ClientToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour), Scopes: scopes}, nil
+}
+
+func (f *fakeClients) AuthenticateClient(_ context.Context, clientID string, clientSecret string) (models.App, error) {
+	if clientID != testClientID || clientSecret != testClientSecret {
+		return models.App{}, client.ErrInvalidClient
+	}
+
+	return models.App{ID: uuid.MustParse(testClientID)}, nil
+}
+
+func (f *fakeClients) CheckPublicClient(_ context.Context, clientID string) error {
+	if clientID == testConfidentialClientID {
+		return client.ErrInvalidClient
+	}
+
+	return nil
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return newServerWithCodeTTL(t, time.Minute)
+}
+
+func newServerWithCodeTTL(t *testing.T, codeTTL time.Duration) *httptest.Server {
+	t.Helper()
+
+	srv, _ := newOIDCServer(t, codeTTL)
+
+	return srv
+}
+
+// newOIDCServer serves the real OAuth, device and service account services on top of in-memory fakes, and
+// returns the users fake to check sessions revoked by logout. Authorization and device codes
+// both live for codeTTL.
+func newOIDCServer(t *testing.T, codeTTL time.Duration) (*httptest.Server, *fakeUsers) {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	users := &fakeUsers{user: testUser}
+	provider := oauthsvc.New(
+		log,
+		fakeAuthenticator{},
+		newFakeMFA(),
+		users,
+		fakeApps{},
+		newFakeCodes(),
+		fakeLogins{},
+		users,
+		testSigningKey(t),
+		testIssuer,
+		codeTTL,
+		time.Hour,
+		24*time.Hour,
+	)
+
+	devices := device.New(
+		log,
+		fakeAuthenticator{},
+		newFakeMFA(),
+		users,
+		fakeApps{},
+		newFakeDeviceCodes(),
+		fakeLogins{},
+		codeTTL,
+		testPollInterval,
+		time.Hour,
+		24*time.Hour,
+	)
+
+	serviceAccounts := serviceaccount.New(
+		log,
+		&fakeUsers{user: models.User{ID: testAccount.ID, Status: models.UserStatusActive}},
+		newFakeServiceAccounts(),
+		testIssuer,
+		time.Minute,
+	)
+
+	mux := http.NewServeMux()
+	Register(mux, log, &fakeClients{allowed: []string{"reports:read"}}, provider, provider, devices, serviceAccounts)
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, users
+}
+
+func postToken(t *testing.T, srv *httptest.Server, form url.Values, basicID, basicSecret string) (*http.Response, map[string]any) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/oauth/token", strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicID != "" {
+		req.SetBasicAuth(url.QueryEscape(basicID), url.QueryEscape(basicSecret))
+	}
+
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	return resp, body
+}
+
+func TestToken_ClientCredentials(t *testing.T) {
+	srv := newServer(t)
+
+	tests := []struct {
+		name        string
+		form        url.Values
+		basicID     string
+		basicSecret string
+		wantStatus  int
+		wantError   string
+		wantScope   string
+	}{
+		{
+			name:        "basic auth",
+			form:        url.Values{"grant_type": {"client_credentials"}, "scope": {"reports:read reports:write"}},
+			basicID:     testClientID,
+			basicSecret: testClientSecret,
+			wantStatus:  http.StatusOK,
+			wantScope:   "reports:read reports:write",
+		},
+		{
+			name: "form auth with default scopes",
+			form: url.Values{
+				"grant_type":    {"client_credentials"},
+				"client_id":     {testClientID},
+				"client_secret": {testClientSecret},
+			},
+			wantStatus: http.StatusOK,
+			wantScope:  "reports:read",
+		},
+		{
+			name:        "wrong secret",
+			form:        url.Values{"grant_type": {"client_credentials"}},
+			basicID:     testClientID,
+			basicSecret: "cs_wrong",
+			wantStatus:  http.StatusUnauthorized,
+			wantError:   errInvalidClient,
+		},
+		{
+			name:       "no client authentication",
+			form:       url.Values{"grant_type": {"client_credentials"}},
+			wantStatus: http.StatusBadRequest,
+			wantError:  errInvalidRequest,
+		},
+		{
+			name:        "scope not allowed",
+			form:        url.Values{"grant_type": {"client_credentials"}, "scope": {"users:delete"}},
+			basicID:     testClientID,
+			basicSecret: testClientSecret,
+			wantStatus:  http.StatusBadRequest,
+			wantError:   errInvalidScope,
+		},
+		{
+			name:        "unsupported grant",
+			form:        url.Values{"grant_type": {"password"}},
+			basicID:     testClientID,
+			basicSecret: testClientSecret,
+			wantStatus:  http.StatusBadRequest,
+			wantError:   errUnsupportedGrantType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := postToken(t, srv, tt.form, tt.basicID, tt.basicSecret)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, body["error"])
+				return
+			}
+
+			assert.Equal(t, "token", body["access_token"])
+			assert.Equal(t, "Bearer", body["token_type"])
+			assert.InDelta(t, time.Hour.Seconds(), body["expires_in"], 5)
+			assert.Equal(t, tt.wantScope, body["scope"])
+		})
+	}
+}
+
+func TestToken_InvalidClientWithBasicAuthChallenges(t *testing.T) {
+	srv := newServer(t)
+
+	resp, _ := postToken(t, srv, url.Values{"grant_type": {"client_credentials"}}, testClientID, "cs_wrong")
+
+	assert.Equal(t, `Basic realm="oauth"`, resp.Header.Get("WWW-Authenticate"))
+}
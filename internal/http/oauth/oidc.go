@@ -0,0 +1,159 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/signingkey"
+	"github.com/sol1corejz/auth-service/internal/services/oauth"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	jwksPath      = "/oauth/jwks"
+	userInfoPath  = "/oauth/userinfo"
+	logoutPath    = "/oauth/logout"
+)
+
+// Error codes of RFC 6750, section 3.1.
+const (
+	errInvalidToken      = "invalid_token"
+	errInsufficientScope = "insufficient_scope"
+)
+
+// OpenID backs OpenID Connect endpoints.
+type OpenID interface {
+	Issuer() string
+	JWKS() signingkey.JWKS
+	UserInfo(ctx context.Context, accessToken string) (map[string]any, error)
+	Logout(ctx context.Context, req models.LogoutRequest) (models.LogoutRequest, error)
+}
+
+// providerMetadata is the discovery document (OpenID Connect Discovery 1.0, section 3).
+type providerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+func (h *handler) discovery(w http.ResponseWriter, _ *http.Request) {
+	issuer := h.openID.Issuer()
+
+	writeJSON(w, http.StatusOK, providerMetadata{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + authorizePath,
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserInfoEndpoint:                  issuer + userInfoPath,
+		JWKSURI:                           issuer + jwksPath,
+		EndSessionEndpoint:                issuer + logoutPath,
+		DeviceAuthorizationEndpoint:       issuer + deviceAuthorizationPath,
+		ScopesSupported:                   oauth.SupportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{grantAuthorizationCode, grantClientCredentials, grantDeviceCode, grantJWTBearer},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{signingkey.Algorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "acr", "amr",
+			"email", "preferred_username", "updated_at",
+		},
+	})
+}
+
+func (h *handler) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.openID.JWKS())
+}
+
+// userInfo returns claims about the user to a client holding their access token
+// (OpenID Connect Core, section 5.3). The token is accepted in the Authorization header only.
+func (h *handler) userInfo(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oauth"`)
+		writeError(w, http.StatusUnauthorized, errInvalidRequest, "bearer token required")
+		return
+	}
+
+	claims, err := h.openID.UserInfo(r.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, oauth.ErrInvalidToken):
+			w.Header().Set("WWW-Authenticate", bearerChallenge(errInvalidToken))
+			writeError(w, http.StatusUnauthorized, errInvalidToken, "")
+		case errors.Is(err, oauth.ErrInsufficientScope):
+			w.Header().Set("WWW-Authenticate", bearerChallenge(errInsufficientScope))
+			writeError(w, http.StatusForbidden, errInsufficientScope, "openid scope required")
+		default:
+			h.log.Error("failed to get user info", sl.Err(err))
+			writeError(w, http.StatusInternalServerError, errServerError, "")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, claims)
+}
+
+// logout handles RP-initiated logout (OpenID Connect RP-Initiated Logout 1.0) and sends the user
+// to a registered post logout redirect URI, or shows that they have been signed out.
+// Errors are always shown to the user, as the redirect URI can't be trusted then.
+func (h *handler) logout(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderLogin(w, http.StatusBadRequest, loginPageData{Title: "Sign out", Error: "Malformed request."})
+		return
+	}
+
+	req, err := h.openID.Logout(r.Context(), models.LogoutRequest{
+		IDTokenHint:           r.Form.Get("id_token_hint"),
+		ClientID:              r.Form.Get("client_id"),
+		PostLogoutRedirectURI: r.Form.Get("post_logout_redirect_uri"),
+		State:                 r.Form.Get("state"),
+	})
+	if err != nil {
+		var message string
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, oauth.ErrInvalidRequest):
+			message = "The application sent an invalid sign out request."
+		case errors.Is(err, oauth.ErrInvalidClient):
+			message = "Unknown application."
+		case errors.Is(err, oauth.ErrInvalidRedirectURI):
+			message = "The application sent you to an unregistered address."
+		default:
+			h.log.Error("failed to log out", sl.Err(err))
+			message = "Something went wrong, please try again later."
+			status = http.StatusInternalServerError
+		}
+
+		renderLogin(w, status, loginPageData{Title: "Sign out", Error: message})
+		return
+	}
+
+	if req.PostLogoutRedirectURI != "" {
+		redirect(w, r, req.PostLogoutRedirectURI, nil, req.State)
+		return
+	}
+
+	renderLogin(w, http.StatusOK, loginPageData{Title: "Signed out", Message: "You have been signed out."})
+}
+
+func bearerChallenge(code string) string {
+	return fmt.Sprintf(`Bearer realm="oauth", error=%q`, code)
+}
@@ -0,0 +1,284 @@
+package oauth
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/sol1corejz/auth-service/internal/lib/signingkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer = "https://sso.example.com"
+	testNonce  = "n-0S6_WzA2Mj"
+)
+
+var (
+	signingKeyOnce sync.Once
+	signingKey     *signingkey.Key
+	signingKeyErr  error
+)
+
+// testSigningKey returns a key shared by tests, as generating RSA keys is slow.
+func testSigningKey(t *testing.T) *signingkey.Key {
+	t.Helper()
+
+	signingKeyOnce.Do(func() {
+		signingKey, signingKeyErr = signingkey.Generate()
+	})
+	require.NoError(t, signingKeyErr)
+
+	return signingKey
+}
+
+func getJSON(t *testing.T, srv *httptest.Server, path string, accessToken string) (*http.Response, map[string]any) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	require.NoError(t, err)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	return resp, body
+}
+
+// signIn runs the authorization code flow with the scopes and returns the token response.
+func signIn(t *testing.T, srv *httptest.Server, scope string) map[string]any {
+	t.Helper()
+
+	params := authorizeParams()
+	params.Set("scope", scope)
+	params.Set("nonce", testNonce)
+
+	resp, _ := postLogin(t, srv, params, testLogin, testPassword)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.NotEmpty(t, location.Query().Get("code"))
+
+	resp, body := postToken(t, srv, exchangeForm(location.Query().Get("code"), testVerifier), "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	return body
+}
+
+// verifyIDToken checks the ID token against the published JWK Set, as a client would.
+func verifyIDToken(t *testing.T, srv *httptest.Server, idToken string) jwtlib.MapClaims {
+	t.Helper()
+
+	_, set := getJSON(t, srv, "/oauth/jwks", "")
+	keys, _ := set["keys"].([]any)
+	require.Len(t, keys, 1)
+	jwk, _ := keys[0].(map[string]any)
+
+	claims := jwtlib.MapClaims{}
+	token, err := jwtlib.ParseWithClaims(idToken, claims, func(token *jwtlib.Token) (interface{}, error) {
+		assert.Equal(t, jwk["kid"], token.Header["kid"])
+		return testSigningKey(t).Public(), nil
+	}, jwtlib.WithValidMethods([]string{"RS256"}), jwtlib.WithIssuer(testIssuer), jwtlib.WithAudience(testClientID))
+	require.NoError(t, err)
+	require.True(t, token.Valid)
+
+	return claims
+}
+
+func TestDiscovery(t *testing.T) {
+	srv := newServer(t)
+
+	resp, doc := getJSON(t, srv, "/.well-known/openid-configuration", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, testIssuer, doc["issuer"])
+	assert.Equal(t, testIssuer+"/oauth/authorize", doc["authorization_endpoint"])
+	assert.Equal(t, testIssuer+"/oauth/token", doc["token_endpoint"])
+	assert.Equal(t, testIssuer+"/oauth/userinfo", doc["userinfo_endpoint"])
+	assert.Equal(t, testIssuer+"/oauth/jwks", doc["jwks_uri"])
+	assert.Equal(t, testIssuer+"/oauth/logout", doc["end_session_endpoint"])
+	assert.Equal(t, testIssuer+"/oauth/device_authorization", doc["device_authorization_endpoint"])
+	assert.Contains(t, doc["grant_types_supported"], "urn:ietf:params:oauth:grant-type:device_code")
+	assert.Contains(t, doc["grant_types_supported"], "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	assert.ElementsMatch(t, []any{"openid", "email", "profile"}, doc["scopes_supported"])
+	assert.Equal(t, []any{"RS256"}, doc["id_token_signing_alg_values_supported"])
+
+	resp, set := getJSON(t, srv, "/oauth/jwks", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	keys, _ := set["keys"].([]any)
+	require.Len(t, keys, 1)
+	jwk, _ := keys[0].(map[string]any)
+	assert.Equal(t, "RSA", jwk["kty"])
+	assert.Equal(t, testSigningKey(t).ID, jwk["kid"])
+}
+
+func TestOpenIDFlow(t *testing.T) {
+	srv := newServer(t)
+
+	body := signIn(t, srv, "openid email profile")
+	assert.Equal(t, "openid email profile", body["scope"])
+	idToken, _ := body["id_token"].(string)
+	require.NotEmpty(t, idToken)
+
+	claims := verifyIDToken(t, srv, idToken)
+	assert.Equal(t, testUser.ID.String(), claims["sub"])
+	assert.Equal(t, testNonce, claims["nonce"])
+	assert.Equal(t, testLogin, claims["email"])
+	assert.Equal(t, "1", claims["acr"])
+	assert.Equal(t, []any{"pwd"}, claims["amr"])
+	assert.NotNil(t, claims["auth_time"])
+
+	accessToken, _ := body["access_token"].(string)
+	resp, info := getJSON(t, srv, "/oauth/userinfo", accessToken)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, testUser.ID.String(), info["sub"])
+	assert.Equal(t, testLogin, info["email"])
+}
+
+func TestOpenIDFlow_ScopesLimitClaims(t *testing.T) {
+	srv := newServer(t)
+
+	body := signIn(t, srv, "openid")
+	claims := verifyIDToken(t, srv, body["id_token"].(string))
+	assert.NotContains(t, claims, "email")
+
+	accessToken, _ := body["access_token"].(string)
+	_, info := getJSON(t, srv, "/oauth/userinfo", accessToken)
+	assert.Equal(t, map[string]any{"sub": testUser.ID.String()}, info)
+}
+
+func TestOAuthFlow_NoIDTokenWithoutOpenIDScope(t *testing.T) {
+	srv := newServer(t)
+
+	body := signIn(t, srv, "")
+	assert.NotContains(t, body, "id_token")
+
+	accessToken, _ := body["access_token"].(string)
+	resp, info := getJSON(t, srv, "/oauth/userinfo", accessToken)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, errInsufficientScope, info["error"])
+}
+
+func TestAuthorize_UnsupportedScope(t *testing.T) {
+	srv := newServer(t)
+
+	params := authorizeParams()
+	params.Set("scope", "openid admin")
+
+	resp, _ := getAuthorize(t, srv, params)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, errInvalidScope, location.Query().Get("error"))
+}
+
+func TestUserInfo_InvalidToken(t *testing.T) {
+	srv := newServer(t)
+
+	resp, body := getJSON(t, srv, "/oauth/userinfo", "forged")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, errInvalidToken, body["error"])
+	assert.Contains(t, resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`)
+
+	resp, _ = getJSON(t, srv, "/oauth/userinfo", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func getLogout(t *testing.T, srv *httptest.Server, params url.Values) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := noRedirects(srv).Get(srv.URL + "/oauth/logout?" + params.Encode())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(body)
+}
+
+func TestLogout(t *testing.T) {
+	srv, users := newOIDCServer(t, time.Minute)
+
+	body := signIn(t, srv, "openid")
+	accessToken, _ := body["access_token"].(string)
+
+	resp, _ := getLogout(t, srv, url.Values{
+		"id_token_hint":            {body["id_token"].(string)},
+		"post_logout_redirect_uri": {testPostLogoutRedirectURI},
+		"state":                    {testState},
+	})
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, testPostLogoutRedirectURI+"?state="+testState, resp.Header.Get("Location"))
+	assert.False(t, users.revokedAt().IsZero())
+
+	resp, _ = getJSON(t, srv, "/oauth/userinfo", accessToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "tokens of ended sessions are rejected")
+}
+
+func TestLogout_Rejected(t *testing.T) {
+	srv, users := newOIDCServer(t, time.Minute)
+
+	idToken, _ := signIn(t, srv, "openid")["id_token"].(string)
+
+	tests := []struct {
+		name   string
+		params url.Values
+	}{
+		{
+			name: "unregistered redirect uri",
+			params: url.Values{
+				"id_token_hint":            {idToken},
+				"post_logout_redirect_uri": {"https://evil.example.com/"},
+			},
+		},
+		{
+			name:   "redirect uri without client",
+			params: url.Values{"post_logout_redirect_uri": {testPostLogoutRedirectURI}},
+		},
+		{
+			name:   "forged hint",
+			params: url.Values{"id_token_hint": {"forged"}},
+		},
+		{
+			name:   "hint of another client",
+			params: url.Values{"id_token_hint": {idToken}, "client_id": {"7d7c2b7e-1f0e-4b57-9a55-6d3c1f0b2a12"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, page := getLogout(t, srv, tt.params)
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get("Location"))
+			assert.Contains(t, page, "Sign out")
+		})
+	}
+
+	assert.True(t, users.revokedAt().IsZero(), "rejected logouts keep sessions")
+}
+
+func TestLogout_WithoutRedirect(t *testing.T) {
+	srv := newServer(t)
+
+	resp, page := getLogout(t, srv, url.Values{"client_id": {testClientID}})
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, page, "You have been signed out.")
+}
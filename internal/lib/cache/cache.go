@@ -0,0 +1,104 @@
+// Package cache implements a bounded in-process cache with per-entry expiry.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache maps keys to values for a fixed time. It is safe for concurrent use.
+//
+// Invalidation bumps the cache generation. Values loaded before an invalidation
+// are dropped by Set, so a slow loader cannot put stale data back.
+type Cache[K comparable, V any] struct {
+	mu         sync.RWMutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[K]entry[V]
+	generation uint64
+	now        func() time.Time
+}
+
+// New returns a cache keeping values for ttl and holding at most maxEntries of them.
+func New[K comparable, V any](ttl time.Duration, maxEntries int) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[K]entry[V]),
+		now:        time.Now,
+	}
+}
+
+// Get returns the value of the key unless it is missing or expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || !c.now().Before(e.expiresAt) {
+		var zero V
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Generation returns the current generation. Take it before loading a value and pass it to Set.
+func (c *Cache[K, V]) Generation() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.generation
+}
+
+// Set stores the value loaded at the given generation. The value is dropped if the cache
+// was invalidated since, or if the cache is full even after expired entries are evicted.
+func (c *Cache[K, V]) Set(key K, value V, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+
+	c.entries[key] = entry[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Invalidate removes all values.
+func (c *Cache[K, V]) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	c.generation++
+}
+
+// InvalidateFunc removes values whose keys match.
+func (c *Cache[K, V]) InvalidateFunc(match func(K) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range c.entries {
+		if match(k) {
+			delete(c.entries, k)
+		}
+	}
+	c.generation++
+}
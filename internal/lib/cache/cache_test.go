@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCache(ttl time.Duration, maxEntries int) (*Cache[string, int], *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New[string, int](ttl, maxEntries)
+	c.now = func() time.Time { return now }
+
+	return c, &now
+}
+
+func TestCache_Expiry(t *testing.T) {
+	c, now := newTestCache(time.Minute, 10)
+
+	c.Set("a", 1, c.Generation())
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	*now = now.Add(time.Minute)
+
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	c, _ := newTestCache(time.Minute, 10)
+
+	c.Set("a", 1, c.Generation())
+	c.Set("b", 2, c.Generation())
+
+	c.InvalidateFunc(func(k string) bool { return k == "a" })
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	_, ok = c.Get("b")
+	assert.True(t, ok)
+
+	c.Invalidate()
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+}
+
+func TestCache_SetDropsValuesLoadedBeforeInvalidation(t *testing.T) {
+	c, _ := newTestCache(time.Minute, 10)
+
+	generation := c.Generation()
+	c.Invalidate()
+	c.Set("a", 1, generation)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	c, now := newTestCache(time.Minute, 2)
+
+	c.Set("a", 1, c.Generation())
+	c.Set("b", 2, c.Generation())
+	c.Set("c", 3, c.Generation())
+
+	_, ok := c.Get("c")
+	assert.False(t, ok, "full cache must not grow")
+
+	c.Set("a", 10, c.Generation())
+	v, _ := c.Get("a")
+	assert.Equal(t, 10, v, "existing keys are updated when full")
+
+	*now = now.Add(time.Minute)
+	c.Set("c", 3, c.Generation())
+
+	v, ok = c.Get("c")
+	assert.True(t, ok, "expired entries are evicted to make room")
+	assert.Equal(t, 3, v)
+}
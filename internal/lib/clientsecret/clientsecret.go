@@ -0,0 +1,20 @@
+// Package clientsecret generates secrets apps authenticate with as OAuth clients.
+// Secrets are stored as randtoken.Hash.
+package clientsecret
+
+import (
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+)
+
+// prefix makes leaked secrets easy to spot by secret scanners.
+const prefix = "cs_"
+
+// Generate returns a new random secret.
+func Generate() (string, error) {
+	return randtoken.Generate(prefix)
+}
+
+// Looks reports whether secret has the shape of a generated secret.
+func Looks(secret string) bool {
+	return randtoken.Looks(secret, prefix)
+}
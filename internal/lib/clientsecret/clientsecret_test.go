@@ -0,0 +1,24 @@
+package clientsecret
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	a, err := Generate()
+	require.NoError(t, err)
+	b, err := Generate()
+	require.NoError(t, err)
+
+	assert.NotEqual(t, a, b)
+	assert.True(t, Looks(a))
+}
+
+func TestLooks(t *testing.T) {
+	assert.False(t, Looks(""))
+	assert.False(t, Looks("cs_short"))
+	assert.False(t, Looks("password"))
+}
@@ -0,0 +1,40 @@
+package email
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxLen = 254
+
+var ErrInvalidEmail = errors.New("invalid email")
+
+// Normalize returns canonical form of email: trimmed, NFC-normalized and lower-cased.
+// Two emails that differ only in case, surrounding whitespace or Unicode
+// composition are normalized to the same value.
+func Normalize(email string) string {
+	return strings.ToLower(norm.NFC.String(strings.TrimSpace(email)))
+}
+
+// Validate checks that email is a bare address (no display name, no angle brackets).
+// Email is expected to be normalized already.
+func Validate(email string) error {
+	if email == "" || len(email) > maxLen {
+		return ErrInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 || !strings.Contains(email[at+1:], ".") {
+		return ErrInvalidEmail
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "lower case", in: "bob@x.com", want: "bob@x.com"},
+		{name: "mixed case", in: "Bob@X.Com", want: "bob@x.com"},
+		{name: "surrounding whitespace", in: "  bob@x.com\t\n", want: "bob@x.com"},
+		{name: "unicode whitespace", in: "\u00a0\u3000bob@x.com\u2028\v", want: "bob@x.com"},
+		{name: "decomposed unicode", in: "jose\u0301@x.com", want: "jos\u00e9@x.com"},
+		{name: "composed unicode upper", in: "JOS\u00c9@x.com", want: "jos\u00e9@x.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Normalize(tt.in))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{"bob@x.com", "first.last+tag@sub.example.org", "josé@x.com"}
+	for _, e := range valid {
+		assert.NoError(t, Validate(e), e)
+	}
+
+	invalid := []string{"", "bob", "bob@", "@x.com", "bob@x", "Bob <bob@x.com>", "<bob@x.com>", "bob@@x.com"}
+	for _, e := range invalid {
+		assert.ErrorIs(t, Validate(e), ErrInvalidEmail, e)
+	}
+}
@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/lib/publickey"
+)
+
+var ErrInvalidAssertion = errors.New("invalid assertion")
+
+// AssertionClaims are claims of a verified JWT bearer assertion (RFC 7523, section 3).
+type AssertionClaims struct {
+	Subject   string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// AssertionKey returns the subject of the assertion and the ID of the key it claims to be signed
+// with, without verifying anything, to find the key VerifyAssertion needs.
+func AssertionKey(tokenString string) (string, string, error) {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return "", "", ErrInvalidAssertion
+	}
+
+	sub, _ := token.Claims.GetSubject()
+	kid, _ := token.Header["kid"].(string)
+	if sub == "" {
+		return "", "", ErrInvalidAssertion
+	}
+
+	return sub, kid, nil
+}
+
+// VerifyAssertion verifies the assertion against the public key and returns its claims.
+// The assertion must be issued by its subject for one of audiences, have jti and exp,
+// and expire within maxLifetime, so that replays only have to be tracked for that long.
+func VerifyAssertion(tokenString string, key publickey.Key, audiences []string, maxLifetime time.Duration) (AssertionClaims, error) {
+	token, err := jwt.Parse(tokenString, func(*jwt.Token) (interface{}, error) {
+		return key.Public, nil
+	}, jwt.WithValidMethods([]string{key.Algorithm}), jwt.WithExpirationRequired(), jwt.WithLeeway(time.Minute))
+	if err != nil || !token.Valid {
+		return AssertionClaims{}, ErrInvalidAssertion
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return AssertionClaims{}, ErrInvalidAssertion
+	}
+
+	iss, _ := claims.GetIssuer()
+	sub, _ := claims.GetSubject()
+	aud, _ := claims.GetAudience()
+	jti, _ := claims["jti"].(string)
+	if sub == "" || iss != sub || jti == "" || !slices.ContainsFunc(aud, func(a string) bool {
+		return slices.Contains(audiences, a)
+	}) {
+		return AssertionClaims{}, ErrInvalidAssertion
+	}
+
+	expiresAt := unixClaim(claims, "exp")
+	if time.Until(expiresAt) > maxLifetime {
+		return AssertionClaims{}, ErrInvalidAssertion
+	}
+
+	return AssertionClaims{Subject: sub, ID: jti, ExpiresAt: expiresAt}, nil
+}
+
+// ServiceAccountClaims are claims of a valid, unexpired access token issued to a service account.
+type ServiceAccountClaims struct {
+	AccountID string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// NewServiceAccountToken issues an access token to the service account. Its sub is the account ID,
+// and it has no uid or email, so it is never accepted as a user's token.
+func NewServiceAccountToken(accountID uuid.UUID, duration time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(duration)
+
+	claims := jwt.MapClaims{
+		"sub":                accountID.String(),
+		"service_account_id": accountID.String(),
+		"iat":                now.Unix(),
+		"exp":                expiresAt.Unix(),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(GetSecretKey(jwtAccess)))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, time.Unix(expiresAt.Unix(), 0), nil
+}
+
+// ParseServiceAccountToken validates an access token issued by NewServiceAccountToken and returns its claims.
+func ParseServiceAccountToken(tokenString string) (ServiceAccountClaims, error) {
+	claims, err := parseAccessToken(tokenString)
+	if err != nil {
+		return ServiceAccountClaims{}, ErrAccessDenied
+	}
+
+	accountID, _ := claims["service_account_id"].(string)
+	if accountID == "" || claims["sub"] != accountID {
+		return ServiceAccountClaims{}, ErrAccessDenied
+	}
+
+	return ServiceAccountClaims{
+		AccountID: accountID,
+		IssuedAt:  unixClaim(claims, "iat"),
+		ExpiresAt: unixClaim(claims, "exp"),
+	}, nil
+}
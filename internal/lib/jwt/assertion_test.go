@@ -0,0 +1,107 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/lib/publickey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAudience = "https://sso.example.com/oauth/token"
+
+func signAssertion(t *testing.T, key *ecdsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = "key-1"
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key := publickey.Key{Public: &private.PublicKey, Algorithm: publickey.AlgorithmES256}
+
+	subject := uuid.NewString()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": subject,
+			"sub": subject,
+			"aud": testAudience,
+			"jti": uuid.NewString(),
+			"exp": time.Now().Add(5 * time.Minute).Unix(),
+		}
+	}
+
+	assertion := signAssertion(t, private, valid())
+
+	sub, kid, err := AssertionKey(assertion)
+	require.NoError(t, err)
+	assert.Equal(t, subject, sub)
+	assert.Equal(t, "key-1", kid)
+
+	claims, err := VerifyAssertion(assertion, key, []string{testAudience}, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, subject, claims.Subject)
+	assert.NotEmpty(t, claims.ID)
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{name: "another issuer", modify: func(c jwt.MapClaims) { c["iss"] = uuid.NewString() }},
+		{name: "another audience", modify: func(c jwt.MapClaims) { c["aud"] = "https://evil.example.com" }},
+		{name: "no jti", modify: func(c jwt.MapClaims) { delete(c, "jti") }},
+		{name: "no exp", modify: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "lives too long", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(2 * time.Hour).Unix() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+
+			_, err := VerifyAssertion(signAssertion(t, private, claims), key, []string{testAudience}, time.Hour)
+			assert.ErrorIs(t, err, ErrInvalidAssertion)
+		})
+	}
+
+	t.Run("another key", func(t *testing.T) {
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		_, err = VerifyAssertion(signAssertion(t, other, valid()), key, []string{testAudience}, time.Hour)
+		assert.ErrorIs(t, err, ErrInvalidAssertion)
+	})
+}
+
+func TestNewServiceAccountToken(t *testing.T) {
+	setSecrets(t)
+
+	accountID := uuid.New()
+
+	token, expiresAt, err := NewServiceAccountToken(accountID, time.Minute)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, 5*time.Second)
+
+	claims, err := ParseServiceAccountToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, accountID.String(), claims.AccountID)
+
+	_, err = ParseAccessToken(token)
+	assert.ErrorIs(t, err, ErrAccessDenied, "service account tokens are not user tokens")
+	_, err = ParseClientToken(token)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
@@ -0,0 +1,53 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sol1corejz/auth-service/internal/lib/signingkey"
+)
+
+// IDTokenHint is what an ID token sent back by a client tells about the session it was issued for.
+type IDTokenHint struct {
+	Subject  string
+	Audience []string
+	IssuedAt time.Time
+}
+
+// NewIDToken signs OpenID Connect ID token claims with the key. Unlike access tokens, ID tokens
+// are verified by clients, so they are signed with a public key published in the JWK Set.
+func NewIDToken(key *signingkey.Key, claims map[string]any) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims(claims))
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.Private)
+}
+
+// ParseIDTokenHint checks that the ID token was issued by this server and returns its claims.
+// Expiration is not checked: clients send ID tokens back at logout long after they expire.
+func ParseIDTokenHint(key *signingkey.Key, issuer string, tokenString string) (IDTokenHint, error) {
+	token, err := jwt.Parse(tokenString, func(*jwt.Token) (interface{}, error) {
+		return key.Public(), nil
+	}, jwt.WithValidMethods([]string{signingkey.Algorithm}), jwt.WithoutClaimsValidation())
+	if err != nil || !token.Valid {
+		return IDTokenHint{}, ErrAccessDenied
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return IDTokenHint{}, ErrAccessDenied
+	}
+
+	iss, _ := claims.GetIssuer()
+	sub, _ := claims.GetSubject()
+	aud, _ := claims.GetAudience()
+	if iss != issuer || sub == "" || len(aud) == 0 {
+		return IDTokenHint{}, ErrAccessDenied
+	}
+
+	return IDTokenHint{
+		Subject:  sub,
+		Audience: aud,
+		IssuedAt: unixClaim(claims, "iat"),
+	}, nil
+}
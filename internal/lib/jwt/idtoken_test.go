@@ -0,0 +1,49 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sol1corejz/auth-service/internal/lib/signingkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIssuer = "https://sso.example.com"
+
+func TestParseIDTokenHint(t *testing.T) {
+	key, err := signingkey.Generate()
+	require.NoError(t, err)
+	other, err := signingkey.Generate()
+	require.NoError(t, err)
+
+	issuedAt := time.Now().Add(-time.Hour)
+	claims := func(issuer string) map[string]any {
+		return map[string]any{
+			"iss": issuer,
+			"sub": "user",
+			"aud": "client",
+			"iat": issuedAt.Unix(),
+			"exp": issuedAt.Add(time.Minute).Unix(),
+		}
+	}
+
+	token, err := NewIDToken(key, claims(testIssuer))
+	require.NoError(t, err)
+
+	hint, err := ParseIDTokenHint(key, testIssuer, token)
+	require.NoError(t, err, "expired ID tokens are accepted as hints")
+	assert.Equal(t, "user", hint.Subject)
+	assert.Equal(t, []string{"client"}, hint.Audience)
+	assert.Equal(t, issuedAt.Unix(), hint.IssuedAt.Unix())
+
+	foreign, err := NewIDToken(other, claims(testIssuer))
+	require.NoError(t, err)
+	_, err = ParseIDTokenHint(key, testIssuer, foreign)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+
+	otherIssuer, err := NewIDToken(key, claims("https://evil.example.com"))
+	require.NoError(t, err)
+	_, err = ParseIDTokenHint(key, testIssuer, otherIssuer)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
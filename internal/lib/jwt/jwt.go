@@ -9,6 +9,7 @@ import (
 	"github.com/sol1corejz/auth-service/internal/domain/models"
 	log "log/slog"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -19,27 +20,52 @@ const (
 
 var ErrAccessDenied = errors.New("access denied")
 
-func NewTokenPair(user models.User, app models.App, accessDuration time.Duration, refreshDuration time.Duration) (string, string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"uid":    user.ID,
-		"email":  user.Email,
-		"exp":    time.Now().Add(accessDuration).Unix(),
-		"app_id": app.ID,
-	})
+// AccessClaims are claims of a valid, unexpired access token.
+type AccessClaims struct {
+	UserID    string
+	Email     string
+	AppID     string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// Auth comes from amr, auth_time and org_id; tokens issued before they were introduced have it empty.
+	Auth models.Authentication
+	ACR  models.ACR
+}
 
-	accessSecret := GetSecretKey(jwtAccess)
-	refreshSecret := GetSecretKey(jwtRefresh)
+// RefreshClaims are claims of a refresh token with valid signature.
+type RefreshClaims struct {
+	UserID   string
+	AppID    string
+	IssuedAt time.Time
+	Auth     models.Authentication
+}
 
-	accessTokenString, err := token.SignedString([]byte(accessSecret))
+// NewTokenPair issues access and refresh tokens of a session started with the given authentication.
+func NewTokenPair(
+	user models.User,
+	app models.App,
+	auth models.Authentication,
+	accessDuration time.Duration,
+	refreshDuration time.Duration,
+) (string, string, error) {
+	now := time.Now()
+
+	accessTokenString, err := newAccessToken(user, app, auth, auth.ACR(), now, accessDuration)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	refreshSecret := GetSecretKey(jwtRefresh)
+
+	refreshClaims := jwt.MapClaims{
 		"uid":    user.ID,
-		"exp":    time.Now().Add(refreshDuration).Unix(),
+		"iat":    now.Unix(),
+		"exp":    now.Add(refreshDuration).Unix(),
 		"app_id": app.ID,
-	})
+	}
+	setAuthClaims(refreshClaims, auth)
+
+	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
 
 	refreshTokenString, err := refreshToken.SignedString([]byte(refreshSecret))
 	if err != nil {
@@ -49,6 +75,54 @@ func NewTokenPair(user models.User, app models.App, accessDuration time.Duration
 	return accessTokenString, refreshTokenString, nil
 }
 
+// NewStepUpToken issues a short-lived access token with models.ACRStepUp
+// after a factor was re-verified within a session. It comes without a refresh token.
+func NewStepUpToken(user models.User, app models.App, auth models.Authentication, duration time.Duration) (string, error) {
+	return newAccessToken(user, app, auth, models.ACRStepUp, time.Now(), duration)
+}
+
+func newAccessToken(
+	user models.User,
+	app models.App,
+	auth models.Authentication,
+	acr models.ACR,
+	now time.Time,
+	duration time.Duration,
+) (string, error) {
+	claims := jwt.MapClaims{
+		"uid":    user.ID,
+		"email":  user.Email,
+		"iat":    now.Unix(),
+		"exp":    now.Add(duration).Unix(),
+		"app_id": app.ID,
+		"acr":    acr,
+	}
+	setAuthClaims(claims, auth)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(GetSecretKey(jwtAccess)))
+}
+
+// setAuthClaims sets org_id of org-scoped sessions, scope of sessions started by OAuth clients,
+// and amr and auth_time unless the authentication is unknown, as for sessions refreshed
+// from tokens issued before these claims existed.
+func setAuthClaims(claims jwt.MapClaims, auth models.Authentication) {
+	if auth.OrgID != uuid.Nil {
+		claims["org_id"] = auth.OrgID
+	}
+	if len(auth.Scopes) > 0 {
+		claims["scope"] = strings.Join(auth.Scopes, " ")
+	}
+
+	if auth.Time.IsZero() {
+		return
+	}
+
+	claims["amr"] = auth.Methods
+	claims["auth_time"] = auth.Time.Unix()
+}
+
 // CheckTokens проверяет валидность токенов и возвращает:
 // 1. Если оба токена валидны: true, текущие токены, nil
 // 2. Если refresh валиден, а access нет: true, новые токены, nil
@@ -73,6 +147,7 @@ func CheckTokens(
 	// Получаем claims из refresh токена (если он валиден)
 	var user models.User
 	var app models.App
+	var auth models.Authentication
 	if refreshValid {
 		if claims, ok := refreshTokenObj.Claims.(jwt.MapClaims); ok {
 			// Приводим типы, так как jwt возвращает float64 для чисел
@@ -93,6 +168,8 @@ func CheckTokens(
 				}
 				app.ID = ID
 			}
+			// Refreshed tokens keep how and when the session was authenticated.
+			auth = authFromClaims(claims)
 		}
 	}
 
@@ -106,7 +183,7 @@ func CheckTokens(
 
 	// Случай 2: Refresh валиден, access нет
 	if refreshValid {
-		newAccess, newRefresh, err := NewTokenPair(user, app, accessDuration, refreshDuration)
+		newAccess, newRefresh, err := NewTokenPair(user, app, auth, accessDuration, refreshDuration)
 		if err != nil {
 			return false, nil, fmt.Errorf("failed to generate new tokens: %w", err)
 		}
@@ -180,6 +257,91 @@ func parseAccessToken(tokenString string) (jwt.MapClaims, error) {
 	return claims, nil
 }
 
+// ParseAccessToken validates access token and returns its claims.
+func ParseAccessToken(tokenString string) (AccessClaims, error) {
+	if !validateAccessToken(tokenString) {
+		return AccessClaims{}, ErrAccessDenied
+	}
+
+	claims, err := parseAccessToken(tokenString)
+	if err != nil {
+		return AccessClaims{}, ErrAccessDenied
+	}
+
+	acr, _ := claims["acr"].(string)
+	if !models.ACR(acr).Valid() {
+		acr = string(models.ACRSingleFactor)
+	}
+
+	return AccessClaims{
+		UserID:    claims["uid"].(string),
+		Email:     claims["email"].(string),
+		AppID:     claims["app_id"].(string),
+		IssuedAt:  unixClaim(claims, "iat"),
+		ExpiresAt: unixClaim(claims, "exp"),
+		Auth:      authFromClaims(claims),
+		ACR:       models.ACR(acr),
+	}, nil
+}
+
+// ParseRefreshToken validates refresh token and returns its claims.
+// Tokens issued before iat was introduced have zero IssuedAt.
+func ParseRefreshToken(tokenString string) (RefreshClaims, error) {
+	token, err := validateRefreshToken(tokenString)
+	if err != nil || !token.Valid {
+		return RefreshClaims{}, ErrAccessDenied
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return RefreshClaims{}, ErrAccessDenied
+	}
+
+	uid, _ := claims["uid"].(string)
+	if uid == "" {
+		return RefreshClaims{}, ErrAccessDenied
+	}
+	appID, _ := claims["app_id"].(string)
+
+	return RefreshClaims{
+		UserID:   uid,
+		AppID:    appID,
+		IssuedAt: unixClaim(claims, "iat"),
+		Auth:     authFromClaims(claims),
+	}, nil
+}
+
+// authFromClaims reads amr, auth_time, org_id and scope.
+func authFromClaims(claims jwt.MapClaims) models.Authentication {
+	auth := models.Authentication{Time: unixClaim(claims, "auth_time")}
+
+	if scope, ok := claims["scope"].(string); ok {
+		auth.Scopes = strings.Fields(scope)
+	}
+
+	if orgID, ok := claims["org_id"].(string); ok {
+		auth.OrgID, _ = uuid.Parse(orgID)
+	}
+
+	amr, _ := claims["amr"].([]interface{})
+	for _, method := range amr {
+		if m, ok := method.(string); ok {
+			auth.Methods = append(auth.Methods, models.AuthMethod(m))
+		}
+	}
+
+	return auth
+}
+
+// unixClaim returns a NumericDate claim, or zero time if it is missing.
+func unixClaim(claims jwt.MapClaims, name string) time.Time {
+	if v, ok := claims[name].(float64); ok {
+		return time.Unix(int64(v), 0)
+	}
+
+	return time.Time{}
+}
+
 func validateRefreshToken(tokenString string) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -216,6 +378,6 @@ func GetSecretKey(tokenType string) string {
 		}
 	}
 
-	log.Error("%s not found in environment or .env file", tokenType)
+	log.Error("secret not found in environment or .env file", log.String("key", tokenType))
 	return ""
 }
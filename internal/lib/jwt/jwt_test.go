@@ -0,0 +1,149 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setSecrets(t *testing.T) {
+	t.Setenv(jwtAccess, "test-access-secret")
+	t.Setenv(jwtRefresh, "test-refresh-secret")
+}
+
+func TestNewTokenPair_AuthClaims(t *testing.T) {
+	setSecrets(t)
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com"}
+	app := models.App{ID: uuid.New()}
+	auth := models.NewAuthentication(models.AuthMethodPassword, models.AuthMethodOTP)
+
+	access, refresh, err := NewTokenPair(user, app, auth, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := ParseAccessToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, models.ACRMultiFactor, claims.ACR)
+	assert.Equal(t,
+		[]models.AuthMethod{models.AuthMethodPassword, models.AuthMethodOTP, models.AuthMethodMultiFactor},
+		claims.Auth.Methods,
+	)
+	assert.Equal(t, auth.Time.Unix(), claims.Auth.Time.Unix())
+
+	refreshClaims, err := ParseRefreshToken(refresh)
+	require.NoError(t, err)
+	assert.Equal(t, claims.Auth, refreshClaims.Auth)
+}
+
+func TestNewStepUpToken(t *testing.T) {
+	setSecrets(t)
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com"}
+	app := models.App{ID: uuid.New()}
+
+	token, err := NewStepUpToken(user, app, models.NewAuthentication(models.AuthMethodPassword), time.Minute)
+	require.NoError(t, err)
+
+	claims, err := ParseAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, models.ACRStepUp, claims.ACR)
+	assert.True(t, claims.ACR.AtLeast(models.ACRMultiFactor))
+	assert.WithinDuration(t, time.Now().Add(time.Minute), claims.ExpiresAt, 5*time.Second)
+}
+
+func TestParseAccessToken_WithoutAuthClaims(t *testing.T) {
+	setSecrets(t)
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com"}
+	app := models.App{ID: uuid.New()}
+
+	// Tokens refreshed from sessions that predate amr and auth_time.
+	access, _, err := NewTokenPair(user, app, models.Authentication{}, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := ParseAccessToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, models.ACRSingleFactor, claims.ACR)
+	assert.Empty(t, claims.Auth.Methods)
+	assert.True(t, claims.Auth.Time.IsZero())
+}
+
+func TestNewTokenPair_OrgScoped(t *testing.T) {
+	setSecrets(t)
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com"}
+	app := models.App{ID: uuid.New()}
+	auth := models.NewAuthentication(models.AuthMethodPassword)
+	auth.OrgID = uuid.New()
+
+	access, refresh, err := NewTokenPair(user, app, auth, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := ParseAccessToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, auth.OrgID, claims.Auth.OrgID)
+
+	refreshClaims, err := ParseRefreshToken(refresh)
+	require.NoError(t, err)
+	assert.Equal(t, auth.OrgID, refreshClaims.Auth.OrgID, "refreshed sessions stay in the org")
+
+	stepUp, err := NewStepUpToken(user, app, claims.Auth.With(models.AuthMethodOTP), time.Minute)
+	require.NoError(t, err)
+
+	stepUpClaims, err := ParseAccessToken(stepUp)
+	require.NoError(t, err)
+	assert.Equal(t, auth.OrgID, stepUpClaims.Auth.OrgID)
+}
+
+func TestNewClientToken(t *testing.T) {
+	setSecrets(t)
+
+	app := models.App{ID: uuid.New()}
+
+	token, expiresAt, err := NewClientToken(app, []string{"reports:read", "reports:write"}, time.Minute)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, 5*time.Second)
+
+	claims, err := ParseClientToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, app.ID.String(), claims.ClientID)
+	assert.Equal(t, []string{"reports:read", "reports:write"}, claims.Scopes)
+
+	_, err = ParseAccessToken(token)
+	assert.ErrorIs(t, err, ErrAccessDenied, "client tokens must not pass as user tokens")
+}
+
+func TestParseClientToken_UserToken(t *testing.T) {
+	setSecrets(t)
+
+	access, _, err := NewTokenPair(
+		models.User{ID: uuid.New(), Email: "alice@example.com"}, models.App{ID: uuid.New()},
+		models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour,
+	)
+	require.NoError(t, err)
+
+	_, err = ParseClientToken(access)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
+
+func TestNewTokenPair_Scopes(t *testing.T) {
+	setSecrets(t)
+
+	auth := models.NewAuthentication(models.AuthMethodPassword)
+	auth.Scopes = []string{"openid", "email"}
+
+	access, refresh, err := NewTokenPair(models.User{ID: uuid.New(), Email: "alice@example.com"}, models.App{ID: uuid.New()}, auth, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	claims, err := ParseAccessToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, auth.Scopes, claims.Auth.Scopes)
+
+	refreshClaims, err := ParseRefreshToken(refresh)
+	require.NoError(t, err)
+	assert.Equal(t, auth.Scopes, refreshClaims.Auth.Scopes, "refreshed sessions keep granted scopes")
+}
@@ -0,0 +1,20 @@
+// Package pat generates personal access tokens users create for scripts.
+// Tokens are stored as randtoken.Hash.
+package pat
+
+import (
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+)
+
+// prefix makes leaked tokens easy to spot by secret scanners and tells them from JWTs.
+const prefix = "pat_"
+
+// Generate returns a new random token.
+func Generate() (string, error) {
+	return randtoken.Generate(prefix)
+}
+
+// Looks reports whether token has the shape of a generated token.
+func Looks(token string) bool {
+	return randtoken.Looks(token, prefix)
+}
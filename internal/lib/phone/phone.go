@@ -0,0 +1,46 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	minDigits = 8
+	maxDigits = 15
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// Normalize converts phone number to E.164 form ("+" followed by digits).
+// Spaces, dashes, dots and parentheses are dropped; leading "00" is treated as "+".
+func Normalize(number string) (string, error) {
+	number = strings.TrimSpace(number)
+	if strings.HasPrefix(number, "00") {
+		number = "+" + number[2:]
+	}
+
+	if !strings.HasPrefix(number, "+") {
+		return "", ErrInvalidPhone
+	}
+
+	var b strings.Builder
+	b.WriteByte('+')
+	for _, r := range number[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	normalized := b.String()
+	digits := len(normalized) - 1
+	if digits < minDigits || digits > maxDigits || normalized[1] == '0' {
+		return "", ErrInvalidPhone
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,27 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	valid := map[string]string{
+		"+7 (999) 123-45-67": "+79991234567",
+		"0044 20 7946 0958":  "+442079460958",
+		" +1.415.555.2671 ":  "+14155552671",
+	}
+	for in, want := range valid {
+		got, err := Normalize(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got)
+	}
+
+	invalid := []string{"", "89991234567", "+", "+0123456789", "+7999abc4567", "+1234567", "+1234567890123456"}
+	for _, in := range invalid {
+		_, err := Normalize(in)
+		assert.ErrorIs(t, err, ErrInvalidPhone, in)
+	}
+}
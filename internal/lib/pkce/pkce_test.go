@@ -0,0 +1,47 @@
+package pkce
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChallenge(t *testing.T) {
+	// Example from RFC 7636, appendix B.
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.Equal(t, challenge, Challenge(verifier))
+	assert.True(t, ValidChallenge(challenge))
+	assert.True(t, Verify(verifier, challenge))
+}
+
+func TestVerify(t *testing.T) {
+	verifier := strings.Repeat("a", 43)
+	challenge := Challenge(verifier)
+
+	tests := []struct {
+		name     string
+		verifier string
+		want     bool
+	}{
+		{name: "matching", verifier: verifier, want: true},
+		{name: "other verifier", verifier: strings.Repeat("b", 43)},
+		{name: "too short", verifier: strings.Repeat("a", 42)},
+		{name: "too long", verifier: strings.Repeat("a", 129)},
+		{name: "reserved characters", verifier: strings.Repeat("a", 42) + "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Verify(tt.verifier, challenge))
+		})
+	}
+}
+
+func TestValidChallenge(t *testing.T) {
+	assert.False(t, ValidChallenge(""))
+	assert.False(t, ValidChallenge("plain-challenge"))
+	assert.False(t, ValidChallenge(strings.Repeat("a", 42)+"="))
+}
@@ -0,0 +1,397 @@
+// Package policy evaluates attribute-based authorization rules.
+//
+// A policy is a list of rules. A rule applies to an action when one of its action
+// patterns matches it ("users:read", "users:*" or "*") and all of its conditions hold.
+// Deny rules override allow rules; an action no rule applies to is denied.
+//
+// Conditions compare attributes addressed by dotted paths under "subject", "resource"
+// and "context", e.g. "subject.attributes.region", with a literal value or another attribute:
+//
+//	rules:
+//	  - id: support-reads-users-in-region
+//	    effect: allow
+//	    actions: ["users:read"]
+//	    when:
+//	      - {attr: subject.roles, op: contains, value: support}
+//	      - {attr: resource.region, op: eq, ref: subject.attributes.region}
+//	      - {attr: context.hour, op: gte, value: 9}
+//	      - {attr: context.hour, op: lt, value: 18}
+//
+// Rules marked dry_run do not affect decisions. Evaluate reports what the decision
+// would be with them enforced, so a rule can be watched before it is rolled out.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+type Op string
+
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpIn       Op = "in"
+	OpNotIn    Op = "not_in"
+	OpContains Op = "contains"
+	OpGt       Op = "gt"
+	OpGte      Op = "gte"
+	OpLt       Op = "lt"
+	OpLte      Op = "lte"
+	OpExists   Op = "exists"
+)
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Condition compares attribute Attr with either literal Value or attribute Ref.
+type Condition struct {
+	Attr  string `yaml:"attr"`
+	Op    Op     `yaml:"op"`
+	Value any    `yaml:"value"`
+	Ref   string `yaml:"ref"`
+}
+
+type Rule struct {
+	ID          string      `yaml:"id"`
+	Description string      `yaml:"description"`
+	Effect      Effect      `yaml:"effect"`
+	Actions     []string    `yaml:"actions"`
+	When        []Condition `yaml:"when"`
+	DryRun      bool        `yaml:"dry_run"`
+}
+
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Input holds attributes of the subject, the resource and the request context.
+type Input struct {
+	Subject  map[string]any
+	Resource map[string]any
+	Context  map[string]any
+}
+
+// Decision is the outcome of evaluation. Rule is the ID of the rule that decided it,
+// empty if no rule applied and the action was denied by default.
+type Decision struct {
+	Allowed bool
+	Rule    string
+}
+
+// Result holds the enforced decision and the decision with dry-run rules enforced too.
+type Result struct {
+	Decision
+	DryRun Decision
+}
+
+// Changed tells whether enforcing dry-run rules would change the decision.
+func (r Result) Changed() bool {
+	return r.Decision != r.DryRun
+}
+
+// Load reads and validates a YAML policy file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse decodes and validates a YAML policy.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p *Policy) validate() error {
+	ids := make(map[string]struct{}, len(p.Rules))
+
+	for i, rule := range p.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("%w: rule %d has no id", ErrInvalidPolicy, i)
+		}
+		if _, ok := ids[rule.ID]; ok {
+			return fmt.Errorf("%w: duplicate rule id %q", ErrInvalidPolicy, rule.ID)
+		}
+		ids[rule.ID] = struct{}{}
+
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("%w: rule %q: unknown effect %q", ErrInvalidPolicy, rule.ID, rule.Effect)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("%w: rule %q has no actions", ErrInvalidPolicy, rule.ID)
+		}
+
+		for _, c := range rule.When {
+			if err := c.validate(); err != nil {
+				return fmt.Errorf("%w: rule %q: %v", ErrInvalidPolicy, rule.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c Condition) validate() error {
+	if !validPath(c.Attr) {
+		return fmt.Errorf("invalid attribute %q", c.Attr)
+	}
+	if c.Ref != "" && !validPath(c.Ref) {
+		return fmt.Errorf("invalid reference %q", c.Ref)
+	}
+	if c.Ref != "" && c.Value != nil {
+		return fmt.Errorf("condition on %q has both value and ref", c.Attr)
+	}
+
+	switch c.Op {
+	case OpEq, OpNe, OpContains, OpGt, OpGte, OpLt, OpLte:
+	case OpIn, OpNotIn:
+		if _, ok := c.Value.([]any); !ok && c.Ref == "" {
+			return fmt.Errorf("%s on %q needs a list", c.Op, c.Attr)
+		}
+	case OpExists:
+		if _, ok := c.Value.(bool); !ok {
+			return fmt.Errorf("exists on %q needs true or false", c.Attr)
+		}
+	default:
+		return fmt.Errorf("unknown op %q", c.Op)
+	}
+
+	return nil
+}
+
+func validPath(path string) bool {
+	root, rest, ok := strings.Cut(path, ".")
+	if !ok || rest == "" {
+		return false
+	}
+
+	return root == "subject" || root == "resource" || root == "context"
+}
+
+// Evaluate decides whether the action is allowed for the input.
+func (p *Policy) Evaluate(action string, in Input) Result {
+	attrs := map[string]any{
+		"subject":  in.Subject,
+		"resource": in.Resource,
+		"context":  in.Context,
+	}
+
+	var enforced, all combiner
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.applies(action, attrs) {
+			continue
+		}
+
+		all.add(rule)
+		if !rule.DryRun {
+			enforced.add(rule)
+		}
+	}
+
+	return Result{Decision: enforced.decision(), DryRun: all.decision()}
+}
+
+// combiner implements deny-overrides: the first applicable deny rule wins,
+// otherwise the first applicable allow rule.
+type combiner struct {
+	allow, deny *Rule
+}
+
+func (c *combiner) add(rule *Rule) {
+	if rule.Effect == EffectDeny && c.deny == nil {
+		c.deny = rule
+	}
+	if rule.Effect == EffectAllow && c.allow == nil {
+		c.allow = rule
+	}
+}
+
+func (c *combiner) decision() Decision {
+	switch {
+	case c.deny != nil:
+		return Decision{Allowed: false, Rule: c.deny.ID}
+	case c.allow != nil:
+		return Decision{Allowed: true, Rule: c.allow.ID}
+	default:
+		return Decision{}
+	}
+}
+
+func (r *Rule) applies(action string, attrs map[string]any) bool {
+	matched := false
+	for _, pattern := range r.Actions {
+		if matchAction(pattern, action) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	for _, c := range r.When {
+		if !c.holds(attrs) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchAction matches "*", exact actions and prefixes like "users:*".
+func matchAction(pattern, action string) bool {
+	if pattern == "*" || pattern == action {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(pattern, "*")
+
+	return ok && strings.HasPrefix(action, prefix)
+}
+
+func (c Condition) holds(attrs map[string]any) bool {
+	actual, found := lookup(attrs, c.Attr)
+
+	if c.Op == OpExists {
+		return found == c.Value.(bool)
+	}
+	if !found {
+		// Missing attributes never satisfy a comparison, so a rule can't be
+		// tricked into applying by leaving an attribute out.
+		return false
+	}
+
+	expected := c.Value
+	if c.Ref != "" {
+		ref, ok := lookup(attrs, c.Ref)
+		if !ok {
+			return false
+		}
+		expected = ref
+	}
+
+	switch c.Op {
+	case OpEq:
+		return equal(actual, expected)
+	case OpNe:
+		return !equal(actual, expected)
+	case OpIn:
+		return contains(expected, actual)
+	case OpNotIn:
+		return !contains(expected, actual)
+	case OpContains:
+		return contains(actual, expected)
+	case OpGt, OpGte, OpLt, OpLte:
+		a, ok1 := number(actual)
+		b, ok2 := number(expected)
+		if !ok1 || !ok2 {
+			return false
+		}
+
+		switch c.Op {
+		case OpGt:
+			return a > b
+		case OpGte:
+			return a >= b
+		case OpLt:
+			return a < b
+		default:
+			return a <= b
+		}
+	default:
+		return false
+	}
+}
+
+func lookup(attrs map[string]any, path string) (any, bool) {
+	var current any = attrs
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, current != nil
+}
+
+func equal(a, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+
+	return fmt.Sprint(a) == fmt.Sprint(b) && sameKind(a, b)
+}
+
+// sameKind keeps the string "true" from equalling the boolean true.
+func sameKind(a, b any) bool {
+	_, aStr := a.(string)
+	_, bStr := b.(string)
+
+	return aStr == bStr
+}
+
+func contains(list any, item any) bool {
+	switch l := list.(type) {
+	case []any:
+		for _, v := range l {
+			if equal(v, item) {
+				return true
+			}
+		}
+	case []string:
+		for _, v := range l {
+			if equal(v, item) {
+				return true
+			}
+		}
+	case string:
+		s, ok := item.(string)
+		return ok && strings.Contains(l, s)
+	}
+
+	return false
+}
+
+// number converts numeric attributes, which come as float64 from JSON and as int from YAML.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
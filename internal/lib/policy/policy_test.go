@@ -0,0 +1,180 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+rules:
+  - id: support-reads-users-in-region
+    effect: allow
+    actions: ["users:read"]
+    when:
+      - {attr: subject.roles, op: contains, value: support}
+      - {attr: resource.region, op: eq, ref: subject.attributes.region}
+      - {attr: context.hour, op: gte, value: 9}
+      - {attr: context.hour, op: lt, value: 18}
+  - id: admins-do-anything
+    effect: allow
+    actions: ["*"]
+    when:
+      - {attr: subject.roles, op: contains, value: admin}
+  - id: nobody-touches-suspended
+    effect: deny
+    actions: ["users:*"]
+    when:
+      - {attr: resource.status, op: in, value: [suspended, deactivated]}
+  - id: block-weekends
+    effect: deny
+    actions: ["users:*"]
+    dry_run: true
+    when:
+      - {attr: context.weekday, op: in, value: [Saturday, Sunday]}
+`
+
+func mustParse(t *testing.T) *Policy {
+	t.Helper()
+
+	p, err := Parse([]byte(testPolicy))
+	require.NoError(t, err)
+
+	return p
+}
+
+func support(region string) map[string]any {
+	return map[string]any{
+		"roles":      []string{"support"},
+		"attributes": map[string]any{"region": region},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	p := mustParse(t)
+
+	tests := []struct {
+		name   string
+		action string
+		in     Input
+		want   Decision
+	}{
+		{
+			name:   "support in region during business hours",
+			action: "users:read",
+			in: Input{
+				Subject:  support("eu"),
+				Resource: map[string]any{"region": "eu"},
+				Context:  map[string]any{"hour": 10},
+			},
+			want: Decision{Allowed: true, Rule: "support-reads-users-in-region"},
+		},
+		{
+			name:   "support in another region",
+			action: "users:read",
+			in: Input{
+				Subject:  support("eu"),
+				Resource: map[string]any{"region": "us"},
+				Context:  map[string]any{"hour": 10},
+			},
+			want: Decision{},
+		},
+		{
+			name:   "support after hours",
+			action: "users:read",
+			in: Input{
+				Subject:  support("eu"),
+				Resource: map[string]any{"region": "eu"},
+				Context:  map[string]any{"hour": 18.0},
+			},
+			want: Decision{},
+		},
+		{
+			name:   "support writing",
+			action: "users:write",
+			in: Input{
+				Subject:  support("eu"),
+				Resource: map[string]any{"region": "eu"},
+				Context:  map[string]any{"hour": 10},
+			},
+			want: Decision{},
+		},
+		{
+			name:   "missing attributes do not match",
+			action: "users:read",
+			in: Input{
+				Subject:  map[string]any{"roles": []string{"support"}},
+				Resource: map[string]any{},
+				Context:  map[string]any{"hour": 10},
+			},
+			want: Decision{},
+		},
+		{
+			name:   "deny overrides allow",
+			action: "users:read",
+			in: Input{
+				Subject:  map[string]any{"roles": []string{"admin"}},
+				Resource: map[string]any{"status": "suspended"},
+			},
+			want: Decision{Allowed: false, Rule: "nobody-touches-suspended"},
+		},
+		{
+			name:   "wildcard action",
+			action: "apps:delete",
+			in: Input{
+				Subject: map[string]any{"roles": []any{"admin"}},
+			},
+			want: Decision{Allowed: true, Rule: "admins-do-anything"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := p.Evaluate(tt.action, tt.in)
+			assert.Equal(t, tt.want, res.Decision)
+			assert.False(t, res.Changed())
+		})
+	}
+}
+
+func TestEvaluate_DryRun(t *testing.T) {
+	p := mustParse(t)
+
+	res := p.Evaluate("users:read", Input{
+		Subject:  support("eu"),
+		Resource: map[string]any{"region": "eu"},
+		Context:  map[string]any{"hour": 10, "weekday": "Sunday"},
+	})
+
+	assert.Equal(t, Decision{Allowed: true, Rule: "support-reads-users-in-region"}, res.Decision)
+	assert.Equal(t, Decision{Allowed: false, Rule: "block-weekends"}, res.DryRun)
+	assert.True(t, res.Changed())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no id":          `rules: [{effect: allow, actions: [a]}]`,
+		"duplicate id":   `rules: [{id: a, effect: allow, actions: [a]}, {id: a, effect: deny, actions: [a]}]`,
+		"unknown effect": `rules: [{id: a, effect: maybe, actions: [a]}]`,
+		"no actions":     `rules: [{id: a, effect: allow}]`,
+		"unknown op":     `rules: [{id: a, effect: allow, actions: [a], when: [{attr: subject.x, op: like, value: 1}]}]`,
+		"unknown root":   `rules: [{id: a, effect: allow, actions: [a], when: [{attr: user.x, op: eq, value: 1}]}]`,
+		"in needs list":  `rules: [{id: a, effect: allow, actions: [a], when: [{attr: subject.x, op: in, value: 1}]}]`,
+		"value and ref":  `rules: [{id: a, effect: allow, actions: [a], when: [{attr: subject.x, op: eq, value: 1, ref: resource.x}]}]`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			assert.ErrorIs(t, err, ErrInvalidPolicy)
+		})
+	}
+}
+
+func TestEqual_Types(t *testing.T) {
+	assert.True(t, equal(1, 1.0))
+	assert.False(t, equal("true", true))
+	assert.False(t, equal("1", 1))
+	assert.True(t, equal("eu", "eu"))
+}
@@ -0,0 +1,70 @@
+// Package publickey parses public keys service accounts register to sign JWT assertions with.
+package publickey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// JWS algorithms of supported keys.
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+const minRSABits = 2048
+
+var ErrUnsupportedKey = errors.New("unsupported public key")
+
+// Key is a public key with the JWS algorithm assertions signed by its private key use.
+type Key struct {
+	Public    crypto.PublicKey
+	Algorithm string
+}
+
+// ParsePEM parses a PEM-encoded PKIX public key, i.e. a "PUBLIC KEY" block, and returns it
+// with its DER encoding to store. RSA keys of at least 2048 bits and P-256 keys are supported.
+func ParsePEM(pemKey []byte) (Key, []byte, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return Key{}, nil, fmt.Errorf("%w: no PEM public key block", ErrUnsupportedKey)
+	}
+
+	key, err := ParseDER(block.Bytes)
+	if err != nil {
+		return Key{}, nil, err
+	}
+
+	return key, block.Bytes, nil
+}
+
+// ParseDER parses a key stored by ParsePEM.
+func ParseDER(der []byte) (Key, error) {
+	public, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: %w", ErrUnsupportedKey, err)
+	}
+
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("%w: rsa keys must have at least %d bits", ErrUnsupportedKey, minRSABits)
+		}
+
+		return Key{Public: public, Algorithm: AlgorithmRS256}, nil
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return Key{}, fmt.Errorf("%w: ecdsa keys must use P-256", ErrUnsupportedKey)
+		}
+
+		return Key{Public: public, Algorithm: AlgorithmES256}, nil
+	default:
+		return Key{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, public)
+	}
+}
@@ -0,0 +1,67 @@
+package publickey
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, public any) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestParsePEM(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	key, der, err := ParsePEM(encode(t, &ecKey.PublicKey))
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmES256, key.Algorithm)
+	assert.True(t, ecKey.PublicKey.Equal(key.Public))
+
+	stored, err := ParseDER(der)
+	require.NoError(t, err)
+	assert.Equal(t, key, stored)
+
+	key, _, err = ParsePEM(encode(t, &rsaKey.PublicKey))
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmRS256, key.Algorithm)
+}
+
+func TestParsePEM_Unsupported(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{name: "not pem", key: []byte("ssh-ed25519 AAAA")},
+		{name: "private key block", key: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1}})},
+		{name: "p-384", key: encode(t, &p384.PublicKey)},
+		{name: "weak rsa", key: encode(t, &weak.PublicKey)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParsePEM(tt.key)
+			assert.ErrorIs(t, err, ErrUnsupportedKey)
+		})
+	}
+}
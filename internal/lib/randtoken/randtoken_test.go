@@ -0,0 +1,27 @@
+package randtoken
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	a, err := Generate("cs_")
+	require.NoError(t, err)
+	b, err := Generate("cs_")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, a, b)
+	assert.True(t, Looks(a, "cs_"))
+	assert.False(t, Looks(a, "pat_"))
+	assert.NotEqual(t, Hash(a), Hash(b))
+	assert.Equal(t, Hash(a), Hash(a))
+}
+
+func TestLooks(t *testing.T) {
+	assert.False(t, Looks("", "cs_"))
+	assert.False(t, Looks("cs_short", "cs_"))
+	assert.False(t, Looks("password", ""))
+}
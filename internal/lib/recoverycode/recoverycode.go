@@ -0,0 +1,66 @@
+package recoverycode
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+)
+
+// Count is how many recovery codes a user gets at once.
+const Count = 10
+
+// alphabet leaves out characters that are easy to confuse when read from paper.
+const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+const (
+	groupLen = 5
+	groups   = 2
+)
+
+// Generate returns n random codes formatted as "xxxxx-xxxxx".
+func Generate(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	buf := make([]byte, groupLen*groups)
+
+	for i := 0; i < n; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		var b strings.Builder
+		for j, c := range buf {
+			if j > 0 && j%groupLen == 0 {
+				b.WriteByte('-')
+			}
+			// Modulo bias is negligible for 31 symbols out of 256 against online guessing.
+			b.WriteByte(alphabet[int(c)%len(alphabet)])
+		}
+		codes = append(codes, b.String())
+	}
+
+	return codes, nil
+}
+
+// Normalize drops separators and case so codes typed by hand still match.
+func Normalize(code string) string {
+	code = strings.ToLower(code)
+
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// Looks reports whether code has the shape of a recovery code rather than a TOTP code.
+func Looks(code string) bool {
+	return len(Normalize(code)) == groupLen*groups
+}
+
+// Hash returns hex SHA-256 of the normalized code. Codes are random enough not to need a slow hash.
+func Hash(code string) string {
+	return randtoken.Hash(Normalize(code))
+}
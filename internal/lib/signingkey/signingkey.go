@@ -0,0 +1,125 @@
+// Package signingkey holds the RSA key ID tokens are signed with and publishes it as a JWK Set.
+package signingkey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Algorithm is the JWS algorithm of the key.
+const Algorithm = "RS256"
+
+const generatedBits = 2048
+
+// Key is an RSA private key with its key ID, the RFC 7638 thumbprint of the public key.
+type Key struct {
+	ID      string
+	Private *rsa.PrivateKey
+}
+
+// JWK is an RSA public key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var ErrNotRSA = errors.New("not an RSA private key")
+
+// Load reads a PEM-encoded RSA private key in PKCS #1 or PKCS #8 form.
+func Load(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses a PEM-encoded RSA private key in PKCS #1 or PKCS #8 form.
+func Parse(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrNotRSA)
+	}
+
+	if private, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return newKey(private), nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotRSA, err)
+	}
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotRSA
+	}
+
+	return newKey(private), nil
+}
+
+// Generate returns a new random key.
+func Generate() (*Key, error) {
+	private, err := rsa.GenerateKey(rand.Reader, generatedBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	return newKey(private), nil
+}
+
+// Public returns the public key.
+func (k *Key) Public() *rsa.PublicKey {
+	return &k.Private.PublicKey
+}
+
+// JWKS returns the JWK Set with the public key.
+func (k *Key) JWKS() JWKS {
+	return JWKS{Keys: []JWK{{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: Algorithm,
+		KeyID:     k.ID,
+		Modulus:   base64.RawURLEncoding.EncodeToString(k.Private.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.Private.E)).Bytes()),
+	}}}
+}
+
+func newKey(private *rsa.PrivateKey) *Key {
+	return &Key{ID: thumbprint(&private.PublicKey), Private: private}
+}
+
+// thumbprint returns the RFC 7638 thumbprint of the key, so the ID changes whenever the key does.
+func thumbprint(public *rsa.PublicKey) string {
+	// Members must be in lexicographic order, which json.Marshal keeps for struct fields as declared.
+	canonical, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+	})
+	sum := sha256.Sum256(canonical)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
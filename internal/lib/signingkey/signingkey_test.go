@@ -0,0 +1,76 @@
+package signingkey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		block *pem.Block
+	}{
+		{name: "pkcs1", block: &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)}},
+		{name: "pkcs8", block: &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := Parse(pem.EncodeToMemory(tt.block))
+			require.NoError(t, err)
+
+			assert.True(t, private.Equal(key.Private))
+			assert.NotEmpty(t, key.ID)
+		})
+	}
+}
+
+func TestParse_NotRSA(t *testing.T) {
+	_, err := Parse([]byte("not a key"))
+	assert.ErrorIs(t, err, ErrNotRSA)
+}
+
+func TestJWKS(t *testing.T) {
+	key, err := Generate()
+	require.NoError(t, err)
+
+	set := key.JWKS()
+	require.Len(t, set.Keys, 1)
+
+	jwk := set.Keys[0]
+	assert.Equal(t, "RSA", jwk.KeyType)
+	assert.Equal(t, Algorithm, jwk.Algorithm)
+	assert.Equal(t, key.ID, jwk.KeyID)
+
+	n, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+	require.NoError(t, err)
+	e, err := base64.RawURLEncoding.DecodeString(jwk.Exponent)
+	require.NoError(t, err)
+	assert.Equal(t, 0, key.Public().N.Cmp(new(big.Int).SetBytes(n)))
+	assert.Equal(t, "AQAB", jwk.Exponent)
+	assert.Equal(t, key.Public().E, int(new(big.Int).SetBytes(e).Int64()))
+}
+
+func TestThumbprint(t *testing.T) {
+	// The example key of RFC 7638, section 3.1.
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	require.NoError(t, err)
+
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint(public))
+}
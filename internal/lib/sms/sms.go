@@ -0,0 +1,119 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sender delivers text messages to phone numbers in E.164 form.
+type Sender interface {
+	Send(ctx context.Context, phone string, text string) error
+}
+
+// LogSender writes messages to the log instead of sending them. Development only.
+type LogSender struct {
+	log *slog.Logger
+}
+
+func NewLogSender(log *slog.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+func (s *LogSender) Send(_ context.Context, phone string, text string) error {
+	s.log.Info("sms sent", slog.String("phone", phone), slog.String("text", text))
+
+	return nil
+}
+
+// FileSender appends messages to a file instead of sending them. Development only.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(_ context.Context, phone string, text string) error {
+	const op = "sms.FileSender.Send"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), phone, text); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// HTTPSender posts messages as JSON to a provider endpoint:
+//
+//	{"from": "...", "to": "+79991234567", "text": "..."}
+//
+// Any 2xx response is treated as accepted.
+type HTTPSender struct {
+	client *http.Client
+	url    string
+	token  string
+	from   string
+}
+
+func NewHTTPSender(url, token, from string, timeout time.Duration) *HTTPSender {
+	return &HTTPSender{
+		client: &http.Client{Timeout: timeout},
+		url:    url,
+		token:  token,
+		from:   from,
+	}
+}
+
+type httpMessage struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+func (s *HTTPSender) Send(ctx context.Context, phone string, text string) error {
+	const op = "sms.HTTPSender.Send"
+
+	body, err := json.Marshal(httpMessage{From: s.from, To: phone, Text: text})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: provider responded with status %d", op, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPSender_Send(t *testing.T) {
+	var got httpMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sender := NewHTTPSender(srv.URL, "secret", "sso", time.Second)
+	require.NoError(t, sender.Send(context.Background(), "+79991234567", "code: 123456"))
+
+	assert.Equal(t, httpMessage{From: "sso", To: "+79991234567", Text: "code: 123456"}, got)
+}
+
+func TestHTTPSender_Send_ProviderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	sender := NewHTTPSender(srv.URL, "", "", time.Second)
+	assert.Error(t, sender.Send(context.Background(), "+79991234567", "code: 123456"))
+}
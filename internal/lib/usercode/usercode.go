@@ -0,0 +1,50 @@
+// Package usercode generates the short codes users type in to approve a device (RFC 8628, section 6.1).
+package usercode
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// alphabet has no vowels, so codes don't spell words, and no characters easily confused.
+const alphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// Length gives 20^8 ≈ 2^34.5 codes, plenty for codes that live minutes.
+const Length = 8
+
+// Generate returns a random code in its normalized form.
+func Generate() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(alphabet)))
+	for range Length {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+		b.WriteByte(alphabet[n.Int64()])
+	}
+
+	return b.String(), nil
+}
+
+// Format returns the code as shown to users, split in halves by a dash.
+func Format(code string) string {
+	if len(code) != Length {
+		return code
+	}
+
+	return code[:Length/2] + "-" + code[Length/2:]
+}
+
+// Normalize returns the code as typed by a user in its normalized form:
+// upper case, without dashes and spaces.
+func Normalize(input string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(input)))
+}
@@ -0,0 +1,40 @@
+package usercode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	code, err := Generate()
+	require.NoError(t, err)
+
+	assert.Len(t, code, Length)
+	for _, r := range code {
+		assert.True(t, strings.ContainsRune(alphabet, r), "unexpected character %q", r)
+	}
+	assert.Equal(t, code, Normalize(Format(code)))
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "WDJB-MJHT", want: "WDJBMJHT"},
+		{input: " wdjb mjht ", want: "WDJBMJHT"},
+		{input: "wdjbmjht", want: "WDJBMJHT"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Normalize(tt.input), tt.input)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "WDJB-MJHT", Format("WDJBMJHT"))
+	assert.Equal(t, "ABC", Format("ABC"))
+}
@@ -0,0 +1,86 @@
+package username
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	minLen = 3
+	maxLen = 32
+)
+
+var (
+	ErrInvalidUsername  = errors.New("invalid username")
+	ErrReservedUsername = errors.New("reserved username")
+)
+
+// reserved contains names that can be confused with the service itself or its staff.
+var reserved = map[string]struct{}{
+	"admin":         {},
+	"administrator": {},
+	"root":          {},
+	"system":        {},
+	"support":       {},
+	"help":          {},
+	"security":      {},
+	"sso":           {},
+	"auth":          {},
+	"api":           {},
+	"me":            {},
+	"null":          {},
+	"undefined":     {},
+	"anonymous":     {},
+	"owner":         {},
+	"moderator":     {},
+	"staff":         {},
+	"noreply":       {},
+	"no-reply":      {},
+	"postmaster":    {},
+	"webmaster":     {},
+}
+
+// Normalize returns canonical form of username: trimmed and lower-cased.
+func Normalize(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Validate checks normalized username against charset and reserved-name rules.
+//
+// Username must be 3-32 characters long, start with a letter and contain only
+// latin letters, digits, '_', '-' and '.'. Separators can't be repeated or trailing.
+func Validate(username string) error {
+	if len(username) < minLen || len(username) > maxLen {
+		return ErrInvalidUsername
+	}
+
+	if username[0] < 'a' || username[0] > 'z' {
+		return ErrInvalidUsername
+	}
+
+	prevSep := false
+	for i := 0; i < len(username); i++ {
+		c := username[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			prevSep = false
+		case c == '_' || c == '-' || c == '.':
+			if prevSep {
+				return ErrInvalidUsername
+			}
+			prevSep = true
+		default:
+			return ErrInvalidUsername
+		}
+	}
+
+	if prevSep {
+		return ErrInvalidUsername
+	}
+
+	if _, ok := reserved[username]; ok {
+		return ErrReservedUsername
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package username
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		wantErr  error
+	}{
+		{name: "simple", username: "bob"},
+		{name: "with separators", username: "bob.the_builder-2"},
+		{name: "too short", username: "bo", wantErr: ErrInvalidUsername},
+		{name: "too long", username: "abcdefghijabcdefghijabcdefghijabc", wantErr: ErrInvalidUsername},
+		{name: "starts with digit", username: "1bob", wantErr: ErrInvalidUsername},
+		{name: "upper case", username: "Bob", wantErr: ErrInvalidUsername},
+		{name: "repeated separator", username: "bob..x", wantErr: ErrInvalidUsername},
+		{name: "trailing separator", username: "bob_", wantErr: ErrInvalidUsername},
+		{name: "at sign", username: "bob@x", wantErr: ErrInvalidUsername},
+		{name: "non latin", username: "боб", wantErr: ErrInvalidUsername},
+		{name: "reserved", username: "admin", wantErr: ErrReservedUsername},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.username)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "bob", Normalize("  Bob "))
+}
@@ -0,0 +1,610 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	eventUserStatusChanged   = "user.status_changed"
+	eventAppMFAPolicyChanged = "app.mfa_policy_changed"
+	eventAppWebAuthnChanged  = "app.webauthn_changed"
+	eventUserAttrsChanged    = "user.attributes_changed"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// maxRoleNameLength matches the longest role name rbac accepts.
+const maxRoleNameLength = 64
+
+const (
+	maxAttributes       = 32
+	maxAttributesLength = 4096
+)
+
+// attributeKeyRe matches keys of user attributes, e.g. "region" or "cost_center".
+var attributeKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+type Admin struct {
+	log             *slog.Logger
+	userProvider    UserProvider
+	statusChanger   StatusChanger
+	appProvider     AppProvider
+	appSaver        AppSaver
+	attrSaver       AttributeSaver
+	clientSecrets   ClientSecretStorage
+	serviceAccounts ServiceAccountStorage
+	auditLogger     AuditLogger
+	permissionCache PermissionCache
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+	IsAdmin(ctx context.Context, userID string, appID uuid.UUID) (bool, error)
+	ListUsers(ctx context.Context, filter models.UserFilter) (users []models.User, nextCursor string, err error)
+}
+
+type StatusChanger interface {
+	ChangeUserStatus(ctx context.Context, change models.UserStatusChange, revokeSessions bool, event models.AuditEvent) error
+}
+
+type AppSaver interface {
+	CreateApp(ctx context.Context, app models.App) (models.App, error)
+	UpdateApp(ctx context.Context, appID uuid.UUID, update models.AppUpdate) (models.App, error)
+	DeleteApp(ctx context.Context, appID uuid.UUID) error
+	SetAppMFAPolicy(ctx context.Context, appName string, policy models.MFAPolicy) error
+	SetAppWebAuthn(ctx context.Context, appName string, rpID string, origins []string) error
+	AddAppMember(ctx context.Context, member models.AppMember) error
+	RemoveAppMember(ctx context.Context, appID uuid.UUID, userID uuid.UUID) error
+}
+
+type AttributeSaver interface {
+	SetUserAttributes(ctx context.Context, userID string, attributes map[string]any) error
+}
+
+type AuditLogger interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+}
+
+// PermissionCache holds permission checks that depend on the user status and on apps.
+type PermissionCache interface {
+	InvalidateUser(userID string)
+	InvalidateApps()
+}
+
+var (
+	ErrPermissionDenied  = errors.New("permission denied")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrStatusConflict    = errors.New("user status changed concurrently")
+	ErrInvalidFilter     = errors.New("invalid filter")
+	ErrInvalidPolicy     = errors.New("invalid mfa policy")
+	ErrAppNotFound       = errors.New("app not found")
+	ErrInvalidRP         = errors.New("invalid webauthn relying party")
+	ErrInvalidAttributes = errors.New("invalid user attributes")
+)
+
+// transitions lists statuses a user can be moved to from each status.
+var transitions = map[models.UserStatus][]models.UserStatus{
+	models.UserStatusPending:     {models.UserStatusActive, models.UserStatusDeactivated},
+	models.UserStatusActive:      {models.UserStatusSuspended, models.UserStatusDeactivated},
+	models.UserStatusSuspended:   {models.UserStatusActive, models.UserStatusSuspended, models.UserStatusDeactivated},
+	models.UserStatusDeactivated: {models.UserStatusActive},
+}
+
+// New returns a new instance of the Admin service.
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	statusChanger StatusChanger,
+	appProvider AppProvider,
+	appSaver AppSaver,
+	attrSaver AttributeSaver,
+	clientSecrets ClientSecretStorage,
+	serviceAccounts ServiceAccountStorage,
+	auditLogger AuditLogger,
+	permissionCache PermissionCache,
+) *Admin {
+	return &Admin{
+		log:             log,
+		userProvider:    userProvider,
+		statusChanger:   statusChanger,
+		appProvider:     appProvider,
+		appSaver:        appSaver,
+		attrSaver:       attrSaver,
+		clientSecrets:   clientSecrets,
+		serviceAccounts: serviceAccounts,
+		auditLogger:     auditLogger,
+		permissionCache: permissionCache,
+	}
+}
+
+// ActivateUser activates pending user or lifts suspension or deactivation.
+func (a *Admin) ActivateUser(ctx context.Context, adminToken string, userID string) error {
+	const op = "admin.ActivateUser"
+
+	if err := a.changeStatus(ctx, op, adminToken, userID, models.UserStatusActive, "", time.Time{}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SuspendUser blocks user until the given time, or indefinitely if until is zero.
+// All sessions of the user are revoked.
+func (a *Admin) SuspendUser(ctx context.Context, adminToken string, userID string, reason string, until time.Time) error {
+	const op = "admin.SuspendUser"
+
+	if err := a.changeStatus(ctx, op, adminToken, userID, models.UserStatusSuspended, reason, until); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeactivateUser blocks user without an end date. All sessions of the user are revoked.
+func (a *Admin) DeactivateUser(ctx context.Context, adminToken string, userID string, reason string) error {
+	const op = "admin.DeactivateUser"
+
+	if err := a.changeStatus(ctx, op, adminToken, userID, models.UserStatusDeactivated, reason, time.Time{}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *Admin) changeStatus(
+	ctx context.Context,
+	op string,
+	adminToken string,
+	userID string,
+	to models.UserStatus,
+	reason string,
+	until time.Time,
+) error {
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID),
+	)
+
+	log.Info("changing user status", slog.String("status", string(to)))
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to change user status", sl.Err(err))
+
+		return err
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	user, err := a.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return ErrUserNotFound
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return err
+	}
+
+	if !canTransition(user.Status, to) {
+		log.Warn("invalid status transition", slog.String("from", string(user.Status)))
+
+		return ErrInvalidTransition
+	}
+
+	change := models.UserStatusChange{
+		UserID:  user.ID,
+		ActorID: actor.ID,
+		From:    user.Status,
+		To:      to,
+		Reason:  reason,
+		Until:   until,
+	}
+
+	details := map[string]any{
+		"from":   change.From,
+		"to":     change.To,
+		"reason": change.Reason,
+	}
+	if !until.IsZero() {
+		details["until"] = until.UTC().Format(time.RFC3339)
+	}
+
+	revokeSessions := to == models.UserStatusSuspended || to == models.UserStatusDeactivated
+
+	err = a.statusChanger.ChangeUserStatus(ctx, change, revokeSessions, models.AuditEvent{
+		UserID:  user.ID,
+		ActorID: actor.ID,
+		Event:   eventUserStatusChanged,
+		Details: details,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrStatusConflict) {
+			log.Warn("user status changed concurrently", sl.Err(err))
+
+			return ErrStatusConflict
+		}
+
+		log.Error("failed to change user status", sl.Err(err))
+
+		return err
+	}
+	// Inactive users have no permissions, so cached checks must not outlive the change.
+	a.permissionCache.InvalidateUser(user.ID.String())
+
+	log.Info("user status changed", slog.String("from", string(change.From)))
+
+	return nil
+}
+
+// ListUsers returns a page of users matching the filter and a cursor of the next page.
+func (a *Admin) ListUsers(ctx context.Context, adminToken string, filter models.UserFilter) ([]models.User, string, error) {
+	const op = "admin.ListUsers"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("listing users")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to list users", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	if filter.SortBy == "" {
+		filter.SortBy = models.UserSortCreatedAt
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	if err := validateFilter(filter); err != nil {
+		log.Warn("invalid filter", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	users, next, err := a.userProvider.ListUsers(ctx, filter)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			log.Warn("invalid cursor", sl.Err(err))
+
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidFilter)
+		}
+
+		log.Error("failed to list users", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("users listed", slog.Int("count", len(users)))
+
+	return users, next, nil
+}
+
+// SetAppMFAPolicy sets which users of the app must pass a second factor on login.
+func (a *Admin) SetAppMFAPolicy(ctx context.Context, adminToken string, appName string, policy models.MFAPolicy) error {
+	const op = "admin.SetAppMFAPolicy"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", appName),
+	)
+
+	log.Info("setting app mfa policy", slog.String("policy", string(policy)))
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to change app mfa policy", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	switch policy {
+	case models.MFAPolicyOptional, models.MFAPolicyAdmins, models.MFAPolicyAll:
+	default:
+		return fmt.Errorf("%s: %w", op, ErrInvalidPolicy)
+	}
+
+	if err := a.appSaver.SetAppMFAPolicy(ctx, appName, policy); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to set app mfa policy", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.auditLogger.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   eventAppMFAPolicyChanged,
+		Details: map[string]any{"app": appName, "policy": policy},
+	}); err != nil {
+		log.Error("failed to audit mfa policy change", sl.Err(err))
+	}
+
+	log.Info("app mfa policy set")
+
+	return nil
+}
+
+// SetAppWebAuthn sets the relying party ID and origins passkeys of the app are bound to.
+// Empty rpID and no origins disable passkeys for the app.
+func (a *Admin) SetAppWebAuthn(ctx context.Context, adminToken string, appName string, rpID string, origins []string) error {
+	const op = "admin.SetAppWebAuthn"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", appName),
+	)
+
+	log.Info("setting app webauthn relying party", slog.String("rp_id", rpID), slog.Any("origins", origins))
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to change app webauthn relying party", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	if err := validateRP(rpID, origins); err != nil {
+		log.Warn("invalid relying party", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.appSaver.SetAppWebAuthn(ctx, appName, rpID, origins); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to set app webauthn relying party", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.auditLogger.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   eventAppWebAuthnChanged,
+		Details: map[string]any{"app": appName, "rp_id": rpID, "origins": origins},
+	}); err != nil {
+		log.Error("failed to audit webauthn change", sl.Err(err))
+	}
+
+	log.Info("app webauthn relying party set")
+
+	return nil
+}
+
+// SetUserAttributes replaces attributes of the user evaluated by authorization policies,
+// e.g. {"region": "eu"}. Values are strings, numbers, booleans or lists of them.
+func (a *Admin) SetUserAttributes(ctx context.Context, adminToken string, userID string, attributes map[string]any) error {
+	const op = "admin.SetUserAttributes"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID),
+	)
+
+	log.Info("setting user attributes")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to change user attributes", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	if err := validateAttributes(attributes); err != nil {
+		log.Warn("invalid attributes", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.attrSaver.SetUserAttributes(ctx, userID, attributes); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to set user attributes", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.auditLogger.SaveAuditEvent(ctx, models.AuditEvent{
+		UserID:  uuid.MustParse(userID),
+		ActorID: actor.ID,
+		Event:   eventUserAttrsChanged,
+		Details: map[string]any{"attributes": attributes},
+	}); err != nil {
+		log.Error("failed to audit attributes change", sl.Err(err))
+	}
+
+	log.Info("user attributes set")
+
+	return nil
+}
+
+// authorize returns the caller if the access token belongs to an active admin and was
+// issued after the admin's sessions were last revoked.
+func (a *Admin) authorize(ctx context.Context, adminToken string) (models.User, error) {
+	claims, err := jwt.ParseAccessToken(adminToken)
+	if err != nil {
+		return models.User{}, ErrPermissionDenied
+	}
+	actorID := claims.UserID
+
+	actor, err := a.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: actorID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, ErrPermissionDenied
+		}
+
+		return models.User{}, err
+	}
+
+	if !actor.IsActive(time.Now()) || claims.IssuedAt.Before(actor.SessionsRevokedAt) {
+		return models.User{}, ErrPermissionDenied
+	}
+
+	isAdmin, err := a.userProvider.IsAdmin(ctx, actorID, uuid.Nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !isAdmin {
+		return models.User{}, ErrPermissionDenied
+	}
+
+	return actor, nil
+}
+
+func canTransition(from, to models.UserStatus) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateFilter(filter models.UserFilter) error {
+	switch filter.SortBy {
+	case models.UserSortCreatedAt, models.UserSortLastLoginAt, models.UserSortEmail:
+	default:
+		return ErrInvalidFilter
+	}
+
+	if len(filter.Role) > maxRoleNameLength {
+		return ErrInvalidFilter
+	}
+
+	for _, st := range filter.Statuses {
+		if _, ok := transitions[st]; !ok {
+			return ErrInvalidFilter
+		}
+	}
+
+	if filter.Limit > maxPageSize {
+		return ErrInvalidFilter
+	}
+
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return ErrInvalidFilter
+	}
+	if !filter.LastLoginFrom.IsZero() && !filter.LastLoginTo.IsZero() && !filter.LastLoginFrom.Before(filter.LastLoginTo) {
+		return ErrInvalidFilter
+	}
+
+	return nil
+}
+
+// validateRP checks that every origin is the RP ID or its subdomain, as browsers require.
+// Plain http is only allowed for localhost.
+func validateRP(rpID string, origins []string) error {
+	if rpID == "" {
+		if len(origins) != 0 {
+			return fmt.Errorf("%w: origins without rp id", ErrInvalidRP)
+		}
+
+		return nil
+	}
+
+	if len(origins) == 0 {
+		return fmt.Errorf("%w: at least one origin is required", ErrInvalidRP)
+	}
+
+	if strings.ContainsAny(rpID, ":/") || rpID != strings.ToLower(rpID) {
+		return fmt.Errorf("%w: rp id must be a lowercase host", ErrInvalidRP)
+	}
+
+	for _, origin := range origins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("%w: malformed origin %q", ErrInvalidRP, origin)
+		}
+
+		host := u.Hostname()
+		if u.Scheme != "https" && !(u.Scheme == "http" && host == "localhost") {
+			return fmt.Errorf("%w: origin %q must use https", ErrInvalidRP, origin)
+		}
+
+		if host != rpID && !strings.HasSuffix(host, "."+rpID) {
+			return fmt.Errorf("%w: origin %q is outside rp id %q", ErrInvalidRP, origin, rpID)
+		}
+	}
+
+	return nil
+}
+
+func validateAttributes(attributes map[string]any) error {
+	if len(attributes) > maxAttributes {
+		return fmt.Errorf("%w: at most %d attributes are allowed", ErrInvalidAttributes, maxAttributes)
+	}
+
+	for key, value := range attributes {
+		if !attributeKeyRe.MatchString(key) {
+			return fmt.Errorf("%w: malformed key %q", ErrInvalidAttributes, key)
+		}
+
+		if list, ok := value.([]any); ok {
+			for _, v := range list {
+				if !scalar(v) {
+					return fmt.Errorf("%w: list %q must hold scalars", ErrInvalidAttributes, key)
+				}
+			}
+			continue
+		}
+		if !scalar(value) {
+			return fmt.Errorf("%w: value of %q must be a scalar or a list", ErrInvalidAttributes, key)
+		}
+	}
+
+	data, err := json.Marshal(attributes)
+	if err != nil || len(data) > maxAttributesLength {
+		return fmt.Errorf("%w: attributes must fit into %d bytes", ErrInvalidAttributes, maxAttributesLength)
+	}
+
+	return nil
+}
+
+func scalar(v any) bool {
+	switch v.(type) {
+	case string, bool, float64, int, int64:
+		return true
+	default:
+		return false
+	}
+}
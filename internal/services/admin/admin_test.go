@@ -0,0 +1,338 @@
+package admin
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeStatus_Lifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusPending)
+
+	require.NoError(t, env.service.ActivateUser(ctx, env.adminToken, user.ID.String()))
+	assert.Equal(t, models.UserStatusActive, env.storage.users[user.ID].Status)
+
+	until := time.Now().Add(time.Hour)
+	require.NoError(t, env.service.SuspendUser(ctx, env.adminToken, user.ID.String(), "spam", until))
+	assert.Equal(t, models.UserStatusSuspended, env.storage.users[user.ID].Status)
+
+	require.NoError(t, env.service.DeactivateUser(ctx, env.adminToken, user.ID.String(), "left"))
+	assert.Equal(t, models.UserStatusDeactivated, env.storage.users[user.ID].Status)
+
+	require.NoError(t, env.service.ActivateUser(ctx, env.adminToken, user.ID.String()))
+	assert.Equal(t, models.UserStatusActive, env.storage.users[user.ID].Status)
+
+	// Every change is audited with the admin as the actor.
+	require.Len(t, env.storage.events, 4)
+	for _, event := range env.storage.events {
+		assert.Equal(t, eventUserStatusChanged, event.Event)
+		assert.Equal(t, env.admin.ID, event.ActorID)
+		assert.Equal(t, user.ID, event.UserID)
+	}
+	assert.Equal(t, until.UTC().Format(time.RFC3339), env.storage.events[1].Details["until"])
+	assert.Equal(t, "spam", env.storage.events[1].Details["reason"])
+}
+
+func TestChangeStatus_RevokesSessions(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	require.NoError(t, env.service.SuspendUser(ctx, env.adminToken, user.ID.String(), "spam", time.Time{}))
+	assert.True(t, env.storage.revoked[user.ID])
+
+	delete(env.storage.revoked, user.ID)
+	require.NoError(t, env.service.ActivateUser(ctx, env.adminToken, user.ID.String()))
+	assert.False(t, env.storage.revoked[user.ID])
+
+	require.NoError(t, env.service.DeactivateUser(ctx, env.adminToken, user.ID.String(), "left"))
+	assert.True(t, env.storage.revoked[user.ID])
+}
+
+func TestChangeStatus_InvalidatesPermissions(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	require.NoError(t, env.service.SuspendUser(ctx, env.adminToken, user.ID.String(), "spam", time.Time{}))
+	assert.Equal(t, []string{user.ID.String()}, env.storage.invalidated)
+
+	require.NoError(t, env.service.ActivateUser(ctx, env.adminToken, user.ID.String()))
+	assert.Equal(t, []string{user.ID.String(), user.ID.String()}, env.storage.invalidated)
+
+	// A rejected transition changes nothing and keeps the cache.
+	require.Error(t, env.service.ActivateUser(ctx, env.adminToken, user.ID.String()))
+	assert.Len(t, env.storage.invalidated, 2)
+}
+
+func TestChangeStatus_InvalidTransition(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   models.UserStatus
+		change func(env *testEnv, userID string) error
+	}{
+		{
+			name: "activate active",
+			from: models.UserStatusActive,
+			change: func(env *testEnv, userID string) error {
+				return env.service.ActivateUser(context.Background(), env.adminToken, userID)
+			},
+		},
+		{
+			name: "suspend pending",
+			from: models.UserStatusPending,
+			change: func(env *testEnv, userID string) error {
+				return env.service.SuspendUser(context.Background(), env.adminToken, userID, "", time.Time{})
+			},
+		},
+		{
+			name: "suspend deactivated",
+			from: models.UserStatusDeactivated,
+			change: func(env *testEnv, userID string) error {
+				return env.service.SuspendUser(context.Background(), env.adminToken, userID, "", time.Time{})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.addUser(tt.from)
+
+			err := tt.change(env, user.ID.String())
+			assert.ErrorIs(t, err, ErrInvalidTransition)
+			assert.Equal(t, tt.from, env.storage.users[user.ID].Status)
+			assert.Empty(t, env.storage.events)
+		})
+	}
+}
+
+func TestChangeStatus_Rejected(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	err := env.service.SuspendUser(ctx, "not-a-token", user.ID.String(), "", time.Time{})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	// Users that aren't admins can't change statuses.
+	userToken := env.token(user)
+	other := env.addUser(models.UserStatusActive)
+	err = env.service.SuspendUser(ctx, userToken, other.ID.String(), "", time.Time{})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	err = env.service.SuspendUser(ctx, env.adminToken, uuid.NewString(), "", time.Time{})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	env.storage.conflict = true
+	err = env.service.SuspendUser(ctx, env.adminToken, user.ID.String(), "", time.Time{})
+	assert.ErrorIs(t, err, ErrStatusConflict)
+
+	// Suspended admins lose their admin rights.
+	env.storage.conflict = false
+	env.admin.Status = models.UserStatusSuspended
+	env.storage.users[env.admin.ID] = env.admin
+	err = env.service.SuspendUser(ctx, env.adminToken, user.ID.String(), "", time.Time{})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	// So do tokens of admins whose sessions were revoked since.
+	env.admin.Status = models.UserStatusActive
+	env.admin.SessionsRevokedAt = time.Now().Add(time.Minute)
+	env.storage.users[env.admin.ID] = env.admin
+	err = env.service.SuspendUser(ctx, env.adminToken, user.ID.String(), "", time.Time{})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
+
+func TestListUsers(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	_, _, err := env.service.ListUsers(ctx, env.adminToken, models.UserFilter{Role: "support"})
+	require.NoError(t, err)
+	assert.Equal(t, models.UserSortCreatedAt, env.storage.filter.SortBy)
+	assert.Equal(t, defaultPageSize, env.storage.filter.Limit)
+	assert.Equal(t, "support", env.storage.filter.Role)
+
+	user := env.addUser(models.UserStatusActive)
+	_, _, err = env.service.ListUsers(ctx, env.token(user), models.UserFilter{})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
+
+func TestListUsers_InvalidFilter(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		filter models.UserFilter
+	}{
+		{name: "unknown sort", filter: models.UserFilter{SortBy: "password"}},
+		{name: "unknown status", filter: models.UserFilter{Statuses: []models.UserStatus{"banned"}}},
+		{name: "page too large", filter: models.UserFilter{Limit: maxPageSize + 1}},
+		{name: "empty created range", filter: models.UserFilter{CreatedFrom: now, CreatedTo: now}},
+		{name: "reversed last login range", filter: models.UserFilter{LastLoginFrom: now, LastLoginTo: now.Add(-time.Hour)}},
+		{name: "long role", filter: models.UserFilter{Role: strings.Repeat("r", maxRoleNameLength+1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+
+			_, _, err := env.service.ListUsers(context.Background(), env.adminToken, tt.filter)
+			assert.ErrorIs(t, err, ErrInvalidFilter)
+		})
+	}
+}
+
+type testEnv struct {
+	t          *testing.T
+	service    *Admin
+	storage    *fakeStorage
+	admin      models.User
+	app        models.App
+	adminToken string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	fake := &fakeStorage{
+		users:    map[uuid.UUID]models.User{},
+		apps:     map[uuid.UUID]models.App{},
+		accounts: map[uuid.UUID]models.ServiceAccount{},
+		keys:     map[uuid.UUID]models.ServiceAccountKey{},
+		admins:   map[uuid.UUID]bool{},
+		revoked:  map[uuid.UUID]bool{},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	env := &testEnv{
+		t:       t,
+		service: New(log, fake, fake, fake, fake, fake, fake, fake, fake, fake),
+		storage: fake,
+		app:     models.App{ID: uuid.New(), Name: "test-app", Enabled: true},
+	}
+
+	env.admin = env.addUser(models.UserStatusActive)
+	fake.admins[env.admin.ID] = true
+	env.adminToken = env.token(env.admin)
+
+	return env
+}
+
+func (env *testEnv) addUser(status models.UserStatus) models.User {
+	id := uuid.New()
+	user := models.User{ID: id, Email: id.String() + "@example.com", Status: status}
+	env.storage.users[user.ID] = user
+
+	return user
+}
+
+func (env *testEnv) token(user models.User) string {
+	env.t.Helper()
+
+	token, _, err := jwt.NewTokenPair(user, env.app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(env.t, err)
+
+	return token
+}
+
+// fakeStorage keeps users in memory. Storage interfaces the tests don't exercise
+// are embedded as nil and panic if called.
+type fakeStorage struct {
+	AppProvider
+	AppSaver
+	AttributeSaver
+	ClientSecretStorage
+	ServiceAccountStorage
+
+	users    map[uuid.UUID]models.User
+	apps     map[uuid.UUID]models.App
+	accounts map[uuid.UUID]models.ServiceAccount
+	keys     map[uuid.UUID]models.ServiceAccountKey
+	members  []models.AppMember
+	admins   map[uuid.UUID]bool
+	revoked  map[uuid.UUID]bool
+	events   []models.AuditEvent
+	conflict bool
+	filter   models.UserFilter
+
+	invalidated     []string
+	appsInvalidated int
+}
+
+func (f *fakeStorage) InvalidateUser(userID string) {
+	f.invalidated = append(f.invalidated, userID)
+}
+
+func (f *fakeStorage) InvalidateApps() {
+	f.appsInvalidated++
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	id, err := uuid.Parse(identifier.Value)
+	if err != nil {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	user, ok := f.users[id]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeStorage) IsAdmin(_ context.Context, userID string, _ uuid.UUID) (bool, error) {
+	return f.admins[uuid.MustParse(userID)], nil
+}
+
+func (f *fakeStorage) ListUsers(_ context.Context, filter models.UserFilter) ([]models.User, string, error) {
+	f.filter = filter
+
+	return nil, "", nil
+}
+
+func (f *fakeStorage) ChangeUserStatus(_ context.Context, change models.UserStatusChange, revokeSessions bool, event models.AuditEvent) error {
+	user := f.users[change.UserID]
+	if f.conflict || user.Status != change.From {
+		return storage.ErrStatusConflict
+	}
+
+	user.Status = change.To
+	user.StatusReason = change.Reason
+	user.SuspendedUntil = change.Until
+	f.users[user.ID] = user
+
+	if revokeSessions {
+		f.revoked[user.ID] = true
+	}
+	f.events = append(f.events, event)
+
+	return nil
+}
+
+func (f *fakeStorage) SaveAuditEvent(_ context.Context, event models.AuditEvent) error {
+	f.events = append(f.events, event)
+
+	return nil
+}
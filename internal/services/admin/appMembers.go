@@ -0,0 +1,206 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const (
+	eventAppMemberAdded   = "app.member_added"
+	eventAppMemberRemoved = "app.member_removed"
+)
+
+const maxAllowedEmailDomains = 50
+
+// emailDomainRe matches lower-case domain names, e.g. "example.com".
+var emailDomainRe = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+var (
+	ErrInvalidAccess     = errors.New("invalid app access")
+	ErrAppMemberExists   = errors.New("user is already a member of the app")
+	ErrAppMemberNotFound = errors.New("app member not found")
+)
+
+// AddAppMember adds the user to the app, found by id or name, so that they may log in
+// while the app is in members mode.
+func (a *Admin) AddAppMember(ctx context.Context, adminToken string, app string, userID string) error {
+	const op = "admin.AddAppMember"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", app),
+		slog.String("user_id", userID),
+	)
+
+	log.Info("adding app member")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to add app members", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	found, err := a.lookupApp(ctx, log, app)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+
+	if err := a.appSaver.AddAppMember(ctx, models.AppMember{AppID: found.ID, UserID: id, AddedBy: actor.ID}); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrAppMemberExists):
+			log.Warn("user is already a member", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrAppMemberExists)
+		case errors.Is(err, storage.ErrUserNotFound):
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		case errors.Is(err, storage.ErrAppNotFound):
+			log.Warn("app not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to add app member", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditAppMember(ctx, log, actor, eventAppMemberAdded, found, id)
+
+	log.Info("app member added")
+
+	return nil
+}
+
+// RemoveAppMember removes the user from the app, found by id or name. Access tokens the user
+// already holds stay valid until they expire, but while the app is in members mode they can't
+// log in again nor refresh their tokens.
+func (a *Admin) RemoveAppMember(ctx context.Context, adminToken string, app string, userID string) error {
+	const op = "admin.RemoveAppMember"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", app),
+		slog.String("user_id", userID),
+	)
+
+	log.Info("removing app member")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to remove app members", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	found, err := a.lookupApp(ctx, log, app)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrAppMemberNotFound)
+	}
+
+	if err := a.appSaver.RemoveAppMember(ctx, found.ID, id); err != nil {
+		if errors.Is(err, storage.ErrAppMemberNotFound) {
+			log.Warn("app member not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrAppMemberNotFound)
+		}
+
+		log.Error("failed to remove app member", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditAppMember(ctx, log, actor, eventAppMemberRemoved, found, id)
+
+	log.Info("app member removed")
+
+	return nil
+}
+
+// ListAppMembers returns members of the app, found by id or name, in the order they were added.
+func (a *Admin) ListAppMembers(ctx context.Context, adminToken string, app string) ([]models.AppMember, error) {
+	const op = "admin.ListAppMembers"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", app),
+	)
+
+	if _, err := a.authorize(ctx, adminToken); err != nil {
+		log.Warn("caller is not allowed to list app members", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	found, err := a.lookupApp(ctx, log, app)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	members, err := a.appProvider.AppMembers(ctx, found.ID)
+	if err != nil {
+		log.Error("failed to list app members", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return members, nil
+}
+
+func (a *Admin) auditAppMember(ctx context.Context, log *slog.Logger, actor models.User, event string, app models.App, userID uuid.UUID) {
+	if err := a.auditLogger.SaveAuditEvent(ctx, models.AuditEvent{
+		UserID:  userID,
+		ActorID: actor.ID,
+		Event:   event,
+		Details: map[string]any{"app": app.Name, "app_id": app.ID},
+	}); err != nil {
+		log.Error("failed to audit app member change", sl.Err(err), slog.String("event", event))
+	}
+}
+
+// validateAccess checks the access mode and the allowed email domains. Email domain mode
+// needs a domain, as it would admit nobody otherwise.
+func validateAccess(mode models.AppAccessMode, domains []string) error {
+	switch mode {
+	case models.AppAccessOpen, models.AppAccessMembers:
+	case models.AppAccessEmailDomain:
+		if len(domains) == 0 {
+			return fmt.Errorf("%w: %s mode requires allowed email domains", ErrInvalidAccess, mode)
+		}
+	default:
+		return fmt.Errorf("%w: unknown access mode %q", ErrInvalidAccess, mode)
+	}
+
+	if len(domains) > maxAllowedEmailDomains {
+		return fmt.Errorf("%w: at most %d email domains are allowed", ErrInvalidAccess, maxAllowedEmailDomains)
+	}
+	for _, domain := range domains {
+		if !emailDomainRe.MatchString(domain) {
+			return fmt.Errorf("%w: malformed email domain %q", ErrInvalidAccess, domain)
+		}
+	}
+
+	return nil
+}
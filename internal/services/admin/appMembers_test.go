@@ -0,0 +1,156 @@
+package admin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppMembers_Lifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(models.UserStatusActive)
+
+	app, err := env.service.CreateApp(ctx, env.adminToken, "coin-keeper", "", "")
+	require.NoError(t, err)
+
+	require.NoError(t, env.service.AddAppMember(ctx, env.adminToken, "coin-keeper", user.ID.String()))
+
+	err = env.service.AddAppMember(ctx, env.adminToken, "coin-keeper", user.ID.String())
+	assert.ErrorIs(t, err, ErrAppMemberExists)
+
+	members, err := env.service.ListAppMembers(ctx, env.adminToken, app.ID.String())
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, user.ID, members[0].UserID)
+	assert.Equal(t, env.admin.ID, members[0].AddedBy)
+
+	require.NoError(t, env.service.RemoveAppMember(ctx, env.adminToken, "coin-keeper", user.ID.String()))
+
+	err = env.service.RemoveAppMember(ctx, env.adminToken, "coin-keeper", user.ID.String())
+	assert.ErrorIs(t, err, ErrAppMemberNotFound)
+
+	members, err = env.service.ListAppMembers(ctx, env.adminToken, "coin-keeper")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	var events []string
+	for _, event := range env.storage.events {
+		events = append(events, event.Event)
+	}
+	assert.Equal(t, []string{eventAppCreated, eventAppMemberAdded, eventAppMemberRemoved}, events)
+}
+
+func TestAddAppMember_Rejected(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(models.UserStatusActive)
+
+	_, err := env.service.CreateApp(ctx, env.adminToken, "coin-keeper", "", "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		token  string
+		app    string
+		userID string
+		want   error
+	}{
+		{name: "not admin", token: env.token(user), app: "coin-keeper", userID: user.ID.String(), want: ErrPermissionDenied},
+		{name: "unknown app", token: env.adminToken, app: "notes", userID: user.ID.String(), want: ErrAppNotFound},
+		{name: "unknown user", token: env.adminToken, app: "coin-keeper", userID: uuid.NewString(), want: ErrUserNotFound},
+		{name: "malformed user", token: env.adminToken, app: "coin-keeper", userID: "user", want: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.service.AddAppMember(ctx, tt.token, tt.app, tt.userID)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestUpdateApp_Access(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	_, err := env.service.CreateApp(ctx, env.adminToken, "coin-keeper", "", "")
+	require.NoError(t, err)
+
+	mode, domains := models.AppAccessEmailDomain, []string{"example.com"}
+	updated, err := env.service.UpdateApp(ctx, env.adminToken, "coin-keeper", models.AppUpdate{
+		AccessMode:          &mode,
+		AllowedEmailDomains: &domains,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.AppAccessEmailDomain, updated.AccessMode)
+	assert.Equal(t, []string{"example.com"}, updated.AllowedEmailDomains)
+
+	tests := []struct {
+		name    string
+		mode    models.AppAccessMode
+		domains []string
+	}{
+		{name: "unknown mode", mode: "everyone"},
+		{name: "no domains", mode: models.AppAccessEmailDomain, domains: []string{}},
+		{name: "upper case domain", mode: models.AppAccessEmailDomain, domains: []string{"Example.com"}},
+		{name: "email as domain", mode: models.AppAccessEmailDomain, domains: []string{"alice@example.com"}},
+		{name: "too many domains", mode: models.AppAccessEmailDomain, domains: strings.Split(strings.Repeat("example.com,", maxAllowedEmailDomains+1), ",")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := models.AppUpdate{AccessMode: &tt.mode}
+			if tt.domains != nil {
+				update.AllowedEmailDomains = &tt.domains
+			}
+
+			_, err := env.service.UpdateApp(ctx, env.adminToken, "coin-keeper", update)
+			assert.ErrorIs(t, err, ErrInvalidAccess)
+		})
+	}
+}
+
+func (f *fakeStorage) AddAppMember(_ context.Context, member models.AppMember) error {
+	if _, ok := f.users[member.UserID]; !ok {
+		return storage.ErrUserNotFound
+	}
+	for _, existing := range f.members {
+		if existing.AppID == member.AppID && existing.UserID == member.UserID {
+			return storage.ErrAppMemberExists
+		}
+	}
+
+	f.members = append(f.members, member)
+
+	return nil
+}
+
+func (f *fakeStorage) RemoveAppMember(_ context.Context, appID uuid.UUID, userID uuid.UUID) error {
+	for i, member := range f.members {
+		if member.AppID == appID && member.UserID == userID {
+			f.members = append(f.members[:i], f.members[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return storage.ErrAppMemberNotFound
+}
+
+func (f *fakeStorage) AppMembers(_ context.Context, appID uuid.UUID) ([]models.AppMember, error) {
+	var members []models.AppMember
+	for _, member := range f.members {
+		if member.AppID == appID {
+			members = append(members, member)
+		}
+	}
+
+	return members, nil
+}
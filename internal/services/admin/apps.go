@@ -0,0 +1,390 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const (
+	eventAppCreated = "app.created"
+	eventAppUpdated = "app.updated"
+	eventAppDeleted = "app.deleted"
+)
+
+const (
+	maxAppDescriptionLength = 1024
+	maxRedirectURIs         = 20
+)
+
+// appNameRe matches app names, e.g. "coin-keeper".
+var appNameRe = regexp.MustCompile(`^[a-z][a-z0-9-]{1,62}$`)
+
+type AppProvider interface {
+	// LookupApp returns app by id or, if idOrName is not a UUID, by name.
+	LookupApp(ctx context.Context, idOrName string) (models.App, error)
+	Apps(ctx context.Context) ([]models.App, error)
+	AppMembers(ctx context.Context, appID uuid.UUID) ([]models.AppMember, error)
+}
+
+var (
+	ErrAppExists           = errors.New("app already exists")
+	ErrInvalidApp          = errors.New("invalid app")
+	ErrAppOwnerNotFound    = errors.New("app owner not found")
+	ErrInvalidRedirectURIs = errors.New("invalid redirect uris")
+)
+
+// CreateApp registers an enabled app. OwnerID is optional.
+func (a *Admin) CreateApp(ctx context.Context, adminToken string, name string, description string, ownerID string) (models.App, error) {
+	const op = "admin.CreateApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", name),
+	)
+
+	log.Info("creating app")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to create apps", sl.Err(err))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	app := models.App{Name: name, Description: description, Enabled: true}
+	if ownerID != "" {
+		if app.OwnerID, err = uuid.Parse(ownerID); err != nil {
+			return models.App{}, fmt.Errorf("%s: %w", op, ErrAppOwnerNotFound)
+		}
+	}
+
+	if err := validateApp(app.Name, app.Description); err != nil {
+		log.Warn("invalid app", sl.Err(err))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err = a.appSaver.CreateApp(ctx, app)
+	if err != nil {
+		err = appError(err)
+		if errors.Is(err, ErrAppExists) || errors.Is(err, ErrAppOwnerNotFound) {
+			log.Warn("failed to create app", sl.Err(err))
+
+			return models.App{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Error("failed to create app", sl.Err(err))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditApp(ctx, log, actor, eventAppCreated, map[string]any{"app": app.Name, "app_id": app.ID})
+
+	log.Info("app created", slog.String("app_id", app.ID.String()))
+
+	return app, nil
+}
+
+// GetApp returns app by id or name.
+func (a *Admin) GetApp(ctx context.Context, adminToken string, app string) (models.App, error) {
+	const op = "admin.GetApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", app),
+	)
+
+	if _, err := a.authorize(ctx, adminToken); err != nil {
+		log.Warn("caller is not allowed to get apps", sl.Err(err))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	found, err := a.lookupApp(ctx, log, app)
+	if err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return found, nil
+}
+
+// ListApps returns all apps ordered by name.
+func (a *Admin) ListApps(ctx context.Context, adminToken string) ([]models.App, error) {
+	const op = "admin.ListApps"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if _, err := a.authorize(ctx, adminToken); err != nil {
+		log.Warn("caller is not allowed to list apps", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	apps, err := a.appProvider.Apps(ctx)
+	if err != nil {
+		log.Error("failed to list apps", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return apps, nil
+}
+
+// UpdateApp changes fields of the app, found by id or name, that are set in the update.
+// Disabled apps reject logins, and so do apps whose access mode does not admit the user.
+func (a *Admin) UpdateApp(ctx context.Context, adminToken string, app string, update models.AppUpdate) (models.App, error) {
+	const op = "admin.UpdateApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", app),
+	)
+
+	log.Info("updating app")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to update apps", sl.Err(err))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	found, err := a.lookupApp(ctx, log, app)
+	if err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	name, description := found.Name, found.Description
+	if update.Name != nil {
+		name = *update.Name
+	}
+	if update.Description != nil {
+		description = *update.Description
+	}
+	if err := validateApp(name, description); err != nil {
+		log.Warn("invalid app", sl.Err(err))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if update.ClientScopes != nil {
+		if err := validateScopes(*update.ClientScopes); err != nil {
+			log.Warn("invalid client scopes", sl.Err(err))
+
+			return models.App{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if update.RedirectURIs != nil {
+		if err := validateRedirectURIs(*update.RedirectURIs); err != nil {
+			log.Warn("invalid redirect uris", sl.Err(err))
+
+			return models.App{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if update.PostLogoutRedirectURIs != nil {
+		if err := validateRedirectURIs(*update.PostLogoutRedirectURIs); err != nil {
+			log.Warn("invalid post logout redirect uris", sl.Err(err))
+
+			return models.App{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if update.AccessMode != nil || update.AllowedEmailDomains != nil {
+		mode, domains := found.AccessMode, found.AllowedEmailDomains
+		if update.AccessMode != nil {
+			mode = *update.AccessMode
+		}
+		if update.AllowedEmailDomains != nil {
+			domains = *update.AllowedEmailDomains
+		}
+		if err := validateAccess(mode, domains); err != nil {
+			log.Warn("invalid app access", sl.Err(err))
+
+			return models.App{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	updated, err := a.appSaver.UpdateApp(ctx, found.ID, update)
+	if err != nil {
+		err = appError(err)
+		if errors.Is(err, ErrAppNotFound) || errors.Is(err, ErrAppExists) || errors.Is(err, ErrAppOwnerNotFound) {
+			log.Warn("failed to update app", sl.Err(err))
+
+			return models.App{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Error("failed to update app", sl.Err(err))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+	a.permissionCache.InvalidateApps()
+
+	details := map[string]any{"app": found.Name, "app_id": found.ID}
+	if update.Name != nil {
+		details["name"] = updated.Name
+	}
+	if update.Description != nil {
+		details["description"] = updated.Description
+	}
+	if update.OwnerID != nil {
+		details["owner_id"] = update.OwnerID.String()
+	}
+	if update.Enabled != nil {
+		details["enabled"] = updated.Enabled
+	}
+	if update.ClientScopes != nil {
+		details["client_scopes"] = updated.ClientScopes
+	}
+	if update.RedirectURIs != nil {
+		details["redirect_uris"] = updated.RedirectURIs
+	}
+	if update.PostLogoutRedirectURIs != nil {
+		details["post_logout_redirect_uris"] = updated.PostLogoutRedirectURIs
+	}
+	if update.AccessMode != nil {
+		details["access_mode"] = updated.AccessMode
+	}
+	if update.AllowedEmailDomains != nil {
+		details["allowed_email_domains"] = updated.AllowedEmailDomains
+	}
+	a.auditApp(ctx, log, actor, eventAppUpdated, details)
+
+	log.Info("app updated")
+
+	return updated, nil
+}
+
+// DeleteApp deletes the app, found by id or name, with its roles, permissions and settings.
+func (a *Admin) DeleteApp(ctx context.Context, adminToken string, app string) error {
+	const op = "admin.DeleteApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", app),
+	)
+
+	log.Info("deleting app")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to delete apps", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	found, err := a.lookupApp(ctx, log, app)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.appSaver.DeleteApp(ctx, found.ID); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to delete app", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.permissionCache.InvalidateApps()
+
+	a.auditApp(ctx, log, actor, eventAppDeleted, map[string]any{"app": found.Name, "app_id": found.ID})
+
+	log.Info("app deleted")
+
+	return nil
+}
+
+func (a *Admin) lookupApp(ctx context.Context, log *slog.Logger, app string) (models.App, error) {
+	found, err := a.appProvider.LookupApp(ctx, app)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return models.App{}, ErrAppNotFound
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.App{}, err
+	}
+
+	return found, nil
+}
+
+func (a *Admin) auditApp(ctx context.Context, log *slog.Logger, actor models.User, event string, details map[string]any) {
+	if err := a.auditLogger.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   event,
+		Details: details,
+	}); err != nil {
+		log.Error("failed to audit app change", sl.Err(err), slog.String("event", event))
+	}
+}
+
+// validateApp checks the name and the description. Names must not look like UUIDs,
+// so that apps can be referred to by either.
+func validateApp(name string, description string) error {
+	if !appNameRe.MatchString(name) || uuid.Validate(name) == nil {
+		return fmt.Errorf("%w: malformed name", ErrInvalidApp)
+	}
+	if len(description) > maxAppDescriptionLength {
+		return fmt.Errorf("%w: description is longer than %d bytes", ErrInvalidApp, maxAppDescriptionLength)
+	}
+
+	return nil
+}
+
+// validateRedirectURIs accepts https URIs, http URIs on the loopback interface and
+// private-use schemes of native apps such as "com.example.app:/callback" (RFC 8252).
+func validateRedirectURIs(uris []string) error {
+	if len(uris) > maxRedirectURIs {
+		return fmt.Errorf("%w: at most %d redirect uris are allowed", ErrInvalidRedirectURIs, maxRedirectURIs)
+	}
+
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" || strings.Contains(uri, "#") {
+			return fmt.Errorf("%w: malformed uri %q", ErrInvalidRedirectURIs, uri)
+		}
+
+		switch host := u.Hostname(); {
+		case u.Scheme == "https" && host != "":
+		case u.Scheme == "http" && (host == "localhost" || host == "127.0.0.1" || host == "::1"):
+		case strings.Contains(u.Scheme, "."):
+		default:
+			return fmt.Errorf("%w: uri %q must use https", ErrInvalidRedirectURIs, uri)
+		}
+	}
+
+	return nil
+}
+
+// appError maps storage errors of app changes to service errors.
+func appError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrAppNotFound):
+		return ErrAppNotFound
+	case errors.Is(err, storage.ErrAppExists):
+		return ErrAppExists
+	case errors.Is(err, storage.ErrUserNotFound):
+		return ErrAppOwnerNotFound
+	default:
+		return err
+	}
+}
@@ -0,0 +1,174 @@
+package admin
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApps_Lifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	owner := env.addUser(models.UserStatusActive)
+
+	app, err := env.service.CreateApp(ctx, env.adminToken, "coin-keeper", "Budgets", owner.ID.String())
+	require.NoError(t, err)
+	assert.True(t, app.Enabled, "new apps accept logins")
+	assert.Equal(t, owner.ID, app.OwnerID)
+
+	found, err := env.service.GetApp(ctx, env.adminToken, app.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, app, found)
+	found, err = env.service.GetApp(ctx, env.adminToken, "coin-keeper")
+	require.NoError(t, err)
+	assert.Equal(t, app.ID, found.ID)
+
+	name, enabled, noOwner := "coin-tracker", false, uuid.Nil
+	updated, err := env.service.UpdateApp(ctx, env.adminToken, "coin-keeper", models.AppUpdate{
+		Name:    &name,
+		Enabled: &enabled,
+		OwnerID: &noOwner,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "coin-tracker", updated.Name)
+	assert.Equal(t, "Budgets", updated.Description, "unset fields stay unchanged")
+	assert.False(t, updated.Enabled)
+	assert.Equal(t, uuid.Nil, updated.OwnerID)
+	assert.Equal(t, 1, env.storage.appsInvalidated, "permission checks drop the old name")
+
+	apps, err := env.service.ListApps(ctx, env.adminToken)
+	require.NoError(t, err)
+	assert.Len(t, apps, 1)
+
+	require.NoError(t, env.service.DeleteApp(ctx, env.adminToken, "coin-tracker"))
+	assert.Equal(t, 2, env.storage.appsInvalidated)
+	_, err = env.service.GetApp(ctx, env.adminToken, app.ID.String())
+	assert.ErrorIs(t, err, ErrAppNotFound)
+
+	var events []string
+	for _, event := range env.storage.events {
+		events = append(events, event.Event)
+	}
+	assert.Equal(t, []string{eventAppCreated, eventAppUpdated, eventAppDeleted}, events)
+}
+
+func TestCreateApp_Rejected(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(models.UserStatusActive)
+
+	_, err := env.service.CreateApp(ctx, env.adminToken, "coin-keeper", "", "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		appName string
+		desc    string
+		ownerID string
+		want    error
+	}{
+		{name: "not admin", token: env.token(user), appName: "notes", want: ErrPermissionDenied},
+		{name: "taken", token: env.adminToken, appName: "coin-keeper", want: ErrAppExists},
+		{name: "upper case", token: env.adminToken, appName: "Notes", want: ErrInvalidApp},
+		{name: "uuid name", token: env.adminToken, appName: uuid.NewString(), want: ErrInvalidApp},
+		{name: "long description", token: env.adminToken, appName: "notes", desc: strings.Repeat("a", 1025), want: ErrInvalidApp},
+		{name: "unknown owner", token: env.adminToken, appName: "notes", ownerID: uuid.NewString(), want: ErrAppOwnerNotFound},
+		{name: "malformed owner", token: env.adminToken, appName: "notes", ownerID: "owner", want: ErrAppOwnerNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := env.service.CreateApp(ctx, tt.token, tt.appName, tt.desc, tt.ownerID)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func (f *fakeStorage) LookupApp(_ context.Context, idOrName string) (models.App, error) {
+	if id, err := uuid.Parse(idOrName); err == nil {
+		app, ok := f.apps[id]
+		if !ok {
+			return models.App{}, storage.ErrAppNotFound
+		}
+
+		return app, nil
+	}
+
+	for _, app := range f.apps {
+		if app.Name == idOrName {
+			return app, nil
+		}
+	}
+
+	return models.App{}, storage.ErrAppNotFound
+}
+
+func (f *fakeStorage) Apps(context.Context) ([]models.App, error) {
+	apps := make([]models.App, 0, len(f.apps))
+	for _, app := range f.apps {
+		apps = append(apps, app)
+	}
+	slices.SortFunc(apps, func(a, b models.App) int { return strings.Compare(a.Name, b.Name) })
+
+	return apps, nil
+}
+
+func (f *fakeStorage) CreateApp(_ context.Context, app models.App) (models.App, error) {
+	if _, err := f.LookupApp(context.Background(), app.Name); err == nil {
+		return models.App{}, storage.ErrAppExists
+	}
+	if _, ok := f.users[app.OwnerID]; app.OwnerID != uuid.Nil && !ok {
+		return models.App{}, storage.ErrUserNotFound
+	}
+
+	app.ID = uuid.New()
+	f.apps[app.ID] = app
+
+	return app, nil
+}
+
+func (f *fakeStorage) UpdateApp(_ context.Context, appID uuid.UUID, update models.AppUpdate) (models.App, error) {
+	app, ok := f.apps[appID]
+	if !ok {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	if update.Name != nil {
+		app.Name = *update.Name
+	}
+	if update.Description != nil {
+		app.Description = *update.Description
+	}
+	if update.OwnerID != nil {
+		app.OwnerID = *update.OwnerID
+	}
+	if update.Enabled != nil {
+		app.Enabled = *update.Enabled
+	}
+	if update.AccessMode != nil {
+		app.AccessMode = *update.AccessMode
+	}
+	if update.AllowedEmailDomains != nil {
+		app.AllowedEmailDomains = *update.AllowedEmailDomains
+	}
+	f.apps[appID] = app
+
+	return app, nil
+}
+
+func (f *fakeStorage) DeleteApp(_ context.Context, appID uuid.UUID) error {
+	if _, ok := f.apps[appID]; !ok {
+		return storage.ErrAppNotFound
+	}
+	delete(f.apps, appID)
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/clientsecret"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const (
+	eventClientSecretCreated = "app.client_secret_created"
+	eventClientSecretRevoked = "app.client_secret_revoked"
+)
+
+const maxClientScopes = 64
+
+// scopeRe matches scopes apps may request, e.g. "reports:read".
+var scopeRe = regexp.MustCompile(`^[a-z][a-z0-9_.:-]{0,63}$`)
+
+type ClientSecretStorage interface {
+	CreateClientSecret(ctx context.Context, secret models.ClientSecret, hash string) (models.ClientSecret, error)
+	ClientSecrets(ctx context.Context, appID uuid.UUID) ([]models.ClientSecret, error)
+	DeleteClientSecret(ctx context.Context, appID uuid.UUID, secretID uuid.UUID) error
+}
+
+var (
+	ErrInvalidScopes        = errors.New("invalid client scopes")
+	ErrClientSecretNotFound = errors.New("client secret not found")
+	ErrInvalidSecretTTL     = errors.New("invalid client secret ttl")
+)
+
+// CreateClientSecret adds a secret the app, found by id or name, authenticates with as an OAuth client.
+// The secret is returned only here. Zero ttl creates a secret that does not expire.
+func (a *Admin) CreateClientSecret(
+	ctx context.Context,
+	adminToken string,
+	app string,
+	name string,
+	ttl time.Duration,
+) (models.ClientSecret, string, error) {
+	const op = "admin.CreateClientSecret"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", app),
+	)
+
+	log.Info("creating client secret")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to create client secrets", sl.Err(err))
+
+		return models.ClientSecret{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	if ttl < 0 {
+		return models.ClientSecret{}, "", fmt.Errorf("%s: %w", op, ErrInvalidSecretTTL)
+	}
+
+	found, err := a.lookupApp(ctx, log, app)
+	if err != nil {
+		return models.ClientSecret{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, err := clientsecret.Generate()
+	if err != nil {
+		log.Error("failed to generate client secret", sl.Err(err))
+
+		return models.ClientSecret{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	meta := models.ClientSecret{AppID: found.ID, Name: name}
+	if ttl > 0 {
+		meta.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	meta, err = a.clientSecrets.CreateClientSecret(ctx, meta, randtoken.Hash(secret))
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return models.ClientSecret{}, "", fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to save client secret", sl.Err(err))
+
+		return models.ClientSecret{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditApp(ctx, log, actor, eventClientSecretCreated, map[string]any{
+		"app": found.Name, "app_id": found.ID, "secret_id": meta.ID, "name": name,
+	})
+
+	log.Info("client secret created", slog.String("secret_id", meta.ID.String()))
+
+	return meta, secret, nil
+}
+
+// ListClientSecrets returns secrets of the app, found by id or name, without the secrets themselves.
+func (a *Admin) ListClientSecrets(ctx context.Context, adminToken string, app string) ([]models.ClientSecret, error) {
+	const op = "admin.ListClientSecrets"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", app),
+	)
+
+	if _, err := a.authorize(ctx, adminToken); err != nil {
+		log.Warn("caller is not allowed to list client secrets", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	found, err := a.lookupApp(ctx, log, app)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	secrets, err := a.clientSecrets.ClientSecrets(ctx, found.ID)
+	if err != nil {
+		log.Error("failed to list client secrets", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return secrets, nil
+}
+
+// RevokeClientSecret deletes the secret of the app, found by id or name. Tokens already
+// issued with it stay valid until they expire.
+func (a *Admin) RevokeClientSecret(ctx context.Context, adminToken string, app string, secretID string) error {
+	const op = "admin.RevokeClientSecret"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("app", app),
+		slog.String("secret_id", secretID),
+	)
+
+	log.Info("revoking client secret")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to revoke client secrets", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	id, err := uuid.Parse(secretID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrClientSecretNotFound)
+	}
+
+	found, err := a.lookupApp(ctx, log, app)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.clientSecrets.DeleteClientSecret(ctx, found.ID, id); err != nil {
+		if errors.Is(err, storage.ErrClientSecretNotFound) {
+			log.Warn("client secret not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrClientSecretNotFound)
+		}
+
+		log.Error("failed to revoke client secret", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditApp(ctx, log, actor, eventClientSecretRevoked, map[string]any{
+		"app": found.Name, "app_id": found.ID, "secret_id": id,
+	})
+
+	log.Info("client secret revoked")
+
+	return nil
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) > maxClientScopes {
+		return fmt.Errorf("%w: at most %d scopes are allowed", ErrInvalidScopes, maxClientScopes)
+	}
+
+	for _, scope := range scopes {
+		if !scopeRe.MatchString(scope) {
+			return fmt.Errorf("%w: malformed scope %q", ErrInvalidScopes, scope)
+		}
+	}
+
+	return nil
+}
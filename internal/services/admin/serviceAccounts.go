@@ -0,0 +1,351 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/publickey"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const (
+	eventServiceAccountCreated    = "service_account.created"
+	eventServiceAccountDeleted    = "service_account.deleted"
+	eventServiceAccountKeyAdded   = "service_account.key_added"
+	eventServiceAccountKeyRemoved = "service_account.key_removed"
+)
+
+const maxServiceAccountDescriptionLength = 1024
+
+type ServiceAccountStorage interface {
+	CreateServiceAccount(ctx context.Context, account models.ServiceAccount) (models.ServiceAccount, error)
+	// LookupServiceAccount returns service account by id or, if idOrName is not a UUID, by name.
+	LookupServiceAccount(ctx context.Context, idOrName string) (models.ServiceAccount, error)
+	ServiceAccounts(ctx context.Context) ([]models.ServiceAccount, error)
+	DeleteServiceAccount(ctx context.Context, accountID uuid.UUID) error
+	AddServiceAccountKey(ctx context.Context, key models.ServiceAccountKey) (models.ServiceAccountKey, error)
+	ServiceAccountKeys(ctx context.Context, accountID uuid.UUID) ([]models.ServiceAccountKey, error)
+	DeleteServiceAccountKey(ctx context.Context, accountID uuid.UUID, keyID uuid.UUID) error
+}
+
+var (
+	ErrServiceAccountExists      = errors.New("service account already exists")
+	ErrServiceAccountNotFound    = errors.New("service account not found")
+	ErrInvalidServiceAccount     = errors.New("invalid service account")
+	ErrInvalidPublicKey          = errors.New("invalid public key")
+	ErrInvalidKeyTTL             = errors.New("invalid key ttl")
+	ErrServiceAccountKeyNotFound = errors.New("service account key not found")
+)
+
+// CreateServiceAccount creates a service account. Its ID can be given roles and added to groups
+// like a user ID; it authenticates only with keys added to it.
+func (a *Admin) CreateServiceAccount(ctx context.Context, adminToken string, name string, description string) (models.ServiceAccount, error) {
+	const op = "admin.CreateServiceAccount"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("service_account", name),
+	)
+
+	log.Info("creating service account")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to create service accounts", sl.Err(err))
+
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	// Service accounts are named like apps, and likewise must not look like UUIDs.
+	if !appNameRe.MatchString(name) || uuid.Validate(name) == nil {
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w: malformed name", op, ErrInvalidServiceAccount)
+	}
+	if len(description) > maxServiceAccountDescriptionLength {
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w: description is longer than %d bytes",
+			op, ErrInvalidServiceAccount, maxServiceAccountDescriptionLength)
+	}
+
+	account, err := a.serviceAccounts.CreateServiceAccount(ctx, models.ServiceAccount{Name: name, Description: description})
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountExists) {
+			log.Warn("service account already exists", sl.Err(err))
+
+			return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, ErrServiceAccountExists)
+		}
+
+		log.Error("failed to create service account", sl.Err(err))
+
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditServiceAccount(ctx, log, actor, eventServiceAccountCreated, account, nil)
+
+	log.Info("service account created", slog.String("account_id", account.ID.String()))
+
+	return account, nil
+}
+
+// ListServiceAccounts returns all service accounts ordered by name.
+func (a *Admin) ListServiceAccounts(ctx context.Context, adminToken string) ([]models.ServiceAccount, error) {
+	const op = "admin.ListServiceAccounts"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if _, err := a.authorize(ctx, adminToken); err != nil {
+		log.Warn("caller is not allowed to list service accounts", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	accounts, err := a.serviceAccounts.ServiceAccounts(ctx)
+	if err != nil {
+		log.Error("failed to list service accounts", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return accounts, nil
+}
+
+// DeleteServiceAccount deletes the service account, found by id or name, with its keys and roles.
+// Access tokens it holds stop passing introspection.
+func (a *Admin) DeleteServiceAccount(ctx context.Context, adminToken string, account string) error {
+	const op = "admin.DeleteServiceAccount"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("service_account", account),
+	)
+
+	log.Info("deleting service account")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to delete service accounts", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	found, err := a.lookupServiceAccount(ctx, log, account)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.serviceAccounts.DeleteServiceAccount(ctx, found.ID); err != nil {
+		if errors.Is(err, storage.ErrServiceAccountNotFound) {
+			log.Warn("service account not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrServiceAccountNotFound)
+		}
+
+		log.Error("failed to delete service account", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditServiceAccount(ctx, log, actor, eventServiceAccountDeleted, found, nil)
+
+	log.Info("service account deleted")
+
+	return nil
+}
+
+// AddServiceAccountKey registers a PEM-encoded RSA or P-256 public key of the service account,
+// found by id or name. Zero ttl adds a key that does not expire.
+func (a *Admin) AddServiceAccountKey(
+	ctx context.Context,
+	adminToken string,
+	account string,
+	publicKeyPEM string,
+	ttl time.Duration,
+) (models.ServiceAccountKey, error) {
+	const op = "admin.AddServiceAccountKey"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("service_account", account),
+	)
+
+	log.Info("adding service account key")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to add service account keys", sl.Err(err))
+
+		return models.ServiceAccountKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	found, err := a.lookupServiceAccount(ctx, log, account)
+	if err != nil {
+		return models.ServiceAccountKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	parsed, der, err := publickey.ParsePEM([]byte(publicKeyPEM))
+	if err != nil {
+		log.Warn("invalid public key", sl.Err(err))
+
+		return models.ServiceAccountKey{}, fmt.Errorf("%s: %w: %w", op, ErrInvalidPublicKey, err)
+	}
+
+	if ttl < 0 {
+		return models.ServiceAccountKey{}, fmt.Errorf("%s: %w", op, ErrInvalidKeyTTL)
+	}
+
+	key := models.ServiceAccountKey{AccountID: found.ID, PublicKey: der, Algorithm: parsed.Algorithm}
+	if ttl > 0 {
+		key.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	key, err = a.serviceAccounts.AddServiceAccountKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountNotFound) {
+			log.Warn("service account not found", sl.Err(err))
+
+			return models.ServiceAccountKey{}, fmt.Errorf("%s: %w", op, ErrServiceAccountNotFound)
+		}
+
+		log.Error("failed to add service account key", sl.Err(err))
+
+		return models.ServiceAccountKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditServiceAccount(ctx, log, actor, eventServiceAccountKeyAdded, found, map[string]any{
+		"key_id":    key.ID,
+		"algorithm": key.Algorithm,
+	})
+
+	log.Info("service account key added", slog.String("key_id", key.ID.String()))
+
+	return key, nil
+}
+
+// ListServiceAccountKeys returns keys of the service account, found by id or name, including expired ones.
+func (a *Admin) ListServiceAccountKeys(ctx context.Context, adminToken string, account string) ([]models.ServiceAccountKey, error) {
+	const op = "admin.ListServiceAccountKeys"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("service_account", account),
+	)
+
+	if _, err := a.authorize(ctx, adminToken); err != nil {
+		log.Warn("caller is not allowed to list service account keys", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	found, err := a.lookupServiceAccount(ctx, log, account)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	keys, err := a.serviceAccounts.ServiceAccountKeys(ctx, found.ID)
+	if err != nil {
+		log.Error("failed to list service account keys", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// RemoveServiceAccountKey deletes the key of the service account, found by id or name.
+// Tokens issued for assertions signed with the key stay valid until they expire.
+func (a *Admin) RemoveServiceAccountKey(ctx context.Context, adminToken string, account string, keyID string) error {
+	const op = "admin.RemoveServiceAccountKey"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("service_account", account),
+		slog.String("key_id", keyID),
+	)
+
+	log.Info("removing service account key")
+
+	actor, err := a.authorize(ctx, adminToken)
+	if err != nil {
+		log.Warn("caller is not allowed to remove service account keys", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	found, err := a.lookupServiceAccount(ctx, log, account)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := uuid.Parse(keyID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrServiceAccountKeyNotFound)
+	}
+
+	if err := a.serviceAccounts.DeleteServiceAccountKey(ctx, found.ID, id); err != nil {
+		if errors.Is(err, storage.ErrServiceAccountKeyNotFound) {
+			log.Warn("service account key not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrServiceAccountKeyNotFound)
+		}
+
+		log.Error("failed to remove service account key", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditServiceAccount(ctx, log, actor, eventServiceAccountKeyRemoved, found, map[string]any{"key_id": id})
+
+	log.Info("service account key removed")
+
+	return nil
+}
+
+func (a *Admin) lookupServiceAccount(ctx context.Context, log *slog.Logger, account string) (models.ServiceAccount, error) {
+	found, err := a.serviceAccounts.LookupServiceAccount(ctx, account)
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountNotFound) {
+			log.Warn("service account not found", sl.Err(err))
+
+			return models.ServiceAccount{}, ErrServiceAccountNotFound
+		}
+
+		log.Error("failed to get service account", sl.Err(err))
+
+		return models.ServiceAccount{}, err
+	}
+
+	return found, nil
+}
+
+func (a *Admin) auditServiceAccount(
+	ctx context.Context,
+	log *slog.Logger,
+	actor models.User,
+	event string,
+	account models.ServiceAccount,
+	details map[string]any,
+) {
+	if details == nil {
+		details = map[string]any{}
+	}
+	details["service_account"] = account.Name
+	details["account_id"] = account.ID
+
+	if err := a.auditLogger.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   event,
+		Details: details,
+	}); err != nil {
+		log.Error("failed to audit service account change", sl.Err(err), slog.String("event", event))
+	}
+}
@@ -0,0 +1,232 @@
+package admin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/publickey"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *fakeStorage) CreateServiceAccount(_ context.Context, account models.ServiceAccount) (models.ServiceAccount, error) {
+	for _, existing := range f.accounts {
+		if existing.Name == account.Name {
+			return models.ServiceAccount{}, storage.ErrServiceAccountExists
+		}
+	}
+
+	account.ID = uuid.New()
+	account.CreatedAt = time.Now()
+	f.accounts[account.ID] = account
+
+	return account, nil
+}
+
+func (f *fakeStorage) LookupServiceAccount(_ context.Context, idOrName string) (models.ServiceAccount, error) {
+	for _, account := range f.accounts {
+		if account.ID.String() == idOrName || account.Name == idOrName {
+			return account, nil
+		}
+	}
+
+	return models.ServiceAccount{}, storage.ErrServiceAccountNotFound
+}
+
+func (f *fakeStorage) ServiceAccounts(context.Context) ([]models.ServiceAccount, error) {
+	accounts := make([]models.ServiceAccount, 0, len(f.accounts))
+	for _, account := range f.accounts {
+		accounts = append(accounts, account)
+	}
+	slices.SortFunc(accounts, func(a, b models.ServiceAccount) int { return strings.Compare(a.Name, b.Name) })
+
+	return accounts, nil
+}
+
+func (f *fakeStorage) DeleteServiceAccount(_ context.Context, accountID uuid.UUID) error {
+	if _, ok := f.accounts[accountID]; !ok {
+		return storage.ErrServiceAccountNotFound
+	}
+	delete(f.accounts, accountID)
+
+	for id, key := range f.keys {
+		if key.AccountID == accountID {
+			delete(f.keys, id)
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) AddServiceAccountKey(_ context.Context, key models.ServiceAccountKey) (models.ServiceAccountKey, error) {
+	if _, ok := f.accounts[key.AccountID]; !ok {
+		return models.ServiceAccountKey{}, storage.ErrServiceAccountNotFound
+	}
+
+	key.ID = uuid.New()
+	key.CreatedAt = time.Now()
+	f.keys[key.ID] = key
+
+	return key, nil
+}
+
+func (f *fakeStorage) ServiceAccountKeys(_ context.Context, accountID uuid.UUID) ([]models.ServiceAccountKey, error) {
+	var keys []models.ServiceAccountKey
+	for _, key := range f.keys {
+		if key.AccountID == accountID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (f *fakeStorage) DeleteServiceAccountKey(_ context.Context, accountID uuid.UUID, keyID uuid.UUID) error {
+	key, ok := f.keys[keyID]
+	if !ok || key.AccountID != accountID {
+		return storage.ErrServiceAccountKeyNotFound
+	}
+	delete(f.keys, keyID)
+
+	return nil
+}
+
+// publicKeyPEM returns a new P-256 public key in PEM.
+func publicKeyPEM(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestServiceAccounts_Lifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	account, err := env.service.CreateServiceAccount(ctx, env.adminToken, "billing-job", "Nightly billing")
+	require.NoError(t, err)
+	assert.Equal(t, "billing-job", account.Name)
+
+	_, err = env.service.CreateServiceAccount(ctx, env.adminToken, "billing-job", "")
+	assert.ErrorIs(t, err, ErrServiceAccountExists)
+
+	accounts, err := env.service.ListServiceAccounts(ctx, env.adminToken)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ServiceAccount{account}, accounts)
+
+	key, err := env.service.AddServiceAccountKey(ctx, env.adminToken, "billing-job", publicKeyPEM(t), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, key.AccountID)
+	assert.Equal(t, publickey.AlgorithmES256, key.Algorithm)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), key.ExpiresAt, time.Minute)
+
+	forever, err := env.service.AddServiceAccountKey(ctx, env.adminToken, account.ID.String(), publicKeyPEM(t), 0)
+	require.NoError(t, err)
+	assert.True(t, forever.ExpiresAt.IsZero(), "zero ttl adds a key that does not expire")
+
+	keys, err := env.service.ListServiceAccountKeys(ctx, env.adminToken, "billing-job")
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	require.NoError(t, env.service.RemoveServiceAccountKey(ctx, env.adminToken, "billing-job", key.ID.String()))
+	err = env.service.RemoveServiceAccountKey(ctx, env.adminToken, "billing-job", key.ID.String())
+	assert.ErrorIs(t, err, ErrServiceAccountKeyNotFound)
+	err = env.service.RemoveServiceAccountKey(ctx, env.adminToken, "billing-job", "not-a-uuid")
+	assert.ErrorIs(t, err, ErrServiceAccountKeyNotFound)
+
+	require.NoError(t, env.service.DeleteServiceAccount(ctx, env.adminToken, "billing-job"))
+	_, err = env.service.ListServiceAccountKeys(ctx, env.adminToken, "billing-job")
+	assert.ErrorIs(t, err, ErrServiceAccountNotFound)
+	assert.Empty(t, env.storage.keys, "keys go with the account")
+
+	var events []string
+	for _, event := range env.storage.events {
+		events = append(events, event.Event)
+	}
+	assert.Equal(t, []string{
+		eventServiceAccountCreated,
+		eventServiceAccountKeyAdded,
+		eventServiceAccountKeyAdded,
+		eventServiceAccountKeyRemoved,
+		eventServiceAccountDeleted,
+	}, events)
+}
+
+func TestCreateServiceAccount_Rejected(t *testing.T) {
+	tests := []struct {
+		name        string
+		account     string
+		description string
+		notAdmin    bool
+		want        error
+	}{
+		{name: "not admin", account: "billing-job", notAdmin: true, want: ErrPermissionDenied},
+		{name: "upper case", account: "Billing", want: ErrInvalidServiceAccount},
+		{name: "uuid", account: uuid.NewString(), want: ErrInvalidServiceAccount},
+		{
+			name:        "long description",
+			account:     "billing-job",
+			description: strings.Repeat("a", maxServiceAccountDescriptionLength+1),
+			want:        ErrInvalidServiceAccount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+
+			token := env.adminToken
+			if tt.notAdmin {
+				token = env.token(env.addUser(models.UserStatusActive))
+			}
+
+			_, err := env.service.CreateServiceAccount(context.Background(), token, tt.account, tt.description)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, env.storage.accounts)
+		})
+	}
+}
+
+func TestAddServiceAccountKey_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		account string
+		pem     func(t *testing.T) string
+		ttl     time.Duration
+		want    error
+	}{
+		{name: "unknown account", account: "unknown", pem: publicKeyPEM, want: ErrServiceAccountNotFound},
+		{name: "not a key", account: "billing-job", pem: func(*testing.T) string { return "garbage" }, want: ErrInvalidPublicKey},
+		{name: "negative ttl", account: "billing-job", pem: publicKeyPEM, ttl: -time.Second, want: ErrInvalidKeyTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+
+			_, err := env.service.CreateServiceAccount(ctx, env.adminToken, "billing-job", "")
+			require.NoError(t, err)
+
+			_, err = env.service.AddServiceAccountKey(ctx, env.adminToken, tt.account, tt.pem(t), tt.ttl)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, env.storage.keys)
+		})
+	}
+}
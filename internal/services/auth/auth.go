@@ -4,12 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/email"
 	"github.com/sol1corejz/auth-service/internal/lib/jwt"
 	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/pat"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/lib/username"
 	"github.com/sol1corejz/auth-service/internal/storage"
 	"golang.org/x/crypto/bcrypt"
 	"log/slog"
+	"strings"
 	"time"
 )
 
@@ -18,33 +24,81 @@ type Auth struct {
 	userSaver       UserSaver
 	userProvider    UserProvider
 	appProvider     AppProvider
+	orgProvider     OrgProvider
 	tokenProvider   TokenProvider
+	mfaChallenger   MFAChallenger
+	patProvider     PersonalAccessTokenProvider
 	tokenTTL        time.Duration
 	refreshTokenTTL time.Duration
+	requireApproval bool
 }
 
 type UserSaver interface {
-	SaveUser(ctx context.Context, email string, passHash []byte) (uid string, err error)
+	SaveUser(ctx context.Context, email string, passHash []byte, status models.UserStatus) (uid string, err error)
+	SetUsername(ctx context.Context, userID string, username string) error
+	RecordLogin(ctx context.Context, userID string, appID string) error
 }
 
 type UserProvider interface {
-	User(ctx context.Context, email string) (models.User, error)
-	IsAdmin(ctx context.Context, userID string) (bool, error)
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+	IsAdmin(ctx context.Context, userID string, appID uuid.UUID) (bool, error)
 }
 
 type AppProvider interface {
-	App(ctx context.Context, appId string) (models.App, error)
+	// LookupApp returns app by id or, if idOrName is not a UUID, by name.
+	LookupApp(ctx context.Context, idOrName string) (models.App, error)
+	models.AppMembership
+}
+
+type OrgProvider interface {
+	OrgMember(ctx context.Context, orgID string, userID string) (models.OrgMember, error)
 }
 
 type TokenProvider interface {
 	CheckToken(ctx context.Context, accessToken string, refreshToken string) (models.TokenPair, error)
 }
 
+type MFAChallenger interface {
+	// Challenge issues a challenge if login of the user into the app needs a second factor.
+	Challenge(
+		ctx context.Context,
+		user models.User,
+		app models.App,
+		firstFactor models.AuthMethod,
+	) (challenge models.MFAChallenge, required bool, err error)
+}
+
+type PersonalAccessTokenProvider interface {
+	// UsePersonalAccessToken returns the unexpired token with the hash and records it was used.
+	UsePersonalAccessToken(ctx context.Context, hash string, now time.Time) (models.PersonalAccessToken, error)
+}
+
+// MFARequiredError is returned by Login when the password is correct,
+// but the challenge has to be completed with a second factor to get tokens.
+type MFARequiredError struct {
+	ChallengeID        string
+	EnrollmentRequired bool
+	Methods            []models.MFAMethod
+}
+
+func (e *MFARequiredError) Error() string {
+	return "mfa required"
+}
+
 var (
 	ErrInvalidAppID       = errors.New("invalid app id")
+	ErrAppDisabled        = errors.New("app is disabled")
+	ErrAppAccessDenied    = errors.New("user is not allowed into the app")
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserExists         = errors.New("user exists")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidUsername    = errors.New("invalid username")
+	ErrUsernameTaken      = errors.New("username taken")
+	ErrUserInactive       = errors.New("user is not active")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrInvalidACR         = errors.New("invalid acr")
+	// ErrInsufficientAuthentication means the token is valid, but the session has to be stepped up.
+	ErrInsufficientAuthentication = errors.New("insufficient user authentication")
 )
 
 // New returns a new instance of the Auth service.
@@ -53,26 +107,36 @@ func New(
 	userSaver UserSaver,
 	userProvider UserProvider,
 	appProvider AppProvider,
+	orgProvider OrgProvider,
 	tokenProvider TokenProvider,
+	mfaChallenger MFAChallenger,
+	patProvider PersonalAccessTokenProvider,
 	tokenTTL time.Duration,
 	refreshTokenTTL time.Duration,
+	requireApproval bool,
 ) *Auth {
 	return &Auth{
 		log:             log,
 		userSaver:       userSaver,
 		userProvider:    userProvider,
 		appProvider:     appProvider,
+		orgProvider:     orgProvider,
 		tokenProvider:   tokenProvider,
+		mfaChallenger:   mfaChallenger,
+		patProvider:     patProvider,
 		tokenTTL:        tokenTTL,
 		refreshTokenTTL: refreshTokenTTL,
+		requireApproval: requireApproval,
 	}
 }
 
-// Login checks if user with given credentials exists in the system
+// Login checks if user with given credentials exists in the system.
+// Login is either user email or username, app is either app ID or name.
 //
+// If a second factor is needed, returns *MFARequiredError with the challenge to complete.
 // If user exists, but password is incorrect, returns error
 // If user doesn`t exists, returns error
-func (a *Auth) Login(ctx context.Context, email, password string, appName string) (string, string, error) {
+func (a *Auth) Login(ctx context.Context, login, password string, appIDOrName string) (string, string, error) {
 	const op = "auth.LoginUser"
 
 	log := a.log.With(
@@ -81,45 +145,137 @@ func (a *Auth) Login(ctx context.Context, email, password string, appName string
 
 	log.Info("attempting to login user")
 
-	user, err := a.userProvider.User(ctx, email)
+	user, app, auth, err := a.authenticate(ctx, log, login, password, appIDOrName)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("successfully logged in")
+
+	accessToken, refreshToken, err := jwt.NewTokenPair(user, app, auth, a.tokenTTL, a.refreshTokenTTL)
+	if err != nil {
+		a.log.Error("failed to generate token", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Login stats are best effort and must not block the user.
+	if err := a.userSaver.RecordLogin(ctx, user.ID.String(), app.ID.String()); err != nil {
+		log.Error("failed to record login", sl.Err(err))
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// Authenticate checks credentials like Login does, but returns the user, the app and how
+// the user authenticated instead of tokens, for flows that issue tokens later,
+// e.g. the OAuth authorization code flow.
+func (a *Auth) Authenticate(
+	ctx context.Context,
+	login string,
+	password string,
+	appIDOrName string,
+) (models.User, models.App, models.Authentication, error) {
+	const op = "auth.Authenticate"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	user, app, auth, err := a.authenticate(ctx, log, login, password, appIDOrName)
+	if err != nil {
+		return models.User{}, models.App{}, models.Authentication{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, app, auth, nil
+}
+
+func (a *Auth) authenticate(
+	ctx context.Context,
+	log *slog.Logger,
+	login string,
+	password string,
+	appIDOrName string,
+) (models.User, models.App, models.Authentication, error) {
+	user, err := a.userProvider.User(ctx, parseIdentifier(login))
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
-			a.log.Warn("user not found", sl.Err(err))
+			log.Warn("user not found", sl.Err(err))
 
-			return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+			return models.User{}, models.App{}, models.Authentication{}, ErrInvalidCredentials
 		}
 
-		a.log.Error("failed to get user", sl.Err(err))
+		log.Error("failed to get user", sl.Err(err))
 
-		return "", "", fmt.Errorf("%s: %w", op, err)
+		return models.User{}, models.App{}, models.Authentication{}, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		a.log.Info("invalid credentials", sl.Err(err))
+		log.Info("invalid credentials", sl.Err(err))
+
+		return models.User{}, models.App{}, models.Authentication{}, ErrInvalidCredentials
+	}
 
-		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	if !user.IsActive(time.Now()) {
+		log.Warn("user is not active", slog.String("status", string(user.Status)))
+
+		return models.User{}, models.App{}, models.Authentication{}, ErrUserInactive
 	}
 
-	app, err := a.appProvider.App(ctx, appName)
+	app, err := a.appProvider.LookupApp(ctx, appIDOrName)
 	if err != nil {
-		return "", "", fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return models.User{}, models.App{}, models.Authentication{}, ErrInvalidAppID
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.User{}, models.App{}, models.Authentication{}, err
 	}
 
-	log.Info("successfully logged in")
+	if !app.Enabled {
+		log.Warn("app is disabled", slog.String("app", app.Name))
+
+		return models.User{}, models.App{}, models.Authentication{}, ErrAppDisabled
+	}
 
-	accessToken, refreshToken, err := jwt.NewTokenPair(user, app, a.tokenTTL, a.refreshTokenTTL)
+	admitted, err := app.Admits(ctx, a.appProvider, user)
 	if err != nil {
-		a.log.Error("failed to generate token", sl.Err(err))
+		log.Error("failed to check app access", sl.Err(err))
 
-		return "", "", fmt.Errorf("%s: %w", op, err)
+		return models.User{}, models.App{}, models.Authentication{}, err
 	}
+	if !admitted {
+		log.Warn("user is not allowed into the app", slog.String("app", app.Name), slog.String("access_mode", string(app.AccessMode)))
 
-	return accessToken, refreshToken, nil
+		return models.User{}, models.App{}, models.Authentication{}, ErrAppAccessDenied
+	}
+
+	challenge, required, err := a.mfaChallenger.Challenge(ctx, user, app, models.AuthMethodPassword)
+	if err != nil {
+		log.Error("failed to issue mfa challenge", sl.Err(err))
+
+		return models.User{}, models.App{}, models.Authentication{}, err
+	}
+	if required {
+		log.Info("mfa challenge issued", slog.Bool("enrollment_required", challenge.EnrollmentRequired))
+
+		return models.User{}, models.App{}, models.Authentication{}, &MFARequiredError{
+			ChallengeID:        challenge.ID.String(),
+			EnrollmentRequired: challenge.EnrollmentRequired,
+			Methods:            challenge.Methods,
+		}
+	}
+
+	return user, app, models.NewAuthentication(models.AuthMethodPassword), nil
 }
 
 // RegisterNewUser registers new user in the system and returns  user ID.
-// If user with given username already exists, returns error.
-func (a *Auth) RegisterNewUser(ctx context.Context, email string, pass string) (string, error) {
+// Email is stored normalized, see email.Normalize.
+// If user with given email already exists, returns error.
+func (a *Auth) RegisterNewUser(ctx context.Context, userEmail string, pass string) (string, error) {
 	const op = "auth.RegisterNewUser"
 
 	log := a.log.With(
@@ -135,7 +291,13 @@ func (a *Auth) RegisterNewUser(ctx context.Context, email string, pass string) (
 		return "", fmt.Errorf("%s, %w", op, err)
 	}
 
-	id, err := a.userSaver.SaveUser(ctx, email, passHash)
+	// Users pending approval can't log in until an admin activates them.
+	status := models.UserStatusActive
+	if a.requireApproval {
+		status = models.UserStatusPending
+	}
+
+	id, err := a.userSaver.SaveUser(ctx, email.Normalize(userEmail), passHash, status)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
 			log.Warn("user already exists", sl.Err(err))
@@ -147,27 +309,99 @@ func (a *Auth) RegisterNewUser(ctx context.Context, email string, pass string) (
 		return "", fmt.Errorf("%s, %w", op, err)
 	}
 
-	log.Info("user registered")
+	log.Info("user registered", slog.String("status", string(status)))
 	return id, nil
 }
 
-// IsAdmin checks if user is admin
-func (a *Auth) IsAdmin(ctx context.Context, userID string) (bool, error) {
+// SetUsername sets username the owner of the access token can log in with instead of email.
+//
+// If the token is not a valid access token of an active user, returns ErrInvalidToken.
+// If username breaks charset rules or is reserved, returns ErrInvalidUsername.
+// If username belongs to another user, returns ErrUsernameTaken.
+func (a *Auth) SetUsername(ctx context.Context, accessToken string, name string) error {
+	const op = "auth.SetUsername"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	log = log.With(slog.String("user_id", claims.UserID))
+
+	if _, err := a.activeUser(ctx, log, claims.UserID, claims.IssuedAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("setting username")
+
+	name = username.Normalize(name)
+	if err := username.Validate(name); err != nil {
+		log.Warn("invalid username", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidUsername)
+	}
+
+	if err := a.userSaver.SetUsername(ctx, claims.UserID, name); err != nil {
+		if errors.Is(err, storage.ErrUsernameTaken) {
+			log.Warn("username already taken", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUsernameTaken)
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to set username", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("username set")
+
+	return nil
+}
+
+// IsAdmin checks if user is admin of the app. Admins of all apps are admins of each of them.
+// App is either app ID or name; empty appName checks whether user is admin of all apps.
+func (a *Auth) IsAdmin(ctx context.Context, userID string, appName string) (bool, error) {
 	const op = "auth.IsAdmin"
 
 	log := a.log.With(
 		slog.String("op", op),
 		slog.String("user_id", userID),
+		slog.String("app", appName),
 	)
 
 	log.Info("checking if user is admin")
 
-	isAdmin, err := a.userProvider.IsAdmin(ctx, userID)
+	var appID uuid.UUID
+	if appName != "" {
+		app, err := a.appProvider.LookupApp(ctx, appName)
+		if err != nil {
+			if errors.Is(err, storage.ErrAppNotFound) {
+				log.Warn("app not found", sl.Err(err))
+
+				return false, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+			}
+
+			log.Error("failed to get app", sl.Err(err))
+
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		appID = app.ID
+	}
+
+	isAdmin, err := a.userProvider.IsAdmin(ctx, userID, appID)
 	if err != nil {
-		if errors.Is(err, storage.ErrAppNotFound) {
-			log.Warn("app not found", sl.Err(err))
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
 
-			return false, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+			return false, fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
 
 		log.Error("failed to check if user is admin", sl.Err(err))
@@ -180,6 +414,9 @@ func (a *Auth) IsAdmin(ctx context.Context, userID string) (bool, error) {
 	return isAdmin, nil
 }
 
+// CheckAndRefreshTokens checks the token pair and, if the access token expired, returns a new pair.
+// A personal access token is checked on its own, without a refresh token, and never refreshed.
+// Returns ErrInvalidToken for invalid, expired or revoked tokens.
 func (a *Auth) CheckAndRefreshTokens(ctx context.Context, accessToken string, refreshToken string) (bool, string, string, error) {
 	const op = "auth.CheckToken"
 
@@ -187,16 +424,354 @@ func (a *Auth) CheckAndRefreshTokens(ctx context.Context, accessToken string, re
 		slog.String("op", op),
 	)
 
+	if refreshToken == "" && pat.Looks(accessToken) {
+		log.Info("checking personal access token")
+
+		if _, _, err := a.personalAccessToken(ctx, log, accessToken); err != nil {
+			return false, "", "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		return true, "", "", nil
+	}
+
 	log.Info("checking token pair")
 
-	tokenPair, err := a.tokenProvider.CheckToken(ctx, accessToken, refreshToken)
+	claims, err := jwt.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return false, "", "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	user, err := a.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: claims.UserID})
 	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return false, "", "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return false, "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !user.IsActive(time.Now()) {
+		log.Warn("user is not active", slog.String("status", string(user.Status)))
+
+		return false, "", "", fmt.Errorf("%s: %w", op, ErrUserInactive)
+	}
+
+	if claims.IssuedAt.Before(user.SessionsRevokedAt) {
+		log.Warn("refresh token was revoked")
+
+		return false, "", "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if err := a.checkOrgMembership(ctx, claims.Auth.OrgID, claims.UserID); err != nil {
+		if errors.Is(err, storage.ErrOrgMemberNotFound) {
+			log.Warn("user left the organization of the session")
+
+			return false, "", "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to check organization membership", sl.Err(err))
+
 		return false, "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
+	app, err := a.appProvider.LookupApp(ctx, claims.AppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app of the session not found", sl.Err(err))
+
+			return false, "", "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return false, "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !app.Enabled {
+		log.Warn("app is disabled", slog.String("app", app.Name))
+
+		return false, "", "", fmt.Errorf("%s: %w", op, ErrAppDisabled)
+	}
+
+	admitted, err := app.Admits(ctx, a.appProvider, user)
+	if err != nil {
+		log.Error("failed to check app access", sl.Err(err))
+
+		return false, "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	if !admitted {
+		log.Warn("user is no longer allowed into the app", slog.String("app", app.Name), slog.String("access_mode", string(app.AccessMode)))
+
+		return false, "", "", fmt.Errorf("%s: %w", op, ErrAppAccessDenied)
+	}
+
+	tokenPair, err := a.tokenProvider.CheckToken(ctx, accessToken, refreshToken)
+	if err != nil {
+		return false, "", "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
 	if tokenPair.AccessToken == accessToken {
 		return true, "", "", nil
 	}
 
 	return true, accessToken, refreshToken, nil
 }
+
+// parseIdentifier tells email from username: usernames can't contain '@'.
+func parseIdentifier(login string) models.Identifier {
+	if strings.Contains(login, "@") {
+		return models.Identifier{Kind: models.IdentifierEmail, Value: email.Normalize(login)}
+	}
+
+	return models.Identifier{Kind: models.IdentifierUsername, Value: username.Normalize(login)}
+}
+
+// Introspect validates access token for a resource server and returns its details.
+// Personal access tokens and tokens apps got with client credentials are accepted too; their
+// scopes are in the Auth of the result.
+//
+// A non-empty minACR and a positive maxAge demand at least that authentication class and
+// an authentication no older than maxAge. A valid token that fails them is rejected with
+// ErrInsufficientAuthentication, so the client can step up and retry. Personal access tokens
+// and client tokens don't come from a login, so they never meet such demands.
+func (a *Auth) Introspect(ctx context.Context, accessToken string, minACR models.ACR, maxAge time.Duration) (models.TokenInfo, error) {
+	const op = "auth.Introspect"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if minACR != "" && !minACR.Valid() {
+		return models.TokenInfo{}, fmt.Errorf("%s: %w", op, ErrInvalidACR)
+	}
+
+	if pat.Looks(accessToken) {
+		token, user, err := a.personalAccessToken(ctx, log, accessToken)
+		if err != nil {
+			return models.TokenInfo{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if minACR != "" || maxAge > 0 {
+			log.Info("personal access token can't meet authentication demands", slog.String("token_id", token.ID.String()))
+
+			return models.TokenInfo{}, fmt.Errorf("%s: %w", op, ErrInsufficientAuthentication)
+		}
+
+		return models.TokenInfo{
+			UserID:                user.ID.String(),
+			Email:                 user.Email,
+			PersonalAccessTokenID: token.ID.String(),
+			IssuedAt:              token.CreatedAt,
+			ExpiresAt:             token.ExpiresAt,
+			Auth:                  models.Authentication{Scopes: token.Scopes},
+		}, nil
+	}
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		info, err := a.clientToken(ctx, log, accessToken, minACR, maxAge)
+		if errors.Is(err, ErrInvalidToken) {
+			info, err = a.serviceAccountToken(ctx, log, accessToken, minACR, maxAge)
+		}
+		if err != nil {
+			return models.TokenInfo{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return info, nil
+	}
+	log = log.With(slog.String("user_id", claims.UserID))
+
+	if _, err := a.activeUser(ctx, log, claims.UserID, claims.IssuedAt); err != nil {
+		return models.TokenInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.checkOrgMembership(ctx, claims.Auth.OrgID, claims.UserID); err != nil {
+		if errors.Is(err, storage.ErrOrgMemberNotFound) {
+			return models.TokenInfo{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to check organization membership", sl.Err(err))
+
+		return models.TokenInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if minACR != "" && !claims.ACR.AtLeast(minACR) {
+		log.Info("token acr is too low", slog.String("acr", string(claims.ACR)), slog.String("min_acr", string(minACR)))
+
+		return models.TokenInfo{}, fmt.Errorf("%s: %w", op, ErrInsufficientAuthentication)
+	}
+
+	if maxAge > 0 && (claims.Auth.Time.IsZero() || time.Since(claims.Auth.Time) > maxAge) {
+		log.Info("token authentication is too old", slog.Time("auth_time", claims.Auth.Time))
+
+		return models.TokenInfo{}, fmt.Errorf("%s: %w", op, ErrInsufficientAuthentication)
+	}
+
+	var orgID string
+	if claims.Auth.OrgID != uuid.Nil {
+		orgID = claims.Auth.OrgID.String()
+	}
+
+	return models.TokenInfo{
+		UserID:    claims.UserID,
+		Email:     claims.Email,
+		AppID:     claims.AppID,
+		OrgID:     orgID,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+		Auth:      claims.Auth,
+		ACR:       claims.ACR,
+	}, nil
+}
+
+// clientToken introspects an access token an app got for itself with client credentials.
+// The token is valid while the app exists and is enabled. Apps authenticate with secrets
+// alone, so tokens can't meet acr or max age demands.
+func (a *Auth) clientToken(
+	ctx context.Context,
+	log *slog.Logger,
+	accessToken string,
+	minACR models.ACR,
+	maxAge time.Duration,
+) (models.TokenInfo, error) {
+	claims, err := jwt.ParseClientToken(accessToken)
+	if err != nil {
+		return models.TokenInfo{}, ErrInvalidToken
+	}
+	log = log.With(slog.String("client_id", claims.ClientID))
+
+	app, err := a.appProvider.LookupApp(ctx, claims.ClientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Info("client token of a deleted app")
+
+			return models.TokenInfo{}, ErrInvalidToken
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.TokenInfo{}, err
+	}
+
+	if !app.Enabled {
+		log.Info("client token of a disabled app")
+
+		return models.TokenInfo{}, ErrInvalidToken
+	}
+
+	if minACR != "" || maxAge > 0 {
+		log.Info("client token can't meet authentication demands")
+
+		return models.TokenInfo{}, ErrInsufficientAuthentication
+	}
+
+	return models.TokenInfo{
+		AppID:     claims.ClientID,
+		ClientID:  claims.ClientID,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+		Auth:      models.Authentication{Scopes: claims.Scopes},
+	}, nil
+}
+
+// serviceAccountToken introspects an access token issued to a service account. Service accounts
+// authenticate with keys alone, so tokens can't meet acr or max age demands.
+func (a *Auth) serviceAccountToken(
+	ctx context.Context,
+	log *slog.Logger,
+	accessToken string,
+	minACR models.ACR,
+	maxAge time.Duration,
+) (models.TokenInfo, error) {
+	claims, err := jwt.ParseServiceAccountToken(accessToken)
+	if err != nil {
+		return models.TokenInfo{}, ErrInvalidToken
+	}
+	log = log.With(slog.String("account_id", claims.AccountID))
+
+	if _, err := a.activeUser(ctx, log, claims.AccountID, claims.IssuedAt); err != nil {
+		return models.TokenInfo{}, err
+	}
+
+	if minACR != "" || maxAge > 0 {
+		log.Info("service account token can't meet authentication demands")
+
+		return models.TokenInfo{}, ErrInsufficientAuthentication
+	}
+
+	return models.TokenInfo{
+		UserID:         claims.AccountID,
+		ServiceAccount: true,
+		IssuedAt:       claims.IssuedAt,
+		ExpiresAt:      claims.ExpiresAt,
+	}, nil
+}
+
+// activeUser returns the user a token issued at issuedAt belongs to, if the user is active
+// and their sessions were not revoked since.
+func (a *Auth) activeUser(ctx context.Context, log *slog.Logger, userID string, issuedAt time.Time) (models.User, error) {
+	user, err := a.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, ErrInvalidToken
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.User{}, err
+	}
+
+	if !user.IsActive(time.Now()) || issuedAt.Before(user.SessionsRevokedAt) {
+		return models.User{}, ErrInvalidToken
+	}
+
+	return user, nil
+}
+
+// personalAccessToken returns the unexpired personal access token and its active owner,
+// and records the token was used. Revoking sessions of the user doesn't revoke their tokens.
+func (a *Auth) personalAccessToken(ctx context.Context, log *slog.Logger, token string) (models.PersonalAccessToken, models.User, error) {
+	found, err := a.patProvider.UsePersonalAccessToken(ctx, randtoken.Hash(token), time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrPersonalAccessTokenNotFound) {
+			return models.PersonalAccessToken{}, models.User{}, ErrInvalidToken
+		}
+
+		log.Error("failed to get personal access token", sl.Err(err))
+
+		return models.PersonalAccessToken{}, models.User{}, err
+	}
+
+	user, err := a.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: found.UserID.String()})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.PersonalAccessToken{}, models.User{}, ErrInvalidToken
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.PersonalAccessToken{}, models.User{}, err
+	}
+
+	if !user.IsActive(time.Now()) {
+		return models.PersonalAccessToken{}, models.User{}, ErrInvalidToken
+	}
+
+	return found, user, nil
+}
+
+// checkOrgMembership returns storage.ErrOrgMemberNotFound if the session is scoped to
+// an organization the user is no longer a member of.
+func (a *Auth) checkOrgMembership(ctx context.Context, orgID uuid.UUID, userID string) error {
+	if orgID == uuid.Nil {
+		return nil
+	}
+
+	_, err := a.orgProvider.OrgMember(ctx, orgID.String(), userID)
+
+	return err
+}
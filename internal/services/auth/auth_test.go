@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetUsername(t *testing.T) {
+	env := newTestEnv(t)
+
+	err := env.service.SetUsername(context.Background(), env.accessToken, "  Alice.Dev ")
+	require.NoError(t, err)
+	assert.Equal(t, "alice.dev", env.storage.users[env.user.ID].Username)
+}
+
+func TestSetUsername_Rejected(t *testing.T) {
+	env := newTestEnv(t)
+
+	other := models.User{ID: uuid.New(), Username: "taken", Status: models.UserStatusActive}
+	env.storage.users[other.ID] = other
+
+	err := env.service.SetUsername(context.Background(), "not-a-token", "alice")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	err = env.service.SetUsername(context.Background(), env.accessToken, "admin")
+	assert.ErrorIs(t, err, ErrInvalidUsername)
+
+	err = env.service.SetUsername(context.Background(), env.accessToken, "Taken")
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+
+	// Revoking sessions also revokes the access tokens of those sessions.
+	env.user.SessionsRevokedAt = time.Now().Add(time.Minute)
+	env.storage.users[env.user.ID] = env.user
+
+	err = env.service.SetUsername(context.Background(), env.accessToken, "alice")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRegisterNewUser_RequireApproval(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	id, err := env.service.RegisterNewUser(ctx, "bob@example.com", "password")
+	require.NoError(t, err)
+	assert.Equal(t, models.UserStatusActive, env.storage.users[uuid.MustParse(id)].Status)
+
+	env.service.requireApproval = true
+
+	id, err = env.service.RegisterNewUser(ctx, "carol@example.com", "password")
+	require.NoError(t, err)
+	assert.Equal(t, models.UserStatusPending, env.storage.users[uuid.MustParse(id)].Status)
+
+	// Pending users can't log in until an admin activates them.
+	_, _, err = env.service.Login(ctx, "carol@example.com", "password", env.app.Name)
+	assert.ErrorIs(t, err, ErrUserInactive)
+}
+
+func TestLogin_AppAccess(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	id, err := env.service.RegisterNewUser(ctx, "bob@example.com", "password")
+	require.NoError(t, err)
+
+	app := env.app
+	app.AccessMode = models.AppAccessMembers
+	env.storage.apps[app.ID] = app
+
+	_, _, err = env.service.Login(ctx, "bob@example.com", "password", app.Name)
+	assert.ErrorIs(t, err, ErrAppAccessDenied)
+
+	env.storage.members[uuid.MustParse(id)] = true
+
+	_, _, err = env.service.Login(ctx, "bob@example.com", "password", app.Name)
+	require.NoError(t, err)
+
+	app.AccessMode = models.AppAccessEmailDomain
+	app.AllowedEmailDomains = []string{"corp.example.com"}
+	env.storage.apps[app.ID] = app
+
+	_, _, err = env.service.Login(ctx, "bob@example.com", "password", app.Name)
+	assert.ErrorIs(t, err, ErrAppAccessDenied)
+
+	app.AccessMode = models.AppAccessOpen
+	app.Enabled = false
+	env.storage.apps[app.ID] = app
+
+	_, _, err = env.service.Login(ctx, "bob@example.com", "password", app.Name)
+	assert.ErrorIs(t, err, ErrAppDisabled)
+}
+
+func TestCheckAndRefreshTokens_AppAccess(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	accessToken, refreshToken, err := jwt.NewTokenPair(env.user, env.app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	valid, _, _, err := env.service.CheckAndRefreshTokens(ctx, accessToken, refreshToken)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// A session outlives neither the membership nor the app it was issued for.
+	app := env.app
+	app.AccessMode = models.AppAccessMembers
+	env.storage.apps[app.ID] = app
+
+	_, _, _, err = env.service.CheckAndRefreshTokens(ctx, accessToken, refreshToken)
+	assert.ErrorIs(t, err, ErrAppAccessDenied)
+
+	env.storage.members[env.user.ID] = true
+
+	_, _, _, err = env.service.CheckAndRefreshTokens(ctx, accessToken, refreshToken)
+	require.NoError(t, err)
+
+	app.Enabled = false
+	env.storage.apps[app.ID] = app
+
+	_, _, _, err = env.service.CheckAndRefreshTokens(ctx, accessToken, refreshToken)
+	assert.ErrorIs(t, err, ErrAppDisabled)
+
+	delete(env.storage.apps, app.ID)
+
+	_, _, _, err = env.service.CheckAndRefreshTokens(ctx, accessToken, refreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestIntrospect_ClientToken(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	token, expiresAt, err := jwt.NewClientToken(env.app, []string{"reports:read"}, time.Hour)
+	require.NoError(t, err)
+
+	info, err := env.service.Introspect(ctx, token, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, env.app.ID.String(), info.ClientID)
+	assert.Equal(t, env.app.ID.String(), info.AppID)
+	assert.Empty(t, info.UserID)
+	assert.Equal(t, []string{"reports:read"}, info.Auth.Scopes)
+	assert.Equal(t, expiresAt, info.ExpiresAt)
+
+	_, err = env.service.Introspect(ctx, token, models.ACRMultiFactor, 0)
+	assert.ErrorIs(t, err, ErrInsufficientAuthentication)
+
+	app := env.app
+	app.Enabled = false
+	env.storage.apps[app.ID] = app
+
+	_, err = env.service.Introspect(ctx, token, "", 0)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	delete(env.storage.apps, app.ID)
+
+	_, err = env.service.Introspect(ctx, token, "", 0)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+type testEnv struct {
+	service     *Auth
+	storage     *fakeStorage
+	user        models.User
+	app         models.App
+	accessToken string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com", Status: models.UserStatusActive}
+	app := models.App{ID: uuid.New(), Name: "test-app", Enabled: true, AccessMode: models.AppAccessOpen}
+
+	fake := newFakeStorage()
+	fake.users[user.ID] = user
+	fake.apps[app.ID] = app
+
+	accessToken, _, err := jwt.NewTokenPair(user, app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testEnv{
+		service:     New(log, fake, fake, fake, fake, fake, fake, fake, time.Hour, time.Hour, false),
+		storage:     fake,
+		user:        user,
+		app:         app,
+		accessToken: accessToken,
+	}
+}
+
+// fakeStorage keeps users and apps in memory. MFA is never required.
+type fakeStorage struct {
+	users   map[uuid.UUID]models.User
+	apps    map[uuid.UUID]models.App
+	members map[uuid.UUID]bool
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		users:   map[uuid.UUID]models.User{},
+		apps:    map[uuid.UUID]models.App{},
+		members: map[uuid.UUID]bool{},
+	}
+}
+
+func (f *fakeStorage) SaveUser(_ context.Context, email string, passHash []byte, status models.UserStatus) (string, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return "", storage.ErrUserExists
+		}
+	}
+
+	user := models.User{ID: uuid.New(), Email: email, PassHash: passHash, Status: status}
+	f.users[user.ID] = user
+
+	return user.ID.String(), nil
+}
+
+func (f *fakeStorage) SetUsername(_ context.Context, userID string, username string) error {
+	for _, user := range f.users {
+		if user.Username == username && user.ID.String() != userID {
+			return storage.ErrUsernameTaken
+		}
+	}
+
+	user, ok := f.users[uuid.MustParse(userID)]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	user.Username = username
+	f.users[user.ID] = user
+
+	return nil
+}
+
+func (f *fakeStorage) RecordLogin(context.Context, string, string) error {
+	return nil
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	for _, user := range f.users {
+		switch {
+		case identifier.Kind == models.IdentifierID && user.ID.String() == identifier.Value,
+			identifier.Kind == models.IdentifierEmail && user.Email == identifier.Value,
+			identifier.Kind == models.IdentifierUsername && user.Username != "" && user.Username == identifier.Value:
+			return user, nil
+		}
+	}
+
+	return models.User{}, storage.ErrUserNotFound
+}
+
+func (f *fakeStorage) IsAdmin(_ context.Context, userID string, _ uuid.UUID) (bool, error) {
+	if _, ok := f.users[uuid.MustParse(userID)]; !ok {
+		return false, storage.ErrUserNotFound
+	}
+
+	return false, nil
+}
+
+func (f *fakeStorage) LookupApp(_ context.Context, idOrName string) (models.App, error) {
+	for _, app := range f.apps {
+		if app.ID.String() == idOrName || app.Name == idOrName {
+			return app, nil
+		}
+	}
+
+	return models.App{}, storage.ErrAppNotFound
+}
+
+func (f *fakeStorage) IsAppMember(_ context.Context, _ uuid.UUID, userID uuid.UUID) (bool, error) {
+	return f.members[userID], nil
+}
+
+func (f *fakeStorage) OrgMember(context.Context, string, string) (models.OrgMember, error) {
+	return models.OrgMember{}, storage.ErrOrgMemberNotFound
+}
+
+func (f *fakeStorage) CheckToken(_ context.Context, accessToken string, refreshToken string) (models.TokenPair, error) {
+	return models.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (f *fakeStorage) Challenge(context.Context, models.User, models.App, models.AuthMethod) (models.MFAChallenge, bool, error) {
+	return models.MFAChallenge{}, false, nil
+}
+
+func (f *fakeStorage) UsePersonalAccessToken(context.Context, string, time.Time) (models.PersonalAccessToken, error) {
+	return models.PersonalAccessToken{}, storage.ErrPersonalAccessTokenNotFound
+}
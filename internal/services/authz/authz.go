@@ -0,0 +1,230 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/policy"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// Authz answers whether a user may perform an action on a resource
+// by evaluating attribute-based policy rules.
+type Authz struct {
+	log             *slog.Logger
+	userProvider    UserProvider
+	appProvider     AppProvider
+	subjectProvider SubjectProvider
+	policy          *policy.Policy
+	location        *time.Location
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+}
+
+type AppProvider interface {
+	// LookupApp returns app by id or, if idOrName is not a UUID, by name.
+	LookupApp(ctx context.Context, idOrName string) (models.App, error)
+}
+
+// SubjectProvider supplies what policies know about a user besides the user record.
+type SubjectProvider interface {
+	UserRoles(ctx context.Context, userID string, appID uuid.UUID) ([]models.Role, error)
+	UserPermissions(ctx context.Context, userID string, appID uuid.UUID) ([]string, error)
+	UserGroups(ctx context.Context, userID string) ([]models.Group, error)
+	UserOrganizations(ctx context.Context, userID string) ([]models.Organization, error)
+	UserAttributes(ctx context.Context, userID string) (map[string]any, error)
+}
+
+// RuleUserInactive is the Rule of decisions denying users that are not active: pending,
+// deactivated or suspended, with or without an end date. Policies are not evaluated for them.
+const RuleUserInactive = "builtin:user-inactive"
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrAppNotFound  = errors.New("app not found")
+	ErrInvalidInput = errors.New("invalid authorization request")
+)
+
+// New returns a new instance of the Authz service. Hour and weekday put into
+// the context of evaluations are taken in the given location.
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	subjectProvider SubjectProvider,
+	policy *policy.Policy,
+	location *time.Location,
+) *Authz {
+	return &Authz{
+		log:             log,
+		userProvider:    userProvider,
+		appProvider:     appProvider,
+		subjectProvider: subjectProvider,
+		policy:          policy,
+		location:        location,
+	}
+}
+
+// Authorize decides whether the user may perform the action on the resource in the app,
+// given by ID or name.
+// Users that are not active are denied with RuleUserInactive whatever the policy says.
+//
+// Policies see the subject with its id, email, username, status, roles, permissions,
+// groups, organizations and custom attributes; the resource as passed by the caller;
+// and the context passed by the caller with time, hour, weekday and app set by the server.
+func (a *Authz) Authorize(
+	ctx context.Context,
+	userID string,
+	appName string,
+	action string,
+	resource map[string]any,
+	reqContext map[string]any,
+) (models.AuthzDecision, error) {
+	const op = "authz.Authorize"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID),
+		slog.String("app", appName),
+		slog.String("action", action),
+	)
+
+	if action == "" {
+		return models.AuthzDecision{}, fmt.Errorf("%s: %w", op, ErrInvalidInput)
+	}
+
+	app, err := a.appProvider.LookupApp(ctx, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return models.AuthzDecision{}, fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.AuthzDecision{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return models.AuthzDecision{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.AuthzDecision{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !user.IsActive(time.Now()) {
+		log.Info("user is not active, access denied", slog.String("status", string(user.Status)))
+
+		return models.AuthzDecision{Allowed: false, Rule: RuleUserInactive}, nil
+	}
+
+	subject, err := a.subject(ctx, user, app.ID)
+	if err != nil {
+		log.Error("failed to get subject attributes", sl.Err(err))
+
+		return models.AuthzDecision{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if resource == nil {
+		resource = map[string]any{}
+	}
+
+	now := time.Now().In(a.location)
+	evalContext := maps.Clone(reqContext)
+	if evalContext == nil {
+		evalContext = map[string]any{}
+	}
+	evalContext["time"] = now.Format(time.RFC3339)
+	evalContext["hour"] = now.Hour()
+	evalContext["weekday"] = now.Weekday().String()
+	evalContext["app"] = app.Name
+
+	res := a.policy.Evaluate(action, policy.Input{
+		Subject:  subject,
+		Resource: resource,
+		Context:  evalContext,
+	})
+
+	if res.Changed() {
+		log.Info("dry-run rules would change the decision",
+			slog.Bool("allowed", res.Allowed),
+			slog.String("rule", res.Rule),
+			slog.Bool("dry_run_allowed", res.DryRun.Allowed),
+			slog.String("dry_run_rule", res.DryRun.Rule),
+		)
+	}
+
+	log.Debug("authorization decided", slog.Bool("allowed", res.Allowed), slog.String("rule", res.Rule))
+
+	return models.AuthzDecision{Allowed: res.Allowed, Rule: res.Rule}, nil
+}
+
+// subject collects attributes of the user in the app.
+func (a *Authz) subject(ctx context.Context, user models.User, appID uuid.UUID) (map[string]any, error) {
+	userID := user.ID.String()
+
+	roles, err := a.subjectProvider.UserRoles(ctx, userID, appID)
+	if err != nil {
+		return nil, err
+	}
+	roleNames := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+	}
+
+	permissions, err := a.subjectProvider.UserPermissions(ctx, userID, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := a.subjectProvider.UserGroups(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	groupNames := make([]string, 0, len(groups))
+	for _, group := range groups {
+		groupNames = append(groupNames, group.Name)
+	}
+
+	orgs, err := a.subjectProvider.UserOrganizations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	orgIDs := make([]string, 0, len(orgs))
+	for _, org := range orgs {
+		orgIDs = append(orgIDs, org.ID.String())
+	}
+
+	attributes, err := a.subjectProvider.UserAttributes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"id":          user.ID.String(),
+		"email":       user.Email,
+		"username":    user.Username,
+		"status":      string(user.Status),
+		"roles":       roleNames,
+		"permissions": permissions,
+		"groups":      groupNames,
+		"orgs":        orgIDs,
+		"attributes":  attributes,
+	}, nil
+}
@@ -0,0 +1,278 @@
+package authz
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/policy"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+rules:
+  - id: admins
+    effect: allow
+    actions: ["*"]
+    when:
+      - {attr: subject.roles, op: contains, value: admin}
+  - id: support-reads-users-in-region
+    effect: allow
+    actions: ["users:read"]
+    when:
+      - {attr: subject.permissions, op: contains, value: "users:read"}
+      - {attr: resource.region, op: eq, ref: subject.attributes.region}
+      - {attr: context.app, op: eq, value: test-app}
+  - id: no-exports
+    effect: deny
+    actions: ["reports:export"]
+    when:
+      - {attr: subject.groups, op: contains, value: contractors}
+`
+
+func TestAuthorize_Policy(t *testing.T) {
+	env := newTestEnv(t)
+
+	support := env.addUser(models.User{Status: models.UserStatusActive})
+	env.storage.permissions[support.ID] = []string{"users:read"}
+	env.storage.attributes[support.ID] = map[string]any{"region": "eu"}
+
+	contractor := env.addUser(models.User{Status: models.UserStatusActive})
+	env.storage.roles[contractor.ID] = []string{"admin"}
+	env.storage.groups[contractor.ID] = []string{"contractors"}
+
+	tests := []struct {
+		name     string
+		user     models.User
+		action   string
+		resource map[string]any
+		want     models.AuthzDecision
+	}{
+		{
+			name:     "allowed in region",
+			user:     support,
+			action:   "users:read",
+			resource: map[string]any{"region": "eu"},
+			want:     models.AuthzDecision{Allowed: true, Rule: "support-reads-users-in-region"},
+		},
+		{
+			name:     "other region",
+			user:     support,
+			action:   "users:read",
+			resource: map[string]any{"region": "us"},
+			want:     models.AuthzDecision{},
+		},
+		{
+			name:   "deny overrides admin",
+			user:   contractor,
+			action: "reports:export",
+			want:   models.AuthzDecision{Rule: "no-exports"},
+		},
+		{
+			name:   "admin",
+			user:   contractor,
+			action: "users:delete",
+			want:   models.AuthzDecision{Allowed: true, Rule: "admins"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := env.service.Authorize(context.Background(), tt.user.ID.String(), env.app.Name, tt.action, tt.resource, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, decision)
+		})
+	}
+}
+
+func TestAuthorize_AppByID(t *testing.T) {
+	env := newTestEnv(t)
+
+	support := env.addUser(models.User{Status: models.UserStatusActive})
+	env.storage.permissions[support.ID] = []string{"users:read"}
+	env.storage.attributes[support.ID] = map[string]any{"region": "eu"}
+
+	// Policies see the app name whichever way the caller identified the app.
+	decision, err := env.service.Authorize(context.Background(), support.ID.String(), env.app.ID.String(), "users:read", map[string]any{"region": "eu"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.AuthzDecision{Allowed: true, Rule: "support-reads-users-in-region"}, decision)
+}
+
+func TestAuthorize_InactiveUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		user   models.User
+		denied bool
+	}{
+		{name: "pending", user: models.User{Status: models.UserStatusPending}, denied: true},
+		{name: "deactivated", user: models.User{Status: models.UserStatusDeactivated}, denied: true},
+		{name: "banned", user: models.User{Status: models.UserStatusSuspended}, denied: true},
+		{
+			name:   "suspended",
+			user:   models.User{Status: models.UserStatusSuspended, SuspendedUntil: time.Now().Add(time.Hour)},
+			denied: true,
+		},
+		{
+			name: "suspension over",
+			user: models.User{Status: models.UserStatusSuspended, SuspendedUntil: time.Now().Add(-time.Hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+
+			// The admins rule would allow anything if it were evaluated.
+			user := env.addUser(tt.user)
+			env.storage.roles[user.ID] = []string{"admin"}
+
+			decision, err := env.service.Authorize(context.Background(), user.ID.String(), env.app.Name, "users:delete", nil, nil)
+			require.NoError(t, err)
+
+			if tt.denied {
+				assert.Equal(t, models.AuthzDecision{Rule: RuleUserInactive}, decision)
+				assert.Zero(t, env.storage.subjectCalls, "subject attributes are not loaded for inactive users")
+			} else {
+				assert.Equal(t, models.AuthzDecision{Allowed: true, Rule: "admins"}, decision)
+			}
+		})
+	}
+}
+
+func TestAuthorize_Errors(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.User{Status: models.UserStatusActive})
+
+	_, err := env.service.Authorize(ctx, user.ID.String(), env.app.Name, "", nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	_, err = env.service.Authorize(ctx, user.ID.String(), "missing", "users:read", nil, nil)
+	assert.ErrorIs(t, err, ErrAppNotFound)
+
+	_, err = env.service.Authorize(ctx, uuid.NewString(), env.app.Name, "users:read", nil, nil)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+type testEnv struct {
+	service *Authz
+	storage *fakeStorage
+	app     models.App
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	p, err := policy.Parse([]byte(testPolicy))
+	require.NoError(t, err)
+
+	app := models.App{ID: uuid.New(), Name: "test-app", Enabled: true}
+
+	fake := &fakeStorage{
+		users:       map[uuid.UUID]models.User{},
+		app:         app,
+		roles:       map[uuid.UUID][]string{},
+		permissions: map[uuid.UUID][]string{},
+		groups:      map[uuid.UUID][]string{},
+		attributes:  map[uuid.UUID]map[string]any{},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testEnv{
+		service: New(log, fake, fake, fake, p, time.UTC),
+		storage: fake,
+		app:     app,
+	}
+}
+
+func (e *testEnv) addUser(user models.User) models.User {
+	user.ID = uuid.New()
+	user.Email = user.ID.String() + "@example.com"
+	e.storage.users[user.ID] = user
+
+	return user
+}
+
+// fakeStorage keeps users and what policies know about them in memory.
+// Roles and groups are kept by name, since policies see only names.
+type fakeStorage struct {
+	users       map[uuid.UUID]models.User
+	app         models.App
+	roles       map[uuid.UUID][]string
+	permissions map[uuid.UUID][]string
+	groups      map[uuid.UUID][]string
+	attributes  map[uuid.UUID]map[string]any
+
+	// subjectCalls counts lookups of subject attributes besides the user record.
+	subjectCalls int
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	id, err := uuid.Parse(identifier.Value)
+	if err != nil {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	user, ok := f.users[id]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeStorage) LookupApp(_ context.Context, idOrName string) (models.App, error) {
+	if idOrName != f.app.ID.String() && idOrName != f.app.Name {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return f.app, nil
+}
+
+func (f *fakeStorage) UserRoles(_ context.Context, userID string, _ uuid.UUID) ([]models.Role, error) {
+	f.subjectCalls++
+
+	var roles []models.Role
+	for _, name := range f.roles[uuid.MustParse(userID)] {
+		roles = append(roles, models.Role{Name: name})
+	}
+
+	return roles, nil
+}
+
+func (f *fakeStorage) UserPermissions(_ context.Context, userID string, _ uuid.UUID) ([]string, error) {
+	f.subjectCalls++
+
+	return f.permissions[uuid.MustParse(userID)], nil
+}
+
+func (f *fakeStorage) UserGroups(_ context.Context, userID string) ([]models.Group, error) {
+	f.subjectCalls++
+
+	var groups []models.Group
+	for _, name := range f.groups[uuid.MustParse(userID)] {
+		groups = append(groups, models.Group{Name: name})
+	}
+
+	return groups, nil
+}
+
+func (f *fakeStorage) UserOrganizations(_ context.Context, _ string) ([]models.Organization, error) {
+	f.subjectCalls++
+
+	return nil, nil
+}
+
+func (f *fakeStorage) UserAttributes(_ context.Context, userID string) (map[string]any, error) {
+	f.subjectCalls++
+
+	return f.attributes[uuid.MustParse(userID)], nil
+}
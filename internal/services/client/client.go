@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/clientsecret"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// Client issues tokens to apps acting on their own behalf, as OAuth clients
+// whose client ID is the app ID.
+type Client struct {
+	log           *slog.Logger
+	appProvider   AppProvider
+	secretChecker SecretChecker
+	tokenTTL      time.Duration
+}
+
+type AppProvider interface {
+	AppByID(ctx context.Context, appID string) (models.App, error)
+}
+
+type SecretChecker interface {
+	UseClientSecret(ctx context.Context, appID uuid.UUID, hash string, now time.Time) error
+	HasClientSecret(ctx context.Context, appID uuid.UUID, now time.Time) (bool, error)
+}
+
+var (
+	// ErrInvalidClient hides whether the client, its secret or the app being enabled was the problem.
+	ErrInvalidClient = errors.New("invalid client")
+	ErrInvalidScope  = errors.New("invalid scope")
+)
+
+// New returns a new instance of the Client service.
+func New(log *slog.Logger, appProvider AppProvider, secretChecker SecretChecker, tokenTTL time.Duration) *Client {
+	return &Client{
+		log:           log,
+		appProvider:   appProvider,
+		secretChecker: secretChecker,
+		tokenTTL:      tokenTTL,
+	}
+}
+
+// ClientCredentials authenticates the client with its secret and issues an access token
+// with the requested scopes, which must be allowed for the app. Without requested scopes
+// the token gets all scopes allowed for the app.
+func (c *Client) ClientCredentials(ctx context.Context, clientID string, clientSecret string, scopes []string) (models.ClientToken, error) {
+	const op = "client.ClientCredentials"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	app, err := c.authenticate(ctx, log, clientID, clientSecret)
+	if err != nil {
+		return models.ClientToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(scopes) == 0 {
+		scopes = app.ClientScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(app.ClientScopes, scope) {
+			log.Warn("scope is not allowed", slog.String("scope", scope))
+
+			return models.ClientToken{}, fmt.Errorf("%s: %w", op, ErrInvalidScope)
+		}
+	}
+
+	token, expiresAt, err := jwt.NewClientToken(app, scopes, c.tokenTTL)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return models.ClientToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("client token issued", slog.Any("scopes", scopes))
+
+	return models.ClientToken{AccessToken: token, ExpiresAt: expiresAt, Scopes: scopes}, nil
+}
+
+// AuthenticateClient checks the secret of the client and returns its app.
+// Confidential clients use it to authenticate other grants, e.g. the authorization code exchange.
+func (c *Client) AuthenticateClient(ctx context.Context, clientID string, clientSecret string) (models.App, error) {
+	const op = "client.AuthenticateClient"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	app, err := c.authenticate(ctx, log, clientID, clientSecret)
+	if err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, nil
+}
+
+// CheckPublicClient returns ErrInvalidClient if the client has an unexpired secret, so it is
+// confidential and must authenticate instead of only sending its client ID. Grants check that
+// the client exists themselves.
+func (c *Client) CheckPublicClient(ctx context.Context, clientID string) error {
+	const op = "client.CheckPublicClient"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	appID, err := uuid.Parse(clientID)
+	if err != nil {
+		return nil
+	}
+
+	confidential, err := c.secretChecker.HasClientSecret(ctx, appID, time.Now())
+	if err != nil {
+		log.Error("failed to check client secrets", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if confidential {
+		log.Warn("confidential client sent no secret")
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidClient)
+	}
+
+	return nil
+}
+
+func (c *Client) authenticate(ctx context.Context, log *slog.Logger, clientID string, clientSecret string) (models.App, error) {
+	if !clientsecret.Looks(clientSecret) {
+		log.Warn("malformed client secret")
+
+		return models.App{}, ErrInvalidClient
+	}
+
+	app, err := c.appProvider.AppByID(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("client not found", sl.Err(err))
+
+			return models.App{}, ErrInvalidClient
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.App{}, err
+	}
+
+	if err := c.secretChecker.UseClientSecret(ctx, app.ID, randtoken.Hash(clientSecret), time.Now()); err != nil {
+		if errors.Is(err, storage.ErrClientSecretNotFound) {
+			log.Warn("invalid client secret", sl.Err(err))
+
+			return models.App{}, ErrInvalidClient
+		}
+
+		log.Error("failed to check client secret", sl.Err(err))
+
+		return models.App{}, err
+	}
+
+	if !app.Enabled {
+		log.Warn("app is disabled")
+
+		return models.App{}, ErrInvalidClient
+	}
+
+	return app, nil
+}
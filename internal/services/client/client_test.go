@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/clientsecret"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCredentials(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	token, err := env.service.ClientCredentials(ctx, env.app.ID.String(), env.secret, []string{"jobs:run"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, token.AccessToken)
+	assert.Equal(t, []string{"jobs:run"}, token.Scopes)
+	assert.True(t, env.storage.used, "use of the secret is recorded")
+
+	token, err = env.service.ClientCredentials(ctx, env.app.ID.String(), env.secret, nil)
+	require.NoError(t, err)
+	assert.Equal(t, env.app.ClientScopes, token.Scopes, "no requested scopes grants all allowed ones")
+}
+
+func TestClientCredentials_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		clientID func(env *testEnv) string
+		secret   func(env *testEnv) string
+		scopes   []string
+		disabled bool
+		want     error
+	}{
+		{name: "unknown client", clientID: func(*testEnv) string { return uuid.NewString() }, want: ErrInvalidClient},
+		{name: "malformed secret", secret: func(*testEnv) string { return "secret" }, want: ErrInvalidClient},
+		{name: "wrong secret", secret: func(*testEnv) string { s, _ := clientsecret.Generate(); return s }, want: ErrInvalidClient},
+		{name: "disabled app", disabled: true, want: ErrInvalidClient},
+		{name: "scope not allowed", scopes: []string{"users:delete"}, want: ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if tt.disabled {
+				env.app.Enabled = false
+				env.storage.apps[env.app.ID] = env.app
+			}
+
+			clientID, secret := env.app.ID.String(), env.secret
+			if tt.clientID != nil {
+				clientID = tt.clientID(env)
+			}
+			if tt.secret != nil {
+				secret = tt.secret(env)
+			}
+
+			_, err := env.service.ClientCredentials(context.Background(), clientID, secret, tt.scopes)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+type testEnv struct {
+	service *Client
+	storage *fakeStorage
+	app     models.App
+	secret  string
+}
+
+func TestCheckPublicClient(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	err := env.service.CheckPublicClient(ctx, env.app.ID.String())
+	assert.ErrorIs(t, err, ErrInvalidClient, "apps with secrets must authenticate")
+
+	delete(env.storage.hashes, env.app.ID)
+	assert.NoError(t, env.service.CheckPublicClient(ctx, env.app.ID.String()))
+
+	// Unknown clients are left for the grant to reject.
+	assert.NoError(t, env.service.CheckPublicClient(ctx, "not-a-uuid"))
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	secret, err := clientsecret.Generate()
+	require.NoError(t, err)
+
+	app := models.App{ID: uuid.New(), Name: "billing", Enabled: true, ClientScopes: []string{"jobs:run", "jobs:read"}}
+	fake := &fakeStorage{
+		apps:   map[uuid.UUID]models.App{app.ID: app},
+		hashes: map[uuid.UUID]string{app.ID: randtoken.Hash(secret)},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testEnv{
+		service: New(log, fake, fake, time.Hour),
+		storage: fake,
+		app:     app,
+		secret:  secret,
+	}
+}
+
+type fakeStorage struct {
+	apps   map[uuid.UUID]models.App
+	hashes map[uuid.UUID]string
+	used   bool
+}
+
+func (f *fakeStorage) AppByID(_ context.Context, appID string) (models.App, error) {
+	id, err := uuid.Parse(appID)
+	if err != nil {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	app, ok := f.apps[id]
+	if !ok {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return app, nil
+}
+
+func (f *fakeStorage) HasClientSecret(_ context.Context, appID uuid.UUID, _ time.Time) (bool, error) {
+	_, ok := f.hashes[appID]
+
+	return ok, nil
+}
+
+func (f *fakeStorage) UseClientSecret(_ context.Context, appID uuid.UUID, hash string, _ time.Time) error {
+	if f.hashes[appID] != hash {
+		return storage.ErrClientSecretNotFound
+	}
+	f.used = true
+
+	return nil
+}
@@ -0,0 +1,560 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/lib/usercode"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/services/mfa"
+	"github.com/sol1corejz/auth-service/internal/services/oauth"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// userCodeAttempts bounds retries of user codes colliding with pending ones.
+const userCodeAttempts = 3
+
+// Device implements the OAuth 2.0 device authorization grant (RFC 8628) for input-constrained
+// devices such as CLI tools on headless servers. The client ID of an app is its ID.
+type Device struct {
+	log             *slog.Logger
+	authenticator   Authenticator
+	mfaVerifier     MFAVerifier
+	userProvider    UserProvider
+	appProvider     AppProvider
+	codeStorage     CodeStorage
+	loginRecorder   LoginRecorder
+	codeTTL         time.Duration
+	interval        time.Duration
+	tokenTTL        time.Duration
+	refreshTokenTTL time.Duration
+}
+
+// Authenticator checks credentials the user enters on the verification page.
+type Authenticator interface {
+	Authenticate(ctx context.Context, login string, password string, appIDOrName string) (models.User, models.App, models.Authentication, error)
+}
+
+// MFAVerifier completes the second step of logins started on the verification page.
+type MFAVerifier interface {
+	SendChallengeEmailCode(ctx context.Context, challengeID string) error
+	VerifyChallenge(ctx context.Context, challengeID string, code string) (models.User, models.App, models.Authentication, error)
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+}
+
+type AppProvider interface {
+	AppByID(ctx context.Context, appID string) (models.App, error)
+}
+
+type CodeStorage interface {
+	SaveDeviceCode(ctx context.Context, code models.DeviceCode, hash string) error
+	PendingDeviceCode(ctx context.Context, userCode string, now time.Time) (models.DeviceCode, error)
+	ResolveDeviceCode(
+		ctx context.Context,
+		userCode string,
+		userID uuid.UUID,
+		auth models.Authentication,
+		status models.DeviceCodeStatus,
+		now time.Time,
+	) error
+	PollDeviceCode(ctx context.Context, hash string, now time.Time) (models.DeviceCode, error)
+}
+
+type LoginRecorder interface {
+	RecordLogin(ctx context.Context, userID string, appID string) error
+}
+
+var (
+	ErrInvalidClient      = errors.New("invalid client")
+	ErrInvalidScope       = errors.New("invalid scope")
+	ErrInvalidUserCode    = errors.New("invalid user code")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidCode        = errors.New("invalid code")
+	ErrInvalidChallenge   = errors.New("invalid or expired challenge")
+	ErrEnrollmentRequired = errors.New("second factor enrollment required")
+
+	// Errors of polling, see RFC 8628, section 3.5.
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("slow down")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrExpiredToken         = errors.New("device code expired")
+	ErrInvalidGrant         = errors.New("invalid grant")
+)
+
+// MFARequiredError is returned by Resolve when the password is correct, but the user has
+// to complete the challenge with ResolveMFA to resolve the code.
+type MFARequiredError struct {
+	ChallengeID string
+	// EmailSent is set if a code was emailed to the user, who has no authenticator app enrolled.
+	EmailSent bool
+}
+
+func (e *MFARequiredError) Error() string {
+	return "mfa required"
+}
+
+// New returns a new instance of the Device service. Device codes live for codeTTL, and devices
+// must wait interval between polls.
+func New(
+	log *slog.Logger,
+	authenticator Authenticator,
+	mfaVerifier MFAVerifier,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	codeStorage CodeStorage,
+	loginRecorder LoginRecorder,
+	codeTTL time.Duration,
+	interval time.Duration,
+	tokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+) *Device {
+	return &Device{
+		log:             log,
+		authenticator:   authenticator,
+		mfaVerifier:     mfaVerifier,
+		userProvider:    userProvider,
+		appProvider:     appProvider,
+		codeStorage:     codeStorage,
+		loginRecorder:   loginRecorder,
+		codeTTL:         codeTTL,
+		interval:        interval,
+		tokenTTL:        tokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+// Authorize starts a device authorization of the client and returns the device code the device
+// polls with and the user code the user approves it with. Scopes are those of the authorization
+// code flow.
+func (d *Device) Authorize(ctx context.Context, clientID string, scopes []string) (models.DeviceAuthorization, error) {
+	const op = "device.Authorize"
+
+	log := d.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	app, err := d.client(ctx, log, clientID)
+	if err != nil {
+		return models.DeviceAuthorization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := validateScopes(scopes); err != nil {
+		log.Warn("unsupported scopes requested", sl.Err(err))
+
+		return models.DeviceAuthorization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deviceCode, err := randtoken.Generate("")
+	if err != nil {
+		log.Error("failed to generate device code", sl.Err(err))
+
+		return models.DeviceAuthorization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		userCode, err := usercode.Generate()
+		if err != nil {
+			log.Error("failed to generate user code", sl.Err(err))
+
+			return models.DeviceAuthorization{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		err = d.codeStorage.SaveDeviceCode(ctx, models.DeviceCode{
+			AppID:     app.ID,
+			UserCode:  userCode,
+			Scopes:    scopes,
+			ExpiresAt: time.Now().Add(d.codeTTL),
+		}, randtoken.Hash(deviceCode))
+		if errors.Is(err, storage.ErrUserCodeTaken) && attempt < userCodeAttempts {
+			continue
+		}
+		if err != nil {
+			log.Error("failed to save device code", sl.Err(err))
+
+			return models.DeviceAuthorization{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Info("device authorization started")
+
+		return models.DeviceAuthorization{
+			DeviceCode: deviceCode,
+			UserCode:   usercode.Format(userCode),
+			ExpiresIn:  d.codeTTL,
+			Interval:   d.interval,
+		}, nil
+	}
+}
+
+// Pending returns the app a pending device authorization with the user code was started for,
+// so the user can see what they are about to approve.
+func (d *Device) Pending(ctx context.Context, userCode string) (models.App, error) {
+	const op = "device.Pending"
+
+	log := d.log.With(
+		slog.String("op", op),
+	)
+
+	code, err := d.codeStorage.PendingDeviceCode(ctx, usercode.Normalize(userCode), time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceCodeNotFound) {
+			return models.App{}, fmt.Errorf("%s: %w", op, ErrInvalidUserCode)
+		}
+
+		log.Error("failed to get device code", sl.Err(err))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := d.appProvider.AppByID(ctx, code.AppID.String())
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.App{}, fmt.Errorf("%s: %w", op, ErrInvalidUserCode)
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, nil
+}
+
+// Resolve logs the user in with the credentials entered on the verification page and approves
+// or denies the pending device authorization with the user code on their behalf.
+//
+// Accounts that need a second factor get *MFARequiredError, whose challenge ResolveMFA
+// completes. The page can't enroll a second factor, so users who have yet to enroll
+// get ErrEnrollmentRequired.
+func (d *Device) Resolve(ctx context.Context, userCode string, login string, password string, approve bool) error {
+	const op = "device.Resolve"
+
+	log := d.log.With(
+		slog.String("op", op),
+		slog.Bool("approve", approve),
+	)
+
+	userCode = usercode.Normalize(userCode)
+
+	code, err := d.pending(ctx, log, userCode)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("client_id", code.AppID.String()))
+
+	user, _, authentication, err := d.authenticator.Authenticate(ctx, login, password, code.AppID.String())
+	if err != nil {
+		var mfaErr *auth.MFARequiredError
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		case errors.As(err, &mfaErr):
+			return fmt.Errorf("%s: %w", op, d.mfaRequired(ctx, log, mfaErr))
+		case errors.Is(err, auth.ErrUserInactive), errors.Is(err, auth.ErrAppDisabled), errors.Is(err, auth.ErrAppAccessDenied):
+			return fmt.Errorf("%s: %w", op, ErrAccessDenied)
+		}
+
+		log.Error("failed to authenticate user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := d.resolve(ctx, log, userCode, user, authentication, approve); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ResolveMFA completes the challenge Resolve returned with a TOTP, emailed or recovery code
+// and then approves or denies the device authorization like Resolve. ErrInvalidCode leaves the
+// challenge open for another attempt; after ErrInvalidChallenge the user has to log in again.
+func (d *Device) ResolveMFA(ctx context.Context, userCode string, challengeID string, mfaCode string, approve bool) error {
+	const op = "device.ResolveMFA"
+
+	log := d.log.With(
+		slog.String("op", op),
+		slog.String("challenge_id", challengeID),
+		slog.Bool("approve", approve),
+	)
+
+	userCode = usercode.Normalize(userCode)
+
+	code, err := d.pending(ctx, log, userCode)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("client_id", code.AppID.String()))
+
+	user, app, authentication, err := d.mfaVerifier.VerifyChallenge(ctx, challengeID, mfaCode)
+	if err != nil {
+		switch {
+		// A locked TOTP leaves the challenge open for an emailed or recovery code.
+		case errors.Is(err, mfa.ErrInvalidCode), errors.Is(err, mfa.ErrTooManyAttempts):
+			return fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		case errors.Is(err, mfa.ErrInvalidChallenge):
+			return fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+		case errors.Is(err, mfa.ErrEnrollmentRequired):
+			return fmt.Errorf("%s: %w", op, ErrEnrollmentRequired)
+		case errors.Is(err, mfa.ErrUserInactive):
+			return fmt.Errorf("%s: %w", op, ErrAccessDenied)
+		}
+
+		log.Error("failed to verify mfa challenge", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The challenge is consumed either way, so one issued for another app can't be replayed.
+	if app.ID != code.AppID {
+		log.Warn("challenge was issued for another app", slog.String("app_id", app.ID.String()))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+	}
+
+	if err := d.resolve(ctx, log, userCode, user, authentication, approve); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// pending returns the pending device code with the normalized user code.
+func (d *Device) pending(ctx context.Context, log *slog.Logger, userCode string) (models.DeviceCode, error) {
+	code, err := d.codeStorage.PendingDeviceCode(ctx, userCode, time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceCodeNotFound) {
+			log.Warn("user code is unknown, expired or resolved")
+
+			return models.DeviceCode{}, ErrInvalidUserCode
+		}
+
+		log.Error("failed to get device code", sl.Err(err))
+
+		return models.DeviceCode{}, err
+	}
+
+	return code, nil
+}
+
+// resolve approves or denies the device code on behalf of the logged in user.
+func (d *Device) resolve(
+	ctx context.Context,
+	log *slog.Logger,
+	userCode string,
+	user models.User,
+	authentication models.Authentication,
+	approve bool,
+) error {
+	log = log.With(slog.String("user_id", user.ID.String()))
+
+	status := models.DeviceCodeDenied
+	if approve {
+		status = models.DeviceCodeApproved
+	}
+
+	if err := d.codeStorage.ResolveDeviceCode(ctx, userCode, user.ID, authentication, status, time.Now()); err != nil {
+		if errors.Is(err, storage.ErrDeviceCodeNotFound) {
+			log.Warn("device code was resolved concurrently or expired")
+
+			return ErrInvalidUserCode
+		}
+
+		log.Error("failed to resolve device code", sl.Err(err))
+
+		return err
+	}
+
+	log.Info("device authorization resolved")
+
+	return nil
+}
+
+// mfaRequired turns the challenge of the login into the error Resolve returns, emailing
+// a code to users who have no authenticator app to take it from.
+func (d *Device) mfaRequired(ctx context.Context, log *slog.Logger, mfaErr *auth.MFARequiredError) error {
+	if mfaErr.EnrollmentRequired {
+		log.Warn("user has to enroll a second factor first")
+
+		return ErrEnrollmentRequired
+	}
+
+	required := &MFARequiredError{ChallengeID: mfaErr.ChallengeID}
+	if slices.Contains(mfaErr.Methods, models.MFAMethodEmail) && !slices.Contains(mfaErr.Methods, models.MFAMethodTOTP) {
+		// Recovery codes still work if the email doesn't arrive.
+		if err := d.mfaVerifier.SendChallengeEmailCode(ctx, mfaErr.ChallengeID); err != nil {
+			log.Error("failed to email mfa code", sl.Err(err))
+		} else {
+			required.EmailSent = true
+		}
+	}
+
+	log.Info("second factor required", slog.Bool("email_sent", required.EmailSent))
+
+	return required
+}
+
+// Poll exchanges the device code for a token pair of the user who approved it. Until then it
+// returns ErrAuthorizationPending, or ErrSlowDown if the device polls more often than the interval.
+func (d *Device) Poll(ctx context.Context, clientID string, deviceCode string) (models.OAuthTokens, error) {
+	const op = "device.Poll"
+
+	log := d.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	now := time.Now()
+
+	code, err := d.codeStorage.PollDeviceCode(ctx, randtoken.Hash(deviceCode), now)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceCodeNotFound) {
+			log.Warn("device code is unknown")
+
+			return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+
+		log.Error("failed to poll device code", sl.Err(err))
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if code.AppID.String() != clientID {
+		log.Warn("device code was issued to another client")
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	switch code.Status {
+	case models.DeviceCodeConsumed:
+		log.Warn("device code was already exchanged")
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	case models.DeviceCodeDenied:
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	case models.DeviceCodeApproved:
+		// Approved before it expired: the device may pick the tokens up a bit later.
+	default:
+		if !now.Before(code.ExpiresAt) {
+			return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrExpiredToken)
+		}
+		if !code.LastPolledAt.IsZero() && now.Sub(code.LastPolledAt) < d.interval {
+			return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrSlowDown)
+		}
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrAuthorizationPending)
+	}
+	log = log.With(slog.String("user_id", code.UserID.String()))
+
+	tokens, err := d.issueTokens(ctx, log, code)
+	if err != nil {
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("device code exchanged")
+
+	return tokens, nil
+}
+
+func (d *Device) issueTokens(ctx context.Context, log *slog.Logger, code models.DeviceCode) (models.OAuthTokens, error) {
+	app, err := d.appProvider.AppByID(ctx, code.AppID.String())
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.OAuthTokens{}, ErrInvalidGrant
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.OAuthTokens{}, err
+	}
+
+	user, err := d.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: code.UserID.String()})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.OAuthTokens{}, ErrInvalidGrant
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.OAuthTokens{}, err
+	}
+
+	// The user or the app could have been blocked since the code was approved.
+	if !app.Enabled || !user.IsActive(time.Now()) {
+		log.Warn("app is disabled or user is not active")
+
+		return models.OAuthTokens{}, ErrAccessDenied
+	}
+
+	authentication := code.Auth
+	authentication.Scopes = code.Scopes
+
+	accessToken, refreshToken, err := jwt.NewTokenPair(user, app, authentication, d.tokenTTL, d.refreshTokenTTL)
+	if err != nil {
+		log.Error("failed to generate tokens", sl.Err(err))
+
+		return models.OAuthTokens{}, err
+	}
+
+	// Login stats are best effort and must not block the user.
+	if err := d.loginRecorder.RecordLogin(ctx, user.ID.String(), app.ID.String()); err != nil {
+		log.Error("failed to record login", sl.Err(err))
+	}
+
+	return models.OAuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scopes:       code.Scopes,
+		ExpiresIn:    d.tokenTTL,
+	}, nil
+}
+
+// client returns the enabled app with the client ID.
+func (d *Device) client(ctx context.Context, log *slog.Logger, clientID string) (models.App, error) {
+	if uuid.Validate(clientID) != nil {
+		return models.App{}, ErrInvalidClient
+	}
+
+	app, err := d.appProvider.AppByID(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("client not found", sl.Err(err))
+
+			return models.App{}, ErrInvalidClient
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.App{}, err
+	}
+
+	if !app.Enabled {
+		log.Warn("app is disabled")
+
+		return models.App{}, ErrInvalidClient
+	}
+
+	return app, nil
+}
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !slices.Contains(oauth.SupportedScopes, scope) {
+			return fmt.Errorf("%w: %q is not supported", ErrInvalidScope, scope)
+		}
+	}
+
+	return nil
+}
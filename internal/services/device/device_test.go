@@ -0,0 +1,467 @@
+package device
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/services/mfa"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testInterval = 5 * time.Second
+
+func TestAuthorize(t *testing.T) {
+	env := newTestEnv(t)
+
+	authorization, err := env.service.Authorize(context.Background(), env.app.ID.String(), []string{"openid"})
+	require.NoError(t, err)
+	assert.Regexp(t, `^[A-Z]{4}-[A-Z]{4}$`, authorization.UserCode)
+	assert.Equal(t, time.Minute, authorization.ExpiresIn)
+	assert.Equal(t, testInterval, authorization.Interval)
+
+	code, ok := env.codes.codes[randtoken.Hash(authorization.DeviceCode)]
+	require.True(t, ok, "device codes are stored hashed")
+	assert.Equal(t, env.app.ID, code.AppID)
+	assert.Equal(t, []string{"openid"}, code.Scopes)
+}
+
+func TestAuthorize_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		clientID func(env *testEnv) string
+		scopes   []string
+		disabled bool
+		want     error
+	}{
+		{name: "malformed client", clientID: func(*testEnv) string { return "reports" }, want: ErrInvalidClient},
+		{name: "unknown client", clientID: func(*testEnv) string { return uuid.NewString() }, want: ErrInvalidClient},
+		{name: "disabled app", disabled: true, want: ErrInvalidClient},
+		{name: "unsupported scope", scopes: []string{"openid", "admin"}, want: ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.apps.app.Enabled = !tt.disabled
+
+			clientID := env.app.ID.String()
+			if tt.clientID != nil {
+				clientID = tt.clientID(env)
+			}
+
+			_, err := env.service.Authorize(context.Background(), clientID, tt.scopes)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, env.codes.codes)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		approve bool
+		want    models.DeviceCodeStatus
+	}{
+		{name: "approve", approve: true, want: models.DeviceCodeApproved},
+		{name: "deny", want: models.DeviceCodeDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			authorization := env.start(t)
+
+			// Users may type the code in lower case and without the dash.
+			err := env.service.Resolve(context.Background(), "  "+authorization.UserCode[:4]+authorization.UserCode[5:], "alice", "password", tt.approve)
+			require.NoError(t, err)
+
+			code := env.codes.codes[randtoken.Hash(authorization.DeviceCode)]
+			assert.Equal(t, tt.want, code.Status)
+			assert.Equal(t, env.user.ID, code.UserID)
+
+			err = env.service.Resolve(context.Background(), authorization.UserCode, "alice", "password", tt.approve)
+			assert.ErrorIs(t, err, ErrInvalidUserCode, "user codes are resolved once")
+		})
+	}
+}
+
+func TestResolve_Rejected(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "wrong password", err: auth.ErrInvalidCredentials, want: ErrInvalidCredentials},
+		{name: "blocked user", err: auth.ErrUserInactive, want: ErrAccessDenied},
+		{name: "disabled app", err: auth.ErrAppDisabled, want: ErrAccessDenied},
+		{name: "no access to app", err: auth.ErrAppAccessDenied, want: ErrAccessDenied},
+		{name: "not enrolled", err: &auth.MFARequiredError{ChallengeID: "challenge", EnrollmentRequired: true}, want: ErrEnrollmentRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			authorization := env.start(t)
+			env.authenticator.err = tt.err
+
+			err := env.service.Resolve(context.Background(), authorization.UserCode, "alice", "password", true)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Equal(t, models.DeviceCodePending, env.codes.codes[randtoken.Hash(authorization.DeviceCode)].Status)
+		})
+	}
+}
+
+func TestResolve_UnknownUserCode(t *testing.T) {
+	env := newTestEnv(t)
+
+	err := env.service.Resolve(context.Background(), "BCDF-GHJK", "alice", "password", true)
+	assert.ErrorIs(t, err, ErrInvalidUserCode)
+}
+
+func TestResolve_MFA(t *testing.T) {
+	tests := []struct {
+		name      string
+		methods   []models.MFAMethod
+		wantEmail bool
+	}{
+		{name: "totp", methods: []models.MFAMethod{models.MFAMethodTOTP}},
+		{name: "totp and email", methods: []models.MFAMethod{models.MFAMethodTOTP, models.MFAMethodEmail}},
+		{name: "email only", methods: []models.MFAMethod{models.MFAMethodEmail}, wantEmail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			authorization := env.start(t)
+			env.authenticator.err = &auth.MFARequiredError{ChallengeID: "challenge", Methods: tt.methods}
+
+			err := env.service.Resolve(context.Background(), authorization.UserCode, "alice", "password", true)
+
+			var mfaErr *MFARequiredError
+			require.ErrorAs(t, err, &mfaErr)
+			assert.Equal(t, "challenge", mfaErr.ChallengeID)
+			assert.Equal(t, tt.wantEmail, mfaErr.EmailSent)
+			assert.Equal(t, models.DeviceCodePending, env.codes.codes[randtoken.Hash(authorization.DeviceCode)].Status)
+
+			require.NoError(t, env.service.ResolveMFA(context.Background(), authorization.UserCode, "challenge", "123456", true))
+
+			code := env.codes.codes[randtoken.Hash(authorization.DeviceCode)]
+			assert.Equal(t, models.DeviceCodeApproved, code.Status)
+			assert.Contains(t, code.Auth.Methods, models.AuthMethodOTP)
+		})
+	}
+}
+
+func TestResolveMFA_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		otherApp bool
+		want     error
+	}{
+		{name: "wrong code", err: mfa.ErrInvalidCode, want: ErrInvalidCode},
+		{name: "expired challenge", err: mfa.ErrInvalidChallenge, want: ErrInvalidChallenge},
+		{name: "not enrolled", err: mfa.ErrEnrollmentRequired, want: ErrEnrollmentRequired},
+		{name: "user blocked since", err: mfa.ErrUserInactive, want: ErrAccessDenied},
+		{name: "challenge of another app", otherApp: true, want: ErrInvalidChallenge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			authorization := env.start(t)
+			env.mfa.err = tt.err
+			if tt.otherApp {
+				env.mfa.app = models.App{ID: uuid.New(), Enabled: true}
+			}
+
+			err := env.service.ResolveMFA(context.Background(), authorization.UserCode, "challenge", "123456", true)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Equal(t, models.DeviceCodePending, env.codes.codes[randtoken.Hash(authorization.DeviceCode)].Status)
+		})
+	}
+}
+
+func TestPoll(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	authorization := env.start(t)
+	clientID := env.app.ID.String()
+
+	_, err := env.service.Poll(ctx, clientID, authorization.DeviceCode)
+	assert.ErrorIs(t, err, ErrAuthorizationPending)
+
+	_, err = env.service.Poll(ctx, clientID, authorization.DeviceCode)
+	assert.ErrorIs(t, err, ErrSlowDown)
+
+	require.NoError(t, env.service.Resolve(ctx, authorization.UserCode, "alice", "password", true))
+
+	// Approved codes are picked up without waiting for the interval.
+	tokens, err := env.service.Poll(ctx, clientID, authorization.DeviceCode)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, []string{"openid"}, tokens.Scopes)
+	assert.Equal(t, []string{env.user.ID.String()}, env.logins.users)
+
+	_, err = env.service.Poll(ctx, clientID, authorization.DeviceCode)
+	assert.ErrorIs(t, err, ErrInvalidGrant, "device codes are exchanged once")
+}
+
+func TestPoll_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, env *testEnv, authorization models.DeviceAuthorization)
+		client  string
+		want    error
+	}{
+		{
+			name: "denied",
+			prepare: func(t *testing.T, env *testEnv, authorization models.DeviceAuthorization) {
+				require.NoError(t, env.service.Resolve(context.Background(), authorization.UserCode, "alice", "password", false))
+			},
+			want: ErrAccessDenied,
+		},
+		{
+			name: "expired",
+			prepare: func(_ *testing.T, env *testEnv, authorization models.DeviceAuthorization) {
+				env.codes.codes[randtoken.Hash(authorization.DeviceCode)].ExpiresAt = time.Now().Add(-time.Second)
+			},
+			want: ErrExpiredToken,
+		},
+		{
+			name:   "another client",
+			client: uuid.NewString(),
+			want:   ErrInvalidGrant,
+		},
+		{
+			name: "user deactivated after approval",
+			prepare: func(t *testing.T, env *testEnv, authorization models.DeviceAuthorization) {
+				require.NoError(t, env.service.Resolve(context.Background(), authorization.UserCode, "alice", "password", true))
+				env.users.user.Status = models.UserStatusDeactivated
+			},
+			want: ErrAccessDenied,
+		},
+		{
+			name: "app disabled after approval",
+			prepare: func(t *testing.T, env *testEnv, authorization models.DeviceAuthorization) {
+				require.NoError(t, env.service.Resolve(context.Background(), authorization.UserCode, "alice", "password", true))
+				env.apps.app.Enabled = false
+			},
+			want: ErrAccessDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			authorization := env.start(t)
+			if tt.prepare != nil {
+				tt.prepare(t, env, authorization)
+			}
+
+			clientID := env.app.ID.String()
+			if tt.client != "" {
+				clientID = tt.client
+			}
+
+			_, err := env.service.Poll(context.Background(), clientID, authorization.DeviceCode)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, env.logins.users)
+		})
+	}
+}
+
+func TestPoll_UnknownCode(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.service.Poll(context.Background(), env.app.ID.String(), "forged")
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+}
+
+type testEnv struct {
+	service       *Device
+	authenticator *fakeAuthenticator
+	mfa           *fakeMFA
+	users         *fakeUsers
+	apps          *fakeApps
+	codes         *fakeCodes
+	logins        *fakeLogins
+	user          models.User
+	app           models.App
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com", Status: models.UserStatusActive}
+	app := models.App{ID: uuid.New(), Name: "reports", Enabled: true}
+
+	env := &testEnv{
+		authenticator: &fakeAuthenticator{user: user, app: app},
+		mfa:           &fakeMFA{user: user, app: app},
+		users:         &fakeUsers{user: user},
+		apps:          &fakeApps{app: app},
+		codes:         &fakeCodes{codes: map[string]*models.DeviceCode{}},
+		logins:        &fakeLogins{},
+		user:          user,
+		app:           app,
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	env.service = New(log, env.authenticator, env.mfa, env.users, env.apps, env.codes, env.logins, time.Minute, testInterval, time.Hour, 24*time.Hour)
+
+	return env
+}
+
+// start starts a device authorization of the test app with the openid scope.
+func (env *testEnv) start(t *testing.T) models.DeviceAuthorization {
+	t.Helper()
+
+	authorization, err := env.service.Authorize(context.Background(), env.app.ID.String(), []string{"openid"})
+	require.NoError(t, err)
+
+	return authorization
+}
+
+type fakeAuthenticator struct {
+	user models.User
+	app  models.App
+	err  error
+}
+
+func (f *fakeAuthenticator) Authenticate(context.Context, string, string, string) (models.User, models.App, models.Authentication, error) {
+	if f.err != nil {
+		return models.User{}, models.App{}, models.Authentication{}, f.err
+	}
+
+	return f.user, f.app, models.NewAuthentication(models.AuthMethodPassword), nil
+}
+
+type fakeMFA struct {
+	user models.User
+	app  models.App
+	err  error
+}
+
+func (f *fakeMFA) SendChallengeEmailCode(context.Context, string) error {
+	return nil
+}
+
+func (f *fakeMFA) VerifyChallenge(context.Context, string, string) (models.User, models.App, models.Authentication, error) {
+	if f.err != nil {
+		return models.User{}, models.App{}, models.Authentication{}, f.err
+	}
+
+	return f.user, f.app, models.NewAuthentication(models.AuthMethodPassword, models.AuthMethodOTP), nil
+}
+
+type fakeUsers struct {
+	user models.User
+}
+
+func (f *fakeUsers) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	if identifier.Value != f.user.ID.String() {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return f.user, nil
+}
+
+type fakeApps struct {
+	app models.App
+}
+
+func (f *fakeApps) AppByID(_ context.Context, appID string) (models.App, error) {
+	if appID != f.app.ID.String() {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return f.app, nil
+}
+
+// fakeCodes keeps device codes like the postgres storage: a user code can be resolved once
+// while pending and unexpired, and an approved code is consumed by the first poll.
+type fakeCodes struct {
+	codes map[string]*models.DeviceCode
+}
+
+func (f *fakeCodes) SaveDeviceCode(_ context.Context, code models.DeviceCode, hash string) error {
+	code.Status = models.DeviceCodePending
+	f.codes[hash] = &code
+
+	return nil
+}
+
+func (f *fakeCodes) PendingDeviceCode(_ context.Context, userCode string, now time.Time) (models.DeviceCode, error) {
+	code := f.pending(userCode, now)
+	if code == nil {
+		return models.DeviceCode{}, storage.ErrDeviceCodeNotFound
+	}
+
+	return *code, nil
+}
+
+func (f *fakeCodes) ResolveDeviceCode(
+	_ context.Context,
+	userCode string,
+	userID uuid.UUID,
+	auth models.Authentication,
+	status models.DeviceCodeStatus,
+	now time.Time,
+) error {
+	code := f.pending(userCode, now)
+	if code == nil {
+		return storage.ErrDeviceCodeNotFound
+	}
+	code.Status, code.UserID, code.Auth = status, userID, auth
+
+	return nil
+}
+
+func (f *fakeCodes) PollDeviceCode(_ context.Context, hash string, now time.Time) (models.DeviceCode, error) {
+	code, ok := f.codes[hash]
+	if !ok {
+		return models.DeviceCode{}, storage.ErrDeviceCodeNotFound
+	}
+
+	old := *code
+	code.LastPolledAt = now
+	if code.Status == models.DeviceCodeApproved {
+		code.Status = models.DeviceCodeConsumed
+	}
+
+	return old, nil
+}
+
+func (f *fakeCodes) pending(userCode string, now time.Time) *models.DeviceCode {
+	for _, code := range f.codes {
+		if code.UserCode == userCode && code.Status == models.DeviceCodePending && now.Before(code.ExpiresAt) {
+			return code
+		}
+	}
+
+	return nil
+}
+
+type fakeLogins struct {
+	users []string
+}
+
+func (f *fakeLogins) RecordLogin(_ context.Context, userID string, _ string) error {
+	f.users = append(f.users, userID)
+
+	return nil
+}
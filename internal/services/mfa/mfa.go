@@ -0,0 +1,1131 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/mailer"
+	"github.com/sol1corejz/auth-service/internal/lib/otp"
+	"github.com/sol1corejz/auth-service/internal/lib/recoverycode"
+	"github.com/sol1corejz/auth-service/internal/lib/secretbox"
+	"github.com/sol1corejz/auth-service/internal/lib/totp"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"time"
+)
+
+// totpSkew is how many 30-second steps of clock drift are tolerated each way.
+const totpSkew = 1
+
+const eventRecoveryCodeUsed = "mfa.recovery_code_used"
+
+const emailCodeDigits = 6
+
+type MFA struct {
+	log              *slog.Logger
+	userProvider     UserProvider
+	appProvider      AppProvider
+	totpStorage      TOTPStorage
+	challengeStorage ChallengeStorage
+	recoveryStorage  RecoveryCodeStorage
+	emailStorage     EmailStorage
+	auditLogger      AuditLogger
+	mailer           mailer.Mailer
+	box              *secretbox.Box
+	settings         Settings
+	tokenTTL         time.Duration
+	refreshTokenTTL  time.Duration
+}
+
+type Settings struct {
+	// Issuer is shown next to the account in authenticator apps.
+	Issuer       string
+	ChallengeTTL time.Duration
+	// MaxAttempts limits codes checked per challenge and per mailed code, and TOTP codes
+	// checked per user in a row without one being accepted.
+	MaxAttempts int
+	// TOTPLockout is how long TOTP codes of a user are refused after MaxAttempts failed.
+	TOTPLockout time.Duration
+
+	EmailCodeTTL         time.Duration
+	EmailResendInterval  time.Duration
+	EmailMaxCodesPerHour int
+
+	// StepUpTTL is the lifetime of access tokens issued by StepUp.
+	StepUpTTL time.Duration
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+	RecordLogin(ctx context.Context, userID string, appID string) error
+	IsAdmin(ctx context.Context, userID string, appID uuid.UUID) (bool, error)
+}
+
+type AppProvider interface {
+	AppByID(ctx context.Context, appID string) (models.App, error)
+}
+
+type TOTPStorage interface {
+	SaveTOTP(ctx context.Context, userID string, secretEncrypted []byte) error
+	TOTP(ctx context.Context, userID string) (models.TOTP, error)
+	UseTOTPAttempt(ctx context.Context, userID string, maxAttempts int, lockout time.Duration) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	DeleteTOTP(ctx context.Context, userID string) error
+}
+
+type ChallengeStorage interface {
+	SaveMFAChallenge(ctx context.Context, challenge models.MFAChallenge) (string, error)
+	MFAChallenge(ctx context.Context, challengeID string) (models.MFAChallenge, error)
+	UseMFAChallengeAttempt(ctx context.Context, challengeID string, maxAttempts int) error
+	ConsumeMFAChallenge(ctx context.Context, challengeID string) error
+}
+
+type RecoveryCodeStorage interface {
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID string, codeHash string) (left int, err error)
+}
+
+type EmailStorage interface {
+	EmailMFAEnabled(ctx context.Context, userID string) (bool, error)
+	EnableEmailMFA(ctx context.Context, userID string) error
+	DisableEmailMFA(ctx context.Context, userID string) error
+	SaveEmailCode(ctx context.Context, code models.EmailCode) (string, error)
+	EmailCodeStats(ctx context.Context, userID string, since time.Time) (count int, lastSentAt time.Time, err error)
+	ActiveEmailCode(ctx context.Context, userID string, challengeID uuid.UUID) (models.EmailCode, error)
+	UseEmailCodeAttempt(ctx context.Context, codeID string, maxAttempts int) error
+	ConsumeEmailCode(ctx context.Context, codeID string) error
+}
+
+type AuditLogger interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+}
+
+var (
+	ErrAccessDenied      = errors.New("access denied")
+	ErrAlreadyEnrolled   = errors.New("mfa already enrolled")
+	ErrNotEnrolled       = errors.New("mfa not enrolled")
+	ErrInvalidCode       = errors.New("invalid code")
+	ErrInvalidChallenge  = errors.New("invalid or expired challenge")
+	ErrEnrollmentBlocked = errors.New("challenge does not allow enrollment")
+	// ErrEnrollmentRequired is returned by VerifyChallenge for users who have yet to enroll.
+	ErrEnrollmentRequired = errors.New("mfa enrollment required")
+	ErrUserInactive       = errors.New("user is not active")
+	ErrRateLimited        = errors.New("too many codes requested")
+	ErrTooManyAttempts    = errors.New("too many invalid codes")
+)
+
+// New returns a new instance of the MFA service.
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	totpStorage TOTPStorage,
+	challengeStorage ChallengeStorage,
+	recoveryStorage RecoveryCodeStorage,
+	emailStorage EmailStorage,
+	auditLogger AuditLogger,
+	mailer mailer.Mailer,
+	box *secretbox.Box,
+	settings Settings,
+	tokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+) *MFA {
+	return &MFA{
+		log:              log,
+		userProvider:     userProvider,
+		appProvider:      appProvider,
+		totpStorage:      totpStorage,
+		challengeStorage: challengeStorage,
+		recoveryStorage:  recoveryStorage,
+		emailStorage:     emailStorage,
+		auditLogger:      auditLogger,
+		mailer:           mailer,
+		box:              box,
+		settings:         settings,
+		tokenTTL:         tokenTTL,
+		refreshTokenTTL:  refreshTokenTTL,
+	}
+}
+
+// Challenge decides whether login of the user into the app needs a second factor and,
+// if so, issues a challenge. The user must already have passed firstFactor.
+func (m *MFA) Challenge(ctx context.Context, user models.User, app models.App, firstFactor models.AuthMethod) (models.MFAChallenge, bool, error) {
+	const op = "mfa.Challenge"
+
+	methods, err := m.methods(ctx, user.ID.String())
+	if err != nil {
+		return models.MFAChallenge{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+	enrolled := len(methods) > 0
+
+	policyRequires := app.MFAPolicy == models.MFAPolicyAll
+	if app.MFAPolicy == models.MFAPolicyAdmins {
+		policyRequires, err = m.userProvider.IsAdmin(ctx, user.ID.String(), app.ID)
+		if err != nil {
+			return models.MFAChallenge{}, false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if !enrolled && !policyRequires {
+		return models.MFAChallenge{}, false, nil
+	}
+
+	challenge := models.MFAChallenge{
+		UserID:             user.ID,
+		AppID:              app.ID,
+		EnrollmentRequired: !enrolled,
+		FirstFactor:        firstFactor,
+		Methods:            methods,
+		ExpiresAt:          time.Now().Add(m.settings.ChallengeTTL),
+	}
+
+	id, err := m.challengeStorage.SaveMFAChallenge(ctx, challenge)
+	if err != nil {
+		return models.MFAChallenge{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+	challenge.ID = uuid.MustParse(id)
+
+	return challenge, true, nil
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret for the owner of the access token.
+// It returns the secret and its otpauth:// URI; enrollment is confirmed with the first code.
+func (m *MFA) BeginTOTPEnrollment(ctx context.Context, accessToken string) (string, string, error) {
+	const op = "mfa.BeginTOTPEnrollment"
+
+	_, user, err := m.session(ctx, accessToken)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, uri, err := m.beginTOTPEnrollment(ctx, op, user)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return secret, uri, nil
+}
+
+// BeginChallengeTOTPEnrollment lets a user who is not enrolled yet, but logs into an app
+// that requires MFA, enroll TOTP using the login challenge instead of an access token.
+// Completing the challenge with the first code confirms the enrollment.
+func (m *MFA) BeginChallengeTOTPEnrollment(ctx context.Context, challengeID string) (string, string, error) {
+	const op = "mfa.BeginChallengeTOTPEnrollment"
+
+	challenge, err := m.challengeStorage.MFAChallenge(ctx, challengeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrChallengeNotFound) {
+			return "", "", fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+		}
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !challenge.EnrollmentRequired {
+		return "", "", fmt.Errorf("%s: %w", op, ErrEnrollmentBlocked)
+	}
+
+	user, err := m.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: challenge.UserID.String()})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return "", "", fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+		}
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, uri, err := m.beginTOTPEnrollment(ctx, op, user)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return secret, uri, nil
+}
+
+// ConfirmTOTPEnrollment confirms pending enrollment of the access token owner with the first code.
+// It returns recovery codes, which are shown to the user once.
+func (m *MFA) ConfirmTOTPEnrollment(ctx context.Context, accessToken string, code string) ([]string, error) {
+	const op = "mfa.ConfirmTOTPEnrollment"
+
+	log := m.log.With(
+		slog.String("op", op),
+	)
+
+	_, user, err := m.session(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	userID := user.ID.String()
+	log = log.With(slog.String("user_id", userID))
+
+	log.Info("confirming totp enrollment")
+
+	enrollment, err := m.totpStorage.TOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrNotEnrolled)
+		}
+
+		log.Error("failed to get totp", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !enrollment.ConfirmedAt.IsZero() {
+		return nil, fmt.Errorf("%s: %w", op, ErrAlreadyEnrolled)
+	}
+
+	if err := m.verifyTOTP(ctx, enrollment, code); err != nil {
+		log.Warn("invalid totp code", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	codes, err := m.issueRecoveryCodes(ctx, userID)
+	if err != nil {
+		log.Error("failed to issue recovery codes", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("totp enrollment confirmed")
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces recovery codes of the access token owner.
+// A current TOTP code is required; previous recovery codes stop working.
+func (m *MFA) RegenerateRecoveryCodes(ctx context.Context, accessToken string, code string) ([]string, error) {
+	const op = "mfa.RegenerateRecoveryCodes"
+
+	log := m.log.With(
+		slog.String("op", op),
+	)
+
+	_, user, err := m.session(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	userID := user.ID.String()
+	log = log.With(slog.String("user_id", userID))
+
+	log.Info("regenerating recovery codes")
+
+	enrollment, err := m.totpStorage.TOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrNotEnrolled)
+		}
+
+		log.Error("failed to get totp", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if enrollment.ConfirmedAt.IsZero() {
+		return nil, fmt.Errorf("%s: %w", op, ErrNotEnrolled)
+	}
+
+	if err := m.verifyTOTP(ctx, enrollment, code); err != nil {
+		log.Warn("invalid totp code", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	codes, err := m.issueRecoveryCodes(ctx, userID)
+	if err != nil {
+		log.Error("failed to issue recovery codes", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("recovery codes regenerated")
+
+	return codes, nil
+}
+
+// DisableTOTP removes TOTP of the access token owner. A current code is required.
+func (m *MFA) DisableTOTP(ctx context.Context, accessToken string, code string) error {
+	const op = "mfa.DisableTOTP"
+
+	log := m.log.With(
+		slog.String("op", op),
+	)
+
+	_, user, err := m.session(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	userID := user.ID.String()
+	log = log.With(slog.String("user_id", userID))
+
+	log.Info("disabling totp")
+
+	enrollment, err := m.totpStorage.TOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrNotEnrolled)
+		}
+
+		log.Error("failed to get totp", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.verifyTOTP(ctx, enrollment, code); err != nil {
+		log.Warn("invalid totp code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.totpStorage.DeleteTOTP(ctx, userID); err != nil {
+		log.Error("failed to delete totp", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("totp disabled")
+
+	return nil
+}
+
+// SendChallengeEmailCode mails a one-time code to complete the login challenge with.
+// It is available to users with email MFA enabled and to users who have to enroll a factor,
+// for whom completing the challenge with the code enables email MFA.
+func (m *MFA) SendChallengeEmailCode(ctx context.Context, challengeID string) error {
+	const op = "mfa.SendChallengeEmailCode"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.String("challenge_id", challengeID),
+	)
+
+	log.Info("sending mfa email code")
+
+	challenge, err := m.challengeStorage.MFAChallenge(ctx, challengeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrChallengeNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+		}
+
+		log.Error("failed to get challenge", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if challenge.Attempts >= m.settings.MaxAttempts {
+		return fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+	}
+
+	userID := challenge.UserID.String()
+
+	if !challenge.EnrollmentRequired {
+		enabled, err := m.emailStorage.EmailMFAEnabled(ctx, userID)
+		if err != nil {
+			log.Error("failed to check email mfa", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !enabled {
+			return fmt.Errorf("%s: %w", op, ErrNotEnrolled)
+		}
+	}
+
+	user, err := m.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID})
+	if err != nil {
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.sendEmailCode(ctx, user, challenge.ID); err != nil {
+		if errors.Is(err, ErrRateLimited) {
+			log.Warn("email code rate limited")
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Error("failed to send email code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("mfa email code sent")
+
+	return nil
+}
+
+// SendEmailMFACode mails the access token owner a code that confirms enabling or disabling email MFA,
+// or steps up the session.
+func (m *MFA) SendEmailMFACode(ctx context.Context, accessToken string) error {
+	const op = "mfa.SendEmailMFACode"
+
+	log := m.log.With(
+		slog.String("op", op),
+	)
+
+	_, user, err := m.session(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("user_id", user.ID.String()))
+
+	log.Info("sending email mfa settings code")
+
+	if err := m.sendEmailCode(ctx, user, uuid.Nil); err != nil {
+		if errors.Is(err, ErrRateLimited) {
+			log.Warn("email code rate limited")
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Error("failed to send email code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailMFA enables email MFA of the access token owner with a code from SendEmailMFACode.
+// If it is the user's first factor, recovery codes are returned, to be shown once.
+func (m *MFA) ConfirmEmailMFA(ctx context.Context, accessToken string, code string) ([]string, error) {
+	const op = "mfa.ConfirmEmailMFA"
+
+	log := m.log.With(
+		slog.String("op", op),
+	)
+
+	_, user, err := m.session(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	userID := user.ID.String()
+	log = log.With(slog.String("user_id", userID))
+
+	log.Info("enabling email mfa")
+
+	methods, err := m.methods(ctx, userID)
+	if err != nil {
+		log.Error("failed to get mfa methods", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, method := range methods {
+		if method == models.MFAMethodEmail {
+			return nil, fmt.Errorf("%s: %w", op, ErrAlreadyEnrolled)
+		}
+	}
+
+	if err := m.useEmailCode(ctx, user.ID, uuid.Nil, code); err != nil {
+		log.Warn("invalid email code", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.emailStorage.EnableEmailMFA(ctx, userID); err != nil {
+		log.Error("failed to enable email mfa", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var codes []string
+	if len(methods) == 0 {
+		codes, err = m.issueRecoveryCodes(ctx, userID)
+		if err != nil {
+			log.Error("failed to issue recovery codes", sl.Err(err))
+
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	log.Info("email mfa enabled")
+
+	return codes, nil
+}
+
+// DisableEmailMFA disables email MFA of the access token owner with a code from SendEmailMFACode.
+func (m *MFA) DisableEmailMFA(ctx context.Context, accessToken string, code string) error {
+	const op = "mfa.DisableEmailMFA"
+
+	log := m.log.With(
+		slog.String("op", op),
+	)
+
+	_, user, err := m.session(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	userID := user.ID.String()
+	log = log.With(slog.String("user_id", userID))
+
+	log.Info("disabling email mfa")
+
+	enabled, err := m.emailStorage.EmailMFAEnabled(ctx, userID)
+	if err != nil {
+		log.Error("failed to check email mfa", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !enabled {
+		return fmt.Errorf("%s: %w", op, ErrNotEnrolled)
+	}
+
+	if err := m.useEmailCode(ctx, user.ID, uuid.Nil, code); err != nil {
+		log.Warn("invalid email code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.emailStorage.DisableEmailMFA(ctx, userID); err != nil {
+		log.Error("failed to disable email mfa", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email mfa disabled")
+
+	return nil
+}
+
+// StepUp re-verifies a second factor within the session of the access token and returns
+// a short-lived access token with models.ACRStepUp for sensitive actions.
+// The code is a TOTP code or a code from SendEmailMFACode; recovery codes are not accepted.
+// TOTP codes count against the same per-user limit as at login, see verifyTOTP.
+func (m *MFA) StepUp(ctx context.Context, accessToken string, code string) (string, error) {
+	const op = "mfa.StepUp"
+
+	log := m.log.With(
+		slog.String("op", op),
+	)
+
+	claims, user, err := m.session(ctx, accessToken)
+	if err != nil {
+		log.Warn("session rejected", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("user_id", claims.UserID))
+
+	log.Info("stepping up authentication")
+
+	if err := m.verifyStepUpCode(ctx, user.ID, code); err != nil {
+		log.Warn("invalid step-up code", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := m.appProvider.AppByID(ctx, claims.AppID)
+	if err != nil {
+		log.Error("failed to get app", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	auth := claims.Auth.With(models.AuthMethodOTP)
+	auth.Time = time.Now()
+
+	token, err := jwt.NewStepUpToken(user, app, auth, m.settings.StepUpTTL)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("authentication stepped up")
+
+	return token, nil
+}
+
+// CompleteMFA exchanges login challenge and a TOTP, emailed or recovery code for a token pair.
+//
+// If the challenge completed the first enrollment of the user, recovery codes are returned as well.
+func (m *MFA) CompleteMFA(ctx context.Context, challengeID string, code string) (string, string, []string, error) {
+	const op = "mfa.CompleteMFA"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.String("challenge_id", challengeID),
+	)
+
+	log.Info("completing mfa challenge")
+
+	challenge, user, app, recoveryCodes, err := m.completeChallenge(ctx, log, challengeID, code, true)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, refreshToken, err := jwt.NewTokenPair(user, app, challengeAuthentication(challenge), m.tokenTTL, m.refreshTokenTTL)
+	if err != nil {
+		log.Error("failed to generate tokens", sl.Err(err))
+
+		return "", "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.userProvider.RecordLogin(ctx, user.ID.String(), app.ID.String()); err != nil {
+		log.Error("failed to record login", sl.Err(err))
+	}
+
+	log.Info("mfa challenge completed")
+
+	return accessToken, refreshToken, recoveryCodes, nil
+}
+
+// VerifyChallenge completes the login challenge like CompleteMFA does, but returns the user,
+// the app and how the user authenticated instead of tokens, for flows that issue tokens later,
+// e.g. the OAuth authorization code flow. Such flows can't show recovery codes, so challenges
+// of users who have yet to enroll get ErrEnrollmentRequired.
+func (m *MFA) VerifyChallenge(ctx context.Context, challengeID string, code string) (models.User, models.App, models.Authentication, error) {
+	const op = "mfa.VerifyChallenge"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.String("challenge_id", challengeID),
+	)
+
+	log.Info("verifying mfa challenge")
+
+	challenge, user, app, _, err := m.completeChallenge(ctx, log, challengeID, code, false)
+	if err != nil {
+		return models.User{}, models.App{}, models.Authentication{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("mfa challenge verified")
+
+	return user, app, challengeAuthentication(challenge), nil
+}
+
+// completeChallenge checks the code and consumes the challenge. Without allowEnrollment,
+// challenges of users with no second factor enrolled are rejected before the code is checked.
+func (m *MFA) completeChallenge(
+	ctx context.Context,
+	log *slog.Logger,
+	challengeID string,
+	code string,
+	allowEnrollment bool,
+) (models.MFAChallenge, models.User, models.App, []string, error) {
+	challenge, err := m.challengeStorage.MFAChallenge(ctx, challengeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrChallengeNotFound) {
+			log.Warn("challenge not found", sl.Err(err))
+
+			return models.MFAChallenge{}, models.User{}, models.App{}, nil, ErrInvalidChallenge
+		}
+
+		log.Error("failed to get challenge", sl.Err(err))
+
+		return models.MFAChallenge{}, models.User{}, models.App{}, nil, err
+	}
+
+	userID := challenge.UserID.String()
+
+	methods, err := m.methods(ctx, userID)
+	if err != nil {
+		log.Error("failed to get mfa methods", sl.Err(err))
+
+		return models.MFAChallenge{}, models.User{}, models.App{}, nil, err
+	}
+	if len(methods) == 0 && !allowEnrollment {
+		log.Warn("challenge requires enrollment")
+
+		return models.MFAChallenge{}, models.User{}, models.App{}, nil, ErrEnrollmentRequired
+	}
+
+	// Every code checked counts, so concurrent guesses can't get past the limit.
+	if err := m.challengeStorage.UseMFAChallengeAttempt(ctx, challengeID, m.settings.MaxAttempts); err != nil {
+		if errors.Is(err, storage.ErrChallengeNotFound) {
+			log.Warn("challenge attempts exhausted")
+
+			return models.MFAChallenge{}, models.User{}, models.App{}, nil, ErrInvalidChallenge
+		}
+
+		log.Error("failed to record attempt", sl.Err(err))
+
+		return models.MFAChallenge{}, models.User{}, models.App{}, nil, err
+	}
+
+	usedRecoveryCode := recoverycode.Looks(code) && len(methods) > 0
+
+	var (
+		method            models.MFAMethod
+		recoveryCodesLeft int
+	)
+	if usedRecoveryCode {
+		recoveryCodesLeft, err = m.recoveryStorage.UseRecoveryCode(ctx, userID, recoverycode.Hash(code))
+		if errors.Is(err, storage.ErrCodeNotFound) {
+			err = ErrInvalidCode
+		}
+	} else {
+		method, err = m.verifyChallengeCode(ctx, challenge, code)
+	}
+	if err != nil {
+		log.Warn("invalid mfa code", sl.Err(err), slog.Bool("recovery_code", usedRecoveryCode))
+
+		return models.MFAChallenge{}, models.User{}, models.App{}, nil, err
+	}
+
+	// The challenge completed the first enrollment of the user.
+	var recoveryCodes []string
+	if len(methods) == 0 {
+		if method == models.MFAMethodEmail {
+			if err := m.emailStorage.EnableEmailMFA(ctx, userID); err != nil {
+				log.Error("failed to enable email mfa", sl.Err(err))
+
+				return models.MFAChallenge{}, models.User{}, models.App{}, nil, err
+			}
+		}
+
+		recoveryCodes, err = m.issueRecoveryCodes(ctx, userID)
+		if err != nil {
+			log.Error("failed to issue recovery codes", sl.Err(err))
+
+			return models.MFAChallenge{}, models.User{}, models.App{}, nil, err
+		}
+	}
+
+	user, app, err := m.consumeChallenge(ctx, challenge)
+	if err != nil {
+		log.Warn("failed to finish login", sl.Err(err))
+
+		return models.MFAChallenge{}, models.User{}, models.App{}, nil, err
+	}
+
+	if usedRecoveryCode {
+		m.reportRecoveryCodeUse(ctx, log, user, challenge.AppID.String(), recoveryCodesLeft)
+	}
+
+	return challenge, user, app, recoveryCodes, nil
+}
+
+// consumeChallenge consumes the challenge and returns the user and the app it logs into.
+func (m *MFA) consumeChallenge(ctx context.Context, challenge models.MFAChallenge) (models.User, models.App, error) {
+	if err := m.challengeStorage.ConsumeMFAChallenge(ctx, challenge.ID.String()); err != nil {
+		if errors.Is(err, storage.ErrChallengeNotFound) {
+			return models.User{}, models.App{}, ErrInvalidChallenge
+		}
+
+		return models.User{}, models.App{}, err
+	}
+
+	user, err := m.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: challenge.UserID.String()})
+	if err != nil {
+		return models.User{}, models.App{}, err
+	}
+
+	// Status could have changed between the password step and now.
+	if !user.IsActive(time.Now()) {
+		return models.User{}, models.App{}, ErrUserInactive
+	}
+
+	app, err := m.appProvider.AppByID(ctx, challenge.AppID.String())
+	if err != nil {
+		return models.User{}, models.App{}, err
+	}
+
+	return user, app, nil
+}
+
+// challengeAuthentication is how the user authenticated by completing the challenge.
+func challengeAuthentication(challenge models.MFAChallenge) models.Authentication {
+	return models.NewAuthentication(challenge.FirstFactor, models.AuthMethodOTP)
+}
+
+func (m *MFA) issueRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes, err := recoverycode.Generate(recoverycode.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, recoverycode.Hash(code))
+	}
+
+	if err := m.recoveryStorage.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// reportRecoveryCodeUse audits the use and warns the user by email, so an attacker
+// holding a stolen code can't sign in unnoticed. Failures don't block the login.
+func (m *MFA) reportRecoveryCodeUse(ctx context.Context, log *slog.Logger, user models.User, appID string, left int) {
+	if err := m.auditLogger.SaveAuditEvent(ctx, models.AuditEvent{
+		UserID:  user.ID,
+		ActorID: user.ID,
+		Event:   eventRecoveryCodeUsed,
+		Details: map[string]any{"app_id": appID, "codes_left": left},
+	}); err != nil {
+		log.Error("failed to audit recovery code use", sl.Err(err))
+	}
+
+	body := fmt.Sprintf(
+		"A recovery code was just used to sign in to your account. You have %d recovery codes left.\n\n"+
+			"If it wasn't you, change your password and regenerate your recovery codes right away.",
+		left,
+	)
+	if err := m.mailer.Send(ctx, user.Email, "Recovery code used", body); err != nil {
+		log.Error("failed to notify about recovery code use", sl.Err(err))
+	}
+}
+
+func (m *MFA) beginTOTPEnrollment(ctx context.Context, op string, user models.User) (string, string, error) {
+	userID := user.ID.String()
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID),
+	)
+
+	log.Info("starting totp enrollment")
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Error("failed to generate totp secret", sl.Err(err))
+
+		return "", "", err
+	}
+
+	encrypted, err := m.box.Seal([]byte(secret))
+	if err != nil {
+		log.Error("failed to encrypt totp secret", sl.Err(err))
+
+		return "", "", err
+	}
+
+	if err := m.totpStorage.SaveTOTP(ctx, userID, encrypted); err != nil {
+		if errors.Is(err, storage.ErrTOTPExists) {
+			log.Warn("totp already enrolled")
+
+			return "", "", ErrAlreadyEnrolled
+		}
+
+		log.Error("failed to save totp secret", sl.Err(err))
+
+		return "", "", err
+	}
+
+	log.Info("totp enrollment started")
+
+	return secret, totp.URI(m.settings.Issuer, user.Email, secret), nil
+}
+
+// verifyTOTP checks code and marks its time step used, which also confirms a pending enrollment.
+// Every check counts against the user, so guesses are limited across challenges and sessions;
+// after MaxAttempts in a row fail, codes are refused with ErrTooManyAttempts for TOTPLockout.
+func (m *MFA) verifyTOTP(ctx context.Context, enrollment models.TOTP, code string) error {
+	userID := enrollment.UserID.String()
+
+	if err := m.totpStorage.UseTOTPAttempt(ctx, userID, m.settings.MaxAttempts, m.settings.TOTPLockout); err != nil {
+		if errors.Is(err, storage.ErrTOTPLocked) {
+			return ErrTooManyAttempts
+		}
+
+		return err
+	}
+
+	secret, err := m.box.Open(enrollment.SecretEncrypted)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(string(secret), code, time.Now(), totpSkew)
+	if !ok || step <= enrollment.LastUsedStep {
+		return ErrInvalidCode
+	}
+
+	if err := m.totpStorage.UseTOTPStep(ctx, userID, step); err != nil {
+		if errors.Is(err, storage.ErrTOTPStepUsed) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// session returns the claims and the owner of the access token. Tokens of users who are not
+// active, or issued before the user's sessions were revoked, are rejected.
+func (m *MFA) session(ctx context.Context, accessToken string) (jwt.AccessClaims, models.User, error) {
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return jwt.AccessClaims{}, models.User{}, ErrAccessDenied
+	}
+
+	user, err := m.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: claims.UserID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return jwt.AccessClaims{}, models.User{}, ErrAccessDenied
+		}
+
+		return jwt.AccessClaims{}, models.User{}, err
+	}
+
+	if !user.IsActive(time.Now()) {
+		return jwt.AccessClaims{}, models.User{}, ErrUserInactive
+	}
+
+	// Revoking sessions must also revoke the access tokens issued in them.
+	if claims.IssuedAt.Before(user.SessionsRevokedAt) {
+		return jwt.AccessClaims{}, models.User{}, ErrAccessDenied
+	}
+
+	return claims, user, nil
+}
+
+// methods returns second factors the user has enrolled.
+func (m *MFA) methods(ctx context.Context, userID string) ([]models.MFAMethod, error) {
+	var methods []models.MFAMethod
+
+	enrollment, err := m.totpStorage.TOTP(ctx, userID)
+	switch {
+	case err == nil:
+		if !enrollment.ConfirmedAt.IsZero() {
+			methods = append(methods, models.MFAMethodTOTP)
+		}
+	case !errors.Is(err, storage.ErrTOTPNotFound):
+		return nil, err
+	}
+
+	emailEnabled, err := m.emailStorage.EmailMFAEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if emailEnabled {
+		methods = append(methods, models.MFAMethodEmail)
+	}
+
+	return methods, nil
+}
+
+// verifyChallengeCode checks the code against the code mailed for the challenge, if there is one,
+// and then against TOTP, which may be pending enrollment through the challenge.
+func (m *MFA) verifyChallengeCode(ctx context.Context, challenge models.MFAChallenge, code string) (models.MFAMethod, error) {
+	err := m.useEmailCode(ctx, challenge.UserID, challenge.ID, code)
+	if err == nil {
+		return models.MFAMethodEmail, nil
+	}
+	if !errors.Is(err, ErrInvalidCode) {
+		return "", err
+	}
+
+	enrollment, err := m.totpStorage.TOTP(ctx, challenge.UserID.String())
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return "", ErrInvalidCode
+		}
+
+		return "", err
+	}
+
+	if err := m.verifyTOTP(ctx, enrollment, code); err != nil {
+		return "", err
+	}
+
+	return models.MFAMethodTOTP, nil
+}
+
+// verifyStepUpCode checks the code against the account code mailed to the user, if there is one,
+// and then against confirmed TOTP.
+func (m *MFA) verifyStepUpCode(ctx context.Context, userID uuid.UUID, code string) error {
+	err := m.useEmailCode(ctx, userID, uuid.Nil, code)
+	if err == nil || !errors.Is(err, ErrInvalidCode) {
+		return err
+	}
+
+	enrollment, err := m.totpStorage.TOTP(ctx, userID.String())
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	// An unconfirmed secret must not be confirmed by stepping up.
+	if enrollment.ConfirmedAt.IsZero() {
+		return ErrInvalidCode
+	}
+
+	return m.verifyTOTP(ctx, enrollment, code)
+}
+
+// sendEmailCode mails a new code to the user, bound to the challenge if it's not zero.
+func (m *MFA) sendEmailCode(ctx context.Context, user models.User, challengeID uuid.UUID) error {
+	count, last, err := m.emailStorage.EmailCodeStats(ctx, user.ID.String(), time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+
+	if count >= m.settings.EmailMaxCodesPerHour || time.Since(last) < m.settings.EmailResendInterval {
+		return ErrRateLimited
+	}
+
+	code, err := otp.GenerateCode(emailCodeDigits)
+	if err != nil {
+		return err
+	}
+
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.emailStorage.SaveEmailCode(ctx, models.EmailCode{
+		UserID:      user.ID,
+		ChallengeID: challengeID,
+		CodeHash:    codeHash,
+		ExpiresAt:   time.Now().Add(m.settings.EmailCodeTTL),
+	}); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		"Your verification code: %s\n\nIt expires in %s. If you didn't request it, change your password.",
+		code, m.settings.EmailCodeTTL,
+	)
+
+	return m.mailer.Send(ctx, user.Email, "Your verification code", body)
+}
+
+// useEmailCode checks code against the latest active one and consumes it on success.
+// Every check counts as an attempt; after MaxAttempts the code is unusable.
+func (m *MFA) useEmailCode(ctx context.Context, userID uuid.UUID, challengeID uuid.UUID, code string) error {
+	emailCode, err := m.emailStorage.ActiveEmailCode(ctx, userID.String(), challengeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrCodeNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	if err := m.emailStorage.UseEmailCodeAttempt(ctx, emailCode.ID.String(), m.settings.MaxAttempts); err != nil {
+		if errors.Is(err, storage.ErrCodeNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(emailCode.CodeHash, []byte(code)); err != nil {
+		return ErrInvalidCode
+	}
+
+	if err := m.emailStorage.ConsumeEmailCode(ctx, emailCode.ID.String()); err != nil {
+		if errors.Is(err, storage.ErrCodeNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,918 @@
+package mfa
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/secretbox"
+	"github.com/sol1corejz/auth-service/internal/lib/totp"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChallenge_Policy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy models.MFAPolicy
+		// adminOf is "app", "all" or "other" app the user is admin of, empty for none.
+		adminOf  string
+		enrolled bool
+		want     bool
+	}{
+		{name: "optional", policy: models.MFAPolicyOptional, want: false},
+		{name: "optional enrolled", policy: models.MFAPolicyOptional, enrolled: true, want: true},
+		{name: "admins for user", policy: models.MFAPolicyAdmins, want: false},
+		{name: "admins for admin", policy: models.MFAPolicyAdmins, adminOf: "app", want: true},
+		{name: "admins for admin of all apps", policy: models.MFAPolicyAdmins, adminOf: "all", want: true},
+		{name: "admins for admin of another app", policy: models.MFAPolicyAdmins, adminOf: "other", want: false},
+		{name: "all", policy: models.MFAPolicyAll, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.app.MFAPolicy = tt.policy
+			switch tt.adminOf {
+			case "app":
+				env.storage.admins[env.user.ID] = env.app.ID
+			case "all":
+				env.storage.admins[env.user.ID] = uuid.Nil
+			case "other":
+				env.storage.admins[env.user.ID] = uuid.New()
+			}
+			if tt.enrolled {
+				env.enrollTOTP()
+			}
+
+			challenge, required, err := env.service.Challenge(context.Background(), env.user, env.app, models.AuthMethodPassword)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, required)
+
+			if !tt.want {
+				assert.Empty(t, env.storage.challenges)
+				return
+			}
+
+			assert.Equal(t, !tt.enrolled, challenge.EnrollmentRequired)
+			assert.Contains(t, env.storage.challenges, challenge.ID)
+		})
+	}
+}
+
+func TestCompleteMFA_TOTP(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+
+	challenge, required, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodSMS)
+	require.NoError(t, err)
+	require.True(t, required)
+	assert.Equal(t, []models.MFAMethod{models.MFAMethodTOTP}, challenge.Methods)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	accessToken, refreshToken, recoveryCodes, err := env.service.CompleteMFA(ctx, challenge.ID.String(), code)
+	require.NoError(t, err)
+	assert.NotEmpty(t, refreshToken)
+	assert.Empty(t, recoveryCodes, "recovery codes are only issued on first enrollment")
+
+	// The first factor of the challenge is kept in the token.
+	claims, err := jwt.ParseAccessToken(accessToken)
+	require.NoError(t, err)
+	assert.Equal(t, models.ACRMultiFactor, claims.ACR)
+	assert.Equal(t,
+		[]models.AuthMethod{models.AuthMethodSMS, models.AuthMethodOTP, models.AuthMethodMultiFactor},
+		claims.Auth.Methods,
+	)
+
+	// The challenge is consumed.
+	_, _, _, err = env.service.CompleteMFA(ctx, challenge.ID.String(), code)
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+}
+
+func TestVerifyChallenge(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+
+	challenge, required, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+	require.True(t, required)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	user, app, authentication, err := env.service.VerifyChallenge(ctx, challenge.ID.String(), code)
+	require.NoError(t, err)
+	assert.Equal(t, env.user.ID, user.ID)
+	assert.Equal(t, env.app.ID, app.ID)
+	assert.Equal(t,
+		[]models.AuthMethod{models.AuthMethodPassword, models.AuthMethodOTP, models.AuthMethodMultiFactor},
+		authentication.Methods,
+	)
+
+	_, _, _, err = env.service.VerifyChallenge(ctx, challenge.ID.String(), code)
+	assert.ErrorIs(t, err, ErrInvalidChallenge, "the challenge is consumed")
+}
+
+func TestVerifyChallenge_EnrollmentRequired(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.app.MFAPolicy = models.MFAPolicyAll
+
+	challenge, required, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+	require.True(t, required)
+	require.True(t, challenge.EnrollmentRequired)
+
+	_, _, _, err = env.service.VerifyChallenge(ctx, challenge.ID.String(), "123456")
+	assert.ErrorIs(t, err, ErrEnrollmentRequired)
+	assert.Zero(t, env.storage.challenges[challenge.ID].challenge.Attempts, "no attempt is used")
+}
+
+func TestCompleteMFA_AttemptsExhausted(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+
+	challenge, _, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+
+	for range env.service.settings.MaxAttempts {
+		_, _, _, err := env.service.CompleteMFA(ctx, challenge.ID.String(), "000000")
+		require.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	_, _, _, err = env.service.CompleteMFA(ctx, challenge.ID.String(), code)
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+}
+
+func TestCompleteMFA_UserSuspended(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+
+	challenge, _, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+
+	suspended := env.user
+	suspended.Status = models.UserStatusSuspended
+	env.storage.users[suspended.ID] = suspended
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	_, _, _, err = env.service.CompleteMFA(ctx, challenge.ID.String(), code)
+	assert.ErrorIs(t, err, ErrUserInactive)
+}
+
+func TestCompleteMFA_ConcurrentGuesses(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.enrollTOTP()
+
+	challenge, _, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _, _ = env.service.CompleteMFA(ctx, challenge.ID.String(), "000000")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, env.service.settings.MaxAttempts, env.storage.challenges[challenge.ID].challenge.Attempts)
+}
+
+func TestCompleteMFA_TOTPLockedAcrossChallenges(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+
+	codes, err := env.service.issueRecoveryCodes(ctx, env.user.ID.String())
+	require.NoError(t, err)
+
+	// Starting a new login for every guess doesn't reset the count.
+	for range env.service.settings.MaxAttempts {
+		challenge, _, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+		require.NoError(t, err)
+
+		_, _, _, err = env.service.CompleteMFA(ctx, challenge.ID.String(), "000000")
+		require.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	challenge, _, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	_, _, _, err = env.service.CompleteMFA(ctx, challenge.ID.String(), code)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	// Recovery codes still let the user in.
+	_, _, _, err = env.service.CompleteMFA(ctx, challenge.ID.String(), codes[0])
+	require.NoError(t, err)
+}
+
+func TestCompleteMFA_RecoveryCode(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.enrollTOTP()
+
+	codes, err := env.service.issueRecoveryCodes(ctx, env.user.ID.String())
+	require.NoError(t, err)
+
+	challenge, _, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+
+	accessToken, _, _, err := env.service.CompleteMFA(ctx, challenge.ID.String(), codes[0])
+	require.NoError(t, err)
+	assert.NotEmpty(t, accessToken)
+
+	// The use is audited and the user is warned.
+	require.Len(t, env.storage.events, 1)
+	assert.Equal(t, eventRecoveryCodeUsed, env.storage.events[0].Event)
+	assert.Equal(t, len(codes)-1, env.storage.events[0].Details["codes_left"])
+	assert.Equal(t, []string{"Recovery code used"}, env.mailer.subjects)
+
+	// The code is single-use.
+	challenge, _, err = env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+
+	_, _, _, err = env.service.CompleteMFA(ctx, challenge.ID.String(), codes[0])
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+
+	old, err := env.service.issueRecoveryCodes(ctx, env.user.ID.String())
+	require.NoError(t, err)
+
+	_, err = env.service.RegenerateRecoveryCodes(ctx, env.token(), "000000")
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	codes, err := env.service.RegenerateRecoveryCodes(ctx, env.token(), code)
+	require.NoError(t, err)
+	assert.Len(t, codes, len(old))
+
+	// Previous codes stop working.
+	challenge, _, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+
+	_, _, _, err = env.service.CompleteMFA(ctx, challenge.ID.String(), old[0])
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestRegenerateRecoveryCodes_Rejected(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+	accessToken := env.token()
+
+	// Guesses are limited, even with a correct code coming last.
+	for range env.service.settings.MaxAttempts {
+		_, err := env.service.RegenerateRecoveryCodes(ctx, accessToken, "000000")
+		require.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	_, err = env.service.RegenerateRecoveryCodes(ctx, accessToken, code)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	// A revoked session can't mint codes for a later bypass of the second factor.
+	env.storage.attempts[env.user.ID.String()] = 0
+	revoked := env.user
+	revoked.SessionsRevokedAt = time.Now().Add(time.Minute)
+	env.storage.users[revoked.ID] = revoked
+
+	_, err = env.service.RegenerateRecoveryCodes(ctx, accessToken, code)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+
+	suspended := revoked
+	suspended.SessionsRevokedAt = time.Time{}
+	suspended.Status = models.UserStatusSuspended
+	env.storage.users[suspended.ID] = suspended
+
+	_, err = env.service.RegenerateRecoveryCodes(ctx, accessToken, code)
+	assert.ErrorIs(t, err, ErrUserInactive)
+	assert.Empty(t, env.storage.recovery[env.user.ID.String()], "no codes were issued")
+}
+
+func TestDisableTOTP_Lockout(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+
+	for range env.service.settings.MaxAttempts {
+		err := env.service.DisableTOTP(ctx, env.token(), "000000")
+		require.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	err = env.service.DisableTOTP(ctx, env.token(), code)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Contains(t, env.storage.totps, env.user.ID.String(), "totp stays enrolled")
+}
+
+func TestTOTPSettings_InactiveSession(t *testing.T) {
+	calls := map[string]func(env *testEnv, accessToken string, code string) error{
+		"begin enrollment": func(env *testEnv, accessToken string, _ string) error {
+			_, _, err := env.service.BeginTOTPEnrollment(context.Background(), accessToken)
+			return err
+		},
+		"confirm enrollment": func(env *testEnv, accessToken string, code string) error {
+			_, err := env.service.ConfirmTOTPEnrollment(context.Background(), accessToken, code)
+			return err
+		},
+		"disable": func(env *testEnv, accessToken string, code string) error {
+			return env.service.DisableTOTP(context.Background(), accessToken, code)
+		},
+	}
+
+	for name, call := range calls {
+		t.Run(name+" suspended", func(t *testing.T) {
+			env := newTestEnv(t)
+			secret := env.enrollTOTP()
+			accessToken := env.token()
+
+			suspended := env.user
+			suspended.Status = models.UserStatusSuspended
+			env.storage.users[suspended.ID] = suspended
+
+			code, err := totp.Code(secret, totp.Step(time.Now()))
+			require.NoError(t, err)
+
+			assert.ErrorIs(t, call(env, accessToken, code), ErrUserInactive)
+		})
+
+		t.Run(name+" revoked", func(t *testing.T) {
+			env := newTestEnv(t)
+			secret := env.enrollTOTP()
+			accessToken := env.token()
+
+			revoked := env.user
+			revoked.SessionsRevokedAt = time.Now().Add(time.Minute)
+			env.storage.users[revoked.ID] = revoked
+
+			code, err := totp.Code(secret, totp.Step(time.Now()))
+			require.NoError(t, err)
+
+			assert.ErrorIs(t, call(env, accessToken, code), ErrAccessDenied)
+		})
+	}
+}
+
+func TestEmailMFA(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.SendEmailMFACode(ctx, env.token()))
+
+	_, err := env.service.ConfirmEmailMFA(ctx, env.token(), "000000")
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	// Email is the first factor of the user, so recovery codes are issued.
+	codes, err := env.service.ConfirmEmailMFA(ctx, env.token(), env.mailer.code())
+	require.NoError(t, err)
+	assert.NotEmpty(t, codes)
+	assert.True(t, env.storage.emailEnabled[env.user.ID.String()])
+
+	// The login is challenged now and completes with a mailed code.
+	challenge, required, err := env.service.Challenge(ctx, env.user, env.app, models.AuthMethodPassword)
+	require.NoError(t, err)
+	require.True(t, required)
+	assert.Equal(t, []models.MFAMethod{models.MFAMethodEmail}, challenge.Methods)
+
+	require.NoError(t, env.service.SendChallengeEmailCode(ctx, challenge.ID.String()))
+
+	accessToken, _, _, err := env.service.CompleteMFA(ctx, challenge.ID.String(), env.mailer.code())
+	require.NoError(t, err)
+	assert.NotEmpty(t, accessToken)
+
+	require.NoError(t, env.service.SendEmailMFACode(ctx, env.token()))
+	require.NoError(t, env.service.DisableEmailMFA(ctx, env.token(), env.mailer.code()))
+	assert.False(t, env.storage.emailEnabled[env.user.ID.String()])
+}
+
+func TestEmailMFA_InactiveSession(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	accessToken := env.token()
+	require.NoError(t, env.service.SendEmailMFACode(ctx, accessToken))
+	code := env.mailer.code()
+
+	revoked := env.user
+	revoked.SessionsRevokedAt = time.Now().Add(time.Minute)
+	env.storage.users[revoked.ID] = revoked
+
+	assert.ErrorIs(t, env.service.SendEmailMFACode(ctx, accessToken), ErrAccessDenied)
+	_, err := env.service.ConfirmEmailMFA(ctx, accessToken, code)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+
+	suspended := env.user
+	suspended.Status = models.UserStatusSuspended
+	env.storage.users[suspended.ID] = suspended
+
+	assert.ErrorIs(t, env.service.SendEmailMFACode(ctx, accessToken), ErrUserInactive)
+	_, err = env.service.ConfirmEmailMFA(ctx, accessToken, code)
+	assert.ErrorIs(t, err, ErrUserInactive)
+	assert.ErrorIs(t, env.service.DisableEmailMFA(ctx, accessToken, code), ErrUserInactive)
+	assert.False(t, env.storage.emailEnabled[env.user.ID.String()])
+}
+
+func TestEmailCode_ConcurrentGuesses(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.SendEmailMFACode(ctx, env.token()))
+	code := env.mailer.code()
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = env.service.useEmailCode(ctx, env.user.ID, uuid.Nil, "000000")
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, env.storage.emailCodes, 1)
+	assert.Equal(t, env.service.settings.MaxAttempts, env.storage.emailCodes[0].code.Attempts)
+
+	// The right code no longer works either.
+	_, err := env.service.ConfirmEmailMFA(ctx, env.token(), code)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestStepUp(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	token, err := env.service.StepUp(ctx, env.token(), code)
+	require.NoError(t, err)
+
+	claims, err := jwt.ParseAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, models.ACRStepUp, claims.ACR)
+	assert.Contains(t, claims.Auth.Methods, models.AuthMethodOTP)
+	assert.Equal(t, env.service.settings.StepUpTTL, claims.ExpiresAt.Sub(claims.IssuedAt))
+
+	// A code can't be used twice.
+	_, err = env.service.StepUp(ctx, env.token(), code)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestStepUp_TOTPLockout(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+
+	for range env.service.settings.MaxAttempts {
+		_, err := env.service.StepUp(ctx, env.token(), "000000")
+		require.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	// A stolen access token doesn't allow guessing through all the codes.
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	_, err = env.service.StepUp(ctx, env.token(), code)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+}
+
+func TestStepUp_RevokedSession(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	secret := env.enrollTOTP()
+	accessToken := env.token()
+
+	revoked := env.user
+	revoked.SessionsRevokedAt = time.Now().Add(time.Minute)
+	env.storage.users[revoked.ID] = revoked
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	_, err = env.service.StepUp(ctx, accessToken, code)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
+
+type testEnv struct {
+	t       *testing.T
+	service *MFA
+	storage *fakeStorage
+	mailer  *fakeMailer
+	box     *secretbox.Box
+	user    models.User
+	app     models.App
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com", Status: models.UserStatusActive}
+	app := models.App{ID: uuid.New(), Name: "test-app", Enabled: true, MFAPolicy: models.MFAPolicyOptional}
+
+	fake := &fakeStorage{
+		users:      map[uuid.UUID]models.User{user.ID: user},
+		apps:       map[uuid.UUID]models.App{app.ID: app},
+		totps:      map[string]models.TOTP{},
+		attempts:   map[string]int{},
+		challenges: map[uuid.UUID]*fakeChallenge{},
+		recovery:   map[string][]string{},
+		admins:     map[uuid.UUID]uuid.UUID{},
+
+		emailEnabled: map[string]bool{},
+	}
+
+	box, err := secretbox.New("test-key")
+	require.NoError(t, err)
+
+	settings := Settings{
+		Issuer:               "test",
+		ChallengeTTL:         5 * time.Minute,
+		MaxAttempts:          3,
+		TOTPLockout:          15 * time.Minute,
+		EmailCodeTTL:         5 * time.Minute,
+		EmailResendInterval:  time.Minute,
+		EmailMaxCodesPerHour: 5,
+		StepUpTTL:            5 * time.Minute,
+	}
+
+	mailer := &fakeMailer{}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testEnv{
+		t:       t,
+		service: New(log, fake, fake, fake, fake, fake, fake, fake, mailer, box, settings, time.Hour, time.Hour),
+		storage: fake,
+		mailer:  mailer,
+		box:     box,
+		user:    user,
+		app:     app,
+	}
+}
+
+// enrollTOTP gives the user a confirmed TOTP secret and returns it.
+func (e *testEnv) enrollTOTP() string {
+	e.t.Helper()
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(e.t, err)
+
+	encrypted, err := e.box.Seal([]byte(secret))
+	require.NoError(e.t, err)
+
+	e.storage.totps[e.user.ID.String()] = models.TOTP{
+		UserID:          e.user.ID,
+		SecretEncrypted: encrypted,
+		ConfirmedAt:     time.Now(),
+	}
+
+	return secret
+}
+
+// token returns an access token of the user.
+func (e *testEnv) token() string {
+	e.t.Helper()
+
+	accessToken, _, err := jwt.NewTokenPair(e.user, e.app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(e.t, err)
+
+	return accessToken
+}
+
+// fakeMailer keeps sent emails instead of sending them.
+type fakeMailer struct {
+	subjects []string
+	bodies   []string
+}
+
+func (f *fakeMailer) Send(_ context.Context, _ string, subject string, body string) error {
+	f.subjects = append(f.subjects, subject)
+	f.bodies = append(f.bodies, body)
+
+	return nil
+}
+
+// code returns the code from the last email.
+func (f *fakeMailer) code() string {
+	body := f.bodies[len(f.bodies)-1]
+	_, code, _ := strings.Cut(body, "Your verification code: ")
+	code, _, _ = strings.Cut(code, "\n")
+
+	return code
+}
+
+type fakeChallenge struct {
+	challenge models.MFAChallenge
+	consumed  bool
+}
+
+type fakeEmailCode struct {
+	code     models.EmailCode
+	consumed bool
+}
+
+// fakeStorage keeps everything the service stores in memory.
+type fakeStorage struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]models.User
+	apps  map[uuid.UUID]models.App
+	totps map[string]models.TOTP
+	// attempts counts TOTP codes checked since the last accepted one; the lockout never passes.
+	attempts   map[string]int
+	challenges map[uuid.UUID]*fakeChallenge
+	recovery   map[string][]string
+	events     []models.AuditEvent
+	// admins maps users to the app they are admin of, uuid.Nil for all apps.
+	admins map[uuid.UUID]uuid.UUID
+
+	emailEnabled map[string]bool
+	emailCodes   []*fakeEmailCode
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	user, ok := f.users[uuid.MustParse(identifier.Value)]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeStorage) RecordLogin(context.Context, string, string) error {
+	return nil
+}
+
+func (f *fakeStorage) IsAdmin(_ context.Context, userID string, appID uuid.UUID) (bool, error) {
+	adminOf, ok := f.admins[uuid.MustParse(userID)]
+	return ok && (adminOf == uuid.Nil || adminOf == appID), nil
+}
+
+func (f *fakeStorage) AppByID(_ context.Context, appID string) (models.App, error) {
+	app, ok := f.apps[uuid.MustParse(appID)]
+	if !ok {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return app, nil
+}
+
+func (f *fakeStorage) SaveTOTP(_ context.Context, userID string, secretEncrypted []byte) error {
+	if _, ok := f.totps[userID]; ok {
+		return storage.ErrTOTPExists
+	}
+
+	f.totps[userID] = models.TOTP{UserID: uuid.MustParse(userID), SecretEncrypted: secretEncrypted}
+
+	return nil
+}
+
+func (f *fakeStorage) TOTP(_ context.Context, userID string) (models.TOTP, error) {
+	enrollment, ok := f.totps[userID]
+	if !ok {
+		return models.TOTP{}, storage.ErrTOTPNotFound
+	}
+
+	return enrollment, nil
+}
+
+func (f *fakeStorage) UseTOTPAttempt(_ context.Context, userID string, maxAttempts int, _ time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.totps[userID]; !ok || f.attempts[userID] >= maxAttempts {
+		return storage.ErrTOTPLocked
+	}
+
+	f.attempts[userID]++
+
+	return nil
+}
+
+func (f *fakeStorage) UseTOTPStep(_ context.Context, userID string, step int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	enrollment := f.totps[userID]
+	if step <= enrollment.LastUsedStep {
+		return storage.ErrTOTPStepUsed
+	}
+	f.attempts[userID] = 0
+
+	enrollment.LastUsedStep = step
+	if enrollment.ConfirmedAt.IsZero() {
+		enrollment.ConfirmedAt = time.Now()
+	}
+	f.totps[userID] = enrollment
+
+	return nil
+}
+
+func (f *fakeStorage) DeleteTOTP(_ context.Context, userID string) error {
+	delete(f.totps, userID)
+
+	return nil
+}
+
+func (f *fakeStorage) SaveMFAChallenge(_ context.Context, challenge models.MFAChallenge) (string, error) {
+	challenge.ID = uuid.New()
+	challenge.Methods = nil
+	f.challenges[challenge.ID] = &fakeChallenge{challenge: challenge}
+
+	return challenge.ID.String(), nil
+}
+
+func (f *fakeStorage) MFAChallenge(_ context.Context, challengeID string) (models.MFAChallenge, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.challenges[uuid.MustParse(challengeID)]
+	if !ok || c.consumed || time.Now().After(c.challenge.ExpiresAt) {
+		return models.MFAChallenge{}, storage.ErrChallengeNotFound
+	}
+
+	return c.challenge, nil
+}
+
+func (f *fakeStorage) UseMFAChallengeAttempt(_ context.Context, challengeID string, maxAttempts int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.challenges[uuid.MustParse(challengeID)]
+	if !ok || c.consumed || c.challenge.Attempts >= maxAttempts {
+		return storage.ErrChallengeNotFound
+	}
+
+	c.challenge.Attempts++
+
+	return nil
+}
+
+func (f *fakeStorage) ConsumeMFAChallenge(_ context.Context, challengeID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.challenges[uuid.MustParse(challengeID)]
+	if !ok || c.consumed {
+		return storage.ErrChallengeNotFound
+	}
+
+	c.consumed = true
+
+	return nil
+}
+
+func (f *fakeStorage) ReplaceRecoveryCodes(_ context.Context, userID string, codeHashes []string) error {
+	f.recovery[userID] = slices.Clone(codeHashes)
+
+	return nil
+}
+
+func (f *fakeStorage) UseRecoveryCode(_ context.Context, userID string, codeHash string) (int, error) {
+	i := slices.Index(f.recovery[userID], codeHash)
+	if i < 0 {
+		return 0, storage.ErrCodeNotFound
+	}
+
+	f.recovery[userID] = slices.Delete(f.recovery[userID], i, i+1)
+
+	return len(f.recovery[userID]), nil
+}
+
+func (f *fakeStorage) EmailMFAEnabled(_ context.Context, userID string) (bool, error) {
+	return f.emailEnabled[userID], nil
+}
+
+func (f *fakeStorage) EnableEmailMFA(_ context.Context, userID string) error {
+	f.emailEnabled[userID] = true
+
+	return nil
+}
+
+func (f *fakeStorage) DisableEmailMFA(_ context.Context, userID string) error {
+	delete(f.emailEnabled, userID)
+
+	return nil
+}
+
+func (f *fakeStorage) SaveEmailCode(_ context.Context, code models.EmailCode) (string, error) {
+	code.ID = uuid.New()
+	code.CreatedAt = time.Now()
+	f.emailCodes = append(f.emailCodes, &fakeEmailCode{code: code})
+
+	return code.ID.String(), nil
+}
+
+func (f *fakeStorage) EmailCodeStats(context.Context, string, time.Time) (int, time.Time, error) {
+	return 0, time.Time{}, nil
+}
+
+func (f *fakeStorage) ActiveEmailCode(_ context.Context, userID string, challengeID uuid.UUID) (models.EmailCode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, c := range slices.Backward(f.emailCodes) {
+		if c.code.UserID.String() == userID && c.code.ChallengeID == challengeID && !c.consumed {
+			return c.code, nil
+		}
+	}
+
+	return models.EmailCode{}, storage.ErrCodeNotFound
+}
+
+func (f *fakeStorage) UseEmailCodeAttempt(_ context.Context, codeID string, maxAttempts int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.emailCode(codeID)
+	if c == nil || c.consumed || c.code.Attempts >= maxAttempts {
+		return storage.ErrCodeNotFound
+	}
+
+	c.code.Attempts++
+
+	return nil
+}
+
+func (f *fakeStorage) ConsumeEmailCode(_ context.Context, codeID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.emailCode(codeID)
+	if c == nil || c.consumed {
+		return storage.ErrCodeNotFound
+	}
+
+	c.consumed = true
+
+	return nil
+}
+
+func (f *fakeStorage) emailCode(codeID string) *fakeEmailCode {
+	for _, c := range f.emailCodes {
+		if c.code.ID.String() == codeID {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) SaveAuditEvent(_ context.Context, event models.AuditEvent) error {
+	f.events = append(f.events, event)
+
+	return nil
+}
@@ -0,0 +1,492 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/pkce"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/lib/signingkey"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/services/mfa"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const responseTypeCode = "code"
+
+// OAuth is an OAuth 2.0 authorization server for the authorization code flow with PKCE,
+// and an OpenID Connect provider on top of it. The client ID of an app is its ID.
+type OAuth struct {
+	log             *slog.Logger
+	authenticator   Authenticator
+	mfaVerifier     MFAVerifier
+	userProvider    UserProvider
+	appProvider     AppProvider
+	codeStorage     CodeStorage
+	loginRecorder   LoginRecorder
+	sessionRevoker  SessionRevoker
+	signingKey      *signingkey.Key
+	issuer          string
+	codeTTL         time.Duration
+	tokenTTL        time.Duration
+	refreshTokenTTL time.Duration
+}
+
+// Authenticator checks credentials the user enters on the hosted login page.
+type Authenticator interface {
+	Authenticate(ctx context.Context, login string, password string, appIDOrName string) (models.User, models.App, models.Authentication, error)
+}
+
+// MFAVerifier completes the second step of logins started on the hosted login page.
+type MFAVerifier interface {
+	SendChallengeEmailCode(ctx context.Context, challengeID string) error
+	VerifyChallenge(ctx context.Context, challengeID string, code string) (models.User, models.App, models.Authentication, error)
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+}
+
+type AppProvider interface {
+	AppByID(ctx context.Context, appID string) (models.App, error)
+}
+
+type CodeStorage interface {
+	SaveAuthorizationCode(ctx context.Context, code models.AuthorizationCode, hash string) error
+	ConsumeAuthorizationCode(ctx context.Context, hash string, now time.Time) (models.AuthorizationCode, error)
+}
+
+type LoginRecorder interface {
+	RecordLogin(ctx context.Context, userID string, appID string) error
+}
+
+type SessionRevoker interface {
+	RevokeSessions(ctx context.Context, userID uuid.UUID) error
+}
+
+var (
+	// ErrInvalidClient and ErrInvalidRedirectURI must be shown to the user:
+	// redirecting to an unverified URI would make the server an open redirector.
+	ErrInvalidClient      = errors.New("invalid client")
+	ErrInvalidRedirectURI = errors.New("invalid redirect uri")
+
+	ErrInvalidRequest          = errors.New("invalid authorization request")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrInvalidScope            = errors.New("invalid scope")
+	ErrInvalidCredentials      = errors.New("invalid credentials")
+	ErrInvalidCode             = errors.New("invalid code")
+	ErrInvalidChallenge        = errors.New("invalid or expired challenge")
+	ErrEnrollmentRequired      = errors.New("second factor enrollment required")
+	ErrAccessDenied            = errors.New("access denied")
+	ErrInvalidGrant            = errors.New("invalid grant")
+)
+
+// MFARequiredError is returned by Authorize when the password is correct, but the user has
+// to complete the challenge with AuthorizeMFA to get a code.
+type MFARequiredError struct {
+	ChallengeID string
+	// EmailSent is set if a code was emailed to the user, who has no authenticator app enrolled.
+	EmailSent bool
+}
+
+func (e *MFARequiredError) Error() string {
+	return "mfa required"
+}
+
+// New returns a new instance of the OAuth service. Authorization codes live for codeTTL.
+// ID tokens are signed with signingKey and name issuer, the URL the HTTP endpoints are served at.
+func New(
+	log *slog.Logger,
+	authenticator Authenticator,
+	mfaVerifier MFAVerifier,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	codeStorage CodeStorage,
+	loginRecorder LoginRecorder,
+	sessionRevoker SessionRevoker,
+	signingKey *signingkey.Key,
+	issuer string,
+	codeTTL time.Duration,
+	tokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+) *OAuth {
+	return &OAuth{
+		log:             log,
+		authenticator:   authenticator,
+		mfaVerifier:     mfaVerifier,
+		userProvider:    userProvider,
+		appProvider:     appProvider,
+		codeStorage:     codeStorage,
+		loginRecorder:   loginRecorder,
+		sessionRevoker:  sessionRevoker,
+		signingKey:      signingKey,
+		issuer:          strings.TrimSuffix(issuer, "/"),
+		codeTTL:         codeTTL,
+		tokenTTL:        tokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+// ValidateAuthorization checks the authorization request and returns the client's app and the
+// request with defaults filled in. Redirect URI can be omitted if the app has exactly one.
+// The request is returned on errors too: unless the error is ErrInvalidClient or
+// ErrInvalidRedirectURI, its redirect URI is verified and the error can be sent there.
+func (o *OAuth) ValidateAuthorization(ctx context.Context, req models.AuthorizationRequest) (models.App, models.AuthorizationRequest, error) {
+	const op = "oauth.ValidateAuthorization"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", req.ClientID),
+	)
+
+	app, req, err := o.validate(ctx, log, req)
+	if err != nil {
+		return app, req, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, req, nil
+}
+
+// Authorize logs the user into the client's app with the credentials entered on the hosted
+// login page and returns an authorization code to redirect the user back with.
+//
+// Accounts that need a second factor get *MFARequiredError, whose challenge AuthorizeMFA
+// completes. The hosted page can't enroll a second factor, so users who have yet to enroll
+// get ErrEnrollmentRequired.
+func (o *OAuth) Authorize(ctx context.Context, req models.AuthorizationRequest, login string, password string) (string, error) {
+	const op = "oauth.Authorize"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", req.ClientID),
+	)
+
+	log.Info("authorizing client")
+
+	app, req, err := o.validate(ctx, log, req)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, _, authentication, err := o.authenticator.Authenticate(ctx, login, password, app.ID.String())
+	if err != nil {
+		var mfaErr *auth.MFARequiredError
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		case errors.As(err, &mfaErr):
+			return "", fmt.Errorf("%s: %w", op, o.mfaRequired(ctx, log, mfaErr))
+		case errors.Is(err, auth.ErrUserInactive), errors.Is(err, auth.ErrAppDisabled), errors.Is(err, auth.ErrAppAccessDenied):
+			return "", fmt.Errorf("%s: %w", op, ErrAccessDenied)
+		}
+
+		log.Error("failed to authenticate user", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	code, err := o.issueCode(ctx, log, app, user, req, authentication)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return code, nil
+}
+
+// AuthorizeMFA completes the challenge Authorize returned with a TOTP, emailed or recovery code
+// and returns an authorization code to redirect the user back with. ErrInvalidCode leaves the
+// challenge open for another attempt; after ErrInvalidChallenge the user has to log in again.
+func (o *OAuth) AuthorizeMFA(ctx context.Context, req models.AuthorizationRequest, challengeID string, code string) (string, error) {
+	const op = "oauth.AuthorizeMFA"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", req.ClientID),
+		slog.String("challenge_id", challengeID),
+	)
+
+	log.Info("authorizing client with second factor")
+
+	app, req, err := o.validate(ctx, log, req)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, challengeApp, authentication, err := o.mfaVerifier.VerifyChallenge(ctx, challengeID, code)
+	if err != nil {
+		switch {
+		// A locked TOTP leaves the challenge open for an emailed or recovery code.
+		case errors.Is(err, mfa.ErrInvalidCode), errors.Is(err, mfa.ErrTooManyAttempts):
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		case errors.Is(err, mfa.ErrInvalidChallenge):
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+		case errors.Is(err, mfa.ErrEnrollmentRequired):
+			return "", fmt.Errorf("%s: %w", op, ErrEnrollmentRequired)
+		case errors.Is(err, mfa.ErrUserInactive):
+			return "", fmt.Errorf("%s: %w", op, ErrAccessDenied)
+		}
+
+		log.Error("failed to verify mfa challenge", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The challenge is consumed either way, so one issued for another app can't be replayed.
+	if challengeApp.ID != app.ID {
+		log.Warn("challenge was issued for another app", slog.String("app_id", challengeApp.ID.String()))
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+	}
+
+	authCode, err := o.issueCode(ctx, log, app, user, req, authentication)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return authCode, nil
+}
+
+// mfaRequired turns the challenge of the login into the error Authorize returns, emailing
+// a code to users who have no authenticator app to take it from.
+func (o *OAuth) mfaRequired(ctx context.Context, log *slog.Logger, mfaErr *auth.MFARequiredError) error {
+	if mfaErr.EnrollmentRequired {
+		log.Warn("user has to enroll a second factor first")
+
+		return ErrEnrollmentRequired
+	}
+
+	required := &MFARequiredError{ChallengeID: mfaErr.ChallengeID}
+	if slices.Contains(mfaErr.Methods, models.MFAMethodEmail) && !slices.Contains(mfaErr.Methods, models.MFAMethodTOTP) {
+		// Recovery codes still work if the email doesn't arrive.
+		if err := o.mfaVerifier.SendChallengeEmailCode(ctx, mfaErr.ChallengeID); err != nil {
+			log.Error("failed to email mfa code", sl.Err(err))
+		} else {
+			required.EmailSent = true
+		}
+	}
+
+	log.Info("second factor required", slog.Bool("email_sent", required.EmailSent))
+
+	return required
+}
+
+// issueCode saves an authorization code for the user logged into the app by the request.
+func (o *OAuth) issueCode(
+	ctx context.Context,
+	log *slog.Logger,
+	app models.App,
+	user models.User,
+	req models.AuthorizationRequest,
+	authentication models.Authentication,
+) (string, error) {
+	log = log.With(slog.String("user_id", user.ID.String()))
+
+	code, err := randtoken.Generate("")
+	if err != nil {
+		log.Error("failed to generate authorization code", sl.Err(err))
+
+		return "", err
+	}
+
+	redirectURI := req.RedirectURI
+	if req.RedirectURIDefaulted {
+		redirectURI = ""
+	}
+
+	err = o.codeStorage.SaveAuthorizationCode(ctx, models.AuthorizationCode{
+		AppID:         app.ID,
+		UserID:        user.ID,
+		RedirectURI:   redirectURI,
+		CodeChallenge: req.CodeChallenge,
+		Scopes:        req.Scopes,
+		Nonce:         req.Nonce,
+		Auth:          authentication,
+		ExpiresAt:     time.Now().Add(o.codeTTL),
+	}, randtoken.Hash(code))
+	if err != nil {
+		log.Error("failed to save authorization code", sl.Err(err))
+
+		return "", err
+	}
+
+	log.Info("authorization code issued")
+
+	return code, nil
+}
+
+// ExchangeCode exchanges the authorization code for a token pair of the user, and an ID token
+// if the openid scope was granted. The redirect URI must be the one the code was issued for,
+// unless the authorization request omitted it, and the verifier must match the PKCE challenge.
+func (o *OAuth) ExchangeCode(
+	ctx context.Context,
+	clientID string,
+	code string,
+	redirectURI string,
+	codeVerifier string,
+) (models.OAuthTokens, error) {
+	const op = "oauth.ExchangeCode"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	issued, err := o.codeStorage.ConsumeAuthorizationCode(ctx, randtoken.Hash(code), time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrAuthCodeNotFound) {
+			log.Warn("authorization code is unknown, expired or used", sl.Err(err))
+
+			return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+
+		log.Error("failed to consume authorization code", sl.Err(err))
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("user_id", issued.UserID.String()))
+
+	if issued.AppID.String() != clientID || (issued.RedirectURI != "" && issued.RedirectURI != redirectURI) {
+		log.Warn("authorization code was issued to another client or redirect uri")
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	if !pkce.Verify(codeVerifier, issued.CodeChallenge) {
+		log.Warn("code verifier does not match the challenge")
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	app, err := o.appProvider.AppByID(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := o.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: issued.UserID.String()})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The user or the app could have been blocked since the code was issued.
+	if !app.Enabled || !user.IsActive(time.Now()) {
+		log.Warn("app is disabled or user is not active")
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	// Scopes ride along in the tokens, so userinfo knows what the client may read.
+	authentication := issued.Auth
+	authentication.Scopes = issued.Scopes
+
+	accessToken, refreshToken, err := jwt.NewTokenPair(user, app, authentication, o.tokenTTL, o.refreshTokenTTL)
+	if err != nil {
+		log.Error("failed to generate tokens", sl.Err(err))
+
+		return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var idToken string
+	if slices.Contains(issued.Scopes, ScopeOpenID) {
+		idToken, err = o.newIDToken(user, app, issued)
+		if err != nil {
+			log.Error("failed to generate id token", sl.Err(err))
+
+			return models.OAuthTokens{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// Login stats are best effort and must not block the user.
+	if err := o.loginRecorder.RecordLogin(ctx, user.ID.String(), app.ID.String()); err != nil {
+		log.Error("failed to record login", sl.Err(err))
+	}
+
+	log.Info("authorization code exchanged")
+
+	return models.OAuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		IDToken:      idToken,
+		Scopes:       issued.Scopes,
+		ExpiresIn:    o.tokenTTL,
+	}, nil
+}
+
+func (o *OAuth) validate(
+	ctx context.Context,
+	log *slog.Logger,
+	req models.AuthorizationRequest,
+) (models.App, models.AuthorizationRequest, error) {
+	if uuid.Validate(req.ClientID) != nil {
+		log.Warn("malformed client id")
+
+		return models.App{}, req, ErrInvalidClient
+	}
+
+	app, err := o.appProvider.AppByID(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("client not found", sl.Err(err))
+
+			return models.App{}, req, ErrInvalidClient
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.App{}, req, err
+	}
+
+	if req.RedirectURI == "" && len(app.RedirectURIs) == 1 {
+		req.RedirectURI = app.RedirectURIs[0]
+		req.RedirectURIDefaulted = true
+	}
+	if !slices.Contains(app.RedirectURIs, req.RedirectURI) {
+		log.Warn("redirect uri is not registered", slog.String("redirect_uri", req.RedirectURI))
+
+		return models.App{}, req, ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != responseTypeCode {
+		return app, req, ErrUnsupportedResponseType
+	}
+
+	if err := validateScopes(req.Scopes); err != nil {
+		log.Warn("unsupported scopes requested", sl.Err(err))
+
+		return app, req, err
+	}
+
+	if req.CodeChallengeMethod != pkce.MethodS256 || !pkce.ValidChallenge(req.CodeChallenge) {
+		log.Warn("missing or malformed pkce challenge")
+
+		return app, req, fmt.Errorf("%w: S256 code challenge required", ErrInvalidRequest)
+	}
+
+	if !app.Enabled {
+		log.Warn("app is disabled")
+
+		return app, req, ErrAccessDenied
+	}
+
+	return app, req, nil
+}
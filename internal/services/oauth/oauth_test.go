@@ -0,0 +1,207 @@
+package oauth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/pkce"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/services/mfa"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURI = "https://app.example.com/callback"
+
+func TestAuthorize_MFA(t *testing.T) {
+	tests := []struct {
+		name      string
+		methods   []models.MFAMethod
+		wantEmail bool
+	}{
+		{name: "totp", methods: []models.MFAMethod{models.MFAMethodTOTP}},
+		{name: "totp and email", methods: []models.MFAMethod{models.MFAMethodTOTP, models.MFAMethodEmail}},
+		{name: "email only", methods: []models.MFAMethod{models.MFAMethodEmail}, wantEmail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.authenticator.err = &auth.MFARequiredError{ChallengeID: "challenge", Methods: tt.methods}
+
+			_, err := env.service.Authorize(context.Background(), env.request(), "alice", "password")
+
+			var mfaErr *MFARequiredError
+			require.ErrorAs(t, err, &mfaErr)
+			assert.Equal(t, "challenge", mfaErr.ChallengeID)
+			assert.Equal(t, tt.wantEmail, mfaErr.EmailSent)
+			if tt.wantEmail {
+				assert.Equal(t, []string{"challenge"}, env.mfa.emailed)
+			} else {
+				assert.Empty(t, env.mfa.emailed)
+			}
+		})
+	}
+}
+
+func TestAuthorize_EnrollmentRequired(t *testing.T) {
+	env := newTestEnv(t)
+	env.authenticator.err = &auth.MFARequiredError{ChallengeID: "challenge", EnrollmentRequired: true}
+
+	_, err := env.service.Authorize(context.Background(), env.request(), "alice", "password")
+	assert.ErrorIs(t, err, ErrEnrollmentRequired)
+}
+
+func TestAuthorizeMFA(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	code, err := env.service.AuthorizeMFA(ctx, env.request(), "challenge", "123456")
+	require.NoError(t, err)
+
+	issued, ok := env.codes.codes[randtoken.Hash(code)]
+	require.True(t, ok, "codes are stored hashed")
+	assert.Equal(t, env.user.ID, issued.UserID)
+	assert.Contains(t, issued.Auth.Methods, models.AuthMethodOTP)
+}
+
+func TestAuthorizeMFA_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		otherApp bool
+		want     error
+	}{
+		{name: "wrong code", err: mfa.ErrInvalidCode, want: ErrInvalidCode},
+		{name: "expired challenge", err: mfa.ErrInvalidChallenge, want: ErrInvalidChallenge},
+		{name: "not enrolled", err: mfa.ErrEnrollmentRequired, want: ErrEnrollmentRequired},
+		{name: "user blocked since", err: mfa.ErrUserInactive, want: ErrAccessDenied},
+		{name: "challenge of another app", otherApp: true, want: ErrInvalidChallenge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.mfa.err = tt.err
+			if tt.otherApp {
+				env.mfa.app = models.App{ID: uuid.New(), Enabled: true}
+			}
+
+			_, err := env.service.AuthorizeMFA(context.Background(), env.request(), "challenge", "123456")
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, env.codes.codes)
+		})
+	}
+}
+
+type testEnv struct {
+	service       *OAuth
+	authenticator *fakeAuthenticator
+	mfa           *fakeMFA
+	codes         *fakeStorage
+	user          models.User
+	app           models.App
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com", Status: models.UserStatusActive}
+	app := models.App{ID: uuid.New(), Name: "reports", Enabled: true, RedirectURIs: []string{testRedirectURI}}
+
+	authenticator := &fakeAuthenticator{user: user, app: app}
+	verifier := &fakeMFA{user: user, app: app}
+	fake := &fakeStorage{app: app, codes: map[string]models.AuthorizationCode{}}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testEnv{
+		service:       New(log, authenticator, verifier, nil, fake, fake, nil, nil, nil, "https://sso.example.com", time.Minute, time.Hour, time.Hour),
+		authenticator: authenticator,
+		mfa:           verifier,
+		codes:         fake,
+		user:          user,
+		app:           app,
+	}
+}
+
+func (env *testEnv) request() models.AuthorizationRequest {
+	return models.AuthorizationRequest{
+		ClientID:            env.app.ID.String(),
+		RedirectURI:         testRedirectURI,
+		ResponseType:        responseTypeCode,
+		CodeChallenge:       pkce.Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"),
+		CodeChallengeMethod: pkce.MethodS256,
+	}
+}
+
+type fakeAuthenticator struct {
+	user models.User
+	app  models.App
+	err  error
+}
+
+func (f *fakeAuthenticator) Authenticate(context.Context, string, string, string) (models.User, models.App, models.Authentication, error) {
+	if f.err != nil {
+		return models.User{}, models.App{}, models.Authentication{}, f.err
+	}
+
+	return f.user, f.app, models.NewAuthentication(models.AuthMethodPassword), nil
+}
+
+type fakeMFA struct {
+	user    models.User
+	app     models.App
+	err     error
+	emailed []string
+}
+
+func (f *fakeMFA) SendChallengeEmailCode(_ context.Context, challengeID string) error {
+	f.emailed = append(f.emailed, challengeID)
+
+	return nil
+}
+
+func (f *fakeMFA) VerifyChallenge(context.Context, string, string) (models.User, models.App, models.Authentication, error) {
+	if f.err != nil {
+		return models.User{}, models.App{}, models.Authentication{}, f.err
+	}
+
+	return f.user, f.app, models.NewAuthentication(models.AuthMethodPassword, models.AuthMethodOTP), nil
+}
+
+type fakeStorage struct {
+	app   models.App
+	codes map[string]models.AuthorizationCode
+}
+
+func (f *fakeStorage) AppByID(_ context.Context, appID string) (models.App, error) {
+	if appID != f.app.ID.String() {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return f.app, nil
+}
+
+func (f *fakeStorage) SaveAuthorizationCode(_ context.Context, code models.AuthorizationCode, hash string) error {
+	f.codes[hash] = code
+
+	return nil
+}
+
+func (f *fakeStorage) ConsumeAuthorizationCode(_ context.Context, hash string, _ time.Time) (models.AuthorizationCode, error) {
+	code, ok := f.codes[hash]
+	if !ok {
+		return models.AuthorizationCode{}, storage.ErrAuthCodeNotFound
+	}
+	delete(f.codes, hash)
+
+	return code, nil
+}
@@ -0,0 +1,250 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/signingkey"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// Scopes of OpenID Connect Core, section 5.4, the only ones the authorization code flow grants.
+const (
+	ScopeOpenID  = "openid"
+	ScopeEmail   = "email"
+	ScopeProfile = "profile"
+)
+
+var SupportedScopes = []string{ScopeOpenID, ScopeEmail, ScopeProfile}
+
+var (
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
+// Issuer returns the issuer identifier of ID tokens, the URL the HTTP endpoints are served at.
+func (o *OAuth) Issuer() string {
+	return o.issuer
+}
+
+// JWKS returns the public keys clients verify ID tokens with.
+func (o *OAuth) JWKS() signingkey.JWKS {
+	return o.signingKey.JWKS()
+}
+
+// UserInfo returns claims about the user the access token was issued to, limited to the scopes
+// the user granted. Tokens without the openid scope, such as those of first-party logins,
+// get ErrInsufficientScope.
+func (o *OAuth) UserInfo(ctx context.Context, accessToken string) (map[string]any, error) {
+	const op = "oauth.UserInfo"
+
+	log := o.log.With(
+		slog.String("op", op),
+	)
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	log = log.With(slog.String("user_id", claims.UserID))
+
+	if !slices.Contains(claims.Auth.Scopes, ScopeOpenID) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInsufficientScope)
+	}
+
+	user, err := o.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: claims.UserID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !user.IsActive(time.Now()) || claims.IssuedAt.Before(user.SessionsRevokedAt) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	return userClaims(user, claims.Auth.Scopes), nil
+}
+
+// Logout handles RP-initiated logout: it ends sessions of the user the ID token hint was issued
+// to and returns the request with the client ID taken from the hint if it was omitted.
+// A post logout redirect URI is returned only if it is registered for the client.
+//
+// The hosted login page keeps no session, so the user's sessions are their refresh tokens,
+// and logging out of one client revokes the tokens of all apps (single logout).
+func (o *OAuth) Logout(ctx context.Context, req models.LogoutRequest) (models.LogoutRequest, error) {
+	const op = "oauth.Logout"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", req.ClientID),
+	)
+
+	var (
+		hint    jwt.IDTokenHint
+		hasHint = req.IDTokenHint != ""
+		err     error
+	)
+	if hasHint {
+		hint, err = jwt.ParseIDTokenHint(o.signingKey, o.issuer, req.IDTokenHint)
+		if err != nil {
+			log.Warn("invalid id token hint")
+
+			return req, fmt.Errorf("%s: %w: invalid id_token_hint", op, ErrInvalidRequest)
+		}
+
+		if req.ClientID == "" {
+			req.ClientID = hint.Audience[0]
+		} else if !slices.Contains(hint.Audience, req.ClientID) {
+			log.Warn("id token hint was issued to another client")
+
+			return req, fmt.Errorf("%s: %w: id_token_hint was issued to another client", op, ErrInvalidRequest)
+		}
+	}
+
+	if req.PostLogoutRedirectURI != "" {
+		if err := o.checkPostLogoutRedirectURI(ctx, log, req); err != nil {
+			return req, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if hasHint {
+		if err := o.endSessions(ctx, log, hint); err != nil {
+			return req, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return req, nil
+}
+
+func (o *OAuth) checkPostLogoutRedirectURI(ctx context.Context, log *slog.Logger, req models.LogoutRequest) error {
+	if req.ClientID == "" {
+		return fmt.Errorf("%w: client_id or id_token_hint required with post_logout_redirect_uri", ErrInvalidRequest)
+	}
+	if uuid.Validate(req.ClientID) != nil {
+		return ErrInvalidClient
+	}
+
+	app, err := o.appProvider.AppByID(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("client not found", sl.Err(err))
+
+			return ErrInvalidClient
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return err
+	}
+
+	if !slices.Contains(app.PostLogoutRedirectURIs, req.PostLogoutRedirectURI) {
+		log.Warn("post logout redirect uri is not registered", slog.String("redirect_uri", req.PostLogoutRedirectURI))
+
+		return ErrInvalidRedirectURI
+	}
+
+	return nil
+}
+
+// endSessions revokes sessions of the user the hint was issued to, unless they already ended:
+// by revocation after the hint was issued, or by the refresh token TTL passing since.
+func (o *OAuth) endSessions(ctx context.Context, log *slog.Logger, hint jwt.IDTokenHint) error {
+	log = log.With(slog.String("user_id", hint.Subject))
+
+	if time.Since(hint.IssuedAt) > o.refreshTokenTTL {
+		log.Info("session of the id token hint has expired")
+
+		return nil
+	}
+
+	user, err := o.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: hint.Subject})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return err
+	}
+
+	if hint.IssuedAt.Before(user.SessionsRevokedAt) {
+		log.Info("sessions already ended")
+
+		return nil
+	}
+
+	if err := o.sessionRevoker.RevokeSessions(ctx, user.ID); err != nil {
+		log.Error("failed to revoke sessions", sl.Err(err))
+
+		return err
+	}
+
+	log.Info("user logged out")
+
+	return nil
+}
+
+// newIDToken returns an ID token of the user for the client the code was issued to
+// (OpenID Connect Core, section 2).
+func (o *OAuth) newIDToken(user models.User, app models.App, code models.AuthorizationCode) (string, error) {
+	now := time.Now()
+
+	claims := userClaims(user, code.Scopes)
+	claims["iss"] = o.issuer
+	claims["aud"] = app.ID.String()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(o.tokenTTL).Unix()
+	claims["auth_time"] = code.Auth.Time.Unix()
+	claims["amr"] = code.Auth.Methods
+	claims["acr"] = code.Auth.ACR()
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+
+	return jwt.NewIDToken(o.signingKey, claims)
+}
+
+// userClaims returns standard claims of the user the scopes allow (OpenID Connect Core, section 5.4).
+// email_verified is left out: the service does not verify email addresses.
+func userClaims(user models.User, scopes []string) map[string]any {
+	claims := map[string]any{"sub": user.ID.String()}
+
+	if slices.Contains(scopes, ScopeEmail) {
+		claims["email"] = user.Email
+	}
+
+	if slices.Contains(scopes, ScopeProfile) {
+		if user.Username != "" {
+			claims["preferred_username"] = user.Username
+		}
+		if !user.UpdatedAt.IsZero() {
+			claims["updated_at"] = user.UpdatedAt.Unix()
+		}
+	}
+
+	return claims
+}
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !slices.Contains(SupportedScopes, scope) {
+			return fmt.Errorf("%w: %q is not supported", ErrInvalidScope, scope)
+		}
+	}
+
+	return nil
+}
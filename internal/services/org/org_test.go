@@ -0,0 +1,477 @@
+package org
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitation_Lifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	owner := env.addUser(models.UserStatusActive)
+	invitee := env.addUser(models.UserStatusActive)
+
+	org, err := env.service.CreateOrganization(ctx, env.adminToken, "Acme", owner.ID.String())
+	require.NoError(t, err)
+	orgID := org.ID.String()
+
+	ownerToken := env.orgToken(owner, orgID)
+	require.NoError(t, env.service.AddMember(ctx, ownerToken, orgID, invitee.ID.String(), models.OrgRoleMember))
+
+	// Until the invitee accepts, they are no member and the organization sees only their ID.
+	_, _, err = env.service.LoginToOrganization(ctx, env.token(invitee), orgID)
+	assert.ErrorIs(t, err, ErrNotMember)
+
+	orgs, err := env.service.UserOrganizations(ctx, env.token(invitee))
+	require.NoError(t, err)
+	assert.Empty(t, orgs)
+
+	invitations, err := env.service.Invitations(ctx, env.token(invitee))
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, org.ID, invitations[0].ID)
+
+	pending := env.member(ctx, ownerToken, orgID, invitee.ID)
+	assert.True(t, pending.Pending())
+	assert.Equal(t, models.User{ID: invitee.ID}, pending.User)
+
+	require.NoError(t, env.service.AcceptInvitation(ctx, env.token(invitee), orgID))
+	assert.ErrorIs(t, env.service.AcceptInvitation(ctx, env.token(invitee), orgID), ErrNotInvited)
+
+	accepted := env.member(ctx, ownerToken, orgID, invitee.ID)
+	assert.False(t, accepted.Pending())
+	assert.Equal(t, invitee.Email, accepted.User.Email)
+
+	access, _, err := env.service.LoginToOrganization(ctx, env.token(invitee), orgID)
+	require.NoError(t, err)
+	claims, err := jwt.ParseAccessToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, org.ID, claims.Auth.OrgID)
+
+	events := make([]string, 0, len(env.storage.events))
+	for _, event := range env.storage.events {
+		events = append(events, event.Event)
+	}
+	assert.Equal(t, []string{eventOrgCreated, eventOrgMemberInvited, eventOrgInvitationAccepted}, events)
+}
+
+func TestLoginToOrganization_AppAccess(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	org, err := env.service.CreateOrganization(ctx, env.adminToken, "Acme", user.ID.String())
+	require.NoError(t, err)
+	orgID := org.ID.String()
+
+	_, _, err = env.service.LoginToOrganization(ctx, env.token(user), orgID)
+	require.NoError(t, err)
+
+	// Switching organizations mints a session, so it is held to the same app checks as a login.
+	env.storage.app.AccessMode = models.AppAccessMembers
+
+	_, _, err = env.service.LoginToOrganization(ctx, env.token(user), orgID)
+	assert.ErrorIs(t, err, ErrAppAccessDenied)
+
+	env.storage.appMembers[user.ID] = true
+
+	_, _, err = env.service.LoginToOrganization(ctx, env.token(user), orgID)
+	require.NoError(t, err)
+
+	env.storage.app.Enabled = false
+
+	_, _, err = env.service.LoginToOrganization(ctx, env.token(user), orgID)
+	assert.ErrorIs(t, err, ErrAppDisabled)
+}
+
+func TestInvitation_Withdrawn(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	owner := env.addUser(models.UserStatusActive)
+	invitee := env.addUser(models.UserStatusActive)
+
+	org, err := env.service.CreateOrganization(ctx, env.adminToken, "Acme", owner.ID.String())
+	require.NoError(t, err)
+	orgID := org.ID.String()
+
+	require.NoError(t, env.service.AddMember(ctx, env.adminToken, orgID, invitee.ID.String(), models.OrgRoleAdmin))
+	assert.ErrorIs(t, env.service.AddMember(ctx, env.adminToken, orgID, invitee.ID.String(), models.OrgRoleMember), ErrMemberExists)
+
+	// A pending invitation neither grants the role nor counts as another admin.
+	assert.ErrorIs(t, env.service.SetMemberRole(ctx, env.adminToken, orgID, invitee.ID.String(), models.OrgRoleMember), ErrNotMember)
+	assert.ErrorIs(t, env.service.RemoveMember(ctx, env.adminToken, orgID, owner.ID.String()), ErrLastAdmin)
+
+	require.NoError(t, env.service.RemoveMember(ctx, env.adminToken, orgID, invitee.ID.String()))
+	assert.ErrorIs(t, env.service.AcceptInvitation(ctx, env.token(invitee), orgID), ErrNotInvited)
+}
+
+func TestManageMembers_Authorization(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	owner := env.addUser(models.UserStatusActive)
+	member := env.addUser(models.UserStatusActive)
+	otherOwner := env.addUser(models.UserStatusActive)
+	target := env.addUser(models.UserStatusActive)
+
+	org, err := env.service.CreateOrganization(ctx, env.adminToken, "Acme", owner.ID.String())
+	require.NoError(t, err)
+	orgID := org.ID.String()
+
+	other, err := env.service.CreateOrganization(ctx, env.adminToken, "Globex", otherOwner.ID.String())
+	require.NoError(t, err)
+
+	require.NoError(t, env.service.AddMember(ctx, env.adminToken, orgID, member.ID.String(), models.OrgRoleMember))
+	require.NoError(t, env.service.AcceptInvitation(ctx, env.token(member), orgID))
+
+	suspended := env.addUser(models.UserStatusSuspended)
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "global admin", token: env.adminToken},
+		{name: "org admin", token: env.orgToken(owner, orgID)},
+		{name: "org admin without org-scoped token", token: env.token(owner), want: ErrPermissionDenied},
+		{name: "admin of another org", token: env.orgToken(otherOwner, other.ID.String()), want: ErrPermissionDenied},
+		{name: "admin of another org scoped to this one", token: env.orgToken(otherOwner, orgID), want: ErrPermissionDenied},
+		{name: "plain member", token: env.orgToken(member, orgID), want: ErrPermissionDenied},
+		{name: "suspended user", token: env.token(suspended), want: ErrPermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := env.service.ListMembers(ctx, tt.token, orgID)
+			if tt.want == nil {
+				require.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.want)
+			}
+
+			err = env.service.AddMember(ctx, tt.token, orgID, target.ID.String(), models.OrgRoleMember)
+			if tt.want == nil {
+				require.NoError(t, err)
+				require.NoError(t, env.service.RemoveMember(ctx, tt.token, orgID, target.ID.String()))
+			} else {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOrganization_Rejected(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	_, err := env.service.CreateOrganization(ctx, env.token(user), "Acme", user.ID.String())
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = env.service.CreateOrganization(ctx, env.adminToken, " Acme", user.ID.String())
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	_, err = env.service.CreateOrganization(ctx, env.adminToken, "Acme", user.ID.String())
+	require.NoError(t, err)
+	_, err = env.service.CreateOrganization(ctx, env.adminToken, "Acme", user.ID.String())
+	assert.ErrorIs(t, err, ErrOrgExists)
+}
+
+type testEnv struct {
+	t          *testing.T
+	service    *Org
+	storage    *fakeStorage
+	app        models.App
+	adminToken string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	app := models.App{ID: uuid.New(), Name: "test-app", Enabled: true}
+
+	fake := &fakeStorage{
+		users:   map[uuid.UUID]models.User{},
+		admins:  map[uuid.UUID]bool{},
+		app:     app,
+		orgs:    map[uuid.UUID]models.Organization{},
+		members: map[uuid.UUID]map[uuid.UUID]models.OrgMember{},
+
+		appMembers: map[uuid.UUID]bool{},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	env := &testEnv{
+		t:       t,
+		service: New(log, fake, fake, fake, fake, time.Hour, time.Hour),
+		storage: fake,
+		app:     app,
+	}
+
+	admin := env.addUser(models.UserStatusActive)
+	fake.admins[admin.ID] = true
+	env.adminToken = env.token(admin)
+
+	return env
+}
+
+func (e *testEnv) addUser(status models.UserStatus) models.User {
+	user := models.User{ID: uuid.New(), Status: status}
+	user.Email = user.ID.String() + "@example.com"
+	e.storage.users[user.ID] = user
+
+	return user
+}
+
+func (e *testEnv) token(user models.User) string {
+	return e.orgToken(user, "")
+}
+
+// orgToken returns an access token scoped to the organization, or a regular one for empty orgID.
+func (e *testEnv) orgToken(user models.User, orgID string) string {
+	e.t.Helper()
+
+	auth := models.NewAuthentication(models.AuthMethodPassword)
+	if orgID != "" {
+		auth.OrgID = uuid.MustParse(orgID)
+	}
+
+	accessToken, _, err := jwt.NewTokenPair(user, e.app, auth, time.Hour, time.Hour)
+	require.NoError(e.t, err)
+
+	return accessToken
+}
+
+// member returns the membership of the user as ListMembers shows it.
+func (e *testEnv) member(ctx context.Context, adminToken string, orgID string, userID uuid.UUID) models.OrgMember {
+	e.t.Helper()
+
+	members, err := e.service.ListMembers(ctx, adminToken, orgID)
+	require.NoError(e.t, err)
+
+	for _, member := range members {
+		if member.User.ID == userID {
+			return member
+		}
+	}
+	e.t.Fatalf("user %s is not listed", userID)
+
+	return models.OrgMember{}
+}
+
+// fakeStorage keeps users, organizations and memberships in memory, following the
+// invitation rules of the Postgres storage.
+type fakeStorage struct {
+	users   map[uuid.UUID]models.User
+	admins  map[uuid.UUID]bool
+	app     models.App
+	orgs    map[uuid.UUID]models.Organization
+	members map[uuid.UUID]map[uuid.UUID]models.OrgMember
+	events  []models.AuditEvent
+
+	// appMembers are users added to the app.
+	appMembers map[uuid.UUID]bool
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	user, ok := f.users[parseID(identifier.Value)]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeStorage) IsAdmin(_ context.Context, userID string, _ uuid.UUID) (bool, error) {
+	return f.admins[parseID(userID)], nil
+}
+
+func (f *fakeStorage) AppByID(_ context.Context, appID string) (models.App, error) {
+	if appID != f.app.ID.String() {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return f.app, nil
+}
+
+func (f *fakeStorage) IsAppMember(_ context.Context, _ uuid.UUID, userID uuid.UUID) (bool, error) {
+	return f.appMembers[userID], nil
+}
+
+func (f *fakeStorage) CreateOrganization(_ context.Context, org models.Organization, adminID string) (models.Organization, error) {
+	for _, existing := range f.orgs {
+		if existing.Name == org.Name {
+			return models.Organization{}, storage.ErrOrgExists
+		}
+	}
+
+	org.ID = uuid.New()
+	org.CreatedAt = time.Now()
+	f.orgs[org.ID] = org
+	f.members[org.ID] = map[uuid.UUID]models.OrgMember{}
+
+	if err := f.add(org.ID, adminID, models.OrgRoleAdmin, true); err != nil {
+		return models.Organization{}, err
+	}
+
+	return org, nil
+}
+
+func (f *fakeStorage) Organization(_ context.Context, orgID string) (models.Organization, error) {
+	org, ok := f.orgs[parseID(orgID)]
+	if !ok {
+		return models.Organization{}, storage.ErrOrgNotFound
+	}
+
+	return org, nil
+}
+
+func (f *fakeStorage) UserOrganizations(_ context.Context, userID string) ([]models.Organization, error) {
+	return f.userOrgs(userID, true), nil
+}
+
+func (f *fakeStorage) UserOrgInvitations(_ context.Context, userID string) ([]models.Organization, error) {
+	return f.userOrgs(userID, false), nil
+}
+
+func (f *fakeStorage) AcceptOrgInvitation(_ context.Context, orgID string, userID string) error {
+	members := f.members[parseID(orgID)]
+
+	member, ok := members[parseID(userID)]
+	if !ok || !member.Pending() {
+		return storage.ErrOrgMemberNotFound
+	}
+	member.AcceptedAt = time.Now()
+	members[member.User.ID] = member
+
+	return nil
+}
+
+func (f *fakeStorage) OrgMember(_ context.Context, orgID string, userID string) (models.OrgMember, error) {
+	member, ok := f.members[parseID(orgID)][parseID(userID)]
+	if !ok || member.Pending() {
+		return models.OrgMember{}, storage.ErrOrgMemberNotFound
+	}
+
+	return member, nil
+}
+
+func (f *fakeStorage) OrgMembers(_ context.Context, orgID string) ([]models.OrgMember, error) {
+	var members []models.OrgMember
+	for _, member := range f.members[parseID(orgID)] {
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+func (f *fakeStorage) AddOrgMember(_ context.Context, orgID string, userID string, role models.OrgRole) error {
+	return f.add(parseID(orgID), userID, role, false)
+}
+
+func (f *fakeStorage) SetOrgMemberRole(_ context.Context, orgID string, userID string, role models.OrgRole) error {
+	members := f.members[parseID(orgID)]
+
+	member, ok := members[parseID(userID)]
+	if !ok || member.Pending() {
+		return storage.ErrOrgMemberNotFound
+	}
+	if role != models.OrgRoleAdmin && f.lastAdmin(members, member.User.ID) {
+		return storage.ErrLastOrgAdmin
+	}
+	member.Role = role
+	members[member.User.ID] = member
+
+	return nil
+}
+
+func (f *fakeStorage) RemoveOrgMember(_ context.Context, orgID string, userID string) error {
+	members := f.members[parseID(orgID)]
+
+	id := parseID(userID)
+	if _, ok := members[id]; !ok {
+		return storage.ErrOrgMemberNotFound
+	}
+	if f.lastAdmin(members, id) {
+		return storage.ErrLastOrgAdmin
+	}
+	delete(members, id)
+
+	return nil
+}
+
+func (f *fakeStorage) SaveAuditEvent(_ context.Context, event models.AuditEvent) error {
+	f.events = append(f.events, event)
+
+	return nil
+}
+
+func (f *fakeStorage) add(orgID uuid.UUID, userID string, role models.OrgRole, accepted bool) error {
+	members, ok := f.members[orgID]
+	if !ok {
+		return storage.ErrOrgNotFound
+	}
+
+	user, ok := f.users[parseID(userID)]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	if _, ok := members[user.ID]; ok {
+		return storage.ErrOrgMemberExists
+	}
+
+	member := models.OrgMember{OrgID: orgID, User: user, Role: role, CreatedAt: time.Now()}
+	if accepted {
+		member.AcceptedAt = member.CreatedAt
+	}
+	members[user.ID] = member
+
+	return nil
+}
+
+func (f *fakeStorage) userOrgs(userID string, accepted bool) []models.Organization {
+	var orgs []models.Organization
+	for orgID, members := range f.members {
+		if member, ok := members[parseID(userID)]; ok && member.Pending() != accepted {
+			orgs = append(orgs, f.orgs[orgID])
+		}
+	}
+
+	return orgs
+}
+
+// lastAdmin reports whether the user is the only admin who accepted the invitation.
+func (f *fakeStorage) lastAdmin(members map[uuid.UUID]models.OrgMember, userID uuid.UUID) bool {
+	var admins []uuid.UUID
+	for id, member := range members {
+		if member.Role == models.OrgRoleAdmin && !member.Pending() {
+			admins = append(admins, id)
+		}
+	}
+
+	return len(admins) == 1 && admins[0] == userID
+}
+
+func parseID(id string) uuid.UUID {
+	parsed, _ := uuid.Parse(id)
+
+	return parsed
+}
@@ -0,0 +1,756 @@
+package passkey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"log/slog"
+	"time"
+)
+
+type Passkey struct {
+	log               *slog.Logger
+	userProvider      UserProvider
+	appProvider       AppProvider
+	credentialStorage CredentialStorage
+	sessionStorage    SessionStorage
+	sessionTTL        time.Duration
+	tokenTTL          time.Duration
+	refreshTokenTTL   time.Duration
+	stepUpTTL         time.Duration
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+	RecordLogin(ctx context.Context, userID string, appID string) error
+}
+
+type AppProvider interface {
+	// LookupApp returns app by id or, if idOrName is not a UUID, by name.
+	LookupApp(ctx context.Context, idOrName string) (models.App, error)
+	AppByID(ctx context.Context, appID string) (models.App, error)
+	models.AppMembership
+}
+
+type CredentialStorage interface {
+	SavePasskeyCredential(ctx context.Context, credential models.PasskeyCredential) error
+	PasskeyCredentials(ctx context.Context, userID string, rpID string) ([]models.PasskeyCredential, error)
+	UsePasskeyCredential(ctx context.Context, credentialID []byte, signCount uint32, backupState bool) error
+}
+
+type SessionStorage interface {
+	SavePasskeySession(ctx context.Context, session models.PasskeySession) (string, error)
+	ConsumePasskeySession(ctx context.Context, sessionID string, ceremony models.PasskeyCeremony) (models.PasskeySession, error)
+}
+
+var (
+	ErrAccessDenied      = errors.New("access denied")
+	ErrAppNotFound       = errors.New("app not found")
+	ErrPasskeysDisabled  = errors.New("passkeys are not enabled for the app")
+	ErrAppDisabled       = errors.New("app is disabled")
+	ErrAppAccessDenied   = errors.New("user is not allowed into the app")
+	ErrInvalidSession    = errors.New("invalid or expired passkey session")
+	ErrInvalidCredential = errors.New("invalid passkey credential")
+	ErrPasskeyExists     = errors.New("passkey already registered")
+	ErrUserInactive      = errors.New("user is not active")
+	ErrNoPasskeys        = errors.New("user has no passkeys for the app")
+)
+
+// New returns a new instance of the Passkey service.
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	credentialStorage CredentialStorage,
+	sessionStorage SessionStorage,
+	sessionTTL time.Duration,
+	tokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+	stepUpTTL time.Duration,
+) *Passkey {
+	return &Passkey{
+		log:               log,
+		userProvider:      userProvider,
+		appProvider:       appProvider,
+		credentialStorage: credentialStorage,
+		sessionStorage:    sessionStorage,
+		sessionTTL:        sessionTTL,
+		tokenTTL:          tokenTTL,
+		refreshTokenTTL:   refreshTokenTTL,
+		stepUpTTL:         stepUpTTL,
+	}
+}
+
+// BeginPasskeyRegistration starts registration of a passkey for the owner of the access token,
+// bound to the app the token was issued for.
+//
+// It returns PublicKeyCredentialCreationOptions as JSON, to be passed to navigator.credentials.create(),
+// and the session ID to finish the ceremony with.
+func (p *Passkey) BeginPasskeyRegistration(ctx context.Context, accessToken string) ([]byte, string, error) {
+	const op = "passkey.BeginPasskeyRegistration"
+
+	log := p.log.With(
+		slog.String("op", op),
+	)
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+	log = log.With(slog.String("user_id", claims.UserID))
+
+	log.Info("beginning passkey registration")
+
+	user, err := p.activeUser(ctx, claims.UserID)
+	if err != nil {
+		log.Warn("user can't register passkey", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, rp, err := p.relyingParty(ctx, p.appProvider.AppByID, claims.AppID)
+	if err != nil {
+		log.Warn("failed to get relying party", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	waUser, err := p.webauthnUser(ctx, user, app.WebAuthnRPID)
+	if err != nil {
+		log.Error("failed to get passkeys", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	creation, session, err := rp.BeginRegistration(
+		waUser,
+		webauthn.WithExclusions(webauthn.Credentials(waUser.credentials).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		log.Error("failed to begin registration", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, sessionID, err := p.saveSession(ctx, models.PasskeyCeremonyRegistration, user.ID, app.ID, creation, session)
+	if err != nil {
+		log.Error("failed to save passkey session", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return options, sessionID, nil
+}
+
+// FinishPasskeyRegistration verifies the attestation response of navigator.credentials.create()
+// and stores the new credential under the given display name.
+func (p *Passkey) FinishPasskeyRegistration(
+	ctx context.Context,
+	accessToken string,
+	sessionID string,
+	response []byte,
+	name string,
+) error {
+	const op = "passkey.FinishPasskeyRegistration"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.String("session_id", sessionID),
+	)
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+	log = log.With(slog.String("user_id", claims.UserID))
+
+	log.Info("finishing passkey registration")
+
+	session, sessionData, err := p.consumeSession(ctx, sessionID, models.PasskeyCeremonyRegistration)
+	if err != nil {
+		log.Warn("failed to get passkey session", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The session must have been started by the same user.
+	if session.UserID.String() != claims.UserID {
+		log.Warn("passkey session belongs to another user")
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidSession)
+	}
+
+	user, err := p.activeUser(ctx, claims.UserID)
+	if err != nil {
+		log.Warn("user can't register passkey", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, rp, err := p.relyingParty(ctx, p.appProvider.AppByID, session.AppID.String())
+	if err != nil {
+		log.Warn("failed to get relying party", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	waUser, err := p.webauthnUser(ctx, user, app.WebAuthnRPID)
+	if err != nil {
+		log.Error("failed to get passkeys", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		log.Warn("failed to parse attestation response", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	credential, err := rp.CreateCredential(waUser, sessionData, parsed)
+	if err != nil {
+		log.Warn("attestation verification failed", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	if err := p.credentialStorage.SavePasskeyCredential(ctx, toModel(*credential, user.ID, app.WebAuthnRPID, name)); err != nil {
+		if errors.Is(err, storage.ErrPasskeyExists) {
+			log.Warn("passkey already registered", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrPasskeyExists)
+		}
+
+		log.Error("failed to save passkey", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("passkey registered")
+
+	return nil
+}
+
+// BeginPasskeyLogin starts a passwordless login into the app, given by ID or name, with a
+// discoverable credential.
+//
+// It returns PublicKeyCredentialRequestOptions as JSON, to be passed to navigator.credentials.get(),
+// and the session ID to finish the ceremony with.
+func (p *Passkey) BeginPasskeyLogin(ctx context.Context, appIDOrName string) ([]byte, string, error) {
+	const op = "passkey.BeginPasskeyLogin"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.String("app", appIDOrName),
+	)
+
+	log.Info("beginning passkey login")
+
+	app, rp, err := p.relyingParty(ctx, p.appProvider.LookupApp, appIDOrName)
+	if err != nil {
+		log.Warn("failed to get relying party", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !app.Enabled {
+		log.Warn("app is disabled")
+
+		return nil, "", fmt.Errorf("%s: %w", op, ErrAppDisabled)
+	}
+
+	// User verification makes the passkey both factors at once, so passkey login skips the MFA step.
+	assertion, session, err := rp.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		log.Error("failed to begin login", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, sessionID, err := p.saveSession(ctx, models.PasskeyCeremonyLogin, uuid.Nil, app.ID, assertion, session)
+	if err != nil {
+		log.Error("failed to save passkey session", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return options, sessionID, nil
+}
+
+// FinishPasskeyLogin verifies the assertion response of navigator.credentials.get()
+// and returns the same token pair as password login.
+func (p *Passkey) FinishPasskeyLogin(ctx context.Context, sessionID string, response []byte) (string, string, error) {
+	const op = "passkey.FinishPasskeyLogin"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.String("session_id", sessionID),
+	)
+
+	log.Info("finishing passkey login")
+
+	session, sessionData, err := p.consumeSession(ctx, sessionID, models.PasskeyCeremonyLogin)
+	if err != nil {
+		log.Warn("failed to get passkey session", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, rp, err := p.relyingParty(ctx, p.appProvider.AppByID, session.AppID.String())
+	if err != nil {
+		log.Warn("failed to get relying party", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		log.Warn("failed to parse assertion response", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	findUser := func(_, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+
+		user, err := p.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID.String()})
+		if err != nil {
+			return nil, err
+		}
+
+		return p.webauthnUser(ctx, user, app.WebAuthnRPID)
+	}
+
+	waUser, credential, err := rp.ValidatePasskeyLogin(findUser, sessionData, parsed)
+	if err != nil {
+		log.Warn("assertion verification failed", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+	user := waUser.(*webauthnUser).user
+	log = log.With(slog.String("user_id", user.ID.String()))
+
+	// A counter that didn't grow means the private key may have been copied.
+	if credential.Authenticator.CloneWarning {
+		log.Warn("passkey sign counter went backwards, possible cloned authenticator")
+
+		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	if !user.IsActive(time.Now()) {
+		log.Warn("user is not active", slog.String("status", string(user.Status)))
+
+		return "", "", fmt.Errorf("%s: %w", op, ErrUserInactive)
+	}
+
+	admitted, err := app.Admits(ctx, p.appProvider, user)
+	if err != nil {
+		log.Error("failed to check app access", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	if !admitted {
+		log.Warn("user is not allowed into the app", slog.String("access_mode", string(app.AccessMode)))
+
+		return "", "", fmt.Errorf("%s: %w", op, ErrAppAccessDenied)
+	}
+
+	if err := p.credentialStorage.UsePasskeyCredential(
+		ctx, credential.ID, credential.Authenticator.SignCount, credential.Flags.BackupState,
+	); err != nil {
+		log.Error("failed to update passkey", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, refreshToken, err := jwt.NewTokenPair(user, app, models.NewAuthentication(models.AuthMethodHardwareKey), p.tokenTTL, p.refreshTokenTTL)
+	if err != nil {
+		log.Error("failed to generate tokens", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := p.userProvider.RecordLogin(ctx, user.ID.String(), app.ID.String()); err != nil {
+		log.Error("failed to record login", sl.Err(err))
+	}
+
+	log.Info("user logged in with passkey")
+
+	return accessToken, refreshToken, nil
+}
+
+// BeginPasskeyStepUp starts re-verification of the access token owner with one of their passkeys.
+//
+// It returns PublicKeyCredentialRequestOptions as JSON and the session ID to finish the ceremony with.
+func (p *Passkey) BeginPasskeyStepUp(ctx context.Context, accessToken string) ([]byte, string, error) {
+	const op = "passkey.BeginPasskeyStepUp"
+
+	log := p.log.With(
+		slog.String("op", op),
+	)
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+	log = log.With(slog.String("user_id", claims.UserID))
+
+	log.Info("beginning passkey step-up")
+
+	user, err := p.sessionUser(ctx, claims)
+	if err != nil {
+		log.Warn("user can't step up", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, rp, err := p.relyingParty(ctx, p.appProvider.AppByID, claims.AppID)
+	if err != nil {
+		log.Warn("failed to get relying party", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	waUser, err := p.webauthnUser(ctx, user, app.WebAuthnRPID)
+	if err != nil {
+		log.Error("failed to get passkeys", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(waUser.credentials) == 0 {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrNoPasskeys)
+	}
+
+	assertion, session, err := rp.BeginLogin(waUser, webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		log.Error("failed to begin step-up", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, sessionID, err := p.saveSession(ctx, models.PasskeyCeremonyStepUp, user.ID, app.ID, assertion, session)
+	if err != nil {
+		log.Error("failed to save passkey session", sl.Err(err))
+
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return options, sessionID, nil
+}
+
+// FinishPasskeyStepUp verifies the assertion and returns a short-lived access token
+// with models.ACRStepUp for sensitive actions.
+func (p *Passkey) FinishPasskeyStepUp(ctx context.Context, accessToken string, sessionID string, response []byte) (string, error) {
+	const op = "passkey.FinishPasskeyStepUp"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.String("session_id", sessionID),
+	)
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+	log = log.With(slog.String("user_id", claims.UserID))
+
+	log.Info("finishing passkey step-up")
+
+	session, sessionData, err := p.consumeSession(ctx, sessionID, models.PasskeyCeremonyStepUp)
+	if err != nil {
+		log.Warn("failed to get passkey session", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if session.UserID.String() != claims.UserID || session.AppID.String() != claims.AppID {
+		log.Warn("passkey session belongs to another session")
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidSession)
+	}
+
+	user, err := p.sessionUser(ctx, claims)
+	if err != nil {
+		log.Warn("user can't step up", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, rp, err := p.relyingParty(ctx, p.appProvider.AppByID, claims.AppID)
+	if err != nil {
+		log.Warn("failed to get relying party", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	waUser, err := p.webauthnUser(ctx, user, app.WebAuthnRPID)
+	if err != nil {
+		log.Error("failed to get passkeys", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		log.Warn("failed to parse assertion response", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	credential, err := rp.ValidateLogin(waUser, sessionData, parsed)
+	if err != nil {
+		log.Warn("assertion verification failed", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	if credential.Authenticator.CloneWarning {
+		log.Warn("passkey sign counter went backwards, possible cloned authenticator")
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredential)
+	}
+
+	if err := p.credentialStorage.UsePasskeyCredential(
+		ctx, credential.ID, credential.Authenticator.SignCount, credential.Flags.BackupState,
+	); err != nil {
+		log.Error("failed to update passkey", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	auth := claims.Auth.With(models.AuthMethodHardwareKey)
+	auth.Time = time.Now()
+
+	token, err := jwt.NewStepUpToken(user, app, auth, p.stepUpTTL)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("authentication stepped up with passkey")
+
+	return token, nil
+}
+
+// relyingParty returns the app found by key with its WebAuthn relying party.
+func (p *Passkey) relyingParty(
+	ctx context.Context,
+	find func(ctx context.Context, key string) (models.App, error),
+	key string,
+) (models.App, *webauthn.WebAuthn, error) {
+	app, err := find(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.App{}, nil, ErrAppNotFound
+		}
+
+		return models.App{}, nil, err
+	}
+
+	if app.WebAuthnRPID == "" || len(app.WebAuthnOrigins) == 0 {
+		return models.App{}, nil, ErrPasskeysDisabled
+	}
+
+	rp, err := webauthn.New(&webauthn.Config{
+		RPID:          app.WebAuthnRPID,
+		RPDisplayName: app.Name,
+		RPOrigins:     app.WebAuthnOrigins,
+	})
+	if err != nil {
+		return models.App{}, nil, err
+	}
+
+	return app, rp, nil
+}
+
+func (p *Passkey) activeUser(ctx context.Context, userID string) (models.User, error) {
+	user, err := p.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, ErrAccessDenied
+		}
+
+		return models.User{}, err
+	}
+
+	if !user.IsActive(time.Now()) {
+		return models.User{}, ErrUserInactive
+	}
+
+	return user, nil
+}
+
+// sessionUser returns the active owner of the access token, unless their sessions were revoked after it was issued.
+func (p *Passkey) sessionUser(ctx context.Context, claims jwt.AccessClaims) (models.User, error) {
+	user, err := p.activeUser(ctx, claims.UserID)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if claims.IssuedAt.Before(user.SessionsRevokedAt) {
+		return models.User{}, ErrAccessDenied
+	}
+
+	return user, nil
+}
+
+func (p *Passkey) webauthnUser(ctx context.Context, user models.User, rpID string) (*webauthnUser, error) {
+	stored, err := p.credentialStorage.PasskeyCredentials(ctx, user.ID.String(), rpID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, c := range stored {
+		credentials = append(credentials, fromModel(c))
+	}
+
+	return &webauthnUser{user: user, credentials: credentials}, nil
+}
+
+// saveSession stores ceremony state and returns the options for the browser as JSON.
+func (p *Passkey) saveSession(
+	ctx context.Context,
+	ceremony models.PasskeyCeremony,
+	userID uuid.UUID,
+	appID uuid.UUID,
+	options any,
+	sessionData *webauthn.SessionData,
+) ([]byte, string, error) {
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := json.Marshal(sessionData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sessionID, err := p.sessionStorage.SavePasskeySession(ctx, models.PasskeySession{
+		Ceremony:  ceremony,
+		UserID:    userID,
+		AppID:     appID,
+		Data:      data,
+		ExpiresAt: time.Now().Add(p.sessionTTL),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return optionsJSON, sessionID, nil
+}
+
+func (p *Passkey) consumeSession(
+	ctx context.Context,
+	sessionID string,
+	ceremony models.PasskeyCeremony,
+) (models.PasskeySession, webauthn.SessionData, error) {
+	session, err := p.sessionStorage.ConsumePasskeySession(ctx, sessionID, ceremony)
+	if err != nil {
+		if errors.Is(err, storage.ErrPasskeySessionNotFound) {
+			return models.PasskeySession{}, webauthn.SessionData{}, ErrInvalidSession
+		}
+
+		return models.PasskeySession{}, webauthn.SessionData{}, err
+	}
+
+	var data webauthn.SessionData
+	if err := json.Unmarshal(session.Data, &data); err != nil {
+		return models.PasskeySession{}, webauthn.SessionData{}, err
+	}
+
+	return session, data, nil
+}
+
+// webauthnUser adapts a user and their passkeys to webauthn.User.
+// The user handle is the raw user UUID, which carries nothing personal.
+type webauthnUser struct {
+	user        models.User
+	credentials []webauthn.Credential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	return u.user.ID[:]
+}
+
+func (u *webauthnUser) WebAuthnName() string {
+	if u.user.Email != "" {
+		return u.user.Email
+	}
+	if u.user.Username != "" {
+		return u.user.Username
+	}
+
+	return u.user.Phone
+}
+
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	if u.user.Username != "" {
+		return u.user.Username
+	}
+
+	return u.WebAuthnName()
+}
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func toModel(c webauthn.Credential, userID uuid.UUID, rpID string, name string) models.PasskeyCredential {
+	transports := make([]string, 0, len(c.Transport))
+	for _, t := range c.Transport {
+		transports = append(transports, string(t))
+	}
+
+	return models.PasskeyCredential{
+		ID:              c.ID,
+		UserID:          userID,
+		RPID:            rpID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		AAGUID:          c.Authenticator.AAGUID,
+		SignCount:       c.Authenticator.SignCount,
+		Transports:      transports,
+		BackupEligible:  c.Flags.BackupEligible,
+		BackupState:     c.Flags.BackupState,
+		Name:            name,
+	}
+}
+
+func fromModel(c models.PasskeyCredential) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+	for _, t := range c.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(t))
+	}
+
+	return webauthn.Credential{
+		ID:              c.ID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: c.BackupEligible,
+			BackupState:    c.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    c.AAGUID,
+			SignCount: c.SignCount,
+		},
+	}
+}
@@ -0,0 +1,551 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// WebAuthn authenticator data flags.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+func TestPasskey_RegisterAndLogin(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+
+	env.register(t, authenticator)
+
+	stored := env.storage.credentials[string(authenticator.credentialID)]
+	require.NotNil(t, stored)
+	assert.Equal(t, env.user.ID, stored.UserID)
+	assert.Equal(t, testRPID, stored.RPID)
+	assert.Equal(t, "laptop", stored.Name)
+
+	accessToken, refreshToken, err := env.login(t, authenticator)
+	require.NoError(t, err)
+	assert.NotEmpty(t, refreshToken)
+
+	claims, err := jwt.ParseAccessToken(accessToken)
+	require.NoError(t, err)
+	assert.Equal(t, env.user.ID.String(), claims.UserID)
+	assert.Equal(t, env.app.ID.String(), claims.AppID)
+
+	assert.Equal(t, authenticator.signCount, env.storage.credentials[string(authenticator.credentialID)].SignCount)
+	assert.Equal(t, []string{env.user.ID.String()}, env.storage.logins)
+}
+
+func TestPasskey_RegisterTwice(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+
+	env.register(t, authenticator)
+
+	options, sessionID, err := env.service.BeginPasskeyRegistration(context.Background(), env.accessToken)
+	require.NoError(t, err)
+
+	// The registered credential is excluded, so browsers won't create a duplicate.
+	var creation struct {
+		PublicKey struct {
+			ExcludeCredentials []struct {
+				ID string `json:"id"`
+			} `json:"excludeCredentials"`
+		} `json:"publicKey"`
+	}
+	require.NoError(t, json.Unmarshal(options, &creation))
+	require.Len(t, creation.PublicKey.ExcludeCredentials, 1)
+
+	err = env.service.FinishPasskeyRegistration(
+		context.Background(), env.accessToken, sessionID, authenticator.create(t, options), "again",
+	)
+	assert.ErrorIs(t, err, ErrPasskeyExists)
+}
+
+func TestPasskey_LoginSessionIsSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(t, authenticator)
+
+	options, sessionID, err := env.service.BeginPasskeyLogin(context.Background(), env.app.Name)
+	require.NoError(t, err)
+
+	response := authenticator.get(t, options, env.user.ID)
+
+	_, _, err = env.service.FinishPasskeyLogin(context.Background(), sessionID, response)
+	require.NoError(t, err)
+
+	_, _, err = env.service.FinishPasskeyLogin(context.Background(), sessionID, response)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+}
+
+func TestPasskey_LoginByAppID(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(t, authenticator)
+
+	options, sessionID, err := env.service.BeginPasskeyLogin(context.Background(), env.app.ID.String())
+	require.NoError(t, err)
+
+	_, _, err = env.service.FinishPasskeyLogin(context.Background(), sessionID, authenticator.get(t, options, env.user.ID))
+	require.NoError(t, err)
+}
+
+func TestPasskey_LoginFromForeignOrigin(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(t, authenticator)
+
+	authenticator.origin = "https://example.com.evil.test"
+
+	_, _, err := env.login(t, authenticator)
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+}
+
+func TestPasskey_LoginWithClonedAuthenticator(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(t, authenticator)
+
+	_, _, err := env.login(t, authenticator)
+	require.NoError(t, err)
+
+	// A copy of the key replays an old counter value.
+	authenticator.signCount--
+
+	_, _, err = env.login(t, authenticator)
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+}
+
+func TestPasskey_LoginInactiveUser(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(t, authenticator)
+
+	env.user.Status = models.UserStatusDeactivated
+	env.storage.users[env.user.ID] = env.user
+
+	_, _, err := env.login(t, authenticator)
+	assert.ErrorIs(t, err, ErrUserInactive)
+}
+
+func TestPasskey_LoginMembersOnlyApp(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(t, authenticator)
+
+	env.app.AccessMode = models.AppAccessMembers
+	env.storage.apps[env.app.ID] = env.app
+
+	_, _, err := env.login(t, authenticator)
+	assert.ErrorIs(t, err, ErrAppAccessDenied)
+
+	env.storage.members[env.user.ID] = true
+
+	_, _, err = env.login(t, authenticator)
+	assert.NoError(t, err)
+}
+
+func TestPasskey_StepUp(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(t, authenticator)
+
+	options, sessionID, err := env.service.BeginPasskeyStepUp(context.Background(), env.accessToken)
+	require.NoError(t, err)
+
+	token, err := env.service.FinishPasskeyStepUp(
+		context.Background(), env.accessToken, sessionID, authenticator.get(t, options, env.user.ID),
+	)
+	require.NoError(t, err)
+
+	claims, err := jwt.ParseAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, models.ACRStepUp, claims.ACR)
+	assert.ElementsMatch(t,
+		[]models.AuthMethod{models.AuthMethodPassword, models.AuthMethodHardwareKey, models.AuthMethodMultiFactor},
+		claims.Auth.Methods,
+	)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), claims.ExpiresAt, time.Minute)
+}
+
+func TestPasskey_StepUpSessionOfAnotherUser(t *testing.T) {
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(t, authenticator)
+
+	options, sessionID, err := env.service.BeginPasskeyStepUp(context.Background(), env.accessToken)
+	require.NoError(t, err)
+
+	other := models.User{ID: uuid.New(), Email: "mallory@example.com", Status: models.UserStatusActive}
+	env.storage.users[other.ID] = other
+	otherToken, _, err := jwt.NewTokenPair(other, env.app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	_, err = env.service.FinishPasskeyStepUp(
+		context.Background(), otherToken, sessionID, authenticator.get(t, options, env.user.ID),
+	)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+}
+
+func TestPasskey_Disabled(t *testing.T) {
+	env := newTestEnv(t)
+
+	app := env.app
+	app.WebAuthnRPID = ""
+	env.storage.apps[app.ID] = app
+
+	_, _, err := env.service.BeginPasskeyLogin(context.Background(), app.Name)
+	assert.ErrorIs(t, err, ErrPasskeysDisabled)
+
+	_, _, err = env.service.BeginPasskeyRegistration(context.Background(), env.accessToken)
+	assert.ErrorIs(t, err, ErrPasskeysDisabled)
+}
+
+type testEnv struct {
+	service     *Passkey
+	storage     *fakeStorage
+	user        models.User
+	app         models.App
+	accessToken string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com", Status: models.UserStatusActive}
+	app := models.App{
+		ID:              uuid.New(),
+		Name:            "test-app",
+		Enabled:         true,
+		WebAuthnRPID:    testRPID,
+		WebAuthnOrigins: []string{testOrigin},
+	}
+
+	fake := newFakeStorage()
+	fake.users[user.ID] = user
+	fake.apps[app.ID] = app
+
+	accessToken, _, err := jwt.NewTokenPair(user, app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testEnv{
+		service:     New(log, fake, fake, fake, fake, time.Minute, time.Hour, time.Hour, 5*time.Minute),
+		storage:     fake,
+		user:        user,
+		app:         app,
+		accessToken: accessToken,
+	}
+}
+
+func (e *testEnv) register(t *testing.T, authenticator *softAuthenticator) {
+	t.Helper()
+
+	options, sessionID, err := e.service.BeginPasskeyRegistration(context.Background(), e.accessToken)
+	require.NoError(t, err)
+
+	err = e.service.FinishPasskeyRegistration(
+		context.Background(), e.accessToken, sessionID, authenticator.create(t, options), "laptop",
+	)
+	require.NoError(t, err)
+}
+
+func (e *testEnv) login(t *testing.T, authenticator *softAuthenticator) (string, string, error) {
+	t.Helper()
+
+	options, sessionID, err := e.service.BeginPasskeyLogin(context.Background(), e.app.Name)
+	require.NoError(t, err)
+
+	return e.service.FinishPasskeyLogin(context.Background(), sessionID, authenticator.get(t, options, e.user.ID))
+}
+
+// softAuthenticator is a platform authenticator with a P-256 key held in memory.
+// It produces "none" attestation and user verified assertions.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	origin       string
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, origin string) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credentialID := make([]byte, 32)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &softAuthenticator{key: key, credentialID: credentialID, origin: origin}
+}
+
+// create answers navigator.credentials.create() for the given creation options.
+func (a *softAuthenticator) create(t *testing.T, options []byte) []byte {
+	t.Helper()
+
+	var creation struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			RP        struct {
+				ID string `json:"id"`
+			} `json:"rp"`
+		} `json:"publicKey"`
+	}
+	require.NoError(t, json.Unmarshal(options, &creation))
+
+	clientData := a.clientData(t, "webauthn.create", creation.PublicKey.Challenge)
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	var authData bytes.Buffer
+	authData.Write(a.authDataHeader(creation.PublicKey.RP.ID, flagUserPresent|flagUserVerified|flagAttestedData))
+	authData.Write(make([]byte, 16)) // AAGUID
+	_ = binary.Write(&authData, binary.BigEndian, uint16(len(a.credentialID)))
+	authData.Write(a.credentialID)
+	authData.Write(publicKey)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData.Bytes(),
+	})
+	require.NoError(t, err)
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+// get answers navigator.credentials.get() for the given request options.
+func (a *softAuthenticator) get(t *testing.T, options []byte, userID uuid.UUID) []byte {
+	t.Helper()
+
+	var assertion struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			RPID      string `json:"rpId"`
+		} `json:"publicKey"`
+	}
+	require.NoError(t, json.Unmarshal(options, &assertion))
+
+	clientData := a.clientData(t, "webauthn.get", assertion.PublicKey.Challenge)
+
+	a.signCount++
+	authData := a.authDataHeader(assertion.PublicKey.RPID, flagUserPresent|flagUserVerified)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.response(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(userID[:]),
+	})
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony string, challenge string) []byte {
+	t.Helper()
+
+	clientData, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    a.origin,
+	})
+	require.NoError(t, err)
+
+	return clientData
+}
+
+func (a *softAuthenticator) authDataHeader(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	header := append(rpIDHash[:], flags)
+
+	return binary.BigEndian.AppendUint32(header, a.signCount)
+}
+
+func (a *softAuthenticator) response(t *testing.T, response map[string]string) []byte {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]any{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+
+	return body
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+type fakeStorage struct {
+	users       map[uuid.UUID]models.User
+	apps        map[uuid.UUID]models.App
+	credentials map[string]models.PasskeyCredential
+	sessions    map[string]models.PasskeySession
+	members     map[uuid.UUID]bool
+	logins      []string
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		users:       map[uuid.UUID]models.User{},
+		apps:        map[uuid.UUID]models.App{},
+		credentials: map[string]models.PasskeyCredential{},
+		sessions:    map[string]models.PasskeySession{},
+		members:     map[uuid.UUID]bool{},
+	}
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	id, err := uuid.Parse(identifier.Value)
+	if err != nil {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	user, ok := f.users[id]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeStorage) RecordLogin(_ context.Context, userID string, _ string) error {
+	f.logins = append(f.logins, userID)
+
+	return nil
+}
+
+func (f *fakeStorage) LookupApp(_ context.Context, idOrName string) (models.App, error) {
+	for _, app := range f.apps {
+		if app.ID.String() == idOrName || app.Name == idOrName {
+			return app, nil
+		}
+	}
+
+	return models.App{}, storage.ErrAppNotFound
+}
+
+func (f *fakeStorage) AppByID(_ context.Context, appID string) (models.App, error) {
+	id, err := uuid.Parse(appID)
+	if err != nil {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	app, ok := f.apps[id]
+	if !ok {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return app, nil
+}
+
+func (f *fakeStorage) IsAppMember(_ context.Context, _ uuid.UUID, userID uuid.UUID) (bool, error) {
+	return f.members[userID], nil
+}
+
+func (f *fakeStorage) SavePasskeyCredential(_ context.Context, credential models.PasskeyCredential) error {
+	if _, ok := f.credentials[string(credential.ID)]; ok {
+		return storage.ErrPasskeyExists
+	}
+	f.credentials[string(credential.ID)] = credential
+
+	return nil
+}
+
+func (f *fakeStorage) PasskeyCredentials(_ context.Context, userID string, rpID string) ([]models.PasskeyCredential, error) {
+	var credentials []models.PasskeyCredential
+	for _, c := range f.credentials {
+		if c.UserID.String() == userID && c.RPID == rpID {
+			credentials = append(credentials, c)
+		}
+	}
+
+	return credentials, nil
+}
+
+func (f *fakeStorage) UsePasskeyCredential(_ context.Context, credentialID []byte, signCount uint32, backupState bool) error {
+	c, ok := f.credentials[string(credentialID)]
+	if !ok {
+		return storage.ErrPasskeyNotFound
+	}
+	c.SignCount = signCount
+	c.BackupState = backupState
+	c.LastUsedAt = time.Now()
+	f.credentials[string(credentialID)] = c
+
+	return nil
+}
+
+func (f *fakeStorage) SavePasskeySession(_ context.Context, session models.PasskeySession) (string, error) {
+	session.ID = uuid.New()
+	f.sessions[session.ID.String()] = session
+
+	return session.ID.String(), nil
+}
+
+func (f *fakeStorage) ConsumePasskeySession(
+	_ context.Context,
+	sessionID string,
+	ceremony models.PasskeyCeremony,
+) (models.PasskeySession, error) {
+	session, ok := f.sessions[sessionID]
+	if !ok || session.Ceremony != ceremony || time.Now().After(session.ExpiresAt) {
+		return models.PasskeySession{}, storage.ErrPasskeySessionNotFound
+	}
+	delete(f.sessions, sessionID)
+
+	return session, nil
+}
@@ -0,0 +1,240 @@
+package pat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/pat"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const (
+	maxNameLength = 100
+	maxScopes     = 64
+)
+
+// scopeRe matches scopes a token may carry, e.g. "reports:read". Resource servers decide what they mean.
+var scopeRe = regexp.MustCompile(`^[a-z][a-z0-9_.:-]{0,63}$`)
+
+// PAT manages personal access tokens: long-lived, scoped tokens users create for scripts,
+// separate from their sessions. Tokens are managed with a session, not with another token.
+type PAT struct {
+	log          *slog.Logger
+	userProvider UserProvider
+	tokenStorage TokenStorage
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+}
+
+type TokenStorage interface {
+	CreatePersonalAccessToken(ctx context.Context, token models.PersonalAccessToken, hash string) (models.PersonalAccessToken, error)
+	PersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error)
+	DeletePersonalAccessToken(ctx context.Context, userID uuid.UUID, tokenID uuid.UUID) error
+}
+
+var (
+	ErrAccessDenied  = errors.New("access denied")
+	ErrUserInactive  = errors.New("user is not active")
+	ErrInvalidName   = errors.New("invalid token name")
+	ErrInvalidScopes = errors.New("invalid token scopes")
+	ErrInvalidTTL    = errors.New("invalid token ttl")
+	ErrTokenNotFound = errors.New("personal access token not found")
+)
+
+// New returns a new instance of the PAT service.
+func New(log *slog.Logger, userProvider UserProvider, tokenStorage TokenStorage) *PAT {
+	return &PAT{
+		log:          log,
+		userProvider: userProvider,
+		tokenStorage: tokenStorage,
+	}
+}
+
+// CreatePersonalAccessToken creates a token for the owner of the access token. The token is
+// returned only here. Zero ttl creates a token that does not expire.
+func (p *PAT) CreatePersonalAccessToken(
+	ctx context.Context,
+	accessToken string,
+	name string,
+	scopes []string,
+	ttl time.Duration,
+) (models.PersonalAccessToken, string, error) {
+	const op = "pat.CreatePersonalAccessToken"
+
+	log := p.log.With(
+		slog.String("op", op),
+	)
+
+	user, err := p.sessionUser(ctx, accessToken)
+	if err != nil {
+		log.Warn("caller can't create personal access tokens", sl.Err(err))
+
+		return models.PersonalAccessToken{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("user_id", user.ID.String()))
+
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return models.PersonalAccessToken{}, "", fmt.Errorf("%s: %w", op, ErrInvalidName)
+	}
+
+	scopes, err = normalizeScopes(scopes)
+	if err != nil {
+		return models.PersonalAccessToken{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if ttl < 0 {
+		return models.PersonalAccessToken{}, "", fmt.Errorf("%s: %w", op, ErrInvalidTTL)
+	}
+
+	token, err := pat.Generate()
+	if err != nil {
+		log.Error("failed to generate personal access token", sl.Err(err))
+
+		return models.PersonalAccessToken{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	meta := models.PersonalAccessToken{UserID: user.ID, Name: name, Scopes: scopes}
+	if ttl > 0 {
+		meta.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	meta, err = p.tokenStorage.CreatePersonalAccessToken(ctx, meta, randtoken.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.PersonalAccessToken{}, "", fmt.Errorf("%s: %w", op, ErrAccessDenied)
+		}
+
+		log.Error("failed to save personal access token", sl.Err(err))
+
+		return models.PersonalAccessToken{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("personal access token created", slog.String("token_id", meta.ID.String()), slog.Any("scopes", scopes))
+
+	return meta, token, nil
+}
+
+// ListPersonalAccessTokens returns tokens of the owner of the access token without the tokens themselves.
+func (p *PAT) ListPersonalAccessTokens(ctx context.Context, accessToken string) ([]models.PersonalAccessToken, error) {
+	const op = "pat.ListPersonalAccessTokens"
+
+	log := p.log.With(
+		slog.String("op", op),
+	)
+
+	user, err := p.sessionUser(ctx, accessToken)
+	if err != nil {
+		log.Warn("caller can't list personal access tokens", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := p.tokenStorage.PersonalAccessTokens(ctx, user.ID)
+	if err != nil {
+		log.Error("failed to list personal access tokens", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// RevokePersonalAccessToken deletes the token of the owner of the access token. It stops
+// passing introspection right away.
+func (p *PAT) RevokePersonalAccessToken(ctx context.Context, accessToken string, tokenID string) error {
+	const op = "pat.RevokePersonalAccessToken"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.String("token_id", tokenID),
+	)
+
+	user, err := p.sessionUser(ctx, accessToken)
+	if err != nil {
+		log.Warn("caller can't revoke personal access tokens", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("user_id", user.ID.String()))
+
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrTokenNotFound)
+	}
+
+	if err := p.tokenStorage.DeletePersonalAccessToken(ctx, user.ID, id); err != nil {
+		if errors.Is(err, storage.ErrPersonalAccessTokenNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrTokenNotFound)
+		}
+
+		log.Error("failed to delete personal access token", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("personal access token revoked")
+
+	return nil
+}
+
+// sessionUser returns the active owner of the access token, unless their sessions were revoked
+// after it was issued. Personal access tokens are not JWTs, so they can't manage tokens.
+func (p *PAT) sessionUser(ctx context.Context, accessToken string) (models.User, error) {
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return models.User{}, ErrAccessDenied
+	}
+
+	user, err := p.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: claims.UserID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, ErrAccessDenied
+		}
+
+		return models.User{}, err
+	}
+
+	if !user.IsActive(time.Now()) {
+		return models.User{}, ErrUserInactive
+	}
+
+	if claims.IssuedAt.Before(user.SessionsRevokedAt) {
+		return models.User{}, ErrAccessDenied
+	}
+
+	return user, nil
+}
+
+// normalizeScopes validates scopes and drops duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) > maxScopes {
+		return nil, fmt.Errorf("%w: at most %d allowed", ErrInvalidScopes, maxScopes)
+	}
+
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !scopeRe.MatchString(scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScopes, scope)
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,232 @@
+package pat
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/pat"
+	"github.com/sol1corejz/auth-service/internal/lib/randtoken"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStorage struct {
+	users  map[uuid.UUID]models.User
+	tokens map[uuid.UUID]models.PersonalAccessToken
+	hashes map[uuid.UUID]string
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	id, err := uuid.Parse(identifier.Value)
+	if err != nil {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	user, ok := f.users[id]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeStorage) CreatePersonalAccessToken(_ context.Context, token models.PersonalAccessToken, hash string) (models.PersonalAccessToken, error) {
+	if _, ok := f.users[token.UserID]; !ok {
+		return models.PersonalAccessToken{}, storage.ErrUserNotFound
+	}
+
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+	f.tokens[token.ID] = token
+	f.hashes[token.ID] = hash
+
+	return token, nil
+}
+
+func (f *fakeStorage) PersonalAccessTokens(_ context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	for _, token := range f.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
+
+func (f *fakeStorage) DeletePersonalAccessToken(_ context.Context, userID uuid.UUID, tokenID uuid.UUID) error {
+	token, ok := f.tokens[tokenID]
+	if !ok || token.UserID != userID {
+		return storage.ErrPersonalAccessTokenNotFound
+	}
+	delete(f.tokens, tokenID)
+
+	return nil
+}
+
+type testEnv struct {
+	t       *testing.T
+	service *PAT
+	storage *fakeStorage
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	fake := &fakeStorage{
+		users:  map[uuid.UUID]models.User{},
+		tokens: map[uuid.UUID]models.PersonalAccessToken{},
+		hashes: map[uuid.UUID]string{},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testEnv{t: t, service: New(log, fake, fake), storage: fake}
+}
+
+func (e *testEnv) addUser(status models.UserStatus) models.User {
+	user := models.User{ID: uuid.New(), Status: status}
+	user.Email = user.ID.String() + "@example.com"
+	e.storage.users[user.ID] = user
+
+	return user
+}
+
+func (e *testEnv) token(user models.User) string {
+	e.t.Helper()
+
+	app := models.App{ID: uuid.New(), Name: "test-app", Enabled: true}
+
+	accessToken, _, err := jwt.NewTokenPair(user, app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(e.t, err)
+
+	return accessToken
+}
+
+func TestPersonalAccessTokens_Lifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(models.UserStatusActive)
+	session := env.token(user)
+
+	meta, token, err := env.service.CreatePersonalAccessToken(ctx, session, "  deploy  ", []string{"reports:read", "reports:read", "reports:write"}, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, pat.Looks(token))
+	assert.Equal(t, "deploy", meta.Name)
+	assert.Equal(t, []string{"reports:read", "reports:write"}, meta.Scopes, "duplicate scopes are dropped")
+	assert.WithinDuration(t, time.Now().Add(time.Hour), meta.ExpiresAt, time.Minute)
+	assert.Equal(t, randtoken.Hash(token), env.storage.hashes[meta.ID], "only the hash is stored")
+
+	forever, _, err := env.service.CreatePersonalAccessToken(ctx, session, "backup", nil, 0)
+	require.NoError(t, err)
+	assert.True(t, forever.ExpiresAt.IsZero(), "zero ttl creates a token that does not expire")
+
+	// Tokens of other users are neither listed nor revocable.
+	other := env.addUser(models.UserStatusActive)
+	_, _, err = env.service.CreatePersonalAccessToken(ctx, env.token(other), "other", nil, 0)
+	require.NoError(t, err)
+
+	tokens, err := env.service.ListPersonalAccessTokens(ctx, session)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 2)
+
+	err = env.service.RevokePersonalAccessToken(ctx, env.token(other), meta.ID.String())
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	require.NoError(t, env.service.RevokePersonalAccessToken(ctx, session, meta.ID.String()))
+
+	tokens, err = env.service.ListPersonalAccessTokens(ctx, session)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, forever.ID, tokens[0].ID)
+
+	err = env.service.RevokePersonalAccessToken(ctx, session, meta.ID.String())
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	err = env.service.RevokePersonalAccessToken(ctx, session, "not-a-uuid")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+}
+
+func TestCreatePersonalAccessToken_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  func(env *testEnv) string
+		tname  string
+		scopes []string
+		ttl    time.Duration
+		want   error
+	}{
+		{name: "malformed session", token: func(*testEnv) string { return "garbage" }, want: ErrAccessDenied},
+		{
+			name: "personal access token as session",
+			token: func(*testEnv) string {
+				token, _ := pat.Generate()
+				return token
+			},
+			want: ErrAccessDenied,
+		},
+		{
+			name: "unknown user",
+			token: func(env *testEnv) string {
+				return env.token(models.User{ID: uuid.New(), Status: models.UserStatusActive})
+			},
+			want: ErrAccessDenied,
+		},
+		{
+			name: "suspended user",
+			token: func(env *testEnv) string {
+				return env.token(env.addUser(models.UserStatusSuspended))
+			},
+			want: ErrUserInactive,
+		},
+		{
+			name: "revoked sessions",
+			token: func(env *testEnv) string {
+				user := env.addUser(models.UserStatusActive)
+				token := env.token(user)
+				user.SessionsRevokedAt = time.Now().Add(time.Minute)
+				env.storage.users[user.ID] = user
+
+				return token
+			},
+			want: ErrAccessDenied,
+		},
+		{name: "blank name", tname: "   ", want: ErrInvalidName},
+		{name: "long name", tname: string(make([]rune, maxNameLength+1)), want: ErrInvalidName},
+		{name: "malformed scope", scopes: []string{"Reports Read"}, want: ErrInvalidScopes},
+		{name: "too many scopes", scopes: make([]string, maxScopes+1), want: ErrInvalidScopes},
+		{name: "negative ttl", ttl: -time.Second, want: ErrInvalidTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+
+			var session string
+			if tt.token != nil {
+				session = tt.token(env)
+			} else {
+				session = env.token(env.addUser(models.UserStatusActive))
+			}
+
+			name := tt.tname
+			if name == "" {
+				name = "deploy"
+			}
+
+			_, _, err := env.service.CreatePersonalAccessToken(context.Background(), session, name, tt.scopes, tt.ttl)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, env.storage.tokens)
+		})
+	}
+}
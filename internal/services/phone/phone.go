@@ -0,0 +1,470 @@
+package phone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/otp"
+	"github.com/sol1corejz/auth-service/internal/lib/phone"
+	"github.com/sol1corejz/auth-service/internal/lib/sms"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"time"
+)
+
+const codeDigits = 6
+
+type Phone struct {
+	log             *slog.Logger
+	userProvider    UserProvider
+	phoneSaver      PhoneSaver
+	codeStorage     CodeStorage
+	appProvider     AppProvider
+	mfaChallenger   MFAChallenger
+	smsSender       sms.Sender
+	limits          Limits
+	tokenTTL        time.Duration
+	refreshTokenTTL time.Duration
+}
+
+// Limits bound how often codes are sent and how long they can be used.
+type Limits struct {
+	CodeTTL         time.Duration
+	ResendInterval  time.Duration
+	MaxCodesPerHour int
+	MaxAttempts     int
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+}
+
+type PhoneSaver interface {
+	SetVerifiedPhone(ctx context.Context, userID string, phone string) error
+	RecordLogin(ctx context.Context, userID string, appID string) error
+}
+
+type CodeStorage interface {
+	SavePhoneCode(ctx context.Context, code models.PhoneCode) (string, error)
+	PhoneCodeStats(ctx context.Context, phone string, since time.Time) (count int, last time.Time, err error)
+	ActivePhoneCode(ctx context.Context, phone string, purpose models.PhoneCodePurpose) (models.PhoneCode, error)
+	UsePhoneCodeAttempt(ctx context.Context, codeID string, maxAttempts int) error
+	ConsumePhoneCode(ctx context.Context, codeID string) error
+}
+
+type AppProvider interface {
+	// LookupApp returns app by id or, if idOrName is not a UUID, by name.
+	LookupApp(ctx context.Context, idOrName string) (models.App, error)
+	models.AppMembership
+}
+
+type MFAChallenger interface {
+	// Challenge issues a challenge if login of the user into the app needs a second factor.
+	Challenge(
+		ctx context.Context,
+		user models.User,
+		app models.App,
+		firstFactor models.AuthMethod,
+	) (challenge models.MFAChallenge, required bool, err error)
+}
+
+var (
+	ErrInvalidPhone    = errors.New("invalid phone")
+	ErrInvalidCode     = errors.New("invalid code")
+	ErrRateLimited     = errors.New("too many codes requested")
+	ErrPhoneTaken      = errors.New("phone taken")
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrUserInactive    = errors.New("user is not active")
+	ErrInvalidApp      = errors.New("invalid app")
+	ErrAppDisabled     = errors.New("app is disabled")
+	ErrAppAccessDenied = errors.New("user is not allowed into the app")
+)
+
+// New returns a new instance of the Phone service.
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	phoneSaver PhoneSaver,
+	codeStorage CodeStorage,
+	appProvider AppProvider,
+	mfaChallenger MFAChallenger,
+	smsSender sms.Sender,
+	limits Limits,
+	tokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+) *Phone {
+	return &Phone{
+		log:             log,
+		userProvider:    userProvider,
+		phoneSaver:      phoneSaver,
+		codeStorage:     codeStorage,
+		appProvider:     appProvider,
+		mfaChallenger:   mfaChallenger,
+		smsSender:       smsSender,
+		limits:          limits,
+		tokenTTL:        tokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+// StartPhoneLogin sends one-time login code to the phone.
+//
+// To not disclose which phones are registered, no error is returned
+// if there is no user with given verified phone; the code is just not sent.
+func (p *Phone) StartPhoneLogin(ctx context.Context, number string) error {
+	const op = "phone.StartPhoneLogin"
+
+	log := p.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("starting phone login")
+
+	number, err := phone.Normalize(number)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrInvalidPhone)
+	}
+
+	if err := p.checkRateLimit(ctx, number); err != nil {
+		log.Warn("phone code rate limited", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := p.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierPhone, Value: number})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found")
+
+			return nil
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := p.sendCode(ctx, number, models.PhoneCodeLogin, user.ID); err != nil {
+		log.Error("failed to send login code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("login code sent")
+
+	return nil
+}
+
+// CompletePhoneLogin exchanges login code sent to the phone for a token pair in the app,
+// given by ID or name. Unknown and disabled apps are rejected before the code is used.
+//
+// If a second factor is needed, returns *auth.MFARequiredError with the challenge to complete,
+// the same as password login does.
+func (p *Phone) CompletePhoneLogin(ctx context.Context, number string, code string, appIDOrName string) (string, string, error) {
+	const op = "phone.CompletePhoneLogin"
+
+	log := p.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("completing phone login")
+
+	number, err := phone.Normalize(number)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidPhone)
+	}
+
+	app, err := p.appProvider.LookupApp(ctx, appIDOrName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return "", "", fmt.Errorf("%s: %w", op, ErrInvalidApp)
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !app.Enabled {
+		log.Warn("app is disabled", slog.String("app", app.Name))
+
+		return "", "", fmt.Errorf("%s: %w", op, ErrAppDisabled)
+	}
+
+	phoneCode, err := p.useCode(ctx, number, models.PhoneCodeLogin, code)
+	if err != nil {
+		log.Warn("failed to use login code", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := p.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierPhone, Value: number})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Phone could have moved to another user after the code was sent.
+	if user.ID != phoneCode.UserID {
+		log.Warn("phone owner changed since code was sent")
+
+		return "", "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	if !user.IsActive(time.Now()) {
+		log.Warn("user is not active", slog.String("status", string(user.Status)))
+
+		return "", "", fmt.Errorf("%s: %w", op, ErrUserInactive)
+	}
+
+	admitted, err := app.Admits(ctx, p.appProvider, user)
+	if err != nil {
+		log.Error("failed to check app access", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	if !admitted {
+		log.Warn("user is not allowed into the app", slog.String("app", app.Name), slog.String("access_mode", string(app.AccessMode)))
+
+		return "", "", fmt.Errorf("%s: %w", op, ErrAppAccessDenied)
+	}
+
+	challenge, required, err := p.mfaChallenger.Challenge(ctx, user, app, models.AuthMethodSMS)
+	if err != nil {
+		log.Error("failed to issue mfa challenge", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	if required {
+		log.Info("mfa challenge issued", slog.Bool("enrollment_required", challenge.EnrollmentRequired))
+
+		return "", "", fmt.Errorf("%s: %w", op, &auth.MFARequiredError{
+			ChallengeID:        challenge.ID.String(),
+			EnrollmentRequired: challenge.EnrollmentRequired,
+			Methods:            challenge.Methods,
+		})
+	}
+
+	accessToken, refreshToken, err := jwt.NewTokenPair(user, app, models.NewAuthentication(models.AuthMethodSMS), p.tokenTTL, p.refreshTokenTTL)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := p.phoneSaver.RecordLogin(ctx, user.ID.String(), app.ID.String()); err != nil {
+		log.Error("failed to record login", sl.Err(err))
+	}
+
+	log.Info("successfully logged in")
+
+	return accessToken, refreshToken, nil
+}
+
+// StartPhoneVerification sends verification code to the phone the owner of the access token
+// wants to attach.
+func (p *Phone) StartPhoneVerification(ctx context.Context, accessToken string, number string) error {
+	const op = "phone.StartPhoneVerification"
+
+	log := p.log.With(
+		slog.String("op", op),
+	)
+
+	user, err := p.tokenOwner(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("user_id", user.ID.String()))
+
+	log.Info("starting phone verification")
+
+	number, err = phone.Normalize(number)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrInvalidPhone)
+	}
+
+	if err := p.checkRateLimit(ctx, number); err != nil {
+		log.Warn("phone code rate limited", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := p.sendCode(ctx, number, models.PhoneCodeVerify, user.ID); err != nil {
+		log.Error("failed to send verification code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("verification code sent")
+
+	return nil
+}
+
+// ConfirmPhone checks verification code and attaches the phone to the owner of the access token.
+func (p *Phone) ConfirmPhone(ctx context.Context, accessToken string, number string, code string) error {
+	const op = "phone.ConfirmPhone"
+
+	log := p.log.With(
+		slog.String("op", op),
+	)
+
+	user, err := p.tokenOwner(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	userID := user.ID.String()
+	log = log.With(slog.String("user_id", userID))
+
+	log.Info("confirming phone")
+
+	number, err = phone.Normalize(number)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrInvalidPhone)
+	}
+
+	phoneCode, err := p.useCode(ctx, number, models.PhoneCodeVerify, code)
+	if err != nil {
+		log.Warn("failed to use verification code", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if phoneCode.UserID != user.ID {
+		log.Warn("verification code was issued to another user")
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	if err := p.phoneSaver.SetVerifiedPhone(ctx, userID, number); err != nil {
+		if errors.Is(err, storage.ErrPhoneTaken) {
+			log.Warn("phone already taken", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrPhoneTaken)
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to save phone", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("phone confirmed")
+
+	return nil
+}
+
+// tokenOwner returns the owner of a valid access token, if they are active
+// and the session of the token was not revoked.
+func (p *Phone) tokenOwner(ctx context.Context, accessToken string) (models.User, error) {
+	claims, err := jwt.ParseAccessToken(accessToken)
+	if err != nil {
+		return models.User{}, ErrInvalidToken
+	}
+
+	user, err := p.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: claims.UserID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, ErrInvalidToken
+		}
+
+		return models.User{}, err
+	}
+
+	if !user.IsActive(time.Now()) || claims.IssuedAt.Before(user.SessionsRevokedAt) {
+		return models.User{}, ErrInvalidToken
+	}
+
+	return user, nil
+}
+
+func (p *Phone) checkRateLimit(ctx context.Context, number string) error {
+	count, last, err := p.codeStorage.PhoneCodeStats(ctx, number, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+
+	if count >= p.limits.MaxCodesPerHour || time.Since(last) < p.limits.ResendInterval {
+		return ErrRateLimited
+	}
+
+	return nil
+}
+
+func (p *Phone) sendCode(ctx context.Context, number string, purpose models.PhoneCodePurpose, userID uuid.UUID) error {
+	code, err := otp.GenerateCode(codeDigits)
+	if err != nil {
+		return err
+	}
+
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.codeStorage.SavePhoneCode(ctx, models.PhoneCode{
+		Phone:     number,
+		Purpose:   purpose,
+		UserID:    userID,
+		CodeHash:  codeHash,
+		ExpiresAt: time.Now().Add(p.limits.CodeTTL),
+	}); err != nil {
+		return err
+	}
+
+	return p.smsSender.Send(ctx, number, fmt.Sprintf("Your code: %s", code))
+}
+
+// useCode checks code against the latest active one and consumes it on success.
+// Every check counts as an attempt, and it is counted before the code is compared,
+// so after MaxAttempts the code is unusable even for concurrent guesses.
+func (p *Phone) useCode(ctx context.Context, number string, purpose models.PhoneCodePurpose, code string) (models.PhoneCode, error) {
+	phoneCode, err := p.codeStorage.ActivePhoneCode(ctx, number, purpose)
+	if err != nil {
+		if errors.Is(err, storage.ErrCodeNotFound) {
+			return models.PhoneCode{}, ErrInvalidCode
+		}
+
+		return models.PhoneCode{}, err
+	}
+
+	if err := p.codeStorage.UsePhoneCodeAttempt(ctx, phoneCode.ID.String(), p.limits.MaxAttempts); err != nil {
+		if errors.Is(err, storage.ErrCodeNotFound) {
+			return models.PhoneCode{}, ErrInvalidCode
+		}
+
+		return models.PhoneCode{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(phoneCode.CodeHash, []byte(code)); err != nil {
+		return models.PhoneCode{}, ErrInvalidCode
+	}
+
+	if err := p.codeStorage.ConsumePhoneCode(ctx, phoneCode.ID.String()); err != nil {
+		if errors.Is(err, storage.ErrCodeNotFound) {
+			return models.PhoneCode{}, ErrInvalidCode
+		}
+
+		return models.PhoneCode{}, err
+	}
+
+	return phoneCode, nil
+}
@@ -0,0 +1,471 @@
+package phone
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/services/auth"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPhone = "+15551234567"
+
+func TestCompletePhoneLogin(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.StartPhoneLogin(ctx, testPhone))
+
+	accessToken, refreshToken, err := env.service.CompletePhoneLogin(ctx, testPhone, env.sms.code(), env.app.Name)
+	require.NoError(t, err)
+	assert.NotEmpty(t, accessToken)
+	assert.NotEmpty(t, refreshToken)
+
+	// The code is consumed.
+	_, _, err = env.service.CompletePhoneLogin(ctx, testPhone, env.sms.code(), env.app.Name)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestCompletePhoneLogin_AppLookup(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.StartPhoneLogin(ctx, testPhone))
+
+	// A mistyped app leaves the code for another attempt.
+	_, _, err := env.service.CompletePhoneLogin(ctx, testPhone, env.sms.code(), "unknown")
+	assert.ErrorIs(t, err, ErrInvalidApp)
+
+	accessToken, _, err := env.service.CompletePhoneLogin(ctx, testPhone, env.sms.code(), env.app.ID.String())
+	require.NoError(t, err)
+	assert.NotEmpty(t, accessToken)
+}
+
+func TestCompletePhoneLogin_MFARequired(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.storage.mfaRequired = true
+
+	require.NoError(t, env.service.StartPhoneLogin(ctx, testPhone))
+
+	accessToken, _, err := env.service.CompletePhoneLogin(ctx, testPhone, env.sms.code(), env.app.Name)
+	assert.Empty(t, accessToken)
+
+	var mfaErr *auth.MFARequiredError
+	require.ErrorAs(t, err, &mfaErr)
+	require.Len(t, env.storage.challenges, 1)
+
+	challenge := env.storage.challenges[0]
+	assert.Equal(t, challenge.ID.String(), mfaErr.ChallengeID)
+	assert.Equal(t, []models.MFAMethod{models.MFAMethodTOTP}, mfaErr.Methods)
+	assert.Equal(t, models.AuthMethodSMS, challenge.FirstFactor)
+	assert.Equal(t, env.user.ID, challenge.UserID)
+}
+
+func TestCompletePhoneLogin_Lockout(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.StartPhoneLogin(ctx, testPhone))
+	code := env.sms.code()
+
+	for range env.service.limits.MaxAttempts {
+		_, _, err := env.service.CompletePhoneLogin(ctx, testPhone, "000000"+code, env.app.Name)
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	// After MaxAttempts wrong guesses even the right code is refused.
+	_, _, err := env.service.CompletePhoneLogin(ctx, testPhone, code, env.app.Name)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestCompletePhoneLogin_ConcurrentGuesses(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.StartPhoneLogin(ctx, testPhone))
+
+	var wg sync.WaitGroup
+	for range 4 * env.service.limits.MaxAttempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, _, _ = env.service.CompletePhoneLogin(ctx, testPhone, "wrong", env.app.Name)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, env.service.limits.MaxAttempts, env.storage.lastCode().Attempts)
+}
+
+func TestCompletePhoneLogin_OwnerChanged(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.StartPhoneLogin(ctx, testPhone))
+
+	// The phone moves to another user after the code was sent.
+	env.user.Phone = ""
+	env.storage.users[env.user.ID] = env.user
+	other := models.User{ID: uuid.New(), Phone: testPhone, Status: models.UserStatusActive}
+	env.storage.users[other.ID] = other
+
+	_, _, err := env.service.CompletePhoneLogin(ctx, testPhone, env.sms.code(), env.app.Name)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestCompletePhoneLogin_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(env *testEnv)
+		appName string
+		want    error
+	}{
+		{
+			name: "suspended user",
+			prepare: func(env *testEnv) {
+				env.user.Status = models.UserStatusSuspended
+				env.storage.users[env.user.ID] = env.user
+			},
+			want: ErrUserInactive,
+		},
+		{
+			name:    "unknown app",
+			appName: "unknown",
+			want:    ErrInvalidApp,
+		},
+		{
+			name: "disabled app",
+			prepare: func(env *testEnv) {
+				env.app.Enabled = false
+				env.storage.apps[env.app.Name] = env.app
+			},
+			want: ErrAppDisabled,
+		},
+		{
+			name: "members only app",
+			prepare: func(env *testEnv) {
+				env.app.AccessMode = models.AppAccessMembers
+				env.storage.apps[env.app.Name] = env.app
+			},
+			want: ErrAppAccessDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+
+			require.NoError(t, env.service.StartPhoneLogin(ctx, testPhone))
+			if tt.prepare != nil {
+				tt.prepare(env)
+			}
+
+			appName := tt.appName
+			if appName == "" {
+				appName = env.app.Name
+			}
+
+			_, _, err := env.service.CompletePhoneLogin(ctx, testPhone, env.sms.code(), appName)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestStartPhoneLogin_RateLimited(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.StartPhoneLogin(ctx, testPhone))
+
+	err := env.service.StartPhoneLogin(ctx, testPhone)
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
+
+func TestStartPhoneLogin_UnknownPhone(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Unknown phones are not disclosed, the code is just not sent.
+	require.NoError(t, env.service.StartPhoneLogin(context.Background(), "+15550000000"))
+	assert.Empty(t, env.sms.sent)
+}
+
+func TestConfirmPhone(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	const newPhone = "+15557654321"
+
+	require.NoError(t, env.service.StartPhoneVerification(ctx, env.accessToken, newPhone))
+	require.NoError(t, env.service.ConfirmPhone(ctx, env.accessToken, newPhone, env.sms.code()))
+	assert.Equal(t, newPhone, env.storage.users[env.user.ID].Phone)
+}
+
+func TestConfirmPhone_Rejected(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	const newPhone = "+15557654321"
+
+	err := env.service.StartPhoneVerification(ctx, "not-a-token", newPhone)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	require.NoError(t, env.service.StartPhoneVerification(ctx, env.accessToken, newPhone))
+
+	// Another user can't confirm the code sent for the phone.
+	other := models.User{ID: uuid.New(), Email: "bob@example.com", Status: models.UserStatusActive}
+	env.storage.users[other.ID] = other
+	otherToken, _, err := jwt.NewTokenPair(other, env.app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	err = env.service.ConfirmPhone(ctx, otherToken, newPhone, env.sms.code())
+	assert.ErrorIs(t, err, ErrInvalidCode)
+	assert.Empty(t, env.storage.users[other.ID].Phone)
+}
+
+type testEnv struct {
+	service     *Phone
+	storage     *fakeStorage
+	sms         *fakeSender
+	user        models.User
+	app         models.App
+	accessToken string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	user := models.User{ID: uuid.New(), Email: "alice@example.com", Phone: testPhone, Status: models.UserStatusActive}
+	app := models.App{ID: uuid.New(), Name: "test-app", Enabled: true, AccessMode: models.AppAccessOpen}
+
+	fake := &fakeStorage{
+		users: map[uuid.UUID]models.User{user.ID: user},
+		apps:  map[string]models.App{app.Name: app},
+	}
+	sender := &fakeSender{}
+
+	accessToken, _, err := jwt.NewTokenPair(user, app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	limits := Limits{
+		CodeTTL:         5 * time.Minute,
+		ResendInterval:  time.Minute,
+		MaxCodesPerHour: 5,
+		MaxAttempts:     3,
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testEnv{
+		service:     New(log, fake, fake, fake, fake, fake, sender, limits, time.Hour, time.Hour),
+		storage:     fake,
+		sms:         sender,
+		user:        user,
+		app:         app,
+		accessToken: accessToken,
+	}
+}
+
+// fakeSender keeps sent messages instead of sending them.
+type fakeSender struct {
+	sent []string
+}
+
+func (f *fakeSender) Send(_ context.Context, _ string, text string) error {
+	f.sent = append(f.sent, text)
+
+	return nil
+}
+
+// code returns the code of the last sent message.
+func (f *fakeSender) code() string {
+	if len(f.sent) == 0 {
+		return ""
+	}
+
+	return strings.TrimPrefix(f.sent[len(f.sent)-1], "Your code: ")
+}
+
+type fakeStorage struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]models.User
+	apps  map[string]models.App
+	codes []models.PhoneCode
+	used  map[uuid.UUID]bool
+	// challenges are MFA challenges issued so far; mfaRequired makes every login need one.
+	challenges  []models.MFAChallenge
+	mfaRequired bool
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, user := range f.users {
+		switch {
+		case identifier.Kind == models.IdentifierID && user.ID.String() == identifier.Value,
+			identifier.Kind == models.IdentifierPhone && user.Phone != "" && user.Phone == identifier.Value:
+			return user, nil
+		}
+	}
+
+	return models.User{}, storage.ErrUserNotFound
+}
+
+func (f *fakeStorage) SetVerifiedPhone(_ context.Context, userID string, phone string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for id, user := range f.users {
+		if user.Phone == phone && id.String() != userID {
+			return storage.ErrPhoneTaken
+		}
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return storage.ErrUserNotFound
+	}
+	user, ok := f.users[id]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	user.Phone = phone
+	f.users[id] = user
+
+	return nil
+}
+
+func (f *fakeStorage) RecordLogin(context.Context, string, string) error {
+	return nil
+}
+
+func (f *fakeStorage) SavePhoneCode(_ context.Context, code models.PhoneCode) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code.ID = uuid.New()
+	code.CreatedAt = time.Now()
+	f.codes = append(f.codes, code)
+
+	return code.ID.String(), nil
+}
+
+func (f *fakeStorage) PhoneCodeStats(_ context.Context, phone string, since time.Time) (int, time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var (
+		count int
+		last  time.Time
+	)
+	for _, code := range f.codes {
+		if code.Phone == phone && code.CreatedAt.After(since) {
+			count++
+			last = code.CreatedAt
+		}
+	}
+
+	return count, last, nil
+}
+
+func (f *fakeStorage) ActivePhoneCode(_ context.Context, phone string, purpose models.PhoneCodePurpose) (models.PhoneCode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := len(f.codes) - 1; i >= 0; i-- {
+		code := f.codes[i]
+		if code.Phone == phone && code.Purpose == purpose && !f.used[code.ID] && time.Now().Before(code.ExpiresAt) {
+			return code, nil
+		}
+	}
+
+	return models.PhoneCode{}, storage.ErrCodeNotFound
+}
+
+func (f *fakeStorage) UsePhoneCodeAttempt(_ context.Context, codeID string, maxAttempts int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, code := range f.codes {
+		if code.ID.String() == codeID && !f.used[code.ID] && code.Attempts < maxAttempts {
+			f.codes[i].Attempts++
+
+			return nil
+		}
+	}
+
+	return storage.ErrCodeNotFound
+}
+
+func (f *fakeStorage) ConsumePhoneCode(_ context.Context, codeID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, code := range f.codes {
+		if code.ID.String() == codeID && !f.used[code.ID] {
+			if f.used == nil {
+				f.used = make(map[uuid.UUID]bool)
+			}
+			f.used[code.ID] = true
+
+			return nil
+		}
+	}
+
+	return storage.ErrCodeNotFound
+}
+
+func (f *fakeStorage) lastCode() models.PhoneCode {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.codes[len(f.codes)-1]
+}
+
+func (f *fakeStorage) LookupApp(_ context.Context, idOrName string) (models.App, error) {
+	for _, app := range f.apps {
+		if app.ID.String() == idOrName || app.Name == idOrName {
+			return app, nil
+		}
+	}
+
+	return models.App{}, storage.ErrAppNotFound
+}
+
+func (f *fakeStorage) IsAppMember(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeStorage) Challenge(_ context.Context, user models.User, app models.App, firstFactor models.AuthMethod) (models.MFAChallenge, bool, error) {
+	if !f.mfaRequired {
+		return models.MFAChallenge{}, false, nil
+	}
+
+	challenge := models.MFAChallenge{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		AppID:       app.ID,
+		FirstFactor: firstFactor,
+		Methods:     []models.MFAMethod{models.MFAMethodTOTP},
+	}
+	f.challenges = append(f.challenges, challenge)
+
+	return challenge, true, nil
+}
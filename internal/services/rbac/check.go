@@ -0,0 +1,139 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+type grantsKey struct {
+	userID string
+	appID  uuid.UUID
+}
+
+// grants is what CheckPermissions needs to know about a user in an app.
+type grants struct {
+	active      bool
+	permissions map[string]struct{}
+}
+
+// CheckPermissions reports for each permission whether the user has it in the app.
+// Inactive users have no permissions, and disabled apps reject checks.
+//
+// Answers are cached in process. Role changes made through this service, status
+// changes reported through InvalidateUser and app changes reported through
+// InvalidateApps apply at once; changes made by another instance show up once cached
+// entries expire.
+func (r *RBAC) CheckPermissions(ctx context.Context, userID string, appName string, permissions []string) ([]bool, error) {
+	const op = "rbac.CheckPermissions"
+
+	appID, err := r.appID(ctx, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+		if errors.Is(err, ErrAppDisabled) {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		r.log.Error("failed to get app", slog.String("op", op), slog.String("app", appName), sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	g, err := r.grants(ctx, userID, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		r.log.Error("failed to get user permissions",
+			slog.String("op", op), slog.String("user_id", userID), slog.String("app", appName), sl.Err(err),
+		)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	allowed := make([]bool, len(permissions))
+	if !g.active {
+		return allowed, nil
+	}
+	for i, permission := range permissions {
+		_, allowed[i] = g.permissions[permission]
+	}
+
+	return allowed, nil
+}
+
+func (r *RBAC) appID(ctx context.Context, appName string) (uuid.UUID, error) {
+	app, ok := r.appCache.Get(appName)
+	if !ok {
+		generation := r.appCache.Generation()
+
+		var err error
+		app, err = r.appProvider.LookupApp(ctx, appName)
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		r.appCache.Set(appName, app, generation)
+	}
+
+	if !app.Enabled {
+		return uuid.Nil, ErrAppDisabled
+	}
+
+	return app.ID, nil
+}
+
+func (r *RBAC) grants(ctx context.Context, userID string, appID uuid.UUID) (grants, error) {
+	key := grantsKey{userID: userID, appID: appID}
+	if g, ok := r.grantsCache.Get(key); ok {
+		return g, nil
+	}
+
+	generation := r.grantsCache.Generation()
+
+	user, err := r.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID})
+	if err != nil {
+		return grants{}, err
+	}
+
+	names, err := r.roleStorage.UserPermissions(ctx, userID, appID)
+	if err != nil {
+		return grants{}, err
+	}
+
+	g := grants{
+		active:      user.IsActive(time.Now()),
+		permissions: make(map[string]struct{}, len(names)),
+	}
+	for _, name := range names {
+		g.permissions[name] = struct{}{}
+	}
+
+	r.grantsCache.Set(key, g, generation)
+
+	return g, nil
+}
+
+// InvalidateUser drops cached permissions of the user in every app. Services changing
+// what CheckPermissions depends on outside of this service, e.g. the user status, call it.
+func (r *RBAC) InvalidateUser(userID string) {
+	r.grantsCache.InvalidateFunc(func(key grantsKey) bool {
+		return key.userID == userID
+	})
+}
+
+// InvalidateApps drops cached apps. Services changing apps, e.g. renaming, disabling or
+// deleting them, call it.
+func (r *RBAC) InvalidateApps() {
+	r.appCache.Invalidate()
+}
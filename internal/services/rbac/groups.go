@@ -0,0 +1,349 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const (
+	eventGroupCreated        = "group.created"
+	eventGroupDeleted        = "group.deleted"
+	eventGroupMemberAdded    = "group.member_added"
+	eventGroupMemberRemoved  = "group.member_removed"
+	eventSubgroupAdded       = "group.subgroup_added"
+	eventSubgroupRemoved     = "group.subgroup_removed"
+	eventGroupRoleAssigned   = "group.role_assigned"
+	eventGroupRoleUnassigned = "group.role_unassigned"
+)
+
+type GroupStorage interface {
+	CreateGroup(ctx context.Context, group models.Group) (models.Group, error)
+	DeleteGroup(ctx context.Context, name string) error
+	AddGroupMember(ctx context.Context, groupName string, userID string) error
+	RemoveGroupMember(ctx context.Context, groupName string, userID string) error
+	AddSubgroup(ctx context.Context, groupName string, subgroupName string) error
+	RemoveSubgroup(ctx context.Context, groupName string, subgroupName string) error
+	AssignGroupRole(ctx context.Context, groupName string, appID uuid.UUID, roleName string) error
+	UnassignGroupRole(ctx context.Context, groupName string, appID uuid.UUID, roleName string) error
+	UserGroups(ctx context.Context, userID string) ([]models.Group, error)
+}
+
+var (
+	ErrGroupExists        = errors.New("group already exists")
+	ErrGroupNotFound      = errors.New("group not found")
+	ErrGroupCycle         = errors.New("group cannot contain itself")
+	ErrMembershipNotFound = errors.New("group membership not found")
+)
+
+// CreateGroup creates an empty group.
+func (r *RBAC) CreateGroup(ctx context.Context, adminToken string, name string, description string) (models.Group, error) {
+	const op = "rbac.CreateGroup"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("group", name),
+	)
+
+	log.Info("creating group")
+
+	actor, _, err := r.prepare(ctx, log, adminToken, "")
+	if err != nil {
+		return models.Group{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !nameRe.MatchString(name) {
+		log.Warn("invalid group name")
+
+		return models.Group{}, fmt.Errorf("%s: %w", op, ErrInvalidName)
+	}
+
+	group, err := r.groupStorage.CreateGroup(ctx, models.Group{Name: name, Description: description})
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupExists) {
+			log.Warn("group already exists", sl.Err(err))
+
+			return models.Group{}, fmt.Errorf("%s: %w", op, ErrGroupExists)
+		}
+
+		log.Error("failed to create group", sl.Err(err))
+
+		return models.Group{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.audit(ctx, log, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   eventGroupCreated,
+		Details: map[string]any{"group": name},
+	})
+
+	log.Info("group created")
+
+	return group, nil
+}
+
+// DeleteGroup deletes the group. Its members lose roles inherited through it.
+func (r *RBAC) DeleteGroup(ctx context.Context, adminToken string, name string) error {
+	const op = "rbac.DeleteGroup"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("group", name),
+	)
+
+	err := r.changeGroups(ctx, log, adminToken, "", models.AuditEvent{
+		Event:   eventGroupDeleted,
+		Details: map[string]any{"group": name},
+	}, func(uuid.UUID) error {
+		return r.groupStorage.DeleteGroup(ctx, name)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AddGroupMember adds the user to the group.
+func (r *RBAC) AddGroupMember(ctx context.Context, adminToken string, groupName string, userID string) error {
+	const op = "rbac.AddGroupMember"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("user_id", userID),
+	)
+
+	err := r.changeGroups(ctx, log, adminToken, "", models.AuditEvent{
+		UserID:  parseID(userID),
+		Event:   eventGroupMemberAdded,
+		Details: map[string]any{"group": groupName},
+	}, func(uuid.UUID) error {
+		return r.groupStorage.AddGroupMember(ctx, groupName, userID)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveGroupMember removes the user from the group. Membership inherited
+// through a subgroup is removed by removing the user from that subgroup.
+func (r *RBAC) RemoveGroupMember(ctx context.Context, adminToken string, groupName string, userID string) error {
+	const op = "rbac.RemoveGroupMember"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("user_id", userID),
+	)
+
+	err := r.changeGroups(ctx, log, adminToken, "", models.AuditEvent{
+		UserID:  parseID(userID),
+		Event:   eventGroupMemberRemoved,
+		Details: map[string]any{"group": groupName},
+	}, func(uuid.UUID) error {
+		return r.groupStorage.RemoveGroupMember(ctx, groupName, userID)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AddSubgroup nests the subgroup into the group, so its members become members of the group.
+// Returns ErrGroupCycle if the group is already nested into the subgroup.
+func (r *RBAC) AddSubgroup(ctx context.Context, adminToken string, groupName string, subgroupName string) error {
+	const op = "rbac.AddSubgroup"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("subgroup", subgroupName),
+	)
+
+	err := r.changeGroups(ctx, log, adminToken, "", models.AuditEvent{
+		Event:   eventSubgroupAdded,
+		Details: map[string]any{"group": groupName, "subgroup": subgroupName},
+	}, func(uuid.UUID) error {
+		return r.groupStorage.AddSubgroup(ctx, groupName, subgroupName)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveSubgroup takes the subgroup out of the group.
+func (r *RBAC) RemoveSubgroup(ctx context.Context, adminToken string, groupName string, subgroupName string) error {
+	const op = "rbac.RemoveSubgroup"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("subgroup", subgroupName),
+	)
+
+	err := r.changeGroups(ctx, log, adminToken, "", models.AuditEvent{
+		Event:   eventSubgroupRemoved,
+		Details: map[string]any{"group": groupName, "subgroup": subgroupName},
+	}, func(uuid.UUID) error {
+		return r.groupStorage.RemoveSubgroup(ctx, groupName, subgroupName)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AssignGroupRole gives members of the group the role within the app,
+// or across all apps if appName is empty.
+func (r *RBAC) AssignGroupRole(ctx context.Context, adminToken string, groupName string, appName string, roleName string) error {
+	const op = "rbac.AssignGroupRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("app", appName),
+		slog.String("role", roleName),
+	)
+
+	err := r.changeGroups(ctx, log, adminToken, appName, models.AuditEvent{
+		Event:   eventGroupRoleAssigned,
+		Details: map[string]any{"group": groupName, "app": appName, "role": roleName},
+	}, func(appID uuid.UUID) error {
+		return r.groupStorage.AssignGroupRole(ctx, groupName, appID, roleName)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UnassignGroupRole takes the role assigned within the app, or across all apps
+// if appName is empty, away from the group.
+func (r *RBAC) UnassignGroupRole(ctx context.Context, adminToken string, groupName string, appName string, roleName string) error {
+	const op = "rbac.UnassignGroupRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("group", groupName),
+		slog.String("app", appName),
+		slog.String("role", roleName),
+	)
+
+	err := r.changeGroups(ctx, log, adminToken, appName, models.AuditEvent{
+		Event:   eventGroupRoleUnassigned,
+		Details: map[string]any{"group": groupName, "app": appName, "role": roleName},
+	}, func(appID uuid.UUID) error {
+		return r.groupStorage.UnassignGroupRole(ctx, groupName, appID, roleName)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UserGroups returns groups the user belongs to, directly or through subgroups.
+func (r *RBAC) UserGroups(ctx context.Context, adminToken string, userID string) ([]models.Group, error) {
+	const op = "rbac.UserGroups"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID),
+	)
+
+	if _, _, err := r.prepare(ctx, log, adminToken, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := r.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID}); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	groups, err := r.groupStorage.UserGroups(ctx, userID)
+	if err != nil {
+		log.Error("failed to get user groups", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return groups, nil
+}
+
+// changeGroups authorizes the caller, applies the change within the app scope, drops cached
+// permissions of everyone it may affect and records the audit event on behalf of the caller.
+func (r *RBAC) changeGroups(
+	ctx context.Context,
+	log *slog.Logger,
+	adminToken string,
+	appName string,
+	event models.AuditEvent,
+	change func(appID uuid.UUID) error,
+) error {
+	log.Info("changing groups", slog.String("event", event.Event))
+
+	actor, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return err
+	}
+	log = log.With(slog.String("actor_id", actor.ID.String()))
+
+	if err := change(appID); err != nil {
+		for storageErr, serviceErr := range groupErrors {
+			if errors.Is(err, storageErr) {
+				log.Warn("groups not changed", sl.Err(err))
+
+				return serviceErr
+			}
+		}
+
+		log.Error("failed to change groups", sl.Err(err))
+
+		return err
+	}
+	r.grantsCache.Invalidate()
+
+	event.ActorID = actor.ID
+	r.audit(ctx, log, event)
+
+	log.Info("groups changed")
+
+	return nil
+}
+
+// groupErrors maps storage errors of group changes to errors of the service.
+var groupErrors = map[error]error{
+	storage.ErrGroupNotFound:      ErrGroupNotFound,
+	storage.ErrGroupCycle:         ErrGroupCycle,
+	storage.ErrMembershipNotFound: ErrMembershipNotFound,
+	storage.ErrUserNotFound:       ErrUserNotFound,
+	storage.ErrRoleNotFound:       ErrRoleNotFound,
+}
+
+// parseID returns zero ID for malformed IDs, which storage rejects anyway.
+func parseID(id string) uuid.UUID {
+	parsed, _ := uuid.Parse(id)
+
+	return parsed
+}
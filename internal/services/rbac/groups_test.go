@@ -0,0 +1,391 @@
+package rbac
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroups_InheritedPermissions(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	_, err := env.service.CreateRole(ctx, env.adminToken, env.app.Name, "deployer", "")
+	require.NoError(t, err)
+	_, err = env.service.CreatePermission(ctx, env.adminToken, env.app.Name, "deploys:create", "")
+	require.NoError(t, err)
+	require.NoError(t, env.service.GrantPermission(ctx, env.adminToken, env.app.Name, "deployer", "deploys:create"))
+
+	for _, name := range []string{"engineering", "backend"} {
+		_, err := env.service.CreateGroup(ctx, env.adminToken, name, "")
+		require.NoError(t, err)
+	}
+	require.NoError(t, env.service.AssignGroupRole(ctx, env.adminToken, "engineering", env.app.Name, "deployer"))
+	require.NoError(t, env.service.AddGroupMember(ctx, env.adminToken, "backend", user.ID.String()))
+
+	check := func() bool {
+		allowed, err := env.service.CheckPermissions(ctx, user.ID.String(), env.app.Name, []string{"deploys:create"})
+		require.NoError(t, err)
+
+		return allowed[0]
+	}
+
+	assert.False(t, check())
+
+	// Members of a subgroup hold roles of the group, although the answer above was cached.
+	require.NoError(t, env.service.AddSubgroup(ctx, env.adminToken, "engineering", "backend"))
+	assert.True(t, check())
+
+	groups, err := env.service.UserGroups(ctx, env.adminToken, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backend", "engineering"}, groupNames(groups))
+
+	require.NoError(t, env.service.RemoveSubgroup(ctx, env.adminToken, "engineering", "backend"))
+	assert.False(t, check())
+
+	require.NoError(t, env.service.AddSubgroup(ctx, env.adminToken, "engineering", "backend"))
+	require.NoError(t, env.service.RemoveGroupMember(ctx, env.adminToken, "backend", user.ID.String()))
+	assert.False(t, check())
+
+	require.NoError(t, env.service.AddGroupMember(ctx, env.adminToken, "engineering", user.ID.String()))
+	assert.True(t, check())
+
+	require.NoError(t, env.service.UnassignGroupRole(ctx, env.adminToken, "engineering", env.app.Name, "deployer"))
+	assert.False(t, check())
+
+	require.NoError(t, env.service.DeleteGroup(ctx, env.adminToken, "engineering"))
+	groups, err = env.service.UserGroups(ctx, env.adminToken, user.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	for _, event := range env.storage.events {
+		assert.Equal(t, env.admin.ID, event.ActorID)
+	}
+}
+
+func TestAddSubgroup_Cycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	// a contains b, which contains c.
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := env.service.CreateGroup(ctx, env.adminToken, name, "")
+		require.NoError(t, err)
+	}
+	require.NoError(t, env.service.AddSubgroup(ctx, env.adminToken, "a", "b"))
+	require.NoError(t, env.service.AddSubgroup(ctx, env.adminToken, "b", "c"))
+	require.NoError(t, env.service.AddGroupMember(ctx, env.adminToken, "c", user.ID.String()))
+
+	events := len(env.storage.events)
+
+	tests := []struct {
+		name     string
+		group    string
+		subgroup string
+	}{
+		{name: "itself", group: "a", subgroup: "a"},
+		{name: "direct parent", group: "b", subgroup: "a"},
+		{name: "indirect parent", group: "c", subgroup: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.service.AddSubgroup(ctx, env.adminToken, tt.group, tt.subgroup)
+			assert.ErrorIs(t, err, ErrGroupCycle)
+		})
+	}
+
+	assert.Len(t, env.storage.events, events, "rejected changes are not audited")
+
+	groups, err := env.service.UserGroups(ctx, env.adminToken, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, groupNames(groups))
+
+	// A group may sit under several parents as long as no path leads back to it.
+	_, err = env.service.CreateGroup(ctx, env.adminToken, "d", "")
+	require.NoError(t, err)
+	require.NoError(t, env.service.AddSubgroup(ctx, env.adminToken, "d", "c"))
+}
+
+func TestGroupChanges_Rejected(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	_, err := env.service.CreateGroup(ctx, env.adminToken, "support", "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{
+			name: "not admin",
+			call: func() error {
+				_, err := env.service.CreateGroup(ctx, env.token(user), "ops", "")
+				return err
+			},
+			want: ErrPermissionDenied,
+		},
+		{
+			name: "invalid name",
+			call: func() error {
+				_, err := env.service.CreateGroup(ctx, env.adminToken, "Ops Team", "")
+				return err
+			},
+			want: ErrInvalidName,
+		},
+		{
+			name: "duplicate",
+			call: func() error {
+				_, err := env.service.CreateGroup(ctx, env.adminToken, "support", "")
+				return err
+			},
+			want: ErrGroupExists,
+		},
+		{
+			name: "unknown group",
+			call: func() error {
+				return env.service.AddGroupMember(ctx, env.adminToken, "missing", user.ID.String())
+			},
+			want: ErrGroupNotFound,
+		},
+		{
+			name: "unknown user",
+			call: func() error {
+				return env.service.AddGroupMember(ctx, env.adminToken, "support", uuid.NewString())
+			},
+			want: ErrUserNotFound,
+		},
+		{
+			name: "remove non-member",
+			call: func() error {
+				return env.service.RemoveGroupMember(ctx, env.adminToken, "support", user.ID.String())
+			},
+			want: ErrMembershipNotFound,
+		},
+		{
+			name: "unknown role",
+			call: func() error {
+				return env.service.AssignGroupRole(ctx, env.adminToken, "support", env.app.Name, "missing")
+			},
+			want: ErrRoleNotFound,
+		},
+		{
+			name: "unknown app",
+			call: func() error {
+				return env.service.AssignGroupRole(ctx, env.adminToken, "support", "missing", models.RoleAdmin)
+			},
+			want: ErrAppNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.call(), tt.want)
+		})
+	}
+}
+
+func groupNames(groups []models.Group) []string {
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// fakeGroup is a group with its direct members, subgroups and roles.
+type fakeGroup struct {
+	group     models.Group
+	members   []uuid.UUID
+	subgroups []string
+	roles     []assignment
+}
+
+// userGroups returns names of groups the user belongs to, directly or through subgroups.
+func (f *fakeStorage) userGroups(userID uuid.UUID) []string {
+	var names []string
+	for name, g := range f.groups {
+		if slices.Contains(g.members, userID) {
+			names = append(names, name)
+		}
+	}
+
+	for i := 0; i < len(names); i++ {
+		for name, g := range f.groups {
+			if slices.Contains(g.subgroups, names[i]) && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+// descendants returns the group and every group nested into it.
+func (f *fakeStorage) descendants(name string) []string {
+	names := []string{name}
+	for i := 0; i < len(names); i++ {
+		for _, sub := range f.groups[names[i]].subgroups {
+			if !slices.Contains(names, sub) {
+				names = append(names, sub)
+			}
+		}
+	}
+
+	return names
+}
+
+func (f *fakeStorage) CreateGroup(_ context.Context, group models.Group) (models.Group, error) {
+	if _, ok := f.groups[group.Name]; ok {
+		return models.Group{}, storage.ErrGroupExists
+	}
+
+	group.ID = uuid.New()
+	group.CreatedAt = time.Now()
+	f.groups[group.Name] = &fakeGroup{group: group}
+
+	return group, nil
+}
+
+func (f *fakeStorage) DeleteGroup(_ context.Context, name string) error {
+	if _, ok := f.groups[name]; !ok {
+		return storage.ErrGroupNotFound
+	}
+
+	delete(f.groups, name)
+	for _, g := range f.groups {
+		g.subgroups = slices.DeleteFunc(g.subgroups, func(sub string) bool { return sub == name })
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) AddGroupMember(_ context.Context, groupName string, userID string) error {
+	g, ok := f.groups[groupName]
+	if !ok {
+		return storage.ErrGroupNotFound
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return storage.ErrUserNotFound
+	}
+	if _, ok := f.users[id]; !ok {
+		return storage.ErrUserNotFound
+	}
+
+	if !slices.Contains(g.members, id) {
+		g.members = append(g.members, id)
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) RemoveGroupMember(_ context.Context, groupName string, userID string) error {
+	g, ok := f.groups[groupName]
+	if !ok {
+		return storage.ErrMembershipNotFound
+	}
+
+	i := slices.Index(g.members, parseID(userID))
+	if i < 0 {
+		return storage.ErrMembershipNotFound
+	}
+	g.members = slices.Delete(g.members, i, i+1)
+
+	return nil
+}
+
+func (f *fakeStorage) AddSubgroup(_ context.Context, groupName string, subgroupName string) error {
+	g, ok := f.groups[groupName]
+	if !ok {
+		return storage.ErrGroupNotFound
+	}
+	if _, ok := f.groups[subgroupName]; !ok {
+		return storage.ErrGroupNotFound
+	}
+
+	if slices.Contains(f.descendants(subgroupName), groupName) {
+		return storage.ErrGroupCycle
+	}
+
+	if !slices.Contains(g.subgroups, subgroupName) {
+		g.subgroups = append(g.subgroups, subgroupName)
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) RemoveSubgroup(_ context.Context, groupName string, subgroupName string) error {
+	g, ok := f.groups[groupName]
+	if !ok {
+		return storage.ErrMembershipNotFound
+	}
+
+	i := slices.Index(g.subgroups, subgroupName)
+	if i < 0 {
+		return storage.ErrMembershipNotFound
+	}
+	g.subgroups = slices.Delete(g.subgroups, i, i+1)
+
+	return nil
+}
+
+func (f *fakeStorage) AssignGroupRole(_ context.Context, groupName string, appID uuid.UUID, roleName string) error {
+	g, ok := f.groups[groupName]
+	if !ok {
+		return storage.ErrGroupNotFound
+	}
+
+	key, ok := f.role(appID, roleName)
+	if !ok {
+		return storage.ErrRoleNotFound
+	}
+
+	g.roles = append(g.roles, assignment{appID: appID, role: key})
+
+	return nil
+}
+
+func (f *fakeStorage) UnassignGroupRole(_ context.Context, groupName string, appID uuid.UUID, roleName string) error {
+	g, ok := f.groups[groupName]
+	if !ok {
+		return storage.ErrRoleNotFound
+	}
+
+	i := slices.IndexFunc(g.roles, func(a assignment) bool {
+		return a.appID == appID && a.role.name == roleName
+	})
+	if i < 0 {
+		return storage.ErrRoleNotFound
+	}
+	g.roles = slices.Delete(g.roles, i, i+1)
+
+	return nil
+}
+
+func (f *fakeStorage) UserGroups(_ context.Context, userID string) ([]models.Group, error) {
+	var groups []models.Group
+	for _, name := range f.userGroups(parseID(userID)) {
+		groups = append(groups, f.groups[name].group)
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,654 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/cache"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const (
+	eventRoleCreated        = "role.created"
+	eventRoleDeleted        = "role.deleted"
+	eventPermissionCreated  = "permission.created"
+	eventPermissionDeleted  = "permission.deleted"
+	eventPermissionGranted  = "role.permission_granted"
+	eventPermissionRevoked  = "role.permission_revoked"
+	eventUserRoleAssigned   = "user.role_assigned"
+	eventUserRoleUnassigned = "user.role_unassigned"
+	eventAdminGranted       = "user.admin_granted"
+	eventAdminRevoked       = "user.admin_revoked"
+)
+
+// nameRe matches role and permission names, e.g. "billing-admin" or "invoices:read".
+var nameRe = regexp.MustCompile(`^[a-z][a-z0-9_.:-]{0,63}$`)
+
+// RBAC manages roles and permissions scoped per app and groups of users holding them.
+// Apps are given by ID or name. Empty app selects the global scope, whose roles and
+// permissions apply to every app.
+type RBAC struct {
+	log          *slog.Logger
+	userProvider UserProvider
+	appProvider  AppProvider
+	roleStorage  RoleStorage
+	groupStorage GroupStorage
+	auditLogger  AuditLogger
+
+	appCache    *cache.Cache[string, models.App]
+	grantsCache *cache.Cache[grantsKey, grants]
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+	IsAdmin(ctx context.Context, userID string, appID uuid.UUID) (bool, error)
+}
+
+type AppProvider interface {
+	// LookupApp returns app by id or, if idOrName is not a UUID, by name.
+	LookupApp(ctx context.Context, idOrName string) (models.App, error)
+}
+
+type RoleStorage interface {
+	CreateRole(ctx context.Context, role models.Role) (models.Role, error)
+	DeleteRole(ctx context.Context, appID uuid.UUID, name string) error
+	Roles(ctx context.Context, appID uuid.UUID) ([]models.Role, error)
+	UserRoles(ctx context.Context, userID string, appID uuid.UUID) ([]models.Role, error)
+	UserPermissions(ctx context.Context, userID string, appID uuid.UUID) ([]string, error)
+	CreatePermission(ctx context.Context, permission models.Permission) (models.Permission, error)
+	DeletePermission(ctx context.Context, appID uuid.UUID, name string) error
+	Permissions(ctx context.Context, appID uuid.UUID) ([]models.Permission, error)
+	GrantPermission(ctx context.Context, appID uuid.UUID, roleName string, permissionName string) error
+	RevokePermission(ctx context.Context, appID uuid.UUID, roleName string, permissionName string) error
+	AssignRole(ctx context.Context, userID string, appID uuid.UUID, roleName string) error
+	UnassignRole(ctx context.Context, userID string, appID uuid.UUID, roleName string) error
+}
+
+type AuditLogger interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+}
+
+var (
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrAppNotFound        = errors.New("app not found")
+	ErrAppDisabled        = errors.New("app is disabled")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidName        = errors.New("invalid role or permission name")
+	ErrRoleExists         = errors.New("role already exists")
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrPermissionExists   = errors.New("permission already exists")
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrReservedRole       = errors.New("role is reserved")
+)
+
+// New returns a new instance of the RBAC service.
+// Permission checks are cached for cacheTTL, for at most cacheSize user and app pairs.
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	roleStorage RoleStorage,
+	groupStorage GroupStorage,
+	auditLogger AuditLogger,
+	cacheTTL time.Duration,
+	cacheSize int,
+) *RBAC {
+	return &RBAC{
+		log:          log,
+		userProvider: userProvider,
+		appProvider:  appProvider,
+		roleStorage:  roleStorage,
+		groupStorage: groupStorage,
+		auditLogger:  auditLogger,
+		appCache:     cache.New[string, models.App](cacheTTL, cacheSize),
+		grantsCache:  cache.New[grantsKey, grants](cacheTTL, cacheSize),
+	}
+}
+
+// CreateRole creates a role without permissions in the app. The admin role name is reserved.
+func (r *RBAC) CreateRole(ctx context.Context, adminToken string, appName string, name string, description string) (models.Role, error) {
+	const op = "rbac.CreateRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("app", appName),
+		slog.String("role", name),
+	)
+
+	log.Info("creating role")
+
+	actor, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !nameRe.MatchString(name) {
+		log.Warn("invalid role name")
+
+		return models.Role{}, fmt.Errorf("%s: %w", op, ErrInvalidName)
+	}
+
+	// Admins of an app hold the global admin role assigned within the app, see GrantAdmin.
+	if name == models.RoleAdmin {
+		log.Warn("attempt to create admin role")
+
+		return models.Role{}, fmt.Errorf("%s: %w", op, ErrReservedRole)
+	}
+
+	role, err := r.roleStorage.CreateRole(ctx, models.Role{AppID: appID, Name: name, Description: description})
+	if err != nil {
+		if errors.Is(err, storage.ErrRoleExists) {
+			log.Warn("role already exists", sl.Err(err))
+
+			return models.Role{}, fmt.Errorf("%s: %w", op, ErrRoleExists)
+		}
+
+		log.Error("failed to create role", sl.Err(err))
+
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.audit(ctx, log, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   eventRoleCreated,
+		Details: map[string]any{"app": appName, "role": name},
+	})
+
+	log.Info("role created")
+
+	return role, nil
+}
+
+// DeleteRole deletes the role of the app. Users holding it lose it.
+// The global admin role cannot be deleted.
+func (r *RBAC) DeleteRole(ctx context.Context, adminToken string, appName string, name string) error {
+	const op = "rbac.DeleteRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("app", appName),
+		slog.String("role", name),
+	)
+
+	log.Info("deleting role")
+
+	actor, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if appID == uuid.Nil && name == models.RoleAdmin {
+		log.Warn("attempt to delete admin role")
+
+		return fmt.Errorf("%s: %w", op, ErrReservedRole)
+	}
+
+	if err := r.roleStorage.DeleteRole(ctx, appID, name); err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			log.Warn("role not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrRoleNotFound)
+		}
+
+		log.Error("failed to delete role", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	r.grantsCache.Invalidate()
+
+	r.audit(ctx, log, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   eventRoleDeleted,
+		Details: map[string]any{"app": appName, "role": name},
+	})
+
+	log.Info("role deleted")
+
+	return nil
+}
+
+// ListRoles returns roles defined in the app with their permissions.
+func (r *RBAC) ListRoles(ctx context.Context, adminToken string, appName string) ([]models.Role, error) {
+	const op = "rbac.ListRoles"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("app", appName),
+	)
+
+	_, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	roles, err := r.roleStorage.Roles(ctx, appID)
+	if err != nil {
+		log.Error("failed to list roles", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+// CreatePermission creates a permission in the app.
+func (r *RBAC) CreatePermission(
+	ctx context.Context,
+	adminToken string,
+	appName string,
+	name string,
+	description string,
+) (models.Permission, error) {
+	const op = "rbac.CreatePermission"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("app", appName),
+		slog.String("permission", name),
+	)
+
+	log.Info("creating permission")
+
+	actor, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return models.Permission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !nameRe.MatchString(name) {
+		log.Warn("invalid permission name")
+
+		return models.Permission{}, fmt.Errorf("%s: %w", op, ErrInvalidName)
+	}
+
+	permission, err := r.roleStorage.CreatePermission(ctx, models.Permission{AppID: appID, Name: name, Description: description})
+	if err != nil {
+		if errors.Is(err, storage.ErrPermissionExists) {
+			log.Warn("permission already exists", sl.Err(err))
+
+			return models.Permission{}, fmt.Errorf("%s: %w", op, ErrPermissionExists)
+		}
+
+		log.Error("failed to create permission", sl.Err(err))
+
+		return models.Permission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.audit(ctx, log, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   eventPermissionCreated,
+		Details: map[string]any{"app": appName, "permission": name},
+	})
+
+	log.Info("permission created")
+
+	return permission, nil
+}
+
+// DeletePermission deletes the permission of the app and removes it from every role.
+func (r *RBAC) DeletePermission(ctx context.Context, adminToken string, appName string, name string) error {
+	const op = "rbac.DeletePermission"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("app", appName),
+		slog.String("permission", name),
+	)
+
+	log.Info("deleting permission")
+
+	actor, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.roleStorage.DeletePermission(ctx, appID, name); err != nil {
+		if errors.Is(err, storage.ErrPermissionNotFound) {
+			log.Warn("permission not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrPermissionNotFound)
+		}
+
+		log.Error("failed to delete permission", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	r.grantsCache.Invalidate()
+
+	r.audit(ctx, log, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   eventPermissionDeleted,
+		Details: map[string]any{"app": appName, "permission": name},
+	})
+
+	log.Info("permission deleted")
+
+	return nil
+}
+
+// ListPermissions returns permissions defined in the app.
+func (r *RBAC) ListPermissions(ctx context.Context, adminToken string, appName string) ([]models.Permission, error) {
+	const op = "rbac.ListPermissions"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("app", appName),
+	)
+
+	_, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	permissions, err := r.roleStorage.Permissions(ctx, appID)
+	if err != nil {
+		log.Error("failed to list permissions", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return permissions, nil
+}
+
+// GrantPermission adds the permission to the role of the app. Roles of an app can hold
+// permissions of the same app and global ones.
+func (r *RBAC) GrantPermission(ctx context.Context, adminToken string, appName string, roleName string, permissionName string) error {
+	const op = "rbac.GrantPermission"
+
+	if err := r.changeRolePermission(ctx, op, adminToken, appName, roleName, permissionName, true); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokePermission removes the permission from the role of the app.
+func (r *RBAC) RevokePermission(ctx context.Context, adminToken string, appName string, roleName string, permissionName string) error {
+	const op = "rbac.RevokePermission"
+
+	if err := r.changeRolePermission(ctx, op, adminToken, appName, roleName, permissionName, false); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *RBAC) changeRolePermission(
+	ctx context.Context,
+	op string,
+	adminToken string,
+	appName string,
+	roleName string,
+	permissionName string,
+	grant bool,
+) error {
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("app", appName),
+		slog.String("role", roleName),
+		slog.String("permission", permissionName),
+	)
+
+	log.Info("changing role permissions", slog.Bool("grant", grant))
+
+	actor, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return err
+	}
+
+	change, event := r.roleStorage.RevokePermission, eventPermissionRevoked
+	if grant {
+		change, event = r.roleStorage.GrantPermission, eventPermissionGranted
+	}
+
+	if err := change(ctx, appID, roleName, permissionName); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRoleNotFound):
+			log.Warn("role not found", sl.Err(err))
+
+			return ErrRoleNotFound
+		case errors.Is(err, storage.ErrPermissionNotFound):
+			log.Warn("permission not found", sl.Err(err))
+
+			return ErrPermissionNotFound
+		}
+
+		log.Error("failed to change role permissions", sl.Err(err))
+
+		return err
+	}
+	r.grantsCache.Invalidate()
+
+	r.audit(ctx, log, models.AuditEvent{
+		ActorID: actor.ID,
+		Event:   event,
+		Details: map[string]any{"app": appName, "role": roleName, "permission": permissionName},
+	})
+
+	log.Info("role permissions changed")
+
+	return nil
+}
+
+// AssignRole gives the user the role within the app, or across all apps if appName is empty.
+// Within an app, both roles of the app and global roles can be assigned.
+func (r *RBAC) AssignRole(ctx context.Context, adminToken string, userID string, appName string, roleName string) error {
+	const op = "rbac.AssignRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID),
+		slog.String("app", appName),
+		slog.String("role", roleName),
+	)
+
+	log.Info("assigning role")
+
+	actor, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.roleStorage.AssignRole(ctx, userID, appID, roleName); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		case errors.Is(err, storage.ErrRoleNotFound):
+			log.Warn("role not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrRoleNotFound)
+		}
+
+		log.Error("failed to assign role", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	r.InvalidateUser(userID)
+
+	r.audit(ctx, log, models.AuditEvent{
+		UserID:  parseID(userID),
+		ActorID: actor.ID,
+		Event:   eventUserRoleAssigned,
+		Details: map[string]any{"app": appName, "role": roleName},
+	})
+
+	log.Info("role assigned")
+
+	return nil
+}
+
+// UnassignRole takes the role assigned within the app, or across all apps if appName is empty,
+// away from the user. Admins cannot take the global admin role away from themselves.
+func (r *RBAC) UnassignRole(ctx context.Context, adminToken string, userID string, appName string, roleName string) error {
+	const op = "rbac.UnassignRole"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID),
+		slog.String("app", appName),
+		slog.String("role", roleName),
+	)
+
+	log.Info("unassigning role")
+
+	actor, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if appID == uuid.Nil && roleName == models.RoleAdmin && actor.ID.String() == userID {
+		log.Warn("attempt to unassign own admin role")
+
+		return fmt.Errorf("%s: %w", op, ErrReservedRole)
+	}
+
+	if err := r.roleStorage.UnassignRole(ctx, userID, appID, roleName); err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			log.Warn("role not assigned", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrRoleNotFound)
+		}
+
+		log.Error("failed to unassign role", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	r.InvalidateUser(userID)
+
+	r.audit(ctx, log, models.AuditEvent{
+		UserID:  parseID(userID),
+		ActorID: actor.ID,
+		Event:   eventUserRoleUnassigned,
+		Details: map[string]any{"app": appName, "role": roleName},
+	})
+
+	log.Info("role unassigned")
+
+	return nil
+}
+
+// UserRoles returns roles the user holds in the app, including roles assigned across all apps
+// and roles inherited through groups.
+func (r *RBAC) UserRoles(ctx context.Context, adminToken string, userID string, appName string) ([]models.Role, error) {
+	const op = "rbac.UserRoles"
+
+	log := r.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID),
+		slog.String("app", appName),
+	)
+
+	_, appID, err := r.prepare(ctx, log, adminToken, appName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := r.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID}); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	roles, err := r.roleStorage.UserRoles(ctx, userID, appID)
+	if err != nil {
+		log.Error("failed to get user roles", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+// prepare authorizes the caller and resolves the app the operation is scoped to.
+// Empty appName resolves to zero ID of the global scope.
+func (r *RBAC) prepare(ctx context.Context, log *slog.Logger, adminToken string, appName string) (models.User, uuid.UUID, error) {
+	actor, err := r.authorize(ctx, adminToken, uuid.Nil)
+	if err != nil {
+		log.Warn("caller is not allowed to manage roles", sl.Err(err))
+
+		return models.User{}, uuid.Nil, err
+	}
+
+	appID, err := r.resolveApp(ctx, log, appName)
+	if err != nil {
+		return models.User{}, uuid.Nil, err
+	}
+
+	return actor, appID, nil
+}
+
+// resolveApp returns ID of the app, or zero ID of the global scope if appName is empty.
+func (r *RBAC) resolveApp(ctx context.Context, log *slog.Logger, appName string) (uuid.UUID, error) {
+	if appName == "" {
+		return uuid.Nil, nil
+	}
+
+	app, err := r.appProvider.LookupApp(ctx, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return uuid.Nil, ErrAppNotFound
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return uuid.Nil, err
+	}
+
+	return app.ID, nil
+}
+
+// authorize returns the caller if the access token belongs to an active admin of the app
+// and was issued after the admin's sessions were last revoked. Zero appID requires an
+// admin of all apps.
+func (r *RBAC) authorize(ctx context.Context, adminToken string, appID uuid.UUID) (models.User, error) {
+	claims, err := jwt.ParseAccessToken(adminToken)
+	if err != nil {
+		return models.User{}, ErrPermissionDenied
+	}
+
+	actor, err := r.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: claims.UserID})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, ErrPermissionDenied
+		}
+
+		return models.User{}, err
+	}
+
+	if !actor.IsActive(time.Now()) || claims.IssuedAt.Before(actor.SessionsRevokedAt) {
+		return models.User{}, ErrPermissionDenied
+	}
+
+	isAdmin, err := r.userProvider.IsAdmin(ctx, claims.UserID, appID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !isAdmin {
+		return models.User{}, ErrPermissionDenied
+	}
+
+	return actor, nil
+}
+
+func (r *RBAC) audit(ctx context.Context, log *slog.Logger, event models.AuditEvent) {
+	if err := r.auditLogger.SaveAuditEvent(ctx, event); err != nil {
+		log.Error("failed to audit role change", sl.Err(err), slog.String("event", event.Event))
+	}
+}
@@ -0,0 +1,586 @@
+package rbac
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	_, err := env.service.CreateRole(ctx, env.adminToken, env.app.Name, "billing-viewer", "")
+	require.NoError(t, err)
+	_, err = env.service.CreatePermission(ctx, env.adminToken, env.app.Name, "invoices:read", "")
+	require.NoError(t, err)
+	_, err = env.service.CreatePermission(ctx, env.adminToken, "", "profile:read", "")
+	require.NoError(t, err)
+
+	// Roles of an app hold permissions of the app and global ones.
+	require.NoError(t, env.service.GrantPermission(ctx, env.adminToken, env.app.Name, "billing-viewer", "invoices:read"))
+	require.NoError(t, env.service.GrantPermission(ctx, env.adminToken, env.app.Name, "billing-viewer", "profile:read"))
+
+	check := func() []bool {
+		allowed, err := env.service.CheckPermissions(ctx, user.ID.String(), env.app.Name, []string{"invoices:read", "profile:read", "invoices:write"})
+		require.NoError(t, err)
+
+		return allowed
+	}
+
+	assert.Equal(t, []bool{false, false, false}, check())
+
+	// Role changes show up at once, although the answer above was cached.
+	require.NoError(t, env.service.AssignRole(ctx, env.adminToken, user.ID.String(), env.app.Name, "billing-viewer"))
+	assert.Equal(t, []bool{true, true, false}, check())
+
+	require.NoError(t, env.service.RevokePermission(ctx, env.adminToken, env.app.Name, "billing-viewer", "profile:read"))
+	assert.Equal(t, []bool{true, false, false}, check())
+
+	require.NoError(t, env.service.UnassignRole(ctx, env.adminToken, user.ID.String(), env.app.Name, "billing-viewer"))
+	assert.Equal(t, []bool{false, false, false}, check())
+
+	require.NoError(t, env.service.DeleteRole(ctx, env.adminToken, env.app.Name, "billing-viewer"))
+	roles, err := env.service.ListRoles(ctx, env.adminToken, env.app.Name)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	events := make([]string, 0, len(env.storage.events))
+	for _, event := range env.storage.events {
+		events = append(events, event.Event)
+	}
+	assert.Equal(t, []string{
+		eventRoleCreated, eventPermissionCreated, eventPermissionCreated,
+		eventPermissionGranted, eventPermissionGranted, eventUserRoleAssigned,
+		eventPermissionRevoked, eventUserRoleUnassigned, eventRoleDeleted,
+	}, events)
+}
+
+func TestCheckPermissions_UserStatus(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	_, err := env.service.CreateRole(ctx, env.adminToken, env.app.Name, "billing-viewer", "")
+	require.NoError(t, err)
+	_, err = env.service.CreatePermission(ctx, env.adminToken, env.app.Name, "invoices:read", "")
+	require.NoError(t, err)
+	require.NoError(t, env.service.GrantPermission(ctx, env.adminToken, env.app.Name, "billing-viewer", "invoices:read"))
+	require.NoError(t, env.service.AssignRole(ctx, env.adminToken, user.ID.String(), env.app.Name, "billing-viewer"))
+
+	check := func() bool {
+		allowed, err := env.service.CheckPermissions(ctx, user.ID.String(), env.app.Name, []string{"invoices:read"})
+		require.NoError(t, err)
+
+		return allowed[0]
+	}
+
+	assert.True(t, check())
+
+	// A status change made elsewhere stays hidden behind the cache until it is reported.
+	user.Status = models.UserStatusSuspended
+	env.storage.users[user.ID] = user
+	assert.True(t, check())
+
+	env.service.InvalidateUser(user.ID.String())
+	assert.False(t, check(), "suspended users have no permissions")
+
+	user.Status = models.UserStatusActive
+	env.storage.users[user.ID] = user
+	env.service.InvalidateUser(user.ID.String())
+	assert.True(t, check())
+
+	_, err = env.service.CheckPermissions(ctx, uuid.NewString(), env.app.Name, []string{"invoices:read"})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = env.service.CheckPermissions(ctx, user.ID.String(), "missing", []string{"invoices:read"})
+	assert.ErrorIs(t, err, ErrAppNotFound)
+}
+
+func TestCheckPermissions_AppChanges(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+
+	_, err := env.service.CheckPermissions(ctx, user.ID.String(), env.app.Name, []string{"invoices:read"})
+	require.NoError(t, err)
+
+	// A renamed app stays reachable by its old name until the change is reported.
+	renamed := env.app
+	renamed.Name = "renamed-app"
+	delete(env.storage.apps, env.app.Name)
+	env.storage.apps[renamed.Name] = renamed
+
+	_, err = env.service.CheckPermissions(ctx, user.ID.String(), env.app.Name, []string{"invoices:read"})
+	require.NoError(t, err)
+
+	env.service.InvalidateApps()
+
+	_, err = env.service.CheckPermissions(ctx, user.ID.String(), env.app.Name, []string{"invoices:read"})
+	assert.ErrorIs(t, err, ErrAppNotFound)
+
+	_, err = env.service.CheckPermissions(ctx, user.ID.String(), renamed.Name, []string{"invoices:read"})
+	require.NoError(t, err)
+	_, err = env.service.CheckPermissions(ctx, user.ID.String(), renamed.ID.String(), []string{"invoices:read"})
+	require.NoError(t, err)
+
+	renamed.Enabled = false
+	env.storage.apps[renamed.Name] = renamed
+	env.service.InvalidateApps()
+
+	_, err = env.service.CheckPermissions(ctx, user.ID.String(), renamed.Name, []string{"invoices:read"})
+	assert.ErrorIs(t, err, ErrAppDisabled)
+}
+
+func TestRoleChanges_Rejected(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.addUser(models.UserStatusActive)
+	userToken := env.token(user)
+
+	suspended := env.addUser(models.UserStatusSuspended)
+	env.storage.assign(suspended.ID, uuid.Nil, models.RoleAdmin)
+
+	revoked := env.addUser(models.UserStatusActive)
+	env.storage.assign(revoked.ID, uuid.Nil, models.RoleAdmin)
+	revokedToken := env.token(revoked)
+	revoked.SessionsRevokedAt = time.Now().Add(time.Minute)
+	env.storage.users[revoked.ID] = revoked
+
+	_, err := env.service.CreateRole(ctx, env.adminToken, env.app.Name, "viewer", "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{
+			name: "not admin",
+			call: func() error {
+				_, err := env.service.CreateRole(ctx, userToken, env.app.Name, "editor", "")
+				return err
+			},
+			want: ErrPermissionDenied,
+		},
+		{
+			name: "suspended admin",
+			call: func() error {
+				_, err := env.service.CreateRole(ctx, env.token(suspended), env.app.Name, "editor", "")
+				return err
+			},
+			want: ErrPermissionDenied,
+		},
+		{
+			name: "revoked admin session",
+			call: func() error {
+				_, err := env.service.CreateRole(ctx, revokedToken, env.app.Name, "editor", "")
+				return err
+			},
+			want: ErrPermissionDenied,
+		},
+		{
+			name: "invalid name",
+			call: func() error {
+				_, err := env.service.CreateRole(ctx, env.adminToken, env.app.Name, "Editor", "")
+				return err
+			},
+			want: ErrInvalidName,
+		},
+		{
+			name: "admin role",
+			call: func() error {
+				_, err := env.service.CreateRole(ctx, env.adminToken, env.app.Name, models.RoleAdmin, "")
+				return err
+			},
+			want: ErrReservedRole,
+		},
+		{
+			name: "duplicate",
+			call: func() error {
+				_, err := env.service.CreateRole(ctx, env.adminToken, env.app.Name, "viewer", "")
+				return err
+			},
+			want: ErrRoleExists,
+		},
+		{
+			name: "unknown app",
+			call: func() error {
+				_, err := env.service.CreateRole(ctx, env.adminToken, "missing", "editor", "")
+				return err
+			},
+			want: ErrAppNotFound,
+		},
+		{
+			name: "delete global admin role",
+			call: func() error {
+				return env.service.DeleteRole(ctx, env.adminToken, "", models.RoleAdmin)
+			},
+			want: ErrReservedRole,
+		},
+		{
+			name: "unassign own admin role",
+			call: func() error {
+				return env.service.UnassignRole(ctx, env.adminToken, env.admin.ID.String(), "", models.RoleAdmin)
+			},
+			want: ErrReservedRole,
+		},
+		{
+			name: "grant unknown permission",
+			call: func() error {
+				return env.service.GrantPermission(ctx, env.adminToken, env.app.Name, "viewer", "invoices:read")
+			},
+			want: ErrPermissionNotFound,
+		},
+		{
+			name: "assign to unknown user",
+			call: func() error {
+				return env.service.AssignRole(ctx, env.adminToken, uuid.NewString(), env.app.Name, "viewer")
+			},
+			want: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.call(), tt.want)
+		})
+	}
+}
+
+type testEnv struct {
+	t          *testing.T
+	service    *RBAC
+	storage    *fakeStorage
+	admin      models.User
+	app        models.App
+	adminToken string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	app := models.App{ID: uuid.New(), Name: "test-app", Enabled: true}
+
+	fake := &fakeStorage{
+		users:       map[uuid.UUID]models.User{},
+		apps:        map[string]models.App{app.Name: app},
+		roles:       map[roleKey]*models.Role{{name: models.RoleAdmin}: {ID: uuid.New(), Name: models.RoleAdmin}},
+		permissions: map[roleKey]models.Permission{},
+		groups:      map[string]*fakeGroup{},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	env := &testEnv{
+		t:       t,
+		service: New(log, fake, fake, fake, fake, fake, time.Minute, 100),
+		storage: fake,
+		app:     app,
+	}
+
+	env.admin = env.addUser(models.UserStatusActive)
+	fake.assign(env.admin.ID, uuid.Nil, models.RoleAdmin)
+	env.adminToken = env.token(env.admin)
+
+	return env
+}
+
+func (e *testEnv) addUser(status models.UserStatus) models.User {
+	user := models.User{ID: uuid.New(), Status: status}
+	user.Email = user.ID.String() + "@example.com"
+	e.storage.users[user.ID] = user
+
+	return user
+}
+
+func (e *testEnv) token(user models.User) string {
+	e.t.Helper()
+
+	accessToken, _, err := jwt.NewTokenPair(user, e.app, models.NewAuthentication(models.AuthMethodPassword), time.Hour, time.Hour)
+	require.NoError(e.t, err)
+
+	return accessToken
+}
+
+// roleKey identifies a role or a permission within an app; zero appID is the global scope.
+type roleKey struct {
+	appID uuid.UUID
+	name  string
+}
+
+// assignment is a role held by a user or a group within an app, or within all apps for zero appID.
+type assignment struct {
+	appID uuid.UUID
+	role  roleKey
+}
+
+// fakeStorage keeps users, roles, permissions and groups in memory.
+type fakeStorage struct {
+	users       map[uuid.UUID]models.User
+	apps        map[string]models.App
+	roles       map[roleKey]*models.Role
+	permissions map[roleKey]models.Permission
+	assignments map[uuid.UUID][]assignment
+	groups      map[string]*fakeGroup
+	events      []models.AuditEvent
+}
+
+// assign gives the user the global role within the app.
+func (f *fakeStorage) assign(userID uuid.UUID, appID uuid.UUID, roleName string) {
+	if f.assignments == nil {
+		f.assignments = map[uuid.UUID][]assignment{}
+	}
+
+	f.assignments[userID] = append(f.assignments[userID], assignment{appID: appID, role: roleKey{name: roleName}})
+}
+
+// heldRoles returns roles the user holds within the app, directly or through groups.
+func (f *fakeStorage) heldRoles(userID uuid.UUID, appID uuid.UUID) []*models.Role {
+	held := slices.Clone(f.assignments[userID])
+	for _, name := range f.userGroups(userID) {
+		held = append(held, f.groups[name].roles...)
+	}
+
+	var roles []*models.Role
+	for _, a := range held {
+		if a.appID != uuid.Nil && a.appID != appID {
+			continue
+		}
+		if role, ok := f.roles[a.role]; ok && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
+
+// role finds the role visible within the app: one of the app or a global one.
+func (f *fakeStorage) role(appID uuid.UUID, name string) (roleKey, bool) {
+	for _, key := range []roleKey{{appID: appID, name: name}, {name: name}} {
+		if _, ok := f.roles[key]; ok {
+			return key, true
+		}
+	}
+
+	return roleKey{}, false
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	id, err := uuid.Parse(identifier.Value)
+	if err != nil {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	user, ok := f.users[id]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeStorage) IsAdmin(_ context.Context, userID string, appID uuid.UUID) (bool, error) {
+	for _, role := range f.heldRoles(uuid.MustParse(userID), appID) {
+		if role.Name == models.RoleAdmin && role.AppID == uuid.Nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (f *fakeStorage) LookupApp(_ context.Context, idOrName string) (models.App, error) {
+	for _, app := range f.apps {
+		if app.ID.String() == idOrName || app.Name == idOrName {
+			return app, nil
+		}
+	}
+
+	return models.App{}, storage.ErrAppNotFound
+}
+
+func (f *fakeStorage) CreateRole(_ context.Context, role models.Role) (models.Role, error) {
+	key := roleKey{appID: role.AppID, name: role.Name}
+	if _, ok := f.roles[key]; ok {
+		return models.Role{}, storage.ErrRoleExists
+	}
+
+	role.ID = uuid.New()
+	role.CreatedAt = time.Now()
+	f.roles[key] = &role
+
+	return role, nil
+}
+
+func (f *fakeStorage) DeleteRole(_ context.Context, appID uuid.UUID, name string) error {
+	key := roleKey{appID: appID, name: name}
+	if _, ok := f.roles[key]; !ok {
+		return storage.ErrRoleNotFound
+	}
+
+	delete(f.roles, key)
+
+	return nil
+}
+
+func (f *fakeStorage) Roles(_ context.Context, appID uuid.UUID) ([]models.Role, error) {
+	var roles []models.Role
+	for key, role := range f.roles {
+		if key.appID == appID {
+			roles = append(roles, *role)
+		}
+	}
+
+	return roles, nil
+}
+
+func (f *fakeStorage) UserRoles(_ context.Context, userID string, appID uuid.UUID) ([]models.Role, error) {
+	var roles []models.Role
+	for _, role := range f.heldRoles(uuid.MustParse(userID), appID) {
+		roles = append(roles, *role)
+	}
+
+	return roles, nil
+}
+
+func (f *fakeStorage) UserPermissions(_ context.Context, userID string, appID uuid.UUID) ([]string, error) {
+	var names []string
+	for _, role := range f.heldRoles(uuid.MustParse(userID), appID) {
+		names = append(names, role.Permissions...)
+	}
+
+	return names, nil
+}
+
+func (f *fakeStorage) CreatePermission(_ context.Context, permission models.Permission) (models.Permission, error) {
+	key := roleKey{appID: permission.AppID, name: permission.Name}
+	if _, ok := f.permissions[key]; ok {
+		return models.Permission{}, storage.ErrPermissionExists
+	}
+
+	permission.ID = uuid.New()
+	permission.CreatedAt = time.Now()
+	f.permissions[key] = permission
+
+	return permission, nil
+}
+
+func (f *fakeStorage) DeletePermission(_ context.Context, appID uuid.UUID, name string) error {
+	key := roleKey{appID: appID, name: name}
+	if _, ok := f.permissions[key]; !ok {
+		return storage.ErrPermissionNotFound
+	}
+
+	delete(f.permissions, key)
+	for _, role := range f.roles {
+		role.Permissions = slices.DeleteFunc(role.Permissions, func(p string) bool { return p == name })
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) Permissions(_ context.Context, appID uuid.UUID) ([]models.Permission, error) {
+	var permissions []models.Permission
+	for key, permission := range f.permissions {
+		if key.appID == appID {
+			permissions = append(permissions, permission)
+		}
+	}
+
+	return permissions, nil
+}
+
+func (f *fakeStorage) GrantPermission(_ context.Context, appID uuid.UUID, roleName string, permissionName string) error {
+	role, ok := f.roles[roleKey{appID: appID, name: roleName}]
+	if !ok {
+		return storage.ErrRoleNotFound
+	}
+
+	_, inApp := f.permissions[roleKey{appID: appID, name: permissionName}]
+	_, global := f.permissions[roleKey{name: permissionName}]
+	if !inApp && !global {
+		return storage.ErrPermissionNotFound
+	}
+
+	if !slices.Contains(role.Permissions, permissionName) {
+		role.Permissions = append(role.Permissions, permissionName)
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) RevokePermission(_ context.Context, appID uuid.UUID, roleName string, permissionName string) error {
+	role, ok := f.roles[roleKey{appID: appID, name: roleName}]
+	if !ok {
+		return storage.ErrRoleNotFound
+	}
+
+	i := slices.Index(role.Permissions, permissionName)
+	if i < 0 {
+		return storage.ErrPermissionNotFound
+	}
+	role.Permissions = slices.Delete(role.Permissions, i, i+1)
+
+	return nil
+}
+
+func (f *fakeStorage) AssignRole(_ context.Context, userID string, appID uuid.UUID, roleName string) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return storage.ErrUserNotFound
+	}
+	if _, ok := f.users[id]; !ok {
+		return storage.ErrUserNotFound
+	}
+
+	key, ok := f.role(appID, roleName)
+	if !ok {
+		return storage.ErrRoleNotFound
+	}
+
+	if f.assignments == nil {
+		f.assignments = map[uuid.UUID][]assignment{}
+	}
+	f.assignments[id] = append(f.assignments[id], assignment{appID: appID, role: key})
+
+	return nil
+}
+
+func (f *fakeStorage) UnassignRole(_ context.Context, userID string, appID uuid.UUID, roleName string) error {
+	id := uuid.MustParse(userID)
+
+	i := slices.IndexFunc(f.assignments[id], func(a assignment) bool {
+		return a.appID == appID && a.role.name == roleName
+	})
+	if i < 0 {
+		return storage.ErrRoleNotFound
+	}
+	f.assignments[id] = slices.Delete(f.assignments[id], i, i+1)
+
+	return nil
+}
+
+func (f *fakeStorage) SaveAuditEvent(_ context.Context, event models.AuditEvent) error {
+	f.events = append(f.events, event)
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package serviceaccount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/logger/sl"
+	"github.com/sol1corejz/auth-service/internal/lib/publickey"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// maxAssertionLifetime bounds how far in the future assertions may expire, and so how long
+// their IDs are kept to reject replays.
+const maxAssertionLifetime = time.Hour
+
+// ServiceAccount exchanges JWT bearer assertions signed by service accounts for access tokens
+// (RFC 7523, section 2.1).
+type ServiceAccount struct {
+	log            *slog.Logger
+	userProvider   UserProvider
+	accountStorage AccountStorage
+	audiences      []string
+	tokenTTL       time.Duration
+}
+
+type UserProvider interface {
+	User(ctx context.Context, identifier models.Identifier) (models.User, error)
+}
+
+type AccountStorage interface {
+	// LookupServiceAccount returns service account by id or, if idOrName is not a UUID, by name.
+	LookupServiceAccount(ctx context.Context, idOrName string) (models.ServiceAccount, error)
+	ActiveServiceAccountKeys(ctx context.Context, accountID uuid.UUID, now time.Time) ([]models.ServiceAccountKey, error)
+	SaveAssertion(ctx context.Context, accountID uuid.UUID, keyID uuid.UUID, jti string, expiresAt time.Time, now time.Time) error
+}
+
+// ErrInvalidGrant hides whether the account, its key, the assertion or a replay was the problem.
+var ErrInvalidGrant = errors.New("invalid grant")
+
+// New returns a new instance of the ServiceAccount service. Assertions must be addressed to
+// the issuer or its token endpoint.
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	accountStorage AccountStorage,
+	issuer string,
+	tokenTTL time.Duration,
+) *ServiceAccount {
+	return &ServiceAccount{
+		log:            log,
+		userProvider:   userProvider,
+		accountStorage: accountStorage,
+		audiences:      []string{issuer, issuer + "/oauth/token"},
+		tokenTTL:       tokenTTL,
+	}
+}
+
+// Exchange verifies the assertion and issues an access token to the service account it names
+// in sub, by id or name. The assertion must carry the ID of its key in kid, unless the account
+// has a single key; each assertion is accepted once.
+func (s *ServiceAccount) Exchange(ctx context.Context, assertion string) (models.ServiceAccountToken, error) {
+	const op = "serviceaccount.Exchange"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	subject, kid, err := jwt.AssertionKey(assertion)
+	if err != nil {
+		log.Warn("malformed assertion", sl.Err(err))
+
+		return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+	log = log.With(slog.String("service_account", subject))
+
+	account, err := s.accountStorage.LookupServiceAccount(ctx, subject)
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountNotFound) {
+			log.Warn("service account not found", sl.Err(err))
+
+			return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+
+		log.Error("failed to get service account", sl.Err(err))
+
+		return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.String("account_id", account.ID.String()))
+
+	now := time.Now()
+
+	key, err := s.key(ctx, account.ID, kid, now)
+	if err != nil {
+		if errors.Is(err, ErrInvalidGrant) {
+			log.Warn("no key to verify the assertion", slog.String("kid", kid))
+		} else {
+			log.Error("failed to get service account keys", sl.Err(err))
+		}
+
+		return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	parsed, err := publickey.ParseDER(key.PublicKey)
+	if err != nil {
+		log.Error("stored service account key is invalid", sl.Err(err), slog.String("key_id", key.ID.String()))
+
+		return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims, err := jwt.VerifyAssertion(assertion, parsed, s.audiences, maxAssertionLifetime)
+	if err != nil {
+		log.Warn("invalid assertion", sl.Err(err), slog.String("key_id", key.ID.String()))
+
+		return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	user, err := s.userProvider.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: account.ID.String()})
+	if err != nil {
+		log.Error("failed to get service account user", sl.Err(err))
+
+		return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !user.IsActive(now) {
+		log.Warn("service account is not active", slog.String("status", string(user.Status)))
+
+		return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	if err := s.accountStorage.SaveAssertion(ctx, account.ID, key.ID, claims.ID, claims.ExpiresAt, now); err != nil {
+		if errors.Is(err, storage.ErrAssertionReplayed) {
+			log.Warn("assertion replayed", slog.String("jti", claims.ID))
+
+			return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+
+		log.Error("failed to save assertion", sl.Err(err))
+
+		return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, expiresAt, err := jwt.NewServiceAccountToken(account.ID, s.tokenTTL)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return models.ServiceAccountToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("access token issued to service account", slog.String("key_id", key.ID.String()))
+
+	return models.ServiceAccountToken{AccessToken: token, ExpiresAt: expiresAt, ExpiresIn: s.tokenTTL}, nil
+}
+
+// key returns the unexpired key with the id kid, or the only unexpired key if kid is empty.
+func (s *ServiceAccount) key(ctx context.Context, accountID uuid.UUID, kid string, now time.Time) (models.ServiceAccountKey, error) {
+	keys, err := s.accountStorage.ActiveServiceAccountKeys(ctx, accountID, now)
+	if err != nil {
+		return models.ServiceAccountKey{}, err
+	}
+
+	if kid == "" {
+		if len(keys) != 1 {
+			return models.ServiceAccountKey{}, ErrInvalidGrant
+		}
+
+		return keys[0], nil
+	}
+
+	for _, key := range keys {
+		if key.ID.String() == kid {
+			return key, nil
+		}
+	}
+
+	return models.ServiceAccountKey{}, ErrInvalidGrant
+}
@@ -0,0 +1,259 @@
+package serviceaccount
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/lib/jwt"
+	"github.com/sol1corejz/auth-service/internal/lib/publickey"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIssuer = "https://sso.example.com"
+
+type fakeStorage struct {
+	account models.ServiceAccount
+	user    models.User
+	keys    []models.ServiceAccountKey
+	jtis    map[string]bool
+	err     error
+}
+
+func (f *fakeStorage) User(_ context.Context, identifier models.Identifier) (models.User, error) {
+	if identifier.Value != f.user.ID.String() {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return f.user, nil
+}
+
+func (f *fakeStorage) LookupServiceAccount(_ context.Context, idOrName string) (models.ServiceAccount, error) {
+	if idOrName != f.account.Name && idOrName != f.account.ID.String() {
+		return models.ServiceAccount{}, storage.ErrServiceAccountNotFound
+	}
+
+	return f.account, nil
+}
+
+func (f *fakeStorage) ActiveServiceAccountKeys(_ context.Context, accountID uuid.UUID, now time.Time) ([]models.ServiceAccountKey, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var keys []models.ServiceAccountKey
+	for _, key := range f.keys {
+		if key.AccountID == accountID && (key.ExpiresAt.IsZero() || now.Before(key.ExpiresAt)) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (f *fakeStorage) SaveAssertion(_ context.Context, accountID uuid.UUID, _ uuid.UUID, jti string, _ time.Time, _ time.Time) error {
+	if f.jtis[accountID.String()+jti] {
+		return storage.ErrAssertionReplayed
+	}
+	f.jtis[accountID.String()+jti] = true
+
+	return nil
+}
+
+type testEnv struct {
+	t       *testing.T
+	service *ServiceAccount
+	storage *fakeStorage
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_ACCESS_SECRET", "test-access-secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+
+	account := models.ServiceAccount{ID: uuid.New(), Name: "ci-deployer"}
+	fake := &fakeStorage{
+		account: account,
+		user:    models.User{ID: account.ID, Status: models.UserStatusActive},
+		jtis:    map[string]bool{},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testEnv{t: t, service: New(log, fake, fake, testIssuer, 15*time.Minute), storage: fake}
+}
+
+// addKey registers a new P-256 key of the test account, expiring at expiresAt unless it's zero.
+func (e *testEnv) addKey(expiresAt time.Time) (uuid.UUID, *ecdsa.PrivateKey) {
+	e.t.Helper()
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(e.t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	require.NoError(e.t, err)
+
+	key := models.ServiceAccountKey{
+		ID:        uuid.New(),
+		AccountID: e.storage.account.ID,
+		PublicKey: der,
+		Algorithm: publickey.AlgorithmES256,
+		ExpiresAt: expiresAt,
+	}
+	e.storage.keys = append(e.storage.keys, key)
+
+	return key.ID, private
+}
+
+// sign signs a valid assertion of the test account with the key, changed by edit.
+func (e *testEnv) sign(kid uuid.UUID, key *ecdsa.PrivateKey, edit func(claims jwtlib.MapClaims, header map[string]any)) string {
+	e.t.Helper()
+
+	claims := jwtlib.MapClaims{
+		"iss": e.storage.account.Name,
+		"sub": e.storage.account.Name,
+		"aud": testIssuer + "/oauth/token",
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+		"jti": uuid.NewString(),
+	}
+	token := jwtlib.NewWithClaims(jwtlib.SigningMethodES256, claims)
+	token.Header["kid"] = kid.String()
+	if edit != nil {
+		edit(claims, token.Header)
+	}
+
+	signed, err := token.SignedString(key)
+	require.NoError(e.t, err)
+
+	return signed
+}
+
+func TestExchange(t *testing.T) {
+	env := newTestEnv(t)
+	kid, key := env.addKey(time.Time{})
+
+	token, err := env.service.Exchange(context.Background(), env.sign(kid, key, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, token.ExpiresIn)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), token.ExpiresAt, time.Minute)
+
+	claims, err := jwt.ParseServiceAccountToken(token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, env.storage.account.ID.String(), claims.AccountID)
+
+	_, err = jwt.ParseAccessToken(token.AccessToken)
+	assert.Error(t, err, "service account tokens are not user tokens")
+}
+
+func TestExchange_KeySelection(t *testing.T) {
+	env := newTestEnv(t)
+	oldKID, oldKey := env.addKey(time.Time{})
+	newKID, newKey := env.addKey(time.Time{})
+	expiredKID, expiredKey := env.addKey(time.Now().Add(-time.Minute))
+
+	ctx := context.Background()
+
+	_, err := env.service.Exchange(ctx, env.sign(newKID, newKey, nil))
+	assert.NoError(t, err, "rotated keys work side by side")
+
+	_, err = env.service.Exchange(ctx, env.sign(oldKID, oldKey, nil))
+	assert.NoError(t, err)
+
+	_, err = env.service.Exchange(ctx, env.sign(oldKID, newKey, nil))
+	assert.ErrorIs(t, err, ErrInvalidGrant, "the key named by kid must have signed the assertion")
+
+	_, err = env.service.Exchange(ctx, env.sign(expiredKID, expiredKey, nil))
+	assert.ErrorIs(t, err, ErrInvalidGrant, "expired keys are ignored")
+
+	_, err = env.service.Exchange(ctx, env.sign(newKID, newKey, func(_ jwtlib.MapClaims, header map[string]any) {
+		delete(header, "kid")
+	}))
+	assert.ErrorIs(t, err, ErrInvalidGrant, "kid is required once the account has several keys")
+}
+
+func TestExchange_SingleKeyWithoutKID(t *testing.T) {
+	env := newTestEnv(t)
+	kid, key := env.addKey(time.Time{})
+
+	_, err := env.service.Exchange(context.Background(), env.sign(kid, key, func(claims jwtlib.MapClaims, header map[string]any) {
+		delete(header, "kid")
+		claims["aud"] = testIssuer
+	}))
+	assert.NoError(t, err)
+}
+
+func TestExchange_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(claims jwtlib.MapClaims, header map[string]any)
+		prepare func(env *testEnv)
+	}{
+		{name: "unknown account", edit: func(claims jwtlib.MapClaims, _ map[string]any) {
+			claims["iss"], claims["sub"] = "unknown", "unknown"
+		}},
+		{name: "unknown kid", edit: func(_ jwtlib.MapClaims, header map[string]any) {
+			header["kid"] = uuid.NewString()
+		}},
+		{name: "wrong audience", edit: func(claims jwtlib.MapClaims, _ map[string]any) {
+			claims["aud"] = "https://other.example.com"
+		}},
+		{name: "too long lifetime", edit: func(claims jwtlib.MapClaims, _ map[string]any) {
+			claims["exp"] = time.Now().Add(2 * maxAssertionLifetime).Unix()
+		}},
+		{name: "no jti", edit: func(claims jwtlib.MapClaims, _ map[string]any) {
+			delete(claims, "jti")
+		}},
+		{name: "suspended account", prepare: func(env *testEnv) {
+			env.storage.user.Status = models.UserStatusSuspended
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			kid, key := env.addKey(time.Time{})
+			if tt.prepare != nil {
+				tt.prepare(env)
+			}
+
+			_, err := env.service.Exchange(context.Background(), env.sign(kid, key, tt.edit))
+			assert.ErrorIs(t, err, ErrInvalidGrant)
+			assert.Empty(t, env.storage.jtis, "rejected assertions are not remembered")
+		})
+	}
+}
+
+func TestExchange_Replay(t *testing.T) {
+	env := newTestEnv(t)
+	kid, key := env.addKey(time.Time{})
+	assertion := env.sign(kid, key, nil)
+
+	_, err := env.service.Exchange(context.Background(), assertion)
+	require.NoError(t, err)
+
+	_, err = env.service.Exchange(context.Background(), assertion)
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+}
+
+func TestExchange_StorageFailure(t *testing.T) {
+	env := newTestEnv(t)
+	kid, key := env.addKey(time.Time{})
+	env.storage.err = errors.New("connection reset")
+
+	_, err := env.service.Exchange(context.Background(), env.sign(kid, key, nil))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidGrant, "storage failures are not the client's fault")
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// AddAppMember adds the user to the app. Returns storage.ErrAppMemberExists if the user
+// is a member already.
+func (s *Storage) AddAppMember(ctx context.Context, member models.AppMember) error {
+	const op = "storage.postgres.AddAppMember"
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO app_memberships (app_id, user_id, added_by) VALUES ($1, $2, $3)",
+		member.AppID, member.UserID, nullUUID(member.AddedBy),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch {
+			case pgErr.Code == "23505":
+				return fmt.Errorf("%s: %w", op, storage.ErrAppMemberExists)
+			case pgErr.Code == "23503" && pgErr.ConstraintName == "app_memberships_app_id_fkey":
+				return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+			case pgErr.Code == "23503":
+				return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+			}
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveAppMember removes the user from the app.
+func (s *Storage) RemoveAppMember(ctx context.Context, appID uuid.UUID, userID uuid.UUID) error {
+	const op = "storage.postgres.RemoveAppMember"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM app_memberships WHERE app_id = $1 AND user_id = $2", appID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppMemberNotFound)
+	}
+
+	return nil
+}
+
+// AppMembers returns members of the app in the order they were added.
+func (s *Storage) AppMembers(ctx context.Context, appID uuid.UUID) ([]models.AppMember, error) {
+	const op = "storage.postgres.AppMembers"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.app_id, m.user_id, COALESCE(u.email, ''), m.added_by, m.created_at
+		FROM app_memberships m
+		JOIN users u ON u.user_id = m.user_id
+		WHERE m.app_id = $1
+		ORDER BY m.created_at, m.user_id`,
+		appID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var members []models.AppMember
+	for rows.Next() {
+		var (
+			member  models.AppMember
+			addedBy uuid.NullUUID
+		)
+		if err := rows.Scan(&member.AppID, &member.UserID, &member.Email, &addedBy, &member.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		member.AddedBy = addedBy.UUID
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return members, nil
+}
+
+// IsAppMember reports whether the user was added to the app.
+func (s *Storage) IsAppMember(ctx context.Context, appID uuid.UUID, userID uuid.UUID) (bool, error) {
+	const op = "storage.postgres.IsAppMember"
+
+	var member bool
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM app_memberships WHERE app_id = $1 AND user_id = $2)",
+		appID, userID,
+	).Scan(&member); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return member, nil
+}
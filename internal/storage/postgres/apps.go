@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// CreateApp saves an app. Returns storage.ErrAppExists if the name is taken
+// and storage.ErrUserNotFound if the owner does not exist.
+func (s *Storage) CreateApp(ctx context.Context, app models.App) (models.App, error) {
+	const op = "storage.postgres.CreateApp"
+
+	created, err := scanApp(s.db.QueryRowContext(ctx, `
+		INSERT INTO apps (name, description, owner_id, enabled) VALUES ($1, $2, $3, $4)
+		RETURNING `+appColumns,
+		app.Name, app.Description, nullUUID(app.OwnerID), app.Enabled,
+	))
+	if err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, appError(err))
+	}
+
+	return created, nil
+}
+
+// LookupApp returns app by id or, if idOrName is not a UUID, by name.
+func (s *Storage) LookupApp(ctx context.Context, idOrName string) (models.App, error) {
+	if uuid.Validate(idOrName) == nil {
+		return s.AppByID(ctx, idOrName)
+	}
+
+	return s.App(ctx, idOrName)
+}
+
+// Apps returns all apps ordered by name.
+func (s *Storage) Apps(ctx context.Context) ([]models.App, error) {
+	const op = "storage.postgres.Apps"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+appColumns+" FROM apps ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var apps []models.App
+	for rows.Next() {
+		app, err := scanApp(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		apps = append(apps, app)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return apps, nil
+}
+
+// UpdateApp changes fields of the app set in the update and returns the updated app.
+func (s *Storage) UpdateApp(ctx context.Context, appID uuid.UUID, update models.AppUpdate) (models.App, error) {
+	const op = "storage.postgres.UpdateApp"
+
+	var ownerID uuid.NullUUID
+	if update.OwnerID != nil {
+		ownerID = nullUUID(*update.OwnerID)
+	}
+	var clientScopes, redirectURIs, postLogoutRedirectURIs, allowedEmailDomains any
+	if update.ClientScopes != nil {
+		clientScopes = append([]string{}, *update.ClientScopes...)
+	}
+	if update.RedirectURIs != nil {
+		redirectURIs = append([]string{}, *update.RedirectURIs...)
+	}
+	if update.PostLogoutRedirectURIs != nil {
+		postLogoutRedirectURIs = append([]string{}, *update.PostLogoutRedirectURIs...)
+	}
+	if update.AllowedEmailDomains != nil {
+		allowedEmailDomains = append([]string{}, *update.AllowedEmailDomains...)
+	}
+
+	app, err := scanApp(s.db.QueryRowContext(ctx, `
+		UPDATE apps SET
+			name = COALESCE($2, name),
+			description = COALESCE($3, description),
+			owner_id = CASE WHEN $4 THEN $5 ELSE owner_id END,
+			enabled = COALESCE($6, enabled),
+			client_scopes = COALESCE($7, client_scopes),
+			redirect_uris = COALESCE($8, redirect_uris),
+			post_logout_redirect_uris = COALESCE($9, post_logout_redirect_uris),
+			access_mode = COALESCE($10, access_mode),
+			allowed_email_domains = COALESCE($11, allowed_email_domains)
+		WHERE app_id = $1
+		RETURNING `+appColumns,
+		appID, update.Name, update.Description, update.OwnerID != nil, ownerID, update.Enabled,
+		clientScopes, redirectURIs, postLogoutRedirectURIs, update.AccessMode, allowedEmailDomains,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+
+		return models.App{}, fmt.Errorf("%s: %w", op, appError(err))
+	}
+
+	return app, nil
+}
+
+// DeleteApp deletes the app together with its roles, permissions and other per-app data.
+func (s *Storage) DeleteApp(ctx context.Context, appID uuid.UUID) error {
+	const op = "storage.postgres.DeleteApp"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM apps WHERE app_id = $1", appID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	return nil
+}
+
+// appError maps constraint violations of apps to storage errors.
+func appError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return storage.ErrAppExists
+		case "23503":
+			return storage.ErrUserNotFound
+		}
+	}
+
+	return err
+}
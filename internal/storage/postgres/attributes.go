@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// UserAttributes returns attributes of the user evaluated by authorization policies.
+func (s *Storage) UserAttributes(ctx context.Context, userID string) (map[string]any, error) {
+	const op = "storage.postgres.UserAttributes"
+
+	if err := uuid.Validate(userID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	var data []byte
+	if err := s.db.QueryRowContext(ctx, "SELECT attributes FROM users WHERE user_id = $1", userID).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	attributes := map[string]any{}
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return attributes, nil
+}
+
+// SetUserAttributes replaces attributes of the user.
+func (s *Storage) SetUserAttributes(ctx context.Context, userID string, attributes map[string]any) error {
+	const op = "storage.postgres.SetUserAttributes"
+
+	if err := uuid.Validate(userID); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET attributes = $2 WHERE user_id = $1", userID, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const clientSecretColumns = "secret_id, app_id, name, expires_at, created_at, last_used_at"
+
+func scanClientSecret(row scanner) (models.ClientSecret, error) {
+	var (
+		secret     models.ClientSecret
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+	)
+	err := row.Scan(&secret.ID, &secret.AppID, &secret.Name, &expiresAt, &secret.CreatedAt, &lastUsedAt)
+	secret.ExpiresAt = expiresAt.Time
+	secret.LastUsedAt = lastUsedAt.Time
+
+	return secret, err
+}
+
+// CreateClientSecret saves a secret of the app by its hash.
+func (s *Storage) CreateClientSecret(ctx context.Context, secret models.ClientSecret, hash string) (models.ClientSecret, error) {
+	const op = "storage.postgres.CreateClientSecret"
+
+	var expiresAt sql.NullTime
+	if !secret.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: secret.ExpiresAt, Valid: true}
+	}
+
+	created, err := scanClientSecret(s.db.QueryRowContext(ctx, `
+		INSERT INTO client_secrets (app_id, secret_hash, name, expires_at) VALUES ($1, $2, $3, $4)
+		RETURNING `+clientSecretColumns,
+		secret.AppID, hash, secret.Name, expiresAt,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.ClientSecret{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+
+		return models.ClientSecret{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// ClientSecrets returns secrets of the app, newest first, including expired ones.
+func (s *Storage) ClientSecrets(ctx context.Context, appID uuid.UUID) ([]models.ClientSecret, error) {
+	const op = "storage.postgres.ClientSecrets"
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+clientSecretColumns+" FROM client_secrets WHERE app_id = $1 ORDER BY created_at DESC",
+		appID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var secrets []models.ClientSecret
+	for rows.Next() {
+		secret, err := scanClientSecret(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		secrets = append(secrets, secret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return secrets, nil
+}
+
+// DeleteClientSecret deletes the secret of the app.
+func (s *Storage) DeleteClientSecret(ctx context.Context, appID uuid.UUID, secretID uuid.UUID) error {
+	const op = "storage.postgres.DeleteClientSecret"
+
+	res, err := s.db.ExecContext(ctx,
+		"DELETE FROM client_secrets WHERE app_id = $1 AND secret_id = $2",
+		appID, secretID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrClientSecretNotFound)
+	}
+
+	return nil
+}
+
+// HasClientSecret reports whether the app has an unexpired secret.
+func (s *Storage) HasClientSecret(ctx context.Context, appID uuid.UUID, now time.Time) (bool, error) {
+	const op = "storage.postgres.HasClientSecret"
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM client_secrets WHERE app_id = $1 AND (expires_at IS NULL OR expires_at > $2)
+		)`,
+		appID, now,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exists, nil
+}
+
+// UseClientSecret marks the unexpired secret of the app with the given hash as used.
+// Returns storage.ErrClientSecretNotFound if there is no such secret.
+func (s *Storage) UseClientSecret(ctx context.Context, appID uuid.UUID, hash string, now time.Time) error {
+	const op = "storage.postgres.UseClientSecret"
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE client_secrets SET last_used_at = $3
+		WHERE app_id = $1 AND secret_hash = $2 AND (expires_at IS NULL OR expires_at > $3)`,
+		appID, hash, now,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrClientSecretNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// SaveDeviceCode saves a device authorization by the hash of its device code.
+// Returns storage.ErrUserCodeTaken if a pending authorization has the same user code.
+func (s *Storage) SaveDeviceCode(ctx context.Context, code models.DeviceCode, hash string) error {
+	const op = "storage.postgres.SaveDeviceCode"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO device_codes (device_code_hash, user_code, app_id, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		hash, code.UserCode, code.AppID, append([]string{}, code.Scopes...), code.ExpiresAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserCodeTaken)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PendingDeviceCode returns the unexpired device authorization with the user code that
+// nobody approved or denied yet. Returns storage.ErrDeviceCodeNotFound if there is none.
+func (s *Storage) PendingDeviceCode(ctx context.Context, userCode string, now time.Time) (models.DeviceCode, error) {
+	const op = "storage.postgres.PendingDeviceCode"
+
+	code, err := scanDeviceCode(s.db.QueryRowContext(ctx, `
+		SELECT `+deviceCodeColumns+` FROM device_codes
+		WHERE user_code = $1 AND status = 'pending' AND expires_at > $2`,
+		userCode, now,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DeviceCode{}, fmt.Errorf("%s: %w", op, storage.ErrDeviceCodeNotFound)
+		}
+
+		return models.DeviceCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return code, nil
+}
+
+// ResolveDeviceCode approves or denies the pending device authorization with the user code on
+// behalf of the user. Returns storage.ErrDeviceCodeNotFound if there is no such authorization.
+func (s *Storage) ResolveDeviceCode(
+	ctx context.Context,
+	userCode string,
+	userID uuid.UUID,
+	auth models.Authentication,
+	status models.DeviceCodeStatus,
+	now time.Time,
+) error {
+	const op = "storage.postgres.ResolveDeviceCode"
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE device_codes SET status = $3, user_id = $4, auth_methods = $5, auth_time = $6
+		WHERE user_code = $1 AND status = 'pending' AND expires_at > $2`,
+		userCode, now, status, userID, authMethods(auth.Methods), auth.Time,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrDeviceCodeNotFound)
+	}
+
+	return nil
+}
+
+// PollDeviceCode records a poll of the device authorization with the device code hash and
+// returns the authorization as it was before the poll. An approved authorization is consumed
+// by the poll, so tokens are issued for it at most once.
+// Returns storage.ErrDeviceCodeNotFound if there is no such authorization.
+func (s *Storage) PollDeviceCode(ctx context.Context, hash string, now time.Time) (models.DeviceCode, error) {
+	const op = "storage.postgres.PollDeviceCode"
+
+	code, err := scanDeviceCode(s.db.QueryRowContext(ctx, `
+		UPDATE device_codes d SET
+			last_polled_at = $2,
+			status = CASE WHEN old.status = 'approved' THEN 'consumed' ELSE old.status END
+		FROM (SELECT `+deviceCodeColumns+` FROM device_codes WHERE device_code_hash = $1 FOR UPDATE) old
+		WHERE d.device_code_hash = $1
+		RETURNING `+oldDeviceCodeColumns,
+		hash, now,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DeviceCode{}, fmt.Errorf("%s: %w", op, storage.ErrDeviceCodeNotFound)
+		}
+
+		return models.DeviceCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return code, nil
+}
+
+// deviceCodeColumns are selected from "device_codes" by every query scanned with scanDeviceCode.
+const deviceCodeColumns = `app_id, user_code, scopes, status, user_id, auth_methods, auth_time, last_polled_at, expires_at`
+
+// oldDeviceCodeColumns are deviceCodeColumns of the row before PollDeviceCode updated it.
+const oldDeviceCodeColumns = `old.app_id, old.user_code, old.scopes, old.status, old.user_id,
+	old.auth_methods, old.auth_time, old.last_polled_at, old.expires_at`
+
+func scanDeviceCode(row scanner) (models.DeviceCode, error) {
+	var (
+		code         models.DeviceCode
+		userID       uuid.NullUUID
+		methods      []string
+		authTime     sql.NullTime
+		lastPolledAt sql.NullTime
+		typeMap      = pgtype.NewMap()
+	)
+	err := row.Scan(
+		&code.AppID, &code.UserCode, typeMap.SQLScanner(&code.Scopes), &code.Status, &userID,
+		typeMap.SQLScanner(&methods), &authTime, &lastPolledAt, &code.ExpiresAt,
+	)
+	if err != nil {
+		return models.DeviceCode{}, err
+	}
+
+	code.UserID = userID.UUID
+	code.Auth.Time = authTime.Time
+	for _, method := range methods {
+		code.Auth.Methods = append(code.Auth.Methods, models.AuthMethod(method))
+	}
+	code.LastPolledAt = lastPolledAt.Time
+
+	return code, nil
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+)
+
+// ExportUsers calls fn for every user matching the filter in (updated_at, user_id) order
+// and returns the time of the snapshot the users were read from.
+//
+// Rows are read through a server-side cursor in batches of batchSize,
+// so memory use does not depend on the number of users.
+func (s *Storage) ExportUsers(
+	ctx context.Context,
+	filter models.UserExportFilter,
+	batchSize int,
+	fn func(models.User) error,
+) (time.Time, error) {
+	const op = "storage.postgres.ExportUsers"
+
+	var (
+		// Service accounts are listed on their own.
+		conds = []string{"u.kind = 'user'"}
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.EmailPrefix != "" {
+		conds = append(conds, "lower(u.email) LIKE "+arg(escapeLike(strings.ToLower(filter.EmailPrefix))+"%"))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, st := range filter.Statuses {
+			statuses = append(statuses, string(st))
+		}
+		conds = append(conds, "u.status = ANY("+arg(statuses)+")")
+	}
+	if !filter.UpdatedSince.IsZero() {
+		conds = append(conds, "u.updated_at >= "+arg(filter.UpdatedSince))
+	}
+
+	query := "SELECT " + userColumns + ", " + isAdminExpr + " FROM users u"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY u.updated_at, u.user_id"
+
+	// Cursors live only inside a transaction; repeatable read keeps one snapshot for all batches.
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// now() is the start of the transaction, taken right before the snapshot.
+	var snapshot time.Time
+	if err := tx.QueryRowContext(ctx, "SELECT now()").Scan(&snapshot); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DECLARE users_export NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM users_export", batchSize)
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		n := 0
+		for rows.Next() {
+			var isAdmin bool
+			user, err := scanUser(rows, &isAdmin)
+			if err != nil {
+				rows.Close()
+				return time.Time{}, fmt.Errorf("%s: %w", op, err)
+			}
+			user.IsAdmin = isAdmin
+			n++
+
+			if err := fn(user); err != nil {
+				rows.Close()
+				return time.Time{}, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return time.Time{}, fmt.Errorf("%s: %w", op, err)
+		}
+		rows.Close()
+
+		if n < batchSize {
+			break
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,313 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func groupID(ctx context.Context, q queryRower, name string) (uuid.UUID, error) {
+	var id uuid.UUID
+	if err := q.QueryRowContext(ctx, "SELECT group_id FROM groups WHERE name = $1", name).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, storage.ErrGroupNotFound
+		}
+
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
+
+// CreateGroup saves a group. Returns storage.ErrGroupExists if the name is taken.
+func (s *Storage) CreateGroup(ctx context.Context, group models.Group) (models.Group, error) {
+	const op = "storage.postgres.CreateGroup"
+
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO groups (name, description) VALUES ($1, $2) RETURNING group_id, created_at",
+		group.Name, group.Description,
+	).Scan(&group.ID, &group.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.Group{}, fmt.Errorf("%s: %w", op, storage.ErrGroupExists)
+		}
+
+		return models.Group{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return group, nil
+}
+
+// DeleteGroup deletes the group with its memberships and role assignments.
+func (s *Storage) DeleteGroup(ctx context.Context, name string) error {
+	const op = "storage.postgres.DeleteGroup"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM groups WHERE name = $1", name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrGroupNotFound)
+	}
+
+	return nil
+}
+
+// AddGroupMember adds the user to the group. Adding an existing member is a no-op.
+func (s *Storage) AddGroupMember(ctx context.Context, groupName string, userID string) error {
+	const op = "storage.postgres.AddGroupMember"
+
+	if err := uuid.Validate(userID); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	id, err := groupID(ctx, s.db, groupName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT INTO group_members (group_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		id, userID,
+	); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveGroupMember removes the user from the group.
+// Returns storage.ErrMembershipNotFound if the user is not a direct member.
+func (s *Storage) RemoveGroupMember(ctx context.Context, groupName string, userID string) error {
+	const op = "storage.postgres.RemoveGroupMember"
+
+	if err := uuid.Validate(userID); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrMembershipNotFound)
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM group_members
+		WHERE user_id = $2 AND group_id = (SELECT group_id FROM groups WHERE name = $1)`,
+		groupName, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrMembershipNotFound)
+	}
+
+	return nil
+}
+
+// AddSubgroup makes members of the subgroup members of the group.
+// Returns storage.ErrGroupCycle if the group is the subgroup or already one of its subgroups.
+func (s *Storage) AddSubgroup(ctx context.Context, groupName string, subgroupName string) error {
+	const op = "storage.postgres.AddSubgroup"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// Two concurrent additions can close a cycle that neither of them sees alone.
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE group_subgroups IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	parentID, err := groupID(ctx, tx, groupName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	childID, err := groupID(ctx, tx, subgroupName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var cycle bool
+	err = tx.QueryRowContext(ctx, `
+		WITH RECURSIVE descendants (group_id) AS (
+			SELECT $2::uuid
+			UNION
+			SELECT gs.child_group_id FROM group_subgroups gs JOIN descendants d ON d.group_id = gs.parent_group_id
+		)
+		SELECT EXISTS (SELECT 1 FROM descendants WHERE group_id = $1)`,
+		parentID, childID,
+	).Scan(&cycle)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if cycle {
+		return fmt.Errorf("%s: %w", op, storage.ErrGroupCycle)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO group_subgroups (parent_group_id, child_group_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		parentID, childID,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveSubgroup detaches the subgroup from the group.
+// Returns storage.ErrMembershipNotFound if it is not a direct subgroup.
+func (s *Storage) RemoveSubgroup(ctx context.Context, groupName string, subgroupName string) error {
+	const op = "storage.postgres.RemoveSubgroup"
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM group_subgroups
+		WHERE parent_group_id = (SELECT group_id FROM groups WHERE name = $1)
+			AND child_group_id = (SELECT group_id FROM groups WHERE name = $2)`,
+		groupName, subgroupName,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrMembershipNotFound)
+	}
+
+	return nil
+}
+
+// AssignGroupRole gives members of the group the role within the app, or across all apps
+// if appID is zero. The role is looked up as in AssignRole. Assigning it twice is a no-op.
+func (s *Storage) AssignGroupRole(ctx context.Context, groupName string, appID uuid.UUID, roleName string) error {
+	const op = "storage.postgres.AssignGroupRole"
+
+	id, err := groupID(ctx, s.db, groupName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var roleID uuid.UUID
+	err = s.db.QueryRowContext(ctx, `
+		SELECT role_id FROM roles
+		WHERE name = $2 AND (app_id IS NULL OR app_id = $1)
+		ORDER BY app_id NULLS LAST
+		LIMIT 1`,
+		nullUUID(appID), roleName,
+	).Scan(&roleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT INTO group_app_roles (group_id, app_id, role_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+		id, nullUUID(appID), roleID,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UnassignGroupRole takes the role assigned within the app, or across all apps if appID is zero,
+// away from the group. Returns storage.ErrRoleNotFound if the group does not hold it there.
+func (s *Storage) UnassignGroupRole(ctx context.Context, groupName string, appID uuid.UUID, roleName string) error {
+	const op = "storage.postgres.UnassignGroupRole"
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM group_app_roles
+		WHERE group_id = (SELECT group_id FROM groups WHERE name = $1)
+			AND app_id IS NOT DISTINCT FROM $2
+			AND role_id = (
+				SELECT role_id FROM roles
+				WHERE name = $3 AND (app_id IS NULL OR app_id = $2)
+				ORDER BY app_id NULLS LAST
+				LIMIT 1)`,
+		groupName, nullUUID(appID), roleName,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	return nil
+}
+
+// UserGroups returns groups the user belongs to, directly or through subgroups.
+func (s *Storage) UserGroups(ctx context.Context, userID string) ([]models.Group, error) {
+	const op = "storage.postgres.UserGroups"
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH RECURSIVE user_groups (group_id) AS (
+			SELECT group_id FROM group_members WHERE user_id = $1
+			UNION
+			SELECT gs.parent_group_id FROM group_subgroups gs JOIN user_groups ug ON ug.group_id = gs.child_group_id
+		)
+		SELECT g.group_id, g.name, g.description, g.created_at
+		FROM groups g
+		WHERE g.group_id IN (SELECT group_id FROM user_groups)
+		ORDER BY g.name`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var groups []models.Group
+	for rows.Next() {
+		var g models.Group
+		if err := rows.Scan(&g.ID, &g.Name, &g.Description, &g.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return groups, nil
+}
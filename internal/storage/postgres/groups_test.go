@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddSubgroup_Cycle(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	// a contains b, which contains c.
+	a, b, c := addTestGroup(t, s), addTestGroup(t, s), addTestGroup(t, s)
+	require.NoError(t, s.AddSubgroup(ctx, a, b))
+	require.NoError(t, s.AddSubgroup(ctx, b, c))
+
+	assert.ErrorIs(t, s.AddSubgroup(ctx, a, a), storage.ErrGroupCycle)
+	assert.ErrorIs(t, s.AddSubgroup(ctx, b, a), storage.ErrGroupCycle)
+	assert.ErrorIs(t, s.AddSubgroup(ctx, c, a), storage.ErrGroupCycle)
+
+	// Adding an existing subgroup again is a no-op.
+	require.NoError(t, s.AddSubgroup(ctx, a, b))
+
+	userID := addTestUser(t, s)
+	require.NoError(t, s.AddGroupMember(ctx, c, userID.String()))
+
+	groups, err := s.UserGroups(ctx, userID.String())
+	require.NoError(t, err)
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	assert.ElementsMatch(t, []string{a, b, c}, names)
+
+	require.NoError(t, s.RemoveSubgroup(ctx, a, b))
+	assert.ErrorIs(t, s.RemoveSubgroup(ctx, a, b), storage.ErrMembershipNotFound)
+
+	groups, err = s.UserGroups(ctx, userID.String())
+	require.NoError(t, err)
+	assert.Len(t, groups, 2)
+}
+
+func TestUserPermissions_NestedGroups(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	appID := uuid.New()
+	exec(t, s, "INSERT INTO apps (app_id, name) VALUES ($1, $2)", appID, "test-"+appID.String())
+	t.Cleanup(func() { _, _ = s.db.Exec("DELETE FROM apps WHERE app_id = $1", appID) })
+
+	_, err := s.CreateRole(ctx, models.Role{AppID: appID, Name: "deployer"})
+	require.NoError(t, err)
+	_, err = s.CreatePermission(ctx, models.Permission{AppID: appID, Name: "deploys:create"})
+	require.NoError(t, err)
+	require.NoError(t, s.GrantPermission(ctx, appID, "deployer", "deploys:create"))
+
+	parent, child := addTestGroup(t, s), addTestGroup(t, s)
+	require.NoError(t, s.AddSubgroup(ctx, parent, child))
+	require.NoError(t, s.AssignGroupRole(ctx, parent, appID, "deployer"))
+
+	userID := addTestUser(t, s)
+	require.NoError(t, s.AddGroupMember(ctx, child, userID.String()))
+
+	permissions, err := s.UserPermissions(ctx, userID.String(), appID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deploys:create"}, permissions)
+
+	permissions, err = s.UserPermissions(ctx, userID.String(), uuid.Nil)
+	require.NoError(t, err)
+	assert.Empty(t, permissions, "the role is held within the app only")
+}
+
+// addTestGroup saves a group that is deleted when the test ends and returns its name.
+func addTestGroup(t *testing.T, s *Storage) string {
+	t.Helper()
+
+	group, err := s.CreateGroup(context.Background(), models.Group{Name: "test-" + uuid.NewString()})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, _ = s.db.Exec("DELETE FROM groups WHERE group_id = $1", group.ID)
+	})
+
+	return group.Name
+}
@@ -0,0 +1,302 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// SaveTOTP stores a new unconfirmed TOTP secret of the user, replacing a previous unconfirmed one.
+// Returns storage.ErrTOTPExists if the user already has a confirmed secret.
+func (s *Storage) SaveTOTP(ctx context.Context, userID string, secretEncrypted []byte) error {
+	const op = "storage.postgres.SaveTOTP"
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, secret_encrypted) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+			SET secret_encrypted = excluded.secret_encrypted, last_used_step = 0, created_at = now()
+			WHERE user_totp.confirmed_at IS NULL`,
+		userID, secretEncrypted,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPExists)
+	}
+
+	return nil
+}
+
+// TOTP returns TOTP enrollment of the user, confirmed or not.
+func (s *Storage) TOTP(ctx context.Context, userID string) (models.TOTP, error) {
+	const op = "storage.postgres.TOTP"
+
+	var (
+		totp        models.TOTP
+		confirmedAt sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx,
+		"SELECT user_id, secret_encrypted, confirmed_at, last_used_step FROM user_totp WHERE user_id = $1",
+		userID,
+	).Scan(&totp.UserID, &totp.SecretEncrypted, &confirmedAt, &totp.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTP{}, fmt.Errorf("%s: %w", op, storage.ErrTOTPNotFound)
+		}
+
+		return models.TOTP{}, fmt.Errorf("%s: %w", op, err)
+	}
+	totp.ConfirmedAt = confirmedAt.Time
+
+	return totp, nil
+}
+
+// UseTOTPAttempt counts a code about to be checked against the TOTP secret of the user.
+// It returns storage.ErrTOTPLocked once maxAttempts were counted since the last accepted code,
+// until lockout has passed since the last attempt, or if the user has no TOTP. Checking and
+// counting in one statement keeps concurrent guesses from getting past the limit.
+func (s *Storage) UseTOTPAttempt(ctx context.Context, userID string, maxAttempts int, lockout time.Duration) error {
+	const op = "storage.postgres.UseTOTPAttempt"
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE user_totp
+		SET attempts = CASE WHEN last_attempt_at > now() - make_interval(secs => $3) THEN attempts + 1 ELSE 1 END,
+			last_attempt_at = now()
+		WHERE user_id = $1 AND (attempts < $2 OR last_attempt_at <= now() - make_interval(secs => $3))`,
+		userID, maxAttempts, lockout.Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPLocked)
+	}
+
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code, confirms the enrollment and resets
+// the attempts counted by UseTOTPAttempt.
+// Returns storage.ErrTOTPStepUsed if the step or a later one was already used.
+func (s *Storage) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	const op = "storage.postgres.UseTOTPStep"
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE user_totp
+		SET last_used_step = $2, confirmed_at = COALESCE(confirmed_at, now()), attempts = 0
+		WHERE user_id = $1 AND last_used_step < $2`,
+		userID, step,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPStepUsed)
+	}
+
+	return nil
+}
+
+// DeleteTOTP removes TOTP enrollment of the user.
+// Recovery codes are removed too, unless email MFA stays enabled.
+func (s *Storage) DeleteTOTP(ctx context.Context, userID string) error {
+	const op = "storage.postgres.DeleteTOTP"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM mfa_recovery_codes
+		WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM user_email_mfa WHERE user_id = $1)`,
+		userID,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveMFAChallenge saves challenge and returns its ID.
+func (s *Storage) SaveMFAChallenge(ctx context.Context, challenge models.MFAChallenge) (string, error) {
+	const op = "storage.postgres.SaveMFAChallenge"
+
+	var id uuid.UUID
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO mfa_challenges (user_id, app_id, enrollment_required, first_factor, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING challenge_id`,
+		challenge.UserID, challenge.AppID, challenge.EnrollmentRequired, challenge.FirstFactor, challenge.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id.String(), nil
+}
+
+// MFAChallenge returns unconsumed and unexpired challenge by ID.
+func (s *Storage) MFAChallenge(ctx context.Context, challengeID string) (models.MFAChallenge, error) {
+	const op = "storage.postgres.MFAChallenge"
+
+	if err := uuid.Validate(challengeID); err != nil {
+		return models.MFAChallenge{}, fmt.Errorf("%s: %w", op, storage.ErrChallengeNotFound)
+	}
+
+	var c models.MFAChallenge
+	err := s.db.QueryRowContext(ctx, `
+		SELECT challenge_id, user_id, app_id, enrollment_required, first_factor, attempts, expires_at
+		FROM mfa_challenges
+		WHERE challenge_id = $1 AND consumed_at IS NULL AND expires_at > now()`,
+		challengeID,
+	).Scan(&c.ID, &c.UserID, &c.AppID, &c.EnrollmentRequired, &c.FirstFactor, &c.Attempts, &c.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MFAChallenge{}, fmt.Errorf("%s: %w", op, storage.ErrChallengeNotFound)
+		}
+
+		return models.MFAChallenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// UseMFAChallengeAttempt counts an attempt to complete the challenge. It returns
+// storage.ErrChallengeNotFound once maxAttempts were made or the challenge was consumed or
+// expired. Checking and counting in one statement keeps concurrent guesses from getting past the limit.
+func (s *Storage) UseMFAChallengeAttempt(ctx context.Context, challengeID string, maxAttempts int) error {
+	const op = "storage.postgres.UseMFAChallengeAttempt"
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE challenge_id = $1 AND attempts < $2 AND consumed_at IS NULL AND expires_at > now()`,
+		challengeID, maxAttempts,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrChallengeNotFound)
+	}
+
+	return nil
+}
+
+// ConsumeMFAChallenge marks challenge as completed. Challenge can be consumed only once.
+func (s *Storage) ConsumeMFAChallenge(ctx context.Context, challengeID string) error {
+	const op = "storage.postgres.ConsumeMFAChallenge"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE mfa_challenges SET consumed_at = now() WHERE challenge_id = $1 AND consumed_at IS NULL",
+		challengeID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrChallengeNotFound)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of the user with the given hashes.
+func (s *Storage) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	const op = "storage.postgres.ReplaceRecoveryCodes"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO mfa_recovery_codes (user_id, code_hash) SELECT $1, unnest($2::text[])",
+		userID, codeHashes,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks recovery code as used and returns how many unused codes are left.
+// Returns storage.ErrCodeNotFound if there is no such unused code.
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID string, codeHash string) (int, error) {
+	const op = "storage.postgres.UseRecoveryCode"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE mfa_recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL",
+		userID, codeHash,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrCodeNotFound)
+	}
+
+	var left int
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT count(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID,
+	).Scan(&left); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return left, nil
+}
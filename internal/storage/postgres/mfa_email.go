@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// EmailMFAEnabled tells whether the user receives one-time codes by email as a second factor.
+func (s *Storage) EmailMFAEnabled(ctx context.Context, userID string) (bool, error) {
+	const op = "storage.postgres.EmailMFAEnabled"
+
+	var enabled bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM user_email_mfa WHERE user_id = $1)", userID,
+	).Scan(&enabled)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return enabled, nil
+}
+
+// EnableEmailMFA enables email one-time codes as a second factor of the user.
+func (s *Storage) EnableEmailMFA(ctx context.Context, userID string) error {
+	const op = "storage.postgres.EnableEmailMFA"
+
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT INTO user_email_mfa (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING", userID,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DisableEmailMFA disables email one-time codes of the user.
+// Recovery codes are removed too, unless TOTP stays enrolled.
+func (s *Storage) DisableEmailMFA(ctx context.Context, userID string) error {
+	const op = "storage.postgres.DisableEmailMFA"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_email_mfa WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM mfa_recovery_codes
+		WHERE user_id = $1
+			AND NOT EXISTS (SELECT 1 FROM user_totp WHERE user_id = $1 AND confirmed_at IS NOT NULL)`,
+		userID,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveEmailCode saves hashed one-time code mailed to the user and returns its ID.
+func (s *Storage) SaveEmailCode(ctx context.Context, code models.EmailCode) (string, error) {
+	const op = "storage.postgres.SaveEmailCode"
+
+	var id uuid.UUID
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO mfa_email_codes (user_id, challenge_id, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING code_id`,
+		code.UserID, nullUUID(code.ChallengeID), code.CodeHash, code.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id.String(), nil
+}
+
+// EmailCodeStats returns how many codes were mailed to the user since given time
+// and when the latest one was sent.
+func (s *Storage) EmailCodeStats(ctx context.Context, userID string, since time.Time) (int, time.Time, error) {
+	const op = "storage.postgres.EmailCodeStats"
+
+	var (
+		count int
+		last  sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx,
+		"SELECT count(*), max(created_at) FROM mfa_email_codes WHERE user_id = $1 AND created_at > $2",
+		userID, since,
+	).Scan(&count, &last)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, last.Time, nil
+}
+
+// ActiveEmailCode returns the latest unconsumed and unexpired code of the user issued for the challenge.
+// Zero challengeID selects codes issued outside of login.
+func (s *Storage) ActiveEmailCode(ctx context.Context, userID string, challengeID uuid.UUID) (models.EmailCode, error) {
+	const op = "storage.postgres.ActiveEmailCode"
+
+	var (
+		code      models.EmailCode
+		challenge uuid.NullUUID
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT code_id, user_id, challenge_id, code_hash, attempts, expires_at, created_at
+		FROM mfa_email_codes
+		WHERE user_id = $1 AND challenge_id IS NOT DISTINCT FROM $2
+			AND consumed_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		userID, nullUUID(challengeID),
+	).Scan(&code.ID, &code.UserID, &challenge, &code.CodeHash, &code.Attempts, &code.ExpiresAt, &code.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.EmailCode{}, fmt.Errorf("%s: %w", op, storage.ErrCodeNotFound)
+		}
+
+		return models.EmailCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	code.ChallengeID = challenge.UUID
+
+	return code, nil
+}
+
+// UseEmailCodeAttempt counts an attempt to use the code. It returns storage.ErrCodeNotFound
+// once maxAttempts were made or the code was consumed or expired. Checking and counting in
+// one statement keeps concurrent guesses from getting past the limit.
+func (s *Storage) UseEmailCodeAttempt(ctx context.Context, codeID string, maxAttempts int) error {
+	const op = "storage.postgres.UseEmailCodeAttempt"
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE mfa_email_codes SET attempts = attempts + 1
+		WHERE code_id = $1 AND attempts < $2 AND consumed_at IS NULL AND expires_at > now()`,
+		codeID, maxAttempts,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCodeNotFound)
+	}
+
+	return nil
+}
+
+// ConsumeEmailCode marks code as used. Code can be consumed only once.
+func (s *Storage) ConsumeEmailCode(ctx context.Context, codeID string) error {
+	const op = "storage.postgres.ConsumeEmailCode"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE mfa_email_codes SET consumed_at = now() WHERE code_id = $1 AND consumed_at IS NULL",
+		codeID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCodeNotFound)
+	}
+
+	return nil
+}
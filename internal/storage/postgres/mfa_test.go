@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseMFAChallengeAttempt(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	appID := uuid.New()
+	exec(t, s, "INSERT INTO apps (app_id, name) VALUES ($1, $2)", appID, "test-"+appID.String())
+	t.Cleanup(func() { _, _ = s.db.Exec("DELETE FROM apps WHERE app_id = $1", appID) })
+
+	userID := addTestUser(t, s)
+
+	id, err := s.SaveMFAChallenge(ctx, models.MFAChallenge{
+		UserID:      userID,
+		AppID:       appID,
+		FirstFactor: models.AuthMethodSMS,
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	for range 2 {
+		require.NoError(t, s.UseMFAChallengeAttempt(ctx, id, 2))
+	}
+	assert.ErrorIs(t, s.UseMFAChallengeAttempt(ctx, id, 2), storage.ErrChallengeNotFound)
+
+	challenge, err := s.MFAChallenge(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, challenge.Attempts)
+	assert.Equal(t, models.AuthMethodSMS, challenge.FirstFactor)
+
+	require.NoError(t, s.ConsumeMFAChallenge(ctx, id))
+	assert.ErrorIs(t, s.UseMFAChallengeAttempt(ctx, id, 5), storage.ErrChallengeNotFound)
+}
+
+func TestUseTOTPAttempt(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	userID := addTestUser(t, s)
+	require.NoError(t, s.SaveTOTP(ctx, userID.String(), []byte("secret")))
+
+	for range 2 {
+		require.NoError(t, s.UseTOTPAttempt(ctx, userID.String(), 2, time.Hour))
+	}
+	assert.ErrorIs(t, s.UseTOTPAttempt(ctx, userID.String(), 2, time.Hour), storage.ErrTOTPLocked)
+
+	// An accepted code resets the counter.
+	require.NoError(t, s.UseTOTPStep(ctx, userID.String(), 1))
+	require.NoError(t, s.UseTOTPAttempt(ctx, userID.String(), 2, time.Hour))
+
+	// So does the lockout passing since the last attempt.
+	exec(t, s, "UPDATE user_totp SET attempts = 2, last_attempt_at = now() - interval '2 hours' WHERE user_id = $1", userID)
+	require.NoError(t, s.UseTOTPAttempt(ctx, userID.String(), 2, time.Hour))
+
+	assert.ErrorIs(t, s.UseTOTPAttempt(ctx, uuid.NewString(), 2, time.Hour), storage.ErrTOTPLocked)
+}
+
+func TestUseEmailCodeAttempt(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	userID := addTestUser(t, s)
+
+	id, err := s.SaveEmailCode(ctx, models.EmailCode{
+		UserID:    userID,
+		CodeHash:  []byte("hash"),
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	for range 2 {
+		require.NoError(t, s.UseEmailCodeAttempt(ctx, id, 2))
+	}
+	assert.ErrorIs(t, s.UseEmailCodeAttempt(ctx, id, 2), storage.ErrCodeNotFound)
+
+	code, err := s.ActiveEmailCode(ctx, userID.String(), uuid.Nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, code.Attempts)
+
+	require.NoError(t, s.ConsumeEmailCode(ctx, id))
+	assert.ErrorIs(t, s.UseEmailCodeAttempt(ctx, id, 5), storage.ErrCodeNotFound)
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// SaveAuthorizationCode saves an authorization code by its hash.
+func (s *Storage) SaveAuthorizationCode(ctx context.Context, code models.AuthorizationCode, hash string) error {
+	const op = "storage.postgres.SaveAuthorizationCode"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO oauth_codes
+			(code_hash, app_id, user_id, redirect_uri, code_challenge, scopes, nonce, auth_methods, auth_time, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		hash, code.AppID, code.UserID, code.RedirectURI, code.CodeChallenge,
+		append([]string{}, code.Scopes...), code.Nonce, authMethods(code.Auth.Methods), code.Auth.Time, code.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ConsumeAuthorizationCode marks the unexpired, unused code with the given hash as used and returns it.
+// Returns storage.ErrAuthCodeNotFound if there is no such code, so a code is exchanged at most once.
+func (s *Storage) ConsumeAuthorizationCode(ctx context.Context, hash string, now time.Time) (models.AuthorizationCode, error) {
+	const op = "storage.postgres.ConsumeAuthorizationCode"
+
+	var (
+		code    models.AuthorizationCode
+		methods []string
+		typeMap = pgtype.NewMap()
+	)
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE oauth_codes SET consumed_at = $2
+		WHERE code_hash = $1 AND consumed_at IS NULL AND expires_at > $2
+		RETURNING app_id, user_id, redirect_uri, code_challenge, scopes, nonce, auth_methods, auth_time, expires_at`,
+		hash, now,
+	).Scan(
+		&code.AppID, &code.UserID, &code.RedirectURI, &code.CodeChallenge,
+		typeMap.SQLScanner(&code.Scopes), &code.Nonce, typeMap.SQLScanner(&methods), &code.Auth.Time, &code.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, storage.ErrAuthCodeNotFound)
+		}
+
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, method := range methods {
+		code.Auth.Methods = append(code.Auth.Methods, models.AuthMethod(method))
+	}
+
+	return code, nil
+}
+
+func authMethods(methods []models.AuthMethod) []string {
+	result := make([]string, 0, len(methods))
+	for _, method := range methods {
+		result = append(result, string(method))
+	}
+
+	return result
+}
@@ -0,0 +1,393 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// Every query on org_members is scoped by org_id, so one organization never sees another's members.
+// Rows with NULL accepted_at are invitations: they grant nothing until the user accepts them.
+
+// CreateOrganization saves the organization with the user as its first admin.
+func (s *Storage) CreateOrganization(ctx context.Context, org models.Organization, adminID string) (models.Organization, error) {
+	const op = "storage.postgres.CreateOrganization"
+
+	if err := uuid.Validate(adminID); err != nil {
+		return models.Organization{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO organizations (name) VALUES ($1) RETURNING org_id, created_at",
+		org.Name,
+	).Scan(&org.ID, &org.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.Organization{}, fmt.Errorf("%s: %w", op, storage.ErrOrgExists)
+		}
+
+		return models.Organization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := addOrgMember(ctx, tx, org.ID.String(), adminID, models.OrgRoleAdmin, true); err != nil {
+		return models.Organization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Organization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return org, nil
+}
+
+// Organization returns organization by id.
+func (s *Storage) Organization(ctx context.Context, orgID string) (models.Organization, error) {
+	const op = "storage.postgres.Organization"
+
+	if err := uuid.Validate(orgID); err != nil {
+		return models.Organization{}, fmt.Errorf("%s: %w", op, storage.ErrOrgNotFound)
+	}
+
+	var org models.Organization
+	err := s.db.QueryRowContext(ctx,
+		"SELECT org_id, name, created_at FROM organizations WHERE org_id = $1", orgID,
+	).Scan(&org.ID, &org.Name, &org.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Organization{}, fmt.Errorf("%s: %w", op, storage.ErrOrgNotFound)
+		}
+
+		return models.Organization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return org, nil
+}
+
+// UserOrganizations returns organizations the user is a member of.
+func (s *Storage) UserOrganizations(ctx context.Context, userID string) ([]models.Organization, error) {
+	const op = "storage.postgres.UserOrganizations"
+
+	orgs, err := s.userOrganizations(ctx, userID, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orgs, nil
+}
+
+// UserOrgInvitations returns organizations the user is invited to and has not joined yet.
+func (s *Storage) UserOrgInvitations(ctx context.Context, userID string) ([]models.Organization, error) {
+	const op = "storage.postgres.UserOrgInvitations"
+
+	orgs, err := s.userOrganizations(ctx, userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orgs, nil
+}
+
+func (s *Storage) userOrganizations(ctx context.Context, userID string, accepted bool) ([]models.Organization, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.org_id, o.name, o.created_at
+		FROM organizations o JOIN org_members m ON m.org_id = o.org_id
+		WHERE m.user_id = $1 AND (m.accepted_at IS NOT NULL) = $2
+		ORDER BY o.name`,
+		userID, accepted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		orgs = append(orgs, org)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+// OrgMember returns membership of the user in the organization. A pending invitation
+// is no membership: storage.ErrOrgMemberNotFound is returned for it.
+func (s *Storage) OrgMember(ctx context.Context, orgID string, userID string) (models.OrgMember, error) {
+	const op = "storage.postgres.OrgMember"
+
+	if uuid.Validate(orgID) != nil || uuid.Validate(userID) != nil {
+		return models.OrgMember{}, fmt.Errorf("%s: %w", op, storage.ErrOrgMemberNotFound)
+	}
+
+	member, err := scanOrgMember(s.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+`, m.org_id, m.role, m.created_at, m.accepted_at
+		FROM org_members m JOIN users u ON u.user_id = m.user_id
+		WHERE m.org_id = $1 AND m.user_id = $2 AND m.accepted_at IS NOT NULL`,
+		orgID, userID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OrgMember{}, fmt.Errorf("%s: %w", op, storage.ErrOrgMemberNotFound)
+		}
+
+		return models.OrgMember{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return member, nil
+}
+
+// OrgMembers returns members of the organization and pending invitations, ordered by email.
+func (s *Storage) OrgMembers(ctx context.Context, orgID string) ([]models.OrgMember, error) {
+	const op = "storage.postgres.OrgMembers"
+
+	if err := uuid.Validate(orgID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrOrgNotFound)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+userColumns+`, m.org_id, m.role, m.created_at, m.accepted_at
+		FROM org_members m JOIN users u ON u.user_id = m.user_id
+		WHERE m.org_id = $1
+		ORDER BY lower(u.email)`,
+		orgID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var members []models.OrgMember
+	for rows.Next() {
+		member, err := scanOrgMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return members, nil
+}
+
+func scanOrgMember(row scanner) (models.OrgMember, error) {
+	var (
+		member     models.OrgMember
+		acceptedAt sql.NullTime
+	)
+
+	user, err := scanUser(row, &member.OrgID, &member.Role, &member.CreatedAt, &acceptedAt)
+	member.User = user
+	member.AcceptedAt = acceptedAt.Time
+
+	return member, err
+}
+
+// AddOrgMember invites the user to the organization with the role. The user becomes a member
+// once AcceptOrgInvitation is called. Returns storage.ErrOrgMemberExists if the user is
+// already a member or invited.
+func (s *Storage) AddOrgMember(ctx context.Context, orgID string, userID string, role models.OrgRole) error {
+	const op = "storage.postgres.AddOrgMember"
+
+	if err := uuid.Validate(orgID); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrOrgNotFound)
+	}
+	if err := uuid.Validate(userID); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	if err := addOrgMember(ctx, s.db, orgID, userID, role, false); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func addOrgMember(ctx context.Context, db execer, orgID string, userID string, role models.OrgRole, accepted bool) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO org_members (org_id, user_id, role, accepted_at)
+		VALUES ($1, $2, $3, CASE WHEN $4 THEN now() END)`,
+		orgID, userID, role, accepted,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch {
+			case pgErr.Code == "23505":
+				return storage.ErrOrgMemberExists
+			case pgErr.Code == "23503" && pgErr.ConstraintName == "org_members_user_id_fkey":
+				return storage.ErrUserNotFound
+			case pgErr.Code == "23503":
+				return storage.ErrOrgNotFound
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// AcceptOrgInvitation makes the invited user a member of the organization.
+// Returns storage.ErrOrgMemberNotFound if there is no pending invitation.
+func (s *Storage) AcceptOrgInvitation(ctx context.Context, orgID string, userID string) error {
+	const op = "storage.postgres.AcceptOrgInvitation"
+
+	if uuid.Validate(orgID) != nil || uuid.Validate(userID) != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrOrgMemberNotFound)
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE org_members SET accepted_at = now() WHERE org_id = $1 AND user_id = $2 AND accepted_at IS NULL",
+		orgID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrOrgMemberNotFound)
+	}
+
+	return nil
+}
+
+// SetOrgMemberRole changes the role of the member. Pending invitations keep their role.
+// Returns storage.ErrLastOrgAdmin if that would leave the organization without admins.
+func (s *Storage) SetOrgMemberRole(ctx context.Context, orgID string, userID string, role models.OrgRole) error {
+	const op = "storage.postgres.SetOrgMemberRole"
+
+	if uuid.Validate(orgID) != nil || uuid.Validate(userID) != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrOrgMemberNotFound)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if role != models.OrgRoleAdmin {
+		if err := keepOrgAdmin(ctx, tx, orgID, userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE org_members SET role = $3 WHERE org_id = $1 AND user_id = $2 AND accepted_at IS NOT NULL",
+		orgID, userID, role,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrOrgMemberNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveOrgMember removes the user from the organization or withdraws their invitation.
+// Returns storage.ErrLastOrgAdmin if that would leave the organization without admins.
+func (s *Storage) RemoveOrgMember(ctx context.Context, orgID string, userID string) error {
+	const op = "storage.postgres.RemoveOrgMember"
+
+	if uuid.Validate(orgID) != nil || uuid.Validate(userID) != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrOrgMemberNotFound)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := keepOrgAdmin(ctx, tx, orgID, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM org_members WHERE org_id = $1 AND user_id = $2", orgID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrOrgMemberNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// keepOrgAdmin locks admins of the organization and fails if the user is the only one,
+// so concurrent changes cannot demote or remove all of them.
+func keepOrgAdmin(ctx context.Context, tx *sql.Tx, orgID string, userID string) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT user_id FROM org_members WHERE org_id = $1 AND role = 'admin' AND accepted_at IS NOT NULL FOR UPDATE",
+		orgID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var admins []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+
+		admins = append(admins, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(admins) == 1 && admins[0] == uuid.MustParse(userID) {
+		return storage.ErrLastOrgAdmin
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgInvitation(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	ownerID := addTestUser(t, s)
+	inviteeID := addTestUser(t, s)
+
+	org, err := s.CreateOrganization(ctx, models.Organization{Name: "test-" + uuid.NewString()}, ownerID.String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = s.db.Exec("DELETE FROM organizations WHERE org_id = $1", org.ID) })
+	orgID := org.ID.String()
+
+	owner, err := s.OrgMember(ctx, orgID, ownerID.String())
+	require.NoError(t, err)
+	assert.False(t, owner.Pending(), "the first admin joins right away")
+
+	require.NoError(t, s.AddOrgMember(ctx, orgID, inviteeID.String(), models.OrgRoleAdmin))
+	assert.ErrorIs(t, s.AddOrgMember(ctx, orgID, inviteeID.String(), models.OrgRoleMember), storage.ErrOrgMemberExists)
+
+	// A pending invitation is no membership.
+	_, err = s.OrgMember(ctx, orgID, inviteeID.String())
+	assert.ErrorIs(t, err, storage.ErrOrgMemberNotFound)
+	orgs, err := s.UserOrganizations(ctx, inviteeID.String())
+	require.NoError(t, err)
+	assert.Empty(t, orgs)
+	invitations, err := s.UserOrgInvitations(ctx, inviteeID.String())
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, org.ID, invitations[0].ID)
+
+	assert.ErrorIs(t, s.SetOrgMemberRole(ctx, orgID, inviteeID.String(), models.OrgRoleMember), storage.ErrOrgMemberNotFound)
+	assert.ErrorIs(t, s.RemoveOrgMember(ctx, orgID, ownerID.String()), storage.ErrLastOrgAdmin)
+
+	members, err := s.OrgMembers(ctx, orgID)
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	require.NoError(t, s.AcceptOrgInvitation(ctx, orgID, inviteeID.String()))
+	assert.ErrorIs(t, s.AcceptOrgInvitation(ctx, orgID, inviteeID.String()), storage.ErrOrgMemberNotFound)
+
+	invitee, err := s.OrgMember(ctx, orgID, inviteeID.String())
+	require.NoError(t, err)
+	assert.False(t, invitee.Pending())
+	assert.Equal(t, models.OrgRoleAdmin, invitee.Role)
+
+	// With a second admin the first one may leave.
+	require.NoError(t, s.RemoveOrgMember(ctx, orgID, ownerID.String()))
+}
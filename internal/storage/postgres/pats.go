@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const personalAccessTokenColumns = "token_id, user_id, name, scopes, expires_at, created_at, last_used_at"
+
+func scanPersonalAccessToken(row scanner) (models.PersonalAccessToken, error) {
+	var (
+		token      models.PersonalAccessToken
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+	)
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Name, pgtype.NewMap().SQLScanner(&token.Scopes),
+		&expiresAt, &token.CreatedAt, &lastUsedAt,
+	)
+	token.ExpiresAt = expiresAt.Time
+	token.LastUsedAt = lastUsedAt.Time
+
+	return token, err
+}
+
+// CreatePersonalAccessToken saves a token of the user by its hash.
+func (s *Storage) CreatePersonalAccessToken(
+	ctx context.Context,
+	token models.PersonalAccessToken,
+	hash string,
+) (models.PersonalAccessToken, error) {
+	const op = "storage.postgres.CreatePersonalAccessToken"
+
+	var expiresAt sql.NullTime
+	if !token.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: token.ExpiresAt, Valid: true}
+	}
+
+	created, err := scanPersonalAccessToken(s.db.QueryRowContext(ctx, `
+		INSERT INTO personal_access_tokens (user_id, token_hash, name, scopes, expires_at) VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+personalAccessTokenColumns,
+		token.UserID, hash, token.Name, append([]string{}, token.Scopes...), expiresAt,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.PersonalAccessToken{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return models.PersonalAccessToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// PersonalAccessTokens returns tokens of the user, newest first, including expired ones.
+func (s *Storage) PersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	const op = "storage.postgres.PersonalAccessTokens"
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+personalAccessTokenColumns+" FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var tokens []models.PersonalAccessToken
+	for rows.Next() {
+		token, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// DeletePersonalAccessToken deletes the token of the user.
+func (s *Storage) DeletePersonalAccessToken(ctx context.Context, userID uuid.UUID, tokenID uuid.UUID) error {
+	const op = "storage.postgres.DeletePersonalAccessToken"
+
+	res, err := s.db.ExecContext(ctx,
+		"DELETE FROM personal_access_tokens WHERE user_id = $1 AND token_id = $2",
+		userID, tokenID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrPersonalAccessTokenNotFound)
+	}
+
+	return nil
+}
+
+// UsePersonalAccessToken marks the unexpired token with the given hash as used and returns it.
+// Returns storage.ErrPersonalAccessTokenNotFound if there is no such token.
+func (s *Storage) UsePersonalAccessToken(ctx context.Context, hash string, now time.Time) (models.PersonalAccessToken, error) {
+	const op = "storage.postgres.UsePersonalAccessToken"
+
+	token, err := scanPersonalAccessToken(s.db.QueryRowContext(ctx, `
+		UPDATE personal_access_tokens SET last_used_at = $2
+		WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > $2)
+		RETURNING `+personalAccessTokenColumns,
+		hash, now,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PersonalAccessToken{}, fmt.Errorf("%s: %w", op, storage.ErrPersonalAccessTokenNotFound)
+		}
+
+		return models.PersonalAccessToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
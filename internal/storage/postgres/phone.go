@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// SavePhoneCode saves hashed one-time code sent to the phone and returns its ID.
+func (s *Storage) SavePhoneCode(ctx context.Context, code models.PhoneCode) (string, error) {
+	const op = "storage.postgres.SavePhoneCode"
+
+	var id uuid.UUID
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO phone_codes (phone, purpose, user_id, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING code_id`,
+		code.Phone, code.Purpose, nullUUID(code.UserID), code.CodeHash, code.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id.String(), nil
+}
+
+// PhoneCodeStats returns how many codes were sent to the phone since given time
+// and when the latest one was sent.
+func (s *Storage) PhoneCodeStats(ctx context.Context, phone string, since time.Time) (int, time.Time, error) {
+	const op = "storage.postgres.PhoneCodeStats"
+
+	var (
+		count int
+		last  sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx,
+		"SELECT count(*), max(created_at) FROM phone_codes WHERE phone = $1 AND created_at > $2",
+		phone, since,
+	).Scan(&count, &last)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, last.Time, nil
+}
+
+// ActivePhoneCode returns the latest unconsumed and unexpired code for the phone.
+func (s *Storage) ActivePhoneCode(ctx context.Context, phone string, purpose models.PhoneCodePurpose) (models.PhoneCode, error) {
+	const op = "storage.postgres.ActivePhoneCode"
+
+	var (
+		code   models.PhoneCode
+		userID uuid.NullUUID
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT code_id, phone, purpose, user_id, code_hash, attempts, expires_at, created_at
+		FROM phone_codes
+		WHERE phone = $1 AND purpose = $2 AND consumed_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		phone, purpose,
+	).Scan(&code.ID, &code.Phone, &code.Purpose, &userID, &code.CodeHash, &code.Attempts, &code.ExpiresAt, &code.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PhoneCode{}, fmt.Errorf("%s: %w", op, storage.ErrCodeNotFound)
+		}
+
+		return models.PhoneCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	code.UserID = userID.UUID
+
+	return code, nil
+}
+
+// UsePhoneCodeAttempt counts an attempt to use the code. It returns storage.ErrCodeNotFound
+// once maxAttempts were made or the code was consumed or expired. Checking and counting in
+// one statement keeps concurrent guesses from getting past the limit.
+func (s *Storage) UsePhoneCodeAttempt(ctx context.Context, codeID string, maxAttempts int) error {
+	const op = "storage.postgres.UsePhoneCodeAttempt"
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE phone_codes SET attempts = attempts + 1
+		WHERE code_id = $1 AND attempts < $2 AND consumed_at IS NULL AND expires_at > now()`,
+		codeID, maxAttempts,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCodeNotFound)
+	}
+
+	return nil
+}
+
+// ConsumePhoneCode marks code as used. Code can be consumed only once.
+func (s *Storage) ConsumePhoneCode(ctx context.Context, codeID string) error {
+	const op = "storage.postgres.ConsumePhoneCode"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE phone_codes SET consumed_at = now() WHERE code_id = $1 AND consumed_at IS NULL",
+		codeID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCodeNotFound)
+	}
+
+	return nil
+}
+
+// SetVerifiedPhone attaches verified phone to the user.
+func (s *Storage) SetVerifiedPhone(ctx context.Context, userID string, phone string) error {
+	const op = "storage.postgres.SetVerifiedPhone"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE users SET phone = $2, phone_verified_at = now() WHERE user_id = $1",
+		userID, phone,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23505" {
+				return fmt.Errorf("%s: %w", op, storage.ErrPhoneTaken)
+			}
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
 	"github.com/sol1corejz/auth-service/internal/domain/models"
@@ -29,12 +30,12 @@ func New() (*Storage, error) {
 	return &Storage{db: db}, nil
 }
 
-// SaveUser saves user to db and returns new user ID
-func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (string, error) {
+// SaveUser saves user with the given status to db and returns new user ID
+func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte, status models.UserStatus) (string, error) {
 	const op = "storage.postgres.SaveUser"
 
 	// Добавляем RETURNING id в запрос
-	stmt, err := s.db.Prepare(`INSERT INTO users (email, pass_hash) VALUES ($1, $2) RETURNING user_id`)
+	stmt, err := s.db.Prepare(`INSERT INTO users (email, pass_hash, status) VALUES ($1, $2, $3) RETURNING user_id`)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
@@ -42,7 +43,7 @@ func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (
 
 	var id uuid.UUID
 	// Используем QueryRowContext с RETURNING
-	err = stmt.QueryRowContext(ctx, email, passHash).Scan(&id)
+	err = stmt.QueryRowContext(ctx, email, passHash, status).Scan(&id)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -56,19 +57,36 @@ func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (
 	return id.String(), nil
 }
 
-// User returns user by email.
-func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
+// identifierConditions maps identifier kinds to the users condition they are matched by.
+var identifierConditions = map[models.IdentifierKind]string{
+	models.IdentifierID:       "u.user_id = $1",
+	models.IdentifierEmail:    "lower(u.email) = lower($1)",
+	models.IdentifierUsername: "lower(u.username) = lower($1)",
+	models.IdentifierPhone:    "u.phone = $1",
+}
+
+// User returns user by identifier. Email and username are compared case-insensitively.
+func (s *Storage) User(ctx context.Context, identifier models.Identifier) (models.User, error) {
 	const op = "storage.postgres.User"
 
-	stmt, err := s.db.Prepare("SELECT user_id, email, pass_hash FROM users WHERE email = $1")
+	condition, ok := identifierConditions[identifier.Kind]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: unknown identifier kind %d", op, identifier.Kind)
+	}
+
+	if identifier.Kind == models.IdentifierID {
+		if err := uuid.Validate(identifier.Value); err != nil {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+	}
+
+	stmt, err := s.db.Prepare("SELECT " + userColumns + " FROM users u WHERE " + condition)
 	if err != nil {
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, email)
-
-	var user models.User
-	err = row.Scan(&user.ID, &user.Email, &user.PassHash)
+	user, err := scanUser(stmt.QueryRowContext(ctx, identifier.Value))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -80,16 +98,55 @@ func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 	return user, nil
 }
 
-// IsAdmin return is user admin
-func (s *Storage) IsAdmin(ctx context.Context, userID string) (bool, error) {
+// SetUsername sets username of the user.
+func (s *Storage) SetUsername(ctx context.Context, userID string, username string) error {
+	const op = "storage.postgres.SetUsername"
+
+	stmt, err := s.db.Prepare("UPDATE users SET username = $2 WHERE user_id = $1")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, userID, username)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23505" {
+				return fmt.Errorf("%s: %w", op, storage.ErrUsernameTaken)
+			}
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// IsAdmin return is user admin of the app, i.e. holds the admin role assigned within the app
+// or across all apps, directly or through groups. Zero appID checks the latter only.
+func (s *Storage) IsAdmin(ctx context.Context, userID string, appID uuid.UUID) (bool, error) {
 	const op = "storage.postgres.IsAdmin"
 
-	stmt, err := s.db.Prepare("SELECT is_admin FROM users WHERE user_id = $1")
+	stmt, err := s.db.Prepare(userRolesCTE + `
+		SELECT EXISTS (
+			SELECT 1 FROM user_roles ur JOIN roles r ON r.role_id = ur.role_id
+			WHERE r.app_id IS NULL AND r.name = 'admin'
+		)
+		FROM users WHERE user_id = $1`)
 	if err != nil {
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, userID)
+	row := stmt.QueryRowContext(ctx, userID, nullUUID(appID))
 	var isAdmin bool
 	err = row.Scan(&isAdmin)
 	if err != nil {
@@ -103,19 +160,42 @@ func (s *Storage) IsAdmin(ctx context.Context, userID string) (bool, error) {
 	return isAdmin, nil
 }
 
-// App returns app by id.
+// appColumns are selected from "apps" by every query scanned with scanApp.
+const appColumns = `app_id, name, description, owner_id, enabled, created_at,
+	mfa_policy, COALESCE(webauthn_rp_id, ''), webauthn_origins, client_scopes, redirect_uris,
+	post_logout_redirect_uris, access_mode, allowed_email_domains`
+
+func scanApp(row scanner) (models.App, error) {
+	var (
+		app     models.App
+		ownerID uuid.NullUUID
+		typeMap = pgtype.NewMap()
+	)
+	err := row.Scan(
+		&app.ID, &app.Name, &app.Description, &ownerID, &app.Enabled, &app.CreatedAt,
+		&app.MFAPolicy, &app.WebAuthnRPID, typeMap.SQLScanner(&app.WebAuthnOrigins),
+		typeMap.SQLScanner(&app.ClientScopes), typeMap.SQLScanner(&app.RedirectURIs),
+		typeMap.SQLScanner(&app.PostLogoutRedirectURIs), &app.AccessMode,
+		typeMap.SQLScanner(&app.AllowedEmailDomains),
+	)
+	app.OwnerID = ownerID.UUID
+
+	return app, err
+}
+
+// App returns app by name.
 func (s *Storage) App(ctx context.Context, name string) (models.App, error) {
 	const op = "storage.postgres.App"
 
-	stmt, err := s.db.Prepare("SELECT app_id, name FROM apps WHERE name = $1")
+	stmt, err := s.db.Prepare("SELECT " + appColumns + " FROM apps WHERE name = $1")
 	if err != nil {
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
 	row := stmt.QueryRowContext(ctx, name)
 
-	var app models.App
-	err = row.Scan(&app.ID, &app.Name)
+	app, err := scanApp(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
@@ -127,6 +207,46 @@ func (s *Storage) App(ctx context.Context, name string) (models.App, error) {
 	return app, nil
 }
 
+// AppByID returns app by id.
+func (s *Storage) AppByID(ctx context.Context, appID string) (models.App, error) {
+	const op = "storage.postgres.AppByID"
+
+	if err := uuid.Validate(appID); err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	app, err := scanApp(s.db.QueryRowContext(ctx, "SELECT "+appColumns+" FROM apps WHERE app_id = $1", appID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, nil
+}
+
+// SetAppMFAPolicy sets which users of the app must pass a second factor.
+func (s *Storage) SetAppMFAPolicy(ctx context.Context, appName string, policy models.MFAPolicy) error {
+	const op = "storage.postgres.SetAppMFAPolicy"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE apps SET mfa_policy = $2 WHERE name = $1", appName, policy)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	return nil
+}
+
 func GetDatabaseURL() string {
 	// Попробуем прочитать из переменных окружения (для Docker)
 	dbURL := os.Getenv("DB_URL")
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStorage connects to the migrated database at DB_URL. Tests using it are skipped
+// without one, so the package tests run anywhere.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	if os.Getenv("DB_URL") == "" {
+		t.Skip("DB_URL is not set")
+	}
+
+	s, err := New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.db.Close() })
+
+	require.NoError(t, s.db.PingContext(context.Background()))
+
+	return s
+}
+
+// addTestUser saves an active user that is deleted when the test ends.
+func addTestUser(t *testing.T, s *Storage) uuid.UUID {
+	t.Helper()
+
+	id, err := s.SaveUser(context.Background(), uuid.NewString()+"@test.local", []byte("hash"), models.UserStatusActive)
+	require.NoError(t, err)
+
+	userID := uuid.MustParse(id)
+	t.Cleanup(func() {
+		_, _ = s.db.Exec("DELETE FROM users WHERE user_id = $1", userID)
+	})
+
+	return userID
+}
+
+// exec runs a statement the test needs to set up data.
+func exec(t *testing.T, s *Storage, query string, args ...any) {
+	t.Helper()
+
+	_, err := s.db.ExecContext(context.Background(), query, args...)
+	require.NoError(t, err)
+}
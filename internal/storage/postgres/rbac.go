@@ -0,0 +1,457 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// isAdminExpr tells whether user "u" holds the global admin role across all apps,
+// directly or through groups. It replaced the users.is_admin column.
+const isAdminExpr = `EXISTS (
+		WITH RECURSIVE admin_groups (group_id) AS (
+			SELECT group_id FROM group_members WHERE user_id = u.user_id
+			UNION
+			SELECT gs.parent_group_id FROM group_subgroups gs JOIN admin_groups ag ON ag.group_id = gs.child_group_id
+		)
+		SELECT 1 FROM roles r
+		WHERE r.app_id IS NULL AND r.name = 'admin' AND (
+			r.role_id IN (SELECT role_id FROM user_app_roles WHERE user_id = u.user_id AND app_id IS NULL)
+			OR r.role_id IN (SELECT role_id FROM group_app_roles
+				WHERE app_id IS NULL AND group_id IN (SELECT group_id FROM admin_groups))))`
+
+// userRolesCTE defines user_groups, the groups user $1 belongs to directly or through
+// subgroups, and user_roles, the roles the user holds in app $2 directly or through those groups.
+// UNION keeps recursion finite even if a cycle slipped into group_subgroups.
+const userRolesCTE = `WITH RECURSIVE user_groups (group_id) AS (
+		SELECT group_id FROM group_members WHERE user_id = $1
+		UNION
+		SELECT gs.parent_group_id FROM group_subgroups gs JOIN user_groups ug ON ug.group_id = gs.child_group_id
+	), user_roles (role_id) AS (
+		SELECT role_id FROM user_app_roles WHERE user_id = $1 AND (app_id IS NULL OR app_id = $2)
+		UNION
+		SELECT role_id FROM group_app_roles
+		WHERE group_id IN (SELECT group_id FROM user_groups) AND (app_id IS NULL OR app_id = $2)
+	)`
+
+// roleColumns are selected from "roles r" by every query scanned with scanRole.
+const roleColumns = `r.role_id, r.app_id, r.name, r.description, r.created_at,
+	ARRAY(SELECT p.name FROM role_permissions rp JOIN permissions p ON p.permission_id = rp.permission_id
+		WHERE rp.role_id = r.role_id ORDER BY p.name)`
+
+func scanRole(row scanner, typeMap *pgtype.Map) (models.Role, error) {
+	var (
+		role  models.Role
+		appID uuid.NullUUID
+	)
+	err := row.Scan(
+		&role.ID, &appID, &role.Name, &role.Description, &role.CreatedAt,
+		typeMap.SQLScanner(&role.Permissions),
+	)
+	role.AppID = appID.UUID
+
+	return role, err
+}
+
+// CreateRole saves a role without permissions. Zero role.AppID creates a global role.
+// Returns storage.ErrRoleExists if the scope already has a role with the same name.
+func (s *Storage) CreateRole(ctx context.Context, role models.Role) (models.Role, error) {
+	const op = "storage.postgres.CreateRole"
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO roles (app_id, name, description) VALUES ($1, $2, $3)
+		RETURNING role_id, created_at`,
+		nullUUID(role.AppID), role.Name, role.Description,
+	).Scan(&role.ID, &role.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.Role{}, fmt.Errorf("%s: %w", op, storage.ErrRoleExists)
+		}
+
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return role, nil
+}
+
+// DeleteRole deletes the role defined in the scope together with its grants and assignments.
+func (s *Storage) DeleteRole(ctx context.Context, appID uuid.UUID, name string) error {
+	const op = "storage.postgres.DeleteRole"
+
+	res, err := s.db.ExecContext(ctx,
+		"DELETE FROM roles WHERE app_id IS NOT DISTINCT FROM $1 AND name = $2",
+		nullUUID(appID), name,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	return nil
+}
+
+// Roles returns roles defined in the scope with their permissions.
+func (s *Storage) Roles(ctx context.Context, appID uuid.UUID) ([]models.Role, error) {
+	const op = "storage.postgres.Roles"
+
+	roles, err := s.queryRoles(ctx,
+		"SELECT "+roleColumns+" FROM roles r WHERE r.app_id IS NOT DISTINCT FROM $1 ORDER BY r.name",
+		nullUUID(appID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+// UserRoles returns roles the user holds in the app, directly or through groups: those assigned
+// within the app and those assigned across all apps. Zero appID returns only the latter.
+func (s *Storage) UserRoles(ctx context.Context, userID string, appID uuid.UUID) ([]models.Role, error) {
+	const op = "storage.postgres.UserRoles"
+
+	roles, err := s.queryRoles(ctx, userRolesCTE+`
+		SELECT `+roleColumns+` FROM roles r
+		WHERE r.role_id IN (SELECT role_id FROM user_roles)
+		ORDER BY r.name`,
+		userID, nullUUID(appID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+func (s *Storage) queryRoles(ctx context.Context, query string, args ...any) ([]models.Role, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	typeMap := pgtype.NewMap()
+
+	var roles []models.Role
+	for rows.Next() {
+		role, err := scanRole(rows, typeMap)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// CreatePermission saves a permission. Zero permission.AppID creates a global permission.
+// Returns storage.ErrPermissionExists if the scope already has a permission with the same name.
+func (s *Storage) CreatePermission(ctx context.Context, permission models.Permission) (models.Permission, error) {
+	const op = "storage.postgres.CreatePermission"
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO permissions (app_id, name, description) VALUES ($1, $2, $3)
+		RETURNING permission_id, created_at`,
+		nullUUID(permission.AppID), permission.Name, permission.Description,
+	).Scan(&permission.ID, &permission.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.Permission{}, fmt.Errorf("%s: %w", op, storage.ErrPermissionExists)
+		}
+
+		return models.Permission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return permission, nil
+}
+
+// DeletePermission deletes the permission defined in the scope and removes it from all roles.
+func (s *Storage) DeletePermission(ctx context.Context, appID uuid.UUID, name string) error {
+	const op = "storage.postgres.DeletePermission"
+
+	res, err := s.db.ExecContext(ctx,
+		"DELETE FROM permissions WHERE app_id IS NOT DISTINCT FROM $1 AND name = $2",
+		nullUUID(appID), name,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrPermissionNotFound)
+	}
+
+	return nil
+}
+
+// Permissions returns permissions defined in the scope.
+func (s *Storage) Permissions(ctx context.Context, appID uuid.UUID) ([]models.Permission, error) {
+	const op = "storage.postgres.Permissions"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT permission_id, app_id, name, description, created_at
+		FROM permissions
+		WHERE app_id IS NOT DISTINCT FROM $1
+		ORDER BY name`,
+		nullUUID(appID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var permissions []models.Permission
+	for rows.Next() {
+		var (
+			p     models.Permission
+			appID uuid.NullUUID
+		)
+		if err := rows.Scan(&p.ID, &appID, &p.Name, &p.Description, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		p.AppID = appID.UUID
+
+		permissions = append(permissions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return permissions, nil
+}
+
+// GrantPermission adds the permission to the role defined in the scope. The permission
+// is looked up in the same scope first and among global permissions second.
+// Granting a permission the role already has is a no-op.
+func (s *Storage) GrantPermission(ctx context.Context, appID uuid.UUID, roleName string, permissionName string) error {
+	const op = "storage.postgres.GrantPermission"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	roleID, permissionID, err := rolePermissionIDs(ctx, tx, appID, roleName, permissionName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		roleID, permissionID,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokePermission removes the permission from the role defined in the scope.
+// Revoking a permission the role does not have is a no-op.
+func (s *Storage) RevokePermission(ctx context.Context, appID uuid.UUID, roleName string, permissionName string) error {
+	const op = "storage.postgres.RevokePermission"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	roleID, permissionID, err := rolePermissionIDs(ctx, tx, appID, roleName, permissionName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2",
+		roleID, permissionID,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func rolePermissionIDs(
+	ctx context.Context,
+	tx *sql.Tx,
+	appID uuid.UUID,
+	roleName string,
+	permissionName string,
+) (uuid.UUID, uuid.UUID, error) {
+	var roleID, permissionID uuid.UUID
+
+	err := tx.QueryRowContext(ctx,
+		"SELECT role_id FROM roles WHERE app_id IS NOT DISTINCT FROM $1 AND name = $2",
+		nullUUID(appID), roleName,
+	).Scan(&roleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, uuid.Nil, storage.ErrRoleNotFound
+		}
+
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT permission_id FROM permissions
+		WHERE name = $2 AND (app_id IS NULL OR app_id = $1)
+		ORDER BY app_id NULLS LAST
+		LIMIT 1`,
+		nullUUID(appID), permissionName,
+	).Scan(&permissionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, uuid.Nil, storage.ErrPermissionNotFound
+		}
+
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	return roleID, permissionID, nil
+}
+
+// AssignRole gives the user the role within the app, or across all apps if appID is zero.
+// The role is looked up among roles of the app first and among global roles second.
+// Assigning a role the user already holds is a no-op.
+func (s *Storage) AssignRole(ctx context.Context, userID string, appID uuid.UUID, roleName string) error {
+	const op = "storage.postgres.AssignRole"
+
+	if err := uuid.Validate(userID); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_app_roles (user_id, app_id, role_id)
+		SELECT $1, $2, role_id FROM roles
+		WHERE name = $3 AND (app_id IS NULL OR app_id = $2)
+		ORDER BY app_id NULLS LAST
+		LIMIT 1
+		ON CONFLICT DO NOTHING`,
+		userID, nullUUID(appID), roleName,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		// Either the role does not exist or it is already assigned.
+		var exists bool
+		if err := s.db.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM roles WHERE name = $2 AND (app_id IS NULL OR app_id = $1))",
+			nullUUID(appID), roleName,
+		).Scan(&exists); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !exists {
+			return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+		}
+	}
+
+	return nil
+}
+
+// UnassignRole takes the role assigned within the app, or across all apps if appID is zero,
+// away from the user. Returns storage.ErrRoleNotFound if the user does not hold it there.
+func (s *Storage) UnassignRole(ctx context.Context, userID string, appID uuid.UUID, roleName string) error {
+	const op = "storage.postgres.UnassignRole"
+
+	if err := uuid.Validate(userID); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM user_app_roles
+		WHERE user_id = $1 AND app_id IS NOT DISTINCT FROM $2 AND role_id = (
+			SELECT role_id FROM roles
+			WHERE name = $3 AND (app_id IS NULL OR app_id = $2)
+			ORDER BY app_id NULLS LAST
+			LIMIT 1)`,
+		userID, nullUUID(appID), roleName,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	return nil
+}
+
+// UserPermissions returns names of permissions granted to the user in the app
+// through roles the user holds there, directly or through groups.
+func (s *Storage) UserPermissions(ctx context.Context, userID string, appID uuid.UUID) ([]string, error) {
+	const op = "storage.postgres.UserPermissions"
+
+	rows, err := s.db.QueryContext(ctx, userRolesCTE+`
+		SELECT DISTINCT p.name
+		FROM user_roles ur
+			JOIN role_permissions rp ON rp.role_id = ur.role_id
+			JOIN permissions p ON p.permission_id = rp.permission_id`,
+		userID, nullUUID(appID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		permissions = append(permissions, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return permissions, nil
+}
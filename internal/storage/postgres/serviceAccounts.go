@@ -0,0 +1,295 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+const serviceAccountColumns = "account_id, name, description, created_at"
+
+const serviceAccountKeyColumns = "key_id, account_id, public_key, algorithm, expires_at, created_at, last_used_at"
+
+func scanServiceAccount(row scanner) (models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := row.Scan(&account.ID, &account.Name, &account.Description, &account.CreatedAt)
+
+	return account, err
+}
+
+func scanServiceAccountKey(row scanner) (models.ServiceAccountKey, error) {
+	var (
+		key        models.ServiceAccountKey
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+	)
+	err := row.Scan(&key.ID, &key.AccountID, &key.PublicKey, &key.Algorithm, &expiresAt, &key.CreatedAt, &lastUsedAt)
+	key.ExpiresAt = expiresAt.Time
+	key.LastUsedAt = lastUsedAt.Time
+
+	return key, err
+}
+
+// CreateServiceAccount saves the service account with the user backing it. The user has
+// no email and an empty password hash, so it can't log in.
+func (s *Storage) CreateServiceAccount(ctx context.Context, account models.ServiceAccount) (models.ServiceAccount, error) {
+	const op = "storage.postgres.CreateServiceAccount"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	if err := tx.QueryRowContext(ctx,
+		"INSERT INTO users (email, pass_hash, kind) VALUES (NULL, '', 'service') RETURNING user_id",
+	).Scan(&id); err != nil {
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, err := scanServiceAccount(tx.QueryRowContext(ctx, `
+		INSERT INTO service_accounts (account_id, name, description) VALUES ($1, $2, $3)
+		RETURNING `+serviceAccountColumns,
+		id, account.Name, account.Description,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, storage.ErrServiceAccountExists)
+		}
+
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// LookupServiceAccount returns service account by id or, if idOrName is not a UUID, by name.
+func (s *Storage) LookupServiceAccount(ctx context.Context, idOrName string) (models.ServiceAccount, error) {
+	const op = "storage.postgres.LookupServiceAccount"
+
+	condition := "name = $1"
+	if uuid.Validate(idOrName) == nil {
+		condition = "account_id = $1"
+	}
+
+	account, err := scanServiceAccount(s.db.QueryRowContext(ctx,
+		"SELECT "+serviceAccountColumns+" FROM service_accounts WHERE "+condition,
+		idOrName,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, storage.ErrServiceAccountNotFound)
+		}
+
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return account, nil
+}
+
+// ServiceAccounts returns all service accounts ordered by name.
+func (s *Storage) ServiceAccounts(ctx context.Context) ([]models.ServiceAccount, error) {
+	const op = "storage.postgres.ServiceAccounts"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+serviceAccountColumns+" FROM service_accounts ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var accounts []models.ServiceAccount
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return accounts, nil
+}
+
+// DeleteServiceAccount deletes the service account with the user backing it, its keys and roles.
+func (s *Storage) DeleteServiceAccount(ctx context.Context, accountID uuid.UUID) error {
+	const op = "storage.postgres.DeleteServiceAccount"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM users WHERE user_id = $1 AND kind = 'service'", accountID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrServiceAccountNotFound)
+	}
+
+	return nil
+}
+
+// AddServiceAccountKey saves a public key of the service account.
+func (s *Storage) AddServiceAccountKey(ctx context.Context, key models.ServiceAccountKey) (models.ServiceAccountKey, error) {
+	const op = "storage.postgres.AddServiceAccountKey"
+
+	var expiresAt sql.NullTime
+	if !key.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: key.ExpiresAt, Valid: true}
+	}
+
+	created, err := scanServiceAccountKey(s.db.QueryRowContext(ctx, `
+		INSERT INTO service_account_keys (account_id, public_key, algorithm, expires_at) VALUES ($1, $2, $3, $4)
+		RETURNING `+serviceAccountKeyColumns,
+		key.AccountID, key.PublicKey, key.Algorithm, expiresAt,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.ServiceAccountKey{}, fmt.Errorf("%s: %w", op, storage.ErrServiceAccountNotFound)
+		}
+
+		return models.ServiceAccountKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// ServiceAccountKeys returns keys of the service account, newest first, including expired ones.
+func (s *Storage) ServiceAccountKeys(ctx context.Context, accountID uuid.UUID) ([]models.ServiceAccountKey, error) {
+	const op = "storage.postgres.ServiceAccountKeys"
+
+	keys, err := s.serviceAccountKeys(ctx,
+		"SELECT "+serviceAccountKeyColumns+" FROM service_account_keys WHERE account_id = $1 ORDER BY created_at DESC",
+		accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// ActiveServiceAccountKeys returns unexpired keys of the service account.
+func (s *Storage) ActiveServiceAccountKeys(ctx context.Context, accountID uuid.UUID, now time.Time) ([]models.ServiceAccountKey, error) {
+	const op = "storage.postgres.ActiveServiceAccountKeys"
+
+	keys, err := s.serviceAccountKeys(ctx, `
+		SELECT `+serviceAccountKeyColumns+` FROM service_account_keys
+		WHERE account_id = $1 AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY created_at DESC`,
+		accountID, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) serviceAccountKeys(ctx context.Context, query string, args ...any) ([]models.ServiceAccountKey, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.ServiceAccountKey
+	for rows.Next() {
+		key, err := scanServiceAccountKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// DeleteServiceAccountKey deletes the key of the service account.
+func (s *Storage) DeleteServiceAccountKey(ctx context.Context, accountID uuid.UUID, keyID uuid.UUID) error {
+	const op = "storage.postgres.DeleteServiceAccountKey"
+
+	res, err := s.db.ExecContext(ctx,
+		"DELETE FROM service_account_keys WHERE account_id = $1 AND key_id = $2",
+		accountID, keyID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrServiceAccountKeyNotFound)
+	}
+
+	return nil
+}
+
+// SaveAssertion records the ID of an assertion verified with the key, and marks the key as used.
+// Returns storage.ErrAssertionReplayed if the account already used an assertion with the ID.
+// IDs of expired assertions are forgotten: those assertions are rejected anyway.
+func (s *Storage) SaveAssertion(
+	ctx context.Context,
+	accountID uuid.UUID,
+	keyID uuid.UUID,
+	jti string,
+	expiresAt time.Time,
+	now time.Time,
+) error {
+	const op = "storage.postgres.SaveAssertion"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM service_account_assertions WHERE expires_at <= $1", now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO service_account_assertions (account_id, jti, expires_at) VALUES ($1, $2, $3)",
+		accountID, jti, expiresAt,
+	); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%s: %w", op, storage.ErrAssertionReplayed)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE service_account_keys SET last_used_at = $3 WHERE account_id = $1 AND key_id = $2",
+		accountID, keyID, now,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// ChangeUserStatus moves user from change.From to change.To status and records the audit event
+// in the same transaction. Returns storage.ErrStatusConflict if user is no longer in change.From.
+// If revokeSessions is set, refresh tokens issued so far stop being accepted.
+func (s *Storage) ChangeUserStatus(
+	ctx context.Context,
+	change models.UserStatusChange,
+	revokeSessions bool,
+	event models.AuditEvent,
+) error {
+	const op = "storage.postgres.ChangeUserStatus"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var until sql.NullTime
+	if !change.Until.IsZero() {
+		until = sql.NullTime{Time: change.Until, Valid: true}
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET status              = $3,
+		    status_reason       = $4,
+		    suspended_until     = $5,
+		    sessions_revoked_at = CASE WHEN $6 THEN now() ELSE sessions_revoked_at END
+		WHERE user_id = $1 AND status = $2`,
+		change.UserID, change.From, change.To, change.Reason, until, revokeSessions,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrStatusConflict)
+	}
+
+	if err := saveAuditEvent(ctx, tx, event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveAuditEvent records a security-relevant action.
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	const op = "storage.postgres.SaveAuditEvent"
+
+	if err := saveAuditEvent(ctx, s.db, event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func saveAuditEvent(ctx context.Context, db execer, event models.AuditEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return err
+	}
+	if event.Details == nil {
+		details = []byte("{}")
+	}
+
+	_, err = db.ExecContext(ctx,
+		"INSERT INTO audit_events (user_id, actor_id, event, details) VALUES ($1, $2, $3, $4)",
+		nullUUID(event.UserID), nullUUID(event.ActorID), event.Event, details,
+	)
+
+	return err
+}
+
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
@@ -0,0 +1,274 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// userColumns are selected from "users u" by every query scanned with scanUser.
+// They leave out isAdminExpr, which only ListUsers and ExportUsers pay for.
+const userColumns = `u.user_id, COALESCE(u.email, ''), COALESCE(u.username, ''), COALESCE(u.phone, ''), u.pass_hash,
+	u.status, u.status_reason, u.suspended_until, u.sessions_revoked_at,
+	u.created_at, u.updated_at, u.last_login_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser scans userColumns; extra receives columns selected after them.
+func scanUser(row scanner, extra ...any) (models.User, error) {
+	var (
+		user              models.User
+		suspendedUntil    sql.NullTime
+		sessionsRevokedAt sql.NullTime
+		lastLoginAt       sql.NullTime
+	)
+	dest := []any{
+		&user.ID, &user.Email, &user.Username, &user.Phone, &user.PassHash,
+		&user.Status, &user.StatusReason, &suspendedUntil, &sessionsRevokedAt,
+		&user.CreatedAt, &user.UpdatedAt, &lastLoginAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return models.User{}, err
+	}
+	user.SuspendedUntil = suspendedUntil.Time
+	user.SessionsRevokedAt = sessionsRevokedAt.Time
+	user.LastLoginAt = lastLoginAt.Time
+
+	return user, nil
+}
+
+// RecordLogin updates last login time of the user and their membership in the app.
+func (s *Storage) RecordLogin(ctx context.Context, userID string, appID string) error {
+	const op = "storage.postgres.RecordLogin"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET last_login_at = now() WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO app_members (app_id, user_id, last_login_at) VALUES ($1, $2, now())
+		ON CONFLICT (app_id, user_id) DO UPDATE SET last_login_at = excluded.last_login_at`,
+		appID, userID,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// sortKeys maps sort fields to expressions backed by the keyset indexes.
+var sortKeys = map[models.UserSortField]struct {
+	expr string
+	cast string
+}{
+	models.UserSortCreatedAt:   {expr: "u.created_at", cast: "timestamptz"},
+	models.UserSortLastLoginAt: {expr: "COALESCE(u.last_login_at, '1970-01-01 00:00:00+00'::timestamptz)", cast: "timestamptz"},
+	models.UserSortEmail:       {expr: "lower(u.email)", cast: "text"},
+}
+
+// roleHoldersQuery selects users holding the role named %[1]s directly or through groups.
+// %[2]s, %[3]s and %[4]s scope the role, group assignments and user assignments to apps.
+// It walks down from the groups holding the role, so unlike isAdminExpr it is evaluated
+// once per query instead of once per user.
+const roleHoldersQuery = `WITH RECURSIVE matched_roles (role_id) AS (
+		SELECT r.role_id FROM roles r WHERE r.name = %[1]s AND %[2]s
+	), role_groups (group_id) AS (
+		SELECT gar.group_id FROM group_app_roles gar JOIN matched_roles mr ON mr.role_id = gar.role_id WHERE %[3]s
+		UNION
+		SELECT gs.child_group_id FROM group_subgroups gs JOIN role_groups rg ON rg.group_id = gs.parent_group_id
+	)
+	SELECT uar.user_id FROM user_app_roles uar JOIN matched_roles mr ON mr.role_id = uar.role_id WHERE %[4]s
+	UNION
+	SELECT gm.user_id FROM group_members gm JOIN role_groups rg ON rg.group_id = gm.group_id`
+
+// userCursor points at the last user of a page.
+type userCursor struct {
+	SortBy models.UserSortField `json:"s"`
+	Key    string               `json:"k"`
+	ID     uuid.UUID            `json:"id"`
+}
+
+// RevokeSessions invalidates refresh tokens of the user issued so far.
+func (s *Storage) RevokeSessions(ctx context.Context, userID uuid.UUID) error {
+	const op = "storage.postgres.RevokeSessions"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET sessions_revoked_at = now() WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// ListUsers returns a page of users matching the filter and a cursor of the next page.
+// The cursor is empty on the last page.
+func (s *Storage) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, string, error) {
+	const op = "storage.postgres.ListUsers"
+
+	sortKey, ok := sortKeys[filter.SortBy]
+	if !ok {
+		return nil, "", fmt.Errorf("%s: unknown sort field %q", op, filter.SortBy)
+	}
+
+	var (
+		// Service accounts are listed on their own.
+		conds = []string{"u.kind = 'user'"}
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.EmailPrefix != "" {
+		conds = append(conds, "lower(u.email) LIKE "+arg(escapeLike(strings.ToLower(filter.EmailPrefix))+"%"))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, st := range filter.Statuses {
+			statuses = append(statuses, string(st))
+		}
+		conds = append(conds, "u.status = ANY("+arg(statuses)+")")
+	}
+	if filter.Role != "" {
+		// Global roles and assignments apply to every app.
+		inScope := func(column string) string { return column + " IS NULL" }
+		if filter.AppID != uuid.Nil {
+			app := arg(filter.AppID)
+			inScope = func(column string) string { return "(" + column + " IS NULL OR " + column + " = " + app + ")" }
+		}
+		holders := fmt.Sprintf(roleHoldersQuery, arg(filter.Role), inScope("r.app_id"), inScope("gar.app_id"), inScope("uar.app_id"))
+		conds = append(conds, "u.user_id IN ("+holders+")")
+	}
+	if filter.AppID != uuid.Nil {
+		conds = append(conds, "EXISTS (SELECT 1 FROM app_members m WHERE m.user_id = u.user_id AND m.app_id = "+arg(filter.AppID)+")")
+	}
+	if !filter.CreatedFrom.IsZero() {
+		conds = append(conds, "u.created_at >= "+arg(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		conds = append(conds, "u.created_at < "+arg(filter.CreatedTo))
+	}
+	if !filter.LastLoginFrom.IsZero() {
+		conds = append(conds, "u.last_login_at >= "+arg(filter.LastLoginFrom))
+	}
+	if !filter.LastLoginTo.IsZero() {
+		conds = append(conds, "u.last_login_at < "+arg(filter.LastLoginTo))
+	}
+
+	cmp, order := ">", "ASC"
+	if filter.Descending {
+		cmp, order = "<", "DESC"
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeUserCursor(filter.Cursor)
+		if err != nil || cursor.SortBy != filter.SortBy {
+			return nil, "", fmt.Errorf("%s: %w", op, storage.ErrInvalidCursor)
+		}
+		conds = append(conds, fmt.Sprintf("(%s, u.user_id) %s (%s::%s, %s)",
+			sortKey.expr, cmp, arg(cursor.Key), sortKey.cast, arg(cursor.ID)))
+	}
+
+	query := "SELECT " + userColumns + ", " + isAdminExpr + ", " + sortKey.expr + "::text FROM users u"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	// One extra row tells whether there is a next page.
+	query += fmt.Sprintf(" ORDER BY %s %s, u.user_id %s LIMIT %s", sortKey.expr, order, order, arg(filter.Limit+1))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var (
+		users   []models.User
+		lastKey string
+	)
+	for rows.Next() {
+		var (
+			isAdmin bool
+			key     string
+		)
+		user, err := scanUser(rows, &isAdmin, &key)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+		user.IsAdmin = isAdmin
+		if len(users) == filter.Limit {
+			next, err := encodeUserCursor(userCursor{SortBy: filter.SortBy, Key: lastKey, ID: users[len(users)-1].ID})
+			if err != nil {
+				return nil, "", fmt.Errorf("%s: %w", op, err)
+			}
+
+			return users, next, nil
+		}
+		users = append(users, user)
+		lastKey = key
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, "", nil
+}
+
+func encodeUserCursor(c userCursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeUserCursor(s string) (userCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return userCursor{}, err
+	}
+
+	var c userCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return userCursor{}, err
+	}
+	if c.ID == uuid.Nil {
+		return userCursor{}, errors.New("cursor without id")
+	}
+
+	return c, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListUsers_Role(t *testing.T) {
+	s := newTestStorage(t)
+
+	roleName := "test-" + uuid.NewString()[:8]
+	roleID := uuid.New()
+	exec(t, s, "INSERT INTO roles (role_id, name) VALUES ($1, $2)", roleID, roleName)
+	t.Cleanup(func() { _, _ = s.db.Exec("DELETE FROM roles WHERE role_id = $1", roleID) })
+
+	appID := uuid.New()
+	exec(t, s, "INSERT INTO apps (app_id, name) VALUES ($1, $2)", appID, "test-"+appID.String())
+	t.Cleanup(func() { _, _ = s.db.Exec("DELETE FROM apps WHERE app_id = $1", appID) })
+
+	parentID, childID := uuid.New(), uuid.New()
+	exec(t, s, "INSERT INTO groups (group_id, name) VALUES ($1, $2), ($3, $4)",
+		parentID, "test-"+parentID.String(), childID, "test-"+childID.String())
+	t.Cleanup(func() { _, _ = s.db.Exec("DELETE FROM groups WHERE group_id IN ($1, $2)", parentID, childID) })
+	exec(t, s, "INSERT INTO group_subgroups (parent_group_id, child_group_id) VALUES ($1, $2)", parentID, childID)
+	exec(t, s, "INSERT INTO group_app_roles (group_id, role_id) VALUES ($1, $2)", parentID, roleID)
+
+	direct := addTestUser(t, s)
+	exec(t, s, "INSERT INTO user_app_roles (user_id, role_id) VALUES ($1, $2)", direct, roleID)
+
+	inherited := addTestUser(t, s)
+	exec(t, s, "INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)", childID, inherited)
+
+	inApp := addTestUser(t, s)
+	exec(t, s, "INSERT INTO user_app_roles (user_id, app_id, role_id) VALUES ($1, $2, $3)", inApp, appID, roleID)
+
+	_ = addTestUser(t, s)
+
+	// Filtering by app lists only members of the app.
+	for _, id := range []uuid.UUID{direct, inherited, inApp} {
+		exec(t, s, "INSERT INTO app_members (app_id, user_id) VALUES ($1, $2)", appID, id)
+	}
+
+	list := func(filter models.UserFilter) []uuid.UUID {
+		filter.SortBy = models.UserSortCreatedAt
+		filter.Limit = 10
+
+		users, _, err := s.ListUsers(context.Background(), filter)
+		require.NoError(t, err)
+
+		ids := make([]uuid.UUID, 0, len(users))
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+
+		return ids
+	}
+
+	assert.ElementsMatch(t, []uuid.UUID{direct, inherited}, list(models.UserFilter{Role: roleName}))
+	assert.ElementsMatch(t, []uuid.UUID{direct, inherited, inApp}, list(models.UserFilter{Role: roleName, AppID: appID}))
+}
+
+func TestUpdatedAt_IgnoresLogins(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	appID := uuid.New()
+	exec(t, s, "INSERT INTO apps (app_id, name) VALUES ($1, $2)", appID, "test-"+appID.String())
+	t.Cleanup(func() { _, _ = s.db.Exec("DELETE FROM apps WHERE app_id = $1", appID) })
+
+	userID := addTestUser(t, s)
+	exec(t, s, "UPDATE users SET updated_at = '2000-01-01' WHERE user_id = $1", userID)
+
+	updatedAt := func() time.Time {
+		user, err := s.User(ctx, models.Identifier{Kind: models.IdentifierID, Value: userID.String()})
+		require.NoError(t, err)
+
+		return user.UpdatedAt
+	}
+	before := updatedAt()
+
+	require.NoError(t, s.RecordLogin(ctx, userID.String(), appID.String()))
+	assert.True(t, before.Equal(updatedAt()), "login changed updated_at")
+
+	require.NoError(t, s.SetUsername(ctx, userID.String(), "test-"+userID.String()[:8]))
+	assert.True(t, updatedAt().After(before), "username change kept updated_at")
+}
@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sol1corejz/auth-service/internal/domain/models"
+	"github.com/sol1corejz/auth-service/internal/storage"
+)
+
+// SetAppWebAuthn sets relying party ID and allowed origins of the app. Empty rpID disables passkeys.
+func (s *Storage) SetAppWebAuthn(ctx context.Context, appName string, rpID string, origins []string) error {
+	const op = "storage.postgres.SetAppWebAuthn"
+
+	if origins == nil {
+		origins = []string{}
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE apps SET webauthn_rp_id = NULLIF($2, ''), webauthn_origins = $3 WHERE name = $1",
+		appName, rpID, origins,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	return nil
+}
+
+// SavePasskeyCredential saves a newly registered credential.
+// Returns storage.ErrPasskeyExists if a credential with the same ID is already registered.
+func (s *Storage) SavePasskeyCredential(ctx context.Context, credential models.PasskeyCredential) error {
+	const op = "storage.postgres.SavePasskeyCredential"
+
+	transports := credential.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webauthn_credentials (
+			credential_id, user_id, rp_id, public_key, attestation_type, aaguid,
+			sign_count, transports, backup_eligible, backup_state, name
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		credential.ID, credential.UserID, credential.RPID, credential.PublicKey, credential.AttestationType,
+		credential.AAGUID, int64(credential.SignCount), transports,
+		credential.BackupEligible, credential.BackupState, credential.Name,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%s: %w", op, storage.ErrPasskeyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PasskeyCredentials returns credentials the user registered for the relying party.
+func (s *Storage) PasskeyCredentials(ctx context.Context, userID string, rpID string) ([]models.PasskeyCredential, error) {
+	const op = "storage.postgres.PasskeyCredentials"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT credential_id, user_id, rp_id, public_key, attestation_type, aaguid, sign_count,
+			transports, backup_eligible, backup_state, name, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE user_id = $1 AND rp_id = $2
+		ORDER BY created_at`,
+		userID, rpID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	typeMap := pgtype.NewMap()
+
+	var credentials []models.PasskeyCredential
+	for rows.Next() {
+		var (
+			c          models.PasskeyCredential
+			signCount  int64
+			lastUsedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&c.ID, &c.UserID, &c.RPID, &c.PublicKey, &c.AttestationType, &c.AAGUID, &signCount,
+			typeMap.SQLScanner(&c.Transports), &c.BackupEligible, &c.BackupState, &c.Name,
+			&c.CreatedAt, &lastUsedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		c.SignCount = uint32(signCount)
+		c.LastUsedAt = lastUsedAt.Time
+
+		credentials = append(credentials, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return credentials, nil
+}
+
+// UsePasskeyCredential records a successful assertion with the credential.
+func (s *Storage) UsePasskeyCredential(ctx context.Context, credentialID []byte, signCount uint32, backupState bool) error {
+	const op = "storage.postgres.UsePasskeyCredential"
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE webauthn_credentials
+		SET sign_count = $2, backup_state = $3, last_used_at = now()
+		WHERE credential_id = $1`,
+		credentialID, int64(signCount), backupState,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrPasskeyNotFound)
+	}
+
+	return nil
+}
+
+// SavePasskeySession saves state of a started ceremony and returns its ID.
+func (s *Storage) SavePasskeySession(ctx context.Context, session models.PasskeySession) (string, error) {
+	const op = "storage.postgres.SavePasskeySession"
+
+	var id uuid.UUID
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO webauthn_sessions (ceremony, user_id, app_id, data, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING session_id`,
+		session.Ceremony, nullUUID(session.UserID), session.AppID, session.Data, session.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id.String(), nil
+}
+
+// ConsumePasskeySession marks unexpired session of the ceremony as consumed and returns it,
+// so each session finishes at most one ceremony.
+func (s *Storage) ConsumePasskeySession(
+	ctx context.Context,
+	sessionID string,
+	ceremony models.PasskeyCeremony,
+) (models.PasskeySession, error) {
+	const op = "storage.postgres.ConsumePasskeySession"
+
+	if err := uuid.Validate(sessionID); err != nil {
+		return models.PasskeySession{}, fmt.Errorf("%s: %w", op, storage.ErrPasskeySessionNotFound)
+	}
+
+	var (
+		session models.PasskeySession
+		userID  uuid.NullUUID
+	)
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE webauthn_sessions SET consumed_at = now()
+		WHERE session_id = $1 AND ceremony = $2 AND consumed_at IS NULL AND expires_at > now()
+		RETURNING session_id, ceremony, user_id, app_id, data, expires_at`,
+		sessionID, ceremony,
+	).Scan(&session.ID, &session.Ceremony, &userID, &session.AppID, &session.Data, &session.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PasskeySession{}, fmt.Errorf("%s: %w", op, storage.ErrPasskeySessionNotFound)
+		}
+
+		return models.PasskeySession{}, fmt.Errorf("%s: %w", op, err)
+	}
+	session.UserID = userID.UUID
+
+	return session, nil
+}
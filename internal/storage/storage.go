@@ -6,4 +6,53 @@ var (
 	ErrUserExists   = errors.New("user already exists")
 	ErrUserNotFound = errors.New("user not found")
 	ErrAppNotFound  = errors.New("app not found")
+	ErrAppExists    = errors.New("app already exists")
+
+	ErrAppMemberExists   = errors.New("user is already a member of the app")
+	ErrAppMemberNotFound = errors.New("app member not found")
+
+	ErrClientSecretNotFound = errors.New("client secret not found")
+	ErrAuthCodeNotFound     = errors.New("authorization code not found")
+	ErrDeviceCodeNotFound   = errors.New("device code not found")
+	ErrUserCodeTaken        = errors.New("user code already taken")
+
+	ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+
+	ErrServiceAccountExists      = errors.New("service account already exists")
+	ErrServiceAccountNotFound    = errors.New("service account not found")
+	ErrServiceAccountKeyNotFound = errors.New("service account key not found")
+	ErrAssertionReplayed         = errors.New("assertion already used")
+
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrPhoneTaken    = errors.New("phone already taken")
+	ErrCodeNotFound  = errors.New("code not found")
+
+	ErrStatusConflict = errors.New("user status changed concurrently")
+	ErrInvalidCursor  = errors.New("invalid cursor")
+
+	ErrTOTPNotFound      = errors.New("totp not enrolled")
+	ErrTOTPExists        = errors.New("totp already enrolled")
+	ErrTOTPStepUsed      = errors.New("totp code already used")
+	ErrTOTPLocked        = errors.New("too many totp attempts")
+	ErrChallengeNotFound = errors.New("mfa challenge not found")
+
+	ErrPasskeyExists          = errors.New("passkey already registered")
+	ErrPasskeyNotFound        = errors.New("passkey not found")
+	ErrPasskeySessionNotFound = errors.New("passkey session not found")
+
+	ErrRoleExists         = errors.New("role already exists")
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrPermissionExists   = errors.New("permission already exists")
+	ErrPermissionNotFound = errors.New("permission not found")
+
+	ErrGroupExists        = errors.New("group already exists")
+	ErrGroupNotFound      = errors.New("group not found")
+	ErrGroupCycle         = errors.New("group membership cycle")
+	ErrMembershipNotFound = errors.New("group membership not found")
+
+	ErrOrgExists         = errors.New("organization already exists")
+	ErrOrgNotFound       = errors.New("organization not found")
+	ErrOrgMemberExists   = errors.New("user is already a member of the organization")
+	ErrOrgMemberNotFound = errors.New("organization member not found")
+	ErrLastOrgAdmin      = errors.New("organization must keep an admin")
 )
@@ -0,0 +1,25 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS mfa_policy TEXT NOT NULL DEFAULT 'optional'
+        CHECK (mfa_policy IN ('optional', 'admins', 'all'));
+
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id          UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    secret_encrypted BYTEA       NOT NULL,
+    confirmed_at     TIMESTAMPTZ,
+    last_used_step   BIGINT      NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges
+(
+    challenge_id        UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    user_id             UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    app_id              UUID        NOT NULL REFERENCES apps (app_id) ON DELETE CASCADE,
+    enrollment_required BOOLEAN     NOT NULL DEFAULT FALSE,
+    first_factor        TEXT        NOT NULL DEFAULT 'pwd',
+    attempts            INT         NOT NULL DEFAULT 0,
+    expires_at          TIMESTAMPTZ NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    consumed_at         TIMESTAMPTZ
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes
+(
+    user_id    UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    code_hash  TEXT        NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, code_hash)
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS webauthn_sessions;
+DROP TABLE IF EXISTS webauthn_credentials;
+
+ALTER TABLE apps
+    DROP COLUMN IF EXISTS webauthn_origins,
+    DROP COLUMN IF EXISTS webauthn_rp_id;
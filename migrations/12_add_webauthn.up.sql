@@ -0,0 +1,34 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS webauthn_rp_id   TEXT,
+    ADD COLUMN IF NOT EXISTS webauthn_origins TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS webauthn_credentials
+(
+    credential_id    BYTEA PRIMARY KEY,
+    user_id          UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    rp_id            TEXT        NOT NULL,
+    public_key       BYTEA       NOT NULL,
+    attestation_type TEXT        NOT NULL,
+    aaguid           BYTEA,
+    sign_count       BIGINT      NOT NULL DEFAULT 0,
+    transports       TEXT[]      NOT NULL DEFAULT '{}',
+    backup_eligible  BOOLEAN     NOT NULL DEFAULT FALSE,
+    backup_state     BOOLEAN     NOT NULL DEFAULT FALSE,
+    name             TEXT        NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials (user_id, rp_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_sessions
+(
+    session_id  UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ceremony    TEXT        NOT NULL CHECK (ceremony IN ('registration', 'login')),
+    user_id     UUID REFERENCES users (user_id) ON DELETE CASCADE,
+    app_id      UUID        NOT NULL REFERENCES apps (app_id) ON DELETE CASCADE,
+    data        JSONB       NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    consumed_at TIMESTAMPTZ
+);
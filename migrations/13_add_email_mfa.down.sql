@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mfa_email_codes;
+DROP TABLE IF EXISTS user_email_mfa;
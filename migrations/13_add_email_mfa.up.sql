@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS user_email_mfa
+(
+    user_id    UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    enabled_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_email_codes
+(
+    code_id      UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    user_id      UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    challenge_id UUID REFERENCES mfa_challenges (challenge_id) ON DELETE CASCADE,
+    code_hash    TEXT        NOT NULL,
+    attempts     INT         NOT NULL DEFAULT 0,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    consumed_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_mfa_email_codes_user_created ON mfa_email_codes (user_id, created_at DESC);
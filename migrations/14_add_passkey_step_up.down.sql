@@ -0,0 +1,5 @@
+DELETE FROM webauthn_sessions WHERE ceremony = 'step_up';
+
+ALTER TABLE webauthn_sessions
+    DROP CONSTRAINT IF EXISTS webauthn_sessions_ceremony_check,
+    ADD CONSTRAINT webauthn_sessions_ceremony_check CHECK (ceremony IN ('registration', 'login'));
@@ -0,0 +1,3 @@
+ALTER TABLE webauthn_sessions
+    DROP CONSTRAINT IF EXISTS webauthn_sessions_ceremony_check,
+    ADD CONSTRAINT webauthn_sessions_ceremony_check CHECK (ceremony IN ('registration', 'login', 'step_up'));
@@ -0,0 +1,17 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users u
+SET is_admin = TRUE
+WHERE EXISTS (SELECT 1
+              FROM user_app_roles ur
+                       JOIN roles r ON r.role_id = ur.role_id
+              WHERE ur.user_id = u.user_id
+                AND ur.app_id IS NULL
+                AND r.app_id IS NULL
+                AND r.name = 'admin');
+
+DROP TABLE IF EXISTS user_app_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS permissions;
@@ -0,0 +1,51 @@
+-- Rows with NULL app_id are global: they apply to every app.
+CREATE TABLE IF NOT EXISTS permissions
+(
+    permission_id UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    app_id        UUID REFERENCES apps (app_id) ON DELETE CASCADE,
+    name          TEXT        NOT NULL,
+    description   TEXT        NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (app_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS roles
+(
+    role_id     UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    app_id      UUID REFERENCES apps (app_id) ON DELETE CASCADE,
+    name        TEXT        NOT NULL,
+    description TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (app_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role_id       UUID NOT NULL REFERENCES roles (role_id) ON DELETE CASCADE,
+    permission_id UUID NOT NULL REFERENCES permissions (permission_id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_app_roles
+(
+    user_id    UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    app_id     UUID REFERENCES apps (app_id) ON DELETE CASCADE,
+    role_id    UUID        NOT NULL REFERENCES roles (role_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (user_id, app_id, role_id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_app_roles_role ON user_app_roles (role_id);
+
+INSERT INTO roles (name, description)
+VALUES ('admin', 'Administrator of the SSO service')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO user_app_roles (user_id, role_id)
+SELECT u.user_id, r.role_id
+FROM users u
+         JOIN roles r ON r.app_id IS NULL AND r.name = 'admin'
+WHERE u.is_admin
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS is_admin;
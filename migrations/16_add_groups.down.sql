@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS group_app_roles;
+DROP TABLE IF EXISTS group_subgroups;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS groups
+(
+    group_id    UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    name        TEXT        NOT NULL UNIQUE,
+    description TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS group_members
+(
+    group_id   UUID        NOT NULL REFERENCES groups (group_id) ON DELETE CASCADE,
+    user_id    UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_group_members_user ON group_members (user_id);
+
+-- Members of the child group are members of the parent group too.
+CREATE TABLE IF NOT EXISTS group_subgroups
+(
+    parent_group_id UUID        NOT NULL REFERENCES groups (group_id) ON DELETE CASCADE,
+    child_group_id  UUID        NOT NULL REFERENCES groups (group_id) ON DELETE CASCADE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (parent_group_id, child_group_id),
+    CHECK (parent_group_id <> child_group_id)
+);
+CREATE INDEX IF NOT EXISTS idx_group_subgroups_child ON group_subgroups (child_group_id);
+
+-- Same as user_app_roles: NULL app_id grants the role across all apps.
+CREATE TABLE IF NOT EXISTS group_app_roles
+(
+    group_id   UUID        NOT NULL REFERENCES groups (group_id) ON DELETE CASCADE,
+    app_id     UUID REFERENCES apps (app_id) ON DELETE CASCADE,
+    role_id    UUID        NOT NULL REFERENCES roles (role_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (group_id, app_id, role_id)
+);
+CREATE INDEX IF NOT EXISTS idx_group_app_roles_role ON group_app_roles (role_id);
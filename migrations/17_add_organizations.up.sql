@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS organizations
+(
+    org_id     UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    name       TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS org_members
+(
+    org_id      UUID        NOT NULL REFERENCES organizations (org_id) ON DELETE CASCADE,
+    user_id     UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    role        TEXT        NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- NULL while the user has not accepted the invitation.
+    accepted_at TIMESTAMPTZ,
+    PRIMARY KEY (org_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_org_members_user ON org_members (user_id);
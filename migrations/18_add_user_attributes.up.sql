@@ -0,0 +1,3 @@
+-- Free-form attributes of users evaluated by authorization policies, e.g. {"region": "eu"}.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
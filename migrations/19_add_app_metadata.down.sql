@@ -0,0 +1,5 @@
+ALTER TABLE apps
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS enabled,
+    DROP COLUMN IF EXISTS owner_id,
+    DROP COLUMN IF EXISTS description;
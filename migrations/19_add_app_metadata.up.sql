@@ -0,0 +1,5 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS description TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS owner_id    UUID REFERENCES users (user_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS enabled     BOOLEAN     NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS created_at  TIMESTAMPTZ NOT NULL DEFAULT now();
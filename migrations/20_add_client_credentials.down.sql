@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS client_secrets;
+
+ALTER TABLE apps
+    DROP COLUMN IF EXISTS client_scopes;
@@ -0,0 +1,17 @@
+-- Apps act as OAuth clients: the client ID is the app ID.
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS client_scopes TEXT[] NOT NULL DEFAULT '{}';
+
+-- Several secrets per app let them be rotated without downtime.
+CREATE TABLE IF NOT EXISTS client_secrets
+(
+    secret_id    UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    app_id       UUID        NOT NULL REFERENCES apps (app_id) ON DELETE CASCADE,
+    secret_hash  TEXT        NOT NULL UNIQUE,
+    name         TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_client_secrets_app ON client_secrets (app_id);
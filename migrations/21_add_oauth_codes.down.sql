@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS oauth_codes;
+
+ALTER TABLE apps
+    DROP COLUMN IF EXISTS redirect_uris;
@@ -0,0 +1,20 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS redirect_uris TEXT[] NOT NULL DEFAULT '{}';
+
+-- Authorization codes are single-use: consumed_at is set by the exchange.
+CREATE TABLE IF NOT EXISTS oauth_codes
+(
+    code_hash      TEXT PRIMARY KEY,
+    app_id         UUID        NOT NULL REFERENCES apps (app_id) ON DELETE CASCADE,
+    user_id        UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    redirect_uri   TEXT        NOT NULL,
+    code_challenge TEXT        NOT NULL,
+    scopes         TEXT[]      NOT NULL DEFAULT '{}',
+    auth_methods   TEXT[]      NOT NULL DEFAULT '{}',
+    auth_time      TIMESTAMPTZ NOT NULL,
+    expires_at     TIMESTAMPTZ NOT NULL,
+    consumed_at    TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_codes_expires_at ON oauth_codes (expires_at);
@@ -0,0 +1,5 @@
+ALTER TABLE oauth_codes
+    DROP COLUMN IF EXISTS nonce;
+
+ALTER TABLE apps
+    DROP COLUMN IF EXISTS post_logout_redirect_uris;
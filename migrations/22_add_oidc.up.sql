@@ -0,0 +1,5 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS post_logout_redirect_uris TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE oauth_codes
+    ADD COLUMN IF NOT EXISTS nonce TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,18 @@
+-- Device authorizations of RFC 8628. The user code is short, so it is unique only among live codes.
+CREATE TABLE IF NOT EXISTS device_codes
+(
+    device_code_hash TEXT PRIMARY KEY,
+    user_code        TEXT        NOT NULL,
+    app_id           UUID        NOT NULL REFERENCES apps (app_id) ON DELETE CASCADE,
+    scopes           TEXT[]      NOT NULL DEFAULT '{}',
+    status           TEXT        NOT NULL DEFAULT 'pending',
+    user_id          UUID REFERENCES users (user_id) ON DELETE CASCADE,
+    auth_methods     TEXT[]      NOT NULL DEFAULT '{}',
+    auth_time        TIMESTAMPTZ,
+    last_polled_at   TIMESTAMPTZ,
+    expires_at       TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_codes_pending_user_code ON device_codes (user_code) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_device_codes_expires_at ON device_codes (expires_at);
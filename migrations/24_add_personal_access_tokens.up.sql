@@ -0,0 +1,14 @@
+-- Long-lived tokens users create for scripts, separate from their sessions. Only hashes are stored.
+CREATE TABLE IF NOT EXISTS personal_access_tokens
+(
+    token_id     UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    user_id      UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    token_hash   TEXT        NOT NULL UNIQUE,
+    name         TEXT        NOT NULL,
+    scopes       TEXT[]      NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user ON personal_access_tokens (user_id);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS service_account_assertions;
+DROP TABLE IF EXISTS service_account_keys;
+DROP TABLE IF EXISTS service_accounts;
+
+DELETE FROM users WHERE kind = 'service';
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_email_required,
+    ALTER COLUMN email SET NOT NULL,
+    DROP COLUMN IF EXISTS kind;
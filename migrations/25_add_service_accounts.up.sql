@@ -0,0 +1,39 @@
+-- A service account is backed by a user without email or password, so that it takes part
+-- in roles, groups and statuses like any user. It authenticates with JWT assertions (RFC 7523)
+-- signed by keys registered here.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'user' CHECK (kind IN ('user', 'service')),
+    ALTER COLUMN email DROP NOT NULL,
+    ADD CONSTRAINT users_email_required CHECK (kind = 'service' OR email IS NOT NULL);
+
+CREATE TABLE IF NOT EXISTS service_accounts
+(
+    account_id  UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    name        TEXT        NOT NULL UNIQUE,
+    description TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS service_account_keys
+(
+    key_id       UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    account_id   UUID        NOT NULL REFERENCES service_accounts (account_id) ON DELETE CASCADE,
+    public_key   BYTEA       NOT NULL,
+    algorithm    TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_account_keys_account ON service_account_keys (account_id);
+
+-- IDs of accepted assertions, kept until the assertions expire to reject replays.
+CREATE TABLE IF NOT EXISTS service_account_assertions
+(
+    account_id UUID        NOT NULL REFERENCES service_accounts (account_id) ON DELETE CASCADE,
+    jti        TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (account_id, jti)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_account_assertions_expires_at ON service_account_assertions (expires_at);
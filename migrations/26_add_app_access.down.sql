@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS app_memberships;
+
+ALTER TABLE apps
+    DROP COLUMN IF EXISTS allowed_email_domains,
+    DROP COLUMN IF EXISTS access_mode;
@@ -0,0 +1,16 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS access_mode           TEXT   NOT NULL DEFAULT 'open'
+        CHECK (access_mode IN ('open', 'members', 'email_domain')),
+    ADD COLUMN IF NOT EXISTS allowed_email_domains TEXT[] NOT NULL DEFAULT '{}';
+
+-- Users admins added to an app. Unlike app_members, which records who logged in to an app,
+-- rows here are only written by admins and decide who may log in to apps in "members" mode.
+CREATE TABLE IF NOT EXISTS app_memberships
+(
+    app_id     UUID        NOT NULL REFERENCES apps (app_id) ON DELETE CASCADE,
+    user_id    UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    added_by   UUID        REFERENCES users (user_id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_app_memberships_user ON app_memberships (user_id);
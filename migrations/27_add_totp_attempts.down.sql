@@ -0,0 +1,3 @@
+ALTER TABLE user_totp
+    DROP COLUMN IF EXISTS last_attempt_at,
+    DROP COLUMN IF EXISTS attempts;
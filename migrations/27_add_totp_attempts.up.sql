@@ -0,0 +1,5 @@
+-- Codes checked against the secret since the last accepted one, so guessing is limited per user
+-- and not only per login challenge.
+ALTER TABLE user_totp
+    ADD COLUMN IF NOT EXISTS attempts        INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_attempt_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_email_lower;
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+CREATE INDEX IF NOT EXISTS idx_email ON users (email);
@@ -0,0 +1,41 @@
+-- Emails are normalized like email.Normalize does. The trimmed class is the white space
+-- of unicode.IsSpace, which strings.TrimSpace strips, not just the spaces btrim strips.
+-- Refuse to migrate while existing emails collide after normalization,
+-- otherwise the unique index below would fail with a less useful error.
+DO
+$$
+DECLARE
+    collisions TEXT;
+BEGIN
+    SELECT string_agg(format('%s (%s users)', normalized, cnt), ', ')
+    INTO collisions
+    FROM (SELECT normalized, count(*) AS cnt
+          FROM (SELECT lower(normalize(regexp_replace(
+                           email,
+                           '^[\t\n\v\f\r \u0085\u00A0\u1680\u2000-\u200A\u2028\u2029\u202F\u205F\u3000]+|[\t\n\v\f\r \u0085\u00A0\u1680\u2000-\u200A\u2028\u2029\u202F\u205F\u3000]+$',
+                           '', 'g'), NFC)) AS normalized
+                FROM users) n
+          GROUP BY normalized
+          HAVING count(*) > 1) c;
+
+    IF collisions IS NOT NULL THEN
+        RAISE EXCEPTION 'users.email has case-insensitive duplicates, resolve them before migrating: %', collisions;
+    END IF;
+END
+$$;
+
+UPDATE users u
+SET email = n.normalized
+FROM (SELECT user_id,
+             lower(normalize(regexp_replace(
+                 email,
+                 '^[\t\n\v\f\r \u0085\u00A0\u1680\u2000-\u200A\u2028\u2029\u202F\u205F\u3000]+|[\t\n\v\f\r \u0085\u00A0\u1680\u2000-\u200A\u2028\u2029\u202F\u205F\u3000]+$',
+                 '', 'g'), NFC)) AS normalized
+      FROM users) n
+WHERE u.user_id = n.user_id
+  AND u.email <> n.normalized;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+DROP INDEX IF EXISTS idx_email;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email));
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS phone_codes;
+
+DROP INDEX IF EXISTS idx_users_phone;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS phone_verified_at,
+    DROP COLUMN IF EXISTS phone;
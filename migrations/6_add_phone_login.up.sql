@@ -0,0 +1,19 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS phone             TEXT,
+    ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone ON users (phone);
+
+CREATE TABLE IF NOT EXISTS phone_codes
+(
+    code_id     UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    phone       TEXT        NOT NULL,
+    purpose     TEXT        NOT NULL,
+    user_id     UUID REFERENCES users (user_id) ON DELETE CASCADE,
+    code_hash   TEXT        NOT NULL,
+    attempts    INT         NOT NULL DEFAULT 0,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    consumed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_phone_codes_phone_created ON phone_codes (phone, created_at DESC);
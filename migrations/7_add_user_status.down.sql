@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS audit_events;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS sessions_revoked_at,
+    DROP COLUMN IF EXISTS suspended_until,
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,17 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status              TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('pending', 'active', 'suspended', 'deactivated')),
+    ADD COLUMN IF NOT EXISTS status_reason       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS suspended_until     TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    event_id   UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    user_id    UUID,
+    actor_id   UUID,
+    event      TEXT        NOT NULL,
+    details    JSONB       NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_created ON audit_events (user_id, created_at DESC);
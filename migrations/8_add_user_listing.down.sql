@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_users_last_login_at;
+DROP INDEX IF EXISTS idx_users_status_created;
+DROP INDEX IF EXISTS idx_users_email_prefix;
+DROP INDEX IF EXISTS idx_users_email_sort;
+DROP INDEX IF EXISTS idx_users_last_login;
+DROP INDEX IF EXISTS idx_users_created;
+
+DROP TABLE IF EXISTS app_members;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS last_login_at,
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,23 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS app_members
+(
+    app_id        UUID        NOT NULL REFERENCES apps (app_id) ON DELETE CASCADE,
+    user_id       UUID        NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_login_at TIMESTAMPTZ,
+    PRIMARY KEY (app_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_app_members_user ON app_members (user_id);
+
+-- Keyset pagination indexes: every sort order is (sort key, user_id).
+CREATE INDEX IF NOT EXISTS idx_users_created ON users (created_at, user_id);
+CREATE INDEX IF NOT EXISTS idx_users_last_login
+    ON users ((COALESCE(last_login_at, '1970-01-01 00:00:00+00'::timestamptz)), user_id);
+CREATE INDEX IF NOT EXISTS idx_users_email_sort ON users (lower(email), user_id);
+CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users (lower(email) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_status_created ON users (status, created_at, user_id);
+-- Last login range filters compare the raw column, which the sort index above can't serve.
+CREATE INDEX IF NOT EXISTS idx_users_last_login_at ON users (last_login_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_updated;
+
+DROP TRIGGER IF EXISTS trg_users_updated_at ON users;
+DROP FUNCTION IF EXISTS set_updated_at();
+
+ALTER TABLE users DROP COLUMN IF EXISTS updated_at;
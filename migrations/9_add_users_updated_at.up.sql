@@ -0,0 +1,22 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+-- Logins are not changes of the user: RecordLogin must not make every active user
+-- show up in incremental exports.
+CREATE TRIGGER trg_users_updated_at
+    BEFORE UPDATE
+    ON users
+    FOR EACH ROW
+    WHEN ((to_jsonb(OLD) - 'last_login_at' - 'updated_at') IS DISTINCT FROM
+          (to_jsonb(NEW) - 'last_login_at' - 'updated_at'))
+EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_users_updated ON users (updated_at, user_id);
@@ -3,8 +3,8 @@ package tests
 import (
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
 	"github.com/sol1corejz/auth-service/tests/suite"
-	ssov1 "github.com/sol1corejz/sso-protos/gen/go/sso"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
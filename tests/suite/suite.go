@@ -2,8 +2,8 @@ package suite
 
 import (
 	"context"
+	ssov1 "github.com/sol1corejz/auth-service/gen/go/sso"
 	"github.com/sol1corejz/auth-service/internal/config"
-	ssov1 "github.com/sol1corejz/sso-protos/gen/go/sso"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"net"